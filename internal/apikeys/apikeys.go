@@ -0,0 +1,205 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// keyPrefix marks API keys so they are recognizable in configs and logs
+const keyPrefix = "vcs_"
+
+// Scopes granted to API keys
+const (
+	ScopeRoomsRead      = "rooms:read"
+	ScopeRoomsWrite     = "rooms:write"
+	ScopeRecordingsRead = "recordings:read"
+	ScopeSCIM           = "scim" // user and group provisioning
+)
+
+// Scopes lists every valid scope
+var Scopes = []string{ScopeRoomsRead, ScopeRoomsWrite, ScopeRecordingsRead, ScopeSCIM}
+
+var (
+	// ErrKeyNotFound is returned for unknown or revoked keys
+	ErrKeyNotFound = errors.New("api key not found")
+
+	// ErrInvalidScope is returned when creating a key with an unknown scope
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// Key is a long-lived credential for server-to-server access. Only a hash of
+// the secret is stored; the secret itself is returned once on create and rotate.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	OwnerID    string     `json:"owner_id"`
+	TenantID   string     `json:"tenant_id,omitempty"`
+	Prefix     string     `json:"prefix"` // first characters of the secret, for identification
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	hash       []byte
+}
+
+// HasScope reports whether the key grants a scope
+func (k *Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Manager stores API keys in memory
+type Manager struct {
+	keys map[string]*Key
+	mu   sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		keys: make(map[string]*Key),
+	}
+}
+
+// generateSecret returns a new random key secret
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// hashSecret hashes a key secret; secrets are random, so a plain SHA-256 suffices
+func hashSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// Create issues a new key acting in a tenant and returns it with its secret
+func (m *Manager) Create(name, ownerID, tenantID string, scopes []string) (*Key, string, error) {
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &Key{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Scopes:    scopes,
+		OwnerID:   ownerID,
+		TenantID:  tenantID,
+		Prefix:    secret[:len(keyPrefix)+6],
+		CreatedAt: time.Now(),
+		hash:      hashSecret(secret),
+	}
+
+	m.mu.Lock()
+	m.keys[key.ID] = key
+	m.mu.Unlock()
+
+	copied := *key
+	return &copied, secret, nil
+}
+
+// Rotate replaces the secret of a key; the old secret stops working immediately
+func (m *Manager) Rotate(id string) (*Key, string, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key, exists := m.keys[id]
+	if !exists {
+		return nil, "", ErrKeyNotFound
+	}
+
+	now := time.Now()
+	key.hash = hashSecret(secret)
+	key.Prefix = secret[:len(keyPrefix)+6]
+	key.RotatedAt = &now
+
+	copied := *key
+	return &copied, secret, nil
+}
+
+// Revoke deletes a key
+func (m *Manager) Revoke(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.keys[id]; !exists {
+		return ErrKeyNotFound
+	}
+	delete(m.keys, id)
+	return nil
+}
+
+// List returns copies of all keys, oldest first
+func (m *Manager) List() []Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]Key, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Authenticate returns the key matching a secret and records its use
+func (m *Manager) Authenticate(secret string) (*Key, error) {
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, ErrKeyNotFound
+	}
+	hash := hashSecret(secret)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.keys {
+		if subtle.ConstantTimeCompare(key.hash, hash) == 1 {
+			now := time.Now()
+			key.LastUsedAt = &now
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// validScope reports whether a scope is known
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
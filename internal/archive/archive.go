@@ -0,0 +1,29 @@
+// Package archive keeps recordings that are no longer watched on a cheaper storage
+// tier, such as a mounted cold volume or S3 Glacier. Archived objects may take hours
+// to become readable again: a restore is requested first and the object is read once
+// the store reports it ready.
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned for objects that are not in the archive
+var ErrNotFound = errors.New("object not found in archive")
+
+// Store keeps archived objects by key
+type Store interface {
+	// Put stores the content of r under key, replacing any previous object
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Restore asks for an object to be made readable; stores whose objects are always
+	// readable do nothing
+	Restore(ctx context.Context, key string) error
+	// Ready reports whether an object can be read with Get
+	Ready(ctx context.Context, key string) (bool, error)
+	// Get opens a readable object
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object; missing objects are not an error
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dir archives objects as files in a directory, e.g. on a slower volume mounted on the
+// node. Objects are readable at once, so restores complete immediately.
+type Dir struct {
+	path string
+}
+
+// NewDir creates an archive in the directory at path
+func NewDir(path string) (*Dir, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %v", err)
+	}
+	return &Dir{path: path}, nil
+}
+
+// file returns the path of the file of an object
+func (d *Dir) file(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid archive key: %q", key)
+	}
+	return filepath.Join(d.path, key), nil
+}
+
+// Put writes an object next to its final file and moves it there once complete
+func (d *Dir) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := d.file(key)
+	if err != nil {
+		return err
+	}
+
+	partial := path + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %v", err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(partial)
+		return fmt.Errorf("failed to write archive file: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to write archive file: %v", err)
+	}
+	return os.Rename(partial, path)
+}
+
+// Restore does nothing: files are always readable
+func (d *Dir) Restore(ctx context.Context, key string) error {
+	path, err := d.file(key)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Ready reports whether the file of an object exists
+func (d *Dir) Ready(ctx context.Context, key string) (bool, error) {
+	path, err := d.file(key)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, ErrNotFound
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Get opens the file of an object
+func (d *Dir) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := d.file(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the file of an object
+func (d *Dir) Delete(ctx context.Context, key string) error {
+	path, err := d.file(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete archive file: %v", err)
+	}
+	return nil
+}
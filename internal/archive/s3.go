@@ -0,0 +1,142 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3PartSize is the size of the parts recordings are uploaded in
+const s3PartSize = 16 << 20
+
+// s3 error codes
+const (
+	s3NoSuchKey         = "NoSuchKey"
+	s3RestoreInProgress = "RestoreAlreadyInProgress"
+)
+
+// S3Config configures an S3 archive
+type S3Config struct {
+	Endpoint     string // host[:port] of the S3 API, e.g. s3.eu-west-1.amazonaws.com
+	Secure       bool   // use HTTPS
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	StorageClass string // e.g. GLACIER, DEEP_ARCHIVE or STANDARD_IA; empty uses the bucket default
+	RestoreDays  int    // how long restored copies of Glacier objects stay readable
+}
+
+// S3 archives objects in an S3 bucket with a storage class such as GLACIER. Objects of
+// the Glacier classes are restored with a Standard retrieval before they can be read.
+type S3 struct {
+	client *minio.Client
+	config S3Config
+}
+
+// NewS3 creates an archive in an S3 bucket
+func NewS3(config S3Config) (*S3, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.Secure,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %v", err)
+	}
+	if config.RestoreDays <= 0 {
+		config.RestoreDays = 1
+	}
+	return &S3{client: client, config: config}, nil
+}
+
+// Put uploads an object with the configured storage class
+func (s *S3) Put(ctx context.Context, key string, r io.Reader) error {
+	_, err := s.client.PutObject(ctx, s.config.Bucket, key, r, -1, minio.PutObjectOptions{
+		ContentType:  "application/x-tar",
+		StorageClass: s.config.StorageClass,
+		PartSize:     s3PartSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to s3: %v", err)
+	}
+	return nil
+}
+
+// Restore starts a Standard retrieval of a Glacier object. Objects of other storage
+// classes are readable at once.
+func (s *S3) Restore(ctx context.Context, key string) error {
+	info, err := s.stat(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !glacier(info.StorageClass) {
+		return nil
+	}
+
+	req := minio.RestoreRequest{}
+	req.SetDays(s.config.RestoreDays)
+	req.SetGlacierJobParameters(minio.GlacierJobParameters{Tier: minio.TierStandard})
+	err = s.client.RestoreObject(ctx, s.config.Bucket, key, "", req)
+	if err != nil && minio.ToErrorResponse(err).Code != s3RestoreInProgress {
+		return fmt.Errorf("failed to restore from s3: %v", err)
+	}
+	return nil
+}
+
+// Ready reports whether an object is readable: it is not in a Glacier class, or a
+// restored copy of it is available
+func (s *S3) Ready(ctx context.Context, key string) (bool, error) {
+	info, err := s.stat(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if !glacier(info.StorageClass) {
+		return true, nil
+	}
+	return info.Restore != nil && !info.Restore.OngoingRestore, nil
+}
+
+// Get downloads an object
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if _, err := s.stat(ctx, key); err != nil {
+		return nil, err
+	}
+	object, err := s.client.GetObject(ctx, s.config.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from s3: %v", err)
+	}
+	return object, nil
+}
+
+// Delete removes an object
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.config.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete from s3: %v", err)
+	}
+	return nil
+}
+
+// stat returns the metadata of an object
+func (s *S3) stat(ctx context.Context, key string) (minio.ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.config.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == s3NoSuchKey {
+			return info, ErrNotFound
+		}
+		return info, fmt.Errorf("failed to read s3 object: %v", err)
+	}
+	return info, nil
+}
+
+// glacier reports whether objects of a storage class must be restored before reading
+func glacier(storageClass string) bool {
+	switch storageClass {
+	case "GLACIER", "DEEP_ARCHIVE":
+		return true
+	}
+	return false
+}
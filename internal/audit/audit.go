@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/logging"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+// maxEntries is the number of audit entries kept in memory
+const maxEntries = 1000
+
+// Entry is a single audited administrative action
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	ActorID string            `json:"actor_id"`
+	Actor   string            `json:"actor"`
+	Action  string            `json:"action"`
+	Target  string            `json:"target"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Logger records audit entries to the log and keeps recent ones in memory
+type Logger struct {
+	entries []Entry
+	mu      sync.RWMutex
+}
+
+// NewLogger creates a new Logger
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// Record stores an audit entry
+func (l *Logger) Record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	if data, err := json.Marshal(entry); err == nil {
+		logger.Infof("AUDIT %s", data)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > maxEntries {
+		l.entries = l.entries[len(l.entries)-maxEntries:]
+	}
+}
+
+// Filter selects audit entries; zero fields match everything
+type Filter struct {
+	Action string // an action, or a prefix of actions ending in a dot such as "legal_hold."
+	Target string
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(entry Entry) bool {
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			if !strings.HasPrefix(entry.Action, f.Action) {
+				return false
+			}
+		} else if entry.Action != f.Action {
+			return false
+		}
+	}
+	return f.Target == "" || entry.Target == f.Target
+}
+
+// Recent returns up to limit most recent entries matching filter, newest first
+func (l *Logger) Recent(limit int, filter Filter) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if limit <= 0 || limit > len(l.entries) {
+		limit = len(l.entries)
+	}
+
+	entries := make([]Entry, 0, limit)
+	for i := len(l.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		if filter.matches(l.entries[i]) {
+			entries = append(entries, l.entries[i])
+		}
+	}
+	return entries
+}
@@ -6,23 +6,48 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/zubans/video-call-server/internal/models"
 )
 
 // JWTSecret is the secret key for JWT tokens
 var JWTSecret = []byte("video-call-server-secret-key-change-in-production")
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents a user in the system
 type User struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Password      string `json:"password"`
+	Role          string `json:"role"`
+	DisplayName   string `json:"display_name,omitempty"`
+	AvatarURL     string `json:"avatar_url,omitempty"`
+	Locale        string `json:"locale,omitempty"`
+	Org           string `json:"org,omitempty"`         // organization assigned by the SSO identity provider
+	SSOIssuer     string `json:"sso_issuer,omitempty"`  // entity ID of the identity provider the user signs in with
+	SSOSubject    string `json:"-"`                     // NameID of the user at the identity provider
+	ExternalID    string `json:"external_id,omitempty"` // ID of the user at the provisioning identity provider
+	Deactivated   bool   `json:"deactivated,omitempty"` // deactivated users cannot sign in or use their tokens
+	TenantID      string `json:"tenant_id,omitempty"`   // customer the account belongs to; empty is the default tenant
+
+	LegalHold *models.LegalHold `json:"legal_hold,omitempty"` // set while the account and its data may not be deleted
 }
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
+	UserID   string     `json:"user_id"`
+	Username string     `json:"username"`
+	Role     string     `json:"role,omitempty"`
+	Room     *RoomGrant `json:"room,omitempty"`       // set on room-scoped tokens
+	Type     string     `json:"token_type,omitempty"` // access, refresh or guest
+	Tenant   string     `json:"tenant,omitempty"`     // tenant of the user or, for guests, of the room
 	jwt.RegisteredClaims
 }
 
@@ -38,25 +63,38 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateJWT generates a JWT token for a user
-func GenerateJWT(userID, username string) (string, error) {
-	// Set expiration time to 24 hours
-	expirationTime := time.Now().Add(24 * time.Hour)
-	
-	// Create claims
-	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+// GenerateJWT generates an access token for a user of a tenant
+func GenerateJWT(userID, username, role, tenantID string) (string, error) {
+	return signToken(&Claims{UserID: userID, Username: username, Role: role, Tenant: tenantID}, TokenAccess, Policy(TokenAccess).TTL)
+}
+
+// GenerateRefreshJWT generates a refresh token, exchanged for new access tokens
+// without signing in again
+func GenerateRefreshJWT(userID, username, tenantID string) (string, error) {
+	return signToken(&Claims{UserID: userID, Username: username, Tenant: tenantID}, TokenRefresh, Policy(TokenRefresh).TTL)
+}
+
+// signToken signs claims as a token of a type expiring after ttl, with a token ID
+// used for revocation, and starts its idle session
+func signToken(claims *Claims, tokenType string, ttl time.Duration) (string, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", err
 	}
-	
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	
-	// Sign token with secret key
-	return token.SignedString(JWTSecret)
+	claims.Type = tokenType
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JWTSecret)
+	if err != nil {
+		return "", err
+	}
+	if _, err := TouchSession(claims); err != nil {
+		return "", err
+	}
+	return token, nil
 }
 
 // ValidateJWT validates a JWT token and returns the claims
@@ -83,11 +121,11 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 // Mock user storage (in production, use a database)
 var users = make(map[string]*User)
 
-// RegisterUser registers a new user
-func RegisterUser(username, email, password string) (*User, error) {
+// RegisterUser registers a new user in a tenant; usernames and emails are unique per tenant
+func RegisterUser(tenantID, username, email, password string) (*User, error) {
 	// Check if user already exists
 	for _, user := range users {
-		if user.Username == username || user.Email == email {
+		if user.TenantID == tenantID && (user.Username == username || user.Email == email) {
 			return nil, errors.New("user already exists")
 		}
 	}
@@ -104,6 +142,8 @@ func RegisterUser(username, email, password string) (*User, error) {
 		Username: username,
 		Email:    email,
 		Password: hashedPassword,
+		Role:     RoleUser,
+		TenantID: tenantID,
 	}
 	
 	// Store user
@@ -112,12 +152,12 @@ func RegisterUser(username, email, password string) (*User, error) {
 	return user, nil
 }
 
-// AuthenticateUser authenticates a user with username/email and password
-func AuthenticateUser(identifier, password string) (*User, error) {
+// AuthenticateUser authenticates a user of a tenant with username/email and password
+func AuthenticateUser(tenantID, identifier, password string) (*User, error) {
 	// Find user by username or email
 	var user *User
 	for _, u := range users {
-		if u.Username == identifier || u.Email == identifier {
+		if u.TenantID == tenantID && (u.Username == identifier || u.Email == identifier) {
 			user = u
 			break
 		}
@@ -132,6 +172,9 @@ func AuthenticateUser(identifier, password string) (*User, error) {
 	if !CheckPasswordHash(password, user.Password) {
 		return nil, errors.New("invalid password")
 	}
+	if user.Deactivated {
+		return nil, ErrUserDeactivated
+	}
 	
 	return user, nil
 }
@@ -142,6 +185,49 @@ func GetUserByID(userID string) (*User, bool) {
 	return user, exists
 }
 
+// GetUserByUsername returns the user of a tenant with a username
+func GetUserByUsername(tenantID, username string) (*User, bool) {
+	for _, user := range users {
+		if user.TenantID == tenantID && user.Username == username {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+// SetUserRole changes the role of a user
+func SetUserRole(userID, role string) error {
+	user, exists := users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+	
+	user.Role = role
+	return nil
+}
+
+// SetUserLegalHold places a legal hold on a user, or releases it when hold is nil
+func SetUserLegalHold(userID string, hold *models.LegalHold) error {
+	user, exists := users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	user.LegalHold = hold
+	return nil
+}
+
+// UsersOnLegalHold returns the users under legal hold
+func UsersOnLegalHold() []*User {
+	var held []*User
+	for _, user := range users {
+		if user.LegalHold != nil {
+			held = append(held, user)
+		}
+	}
+	return held
+}
+
 // generateUserID generates a simple user ID (in production, use UUID)
 func generateUserID() string {
 	// In production, use uuid.New().String()
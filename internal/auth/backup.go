@@ -0,0 +1,43 @@
+package auth
+
+import "unicode/utf8"
+
+// UserBackup is an account as written to backups, including its password hash and
+// SSO subject
+type UserBackup struct {
+	User
+	SSOSubject string `json:"sso_subject,omitempty"`
+}
+
+// ExportUsers returns copies of every account of every tenant
+func ExportUsers() []UserBackup {
+	list := make([]UserBackup, 0, len(users))
+	for _, user := range users {
+		list = append(list, UserBackup{User: *user, SSOSubject: user.SSOSubject})
+	}
+	return list
+}
+
+// ImportUser adds an account from a backup, keeping its ID. It fails with ErrUserExists
+// when the ID is taken or another account of the tenant has the username or email.
+func ImportUser(backup UserBackup) error {
+	if _, exists := users[backup.ID]; exists || deleted[backup.ID] {
+		return ErrUserExists
+	}
+	if err := checkUnique("", UserAttributes{Username: backup.Username, Email: backup.Email, TenantID: backup.TenantID}); err != nil {
+		return err
+	}
+
+	user := backup.User
+	user.SSOSubject = backup.SSOSubject
+	if user.Role == "" {
+		user.Role = RoleUser
+	}
+	users[user.ID] = &user
+
+	// New accounts must not be given an imported ID
+	if r, size := utf8.DecodeRuneInString(user.ID); size == len(user.ID) && int(r) > idCounter {
+		idCounter = int(r)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"time"
+)
+
+// RoomGrant limits a token to one room and to the actions allowed in it
+type RoomGrant struct {
+	RoomID       string `json:"room_id"`
+	CanPublish   bool   `json:"can_publish"`
+	CanSubscribe bool   `json:"can_subscribe"`
+	CanChat      bool   `json:"can_chat"`
+	IsHost       bool   `json:"is_host"`
+}
+
+// GenerateRoomJWT generates a room-scoped guest token carrying a grant, in the
+// tenant of the room
+func GenerateRoomJWT(userID, username, tenantID string, grant RoomGrant, ttl time.Duration) (string, error) {
+	return signToken(&Claims{UserID: userID, Username: username, Tenant: tenantID, Room: &grant}, TokenGuest, ttl)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ProfileUpdate holds the profile fields to change; nil fields are left as they are
+type ProfileUpdate struct {
+	DisplayName *string
+	Locale      *string
+}
+
+// UpdateProfile changes the display name and locale of a user
+func UpdateProfile(userID string, update ProfileUpdate) (*User, error) {
+	user, exists := users[userID]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+
+	if update.DisplayName != nil {
+		user.DisplayName = *update.DisplayName
+	}
+	if update.Locale != nil {
+		user.Locale = *update.Locale
+	}
+	return user, nil
+}
+
+// SetUserAvatar sets the avatar URL of a user and returns the previous one
+func SetUserAvatar(userID, avatarURL string) (string, error) {
+	user, exists := users[userID]
+	if !exists {
+		return "", errors.New("user not found")
+	}
+
+	previous := user.AvatarURL
+	user.AvatarURL = avatarURL
+	return previous, nil
+}
+
+// Name returns the name to show for a user: the display name if set, else the username
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+// SearchUsers returns up to limit users of a tenant whose username starts with the
+// query. Emails are only matched once the query includes the "@", so addresses cannot
+// be enumerated from a few letters.
+func SearchUsers(tenantID, query string, limit int) []*User {
+	query = strings.ToLower(query)
+	matchEmail := strings.Contains(query, "@")
+
+	var matches []*User
+	for _, user := range users {
+		if user.TenantID != tenantID {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(user.Username), query) ||
+			(matchEmail && strings.HasPrefix(strings.ToLower(user.Email), query)) {
+			matches = append(matches, user)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Username < matches[j].Username
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
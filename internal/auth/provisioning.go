@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUserNotFound is returned for unknown users
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserExists is returned when a username or email is taken
+	ErrUserExists = errors.New("user already exists")
+
+	// ErrUserDeactivated is returned when a deactivated user signs in
+	ErrUserDeactivated = errors.New("user is deactivated")
+
+	// ErrLegalHold is returned when deleting a user under legal hold
+	ErrLegalHold = errors.New("user is under legal hold")
+)
+
+// deleted holds the IDs of deleted users, whose tokens are no longer accepted
+var deleted = make(map[string]bool)
+
+// UserAttributes are the attributes of an account managed by a provisioning
+// identity provider
+type UserAttributes struct {
+	Username    string
+	Email       string
+	DisplayName string
+	ExternalID  string
+	Org         string
+	Password    string // optional; provisioned users usually sign in with SSO
+	Active      bool
+	TenantID    string // set on create; accounts do not move between tenants
+}
+
+// ListUsers returns the users of a tenant, by username
+func ListUsers(tenantID string) []*User {
+	list := make([]*User, 0, len(users))
+	for _, user := range users {
+		if user.TenantID == tenantID {
+			list = append(list, user)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Username < list[j].Username
+	})
+	return list
+}
+
+// CreateUser creates a provisioned account
+func CreateUser(attrs UserAttributes) (*User, error) {
+	if err := checkUnique("", attrs); err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		ID:       generateUserID(),
+		Role:     RoleUser,
+		TenantID: attrs.TenantID,
+	}
+	if err := applyAttributes(user, attrs); err != nil {
+		return nil, err
+	}
+	users[user.ID] = user
+	return user, nil
+}
+
+// UpdateUser replaces the provisioned attributes of an account; the password is
+// only changed when given
+func UpdateUser(userID string, attrs UserAttributes) (*User, error) {
+	user, exists := users[userID]
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	attrs.TenantID = user.TenantID
+	if err := checkUnique(userID, attrs); err != nil {
+		return nil, err
+	}
+
+	if err := applyAttributes(user, attrs); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteUser removes an account; its tokens stop being accepted. Accounts under legal
+// hold are kept.
+func DeleteUser(userID string) error {
+	user, exists := users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+	if user.LegalHold != nil {
+		return ErrLegalHold
+	}
+
+	delete(users, userID)
+	deleted[userID] = true
+	return nil
+}
+
+// UserActive reports whether tokens of a user are accepted: users without an
+// account (e.g. room token guests) are active, deleted and deactivated ones not
+func UserActive(userID string) bool {
+	if deleted[userID] {
+		return false
+	}
+	user, exists := users[userID]
+	return !exists || !user.Deactivated
+}
+
+// checkUnique fails if another account of the tenant has the username or email
+func checkUnique(userID string, attrs UserAttributes) error {
+	if attrs.Username == "" {
+		return errors.New("username is required")
+	}
+	for _, user := range users {
+		if user.ID == userID || user.TenantID != attrs.TenantID {
+			continue
+		}
+		if user.Username == attrs.Username || (attrs.Email != "" && strings.EqualFold(user.Email, attrs.Email)) {
+			return ErrUserExists
+		}
+	}
+	return nil
+}
+
+// applyAttributes sets the provisioned attributes of an account. The identity
+// provider vouches for the email, so it counts as verified.
+func applyAttributes(user *User, attrs UserAttributes) error {
+	if attrs.Password != "" {
+		hashedPassword, err := HashPassword(attrs.Password)
+		if err != nil {
+			return err
+		}
+		user.Password = hashedPassword
+	}
+
+	if attrs.Email != user.Email {
+		user.EmailVerified = attrs.Email != ""
+	}
+	user.Username = attrs.Username
+	user.Email = attrs.Email
+	user.DisplayName = attrs.DisplayName
+	user.ExternalID = attrs.ExternalID
+	user.Org = attrs.Org
+	user.Deactivated = !attrs.Active
+	return nil
+}
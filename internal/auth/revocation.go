@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// RevocationStore records revoked token IDs (jti) until the tokens expire
+type RevocationStore interface {
+	Revoke(tokenID string, expiresAt time.Time) error
+	IsRevoked(tokenID string) (bool, error)
+	// TryRevoke revokes a token ID unless it already is, in one atomic step, and
+	// reports whether this call revoked it
+	TryRevoke(tokenID string, expiresAt time.Time) (bool, error)
+}
+
+// revocations is the store consulted for revoked tokens
+var (
+	revocations   RevocationStore = NewMemoryRevocationStore()
+	revocationsMu sync.RWMutex
+)
+
+// SetRevocationStore replaces the revocation store, e.g. with one shared by all nodes
+func SetRevocationStore(store RevocationStore) {
+	revocationsMu.Lock()
+	defer revocationsMu.Unlock()
+
+	revocations = store
+}
+
+// RevokeToken revokes a token by ID until it expires
+func RevokeToken(tokenID string, expiresAt time.Time) error {
+	revocationsMu.RLock()
+	store := revocations
+	revocationsMu.RUnlock()
+
+	return store.Revoke(tokenID, expiresAt)
+}
+
+// TryRevokeToken revokes a token by ID and reports false if it was already revoked,
+// so that of concurrent uses of a single-use token only one succeeds
+func TryRevokeToken(tokenID string, expiresAt time.Time) (bool, error) {
+	revocationsMu.RLock()
+	store := revocations
+	revocationsMu.RUnlock()
+
+	return store.TryRevoke(tokenID, expiresAt)
+}
+
+// IsTokenRevoked reports whether a token ID has been revoked
+func IsTokenRevoked(tokenID string) (bool, error) {
+	revocationsMu.RLock()
+	store := revocations
+	revocationsMu.RUnlock()
+
+	return store.IsRevoked(tokenID)
+}
+
+// generateTokenID generates a random token ID for the jti claim
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// MemoryRevocationStore keeps revoked token IDs in memory
+type MemoryRevocationStore struct {
+	revoked map[string]time.Time
+	mu      sync.Mutex
+}
+
+// NewMemoryRevocationStore creates a new MemoryRevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked: make(map[string]time.Time),
+	}
+}
+
+// Revoke records a token ID and drops entries whose tokens have already expired
+func (m *MemoryRevocationStore) Revoke(tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for id, expiry := range m.revoked {
+		if now.After(expiry) {
+			delete(m.revoked, id)
+		}
+	}
+
+	m.revoked[tokenID] = expiresAt
+	return nil
+}
+
+// IsRevoked reports whether a token ID was revoked and has not expired yet
+func (m *MemoryRevocationStore) IsRevoked(tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiry, exists := m.revoked[tokenID]
+	return exists && time.Now().Before(expiry), nil
+}
+
+// TryRevoke records a token ID unless it is already revoked, checking and recording
+// under one lock
+func (m *MemoryRevocationStore) TryRevoke(tokenID string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if expiry, exists := m.revoked[tokenID]; exists && time.Now().Before(expiry) {
+		return false, nil
+	}
+	m.revoked[tokenID] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Token types, carried in the token_type claim
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+	TokenGuest   = "guest" // room-scoped tokens
+)
+
+// TokenPolicy is the lifetime of a token type: tokens expire TTL after they are
+// issued and, when Idle is set, once they go unused for Idle
+type TokenPolicy struct {
+	TTL  time.Duration
+	Idle time.Duration
+}
+
+// policies are the lifetimes of each token type
+var (
+	policies = map[string]TokenPolicy{
+		TokenAccess:  {TTL: 24 * time.Hour},
+		TokenRefresh: {TTL: 30 * 24 * time.Hour},
+		TokenGuest:   {TTL: time.Hour},
+	}
+	policiesMu sync.RWMutex
+)
+
+// SetTokenPolicy sets the lifetime of a token type
+func SetTokenPolicy(tokenType string, policy TokenPolicy) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+
+	policies[tokenType] = policy
+}
+
+// Policy returns the lifetime of a token type
+func Policy(tokenType string) TokenPolicy {
+	policiesMu.RLock()
+	defer policiesMu.RUnlock()
+
+	return policies[tokenType]
+}
+
+// TokenType returns the type of a token; tokens issued before types were
+// introduced are access tokens, or guest tokens if room-scoped
+func (c *Claims) TokenType() string {
+	switch {
+	case c.Type != "":
+		return c.Type
+	case c.Room != nil:
+		return TokenGuest
+	}
+	return TokenAccess
+}
+
+// SessionStore records when tokens were last used, for idle expiry
+type SessionStore interface {
+	// Touch records a use of a token and reports whether it was used within idle
+	// before. Tokens without a record start one, kept until expiresAt.
+	Touch(tokenID string, idle time.Duration, expiresAt time.Time) (bool, error)
+}
+
+// sessions is the store consulted for idle expiry
+var (
+	sessions   SessionStore = NewMemorySessionStore()
+	sessionsMu sync.RWMutex
+)
+
+// SetSessionStore replaces the session store, e.g. with one shared by all nodes
+func SetSessionStore(store SessionStore) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	sessions = store
+}
+
+// TouchSession records a use of a token whose type has idle expiry, reporting
+// whether the token is still alive
+func TouchSession(claims *Claims) (bool, error) {
+	policy := Policy(claims.TokenType())
+	if policy.Idle <= 0 || claims.ID == "" || claims.ExpiresAt == nil {
+		return true, nil
+	}
+
+	sessionsMu.RLock()
+	store := sessions
+	sessionsMu.RUnlock()
+
+	return store.Touch(claims.ID, policy.Idle, claims.ExpiresAt.Time)
+}
+
+// MemorySessionStore keeps the last use of each token in memory
+type MemorySessionStore struct {
+	sessions map[string]memorySession
+	mu       sync.Mutex
+}
+
+// memorySession is the last use of a token
+type memorySession struct {
+	lastUsed  time.Time
+	expiresAt time.Time
+}
+
+// NewMemorySessionStore creates a new MemorySessionStore
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]memorySession),
+	}
+}
+
+// Touch records a use of a token; idle tokens stay expired and records of expired
+// tokens are dropped
+func (m *MemorySessionStore) Touch(tokenID string, idle time.Duration, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	session, exists := m.sessions[tokenID]
+	if exists && now.Sub(session.lastUsed) > idle {
+		return false, nil
+	}
+
+	if !exists {
+		for id, other := range m.sessions {
+			if now.After(other.expiresAt) {
+				delete(m.sessions, id)
+			}
+		}
+	}
+	m.sessions[tokenID] = memorySession{lastUsed: now, expiresAt: expiresAt}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SSOIdentity is a user asserted by an SSO identity provider
+type SSOIdentity struct {
+	Issuer      string // entity ID of the identity provider
+	Subject     string // NameID of the user at the identity provider
+	Username    string
+	Email       string
+	DisplayName string
+	Org         string
+	Admin       *bool  // role from identity provider groups; nil keeps the current role
+	TenantID    string // tenant the identity provider signs users in to
+}
+
+// ProvisionSSOUser returns the account of a user signed in by an identity provider.
+// On first sign-in it links the account of the tenant with the same email or creates
+// one (just in time); the identity provider's attributes then replace the account's.
+// SSO accounts have no password. It reports whether the account was created.
+func ProvisionSSOUser(identity SSOIdentity) (*User, bool, error) {
+	if identity.Issuer == "" || identity.Subject == "" {
+		return nil, false, errors.New("identity provider did not assert a subject")
+	}
+
+	var user *User
+	for _, u := range users {
+		if u.TenantID == identity.TenantID && u.SSOIssuer == identity.Issuer && u.SSOSubject == identity.Subject {
+			user = u
+			break
+		}
+	}
+	if user == nil && identity.Email != "" {
+		for _, u := range users {
+			if u.TenantID == identity.TenantID && strings.EqualFold(u.Email, identity.Email) {
+				if u.SSOSubject != "" {
+					return nil, false, ErrUserExists
+				}
+				user = u
+				break
+			}
+		}
+	}
+
+	if user != nil && user.Deactivated {
+		return nil, false, ErrUserDeactivated
+	}
+
+	created := false
+	if user == nil {
+		user = &User{
+			ID:       generateUserID(),
+			Username: uniqueUsername(identity.TenantID, ssoUsername(identity)),
+			Role:     RoleUser,
+			TenantID: identity.TenantID,
+		}
+		users[user.ID] = user
+		created = true
+	}
+
+	user.SSOIssuer = identity.Issuer
+	user.SSOSubject = identity.Subject
+	if identity.Email != "" {
+		user.Email = identity.Email
+		user.EmailVerified = true
+	}
+	if identity.DisplayName != "" {
+		user.DisplayName = identity.DisplayName
+	}
+	if identity.Org != "" {
+		user.Org = identity.Org
+	}
+	if identity.Admin != nil {
+		user.Role = RoleUser
+		if *identity.Admin {
+			user.Role = RoleAdmin
+		}
+	}
+	return user, created, nil
+}
+
+// ssoUsername picks the username of a new SSO account: the asserted username, else
+// the local part of the email, else the subject
+func ssoUsername(identity SSOIdentity) string {
+	if identity.Username != "" {
+		return identity.Username
+	}
+	if local, _, found := strings.Cut(identity.Email, "@"); found && local != "" {
+		return local
+	}
+	return identity.Subject
+}
+
+// uniqueUsername returns name, suffixed with a number if another user of the tenant has it
+func uniqueUsername(tenantID, name string) string {
+	taken := func(candidate string) bool {
+		for _, u := range users {
+			if u.TenantID == tenantID && u.Username == candidate {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidate := name
+	for i := 2; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	return candidate
+}
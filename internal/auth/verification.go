@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// EmailVerificationTTL is how long an email verification link stays valid
+const EmailVerificationTTL = 48 * time.Hour
+
+// ErrInvalidVerification is returned for unknown, expired or outdated verification tokens
+var ErrInvalidVerification = errors.New("verification link is invalid or has expired")
+
+// emailVerification is a pending confirmation of a user's email address
+type emailVerification struct {
+	userID  string
+	email   string
+	expires time.Time
+}
+
+var (
+	verifications   = make(map[string]*emailVerification)
+	verificationsMu sync.Mutex
+)
+
+// CreateEmailVerification issues a token confirming the current email address of a
+// user; tokens issued earlier for the user stop working
+func CreateEmailVerification(userID string) (string, error) {
+	user, exists := users[userID]
+	if !exists {
+		return "", errors.New("user not found")
+	}
+
+	token, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	verificationsMu.Lock()
+	defer verificationsMu.Unlock()
+
+	now := time.Now()
+	for t, v := range verifications {
+		if v.userID == userID || now.After(v.expires) {
+			delete(verifications, t)
+		}
+	}
+	verifications[token] = &emailVerification{
+		userID:  userID,
+		email:   user.Email,
+		expires: now.Add(EmailVerificationTTL),
+	}
+	return token, nil
+}
+
+// VerifyEmail marks the email address a token was issued for as verified. The token
+// is used up; it fails if the user has changed the address since.
+func VerifyEmail(token string) (*User, error) {
+	verificationsMu.Lock()
+	v, exists := verifications[token]
+	delete(verifications, token)
+	verificationsMu.Unlock()
+
+	if !exists || time.Now().After(v.expires) {
+		return nil, ErrInvalidVerification
+	}
+	user, exists := users[v.userID]
+	if !exists || user.Email != v.email {
+		return nil, ErrInvalidVerification
+	}
+
+	user.EmailVerified = true
+	return user, nil
+}
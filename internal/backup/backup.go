@@ -0,0 +1,159 @@
+// Package backup reads and writes portable archives of server state: users, room
+// metadata, chat history and recording metadata. Media files are not included.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/repository"
+)
+
+// Version is the archive format written by Write
+const Version = 1
+
+// Files of an archive, each holding one JSON document
+const (
+	manifestFile   = "manifest.json"
+	usersFile      = "users.json"
+	roomsFile      = "rooms.json"
+	chatFile       = "chat.json"
+	recordingsFile = "recordings.json"
+)
+
+// maxFileSize bounds each file read from an archive
+const maxFileSize = 1 << 30
+
+var (
+	// ErrInvalidArchive is returned for data that is not a backup archive
+	ErrInvalidArchive = errors.New("not a backup archive")
+
+	// ErrUnsupportedVersion is returned for archives written by a newer format
+	ErrUnsupportedVersion = errors.New("unsupported backup archive version")
+)
+
+// Manifest describes an archive
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Node      string         `json:"node,omitempty"` // node the backup was taken on
+	Counts    map[string]int `json:"counts"`         // entries by file
+}
+
+// Archive is the server state kept in a backup
+type Archive struct {
+	Manifest   Manifest
+	Users      []auth.UserBackup
+	Rooms      []repository.Room
+	Chat       map[string][]chat.Message // by room ID
+	Recordings []recording.Recording
+}
+
+// Write writes an archive as a gzipped tar of JSON files, filling in its manifest
+func Write(w io.Writer, archive *Archive) error {
+	archive.Manifest.Version = Version
+	if archive.Manifest.CreatedAt.IsZero() {
+		archive.Manifest.CreatedAt = time.Now().UTC()
+	}
+	messages := 0
+	for _, list := range archive.Chat {
+		messages += len(list)
+	}
+	archive.Manifest.Counts = map[string]int{
+		usersFile:      len(archive.Users),
+		roomsFile:      len(archive.Rooms),
+		chatFile:       messages,
+		recordingsFile: len(archive.Recordings),
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{manifestFile, archive.Manifest},
+		{usersFile, archive.Users},
+		{roomsFile, archive.Rooms},
+		{chatFile, archive.Chat},
+		{recordingsFile, archive.Recordings},
+	}
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: archive.Manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read reads an archive written by Write; files it does not know are skipped
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	archive := &Archive{}
+	targets := map[string]interface{}{
+		manifestFile:   &archive.Manifest,
+		usersFile:      &archive.Users,
+		roomsFile:      &archive.Rooms,
+		chatFile:       &archive.Chat,
+		recordingsFile: &archive.Recordings,
+	}
+
+	seen := make(map[string]bool)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+		}
+		target, known := targets[header.Name]
+		if !known {
+			continue
+		}
+		if err := json.NewDecoder(io.LimitReader(tr, maxFileSize)).Decode(target); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode %s: %w", ErrInvalidArchive, header.Name, err)
+		}
+		seen[header.Name] = true
+	}
+
+	if !seen[manifestFile] {
+		return nil, ErrInvalidArchive
+	}
+	if archive.Manifest.Version < 1 || archive.Manifest.Version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+	return archive, nil
+}
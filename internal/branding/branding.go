@@ -0,0 +1,123 @@
+package branding
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBrandingNotFound is returned for organizations without branding
+var ErrBrandingNotFound = errors.New("branding not found")
+
+// slugPattern matches organization slugs: lowercase letters, digits and inner hyphens
+var slugPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,62}[a-z0-9])?$`)
+
+// Branding is how white-label clients of an organization are styled
+type Branding struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	LogoURL     string    `json:"logo_url,omitempty"`
+	Colors      Colors    `json:"colors"`
+	WelcomeText string    `json:"welcome_text,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Colors are CSS hex colors; unset colors keep the client's defaults
+type Colors struct {
+	Primary    string `json:"primary,omitempty"`
+	Secondary  string `json:"secondary,omitempty"`
+	Background string `json:"background,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// ValidSlug reports whether a slug can name an organization
+func ValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// Slug derives the slug of an organization from its name, e.g. "Acme Corp." is "acme-corp"
+func Slug(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > 64 {
+		slug = strings.TrimRight(slug[:64], "-")
+	}
+	return slug
+}
+
+// Manager stores branding in memory, by organization slug
+type Manager struct {
+	brandings map[string]*Branding
+	mu        sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		brandings: make(map[string]*Branding),
+	}
+}
+
+// Set creates or replaces the branding of an organization
+func (m *Manager) Set(branding Branding) Branding {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	branding.UpdatedAt = time.Now()
+	m.brandings[branding.Slug] = &branding
+	return branding
+}
+
+// Get returns the branding of an organization
+func (m *Manager) Get(slug string) (Branding, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	branding, exists := m.brandings[slug]
+	if !exists {
+		return Branding{}, false
+	}
+	return *branding, true
+}
+
+// List returns the branding of every organization, by slug
+func (m *Manager) List() []Branding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Branding, 0, len(m.brandings))
+	for _, branding := range m.brandings {
+		list = append(list, *branding)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Slug < list[j].Slug
+	})
+	return list
+}
+
+// Delete removes the branding of an organization
+func (m *Manager) Delete(slug string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.brandings[slug]; !exists {
+		return ErrBrandingNotFound
+	}
+	delete(m.brandings, slug)
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package broker publishes server events to a message broker, Kafka or NATS, so that
+// analytics and other services can consume the call event stream without polling the
+// API. Every event is sent as an Envelope encoded as JSON.
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/events"
+)
+
+// SchemaVersion is the version of the Envelope schema. Fields may be added within a
+// version; removing or changing the meaning of one bumps it.
+const SchemaVersion = 1
+
+// Headers set on every message, so consumers can route events without decoding them
+const (
+	EventTypeHeader     = "event_type"
+	SchemaVersionHeader = "schema_version"
+)
+
+// Envelope is a server event as published to the broker
+type Envelope struct {
+	SchemaVersion int                    `json:"schema_version"`
+	ID            string                 `json:"id"`     // unique per event, for deduplication
+	Type          string                 `json:"type"`   // e.g. room.created, participant.joined
+	Source        string                 `json:"source"` // ID of the node that produced the event
+	Time          time.Time              `json:"time"`
+	RoomID        string                 `json:"room_id,omitempty"`
+	TenantID      string                 `json:"tenant_id,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+}
+
+// NewEnvelope wraps an event produced by the node source
+func NewEnvelope(event events.Event, source, tenantID string) Envelope {
+	return Envelope{
+		SchemaVersion: SchemaVersion,
+		ID:            uuid.NewString(),
+		Type:          event.Type,
+		Source:        source,
+		Time:          event.Time,
+		RoomID:        event.RoomID,
+		TenantID:      tenantID,
+		Data:          event.Data,
+	}
+}
+
+// Publisher sends encoded envelopes to a broker
+type Publisher interface {
+	// Publish sends the JSON body of an envelope. Events of one room keep their order.
+	Publish(ctx context.Context, envelope Envelope, body []byte) error
+	// Close flushes pending events and disconnects
+	Close() error
+}
+
+// Forwarder publishes server events to a broker. Events are queued in the server's
+// outbox, which retries failed deliveries.
+type Forwarder struct {
+	publisher Publisher
+	source    string
+	events    map[string]bool // nil publishes every event
+	tenant    func(roomID string) string
+}
+
+// NewForwarder creates a Forwarder for events produced by the node source; an empty
+// eventTypes list publishes every event. tenant returns the tenant of a room.
+func NewForwarder(publisher Publisher, source string, eventTypes []string, tenant func(roomID string) string) *Forwarder {
+	f := &Forwarder{
+		publisher: publisher,
+		source:    source,
+		tenant:    tenant,
+	}
+	if len(eventTypes) > 0 {
+		f.events = make(map[string]bool, len(eventTypes))
+		for _, eventType := range eventTypes {
+			f.events[eventType] = true
+		}
+	}
+	return f
+}
+
+// Accepts reports whether events of a type are published
+func (f *Forwarder) Accepts(eventType string) bool {
+	return f.events == nil || f.events[eventType]
+}
+
+// Encode wraps an event in a new envelope and returns its JSON body. The envelope ID is
+// assigned here, so that retried deliveries of the body can be deduplicated.
+func (f *Forwarder) Encode(event events.Event) ([]byte, error) {
+	tenantID := ""
+	if event.RoomID != "" && f.tenant != nil {
+		tenantID = f.tenant(event.RoomID)
+	}
+	return json.Marshal(NewEnvelope(event, f.source, tenantID))
+}
+
+// Deliver makes a single attempt to publish an encoded envelope
+func (f *Forwarder) Deliver(ctx context.Context, body []byte) error {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("invalid envelope: %v", err)
+	}
+	return f.publisher.Publish(ctx, envelope, body)
+}
+
+// Close flushes pending events and disconnects from the broker
+func (f *Forwarder) Close() error {
+	return f.publisher.Close()
+}
@@ -0,0 +1,56 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaBatchTimeout is how long the writer waits to fill a batch; events are published
+// one at a time, so it is kept short
+const kafkaBatchTimeout = 10 * time.Millisecond
+
+// Kafka publishes envelopes to a Kafka topic, keyed by room so that the events of one
+// room land in one partition and keep their order
+type Kafka struct {
+	writer *kafka.Writer
+}
+
+// NewKafka creates a Kafka publisher writing to topic on the given brokers
+func NewKafka(brokers []string, topic string) *Kafka {
+	return &Kafka{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			BatchTimeout:           kafkaBatchTimeout,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Publish writes an envelope to the topic
+func (k *Kafka) Publish(ctx context.Context, envelope Envelope, body []byte) error {
+	err := k.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(envelope.RoomID),
+		Value: body,
+		Time:  envelope.Time,
+		Headers: []kafka.Header{
+			{Key: EventTypeHeader, Value: []byte(envelope.Type)},
+			{Key: SchemaVersionHeader, Value: []byte(strconv.Itoa(envelope.SchemaVersion))},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write to kafka: %v", err)
+	}
+	return nil
+}
+
+// Close flushes pending messages and closes the connections
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
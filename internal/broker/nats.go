@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATS publishes envelopes to NATS subjects named after the event type under a prefix,
+// e.g. videocall.events.room.created. A JetStream stream on the prefix keeps them for
+// consumers that are offline.
+type NATS struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATS connects to the NATS server at url
+func NewNATS(url, prefix string) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("video-call-server"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %v", err)
+	}
+	return &NATS{conn: conn, prefix: prefix}, nil
+}
+
+// Publish sends an envelope to the subject of its type. The event ID is set as
+// Nats-Msg-Id, so JetStream drops duplicates of retried events.
+func (n *NATS) Publish(ctx context.Context, envelope Envelope, body []byte) error {
+	msg := nats.NewMsg(n.prefix + "." + envelope.Type)
+	msg.Data = body
+	msg.Header.Set(nats.MsgIdHdr, envelope.ID)
+	msg.Header.Set(EventTypeHeader, envelope.Type)
+	msg.Header.Set(SchemaVersionHeader, strconv.Itoa(envelope.SchemaVersion))
+
+	if err := n.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("failed to publish to nats: %v", err)
+	}
+	return nil
+}
+
+// Close flushes pending messages and disconnects
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
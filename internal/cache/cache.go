@@ -0,0 +1,72 @@
+// Package cache keeps copies of hot reads, such as room metadata, accounts and recent
+// chat history, in front of slower storage. Entries expire after a TTL and are
+// invalidated by the writer when the stored value changes.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores encoded values by key
+type Cache interface {
+	// Get returns the value stored under key and whether it was found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores a value under key until ttl passes
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the values stored under keys
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix removes every value whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Tiered is a two-level cache: reads are served from the local cache when possible and
+// otherwise from the shared one, whose values are copied to the local cache. Writes and
+// invalidations go to both.
+type Tiered struct {
+	local  Cache
+	shared Cache
+	ttl    time.Duration // how long shared values stay in the local cache
+}
+
+// NewTiered creates a Tiered cache; values read from shared are kept in local for at
+// most localTTL, which bounds how stale a value invalidated by another node can be
+func NewTiered(local, shared Cache, localTTL time.Duration) *Tiered {
+	return &Tiered{local: local, shared: shared, ttl: localTTL}
+}
+
+// Get returns the value stored under key in either level
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, found, err := t.local.Get(ctx, key); err == nil && found {
+		return value, true, nil
+	}
+
+	value, found, err := t.shared.Get(ctx, key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	t.local.Set(ctx, key, value, t.ttl)
+	return value, true, nil
+}
+
+// Set stores a value in both levels
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	localTTL := ttl
+	if localTTL > t.ttl {
+		localTTL = t.ttl
+	}
+	t.local.Set(ctx, key, value, localTTL)
+	return t.shared.Set(ctx, key, value, ttl)
+}
+
+// Delete removes the values stored under keys from both levels
+func (t *Tiered) Delete(ctx context.Context, keys ...string) error {
+	t.local.Delete(ctx, keys...)
+	return t.shared.Delete(ctx, keys...)
+}
+
+// DeletePrefix removes the values whose keys start with prefix from both levels
+func (t *Tiered) DeletePrefix(ctx context.Context, prefix string) error {
+	t.local.DeletePrefix(ctx, prefix)
+	return t.shared.DeletePrefix(ctx, prefix)
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// opTimeout bounds the connection check of NewRedis
+const opTimeout = 2 * time.Second
+
+// scanBatch is how many keys DeletePrefix asks Redis for at a time
+const scanBatch = 100
+
+// Redis is a cache shared through Redis; keys are stored under a prefix
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis connects to the Redis server at redisURL
+func NewRedis(redisURL, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %v", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %v", err)
+	}
+	return &Redis{client: client, prefix: prefix}, nil
+}
+
+// Get returns the value stored under key
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cache: %v", err)
+	}
+	return value, true, nil
+}
+
+// Set stores a value under key until ttl passes
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, r.prefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to write cache: %v", err)
+	}
+	return nil
+}
+
+// Delete removes the values stored under keys
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	if err := r.client.Del(ctx, prefixed...).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %v", err)
+	}
+	return nil
+}
+
+// DeletePrefix removes every value whose key starts with prefix
+func (r *Redis) DeletePrefix(ctx context.Context, prefix string) error {
+	iter := r.client.Scan(ctx, 0, r.prefix+prefix+"*", scanBatch).Iterator()
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == scanBatch {
+			if err := r.client.Del(ctx, batch...).Err(); err != nil {
+				return fmt.Errorf("failed to invalidate cache: %v", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cache: %v", err)
+	}
+	if len(batch) > 0 {
+		if err := r.client.Del(ctx, batch...).Err(); err != nil {
+			return fmt.Errorf("failed to invalidate cache: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
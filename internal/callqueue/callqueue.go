@@ -0,0 +1,497 @@
+package callqueue
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrQueueNotFound is returned for unknown queues
+	ErrQueueNotFound = errors.New("queue not found")
+
+	// ErrQueueExists is returned when another queue of the tenant has the name
+	ErrQueueExists = errors.New("queue already exists")
+
+	// ErrQueueEmpty is returned when no caller is waiting in a queue
+	ErrQueueEmpty = errors.New("no callers waiting")
+
+	// ErrNoAgentAvailable is returned when no agent of a queue can take a call
+	ErrNoAgentAvailable = errors.New("no agent available")
+
+	// ErrInvalidStrategy is returned for unknown routing strategies
+	ErrInvalidStrategy = errors.New("strategy must be manual, round_robin or longest_idle")
+
+	// ErrInvalidStatus is returned for statuses agents cannot set themselves
+	ErrInvalidStatus = errors.New("status must be available or offline")
+)
+
+// Routing strategies choosing the agent that takes the next call
+const (
+	// Agents pull callers themselves
+	StrategyManual = "manual"
+	// Calls go to available agents in turn, in the order they are listed
+	StrategyRoundRobin = "round_robin"
+	// Calls go to the agent available for the longest time
+	StrategyLongestIdle = "longest_idle"
+)
+
+// Agent statuses
+const (
+	// Not taking calls; agents start offline
+	StatusOffline = "offline"
+	// Ready to take a call
+	StatusAvailable = "available"
+	// In a call taken from a queue
+	StatusBusy = "busy"
+	// Finishing up after a call; available again after the wrap-up time
+	StatusWrapUp = "wrap_up"
+)
+
+// Queue is a virtual waiting line: callers wait in it until an agent takes their call
+type Queue struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Strategy  string    `json:"strategy"`
+	Agents    []string  `json:"agents"` // user IDs of the agents taking calls
+	Waiting   int       `json:"waiting"`
+	Stats     Stats     `json:"stats"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Stats sums up how a queue's callers fared since the queue was created
+type Stats struct {
+	Answered        int     `json:"answered"`
+	Abandoned       int     `json:"abandoned"` // callers who left before their call was taken
+	AbandonmentRate float64 `json:"abandonment_rate"`
+	AverageWait     float64 `json:"average_wait_seconds"` // of answered calls
+}
+
+// HasAgent reports whether a user takes the calls of the queue
+func (q Queue) HasAgent(userID string) bool {
+	for _, agent := range q.Agents {
+		if agent == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Caller is a user waiting in a queue
+type Caller struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// Agent is the status of a user taking queue calls; it applies to all of the user's queues
+type Agent struct {
+	UserID string    `json:"user_id"`
+	Status string    `json:"status"`
+	Since  time.Time `json:"since"`
+}
+
+// ValidStrategy reports whether a routing strategy is known
+func ValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyManual, StrategyRoundRobin, StrategyLongestIdle:
+		return true
+	}
+	return false
+}
+
+// queue is a Queue with its callers, first in line first
+type queue struct {
+	Queue
+	callers []Caller
+	turn    int           // index in Agents of the next agent tried by round-robin
+	waitSum time.Duration // of answered calls
+}
+
+// call is a call taken from a queue
+type call struct {
+	queueID string
+	agentID string
+}
+
+// Manager stores queues, their callers and the statuses of agents in memory
+type Manager struct {
+	queues map[string]*queue
+	agents map[string]*Agent // by user ID
+	calls  map[string]call   // by room ID
+	mu     sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		queues: make(map[string]*queue),
+		agents: make(map[string]*Agent),
+		calls:  make(map[string]call),
+	}
+}
+
+// Create adds a queue to a tenant; an empty strategy is manual
+func (m *Manager) Create(tenantID, name, strategy string, agents []string) (Queue, error) {
+	if strategy == "" {
+		strategy = StrategyManual
+	}
+	if !ValidStrategy(strategy) {
+		return Queue{}, ErrInvalidStrategy
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, q := range m.queues {
+		if q.TenantID == tenantID && strings.EqualFold(q.Name, name) {
+			return Queue{}, ErrQueueExists
+		}
+	}
+
+	q := &queue{Queue: Queue{
+		ID:        uuid.New().String(),
+		Name:      name,
+		TenantID:  tenantID,
+		Strategy:  strategy,
+		Agents:    unique(agents),
+		CreatedAt: time.Now(),
+	}}
+	m.queues[q.ID] = q
+	return q.view(), nil
+}
+
+// Get returns a queue
+func (m *Manager) Get(queueID string) (Queue, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return Queue{}, false
+	}
+	return q.view(), true
+}
+
+// List returns the queues of a tenant, by name
+func (m *Manager) List(tenantID string) []Queue {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Queue, 0, len(m.queues))
+	for _, q := range m.queues {
+		if q.TenantID == tenantID {
+			list = append(list, q.view())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Delete removes a queue, returning the callers that were still waiting
+func (m *Manager) Delete(queueID string) ([]Caller, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return nil, ErrQueueNotFound
+	}
+	delete(m.queues, queueID)
+	return q.callers, nil
+}
+
+// Join puts a caller at the end of a queue and returns its position, counting from 1.
+// A caller already waiting keeps its place.
+func (m *Manager) Join(queueID, userID, username string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return 0, ErrQueueNotFound
+	}
+	for i, caller := range q.callers {
+		if caller.UserID == userID {
+			return i + 1, nil
+		}
+	}
+	q.callers = append(q.callers, Caller{UserID: userID, Username: username, JoinedAt: time.Now()})
+	return len(q.callers), nil
+}
+
+// Leave takes a caller out of a queue before its call was taken, counting it as
+// abandoned, and reports whether it was waiting
+func (m *Manager) Leave(queueID, userID string) (Caller, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return Caller{}, false
+	}
+	for i, caller := range q.callers {
+		if caller.UserID == userID {
+			q.callers = append(q.callers[:i:i], q.callers[i+1:]...)
+			q.Stats.Abandoned++
+			return caller, true
+		}
+	}
+	return Caller{}, false
+}
+
+// Next lets an agent pull the caller first in line out of a queue; agents of the queue
+// become busy
+func (m *Manager) Next(queueID, agentID string) (Caller, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return Caller{}, ErrQueueNotFound
+	}
+	if len(q.callers) == 0 {
+		return Caller{}, ErrQueueEmpty
+	}
+	if q.HasAgent(agentID) {
+		m.setStatusLocked(agentID, StatusBusy)
+	}
+	return q.take(), nil
+}
+
+// Assign routes the caller first in line of a queue to an available agent chosen by
+// the queue's strategy, who becomes busy. Manual queues are never routed.
+func (m *Manager) Assign(queueID string) (Caller, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return Caller{}, "", ErrQueueNotFound
+	}
+	if len(q.callers) == 0 {
+		return Caller{}, "", ErrQueueEmpty
+	}
+
+	agentID := ""
+	switch q.Strategy {
+	case StrategyRoundRobin:
+		for i := range q.Agents {
+			candidate := q.Agents[(q.turn+i)%len(q.Agents)]
+			if m.statusLocked(candidate) == StatusAvailable {
+				agentID = candidate
+				q.turn = (q.turn + i + 1) % len(q.Agents)
+				break
+			}
+		}
+	case StrategyLongestIdle:
+		var idleSince time.Time
+		for _, candidate := range q.Agents {
+			agent, exists := m.agents[candidate]
+			if exists && agent.Status == StatusAvailable && (agentID == "" || agent.Since.Before(idleSince)) {
+				agentID = candidate
+				idleSince = agent.Since
+			}
+		}
+	}
+	if agentID == "" {
+		return Caller{}, "", ErrNoAgentAvailable
+	}
+
+	m.setStatusLocked(agentID, StatusBusy)
+	return q.take(), agentID, nil
+}
+
+// Requeue puts a caller taken by Next or Assign back first in line, such as when its
+// call could not be set up; the agent who took the call becomes available again
+func (m *Manager) Requeue(queueID string, caller Caller, agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if q, exists := m.queues[queueID]; exists {
+		q.callers = append([]Caller{caller}, q.callers...)
+		q.Stats.Answered--
+		q.waitSum -= time.Since(caller.JoinedAt)
+	}
+	if agent, exists := m.agents[agentID]; exists && agent.Status == StatusBusy {
+		m.setStatusLocked(agentID, StatusAvailable)
+	}
+}
+
+// StartCall records the room where an agent takes a queue call
+func (m *Manager) StartCall(roomID, queueID, agentID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls[roomID] = call{queueID: queueID, agentID: agentID}
+}
+
+// EndCall ends the queue call in a room when its agent leaves it, or with any userID
+// when it is empty; the agent moves from busy to wrap-up. It returns the agent and
+// when the wrap-up started.
+func (m *Manager) EndCall(roomID, userID string) (string, time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, exists := m.calls[roomID]
+	if !exists || (userID != "" && userID != c.agentID) {
+		return "", time.Time{}, false
+	}
+	delete(m.calls, roomID)
+
+	agent, exists := m.agents[c.agentID]
+	if !exists || agent.Status != StatusBusy {
+		return "", time.Time{}, false
+	}
+	m.setStatusLocked(c.agentID, StatusWrapUp)
+	return c.agentID, agent.Since, true
+}
+
+// FinishWrapUp makes an agent available when it is still in the wrap-up that started
+// at since, reporting whether it did
+func (m *Manager) FinishWrapUp(agentID string, since time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	agent, exists := m.agents[agentID]
+	if !exists || agent.Status != StatusWrapUp || !agent.Since.Equal(since) {
+		return false
+	}
+	m.setStatusLocked(agentID, StatusAvailable)
+	return true
+}
+
+// SetStatus makes an agent available or offline
+func (m *Manager) SetStatus(agentID, status string) (Agent, error) {
+	if status != StatusAvailable && status != StatusOffline {
+		return Agent{}, ErrInvalidStatus
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.setStatusLocked(agentID, status), nil
+}
+
+// Agent returns the status of a user taking queue calls
+func (m *Manager) Agent(agentID string) Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.agentLocked(agentID)
+}
+
+// Agents returns the statuses of the agents of a queue, in the queue's order
+func (m *Manager) Agents(queueID string) []Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return nil
+	}
+	list := make([]Agent, 0, len(q.Agents))
+	for _, agentID := range q.Agents {
+		list = append(list, m.agentLocked(agentID))
+	}
+	return list
+}
+
+// QueuesOf returns the IDs of the queues whose calls an agent takes
+func (m *Manager) QueuesOf(agentID string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []string
+	for _, q := range m.queues {
+		if q.HasAgent(agentID) {
+			ids = append(ids, q.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// agentLocked returns the status of an agent, offline when it never set one; the
+// caller holds m.mu
+func (m *Manager) agentLocked(agentID string) Agent {
+	if agent, exists := m.agents[agentID]; exists {
+		return *agent
+	}
+	return Agent{UserID: agentID, Status: StatusOffline}
+}
+
+// statusLocked returns the status of an agent; the caller holds m.mu
+func (m *Manager) statusLocked(agentID string) string {
+	return m.agentLocked(agentID).Status
+}
+
+// setStatusLocked changes the status of an agent; the caller holds m.mu
+func (m *Manager) setStatusLocked(agentID, status string) Agent {
+	agent, exists := m.agents[agentID]
+	if !exists {
+		agent = &Agent{UserID: agentID}
+		m.agents[agentID] = agent
+	}
+	if agent.Status != status {
+		agent.Status = status
+		agent.Since = time.Now()
+	}
+	return *agent
+}
+
+// take removes the caller first in line, counting its call as answered; the caller
+// holds m.mu
+func (q *queue) take() Caller {
+	caller := q.callers[0]
+	q.callers = q.callers[1:]
+	q.Stats.Answered++
+	q.waitSum += time.Since(caller.JoinedAt)
+	return caller
+}
+
+// Callers returns the callers waiting in a queue, first in line first
+func (m *Manager) Callers(queueID string) []Caller {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q, exists := m.queues[queueID]
+	if !exists {
+		return nil
+	}
+	return append([]Caller{}, q.callers...)
+}
+
+// view returns a copy of a queue that does not share its agent list; the caller holds m.mu
+func (q *queue) view() Queue {
+	v := q.Queue
+	v.Agents = append([]string{}, q.Agents...)
+	v.Waiting = len(q.callers)
+	if total := v.Stats.Answered + v.Stats.Abandoned; total > 0 {
+		v.Stats.AbandonmentRate = float64(v.Stats.Abandoned) / float64(total)
+	}
+	if v.Stats.Answered > 0 {
+		v.Stats.AverageWait = (q.waitSum / time.Duration(v.Stats.Answered)).Seconds()
+	}
+	return v
+}
+
+// unique returns IDs without duplicates, in their first order
+func unique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			list = append(list, id)
+		}
+	}
+	return list
+}
@@ -0,0 +1,65 @@
+package chat
+
+import "sort"
+
+// Export returns copies of the messages of every room, deleted ones included, by room ID
+func (cm *ChatManager) Export() map[string][]Message {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	rooms := make(map[string][]Message, len(cm.rooms))
+	for roomID, messages := range cm.rooms {
+		list := make([]Message, 0, len(messages))
+		for _, message := range messages {
+			list = append(list, *message)
+		}
+		rooms[roomID] = list
+	}
+	return rooms
+}
+
+// Snapshot returns a copy of a message of a room, deleted or not
+func (cm *ChatManager) Snapshot(roomID, messageID string) (Message, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for _, message := range cm.rooms[roomID] {
+		if message.ID == messageID {
+			return *message, true
+		}
+	}
+	return Message{}, false
+}
+
+// Import adds messages from a backup to a room's history, skipping those it already
+// has, and returns how many were added. The history keeps its 100 most recent messages.
+func (cm *ChatManager) Import(roomID string, messages []Message) int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	known := make(map[string]bool, len(cm.rooms[roomID]))
+	for _, message := range cm.rooms[roomID] {
+		known[message.ID] = true
+	}
+
+	added := 0
+	for _, message := range messages {
+		if known[message.ID] {
+			continue
+		}
+		message := message
+		message.RoomID = roomID
+		cm.rooms[roomID] = append(cm.rooms[roomID], &message)
+		known[message.ID] = true
+		added++
+	}
+
+	history := cm.rooms[roomID]
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	if len(history) > 100 {
+		cm.rooms[roomID] = history[len(history)-100:]
+	}
+	return added
+}
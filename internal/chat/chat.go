@@ -1,20 +1,62 @@
 package chat
 
 import (
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Message represents a chat message
+// Message types
+const (
+	TypeUser   = "user"
+	TypeSystem = "system"
+	TypeBot    = "bot"
+)
+
+// Message represents a chat message. System messages are generated by the server;
+// their user fields identify the participant the message is about. Bot messages are
+// posted by room bots; their user fields identify the bot's owner, their username the bot.
 type Message struct {
-	ID        string    `json:"id"`
-	RoomID    string    `json:"room_id"`
-	UserID    string    `json:"user_id"`
-	Username  string    `json:"username"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Event       string     `json:"event,omitempty"`
+	RoomID      string     `json:"room_id"`
+	UserID      string     `json:"user_id"`
+	Username    string     `json:"username"`
+	DisplayName string     `json:"display_name,omitempty"`
+	AvatarURL   string     `json:"avatar_url,omitempty"`
+	Content     string     `json:"content"`
+	Timestamp   time.Time  `json:"timestamp"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy   string     `json:"deleted_by,omitempty"`
+}
+
+// Errors returned when deleting and restoring messages
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrAlreadyDeleted  = errors.New("message is already deleted")
+	ErrNotDeleted      = errors.New("message is not deleted")
+)
+
+// Sender identifies the author of a message
+type Sender struct {
+	UserID      string
+	Username    string
+	DisplayName string
+	AvatarURL   string
+}
+
+// Held selects the messages under legal hold: those of the rooms and by the users in it
+type Held struct {
+	Rooms map[string]bool
+	Users map[string]bool
+}
+
+// Covers reports whether a message is under legal hold
+func (h Held) Covers(message *Message) bool {
+	return h.Rooms[message.RoomID] || h.Users[message.UserID]
 }
 
 // ChatManager manages chat messages for rooms
@@ -31,18 +73,37 @@ func NewChatManager() *ChatManager {
 }
 
 // AddMessage adds a new message to a room
-func (cm *ChatManager) AddMessage(roomID, userID, username, content string) *Message {
+func (cm *ChatManager) AddMessage(roomID string, sender Sender, content string) *Message {
+	return cm.add(TypeUser, "", roomID, sender, content)
+}
+
+// AddSystemMessage adds a server-generated message about a participant to a room
+func (cm *ChatManager) AddSystemMessage(roomID, event string, subject Sender, content string) *Message {
+	return cm.add(TypeSystem, event, roomID, subject, content)
+}
+
+// AddBotMessage adds a message posted by a room bot to a room
+func (cm *ChatManager) AddBotMessage(roomID string, owner Sender, content string) *Message {
+	return cm.add(TypeBot, "", roomID, owner, content)
+}
+
+// add stores a message in a room's history
+func (cm *ChatManager) add(messageType, event, roomID string, sender Sender, content string) *Message {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	
 	// Create message
 	message := &Message{
-		ID:        uuid.New().String(),
-		RoomID:    roomID,
-		UserID:    userID,
-		Username:  username,
-		Content:   content,
-		Timestamp: time.Now(),
+		ID:          uuid.New().String(),
+		Type:        messageType,
+		Event:       event,
+		RoomID:      roomID,
+		UserID:      sender.UserID,
+		Username:    sender.Username,
+		DisplayName: sender.DisplayName,
+		AvatarURL:   sender.AvatarURL,
+		Content:     content,
+		Timestamp:   time.Now(),
 	}
 	
 	// Add to room
@@ -56,41 +117,166 @@ func (cm *ChatManager) AddMessage(roomID, userID, username, content string) *Mes
 	return message
 }
 
-// GetMessages returns messages for a room
+// GetMessages returns the messages of a room that are not deleted
 func (cm *ChatManager) GetMessages(roomID string) []*Message {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 	
 	// Return a copy of messages to prevent external modification
-	messages := make([]*Message, len(cm.rooms[roomID]))
-	copy(messages, cm.rooms[roomID])
-	
-	return messages
+	return visible(cm.rooms[roomID])
 }
 
-// GetRecentMessages returns the most recent messages for a room
+// GetRecentMessages returns the most recent messages for a room that are not deleted
 func (cm *ChatManager) GetRecentMessages(roomID string, count int) []*Message {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 	
-	roomMessages := cm.rooms[roomID]
+	return recent(cm.rooms[roomID], count)
+}
+
+// GetRecentMessagesForRooms returns the most recent messages of several rooms at once,
+// by room ID
+func (cm *ChatManager) GetRecentMessagesForRooms(roomIDs []string, count int) map[string][]*Message {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	
+	result := make(map[string][]*Message, len(roomIDs))
+	for _, roomID := range roomIDs {
+		result[roomID] = recent(cm.rooms[roomID], count)
+	}
+	return result
+}
+
+// recent returns the last count messages that are not deleted
+func recent(messages []*Message, count int) []*Message {
+	roomMessages := visible(messages)
 	
 	// If count is greater than or equal to message count, return all messages
 	if count >= len(roomMessages) {
-		// Return a copy of messages to prevent external modification
-		messages := make([]*Message, len(roomMessages))
-		copy(messages, roomMessages)
-		return messages
+		return roomMessages
 	}
 	
 	// Return the most recent messages
-	startIndex := len(roomMessages) - count
-	messages := make([]*Message, count)
-	copy(messages, roomMessages[startIndex:])
+	return roomMessages[len(roomMessages)-count:]
+}
+
+// GetDeletedMessages returns the deleted messages of a room that have not been purged yet
+func (cm *ChatManager) GetDeletedMessages(roomID string) []*Message {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
 	
+	messages := []*Message{}
+	for _, message := range cm.rooms[roomID] {
+		if message.DeletedAt != nil {
+			messages = append(messages, message)
+		}
+	}
 	return messages
 }
 
+// GetMessage returns a message of a room, deleted or not
+func (cm *ChatManager) GetMessage(roomID, messageID string) (*Message, bool) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	
+	for _, message := range cm.rooms[roomID] {
+		if message.ID == messageID {
+			return message, true
+		}
+	}
+	return nil, false
+}
+
+// DeleteMessage hides a message from history until it is restored or purged
+func (cm *ChatManager) DeleteMessage(roomID, messageID, deletedBy string) (*Message, error) {
+	return cm.update(roomID, messageID, func(message *Message) error {
+		if message.DeletedAt != nil {
+			return ErrAlreadyDeleted
+		}
+		now := time.Now()
+		message.DeletedAt = &now
+		message.DeletedBy = deletedBy
+		return nil
+	})
+}
+
+// RestoreMessage puts a deleted message back into history
+func (cm *ChatManager) RestoreMessage(roomID, messageID string) (*Message, error) {
+	return cm.update(roomID, messageID, func(message *Message) error {
+		if message.DeletedAt == nil {
+			return ErrNotDeleted
+		}
+		message.DeletedAt = nil
+		message.DeletedBy = ""
+		return nil
+	})
+}
+
+// update changes a copy of a message and swaps it in, so that messages already
+// handed out are never modified
+func (cm *ChatManager) update(roomID, messageID string, change func(message *Message) error) (*Message, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	
+	for i, message := range cm.rooms[roomID] {
+		if message.ID != messageID {
+			continue
+		}
+		updated := *message
+		if err := change(&updated); err != nil {
+			return nil, err
+		}
+		cm.rooms[roomID][i] = &updated
+		return &updated, nil
+	}
+	return nil, ErrMessageNotFound
+}
+
+// PurgeDeleted permanently removes messages deleted before a time, except those under
+// legal hold, and returns how many were removed
+func (cm *ChatManager) PurgeDeleted(before time.Time, held Held) int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	
+	purged := 0
+	for roomID, messages := range cm.rooms {
+		kept := messages[:0]
+		for _, message := range messages {
+			if message.DeletedAt != nil && message.DeletedAt.Before(before) && !held.Covers(message) {
+				purged++
+				continue
+			}
+			kept = append(kept, message)
+		}
+		cm.rooms[roomID] = kept
+	}
+	return purged
+}
+
+// visible returns a copy of a message list without deleted messages
+func visible(messages []*Message) []*Message {
+	result := make([]*Message, 0, len(messages))
+	for _, message := range messages {
+		if message.DeletedAt == nil {
+			result = append(result, message)
+		}
+	}
+	return result
+}
+
+// HasHeld reports whether any message of a room, deleted ones included, is under legal hold
+func (cm *ChatManager) HasHeld(roomID string, held Held) bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for _, message := range cm.rooms[roomID] {
+		if held.Covers(message) {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteMessagesForRoom deletes all messages for a room
 func (cm *ChatManager) DeleteMessagesForRoom(roomID string) {
 	cm.mu.Lock()
@@ -0,0 +1,374 @@
+// Package cli implements the server's command-line subcommands.
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/loadtest"
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/server"
+	"github.com/zubans/video-call-server/internal/storage"
+)
+
+// command is a CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in help order
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "Run the video call server (default)", serve},
+		{"migrate", "Apply, undo or list database migrations", migrate},
+		{"create-admin", "Register an admin user on a running server", createAdmin},
+		{"prune-recordings", "Delete recordings older than a retention period", pruneRecordings},
+		{"backup", "Save users, rooms, chat and recording metadata of a running server", backup},
+		{"restore", "Load a backup into a running server", restore},
+		{"loadtest", "Simulate clients against a running server", loadtest.Run},
+		{"help", "Show this help", func([]string) error { usage(os.Stdout); return nil }},
+	}
+}
+
+// Run dispatches to a subcommand; without one the server is started
+func Run(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return serve(args)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// usage prints the list of subcommands
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for command flags.\n", os.Args[0])
+}
+
+// serve runs the server until it is shut down
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := fs.String("port", "", "port to listen on (overrides PORT)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *port != "" {
+		os.Setenv("PORT", *port)
+	}
+
+	// Create and run server
+	s := server.NewServer()
+	s.Run()
+	return nil
+}
+
+// migrate applies, undoes or lists database migrations
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	path := fs.String("db", os.Getenv("SQLITE_PATH"), "SQLite database file (default $SQLITE_PATH)")
+	steps := fs.Int("steps", 1, "number of migrations to undo with down")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s migrate [flags] [up|down|status]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	action := "up"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+	if action != "up" && action != "down" && action != "status" {
+		fs.Usage()
+		return fmt.Errorf("unknown migrate action %q", action)
+	}
+
+	if *path == "" {
+		// All state is kept in memory; there is no schema to migrate
+		fmt.Println("No persistent storage is configured: nothing to migrate")
+		return nil
+	}
+
+	db, err := storage.OpenSQLite(*path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		applied, err := db.MigrateUp(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+
+	case "down":
+		if *steps < 1 {
+			return errors.New("steps must be at least 1")
+		}
+		undone, err := db.MigrateDown(ctx, *steps)
+		for _, migration := range undone {
+			fmt.Printf("Undid %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(undone) == 0 {
+			fmt.Println("No migrations are applied")
+		}
+
+	case "status":
+		list, err := db.Migrations(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range list {
+			state := "pending"
+			if migration.Applied {
+				state = "applied " + migration.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%5d  %-30s %s\n", migration.Version, migration.Name, state)
+		}
+	}
+	return nil
+}
+
+// createAdmin registers an admin through a running server using ADMIN_BOOTSTRAP_TOKEN
+func createAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8181", "base URL of the running server")
+	username := fs.String("username", "", "admin username")
+	email := fs.String("email", "", "admin email")
+	password := fs.String("password", os.Getenv("ADMIN_PASSWORD"), "admin password (default $ADMIN_PASSWORD)")
+	token := fs.String("token", os.Getenv("ADMIN_BOOTSTRAP_TOKEN"), "bootstrap token configured on the server (default $ADMIN_BOOTSTRAP_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" || *email == "" || *password == "" {
+		return errors.New("username, email and password are required")
+	}
+	if *token == "" {
+		return errors.New("bootstrap token is required: set ADMIN_BOOTSTRAP_TOKEN on the server and pass it here")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"username": *username,
+		"email":    *email,
+		"password": *password,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(*target, "/")+"/register", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Admin-Bootstrap-Token", *token)
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UserID string `json:"user_id"`
+		Error  string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create admin: %s %s", resp.Status, result.Error)
+	}
+
+	fmt.Printf("Created admin %s (%s)\n", *username, result.UserID)
+	return nil
+}
+
+// backup downloads a backup archive from a running server using an admin's token
+func backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8181", "base URL of the running server")
+	token := fs.String("token", os.Getenv("ADMIN_TOKEN"), "access token of an admin (default $ADMIN_TOKEN)")
+	out := fs.String("out", "backup-"+time.Now().Format("20060102-150405")+".tar.gz", "file to write the archive to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *token == "" {
+		return errors.New("admin token is required: log in as an admin and pass the access token")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(*target, "/")+"/admin/backup", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", *token)
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to create backup: %s %s", resp.Status, errorMessage(resp.Body))
+	}
+
+	file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*out)
+		return fmt.Errorf("failed to save backup: %v", err)
+	}
+
+	fmt.Printf("Saved backup to %s (%d bytes)\n", *out, written)
+	return nil
+}
+
+// restore uploads a backup archive to a running server using an admin's token
+func restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8181", "base URL of the running server")
+	token := fs.String("token", os.Getenv("ADMIN_TOKEN"), "access token of an admin (default $ADMIN_TOKEN)")
+	in := fs.String("in", "", "backup archive to load")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *in == "" {
+		return errors.New("backup archive is required")
+	}
+	if *token == "" {
+		return errors.New("admin token is required: log in as an admin and pass the access token")
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(*target, "/")+"/admin/restore", file)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set("Authorization", *token)
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to restore backup: %s %s", resp.Status, errorMessage(resp.Body))
+	}
+
+	type counts struct {
+		Users      int `json:"users"`
+		Rooms      int `json:"rooms"`
+		Messages   int `json:"messages"`
+		Recordings int `json:"recordings"`
+	}
+	var result struct {
+		Restored counts `json:"restored"`
+		Skipped  counts `json:"skipped"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to read server response: %v", err)
+	}
+
+	fmt.Printf("Restored %d users, %d rooms, %d messages and %d recordings\n",
+		result.Restored.Users, result.Restored.Rooms, result.Restored.Messages, result.Restored.Recordings)
+	fmt.Printf("Skipped %d users, %d rooms, %d messages and %d recordings already present\n",
+		result.Skipped.Users, result.Skipped.Rooms, result.Skipped.Messages, result.Skipped.Recordings)
+	return nil
+}
+
+// errorMessage reads the error of a failed API response
+func errorMessage(body io.Reader) string {
+	var result struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(body).Decode(&result)
+	return result.Error
+}
+
+// pruneRecordings deletes recording files older than the retention period
+func pruneRecordings(args []string) error {
+	fs := flag.NewFlagSet("prune-recordings", flag.ContinueOnError)
+	dir := fs.String("dir", envString("RECORDINGS_DIR", recording.DefaultDir), "recordings directory (default $RECORDINGS_DIR)")
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "delete recordings last modified longer ago than this")
+	dryRun := fs.Bool("dry-run", false, "only list recordings that would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	pruned, err := recording.PruneRecordings(*dir, time.Now().Add(-*olderThan), *dryRun)
+	for _, path := range pruned {
+		fmt.Println(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	verb := "Deleted"
+	if *dryRun {
+		verb = "Would delete"
+	}
+	fmt.Printf("%s %d recordings older than %s\n", verb, len(pruned), *olderThan)
+	return nil
+}
+
+// envString returns an environment variable or a default value
+func envString(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,230 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/logging"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+const (
+	// nodeTTL is how long a node stays registered without a heartbeat
+	nodeTTL = 30 * time.Second
+
+	// heartbeatInterval is how often a node refreshes its registration
+	heartbeatInterval = 10 * time.Second
+
+	// opTimeout bounds every registry round trip
+	opTimeout = 2 * time.Second
+)
+
+// ErrNodeUnavailable is returned when a room's owner node is no longer registered
+var ErrNodeUnavailable = errors.New("node hosting the room is unavailable")
+
+// Node describes a server instance
+type Node struct {
+	ID  string `json:"id"`
+	URL string `json:"url"` // base URL other nodes and clients use to reach it
+}
+
+// Registry records which node hosts each room so a room's media stays on one box. Its
+// records are kept in Redis or in a NATS key-value bucket.
+type Registry struct {
+	store  store
+	prefix string
+	node   Node
+
+	// Rooms claimed by this node, re-asserted on every heartbeat
+	rooms map[string]bool
+	mu    sync.Mutex
+
+	stop chan struct{}
+}
+
+// NewRegistry connects to Redis and registers the local node
+func NewRegistry(redisURL, prefix string, node Node) (*Registry, error) {
+	s, err := newRedisStore(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	return newRegistry(s, prefix, node)
+}
+
+// NewNATSRegistry keeps the registry in the NATS JetStream key-value bucket at natsURL
+// and registers the local node. Records not rewritten within retention are dropped, so
+// it must exceed the longest token lifetime.
+func NewNATSRegistry(natsURL, bucket string, retention time.Duration, node Node) (*Registry, error) {
+	s, err := newNATSStore(natsURL, bucket, retention)
+	if err != nil {
+		return nil, err
+	}
+	return newRegistry(s, "", node)
+}
+
+// newRegistry registers the local node in a store
+func newRegistry(s store, prefix string, node Node) (*Registry, error) {
+	r := &Registry{
+		store:  s,
+		prefix: prefix,
+		node:   node,
+		rooms:  make(map[string]bool),
+		stop:   make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := r.heartbeat(ctx); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	go r.run()
+
+	return r, nil
+}
+
+// LocalNode returns the node this registry was created for
+func (r *Registry) LocalNode() Node {
+	return r.node
+}
+
+func (r *Registry) nodeKey(nodeID string) string {
+	return r.prefix + "node:" + nodeID
+}
+
+func (r *Registry) roomKey(roomID string) string {
+	return r.prefix + "room:" + roomID
+}
+
+// run refreshes the node registration until Close
+func (r *Registry) run() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+			if err := r.heartbeat(ctx); err != nil {
+				logger.Errorf("Cluster heartbeat failed: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// heartbeat refreshes the node registration and the ownership of its rooms
+func (r *Registry) heartbeat(ctx context.Context) error {
+	r.mu.Lock()
+	rooms := make([]string, 0, len(r.rooms))
+	for roomID := range r.rooms {
+		rooms = append(rooms, roomID)
+	}
+	r.mu.Unlock()
+
+	values := map[string]string{r.nodeKey(r.node.ID): r.node.URL}
+	for _, roomID := range rooms {
+		values[r.roomKey(roomID)] = r.node.ID
+	}
+	if err := r.store.set(ctx, values, nodeTTL); err != nil {
+		return fmt.Errorf("failed to register node: %v", err)
+	}
+	return nil
+}
+
+// ClaimRoom records the local node as the host of a room
+func (r *Registry) ClaimRoom(ctx context.Context, roomID string) error {
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	ok, err := r.store.setNX(ctx, r.roomKey(roomID), r.node.ID, nodeTTL)
+	if err != nil {
+		return fmt.Errorf("failed to claim room: %v", err)
+	}
+	if !ok {
+		return fmt.Errorf("room %s is already hosted by another node", roomID)
+	}
+
+	r.mu.Lock()
+	r.rooms[roomID] = true
+	r.mu.Unlock()
+
+	return nil
+}
+
+// ReleaseRoom removes the local node's ownership of a room
+func (r *Registry) ReleaseRoom(ctx context.Context, roomID string) error {
+	r.mu.Lock()
+	delete(r.rooms, roomID)
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	// Only delete the key if this node still owns it
+	owner, found, err := r.store.get(ctx, r.roomKey(roomID))
+	if err != nil {
+		return fmt.Errorf("failed to release room: %v", err)
+	}
+	if !found || owner != r.node.ID {
+		return nil
+	}
+	return r.store.del(ctx, r.roomKey(roomID))
+}
+
+// RoomOwner returns the node hosting a room. ok is false if the room is not hosted anywhere.
+func (r *Registry) RoomOwner(ctx context.Context, roomID string) (node Node, ok bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+
+	nodeID, found, err := r.store.get(ctx, r.roomKey(roomID))
+	if err != nil {
+		return Node{}, false, fmt.Errorf("failed to look up room owner: %v", err)
+	}
+	if !found {
+		return Node{}, false, nil
+	}
+
+	url, found, err := r.store.get(ctx, r.nodeKey(nodeID))
+	if err != nil {
+		return Node{}, false, fmt.Errorf("failed to look up node: %v", err)
+	}
+	if !found {
+		return Node{ID: nodeID}, true, ErrNodeUnavailable
+	}
+
+	return Node{ID: nodeID, URL: url}, true, nil
+}
+
+// Close stops heartbeats, releases the local node's rooms and disconnects from the store
+func (r *Registry) Close() error {
+	close(r.stop)
+
+	r.mu.Lock()
+	rooms := make([]string, 0, len(r.rooms))
+	for roomID := range r.rooms {
+		rooms = append(rooms, roomID)
+	}
+	r.mu.Unlock()
+
+	for _, roomID := range rooms {
+		if err := r.ReleaseRoom(context.Background(), roomID); err != nil {
+			logger.Errorf("Failed to release room %s: %v", roomID, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+	r.store.del(ctx, r.nodeKey(r.node.ID))
+
+	return r.store.close()
+}
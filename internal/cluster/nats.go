@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsRecord is a value kept in the NATS key-value bucket. Buckets expire entries only
+// after a bucket-wide age, so each value carries its own expiry.
+type natsRecord struct {
+	Value     string `json:"value"`
+	ExpiresAt int64  `json:"expires_at"` // Unix milliseconds
+}
+
+// natsStore keeps records in a NATS JetStream key-value bucket
+type natsStore struct {
+	conn *nats.Conn
+	kv   jetstream.KeyValue
+}
+
+// newNATSStore connects to the NATS server at natsURL and opens the bucket, creating it
+// if needed. Entries are dropped retention after their last write, which must exceed
+// the longest TTL used.
+func newNATSStore(natsURL, bucket string, retention time.Duration) (*natsStore, error) {
+	conn, err := nats.Connect(natsURL, nats.Name("video-call-server"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %v", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open jetstream: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      bucket,
+		Description: "video call server cluster registry",
+		History:     1,
+		TTL:         retention,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open key-value bucket %s: %v", bucket, err)
+	}
+	return &natsStore{conn: conn, kv: kv}, nil
+}
+
+// natsKey maps a registry key to a bucket key, which may only contain letters, digits
+// and -/_=. — ':' separators become '.' and other characters are escaped as =XX
+func natsKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == ':':
+			b.WriteByte('.')
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "=%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// encode returns the stored form of a value expiring after ttl
+func (s *natsStore) encode(value string, ttl time.Duration) ([]byte, error) {
+	return json.Marshal(natsRecord{Value: value, ExpiresAt: time.Now().Add(ttl).UnixMilli()})
+}
+
+// decode returns the value of an entry and whether it has not expired
+func (s *natsStore) decode(entry jetstream.KeyValueEntry) (string, bool) {
+	var record natsRecord
+	if err := json.Unmarshal(entry.Value(), &record); err != nil {
+		return "", false
+	}
+	return record.Value, time.Now().Before(time.UnixMilli(record.ExpiresAt))
+}
+
+func (s *natsStore) set(ctx context.Context, values map[string]string, ttl time.Duration) error {
+	for key, value := range values {
+		data, err := s.encode(value, ttl)
+		if err != nil {
+			return err
+		}
+		if _, err := s.kv.Put(ctx, natsKey(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *natsStore) setNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	data, err := s.encode(value, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	key = natsKey(key)
+	_, err = s.kv.Create(ctx, key, data)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, jetstream.ErrKeyExists) {
+		return false, err
+	}
+
+	// An expired value may be replaced, unless another node replaced it first
+	entry, err := s.kv.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if _, live := s.decode(entry); live {
+		return false, nil
+	}
+	_, err = s.kv.Update(ctx, key, data, entry.Revision())
+	var apiErr *jetstream.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode == jetstream.JSErrCodeStreamWrongLastSequence {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *natsStore) get(ctx context.Context, key string) (string, bool, error) {
+	entry, err := s.kv.Get(ctx, natsKey(key))
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	value, live := s.decode(entry)
+	return value, live, nil
+}
+
+func (s *natsStore) del(ctx context.Context, key string) error {
+	err := s.kv.Delete(ctx, natsKey(key))
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *natsStore) close() error {
+	return s.conn.Drain()
+}
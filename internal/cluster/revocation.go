@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (r *Registry) revokedKey(tokenID string) string {
+	return r.prefix + "revoked:" + tokenID
+}
+
+// Revoke records a revoked token ID for all nodes until the token expires
+func (r *Registry) Revoke(tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := r.store.set(ctx, map[string]string{r.revokedKey(tokenID): "1"}, ttl); err != nil {
+		return fmt.Errorf("failed to revoke token: %v", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether any node has revoked a token ID
+func (r *Registry) IsRevoked(tokenID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	_, found, err := r.store.get(ctx, r.revokedKey(tokenID))
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %v", err)
+	}
+	return found, nil
+}
+
+// TryRevoke records a revoked token ID for all nodes unless a node already has, with
+// a single SETNX, and reports whether this call revoked it
+func (r *Registry) TryRevoke(tokenID string, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	revoked, err := r.store.setNX(ctx, r.revokedKey(tokenID), "1", ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke token: %v", err)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+func (r *Registry) sessionKey(tokenID string) string {
+	return r.prefix + "session:" + tokenID
+}
+
+// Touch records a use of a token for all nodes and reports whether it was used
+// within idle before; the record lives until the token expires
+func (r *Registry) Touch(tokenID string, idle time.Duration, expiresAt time.Time) (bool, error) {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	now := time.Now()
+	value, found, err := r.store.get(ctx, r.sessionKey(tokenID))
+	if err != nil {
+		return false, fmt.Errorf("failed to read session: %v", err)
+	}
+	if found {
+		lastUsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, fmt.Errorf("failed to read session: %v", err)
+		}
+		if now.Sub(time.UnixMilli(lastUsed)) > idle {
+			return false, nil
+		}
+	}
+
+	values := map[string]string{r.sessionKey(tokenID): strconv.FormatInt(now.UnixMilli(), 10)}
+	if err := r.store.set(ctx, values, ttl); err != nil {
+		return false, fmt.Errorf("failed to record session: %v", err)
+	}
+	return true, nil
+}
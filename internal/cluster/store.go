@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// store keeps the registry's records, each of which expires after a TTL
+type store interface {
+	// set writes values by key, all expiring after ttl
+	set(ctx context.Context, values map[string]string, ttl time.Duration) error
+	// setNX writes a value unless the key holds one that has not expired, and reports
+	// whether it was written
+	setNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// get returns the value of a key; found is false if it is missing or expired
+	get(ctx context.Context, key string) (value string, found bool, err error)
+	del(ctx context.Context, key string) error
+	close() error
+}
+
+// redisStore keeps records in Redis, which expires them itself
+type redisStore struct {
+	client *redis.Client
+}
+
+// newRedisStore connects to the Redis server at redisURL
+func newRedisStore(redisURL string) (*redisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %v", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %v", err)
+	}
+	return &redisStore{client: client}, nil
+}
+
+func (s *redisStore) set(ctx context.Context, values map[string]string, ttl time.Duration) error {
+	pipe := s.client.Pipeline()
+	for key, value := range values {
+		pipe.Set(ctx, key, value, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisStore) setNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (s *redisStore) get(ctx context.Context, key string) (string, bool, error) {
+	value, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (s *redisStore) del(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
+
+func (s *redisStore) close() error {
+	return s.client.Close()
+}
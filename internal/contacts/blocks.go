@@ -0,0 +1,80 @@
+package contacts
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNotBlocked is returned when unblocking a user that is not blocked
+var ErrNotBlocked = errors.New("user is not blocked")
+
+// Block is a user blocked by someone
+type Block struct {
+	UserID    string    `json:"user_id"`
+	FromRooms bool      `json:"block_from_rooms"` // also keep them out of rooms the blocker hosts
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// Block blocks a user and removes them from the owner's contacts
+func (m *Manager) Block(ownerID, userID string, fromRooms bool) Block {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, exists := m.blocks[ownerID]
+	if !exists {
+		list = make(map[string]*Block)
+		m.blocks[ownerID] = list
+	}
+
+	block, exists := list[userID]
+	if !exists {
+		block = &Block{UserID: userID, BlockedAt: time.Now()}
+		list[userID] = block
+	}
+	block.FromRooms = fromRooms
+
+	delete(m.lists[ownerID], userID)
+
+	return *block
+}
+
+// Unblock removes a block
+func (m *Manager) Unblock(ownerID, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.blocks[ownerID][userID]; !exists {
+		return ErrNotBlocked
+	}
+	delete(m.blocks[ownerID], userID)
+
+	return nil
+}
+
+// Blocked returns the users an owner has blocked, most recent first
+func (m *Manager) Blocked(ownerID string) []Block {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blocks := make([]Block, 0, len(m.blocks[ownerID]))
+	for _, block := range m.blocks[ownerID] {
+		blocks = append(blocks, *block)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].BlockedAt.After(blocks[j].BlockedAt)
+	})
+	return blocks
+}
+
+// IsBlocked reports whether an owner blocked a user, and whether the block covers their rooms
+func (m *Manager) IsBlocked(ownerID, userID string) (blocked, fromRooms bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	block, exists := m.blocks[ownerID][userID]
+	if !exists {
+		return false, false
+	}
+	return true, block.FromRooms
+}
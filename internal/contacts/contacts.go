@@ -0,0 +1,99 @@
+package contacts
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrContactNotFound is returned when a user is not in the contact list
+var ErrContactNotFound = errors.New("contact not found")
+
+// Contact is a user saved to someone's contact list
+type Contact struct {
+	UserID   string    `json:"user_id"`
+	Favorite bool      `json:"favorite"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Manager stores per-user contact and block lists in memory
+type Manager struct {
+	lists  map[string]map[string]*Contact
+	blocks map[string]map[string]*Block
+	mu     sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		lists:  make(map[string]map[string]*Contact),
+		blocks: make(map[string]map[string]*Block),
+	}
+}
+
+// Add saves a user to an owner's contacts, updating the favorite flag if already present
+func (m *Manager) Add(ownerID, userID string, favorite bool) Contact {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list, exists := m.lists[ownerID]
+	if !exists {
+		list = make(map[string]*Contact)
+		m.lists[ownerID] = list
+	}
+
+	contact, exists := list[userID]
+	if !exists {
+		contact = &Contact{UserID: userID, AddedAt: time.Now()}
+		list[userID] = contact
+	}
+	contact.Favorite = favorite
+
+	return *contact
+}
+
+// SetFavorite marks or unmarks a contact as a favorite
+func (m *Manager) SetFavorite(ownerID, userID string, favorite bool) (Contact, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	contact, exists := m.lists[ownerID][userID]
+	if !exists {
+		return Contact{}, ErrContactNotFound
+	}
+	contact.Favorite = favorite
+
+	return *contact, nil
+}
+
+// Remove deletes a user from an owner's contacts
+func (m *Manager) Remove(ownerID, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.lists[ownerID][userID]; !exists {
+		return ErrContactNotFound
+	}
+	delete(m.lists[ownerID], userID)
+
+	return nil
+}
+
+// List returns an owner's contacts, favorites first, then by when they were added
+func (m *Manager) List(ownerID string) []Contact {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	contacts := make([]Contact, 0, len(m.lists[ownerID]))
+	for _, contact := range m.lists[ownerID] {
+		contacts = append(contacts, *contact)
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		if contacts[i].Favorite != contacts[j].Favorite {
+			return contacts[i].Favorite
+		}
+		return contacts[i].AddedAt.Before(contacts[j].AddedAt)
+	})
+	return contacts
+}
@@ -0,0 +1,57 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// awsEncryptionContext is bound to every wrapped data key, so that KMS ciphertexts made
+// for other purposes cannot be passed off as data keys
+var awsEncryptionContext = map[string]string{"purpose": "video-call-server/recording"}
+
+// AWSKMS wraps data keys with a symmetric AWS KMS key. Credentials and the region come
+// from the usual AWS configuration: environment, shared files or the instance role.
+type AWSKMS struct {
+	client *kms.Client
+	keyID  string
+}
+
+// NewAWSKMS uses the KMS key keyID, an ID, ARN or alias
+func NewAWSKMS(ctx context.Context, keyID string) (*AWSKMS, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws configuration: %v", err)
+	}
+	return &AWSKMS{client: kms.NewFromConfig(cfg), keyID: keyID}, nil
+}
+
+// Wrap encrypts a data key with the KMS key and returns the key's ARN as its ID
+func (a *AWSKMS) Wrap(ctx context.Context, plaintext []byte) (string, []byte, error) {
+	out, err := a.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             aws.String(a.keyID),
+		Plaintext:         plaintext,
+		EncryptionContext: awsEncryptionContext,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return aws.ToString(out.KeyId), out.CiphertextBlob, nil
+}
+
+// Unwrap decrypts a data key with the KMS key that wrapped it. KMS keeps the older
+// material of rotated keys, so data keys wrapped before a rotation still unwrap.
+func (a *AWSKMS) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	out, err := a.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(keyID),
+		CiphertextBlob:    wrapped,
+		EncryptionContext: awsEncryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
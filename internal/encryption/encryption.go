@@ -0,0 +1,113 @@
+// Package encryption encrypts recording files at rest with envelope encryption: each
+// recording gets a random data key, which is stored in the header of its files wrapped
+// by a master key kept in a local key file or in AWS KMS. A copy of the files, such as
+// a leaked archive bucket, is unreadable without the master key.
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// dataKeySize is the size of data keys: AES-256
+const dataKeySize = 32
+
+// maxCachedKeys bounds the unwrapped data keys kept in memory
+const maxCachedKeys = 1024
+
+var (
+	// ErrNoKeyring is returned when reading an encrypted file without a master key
+	// configured
+	ErrNoKeyring = errors.New("file is encrypted and no master key is configured")
+
+	// ErrUnknownKey is returned for data keys wrapped by a master key that is not
+	// configured
+	ErrUnknownKey = errors.New("data key is wrapped by an unknown master key")
+)
+
+// KeyWrapper protects data keys with a master key
+type KeyWrapper interface {
+	// Wrap encrypts a data key and returns it with the ID of the master key used
+	Wrap(ctx context.Context, plaintext []byte) (keyID string, wrapped []byte, err error)
+	// Unwrap decrypts a data key wrapped by the master key keyID
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// DataKey is the key that encrypts the files of one recording
+type DataKey struct {
+	KeyID   string // master key that wrapped it
+	Wrapped []byte
+	key     []byte
+}
+
+// Keyring creates and unwraps data keys with a master key, caching unwrapped keys so
+// that reading a file does not call the key service every time
+type Keyring struct {
+	wrapper KeyWrapper
+	cache   map[string][]byte // unwrapped data keys by master key ID and wrapped key
+	mu      sync.Mutex
+}
+
+// NewKeyring creates a Keyring using a master key
+func NewKeyring(wrapper KeyWrapper) *Keyring {
+	return &Keyring{wrapper: wrapper, cache: make(map[string][]byte)}
+}
+
+// NewDataKey generates a random data key and wraps it
+func (k *Keyring) NewDataKey(ctx context.Context) (DataKey, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return DataKey{}, err
+	}
+	keyID, wrapped, err := k.wrapper.Wrap(ctx, key)
+	if err != nil {
+		return DataKey{}, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	k.remember(keyID, wrapped, key)
+	return DataKey{KeyID: keyID, Wrapped: wrapped, key: key}, nil
+}
+
+// Unwrap returns a data key read from a file header with its plaintext key
+func (k *Keyring) Unwrap(ctx context.Context, key DataKey) (DataKey, error) {
+	if key.key != nil {
+		return key, nil
+	}
+
+	k.mu.Lock()
+	cached, exists := k.cache[cacheKey(key.KeyID, key.Wrapped)]
+	k.mu.Unlock()
+	if exists {
+		key.key = cached
+		return key, nil
+	}
+
+	plaintext, err := k.wrapper.Unwrap(ctx, key.KeyID, key.Wrapped)
+	if err != nil {
+		return DataKey{}, fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+	if len(plaintext) != dataKeySize {
+		return DataKey{}, errors.New("failed to unwrap data key: invalid size")
+	}
+	k.remember(key.KeyID, key.Wrapped, plaintext)
+	key.key = plaintext
+	return key, nil
+}
+
+// remember caches an unwrapped data key, forgetting every key when the cache is full
+func (k *Keyring) remember(keyID string, wrapped, key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.cache) >= maxCachedKeys {
+		k.cache = make(map[string][]byte)
+	}
+	k.cache[cacheKey(keyID, wrapped)] = key
+}
+
+// cacheKey returns the cache key of a wrapped data key
+func cacheKey(keyID string, wrapped []byte) string {
+	return keyID + "\x00" + string(wrapped)
+}
@@ -0,0 +1,341 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Encrypted files start with a header: the magic, the master key ID and the wrapped
+// data key, each preceded by its 16-bit length, and a random nonce prefix. The content
+// follows in chunks of chunkSize bytes, each sealed with AES-256-GCM under a nonce of
+// the prefix and the chunk index. The last chunk is marked in its additional data, so
+// truncated files are detected.
+const (
+	magic       = "VCSENC1\n"
+	chunkSize   = 64 << 10
+	prefixSize  = 8
+	nonceSize   = 12
+	tagSize     = 16
+	sealedChunk = chunkSize + tagSize
+)
+
+// ErrCorrupt is returned for encrypted files that fail authentication
+var ErrCorrupt = errors.New("encrypted file is corrupt")
+
+// IsEncrypted reports whether the file at path is encrypted
+func IsEncrypted(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(file, head); err != nil {
+		return false, nil
+	}
+	return string(head) == magic, nil
+}
+
+// ReadKey returns the wrapped data key in the header of an encrypted file
+func ReadKey(path string) (DataKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return DataKey{}, err
+	}
+	defer file.Close()
+
+	key, _, _, err := readHeader(bufio.NewReader(file))
+	return key, err
+}
+
+// readHeader reads the header of an encrypted file and returns its size
+func readHeader(r io.Reader) (DataKey, []byte, int64, error) {
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, head); err != nil || string(head) != magic {
+		return DataKey{}, nil, 0, errors.New("file is not encrypted")
+	}
+	keyID, err := readField(r)
+	if err != nil {
+		return DataKey{}, nil, 0, err
+	}
+	wrapped, err := readField(r)
+	if err != nil {
+		return DataKey{}, nil, 0, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return DataKey{}, nil, 0, ErrCorrupt
+	}
+	size := int64(len(magic) + 2 + len(keyID) + 2 + len(wrapped) + prefixSize)
+	return DataKey{KeyID: string(keyID), Wrapped: wrapped}, prefix, size, nil
+}
+
+// readField reads a field preceded by its 16-bit length
+func readField(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, ErrCorrupt
+	}
+	field := make([]byte, length)
+	if _, err := io.ReadFull(r, field); err != nil {
+		return nil, ErrCorrupt
+	}
+	return field, nil
+}
+
+// newAEAD returns the cipher of a data key
+func newAEAD(key DataKey) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of a chunk
+func chunkNonce(prefix []byte, index int64) []byte {
+	nonce := make([]byte, nonceSize)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], uint32(index))
+	return nonce
+}
+
+// chunkAD returns the additional data of a chunk, which marks the last one
+func chunkAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// Encrypt writes the content of r to w encrypted with a data key
+func Encrypt(w io.Writer, r io.Reader, key DataKey) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+
+	var header bytes.Buffer
+	header.WriteString(magic)
+	binary.Write(&header, binary.BigEndian, uint16(len(key.KeyID)))
+	header.WriteString(key.KeyID)
+	binary.Write(&header, binary.BigEndian, uint16(len(key.Wrapped)))
+	header.Write(key.Wrapped)
+	header.Write(prefix)
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	// A chunk is sealed once the next one has started, so the last one can be marked
+	current := make([]byte, chunkSize)
+	next := make([]byte, chunkSize)
+	n, err := io.ReadFull(r, current)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	sealed := make([]byte, 0, sealedChunk)
+	for index := int64(0); ; index++ {
+		m := 0
+		if n == chunkSize {
+			m, err = io.ReadFull(r, next)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return err
+			}
+		}
+		last := m == 0
+		sealed = aead.Seal(sealed[:0], chunkNonce(prefix, index), current[:n], chunkAD(last))
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		current, next, n = next, current, m
+	}
+}
+
+// EncryptFile encrypts the file at path in place with a data key. The encrypted copy
+// is written next to it and replaces it once complete; its modification time is kept.
+func EncryptFile(path string, key DataKey) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	partial := path + ".encrypting"
+	dst, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriterSize(dst, sealedChunk)
+	err = Encrypt(w, src, key)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to encrypt %s: %v", path, err)
+	}
+	os.Chtimes(partial, info.ModTime(), info.ModTime())
+	return os.Rename(partial, path)
+}
+
+// Open opens a file for reading, decrypting it with a data key unwrapped by keyring if
+// it is encrypted. keyring may be nil when only plain files are expected.
+func Open(ctx context.Context, path string, keyring *Keyring) (io.ReadSeekCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(file, head); err != nil || string(head) != magic {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+	if keyring == nil {
+		file.Close()
+		return nil, ErrNoKeyring
+	}
+
+	reader, err := newReader(ctx, file, keyring)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// reader decrypts an encrypted file, seeking by chunk
+type reader struct {
+	file   *os.File
+	aead   cipher.AEAD
+	prefix []byte
+	header int64 // size of the header
+	chunks int64
+	size   int64 // of the plain content
+	pos    int64
+	index  int64 // of the chunk in plain, -1 if none
+	plain  []byte
+	sealed []byte
+}
+
+// newReader reads the header of an encrypted file and unwraps its data key
+func newReader(ctx context.Context, file *os.File, keyring *Keyring) (*reader, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	key, prefix, header, err := readHeader(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	key, err = keyring.Unwrap(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	body := info.Size() - header
+	full, rest := body/sealedChunk, body%sealedChunk
+	r := &reader{file: file, aead: aead, prefix: prefix, header: header, index: -1,
+		sealed: make([]byte, sealedChunk)}
+	switch {
+	case rest == 0 && full > 0:
+		r.chunks, r.size = full, full*chunkSize
+	case rest >= tagSize:
+		r.chunks, r.size = full+1, full*chunkSize+rest-tagSize
+	default:
+		return nil, ErrCorrupt
+	}
+	return r, nil
+}
+
+// Read reads plain content from the current position
+func (r *reader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	index := r.pos / chunkSize
+	if index != r.index {
+		if err := r.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain[r.pos-index*chunkSize:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// load decrypts a chunk
+func (r *reader) load(index int64) error {
+	length := int64(sealedChunk)
+	if index == r.chunks-1 {
+		length = r.size - index*chunkSize + tagSize
+	}
+	sealed := r.sealed[:length]
+	if _, err := r.file.ReadAt(sealed, r.header+index*sealedChunk); err != nil {
+		return err
+	}
+	plain, err := r.aead.Open(r.plain[:0], chunkNonce(r.prefix, index), sealed, chunkAD(index == r.chunks-1))
+	if err != nil {
+		r.index = -1
+		return ErrCorrupt
+	}
+	r.plain, r.index = plain, index
+	return nil
+}
+
+// Seek sets the position in the plain content
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close closes the file
+func (r *reader) Close() error {
+	return r.file.Close()
+}
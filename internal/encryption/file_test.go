@@ -0,0 +1,164 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestKeyring returns a keyring wrapping data keys with a local master key
+func newTestKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	master := make([]byte, dataKeySize)
+	rand.Read(master)
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(master)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kms, err := NewLocalKMS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKeyring(kms)
+}
+
+// encryptTestFile writes content to a file, encrypts it and returns its path
+func encryptTestFile(t *testing.T, keyring *Keyring, content []byte) string {
+	t.Helper()
+	key, err := keyring.NewDataKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "recording.webm")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncryptFile(path, key); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// decryptTestFile reads a whole encrypted file through Open
+func decryptTestFile(keyring *Keyring, path string) ([]byte, error) {
+	file, err := Open(context.Background(), path, keyring)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// TestFileRoundTrip checks that files of various sizes, including several chunks and
+// an exact multiple of the chunk size, decrypt to their content
+func TestFileRoundTrip(t *testing.T) {
+	keyring := newTestKeyring(t)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, 2 * chunkSize, 3*chunkSize + 1000} {
+		content := make([]byte, size)
+		rand.Read(content)
+		path := encryptTestFile(t, keyring, content)
+
+		if encrypted, err := IsEncrypted(path); err != nil || !encrypted {
+			t.Fatalf("%d bytes: file is not marked encrypted (%v)", size, err)
+		}
+		plain, err := decryptTestFile(keyring, path)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(plain, content) {
+			t.Errorf("%d bytes: decrypted %d different bytes", size, len(plain))
+		}
+	}
+}
+
+// TestFileSeek checks reading from a position in a later chunk
+func TestFileSeek(t *testing.T) {
+	keyring := newTestKeyring(t)
+	content := make([]byte, 3*chunkSize+1000)
+	rand.Read(content)
+	path := encryptTestFile(t, keyring, content)
+
+	file, err := Open(context.Background(), path, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	offset := int64(2*chunkSize - 10)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 100)
+	if _, err := io.ReadFull(file, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content[offset:offset+100]) {
+		t.Error("read across a chunk boundary after seeking returned different bytes")
+	}
+}
+
+// TestFileCorruption checks that truncated, reordered and tampered chunks fail to
+// decrypt instead of yielding altered content
+func TestFileCorruption(t *testing.T) {
+	keyring := newTestKeyring(t)
+	content := make([]byte, 3*chunkSize+1000)
+	rand.Read(content)
+	path := encryptTestFile(t, keyring, content)
+
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, header, err := readHeader(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := func(data []byte, index int) []byte {
+		start := int(header) + index*sealedChunk
+		return data[start : start+sealedChunk]
+	}
+
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+	}{
+		{"last chunk dropped", func(data []byte) []byte {
+			return data[:int(header)+3*sealedChunk]
+		}},
+		{"truncated within a chunk", func(data []byte) []byte {
+			return data[:len(data)-10]
+		}},
+		{"chunks reordered", func(data []byte) []byte {
+			first := append([]byte(nil), chunk(data, 0)...)
+			copy(chunk(data, 0), chunk(data, 1))
+			copy(chunk(data, 1), first)
+			return data
+		}},
+		{"chunk tampered", func(data []byte) []byte {
+			chunk(data, 1)[100] ^= 0x01
+			return data
+		}},
+		{"tag tampered", func(data []byte) []byte {
+			data[len(data)-1] ^= 0x01
+			return data
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := filepath.Join(t.TempDir(), "corrupt.webm")
+			data := tt.modify(append([]byte(nil), encrypted...))
+			if err := os.WriteFile(corrupt, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decryptTestFile(keyring, corrupt); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("decrypting = %v, want ErrCorrupt", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package encryption
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// localKeyPrefix starts the IDs of master keys read from a key file
+const localKeyPrefix = "local:"
+
+// LocalKMS wraps data keys with AES-256-GCM master keys read from a file. The file
+// holds one base64-encoded 32-byte key per line: the first wraps new data keys and
+// the others still unwrap data keys wrapped before a rotation.
+type LocalKMS struct {
+	current string
+	keys    map[string]cipher.AEAD // by key ID
+}
+
+// NewLocalKMS reads the master keys in the file at path
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %v", err)
+	}
+	defer file.Close()
+
+	kms := &LocalKMS{keys: make(map[string]cipher.AEAD)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(key) != dataKeySize {
+			return nil, errors.New("invalid master key file: each line must be a base64-encoded 32-byte key")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(key)
+		keyID := localKeyPrefix + hex.EncodeToString(sum[:8])
+		if kms.current == "" {
+			kms.current = keyID
+		}
+		kms.keys[keyID] = aead
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %v", err)
+	}
+	if kms.current == "" {
+		return nil, errors.New("invalid master key file: no key")
+	}
+	return kms, nil
+}
+
+// Wrap encrypts a data key with the current master key
+func (l *LocalKMS) Wrap(ctx context.Context, plaintext []byte) (string, []byte, error) {
+	aead := l.keys[l.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return l.current, aead.Seal(nonce, nonce, plaintext, []byte(l.current)), nil
+}
+
+// Unwrap decrypts a data key with the master key that wrapped it
+func (l *LocalKMS) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, exists := l.keys[keyID]
+	if !exists {
+		return nil, ErrUnknownKey
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(keyID))
+}
@@ -0,0 +1,244 @@
+package errreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/logging"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+// Report levels
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// Delivery settings
+const (
+	queueSize       = 256
+	deliveryTimeout = 5 * time.Second
+)
+
+// Report is an error captured for reporting, with the room, user and request it happened in
+type Report struct {
+	ID      string            `json:"id"`
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Stack   string            `json:"stack,omitempty"`
+	Context map[string]string `json:"context,omitempty"`
+}
+
+// Sink delivers reports to an error tracking service
+type Sink interface {
+	Send(report Report) error
+}
+
+// Reporter queues reports and delivers them to sinks in the background, so
+// reporting never blocks a request or a media path
+type Reporter struct {
+	sinks []Sink
+	queue chan Report
+}
+
+// reporter is the process-wide reporter; nil until Configure installs sinks
+var reporter *Reporter
+
+// Configure installs the sinks that receive captured errors; without sinks capturing is a no-op
+func Configure(sinks ...Sink) {
+	if len(sinks) == 0 {
+		return
+	}
+
+	r := &Reporter{
+		sinks: sinks,
+		queue: make(chan Report, queueSize),
+	}
+	go r.run()
+	reporter = r
+}
+
+// Enabled reports whether errors are being reported anywhere
+func Enabled() bool {
+	return reporter != nil
+}
+
+// Capture reports an error with context such as room_id and user_id
+func Capture(err error, context map[string]string) {
+	if err == nil {
+		return
+	}
+	capture(LevelError, err.Error(), "", context)
+}
+
+// CapturePanic reports a recovered panic with its stack trace
+func CapturePanic(value interface{}, stack []byte, context map[string]string) {
+	capture(LevelFatal, fmt.Sprintf("panic: %v", value), string(stack), context)
+}
+
+// capture queues a report, dropping it if the queue is full
+func capture(level, message, stack string, context map[string]string) {
+	r := reporter
+	if r == nil {
+		return
+	}
+
+	report := Report{
+		ID:      newEventID(),
+		Time:    time.Now().UTC(),
+		Level:   level,
+		Message: message,
+		Stack:   stack,
+		Context: context,
+	}
+
+	select {
+	case r.queue <- report:
+	default:
+		logger.Warnf("Dropping error report, queue full: %s", message)
+	}
+}
+
+// run delivers queued reports to every sink
+func (r *Reporter) run() {
+	for report := range r.queue {
+		for _, sink := range r.sinks {
+			if err := sink.Send(report); err != nil {
+				logger.Errorf("Failed to deliver error report: %v", err)
+			}
+		}
+	}
+}
+
+// newEventID returns a random 32-character hex ID, the format Sentry expects
+func newEventID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// postJSON posts a JSON body with extra headers and checks for a 2xx response
+func postJSON(client *http.Client, endpoint string, body interface{}, headers map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// HookSink posts reports as JSON to a generic error-reporting endpoint
+type HookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHookSink creates a HookSink posting to url
+func NewHookSink(url string) *HookSink {
+	return &HookSink{
+		url:    url,
+		client: &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+// Send posts a report
+func (h *HookSink) Send(report Report) error {
+	return postJSON(h.client, h.url, report, nil)
+}
+
+// SentrySink sends reports to Sentry through its store API
+type SentrySink struct {
+	endpoint    string
+	key         string
+	environment string
+	client      *http.Client
+}
+
+// NewSentrySink creates a SentrySink from a DSN such as https://<key>@o1.ingest.sentry.io/<project>
+func NewSentrySink(dsn, environment string) (*SentrySink, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %v", err)
+	}
+
+	key := parsed.User.Username()
+	project := strings.Trim(parsed.Path, "/")
+	if key == "" || project == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: expected scheme://key@host/project")
+	}
+
+	// A DSN path may carry a prefix before the project ID
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+
+	return &SentrySink{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/store/", parsed.Scheme, parsed.Host, prefix, project),
+		key:         key,
+		environment: environment,
+		client:      &http.Client{Timeout: deliveryTimeout},
+	}, nil
+}
+
+// Send stores a report as a Sentry event; room and user context become tags and the user
+func (s *SentrySink) Send(report Report) error {
+	tags := make(map[string]string, len(report.Context))
+	for name, value := range report.Context {
+		tags[name] = value
+	}
+
+	event := map[string]interface{}{
+		"event_id":  report.ID,
+		"timestamp": report.Time.Format(time.RFC3339),
+		"level":     report.Level,
+		"platform":  "go",
+		"logger":    "video-call-server",
+		"message":   report.Message,
+		"tags":      tags,
+	}
+	if s.environment != "" {
+		event["environment"] = s.environment
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		event["server_name"] = hostname
+	}
+	if userID := report.Context["user_id"]; userID != "" {
+		event["user"] = map[string]string{"id": userID}
+	}
+	if report.Stack != "" {
+		event["extra"] = map[string]string{"stack": report.Stack}
+	}
+
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=video-call-server/1.0, sentry_key=%s", s.key)
+	return postJSON(s.client, s.endpoint, event, map[string]string{"X-Sentry-Auth": auth})
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	RoomCreated            = "room.created"
+	RoomUpdated            = "room.updated"
+	RoomParticipants       = "room.participants"
+	RoomSessionEnded       = "room.session_ended"
+	RoomEnded              = "room.ended"
+	RoomDeleted            = "room.deleted"
+	RoomRestored           = "room.restored"
+	RoomReminder           = "room.reminder"
+	RoomStarted            = "room.started"
+	CallMissed             = "call.missed"
+	ParticipantJoined      = "participant.joined"
+	ParticipantLeft        = "participant.left"
+	ParticipantFlagged     = "participant.flagged"
+	ParticipantTransferred = "participant.transferred"
+	UserStatus             = "user.status"
+	ChatMessage            = "chat.message"
+	RecordingStarted       = "recording.started"
+	RecordingStopped       = "recording.stopped"
+	RecordingReady         = "recording.ready"
+	RecordingExported      = "recording.exported"
+	RecordingArchived      = "recording.archived"
+	RecordingRestored      = "recording.restored"
+	RecordingCorrupted     = "recording.corrupted"
+	NotesReady             = "room.notes_ready"
+	NodeDraining           = "node.draining"
+	NodeDrained            = "node.drained"
+	MaintenanceScheduled   = "maintenance.scheduled"
+	MaintenanceStarted     = "maintenance.started"
+	MaintenanceEnded       = "maintenance.ended"
+)
+
+// subscriberBuffer is the number of events buffered per subscriber
+const subscriberBuffer = 64
+
+// Event is a server lifecycle event
+type Event struct {
+	Type   string                 `json:"type"`
+	Time   time.Time              `json:"time"`
+	RoomID string                 `json:"room_id,omitempty"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// Bus fans out events to subscribers
+type Bus struct {
+	subscribers map[chan Event]bool
+	mu          sync.RWMutex
+}
+
+// NewBus creates a new Bus
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[chan Event]bool),
+	}
+}
+
+// Subscribe returns a channel receiving all future events and a function to unsubscribe
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = true
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers an event to every subscriber; slow subscribers miss events rather than block
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrFileTooLarge is returned when an upload exceeds the size limit
+	ErrFileTooLarge = errors.New("file exceeds the maximum upload size")
+
+	// ErrFileRejected is returned when the scanner rejects an upload
+	ErrFileRejected = errors.New("file rejected by scanner")
+)
+
+// File represents a file shared during a room session
+type File struct {
+	ID          string    `json:"id"`
+	RoomID      string    `json:"room_id"`
+	UploaderID  string    `json:"uploader_id"`
+	Uploader    string    `json:"uploader"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	Path        string    `json:"-"`
+}
+
+// Scanner inspects an uploaded file before it is made available to the room
+type Scanner interface {
+	Scan(path string) error
+}
+
+// CommandScanner runs an external command (e.g. clamdscan) with the file path as its last argument;
+// a non-zero exit status rejects the file
+type CommandScanner struct {
+	Command string
+	Args    []string
+}
+
+// Scan runs the scan command
+func (s *CommandScanner) Scan(path string) error {
+	args := append(append([]string{}, s.Args...), path)
+	if out, err := exec.Command(s.Command, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", ErrFileRejected, string(out))
+	}
+	return nil
+}
+
+// Manager stores room-scoped files on disk
+type Manager struct {
+	files    map[string]*File
+	mu       sync.RWMutex
+	basePath string
+	maxSize  int64
+	scanner  Scanner
+}
+
+// NewManager creates a new Manager; scanner may be nil to skip scanning
+func NewManager(basePath string, maxSize int64, scanner Scanner) *Manager {
+	// Create base path if it doesn't exist
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		panic(fmt.Sprintf("Failed to create uploads directory: %v", err))
+	}
+
+	return &Manager{
+		files:    make(map[string]*File),
+		basePath: basePath,
+		maxSize:  maxSize,
+		scanner:  scanner,
+	}
+}
+
+// MaxSize returns the maximum upload size in bytes
+func (m *Manager) MaxSize() int64 {
+	return m.maxSize
+}
+
+// Save stores an uploaded file for a room
+func (m *Manager) Save(roomID, uploaderID, uploader, name, contentType string, r io.Reader) (*File, error) {
+	dir := filepath.Join(m.basePath, filepath.Base(roomID))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create room directory: %v", err)
+	}
+
+	file := &File{
+		ID:          uuid.New().String(),
+		RoomID:      roomID,
+		UploaderID:  uploaderID,
+		Uploader:    uploader,
+		Name:        filepath.Base(name),
+		ContentType: contentType,
+		UploadedAt:  time.Now(),
+	}
+	file.Path = filepath.Join(dir, file.ID)
+
+	// Write at most maxSize+1 bytes so oversized uploads can be detected
+	out, err := os.Create(file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %v", err)
+	}
+	size, err := io.Copy(out, io.LimitReader(r, m.maxSize+1))
+	out.Close()
+	if err != nil {
+		os.Remove(file.Path)
+		return nil, fmt.Errorf("failed to write file: %v", err)
+	}
+	if size > m.maxSize {
+		os.Remove(file.Path)
+		return nil, ErrFileTooLarge
+	}
+	file.Size = size
+
+	// Scan before publishing
+	if m.scanner != nil {
+		if err := m.scanner.Scan(file.Path); err != nil {
+			os.Remove(file.Path)
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	m.files[file.ID] = file
+	m.mu.Unlock()
+
+	return file, nil
+}
+
+// Get returns a file of a room by ID
+func (m *Manager) Get(roomID, fileID string) (*File, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, exists := m.files[fileID]
+	if !exists || file.RoomID != roomID {
+		return nil, false
+	}
+	return file, true
+}
+
+// List returns the files of a room, oldest first
+func (m *Manager) List(roomID string) []*File {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := []*File{}
+	for _, file := range m.files {
+		if file.RoomID == roomID {
+			f := *file
+			files = append(files, &f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].UploadedAt.Before(files[j].UploadedAt)
+	})
+
+	return files
+}
+
+// Delete removes a single file
+func (m *Manager) Delete(fileID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, exists := m.files[fileID]
+	if !exists {
+		return fmt.Errorf("file not found: %s", fileID)
+	}
+
+	if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %v", err)
+	}
+	delete(m.files, fileID)
+
+	return nil
+}
+
+// DeleteRoomFiles removes every file of a room, returning how many were deleted
+func (m *Manager) DeleteRoomFiles(roomID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for id, file := range m.files {
+		if file.RoomID == roomID {
+			delete(m.files, id)
+			count++
+		}
+	}
+	os.RemoveAll(filepath.Join(m.basePath, filepath.Base(roomID)))
+
+	return count
+}
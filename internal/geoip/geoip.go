@@ -0,0 +1,93 @@
+// Package geoip locates client IP addresses with a MaxMind GeoIP2 or GeoLite2
+// database (Country or City edition)
+package geoip
+
+import (
+	"math"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371
+
+// Location is where an IP address is registered. Country databases have no coordinates.
+type Location struct {
+	Country        string
+	Continent      string
+	Latitude       float64
+	Longitude      float64
+	HasCoordinates bool
+}
+
+// Database looks up IP addresses in a MaxMind database
+type Database struct {
+	reader *maxminddb.Reader
+}
+
+// record holds the fields read from a database entry
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// Open opens a MaxMind database file
+func Open(path string) (*Database, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{reader: reader}, nil
+}
+
+// Lookup returns the location of an IP address; it reports false for addresses the
+// database does not know and when db is nil
+func (db *Database) Lookup(ip net.IP) (Location, bool) {
+	if db == nil || ip == nil {
+		return Location{}, false
+	}
+
+	var entry record
+	if _, found, err := db.reader.LookupNetwork(ip, &entry); err != nil || !found {
+		return Location{}, false
+	}
+
+	location := Location{
+		Country:   entry.Country.ISOCode,
+		Continent: entry.Continent.Code,
+	}
+	if entry.Location.Latitude != nil && entry.Location.Longitude != nil {
+		location.Latitude = *entry.Location.Latitude
+		location.Longitude = *entry.Location.Longitude
+		location.HasCoordinates = true
+	}
+	return location, true
+}
+
+// Close releases the database
+func (db *Database) Close() error {
+	if db == nil {
+		return nil
+	}
+	return db.reader.Close()
+}
+
+// DistanceKm returns the great-circle distance between two points in kilometres
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
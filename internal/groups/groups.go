@@ -0,0 +1,237 @@
+package groups
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrGroupNotFound is returned for unknown groups
+	ErrGroupNotFound = errors.New("group not found")
+
+	// ErrGroupExists is returned when another group has the display name
+	ErrGroupExists = errors.New("group already exists")
+)
+
+// Group is a set of users, provisioned by an identity provider
+type Group struct {
+	ID          string    `json:"id"`
+	DisplayName string    `json:"display_name"`
+	ExternalID  string    `json:"external_id,omitempty"`
+	Members     []string  `json:"members"` // user IDs
+	TenantID    string    `json:"tenant_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Manager stores groups in memory
+type Manager struct {
+	groups map[string]*Group
+	mu     sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		groups: make(map[string]*Group),
+	}
+}
+
+// Create adds a group to a tenant
+func (m *Manager) Create(tenantID, displayName, externalID string, members []string) (Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.nameTaken(tenantID, "", displayName) {
+		return Group{}, ErrGroupExists
+	}
+
+	now := time.Now()
+	group := &Group{
+		ID:          uuid.New().String(),
+		DisplayName: displayName,
+		ExternalID:  externalID,
+		Members:     unique(members),
+		TenantID:    tenantID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	m.groups[group.ID] = group
+	return group.copy(), nil
+}
+
+// Get returns a group
+func (m *Manager) Get(groupID string) (Group, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return Group{}, false
+	}
+	return group.copy(), true
+}
+
+// List returns the groups of a tenant, by display name
+func (m *Manager) List(tenantID string) []Group {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Group, 0, len(m.groups))
+	for _, group := range m.groups {
+		if group.TenantID == tenantID {
+			list = append(list, group.copy())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].DisplayName < list[j].DisplayName
+	})
+	return list
+}
+
+// Replace sets the display name, external ID and members of a group
+func (m *Manager) Replace(groupID, displayName, externalID string, members []string) (Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return Group{}, ErrGroupNotFound
+	}
+	if m.nameTaken(group.TenantID, groupID, displayName) {
+		return Group{}, ErrGroupExists
+	}
+
+	group.DisplayName = displayName
+	group.ExternalID = externalID
+	group.Members = unique(members)
+	group.UpdatedAt = time.Now()
+	return group.copy(), nil
+}
+
+// AddMembers adds users to a group
+func (m *Manager) AddMembers(groupID string, userIDs []string) (Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return Group{}, ErrGroupNotFound
+	}
+
+	group.Members = unique(append(group.Members, userIDs...))
+	group.UpdatedAt = time.Now()
+	return group.copy(), nil
+}
+
+// RemoveMembers removes users from a group
+func (m *Manager) RemoveMembers(groupID string, userIDs []string) (Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return Group{}, ErrGroupNotFound
+	}
+
+	group.Members = without(group.Members, userIDs)
+	group.UpdatedAt = time.Now()
+	return group.copy(), nil
+}
+
+// Delete removes a group
+func (m *Manager) Delete(groupID string) (Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, exists := m.groups[groupID]
+	if !exists {
+		return Group{}, ErrGroupNotFound
+	}
+	delete(m.groups, groupID)
+	return group.copy(), nil
+}
+
+// Of returns the groups a user is a member of, by display name
+func (m *Manager) Of(userID string) []Group {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var list []Group
+	for _, group := range m.groups {
+		for _, member := range group.Members {
+			if member == userID {
+				list = append(list, group.copy())
+				break
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].DisplayName < list[j].DisplayName
+	})
+	return list
+}
+
+// RemoveUser removes a user from every group, such as when the user is deleted
+func (m *Manager) RemoveUser(userID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, group := range m.groups {
+		if members := without(group.Members, []string{userID}); len(members) != len(group.Members) {
+			group.Members = members
+			group.UpdatedAt = time.Now()
+		}
+	}
+}
+
+// nameTaken reports whether a group of the tenant other than groupID has a display
+// name; the caller holds m.mu
+func (m *Manager) nameTaken(tenantID, groupID, displayName string) bool {
+	for _, group := range m.groups {
+		if group.ID != groupID && group.TenantID == tenantID && strings.EqualFold(group.DisplayName, displayName) {
+			return true
+		}
+	}
+	return false
+}
+
+// copy returns a copy of a group that does not share its member list
+func (g *Group) copy() Group {
+	c := *g
+	c.Members = append([]string{}, g.Members...)
+	return c
+}
+
+// unique returns IDs without duplicates, in their first order
+func unique(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			list = append(list, id)
+		}
+	}
+	return list
+}
+
+// without returns ids except those in remove
+func without(ids, remove []string) []string {
+	drop := make(map[string]bool, len(remove))
+	for _, id := range remove {
+		drop[id] = true
+	}
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !drop[id] {
+			list = append(list, id)
+		}
+	}
+	return list
+}
@@ -0,0 +1,122 @@
+// Package hostpolicy decides which hosts the server may connect to on behalf of users,
+// such as media streams pulled into rooms and the endpoints of room bots, so that
+// user-supplied URLs cannot reach services on the server's own network.
+package hostpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrHostNotAllowed is returned for hosts the policy does not allow
+var ErrHostNotAllowed = errors.New("host is not allowed")
+
+// dialTimeout bounds connecting through Dialer
+const dialTimeout = 10 * time.Second
+
+// Policy allows the hosts in Hosts, compared case-insensitively; with no hosts it
+// allows any host except those on loopback, private, link-local, multicast and
+// unspecified addresses
+type Policy struct {
+	Hosts []string
+}
+
+// New creates a policy allowing hosts, or any public host if there are none
+func New(hosts []string) Policy {
+	return Policy{Hosts: hosts}
+}
+
+// listed reports whether a host is in the allowlist
+func (p Policy) listed(host string) bool {
+	for _, allowed := range p.Hosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// internal reports whether an address belongs to the server's own or a private network
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// CheckHost reports whether a host may be connected to; without an allowlist the host
+// is resolved and refused if any of its addresses is internal
+func (p Policy) CheckHost(host string) error {
+	if host == "" {
+		return ErrHostNotAllowed
+	}
+	if len(p.Hosts) > 0 {
+		if p.listed(host) {
+			return nil
+		}
+		return ErrHostNotAllowed
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		return ErrHostNotAllowed
+	}
+	for _, ip := range ips {
+		if internal(ip) {
+			return ErrHostNotAllowed
+		}
+	}
+	return nil
+}
+
+// Dialer returns a dial function enforcing the policy on every connection: listed
+// hosts by name, otherwise by the address actually connected to, so that a host name
+// re-resolving to an internal address after CheckHost is still refused
+func (p Policy) Dialer() func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if len(p.Hosts) > 0 {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internal(ip) {
+				return ErrHostNotAllowed
+			}
+			return nil
+		},
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if len(p.Hosts) > 0 {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			if !p.listed(host) {
+				return nil, ErrHostNotAllowed
+			}
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// Client returns an HTTP client that only connects to hosts allowed by the policy.
+// It ignores proxy settings, since a proxy would connect on its behalf.
+func (p Policy) Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         p.Dialer(),
+			TLSHandshakeTimeout: dialTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
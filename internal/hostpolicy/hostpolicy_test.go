@@ -0,0 +1,65 @@
+package hostpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	open := New(nil)
+	for _, host := range []string{"127.0.0.1", "localhost", "10.1.2.3", "192.168.0.1", "169.254.169.254", "::1", "0.0.0.0", ""} {
+		if err := open.CheckHost(host); !errors.Is(err, ErrHostNotAllowed) {
+			t.Errorf("CheckHost(%q) = %v, want ErrHostNotAllowed", host, err)
+		}
+	}
+	if err := open.CheckHost("8.8.8.8"); err != nil {
+		t.Errorf("CheckHost(public address) = %v", err)
+	}
+
+	listed := New([]string{"camera.internal", "127.0.0.1"})
+	if err := listed.CheckHost("CAMERA.internal"); err != nil {
+		t.Errorf("CheckHost(listed host) = %v", err)
+	}
+	if err := listed.CheckHost("127.0.0.1"); err != nil {
+		t.Errorf("CheckHost(listed address) = %v", err)
+	}
+	if err := listed.CheckHost("8.8.8.8"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("CheckHost(unlisted host) = %v, want ErrHostNotAllowed", err)
+	}
+}
+
+// TestDialerRefusesInternalAddresses checks the address connected to, which is what
+// catches a host name re-resolving to an internal address
+func TestDialerRefusesInternalAddresses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	if _, err := New(nil).Dialer()(context.Background(), "tcp", net.JoinHostPort("localhost", port)); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("dial loopback = %v, want ErrHostNotAllowed", err)
+	}
+
+	conn, err := New([]string{"127.0.0.1"}).Dialer()(context.Background(), "tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial listed host: %v", err)
+	}
+	conn.Close()
+
+	if _, err := New([]string{"example.com"}).Dialer()(context.Background(), "tcp", listener.Addr().String()); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("dial unlisted host = %v, want ErrHostNotAllowed", err)
+	}
+}
@@ -0,0 +1,100 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language messages are written in; it needs no catalog
+const DefaultLanguage = "en"
+
+// locales holds one catalog per language, mapping English messages to translations.
+// Parameterized messages are keyed by their format string.
+//
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogs maps a language to its translations
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := locales.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("invalid message catalog %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+}
+
+// Languages returns the supported languages, the default first
+func Languages() []string {
+	languages := []string{DefaultLanguage}
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages[1:])
+	return languages
+}
+
+// Supported reports whether messages can be given in a language
+func Supported(language string) bool {
+	_, ok := catalogs[language]
+	return ok || language == DefaultLanguage
+}
+
+// Negotiate picks the supported language a client prefers from an Accept-Language
+// header such as "ru-RU,ru;q=0.9,en;q=0.8"; it returns fallback if none is supported
+func Negotiate(acceptLanguage, fallback string) string {
+	best, bestQuality := fallback, 0.0
+	for _, item := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		language := Base(tag)
+		if quality > bestQuality && Supported(language) {
+			best, bestQuality = language, quality
+		}
+	}
+	return best
+}
+
+// Base returns the lower-case primary subtag of a language tag: "ru" for "ru-RU"
+func Base(tag string) string {
+	language, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	language, _, _ = strings.Cut(language, "_")
+	return strings.ToLower(language)
+}
+
+// Translate returns a message in a language, or the message itself if it has no translation
+func Translate(language, message string) string {
+	if translated, ok := catalogs[language][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Translatef translates a format string, then formats it
+func Translatef(language, format string, args ...interface{}) string {
+	return fmt.Sprintf(Translate(language, format), args...)
+}
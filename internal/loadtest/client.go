@@ -0,0 +1,426 @@
+package loadtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+
+	ws "github.com/zubans/video-call-server/internal/websocket"
+)
+
+// requestTimeout bounds every HTTP request and the WebSocket join handshake
+const requestTimeout = 15 * time.Second
+
+// simClient is a simulated participant driving the REST API, WebSocket signaling and WebRTC media
+type simClient struct {
+	cfg  *Config
+	name string
+
+	token    string
+	roomID   string
+	clientID string
+
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	pc *webrtc.PeerConnection
+
+	// Signaling messages by type, filled by readLoop
+	joined  chan struct{}
+	offers  chan ws.SessionDescription
+	answers chan ws.SessionDescription
+
+	// Reliable sequence numbers already seen
+	seen map[uint64]bool
+
+	stats *rtpStats
+}
+
+// newSimClient creates a simulated client with a unique name
+func newSimClient(cfg *Config, index int) *simClient {
+	return &simClient{
+		cfg:     cfg,
+		name:    fmt.Sprintf("loadtest_%d_%d", time.Now().UnixNano(), index),
+		joined:  make(chan struct{}, 1),
+		offers:  make(chan ws.SessionDescription, 1),
+		answers: make(chan ws.SessionDescription, 1),
+		seen:    make(map[uint64]bool),
+		stats:   &rtpStats{},
+	}
+}
+
+// call sends a JSON request to the target server and decodes the JSON response
+func (c *simClient) call(method, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(c.cfg.Target, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s: %s %s", method, path, resp.Status, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// login registers the simulated user and obtains a token
+func (c *simClient) login() error {
+	password := "loadtest-" + c.name
+	if err := c.call(http.MethodPost, "/register", map[string]string{
+		"username": c.name,
+		"email":    c.name + "@loadtest.local",
+		"password": password,
+	}, nil); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.call(http.MethodPost, "/login", map[string]string{
+		"identifier": c.name,
+		"password":   password,
+	}, &resp); err != nil {
+		return err
+	}
+
+	c.token = resp.Token
+	return nil
+}
+
+// createRoom creates a room for this client and its peers
+func (c *simClient) createRoom() (string, error) {
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	err := c.call(http.MethodPost, "/create-room", map[string]string{"name": c.name}, &resp)
+	return resp.RoomID, err
+}
+
+// join joins a room over REST and WebSocket, returning the time until the server confirmed the join
+func (c *simClient) join(roomID string) (time.Duration, error) {
+	started := time.Now()
+
+	var resp struct {
+		ClientID string `json:"client_id"`
+	}
+	if err := c.call(http.MethodPost, "/join-room", map[string]string{"room_id": roomID}, &resp); err != nil {
+		return 0, err
+	}
+	c.roomID = roomID
+	c.clientID = resp.ClientID
+
+	if err := c.connect(); err != nil {
+		return 0, err
+	}
+	go c.readLoop()
+
+	if err := c.send("join", ws.RoomPayload{RoomID: c.roomID, SenderID: c.clientID}); err != nil {
+		return 0, err
+	}
+
+	select {
+	case <-c.joined:
+		return time.Since(started), nil
+	case <-time.After(requestTimeout):
+		return 0, errors.New("timed out waiting for joined")
+	}
+}
+
+// connect opens the signaling WebSocket
+func (c *simClient) connect() error {
+	target, err := url.Parse(c.cfg.Target)
+	if err != nil {
+		return err
+	}
+	target.Scheme = strings.Replace(target.Scheme, "http", "ws", 1)
+	target.Path = strings.TrimRight(target.Path, "/") + "/ws"
+	target.RawQuery = url.Values{"token": {c.token}}.Encode()
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: requestTimeout,
+		Subprotocols:     []string{fmt.Sprintf("videocall.v%d", ws.ProtocolVersion)},
+	}
+	conn, _, err := dialer.Dial(target.String(), nil)
+	if err != nil {
+		return fmt.Errorf("websocket: %v", err)
+	}
+
+	c.conn = conn
+	return nil
+}
+
+// send writes an envelope to the WebSocket
+func (c *simClient) send(msgType string, payload interface{}) error {
+	message, err := ws.EncodeEnvelope(ws.ProtocolVersion, msgType, payload)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// readLoop dispatches incoming signaling messages until the connection closes
+func (c *simClient) readLoop() {
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// The server may batch several messages into one frame
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var env ws.Envelope
+			if err := dec.Decode(&env); err != nil {
+				break
+			}
+			c.handle(env)
+		}
+	}
+}
+
+// handle processes one signaling message
+func (c *simClient) handle(env ws.Envelope) {
+
+	// Acknowledge reliable messages and ignore retransmissions
+	if env.Seq != 0 {
+		c.send("ack", ws.AckPayload{Seq: env.Seq})
+		if c.seen[env.Seq] {
+			return
+		}
+		c.seen[env.Seq] = true
+	}
+
+	switch env.Type {
+	case "joined":
+		select {
+		case c.joined <- struct{}{}:
+		default:
+		}
+	case "offer", "answer":
+		var payload ws.SDPPayload
+		if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.SDP == nil {
+			return
+		}
+		ch := c.offers
+		if env.Type == "answer" {
+			ch = c.answers
+		}
+		select {
+		case ch <- *payload.SDP:
+		default:
+		}
+	}
+}
+
+// newPeerConnection creates the client's peer connection
+func (c *simClient) newPeerConnection() error {
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return err
+	}
+	c.pc = pc
+
+	// Count received packets for loss statistics
+	pc.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		for {
+			packet, _, err := track.ReadRTP()
+			if err != nil {
+				return
+			}
+			c.stats.record(packet.SequenceNumber)
+		}
+	})
+	return nil
+}
+
+// publish offers a synthetic video track to the room and waits for the answer
+func (c *simClient) publish(stop <-chan struct{}) error {
+	if err := c.newPeerConnection(); err != nil {
+		return err
+	}
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", c.name)
+	if err != nil {
+		return err
+	}
+	if _, err := c.pc.AddTrack(track); err != nil {
+		return err
+	}
+
+	offer, err := c.pc.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	if err := c.setLocal(offer); err != nil {
+		return err
+	}
+	if err := c.sendDescription("offer"); err != nil {
+		return err
+	}
+
+	select {
+	case answer := <-c.answers:
+		if err := c.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: answer.SDP}); err != nil {
+			return err
+		}
+	case <-time.After(requestTimeout):
+		return errors.New("timed out waiting for answer")
+	}
+
+	go c.sendFrames(track, stop)
+	return nil
+}
+
+// subscribe answers the room peer's offer and receives its media
+func (c *simClient) subscribe() error {
+	if err := c.newPeerConnection(); err != nil {
+		return err
+	}
+
+	select {
+	case offer := <-c.offers:
+		if err := c.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer.SDP}); err != nil {
+			return err
+		}
+	case <-time.After(requestTimeout):
+		return errors.New("timed out waiting for offer")
+	}
+
+	answer, err := c.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err := c.setLocal(answer); err != nil {
+		return err
+	}
+	return c.sendDescription("answer")
+}
+
+// setLocal applies a local description and waits for ICE gathering so no candidates need trickling
+func (c *simClient) setLocal(desc webrtc.SessionDescription) error {
+	gathered := webrtc.GatheringCompletePromise(c.pc)
+	if err := c.pc.SetLocalDescription(desc); err != nil {
+		return err
+	}
+	<-gathered
+	return nil
+}
+
+// sendDescription sends the local description over signaling
+func (c *simClient) sendDescription(msgType string) error {
+	local := c.pc.LocalDescription()
+	return c.send(msgType, ws.SDPPayload{
+		RoomPayload: ws.RoomPayload{RoomID: c.roomID, SenderID: c.clientID},
+		SDP:         &ws.SessionDescription{Type: local.Type.String(), SDP: local.SDP},
+	})
+}
+
+// sendFrames writes random frames at the configured rate until stopped
+func (c *simClient) sendFrames(track *webrtc.TrackLocalStaticSample, stop <-chan struct{}) {
+	interval := time.Second / time.Duration(c.cfg.FPS)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	frame := make([]byte, c.cfg.FrameSize)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rand.Read(frame)
+			if err := track.WriteSample(media.Sample{Data: frame, Duration: interval}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close tears down the client's connections
+func (c *simClient) close() {
+	if c.pc != nil {
+		c.pc.Close()
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// rtpStats counts received packets and the sequence number range they cover
+type rtpStats struct {
+	received uint64
+	first    int64
+	highest  int64 // extended sequence number
+	mu       sync.Mutex
+}
+
+// record accounts for a received packet
+func (s *rtpStats) record(seq uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.received == 0 {
+		s.first = int64(seq)
+		s.highest = int64(seq)
+	} else {
+		// Extend the 16-bit sequence number across wraparounds
+		ext := s.highest&^0xFFFF | int64(seq)
+		if ext < s.highest-0x8000 {
+			ext += 0x10000
+		} else if ext > s.highest+0x8000 {
+			ext -= 0x10000
+		}
+		if ext > s.highest {
+			s.highest = ext
+		}
+	}
+	s.received++
+}
+
+// result returns packets received and expected
+func (s *rtpStats) result() (received, expected uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.received == 0 {
+		return 0, 0
+	}
+	return s.received, uint64(s.highest - s.first + 1)
+}
@@ -0,0 +1,289 @@
+// Package loadtest simulates call participants against a running server to
+// measure join latency, media packet loss and server CPU for capacity planning.
+package loadtest
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// roomSize is the number of clients per room: one publisher and one subscriber
+const roomSize = 2
+
+// Config controls a load test run
+type Config struct {
+	Target    string
+	Clients   int
+	Duration  time.Duration
+	Ramp      time.Duration
+	FPS       int
+	FrameSize int
+}
+
+// Report summarizes a load test run
+type Report struct {
+	Clients         int            `json:"clients"`
+	Joined          int            `json:"joined"`
+	Failed          int            `json:"failed"`
+	JoinLatency     latencySummary `json:"join_latency_ms"`
+	PacketsReceived uint64         `json:"packets_received"`
+	PacketsExpected uint64         `json:"packets_expected"`
+	PacketLoss      float64        `json:"packet_loss_percent"`
+	ServerCPU       cpuSummary     `json:"server_cpu_percent"`
+	Errors          map[string]int `json:"errors,omitempty"`
+}
+
+// latencySummary describes a latency distribution in milliseconds
+type latencySummary struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	Max float64 `json:"max"`
+}
+
+// cpuSummary describes server CPU usage sampled from /load
+type cpuSummary struct {
+	Avg     float64 `json:"avg"`
+	Max     float64 `json:"max"`
+	Samples int     `json:"samples"`
+}
+
+// Run parses loadtest flags, runs the test and prints the report
+func Run(args []string) error {
+	cfg := &Config{}
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	fs.StringVar(&cfg.Target, "target", "http://localhost:8181", "base URL of the server under test")
+	fs.IntVar(&cfg.Clients, "clients", 10, "number of simulated clients")
+	fs.DurationVar(&cfg.Duration, "duration", 30*time.Second, "how long media flows after all clients joined")
+	fs.DurationVar(&cfg.Ramp, "ramp", 50*time.Millisecond, "delay between starting clients")
+	fs.IntVar(&cfg.FPS, "fps", 30, "synthetic video frames per second")
+	fs.IntVar(&cfg.FrameSize, "frame-size", 1200, "synthetic video frame size in bytes")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.Clients < 1 || cfg.FPS < 1 || cfg.FrameSize < 1 {
+		return errors.New("clients, fps and frame-size must be positive")
+	}
+
+	report := Execute(cfg)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.Print(os.Stdout)
+	return nil
+}
+
+// Execute runs a load test and returns its report
+func Execute(cfg *Config) *Report {
+	report := &Report{
+		Clients: cfg.Clients,
+		Errors:  make(map[string]int),
+	}
+
+	// Sample server CPU for the whole run
+	stopCPU := make(chan struct{})
+	cpuDone := make(chan cpuSummary)
+	go func() {
+		cpuDone <- sampleServerCPU(cfg.Target, stopCPU)
+	}()
+
+	var (
+		latencies []time.Duration
+		clients   []*simClient
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+	)
+
+	fail := func(stage string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		report.Failed++
+		report.Errors[stage+": "+err.Error()]++
+	}
+
+	stopMedia := make(chan struct{})
+
+	// Clients are paired into rooms: the first creates the room and publishes
+	// media, the second subscribes. Signaling is broadcast within a room, so
+	// pairs keep every offer addressed to exactly one peer.
+	for first := 0; first < cfg.Clients; first += roomSize {
+		size := min(roomSize, cfg.Clients-first)
+
+		wg.Add(1)
+		go func(first, size int) {
+			defer wg.Done()
+
+			var roomID string
+			var members []*simClient
+			for i := 0; i < size; i++ {
+				time.Sleep(time.Duration(first+i) * cfg.Ramp)
+
+				client := newSimClient(cfg, first+i)
+				if err := client.login(); err != nil {
+					fail("login", err)
+					continue
+				}
+				if roomID == "" {
+					id, err := client.createRoom()
+					if err != nil {
+						fail("create-room", err)
+						continue
+					}
+					roomID = id
+				}
+
+				latency, err := client.join(roomID)
+				if err != nil {
+					fail("join", err)
+					client.close()
+					continue
+				}
+
+				mu.Lock()
+				latencies = append(latencies, latency)
+				clients = append(clients, client)
+				report.Joined++
+				mu.Unlock()
+
+				members = append(members, client)
+			}
+
+			if len(members) < roomSize {
+				return
+			}
+
+			publisher, subscriber := members[0], members[1]
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- subscriber.subscribe()
+			}()
+
+			if err := publisher.publish(stopMedia); err != nil {
+				fail("publish", err)
+				return
+			}
+			if err := <-errCh; err != nil {
+				fail("subscribe", err)
+			}
+		}(first, size)
+	}
+
+	wg.Wait()
+	log.Printf("%d/%d clients joined, sending media for %s", report.Joined, cfg.Clients, cfg.Duration)
+	time.Sleep(cfg.Duration)
+	close(stopMedia)
+
+	for _, client := range clients {
+		received, expected := client.stats.result()
+		report.PacketsReceived += received
+		report.PacketsExpected += expected
+		client.close()
+	}
+	if report.PacketsExpected > 0 {
+		report.PacketLoss = float64(report.PacketsExpected-min(report.PacketsReceived, report.PacketsExpected)) / float64(report.PacketsExpected) * 100
+	}
+
+	close(stopCPU)
+	report.ServerCPU = <-cpuDone
+	report.JoinLatency = summarize(latencies)
+
+	return report
+}
+
+// summarize computes a latency distribution
+func summarize(latencies []time.Duration) latencySummary {
+	if len(latencies) == 0 {
+		return latencySummary{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p float64) float64 {
+		return ms(latencies[int(p*float64(len(latencies)-1))])
+	}
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+
+	return latencySummary{
+		Min: ms(latencies[0]),
+		Avg: ms(total / time.Duration(len(latencies))),
+		P50: percentile(0.5),
+		P95: percentile(0.95),
+		Max: ms(latencies[len(latencies)-1]),
+	}
+}
+
+// sampleServerCPU polls the server's /load endpoint every second until stopped
+func sampleServerCPU(target string, stop <-chan struct{}) cpuSummary {
+	var summary cpuSummary
+	var total float64
+
+	client := &http.Client{Timeout: requestTimeout}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			if summary.Samples > 0 {
+				summary.Avg = total / float64(summary.Samples)
+			}
+			return summary
+		case <-ticker.C:
+		}
+
+		resp, err := client.Get(strings.TrimRight(target, "/") + "/load")
+		if err != nil {
+			continue
+		}
+		var load struct {
+			CPUPercent float64 `json:"cpu_percent"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&load)
+		resp.Body.Close()
+		if err != nil {
+			continue
+		}
+
+		summary.Samples++
+		total += load.CPUPercent
+		summary.Max = max(summary.Max, load.CPUPercent)
+	}
+}
+
+// Print writes a human-readable report
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Clients:        %d joined, %d failed (of %d)\n", r.Joined, r.Failed, r.Clients)
+	fmt.Fprintf(w, "Join latency:   min %.0fms  avg %.0fms  p50 %.0fms  p95 %.0fms  max %.0fms\n",
+		r.JoinLatency.Min, r.JoinLatency.Avg, r.JoinLatency.P50, r.JoinLatency.P95, r.JoinLatency.Max)
+	fmt.Fprintf(w, "Packets:        %d received of %d expected (%.2f%% loss)\n",
+		r.PacketsReceived, r.PacketsExpected, r.PacketLoss)
+	if r.ServerCPU.Samples > 0 {
+		fmt.Fprintf(w, "Server CPU:     avg %.1f%%  max %.1f%%\n", r.ServerCPU.Avg, r.ServerCPU.Max)
+	} else {
+		fmt.Fprintln(w, "Server CPU:     unavailable (GET /load failed)")
+	}
+	for message, count := range r.Errors {
+		fmt.Fprintf(w, "Error (%dx):     %s\n", count, message)
+	}
+}
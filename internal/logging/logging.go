@@ -0,0 +1,229 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels, from most to least verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Subsystems with their own log level
+const (
+	Server    = "server"
+	HTTP      = "http"
+	Hub       = "hub"
+	SFU       = "sfu"
+	Recording = "recording"
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String returns the level name
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// MarshalText encodes the level by name
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// ParseLevel parses debug, info, warn (or warning) or error
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q: use debug, info, warn or error", name)
+}
+
+// ParseLevels parses per-subsystem levels such as "hub=debug,sfu=warn"
+func ParseLevels(spec string) (map[string]Level, error) {
+	levels := make(map[string]Level)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		subsystem, name, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid log level %q: expected subsystem=level", item)
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		levels[strings.TrimSpace(subsystem)] = level
+	}
+	return levels, nil
+}
+
+// Config selects the output format and the level of each subsystem
+type Config struct {
+	Format string           `json:"format"`
+	Level  Level            `json:"level"`
+	Levels map[string]Level `json:"levels,omitempty"` // overrides Level per subsystem
+}
+
+// level returns the level in effect for a subsystem
+func (c *Config) level(subsystem string) Level {
+	if level, ok := c.Levels[subsystem]; ok {
+		return level
+	}
+	return c.Level
+}
+
+// config is the configuration in effect; it can be replaced at any time
+var config atomic.Pointer[Config]
+
+// output receives formatted entries; writes are serialized so lines never interleave
+var (
+	output   io.Writer = os.Stderr
+	outputMu sync.Mutex
+)
+
+func init() {
+	config.Store(&Config{Format: FormatText, Level: LevelInfo})
+}
+
+// Configure replaces the format and levels; loggers pick them up immediately
+func Configure(c Config) {
+	if c.Format != FormatJSON {
+		c.Format = FormatText
+	}
+	config.Store(&c)
+}
+
+// Current returns the configuration in effect
+func Current() Config {
+	return *config.Load()
+}
+
+// Logger writes leveled entries for one subsystem
+type Logger struct {
+	subsystem string
+}
+
+// New returns the logger of a subsystem
+func New(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+// Enabled reports whether entries at a level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= config.Load().level(l.subsystem)
+}
+
+// Debugf logs detail useful when troubleshooting
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+// Infof logs normal operation
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+// Warnf logs unexpected conditions the server recovers from
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+// Errorf logs failures
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
+
+// Fatalf logs a failure and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.write(LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+// logf formats and writes an entry if its level is enabled
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.write(level, fmt.Sprintf(format, args...))
+}
+
+// entry is the JSON form of a log line
+type entry struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Subsystem string `json:"subsystem"`
+	Message   string `json:"msg"`
+}
+
+// write formats an entry in the configured format
+func (l *Logger) write(level Level, message string) {
+	now := time.Now()
+	message = strings.TrimRight(message, "\n")
+
+	var line []byte
+	if config.Load().Format == FormatJSON {
+		line, _ = json.Marshal(entry{
+			Time:      now.UTC().Format(time.RFC3339Nano),
+			Level:     level.String(),
+			Subsystem: l.subsystem,
+			Message:   message,
+		})
+		line = append(line, '\n')
+	} else {
+		line = []byte(fmt.Sprintf("%s %-5s [%s] %s\n", now.Format("2006/01/02 15:04:05"), strings.ToUpper(level.String()), l.subsystem, message))
+	}
+
+	outputMu.Lock()
+	output.Write(line)
+	outputMu.Unlock()
+}
+
+// Writer returns an io.Writer logging each write as an entry of a subsystem at a level,
+// for routing the standard library logger and third-party output through the same sink
+func (l *Logger) Writer(level Level) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		l.logf(level, "%s", p)
+		return len(p), nil
+	})
+}
+
+// writerFunc adapts a function to io.Writer
+type writerFunc func(p []byte) (int, error)
+
+// Write calls the function
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,296 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	pionmedia "github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/ivfreader"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+)
+
+// Default frame durations used before the reader reports real timestamps
+const (
+	defaultVideoFrame = 33 * time.Millisecond
+	defaultAudioFrame = 20 * time.Millisecond
+
+	// Opus granule positions are expressed in 48 kHz samples
+	opusSampleRate = 48000
+)
+
+// frameReader reads timestamped frames from a container
+type frameReader interface {
+	next() ([]byte, time.Duration, error)
+}
+
+// ivfFrameReader adapts ivfreader to frameReader
+type ivfFrameReader struct {
+	reader *ivfreader.IVFReader
+	header *ivfreader.IVFFileHeader
+}
+
+func (r *ivfFrameReader) next() ([]byte, time.Duration, error) {
+	frame, header, err := r.reader.ParseNextFrame()
+	if err != nil {
+		return nil, 0, err
+	}
+	pts := time.Duration(float64(header.Timestamp) * float64(r.header.TimebaseNumerator) / float64(r.header.TimebaseDenominator) * float64(time.Second))
+	return frame, pts, nil
+}
+
+// oggFrameReader adapts oggreader to frameReader
+type oggFrameReader struct {
+	reader *oggreader.OggReader
+}
+
+func (r *oggFrameReader) next() ([]byte, time.Duration, error) {
+	page, header, err := r.reader.ParseNextPage()
+	if err != nil {
+		return nil, 0, err
+	}
+	pts := time.Duration(header.GranulePosition) * time.Second / opusSampleRate
+	return page, pts, nil
+}
+
+// FilePlayer publishes a pre-recorded IVF (VP8/VP9) or Ogg (Opus) file
+type FilePlayer struct {
+	path  string
+	loop  bool
+	track *webrtc.TrackLocalStaticSample
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+	position time.Duration
+}
+
+// NewFilePlayer creates a player for the given file; the track ID and stream ID are taken from id
+func NewFilePlayer(path, id string, loop bool) (*FilePlayer, error) {
+	mimeType, err := detectMimeType(path)
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: mimeType}, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create track: %v", err)
+	}
+
+	return &FilePlayer{
+		path:  path,
+		loop:  loop,
+		track: track,
+	}, nil
+}
+
+// detectMimeType determines the codec of a media file from its extension and header
+func detectMimeType(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ogg", ".opus":
+		return webrtc.MimeTypeOpus, nil
+	case ".ivf":
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		_, header, err := ivfreader.NewWith(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read IVF header: %v", err)
+		}
+
+		switch header.FourCC {
+		case "VP80":
+			return webrtc.MimeTypeVP8, nil
+		case "VP90":
+			return webrtc.MimeTypeVP9, nil
+		}
+	}
+
+	return "", ErrUnsupportedFormat
+}
+
+// Track returns the local track
+func (p *FilePlayer) Track() webrtc.TrackLocal {
+	return p.track
+}
+
+// Start begins playback from the current position
+func (p *FilePlayer) Start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx, p.done, p.position)
+
+	return nil
+}
+
+// Stop pauses playback
+func (p *FilePlayer) Stop() error {
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel = nil
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	return nil
+}
+
+// Seek moves the playback position, restarting playback if it was running
+func (p *FilePlayer) Seek(position time.Duration) error {
+	if position < 0 {
+		return fmt.Errorf("invalid position: %s", position)
+	}
+
+	running := p.Running()
+	if err := p.Stop(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.position = position
+	p.mu.Unlock()
+
+	if running {
+		return p.Start()
+	}
+	return nil
+}
+
+// Position returns the current playback position
+func (p *FilePlayer) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.position
+}
+
+// Running reports whether playback is in progress
+func (p *FilePlayer) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.cancel != nil
+}
+
+// Close stops playback
+func (p *FilePlayer) Close() error {
+	return p.Stop()
+}
+
+// run plays the file until cancelled, looping if configured
+func (p *FilePlayer) run(ctx context.Context, done chan struct{}, start time.Duration) {
+	defer close(done)
+
+	for {
+		err := p.playOnce(ctx, start)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			logger.Errorf("Media playback of %s failed: %v", p.path, err)
+		}
+		if err == nil || !errors.Is(err, io.EOF) || !p.loop {
+			break
+		}
+		start = 0
+	}
+
+	// Playback reached the end on its own: rewind and mark as stopped
+	p.mu.Lock()
+	if p.done == done {
+		p.cancel = nil
+		p.position = 0
+	}
+	p.mu.Unlock()
+}
+
+// playOnce plays the file a single time starting at the given position
+func (p *FilePlayer) playOnce(ctx context.Context, start time.Duration) error {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, frameDuration, err := p.openReader(file)
+	if err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+	last := time.Duration(-1)
+	for {
+		data, pts, err := reader.next()
+		if err != nil {
+			return err
+		}
+		if pts < start {
+			last = pts
+			continue
+		}
+
+		// Pace frames by their presentation timestamps
+		timer := time.NewTimer(time.Until(startedAt.Add(pts - start)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		duration := frameDuration
+		if last >= 0 && pts > last {
+			duration = pts - last
+		}
+		last = pts
+
+		if err := p.track.WriteSample(pionmedia.Sample{Data: data, Duration: duration}); err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		p.position = pts
+		p.mu.Unlock()
+	}
+}
+
+// openReader creates a frame reader matching the track codec
+func (p *FilePlayer) openReader(file io.Reader) (frameReader, time.Duration, error) {
+	if p.track.Kind() == webrtc.RTPCodecTypeAudio {
+		reader, _, err := oggreader.NewWith(file)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &oggFrameReader{reader: reader}, defaultAudioFrame, nil
+	}
+
+	reader, header, err := ivfreader.NewWith(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	if header.TimebaseDenominator == 0 {
+		return nil, 0, ErrUnsupportedFormat
+	}
+	return &ivfFrameReader{reader: reader, header: header}, defaultVideoFrame, nil
+}
@@ -0,0 +1,40 @@
+package media
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	// ErrSeekNotSupported is returned when seeking a live source
+	ErrSeekNotSupported = errors.New("seek is not supported for this source")
+
+	// ErrUnsupportedFormat is returned for media files the player cannot read
+	ErrUnsupportedFormat = errors.New("unsupported media format")
+)
+
+// Source is a server-side media producer published into a room as a local track
+type Source interface {
+	// Track returns the local track the source writes media into
+	Track() webrtc.TrackLocal
+
+	// Start begins (or resumes) publishing media
+	Start() error
+
+	// Stop pauses publishing, keeping the current position
+	Stop() error
+
+	// Seek moves the playback position
+	Seek(position time.Duration) error
+
+	// Position returns the current playback position
+	Position() time.Duration
+
+	// Running reports whether the source is currently publishing
+	Running() bool
+
+	// Close stops the source and releases its resources
+	Close() error
+}
@@ -0,0 +1,281 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/logging"
+)
+
+// logger writes the sfu subsystem log
+var logger = logging.New(logging.SFU)
+
+const (
+	// rtpBufferSize is large enough for a single RTP packet produced by FFmpeg
+	rtpBufferSize = 1500
+
+	// probeTimeout limits how long ffprobe may take to inspect a stream
+	probeTimeout = 15 * time.Second
+)
+
+// StreamSource pulls a live RTSP/RTMP stream through FFmpeg and publishes it as a video track
+type StreamSource struct {
+	url   string
+	track *webrtc.TrackLocalStaticRTP
+
+	// copyCodec forwards the original bitstream instead of transcoding to VP8
+	copyCodec bool
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	conn      *net.UDPConn
+	done      chan struct{}
+	startedAt time.Time
+}
+
+// NewStreamSource creates a source for a live stream URL. With passthrough set, the stream
+// is probed and H.264/VP8 video is forwarded as-is; other codecs are transcoded to VP8.
+func NewStreamSource(streamURL, id string, passthrough bool) (*StreamSource, error) {
+	parsed, err := url.Parse(streamURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream URL: %v", err)
+	}
+
+	switch parsed.Scheme {
+	case "rtsp", "rtsps", "rtmp", "rtmps":
+	default:
+		return nil, fmt.Errorf("unsupported stream scheme: %q", parsed.Scheme)
+	}
+
+	mimeType, copyCodec := webrtc.MimeTypeVP8, false
+	if passthrough {
+		codec, err := probeVideoCodec(streamURL)
+		if err != nil {
+			return nil, err
+		}
+		switch codec {
+		case "h264":
+			mimeType, copyCodec = webrtc.MimeTypeH264, true
+		case "vp8":
+			copyCodec = true
+		}
+	}
+
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: mimeType}, id, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create track: %v", err)
+	}
+
+	return &StreamSource{
+		url:       streamURL,
+		track:     track,
+		copyCodec: copyCodec,
+	}, nil
+}
+
+// ffprobePath returns the ffprobe binary to use
+func ffprobePath() string {
+	if path := os.Getenv("FFPROBE_PATH"); path != "" {
+		return path
+	}
+	return "ffprobe"
+}
+
+// probeVideoCodec returns the codec name of the first video stream, e.g. "h264"
+func probeVideoCodec(streamURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	args := []string{"-v", "error"}
+	if isRTSP(streamURL) {
+		args = append(args, "-rtsp_transport", "tcp")
+	}
+	args = append(args, "-select_streams", "v:0", "-show_entries", "stream=codec_name", "-of", "csv=p=0", streamURL)
+
+	out, err := exec.CommandContext(ctx, ffprobePath(), args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to probe stream: %v", err)
+	}
+
+	codec := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	if codec == "" {
+		return "", fmt.Errorf("stream has no video: %s", streamURL)
+	}
+	return codec, nil
+}
+
+// isRTSP reports whether the URL uses an RTSP scheme
+func isRTSP(streamURL string) bool {
+	parsed, err := url.Parse(streamURL)
+	return err == nil && (parsed.Scheme == "rtsp" || parsed.Scheme == "rtsps")
+}
+
+// ffmpegPath returns the FFmpeg binary to use
+func ffmpegPath() string {
+	if path := os.Getenv("FFMPEG_PATH"); path != "" {
+		return path
+	}
+	return "ffmpeg"
+}
+
+// ffmpegArgs builds the FFmpeg command line sending RTP to the given local port
+func (s *StreamSource) ffmpegArgs(port int) []string {
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if isRTSP(s.url) {
+		args = append(args, "-rtsp_transport", "tcp")
+	}
+	args = append(args, "-i", s.url, "-an")
+
+	switch {
+	case s.copyCodec && s.track.Codec().MimeType == webrtc.MimeTypeH264:
+		args = append(args, "-c:v", "copy", "-bsf:v", "h264_mp4toannexb")
+	case s.copyCodec:
+		args = append(args, "-c:v", "copy")
+	default:
+		args = append(args,
+			"-c:v", "libvpx",
+			"-deadline", "realtime",
+			"-cpu-used", "8",
+			"-b:v", "1M",
+			"-g", "60",
+		)
+	}
+
+	return append(args,
+		"-f", "rtp",
+		fmt.Sprintf("rtp://127.0.0.1:%d?pkt_size=1200", port),
+	)
+}
+
+// Track returns the local track
+func (s *StreamSource) Track() webrtc.TrackLocal {
+	return s.track
+}
+
+// MimeType returns the codec the stream is published with
+func (s *StreamSource) MimeType() string {
+	return s.track.Codec().MimeType
+}
+
+// Start launches FFmpeg and begins forwarding RTP packets
+func (s *StreamSource) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != nil {
+		return nil
+	}
+
+	// Listen for RTP packets from FFmpeg on an ephemeral local port
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return fmt.Errorf("failed to open RTP listener: %v", err)
+	}
+
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	cmd := exec.Command(ffmpegPath(), s.ffmpegArgs(port)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start ffmpeg: %v", err)
+	}
+
+	s.cmd = cmd
+	s.conn = conn
+	s.done = make(chan struct{})
+	s.startedAt = time.Now()
+
+	go s.forward(conn, s.done)
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			logger.Errorf("FFmpeg for %s exited: %v", s.url, err)
+		}
+		conn.Close()
+
+		// FFmpeg exited on its own: mark the source as stopped
+		s.mu.Lock()
+		if s.cmd == cmd {
+			s.cmd = nil
+			s.conn = nil
+		}
+		s.mu.Unlock()
+	}()
+
+	return nil
+}
+
+// forward copies RTP packets from FFmpeg into the track
+func (s *StreamSource) forward(conn *net.UDPConn, done chan struct{}) {
+	defer close(done)
+
+	buf := make([]byte, rtpBufferSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if _, err := s.track.Write(buf[:n]); err != nil {
+			logger.Errorf("Failed to forward RTP from %s: %v", s.url, err)
+			return
+		}
+	}
+}
+
+// Stop terminates FFmpeg
+func (s *StreamSource) Stop() error {
+	s.mu.Lock()
+	cmd, conn, done := s.cmd, s.conn, s.done
+	s.cmd = nil
+	s.conn = nil
+	s.mu.Unlock()
+
+	if cmd == nil {
+		return nil
+	}
+
+	if cmd.Process != nil {
+		cmd.Process.Kill()
+	}
+	conn.Close()
+	<-done
+
+	return nil
+}
+
+// Seek is not supported for live streams
+func (s *StreamSource) Seek(position time.Duration) error {
+	return ErrSeekNotSupported
+}
+
+// Position returns how long the stream has been running
+func (s *StreamSource) Position() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd == nil {
+		return 0
+	}
+	return time.Since(s.startedAt)
+}
+
+// Running reports whether FFmpeg is running
+func (s *StreamSource) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cmd != nil
+}
+
+// Close stops the stream
+func (s *StreamSource) Close() error {
+	return s.Stop()
+}
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"sync"
+	
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -8,7 +10,7 @@ import (
 // Metrics holds all the application metrics
 type Metrics struct {
 	// Room metrics
-	RoomsCreatedTotal     prometheus.Counter
+	RoomsCreatedTotal     *prometheus.CounterVec
 	RoomsActive           prometheus.Gauge
 	RoomParticipants      prometheus.GaugeVec
 	
@@ -21,18 +23,69 @@ type Metrics struct {
 	WebSocketMessagesSent *prometheus.CounterVec
 	WebSocketErrorsTotal  prometheus.Counter
 	
+	// Send queue metrics
+	SignalMessagesDroppedTotal   *prometheus.CounterVec
+	SlowConsumerDisconnectsTotal *prometheus.CounterVec
+	
 	// Call metrics
 	CallsStartedTotal     prometheus.Counter
 	CallsActive           prometheus.Gauge
 	CallDurationSeconds   prometheus.Histogram
 	
 	// Recording metrics
-	RecordingsStartedTotal prometheus.Counter
+	RecordingsStartedTotal *prometheus.CounterVec
 	RecordingsCompletedTotal prometheus.Counter
 	RecordingErrorsTotal   prometheus.Counter
 	
 	// Chat metrics
-	ChatMessagesSentTotal prometheus.Counter
+	ChatMessagesSentTotal *prometheus.CounterVec
+	
+	// Tenant metrics
+	TenantRequestsThrottledTotal *prometheus.CounterVec
+	
+	// Abuse metrics
+	SpamDetectionsTotal *prometheus.CounterVec
+	
+	// Call queue metrics
+	QueueWaitSeconds    *prometheus.HistogramVec
+	QueueCallsTotal     *prometheus.CounterVec
+	QueueCallersWaiting *prometheus.GaugeVec
+	
+	// SFU forwarding metrics
+	SFUPacketsForwardedTotal  *prometheus.CounterVec
+	SFUBytesForwardedTotal    *prometheus.CounterVec
+	SFUPacketsDroppedTotal    *prometheus.CounterVec
+	SFUNacksTotal             *prometheus.CounterVec
+	SFUPLIsTotal              *prometheus.CounterVec
+	SFUTracksActive           *prometheus.GaugeVec
+	SFUBandwidthBitsPerSecond prometheus.Gauge
+	
+	// HTTP API metrics
+	HTTPRequestsTotal          *prometheus.CounterVec
+	HTTPRequestDurationSeconds *prometheus.HistogramVec
+	HTTPRequestsInFlight       prometheus.Gauge
+	
+	// Room lifetime and concurrency metrics
+	RoomLifetimeSeconds        prometheus.Histogram
+	RoomParticipantsAtClose    prometheus.Histogram
+	ParticipantsConcurrent     prometheus.Gauge
+	ParticipantsConcurrentPeak prometheus.Gauge
+	
+	// Email metrics
+	EmailsSentTotal   *prometheus.CounterVec
+	EmailsFailedTotal *prometheus.CounterVec
+	EmailRetriesTotal *prometheus.CounterVec
+	EmailQueueDepth   prometheus.Gauge
+	
+	// Peer connection recovery metrics
+	ICERestartsTotal            prometheus.Counter
+	PeerRecoveriesTotal         *prometheus.CounterVec
+	PeerRecoveryDurationSeconds prometheus.Histogram
+	
+	// Current and peak participants behind the concurrency gauges
+	participants     int
+	participantsPeak int
+	participantsMu   sync.Mutex
 }
 
 // AppMetrics is the global metrics instance
@@ -41,10 +94,10 @@ var AppMetrics *Metrics
 func init() {
 	AppMetrics = &Metrics{
 		// Room metrics
-		RoomsCreatedTotal: promauto.NewCounter(prometheus.CounterOpts{
+		RoomsCreatedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "video_call_rooms_created_total",
 			Help: "Total number of rooms created",
-		}),
+		}, []string{"tenant"}),
 		RoomsActive: promauto.NewGauge(prometheus.GaugeOpts{
 			Name: "video_call_rooms_active",
 			Help: "Number of active rooms",
@@ -78,6 +131,16 @@ func init() {
 			Help: "Total number of WebSocket errors",
 		}),
 		
+		// Send queue metrics
+		SignalMessagesDroppedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_signal_messages_dropped_total",
+			Help: "Total number of messages dropped because a connection send queue was full",
+		}, []string{"transport", "message_type", "policy"}),
+		SlowConsumerDisconnectsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_slow_consumer_disconnects_total",
+			Help: "Total number of connections disconnected for not keeping up with their send queue",
+		}, []string{"transport"}),
+		
 		// Call metrics
 		CallsStartedTotal: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "video_call_calls_started_total",
@@ -94,10 +157,10 @@ func init() {
 		}),
 		
 		// Recording metrics
-		RecordingsStartedTotal: promauto.NewCounter(prometheus.CounterOpts{
+		RecordingsStartedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "video_call_recordings_started_total",
 			Help: "Total number of recordings started",
-		}),
+		}, []string{"tenant"}),
 		RecordingsCompletedTotal: promauto.NewCounter(prometheus.CounterOpts{
 			Name: "video_call_recordings_completed_total",
 			Help: "Total number of recordings completed",
@@ -108,16 +171,149 @@ func init() {
 		}),
 		
 		// Chat metrics
-		ChatMessagesSentTotal: promauto.NewCounter(prometheus.CounterOpts{
+		ChatMessagesSentTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "video_call_chat_messages_sent_total",
 			Help: "Total number of chat messages sent",
+		}, []string{"tenant"}),
+		
+		// Tenant metrics
+		TenantRequestsThrottledTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_tenant_requests_throttled_total",
+			Help: "Total number of requests refused because their tenant exceeded its rate limit",
+		}, []string{"tenant"}),
+		
+		// Abuse metrics
+		SpamDetectionsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_spam_detections_total",
+			Help: "Total number of chat and signaling messages flagged as flooding or spam, by reason",
+		}, []string{"reason"}),
+		
+		// Call queue metrics
+		QueueWaitSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "video_call_queue_wait_seconds",
+			Help:    "Time callers waited in a call queue until an agent took their call",
+			Buckets: []float64{5, 15, 30, 60, 120, 300, 600, 1200, 1800},
+		}, []string{"queue"}),
+		QueueCallsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_queue_calls_total",
+			Help: "Total number of callers leaving a call queue by result (answered, abandoned)",
+		}, []string{"queue", "result"}),
+		QueueCallersWaiting: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "video_call_queue_callers_waiting",
+			Help: "Number of callers waiting in a call queue",
+		}, []string{"queue"}),
+		
+		// SFU forwarding metrics
+		SFUPacketsForwardedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_sfu_packets_forwarded_total",
+			Help: "Total number of RTP packets received from publishers and forwarded to subscribers",
+		}, []string{"room_id", "kind"}),
+		SFUBytesForwardedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_sfu_bytes_forwarded_total",
+			Help: "Total number of RTP payload bytes forwarded",
+		}, []string{"room_id", "kind"}),
+		SFUPacketsDroppedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_sfu_packets_dropped_total",
+			Help: "Total number of RTP packets lost before reaching the server or dropped while forwarding",
+		}, []string{"room_id", "reason"}),
+		SFUNacksTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_sfu_nacks_total",
+			Help: "Total number of NACKs received from subscribers",
+		}, []string{"room_id"}),
+		SFUPLIsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_sfu_plis_total",
+			Help: "Total number of picture loss indications received from subscribers or sent to publishers",
+		}, []string{"room_id", "direction"}),
+		SFUTracksActive: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "video_call_sfu_tracks_active",
+			Help: "Number of tracks being forwarded",
+		}, []string{"room_id", "kind"}),
+		SFUBandwidthBitsPerSecond: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "video_call_sfu_bandwidth_bits_per_second",
+			Help: "Media bandwidth of the node, sent and received, over the last load sample",
+		}),
+		
+		// HTTP API metrics
+		HTTPRequestsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_http_requests_total",
+			Help: "Total number of HTTP requests",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDurationSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "video_call_http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		HTTPRequestsInFlight: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "video_call_http_requests_in_flight",
+			Help: "Number of HTTP requests being served",
+		}),
+		
+		// Room lifetime and concurrency metrics
+		RoomLifetimeSeconds: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "video_call_room_lifetime_seconds",
+			Help:    "Time from room creation until the room is closed or archived",
+			Buckets: prometheus.ExponentialBuckets(60, 2, 12), // 1m, 2m, 4m, ..., ~34h
 		}),
+		RoomParticipantsAtClose: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "video_call_room_participants_at_close",
+			Help:    "Distinct participants of a room's call, observed when the room is closed",
+			Buckets: []float64{0, 1, 2, 3, 5, 10, 20, 50, 100, 200, 500},
+		}),
+		ParticipantsConcurrent: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "video_call_participants_concurrent",
+			Help: "Number of participants in all rooms of the node",
+		}),
+		ParticipantsConcurrentPeak: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "video_call_participants_concurrent_peak",
+			Help: "Highest number of concurrent participants since the node started",
+		}),
+		
+		// Email metrics
+		EmailsSentTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_emails_sent_total",
+			Help: "Total number of emails delivered to the SMTP server",
+		}, []string{"template"}),
+		EmailsFailedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_emails_failed_total",
+			Help: "Total number of emails dropped or given up after all delivery attempts",
+		}, []string{"template"}),
+		EmailRetriesTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_email_retries_total",
+			Help: "Total number of retried email deliveries",
+		}, []string{"template"}),
+		EmailQueueDepth: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "video_call_email_queue_depth",
+			Help: "Number of emails waiting to be sent",
+		}),
+		
+		// Peer connection recovery metrics
+		ICERestartsTotal: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "video_call_ice_restarts_total",
+			Help: "Total number of ICE restarts attempted on failed server-side peer connections",
+		}),
+		PeerRecoveriesTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "video_call_peer_recoveries_total",
+			Help: "Total number of failed server-side peer connections by recovery result (recovered, failed)",
+		}, []string{"result"}),
+		PeerRecoveryDurationSeconds: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "video_call_peer_recovery_duration_seconds",
+			Help:    "Time from the failure of a peer connection until ICE restarts recovered it",
+			Buckets: []float64{1, 2, 5, 10, 20, 30, 60},
+		}),
+	}
+}
+
+// TenantLabel is the metrics label of a tenant; the default tenant has an empty ID
+func TenantLabel(tenantID string) string {
+	if tenantID == "" {
+		return "default"
 	}
+	return tenantID
 }
 
-// IncrementRoomsCreated increments the rooms created counter
-func (m *Metrics) IncrementRoomsCreated() {
-	m.RoomsCreatedTotal.Inc()
+// IncrementRoomsCreated increments the rooms created counter of a tenant
+func (m *Metrics) IncrementRoomsCreated(tenantID string) {
+	m.RoomsCreatedTotal.WithLabelValues(TenantLabel(tenantID)).Inc()
 }
 
 // SetRoomsActive sets the number of active rooms
@@ -155,6 +351,16 @@ func (m *Metrics) IncrementWebSocketErrors() {
 	m.WebSocketErrorsTotal.Inc()
 }
 
+// IncrementSignalMessagesDropped increments the dropped messages counter
+func (m *Metrics) IncrementSignalMessagesDropped(transport, messageType, policy string) {
+	m.SignalMessagesDroppedTotal.WithLabelValues(transport, messageType, policy).Inc()
+}
+
+// IncrementSlowConsumerDisconnects increments the slow consumer disconnects counter
+func (m *Metrics) IncrementSlowConsumerDisconnects(transport string) {
+	m.SlowConsumerDisconnectsTotal.WithLabelValues(transport).Inc()
+}
+
 // IncrementCallsStarted increments the calls started counter
 func (m *Metrics) IncrementCallsStarted() {
 	m.CallsStartedTotal.Inc()
@@ -170,9 +376,9 @@ func (m *Metrics) ObserveCallDuration(duration float64) {
 	m.CallDurationSeconds.Observe(duration)
 }
 
-// IncrementRecordingsStarted increments the recordings started counter
-func (m *Metrics) IncrementRecordingsStarted() {
-	m.RecordingsStartedTotal.Inc()
+// IncrementRecordingsStarted increments the recordings started counter of a tenant
+func (m *Metrics) IncrementRecordingsStarted(tenantID string) {
+	m.RecordingsStartedTotal.WithLabelValues(TenantLabel(tenantID)).Inc()
 }
 
 // IncrementRecordingsCompleted increments the recordings completed counter
@@ -185,7 +391,147 @@ func (m *Metrics) IncrementRecordingErrors() {
 	m.RecordingErrorsTotal.Inc()
 }
 
-// IncrementChatMessagesSent increments the chat messages sent counter
-func (m *Metrics) IncrementChatMessagesSent() {
-	m.ChatMessagesSentTotal.Inc()
-}
\ No newline at end of file
+// IncrementChatMessagesSent increments the chat messages sent counter of a tenant
+func (m *Metrics) IncrementChatMessagesSent(tenantID string) {
+	m.ChatMessagesSentTotal.WithLabelValues(TenantLabel(tenantID)).Inc()
+}
+
+// IncrementTenantRequestsThrottled counts a request refused by its tenant's rate limit
+func (m *Metrics) IncrementTenantRequestsThrottled(tenantID string) {
+	m.TenantRequestsThrottledTotal.WithLabelValues(TenantLabel(tenantID)).Inc()
+}
+
+// IncrementSpamDetections counts a message flagged by flood detection
+func (m *Metrics) IncrementSpamDetections(reason string) {
+	m.SpamDetectionsTotal.WithLabelValues(reason).Inc()
+}
+
+// ObserveQueueAnswered records the wait of a caller whose call an agent took
+func (m *Metrics) ObserveQueueAnswered(queueID string, wait float64) {
+	m.QueueWaitSeconds.WithLabelValues(queueID).Observe(wait)
+	m.QueueCallsTotal.WithLabelValues(queueID, "answered").Inc()
+}
+
+// IncrementQueueAbandoned counts a caller who left a call queue before its call was taken
+func (m *Metrics) IncrementQueueAbandoned(queueID string) {
+	m.QueueCallsTotal.WithLabelValues(queueID, "abandoned").Inc()
+}
+
+// SetQueueCallersWaiting sets the number of callers waiting in a call queue
+func (m *Metrics) SetQueueCallersWaiting(queueID string, count float64) {
+	m.QueueCallersWaiting.WithLabelValues(queueID).Set(count)
+}
+
+// ForgetQueue drops the metrics of a deleted call queue
+func (m *Metrics) ForgetQueue(queueID string) {
+	m.QueueWaitSeconds.DeleteLabelValues(queueID)
+	m.QueueCallsTotal.DeletePartialMatch(prometheus.Labels{"queue": queueID})
+	m.QueueCallersWaiting.DeleteLabelValues(queueID)
+}
+
+// ObserveForwardedPacket counts an RTP packet forwarded in a room
+func (m *Metrics) ObserveForwardedPacket(roomID, kind string, bytes int) {
+	m.SFUPacketsForwardedTotal.WithLabelValues(roomID, kind).Inc()
+	m.SFUBytesForwardedTotal.WithLabelValues(roomID, kind).Add(float64(bytes))
+}
+
+// AddPacketsDropped counts RTP packets lost or dropped in a room
+func (m *Metrics) AddPacketsDropped(roomID, reason string, count int) {
+	m.SFUPacketsDroppedTotal.WithLabelValues(roomID, reason).Add(float64(count))
+}
+
+// IncrementNacks increments the NACKs counter of a room
+func (m *Metrics) IncrementNacks(roomID string) {
+	m.SFUNacksTotal.WithLabelValues(roomID).Inc()
+}
+
+// IncrementPLIs increments the picture loss indications counter of a room
+func (m *Metrics) IncrementPLIs(roomID, direction string) {
+	m.SFUPLIsTotal.WithLabelValues(roomID, direction).Inc()
+}
+
+// AddTracksActive adjusts the number of forwarded tracks in a room
+func (m *Metrics) AddTracksActive(roomID, kind string, delta float64) {
+	m.SFUTracksActive.WithLabelValues(roomID, kind).Add(delta)
+}
+
+// SetBandwidth sets the media bandwidth of the node
+func (m *Metrics) SetBandwidth(bitsPerSecond float64) {
+	m.SFUBandwidthBitsPerSecond.Set(bitsPerSecond)
+}
+
+// ForgetRoom removes the forwarding counters of a room that has ended. The track gauge
+// is kept, since tracks are unpublished asynchronously and must still count down to zero.
+func (m *Metrics) ForgetRoom(roomID string) {
+	labels := prometheus.Labels{"room_id": roomID}
+	m.SFUPacketsForwardedTotal.DeletePartialMatch(labels)
+	m.SFUBytesForwardedTotal.DeletePartialMatch(labels)
+	m.SFUPacketsDroppedTotal.DeletePartialMatch(labels)
+	m.SFUNacksTotal.DeletePartialMatch(labels)
+	m.SFUPLIsTotal.DeletePartialMatch(labels)
+}
+
+// AddHTTPRequestsInFlight adjusts the number of HTTP requests being served
+func (m *Metrics) AddHTTPRequestsInFlight(delta float64) {
+	m.HTTPRequestsInFlight.Add(delta)
+}
+
+// ObserveHTTPRequest records a served HTTP request and its latency
+func (m *Metrics) ObserveHTTPRequest(method, route, status string, seconds float64) {
+	m.HTTPRequestsTotal.WithLabelValues(method, route, status).Inc()
+	m.HTTPRequestDurationSeconds.WithLabelValues(method, route, status).Observe(seconds)
+}
+
+// ObserveRoomClosed records the lifetime and the number of distinct participants of a closed room
+func (m *Metrics) ObserveRoomClosed(lifetimeSeconds float64, participants int) {
+	m.RoomLifetimeSeconds.Observe(lifetimeSeconds)
+	m.RoomParticipantsAtClose.Observe(float64(participants))
+}
+
+// AddParticipants adjusts the number of concurrent participants, raising the peak when exceeded
+func (m *Metrics) AddParticipants(delta int) {
+	m.participantsMu.Lock()
+	defer m.participantsMu.Unlock()
+	
+	m.participants += delta
+	if m.participants > m.participantsPeak {
+		m.participantsPeak = m.participants
+		m.ParticipantsConcurrentPeak.Set(float64(m.participantsPeak))
+	}
+	m.ParticipantsConcurrent.Set(float64(m.participants))
+}
+
+// IncrementEmailsSent increments the delivered emails counter of a template
+func (m *Metrics) IncrementEmailsSent(template string) {
+	m.EmailsSentTotal.WithLabelValues(template).Inc()
+}
+
+// IncrementEmailsFailed increments the failed emails counter of a template
+func (m *Metrics) IncrementEmailsFailed(template string) {
+	m.EmailsFailedTotal.WithLabelValues(template).Inc()
+}
+
+// IncrementEmailRetries increments the retried deliveries counter of a template
+func (m *Metrics) IncrementEmailRetries(template string) {
+	m.EmailRetriesTotal.WithLabelValues(template).Inc()
+}
+
+// SetEmailQueueDepth sets the number of emails waiting to be sent
+func (m *Metrics) SetEmailQueueDepth(depth float64) {
+	m.EmailQueueDepth.Set(depth)
+}
+
+// IncrementICERestarts increments the ICE restarts counter
+func (m *Metrics) IncrementICERestarts() {
+	m.ICERestartsTotal.Inc()
+}
+
+// IncrementPeerRecoveries increments the peer recoveries counter of a result
+func (m *Metrics) IncrementPeerRecoveries(result string) {
+	m.PeerRecoveriesTotal.WithLabelValues(result).Inc()
+}
+
+// ObservePeerRecovery records how long recovering a peer connection took
+func (m *Metrics) ObservePeerRecovery(seconds float64) {
+	m.PeerRecoveryDurationSeconds.Observe(seconds)
+}
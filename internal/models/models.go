@@ -18,27 +18,110 @@ type User struct {
 
 // Room представляет собой комнату для видеозвонка
 type Room struct {
-	ID          string             `json:"id"`
-	Name        string             `json:"name"`
-	CreatorID   string             `json:"creator_id"`
-	Clients     map[string]*Client `json:"clients"`
-	ChatHistory []ChatMessage      `json:"chat_history"`
-	CreatedAt   time.Time          `json:"created_at"`
-	IsActive    bool               `json:"is_active"`
+	ID          string                     `json:"id"`
+	Name        string                     `json:"name"`
+	CreatorID   string                     `json:"creator_id"`
+	TenantID    string                     `json:"tenant_id,omitempty"` // клиент (арендатор), которому принадлежит комната; пусто — арендатор по умолчанию
+	Clients     map[string]*Client         `json:"clients"`
+	ChatHistory []ChatMessage              `json:"chat_history"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	IsActive    bool                       `json:"is_active"`
+	IsPublic    bool                       `json:"is_public"` // отображается в публичном каталоге комнат
+	JoinCode    string                     `json:"join_code"` // короткий код входа вида abc-defg-hij
+	Settings    RoomSettings               `json:"settings"`
+	Schedule    *RoomSchedule              `json:"schedule,omitempty"` // расписание, если встреча запланирована
+	EmptySince  time.Time                  `json:"-"`                  // когда комнату покинул последний участник; нулевое — в комнате есть участники
+	EndedAt     time.Time                  `json:"ended_at,omitempty"` // когда комната закрыта из-за простоя
+	Tracks      map[string]*PublishedTrack `json:"-"`
+	Version     int64                      `json:"-"`                    // растёт при каждом изменении комнаты через API; из него строится ETag
+	LegalHold   *LegalHold                 `json:"legal_hold,omitempty"` // запрет удаления комнаты, её записей и чата; nil — запрета нет
 	Mu          sync.RWMutex
 }
 
+// LegalHold — юридический запрет удаления данных, который снимает только администратор
+type LegalHold struct {
+	PlacedAt time.Time `json:"placed_at"`
+	PlacedBy string    `json:"placed_by"`        // ID администратора, установившего запрет
+	Reason   string    `json:"reason,omitempty"` // например, номер дела или запроса
+}
+
+// Политики записи комнаты
+const (
+	RecordingManual   = "manual"   // запись запускается вручную
+	RecordingAuto     = "auto"     // запись запускается при входе первого участника
+	RecordingDisabled = "disabled" // запись запрещена
+)
+
+// RoomSettings — настройки комнаты, задаваемые при создании напрямую или шаблоном
+type RoomSettings struct {
+	Lobby             bool     `json:"lobby"`                  // участники ждут, пока войдёт ведущий
+	RecordingPolicy   string   `json:"recording_policy"`       // manual, auto или disabled
+	MaxParticipants   int      `json:"max_participants"`       // 0 — без ограничения
+	VideoCodecs       []string `json:"video_codecs,omitempty"` // пусто — все поддерживаемые кодеки
+	ChatAnnouncements bool     `json:"chat_announcements"`     // системные сообщения о входе и выходе участников в чате
+}
+
+// RoomSchedule — расписание запланированной встречи
+type RoomSchedule struct {
+	StartsAt        time.Time       `json:"starts_at"`                  // начало встречи
+	DurationMinutes int             `json:"duration_minutes"`           // продолжительность в минутах
+	Timezone        string          `json:"timezone"`                   // часовой пояс организатора (IANA), например Europe/Moscow
+	Invitees        []string        `json:"invitees,omitempty"`         // ID приглашённых пользователей
+	ReminderMinutes []int           `json:"reminder_minutes,omitempty"` // за сколько минут до начала напоминать; пусто — по настройкам сервера
+	RemindersSent   map[int]bool    `json:"-"`                          // отправленные напоминания по смещению в минутах
+	Attendees       map[string]bool `json:"-"`                          // пользователи, пришедшие на встречу
+	MissedReported  bool            `json:"-"`                          // отправлено ли событие о пропущенной встрече
+}
+
+// Источники треков, которые клиент объявляет сообщением "track-source"
+const (
+	SourceCamera           = "camera"
+	SourceMicrophone       = "microphone"
+	SourceScreenShare      = "screen_share"
+	SourceScreenShareAudio = "screen_share_audio"
+)
+
+// PublishedTrack представляет серверный медиа-трек, опубликованный в комнате
+type PublishedTrack struct {
+	ID       string                            `json:"id"`
+	ClientID string                            `json:"client_id"`
+	Kind     string                            `json:"kind"`
+	Source   string                            `json:"source,omitempty"` // пусто, если клиент не объявил источник
+	Track    webrtc.TrackLocal                 `json:"-"`
+	Senders  map[string]*webrtc.RTPSender      `json:"-"` // по ID клиента-получателя
+	Relays   map[string]*webrtc.PeerConnection `json:"-"` // ретрансляция на другие узлы кластера, по ID ретрансляции
+}
+
 // Client представляет собой клиента в комнате
 type Client struct {
-	ID          string                 `json:"id"`
-	UserID      string                 `json:"user_id"`
-	Username    string                 `json:"username"`
-	Conn        *webrtc.PeerConnection `json:"-"` // Не сериализуем в JSON
-	WebSocket   *WebSocketConnection   `json:"-"`
-	Signal      chan interface{}       `json:"-"`
-	JoinedAt    time.Time              `json:"joined_at"`
-	IsRecording bool                   `json:"is_recording"`
-	RecordingID string                 `json:"recording_id,omitempty"`
+	ID              string                 `json:"id"`
+	UserID          string                 `json:"user_id"`
+	Username        string                 `json:"username"`
+	DisplayName     string                 `json:"display_name,omitempty"`
+	AvatarURL       string                 `json:"avatar_url,omitempty"`
+	Conn            *webrtc.PeerConnection `json:"-"` // Не сериализуем в JSON
+	WebSocket       *WebSocketConnection   `json:"-"`
+	Signal          chan interface{}       `json:"-"`
+	JoinedAt        time.Time              `json:"joined_at"`
+	IsRecording     bool                   `json:"is_recording"`
+	AudioMuted      bool                   `json:"audio_muted"` // по последнему сообщению "mute" клиента
+	VideoMuted      bool                   `json:"video_muted"`
+	RecordingID     string                 `json:"recording_id,omitempty"`
+	IsBot           bool                   `json:"is_bot"`                     // серверный виртуальный участник
+	SignalDrops     int64                  `json:"-"`                          // число сообщений, потерянных из-за переполнения Signal
+	OfferPending    int32                  `json:"-"`                          // 1, если серверу нужен новый offer после ответа на текущий
+	Permissions     *Permissions           `json:"permissions,omitempty"`      // nil — без ограничений
+	TrackSources    map[string]string      `json:"-"`                          // объявленные источники треков по ID трека
+	CaptionLanguage string                 `json:"caption_language,omitempty"` // язык, на котором участник получает субтитры; пусто — язык речи
+	Hold            string                 `json:"hold,omitempty"`             // причина удержания ("lobby" или "host"); пусто — участник в звонке
+}
+
+// Permissions ограничивает действия участника, вошедшего по токену комнаты
+type Permissions struct {
+	CanPublish   bool `json:"can_publish"`
+	CanSubscribe bool `json:"can_subscribe"`
+	CanChat      bool `json:"can_chat"`
+	IsHost       bool `json:"is_host"`
 }
 
 // WebSocketConnection представляет WebSocket соединение клиента
@@ -0,0 +1,466 @@
+// Package netprobe measures the network path between clients and the server before
+// they join a call. A client creates a probe through the HTTP API and sends numbered
+// packets to the probe listeners over UDP and TCP. The listeners echo every packet
+// and the client acknowledges each echo, which gives the server round-trip time,
+// jitter and packet loss per transport.
+//
+// Probe packets are text: "<probe_id> <seq> <sent_ms>" from the client, echoed back
+// unchanged, then "<probe_id> <seq> ack" once the client has received the echo.
+// Sequence numbers start at 0; sent_ms is the client's clock in milliseconds. Over
+// TCP every packet is a line.
+package netprobe
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/logging"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+// Probe parameters announced to clients
+const (
+	Packets  = 50
+	Interval = 20 * time.Millisecond
+	TTL      = 30 * time.Second
+)
+
+// Recommendations for how a client should join
+const (
+	RecommendVideo     = "video"
+	RecommendAudioOnly = "audio-only"
+	RecommendRelay     = "relay"
+)
+
+// Thresholds above which video is not recommended
+const (
+	maxVideoLossPercent = 10
+	maxVideoRTTMs       = 400
+	maxVideoJitterMs    = 50
+)
+
+const (
+	// sweepInterval is how often expired probes are finished
+	sweepInterval = 5 * time.Second
+
+	// maxPacketSize bounds probe packets
+	maxPacketSize = 128
+
+	// recentResults is how many finished probes are kept for reports
+	recentResults = 100
+)
+
+// TransportResult describes what one transport measured
+type TransportResult struct {
+	Reachable         bool    `json:"reachable"`
+	PacketsReceived   int     `json:"packets_received"`
+	PacketsLost       int     `json:"packets_lost"`
+	PacketLossPercent float64 `json:"packet_loss_percent"`
+	RTTMs             float64 `json:"rtt_ms,omitempty"`
+	JitterMs          float64 `json:"jitter_ms"`
+}
+
+// Result is the outcome of a probe
+type Result struct {
+	ID             string          `json:"id"`
+	UserID         string          `json:"user_id"`
+	ClientIP       string          `json:"client_ip"`
+	StartedAt      time.Time       `json:"started_at"`
+	UDP            TransportResult `json:"udp"`
+	TCP            TransportResult `json:"tcp"`
+	Recommendation string          `json:"recommendation"`
+}
+
+// Summary aggregates finished probes for troubleshooting
+type Summary struct {
+	Probes               int            `json:"probes"`
+	Recommendations      map[string]int `json:"recommendations"`
+	UDPReachablePercent  float64        `json:"udp_reachable_percent"`
+	AvgRTTMs             float64        `json:"avg_rtt_ms"`
+	AvgJitterMs          float64        `json:"avg_jitter_ms"`
+	AvgPacketLossPercent float64        `json:"avg_packet_loss_percent"`
+	Recent               []Result       `json:"recent"`
+}
+
+// transport collects the packets of one probe over one transport
+type transport struct {
+	received    []bool
+	count       int
+	highest     int
+	echoed      map[int]time.Time
+	rttSum      float64
+	rttCount    int
+	lastTransit float64
+	jitter      float64
+}
+
+// newTransport creates an empty transport
+func newTransport() *transport {
+	return &transport{
+		received: make([]bool, Packets),
+		highest:  -1,
+		echoed:   make(map[int]time.Time),
+	}
+}
+
+// packet records a probe packet; it reports whether the packet should be echoed
+func (t *transport) packet(seq int, sentMs int64, now time.Time) bool {
+	if seq < 0 || seq >= len(t.received) {
+		return false
+	}
+	if t.received[seq] {
+		return true
+	}
+	t.received[seq] = true
+
+	// Interarrival jitter as in RFC 3550; the clock offset cancels out
+	transit := float64(now.UnixMilli() - sentMs)
+	if t.count > 0 {
+		d := transit - t.lastTransit
+		if d < 0 {
+			d = -d
+		}
+		t.jitter += (d - t.jitter) / 16
+	}
+	t.lastTransit = transit
+	t.count++
+	if seq > t.highest {
+		t.highest = seq
+	}
+	t.echoed[seq] = now
+	return true
+}
+
+// ack records the client's acknowledgement of an echo
+func (t *transport) ack(seq int, now time.Time) {
+	if echoed, exists := t.echoed[seq]; exists {
+		t.rttSum += float64(now.Sub(echoed).Microseconds()) / 1000
+		t.rttCount++
+		delete(t.echoed, seq)
+	}
+}
+
+// result summarizes the transport
+func (t *transport) result() TransportResult {
+	result := TransportResult{
+		Reachable:       t.count > 0,
+		PacketsReceived: t.count,
+		PacketsLost:     t.highest + 1 - t.count,
+		JitterMs:        t.jitter,
+	}
+	if t.highest >= 0 {
+		result.PacketLossPercent = float64(result.PacketsLost) / float64(t.highest+1) * 100
+	}
+	if t.rttCount > 0 {
+		result.RTTMs = t.rttSum / float64(t.rttCount)
+	}
+	return result
+}
+
+// probe is a running measurement
+type probe struct {
+	id        string
+	userID    string
+	clientIP  string
+	startedAt time.Time
+	expires   time.Time
+	udp       *transport
+	tcp       *transport
+}
+
+// result summarizes the probe and recommends how to join
+func (p *probe) result() Result {
+	result := Result{
+		ID:        p.id,
+		UserID:    p.userID,
+		ClientIP:  p.clientIP,
+		StartedAt: p.startedAt,
+		UDP:       p.udp.result(),
+		TCP:       p.tcp.result(),
+	}
+
+	udp := result.UDP
+	switch {
+	case !udp.Reachable:
+		result.Recommendation = RecommendRelay
+	case udp.PacketLossPercent > maxVideoLossPercent || udp.RTTMs > maxVideoRTTMs || udp.JitterMs > maxVideoJitterMs:
+		result.Recommendation = RecommendAudioOnly
+	default:
+		result.Recommendation = RecommendVideo
+	}
+	return result
+}
+
+// Prober runs the probe listeners and keeps running and finished probes in memory
+type Prober struct {
+	udp *net.UDPConn
+	tcp net.Listener
+
+	probes map[string]*probe
+	recent []Result
+	mu     sync.Mutex
+
+	// Totals over all finished probes; averages cover probes that reached UDP
+	finished        int
+	recommendations map[string]int
+	udpReachable    int
+	rttSum          float64
+	jitterSum       float64
+	lossSum         float64
+}
+
+// NewProber starts listeners on the given UDP and TCP addresses; an empty address
+// disables that transport. It returns nil if both are disabled.
+func NewProber(udpAddr, tcpAddr string) (*Prober, error) {
+	if udpAddr == "" && tcpAddr == "" {
+		return nil, nil
+	}
+
+	p := &Prober{
+		probes:          make(map[string]*probe),
+		recommendations: make(map[string]int),
+	}
+	if udpAddr != "" {
+		addr, err := net.ResolveUDPAddr("udp", udpAddr)
+		if err != nil {
+			return nil, err
+		}
+		if p.udp, err = net.ListenUDP("udp", addr); err != nil {
+			return nil, err
+		}
+	}
+	if tcpAddr != "" {
+		listener, err := net.Listen("tcp", tcpAddr)
+		if err != nil {
+			if p.udp != nil {
+				p.udp.Close()
+			}
+			return nil, err
+		}
+		p.tcp = listener
+	}
+
+	if p.udp != nil {
+		go p.serveUDP()
+	}
+	if p.tcp != nil {
+		go p.serveTCP()
+	}
+	go p.runSweeper()
+
+	return p, nil
+}
+
+// UDPPort returns the port of the UDP listener, or 0 if UDP is disabled
+func (p *Prober) UDPPort() int {
+	if p.udp == nil {
+		return 0
+	}
+	return p.udp.LocalAddr().(*net.UDPAddr).Port
+}
+
+// TCPPort returns the port of the TCP listener, or 0 if TCP is disabled
+func (p *Prober) TCPPort() int {
+	if p.tcp == nil {
+		return 0
+	}
+	return p.tcp.Addr().(*net.TCPAddr).Port
+}
+
+// Create starts a probe for a user, finishing the user's previous probe
+func (p *Prober) Create(userID, clientIP string) Result {
+	now := time.Now()
+	created := &probe{
+		id:        uuid.New().String(),
+		userID:    userID,
+		clientIP:  clientIP,
+		startedAt: now,
+		expires:   now.Add(TTL),
+		udp:       newTransport(),
+		tcp:       newTransport(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, existing := range p.probes {
+		if existing.userID == userID {
+			p.finishLocked(existing)
+		}
+	}
+	p.probes[created.id] = created
+	return created.result()
+}
+
+// Result returns the current result of a user's running probe
+func (p *Prober) Result(id, userID string) (Result, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	running, exists := p.probes[id]
+	if exists && running.userID == userID {
+		return running.result(), true
+	}
+	for _, result := range p.recent {
+		if result.ID == id && result.UserID == userID {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+// Summary aggregates finished probes, newest results first
+func (p *Prober) Summary() Summary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	summary := Summary{
+		Probes:          p.finished,
+		Recommendations: make(map[string]int, len(p.recommendations)),
+		Recent:          make([]Result, 0, len(p.recent)),
+	}
+	for recommendation, n := range p.recommendations {
+		summary.Recommendations[recommendation] = n
+	}
+	for i := len(p.recent) - 1; i >= 0; i-- {
+		summary.Recent = append(summary.Recent, p.recent[i])
+	}
+	if p.finished > 0 {
+		summary.UDPReachablePercent = float64(p.udpReachable) / float64(p.finished) * 100
+	}
+	if p.udpReachable > 0 {
+		summary.AvgRTTMs = p.rttSum / float64(p.udpReachable)
+		summary.AvgJitterMs = p.jitterSum / float64(p.udpReachable)
+		summary.AvgPacketLossPercent = p.lossSum / float64(p.udpReachable)
+	}
+	return summary
+}
+
+// finishLocked moves a probe to the finished results and logs it
+func (p *Prober) finishLocked(finished *probe) {
+	delete(p.probes, finished.id)
+
+	result := finished.result()
+	p.recent = append(p.recent, result)
+	if len(p.recent) > recentResults {
+		p.recent = p.recent[len(p.recent)-recentResults:]
+	}
+
+	p.finished++
+	p.recommendations[result.Recommendation]++
+	if result.UDP.Reachable {
+		p.udpReachable++
+		p.rttSum += result.UDP.RTTMs
+		p.jitterSum += result.UDP.JitterMs
+		p.lossSum += result.UDP.PacketLossPercent
+	}
+
+	logger.Infof("Network probe %s from %s: udp=%t loss=%.1f%% rtt=%.0fms jitter=%.1fms tcp=%t rtt=%.0fms, recommending %s",
+		result.ID, result.ClientIP, result.UDP.Reachable, result.UDP.PacketLossPercent, result.UDP.RTTMs, result.UDP.JitterMs,
+		result.TCP.Reachable, result.TCP.RTTMs, result.Recommendation)
+}
+
+// runSweeper finishes probes once they expire
+func (p *Prober) runSweeper() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		p.mu.Lock()
+		for _, running := range p.probes {
+			if now.After(running.expires) {
+				p.finishLocked(running)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// handle records a probe packet and reports whether to echo it
+func (p *Prober) handle(packet string, overUDP bool) bool {
+	fields := strings.Fields(packet)
+	if len(fields) != 3 {
+		return false
+	}
+	seq, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	running, exists := p.probes[fields[0]]
+	if !exists {
+		return false
+	}
+	t := running.tcp
+	if overUDP {
+		t = running.udp
+	}
+
+	now := time.Now()
+	if fields[2] == "ack" {
+		t.ack(seq, now)
+		return false
+	}
+	sentMs, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return false
+	}
+	return t.packet(seq, sentMs, now)
+}
+
+// serveUDP echoes UDP probe packets
+func (p *Prober) serveUDP() {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := p.udp.ReadFromUDP(buf)
+		if err != nil {
+			logger.Errorf("Network probe UDP listener stopped: %v", err)
+			return
+		}
+		if p.handle(string(buf[:n]), true) {
+			if _, err := p.udp.WriteToUDP(buf[:n], addr); err != nil {
+				logger.Debugf("Failed to echo network probe packet to %s: %v", addr, err)
+			}
+		}
+	}
+}
+
+// serveTCP accepts TCP probe connections
+func (p *Prober) serveTCP() {
+	for {
+		conn, err := p.tcp.Accept()
+		if err != nil {
+			logger.Errorf("Network probe TCP listener stopped: %v", err)
+			return
+		}
+		go p.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn echoes the probe lines of one connection until the probe would have expired
+func (p *Prober) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(TTL))
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, maxPacketSize), maxPacketSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p.handle(line, false) {
+			if _, err := conn.Write([]byte(line + "\n")); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package notes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Defaults of the openai backend
+const (
+	defaultOpenAIURL   = "https://api.openai.com/v1"
+	defaultOpenAIModel = "gpt-4o-mini"
+)
+
+// Limits on backend responses, in bytes
+const (
+	maxResponseSize = 1 << 20
+	maxErrorBody    = 512 // of the body quoted in errors
+)
+
+// prompt instructs chat models to answer with a Summary as JSON
+const prompt = `You write meeting notes from transcripts. Answer with a JSON object only:
+{"summary": "<a few paragraphs covering the topics discussed and the decisions made>",
+ "action_items": [{"text": "<task>", "assignee": "<participant, if named>", "due": "<deadline, if named>"}]}
+Write the notes in the language with code %q. Leave action_items empty when no tasks were agreed on.`
+
+func init() {
+	Register("openai", newOpenAI)
+	Register("webhook", newWebhook)
+}
+
+// openAI summarizes with a chat completions API compatible with OpenAI's, which
+// hosted providers and local servers such as vLLM and Ollama also offer
+type openAI struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// newOpenAI creates the openai backend; URL is the API base URL
+func newOpenAI(config Config) (Summarizer, error) {
+	backend := &openAI{
+		url:    strings.TrimSuffix(config.URL, "/"),
+		apiKey: config.APIKey,
+		model:  config.Model,
+		client: &http.Client{},
+	}
+	if backend.url == "" {
+		backend.url = defaultOpenAIURL
+	}
+	if backend.model == "" {
+		backend.model = defaultOpenAIModel
+	}
+	if backend.apiKey == "" && backend.url == defaultOpenAIURL {
+		return nil, errors.New("an API key is required for the OpenAI API")
+	}
+	return backend, nil
+}
+
+// Summarize asks the model for notes in JSON mode
+func (b *openAI) Summarize(ctx context.Context, meeting Meeting, transcript string) (*Summary, error) {
+	details, err := json.Marshal(meeting)
+	if err != nil {
+		return nil, err
+	}
+
+	request := map[string]interface{}{
+		"model": b.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": fmt.Sprintf(prompt, meeting.Language)},
+			{"role": "user", "content": "Meeting: " + string(details) + "\n\nTranscript:\n" + transcript},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+	}
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postJSON(ctx, b.client, b.url+"/chat/completions", b.apiKey, request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, errors.New("model returned no choices")
+	}
+
+	var summary Summary
+	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &summary); err != nil {
+		return nil, fmt.Errorf("model did not answer with notes: %w", err)
+	}
+	return &summary, nil
+}
+
+// webhook summarizes with an external service: the meeting and transcript are
+// posted as {"meeting": ..., "transcript": "..."} and a Summary is expected back
+type webhook struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// newWebhook creates the webhook backend
+func newWebhook(config Config) (Summarizer, error) {
+	if config.URL == "" {
+		return nil, errors.New("a URL is required for the webhook backend")
+	}
+	return &webhook{url: config.URL, apiKey: config.APIKey, client: &http.Client{}}, nil
+}
+
+// Summarize posts the transcript to the service
+func (b *webhook) Summarize(ctx context.Context, meeting Meeting, transcript string) (*Summary, error) {
+	var summary Summary
+	err := postJSON(ctx, b.client, b.url, b.apiKey, map[string]interface{}{
+		"meeting":    meeting,
+		"transcript": transcript,
+	}, &summary)
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// postJSON posts a JSON request with an optional bearer token and decodes the response
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(respBody) > maxErrorBody {
+			respBody = respBody[:maxErrorBody]
+		}
+		return fmt.Errorf("backend responded with %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
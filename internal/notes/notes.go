@@ -0,0 +1,211 @@
+package notes
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits on transcripts
+const (
+	// Largest transcript file read, in bytes
+	maxTranscriptSize = 4 << 20
+
+	// Longest transcript passed to a backend, in characters; longer transcripts keep their beginning
+	maxTranscriptChars = 100000
+)
+
+// ErrUnknownBackend is returned for backend names nothing is registered under
+var ErrUnknownBackend = errors.New("unknown notes backend")
+
+// ActionItem is a task agreed on in a meeting
+type ActionItem struct {
+	Text     string `json:"text"`
+	Assignee string `json:"assignee,omitempty"`
+	Due      string `json:"due,omitempty"`
+}
+
+// Summary is what a backend makes of a transcript
+type Summary struct {
+	Summary     string       `json:"summary"`
+	ActionItems []ActionItem `json:"action_items"`
+}
+
+// Meeting describes the meeting a transcript belongs to
+type Meeting struct {
+	Title        string   `json:"title"`
+	Participants []string `json:"participants"`
+	Language     string   `json:"language"` // language the notes are written in
+}
+
+// Summarizer turns the transcript of a meeting into notes and action items
+type Summarizer interface {
+	Summarize(ctx context.Context, meeting Meeting, transcript string) (*Summary, error)
+}
+
+// Config configures a backend; fields a backend does not use are ignored
+type Config struct {
+	URL    string
+	APIKey string
+	Model  string
+}
+
+// backends maps backend names to their constructors
+var (
+	backends   = map[string]func(Config) (Summarizer, error){}
+	backendsMu sync.RWMutex
+)
+
+// Register makes a backend available under a name, e.g. one calling a model
+// running in-process; built-in backends are "openai" and "webhook"
+func Register(name string, factory func(Config) (Summarizer, error)) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[name] = factory
+}
+
+// New creates the summarizer of a registered backend
+func New(backend string, config Config) (Summarizer, error) {
+	backendsMu.RLock()
+	factory, exists := backends[backend]
+	backendsMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
+	}
+	return factory(config)
+}
+
+// Notes are the meeting notes generated from the transcript of a recording
+type Notes struct {
+	ID          string       `json:"id"`
+	RoomID      string       `json:"room_id"`
+	RecordingID string       `json:"recording_id"`
+	Summary     string       `json:"summary"`
+	ActionItems []ActionItem `json:"action_items"`
+	Attendees   []string     `json:"attendees"` // user IDs of the participants the notes were sent to
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// Store keeps the notes of each room in memory
+type Store struct {
+	rooms map[string][]*Notes
+	mu    sync.RWMutex
+}
+
+// NewStore creates an empty Store
+func NewStore() *Store {
+	return &Store{
+		rooms: make(map[string][]*Notes),
+	}
+}
+
+// Add stores notes generated for a room; notes of the same recording replace earlier ones
+func (s *Store) Add(roomID, recordingID string, summary *Summary, attendees []string) *Notes {
+	notes := &Notes{
+		ID:          uuid.New().String(),
+		RoomID:      roomID,
+		RecordingID: recordingID,
+		Summary:     summary.Summary,
+		ActionItems: summary.ActionItems,
+		Attendees:   attendees,
+		CreatedAt:   time.Now(),
+	}
+	if notes.ActionItems == nil {
+		notes.ActionItems = []ActionItem{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.rooms[roomID][:0]
+	for _, existing := range s.rooms[roomID] {
+		if existing.RecordingID != recordingID {
+			kept = append(kept, existing)
+		}
+	}
+	s.rooms[roomID] = append(kept, notes)
+	return notes
+}
+
+// ForRoom returns the notes of a room, newest first
+func (s *Store) ForRoom(roomID string) []*Notes {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notes := append([]*Notes(nil), s.rooms[roomID]...)
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].CreatedAt.After(notes[j].CreatedAt)
+	})
+	return notes
+}
+
+// Latest returns the newest notes of a room, or nil
+func (s *Store) Latest(roomID string) *Notes {
+	if notes := s.ForRoom(roomID); len(notes) > 0 {
+		return notes[0]
+	}
+	return nil
+}
+
+// DeleteRoom forgets the notes of a room
+func (s *Store) DeleteRoom(roomID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rooms, roomID)
+}
+
+// ReadTranscript reads the content of a transcript file named name as plain text. Cue
+// numbers and timings of WebVTT and SubRip subtitles are dropped; other files are read
+// as they are.
+func ReadTranscript(name string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxTranscriptSize))
+	if err != nil {
+		return "", err
+	}
+	text := string(data)
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".vtt", ".srt":
+		text = stripCues(text)
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("transcript is empty")
+	}
+	if runes := []rune(text); len(runes) > maxTranscriptChars {
+		text = string(runes[:maxTranscriptChars])
+	}
+	return text, nil
+}
+
+// stripCues keeps the text lines of subtitles
+func stripCues(subtitles string) string {
+	var text strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(subtitles))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line == "WEBVTT" || strings.Contains(line, "-->") {
+			continue
+		}
+		if _, err := strconv.Atoi(line); err == nil {
+			continue
+		}
+		text.WriteString(line)
+		text.WriteByte('\n')
+	}
+	return text.String()
+}
@@ -0,0 +1,305 @@
+package channels
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/logging"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+// Chat providers
+const (
+	ProviderSlack = "slack"
+	ProviderTeams = "teams"
+)
+
+// EventTypes lists the events that can be posted to a channel
+var EventTypes = []string{events.RoomStarted, events.RecordingReady, events.CallMissed}
+
+// Delivery settings
+const (
+	deliveryTimeout  = 10 * time.Second
+	deliveryAttempts = 3
+	retryBackoff     = 2 * time.Second
+)
+
+var (
+	// ErrChannelNotFound is returned for unknown channels
+	ErrChannelNotFound = errors.New("channel not found")
+
+	// ErrInvalidProvider is returned for providers other than slack and teams
+	ErrInvalidProvider = errors.New("provider must be slack or teams")
+
+	// ErrInvalidURL is returned for webhook URLs that are not http(s)
+	ErrInvalidURL = errors.New("url must be an http or https incoming webhook URL")
+
+	// ErrInvalidEvent is returned when toggling an event that cannot be posted
+	ErrInvalidEvent = errors.New("invalid event type")
+)
+
+// Channel is a Slack or Microsoft Teams incoming webhook that receives selected
+// server events. The webhook URL embeds its credentials, so only its host is shown.
+type Channel struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Provider  string          `json:"provider"`
+	URLHost   string          `json:"url_host"`
+	Events    map[string]bool `json:"events"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt time.Time       `json:"created_at"`
+	url       string
+}
+
+// copy returns a copy of the channel that does not share its event toggles
+func (ch *Channel) copy() Channel {
+	copied := *ch
+	copied.Events = make(map[string]bool, len(ch.Events))
+	for eventType, enabled := range ch.Events {
+		copied.Events[eventType] = enabled
+	}
+	return copied
+}
+
+// Manager stores chat channels in memory and posts events to them
+type Manager struct {
+	channels map[string]*Channel
+	mu       sync.RWMutex
+	client   *http.Client
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		channels: make(map[string]*Channel),
+		client:   &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+// validEvents checks event toggles against EventTypes
+func validEvents(toggles map[string]bool) error {
+	for eventType := range toggles {
+		known := false
+		for _, t := range EventTypes {
+			known = known || t == eventType
+		}
+		if !known {
+			return fmt.Errorf("%w: %s", ErrInvalidEvent, eventType)
+		}
+	}
+	return nil
+}
+
+// Create adds a channel. Events missing from toggles are enabled.
+func (m *Manager) Create(name, provider, webhookURL string, toggles map[string]bool, createdBy string) (*Channel, error) {
+	if provider != ProviderSlack && provider != ProviderTeams {
+		return nil, ErrInvalidProvider
+	}
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	if err := validEvents(toggles); err != nil {
+		return nil, err
+	}
+
+	ch := &Channel{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Provider:  provider,
+		URLHost:   parsed.Host,
+		Events:    make(map[string]bool, len(EventTypes)),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		url:       webhookURL,
+	}
+	for _, eventType := range EventTypes {
+		enabled, set := toggles[eventType]
+		ch.Events[eventType] = enabled || !set
+	}
+
+	m.mu.Lock()
+	m.channels[ch.ID] = ch
+	m.mu.Unlock()
+
+	copied := ch.copy()
+	return &copied, nil
+}
+
+// Update renames a channel and switches the given events on or off
+func (m *Manager) Update(id string, name *string, toggles map[string]bool) (*Channel, error) {
+	if err := validEvents(toggles); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch, exists := m.channels[id]
+	if !exists {
+		return nil, ErrChannelNotFound
+	}
+	if name != nil {
+		ch.Name = *name
+	}
+	for eventType, enabled := range toggles {
+		ch.Events[eventType] = enabled
+	}
+
+	copied := ch.copy()
+	return &copied, nil
+}
+
+// Delete removes a channel
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.channels[id]; !exists {
+		return ErrChannelNotFound
+	}
+	delete(m.channels, id)
+	return nil
+}
+
+// List returns copies of all channels, oldest first
+func (m *Manager) List() []Channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	channels := make([]Channel, 0, len(m.channels))
+	for _, ch := range m.channels {
+		channels = append(channels, ch.copy())
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].CreatedAt.Before(channels[j].CreatedAt)
+	})
+	return channels
+}
+
+// Run posts events to the channels that enabled them until the event channel is closed
+func (m *Manager) Run(ch <-chan events.Event) {
+	for event := range ch {
+		title, lines := format(event)
+		if title == "" {
+			continue
+		}
+
+		m.mu.RLock()
+		var targets []Channel
+		for _, channel := range m.channels {
+			if channel.Events[event.Type] {
+				targets = append(targets, channel.copy())
+			}
+		}
+		m.mu.RUnlock()
+
+		// Slow endpoints must not hold up other events
+		for _, channel := range targets {
+			go m.deliver(channel, event.Type, title, lines)
+		}
+	}
+}
+
+// deliver posts a message to one channel, retrying failed attempts
+func (m *Manager) deliver(ch Channel, eventType, title string, lines []string) {
+	body, err := json.Marshal(payload(ch.Provider, title, lines))
+	if err != nil {
+		logger.Errorf("Failed to encode %s message for channel %s: %v", eventType, ch.Name, err)
+		return
+	}
+
+	for attempt := 1; attempt <= deliveryAttempts; attempt++ {
+		if err = m.post(ch.url, body); err == nil {
+			return
+		}
+		if attempt < deliveryAttempts {
+			time.Sleep(time.Duration(attempt) * retryBackoff)
+		}
+	}
+	logger.Errorf("Failed to post %s to %s channel %s: %v", eventType, ch.Provider, ch.Name, err)
+}
+
+// post makes a single delivery attempt
+func (m *Manager) post(webhookURL string, body []byte) error {
+	resp, err := m.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// payload builds the webhook body of a provider: a Slack message with mrkdwn text,
+// or a Teams MessageCard
+func payload(provider, title string, lines []string) interface{} {
+	if provider == ProviderTeams {
+		return map[string]interface{}{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  title,
+			"title":    title,
+			"text":     strings.Join(lines, "\n\n"),
+		}
+	}
+	return map[string]interface{}{
+		"text": "*" + title + "*\n" + strings.Join(lines, "\n"),
+	}
+}
+
+// format describes an event as a title and lines of text; an empty title means the
+// event is not posted
+func format(event events.Event) (string, []string) {
+	data := event.Data
+	text := func(key string) string {
+		value, _ := data[key].(string)
+		return value
+	}
+
+	switch event.Type {
+	case events.RoomStarted:
+		return "Call started in " + text("name"), []string{
+			"Started by " + text("started_by"),
+			"Join code: " + text("join_code"),
+		}
+
+	case events.RecordingReady:
+		lines := []string{"Recording " + text("recording_id") + " has been processed."}
+		if manifest, ok := data["manifest"].(recording.Manifest); ok {
+			for _, artifact := range manifest.Artifacts {
+				lines = append(lines, artifact.Name+": "+artifact.URL)
+			}
+		}
+		return "Recording of " + text("name") + " is ready", lines
+
+	case events.CallMissed:
+		names, _ := data["missed_names"].([]string)
+		startsAt := text("starts_at_local")
+		if t, err := time.Parse(time.RFC3339, startsAt); err == nil {
+			startsAt = t.Format("Jan 2, 2006 15:04")
+		}
+		return "Missed meeting: " + text("name"), []string{
+			"Scheduled for " + startsAt + " (" + text("timezone") + ")",
+			"Did not join: " + strings.Join(names, ", "),
+		}
+	}
+	return "", nil
+}
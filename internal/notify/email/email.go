@@ -0,0 +1,308 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/logging"
+	"github.com/zubans/video-call-server/internal/metrics"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+// Email templates
+const (
+	TemplateWelcome        = "welcome"
+	TemplateVerification   = "verification"
+	TemplateInvite         = "invite"
+	TemplateReminder       = "reminder"
+	TemplateRecordingReady = "recording_ready"
+	TemplateMeetingNotes   = "meeting_notes"
+	TemplateUsageReport    = "usage_report"
+)
+
+// defaultLanguage is the language every template exists in
+const defaultLanguage = "en"
+
+// Delivery settings
+const (
+	sendTimeout  = 30 * time.Second
+	retryBackoff = 5 * time.Second
+)
+
+// templateFiles holds one directory of templates per language. Each template defines
+// a "subject" and a plain text "body".
+//
+//go:embed templates/*/*.tmpl
+var templateFiles embed.FS
+
+// Config holds the SMTP server and queue settings
+type Config struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string // sender address, optionally with a name: "Calls <calls@example.com>"
+	Workers   int
+	QueueSize int
+	Attempts  int
+}
+
+// Message is an email to render from a template
+type Message struct {
+	To       string
+	Template string
+	Language string
+	Data     map[string]interface{}
+}
+
+// Mailer renders templated emails and sends them in the background through an SMTP
+// server, retrying failed deliveries. Sending never blocks the caller; a nil Mailer
+// drops every message.
+type Mailer struct {
+	config    Config
+	from      *mail.Address
+	templates map[string]map[string]*template.Template // by language, then name
+	queue     chan Message
+}
+
+// NewMailer creates a Mailer and starts its workers
+func NewMailer(config Config) (*Mailer, error) {
+	if config.Host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", config.From, err)
+	}
+	if config.Port <= 0 {
+		config.Port = 587
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1000
+	}
+	if config.Attempts <= 0 {
+		config.Attempts = 1
+	}
+
+	templates, err := loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mailer{
+		config:    config,
+		from:      from,
+		templates: templates,
+		queue:     make(chan Message, config.QueueSize),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		go m.run()
+	}
+	return m, nil
+}
+
+// loadTemplates parses the embedded templates
+func loadTemplates() (map[string]map[string]*template.Template, error) {
+	files, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]map[string]*template.Template)
+	for _, dir := range files {
+		language := dir.Name()
+		entries, err := templateFiles.ReadDir(path.Join("templates", language))
+		if err != nil {
+			return nil, err
+		}
+		templates[language] = make(map[string]*template.Template)
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".tmpl")
+			tmpl, err := template.ParseFS(templateFiles, path.Join("templates", language, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("invalid email template %s/%s: %w", language, name, err)
+			}
+			templates[language][name] = tmpl
+		}
+	}
+	return templates, nil
+}
+
+// Send queues a message for delivery; it reports false if the message was dropped
+// because the queue is full or no mailer is configured
+func (m *Mailer) Send(msg Message) bool {
+	if m == nil {
+		return false
+	}
+	if msg.To == "" {
+		return false
+	}
+
+	select {
+	case m.queue <- msg:
+		metrics.AppMetrics.SetEmailQueueDepth(float64(len(m.queue)))
+		return true
+	default:
+		logger.Warnf("Dropping %s email to %s, queue full", msg.Template, msg.To)
+		metrics.AppMetrics.IncrementEmailsFailed(msg.Template)
+		return false
+	}
+}
+
+// run delivers queued messages until the queue is closed
+func (m *Mailer) run() {
+	for msg := range m.queue {
+		metrics.AppMetrics.SetEmailQueueDepth(float64(len(m.queue)))
+		m.deliver(msg)
+	}
+}
+
+// deliver renders and sends a message, retrying failed attempts with a growing backoff
+func (m *Mailer) deliver(msg Message) {
+	body, err := m.render(msg)
+	if err != nil {
+		logger.Errorf("Failed to render %s email: %v", msg.Template, err)
+		metrics.AppMetrics.IncrementEmailsFailed(msg.Template)
+		return
+	}
+
+	for attempt := 1; attempt <= m.config.Attempts; attempt++ {
+		if err = m.sendSMTP(msg.To, body); err == nil {
+			metrics.AppMetrics.IncrementEmailsSent(msg.Template)
+			return
+		}
+		if attempt < m.config.Attempts {
+			metrics.AppMetrics.IncrementEmailRetries(msg.Template)
+			time.Sleep(time.Duration(attempt) * retryBackoff)
+		}
+	}
+	logger.Errorf("Failed to send %s email to %s: %v", msg.Template, msg.To, err)
+	metrics.AppMetrics.IncrementEmailsFailed(msg.Template)
+}
+
+// render builds the MIME message of an email in its language, falling back to English
+func (m *Mailer) render(msg Message) ([]byte, error) {
+	tmpl, ok := m.templates[msg.Language][msg.Template]
+	if !ok {
+		if tmpl, ok = m.templates[defaultLanguage][msg.Template]; !ok {
+			return nil, fmt.Errorf("unknown template %q", msg.Template)
+		}
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", msg.Data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", msg.Data); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	header := func(name, value string) {
+		out.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", m.from.String())
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+newMessageID()+"@"+m.domain()+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	out.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&out)
+	text := strings.ReplaceAll(strings.TrimSpace(body.String()), "\n", "\r\n") + "\r\n"
+	if _, err := writer.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// domain returns the domain of the sender address
+func (m *Mailer) domain() string {
+	if _, domain, ok := strings.Cut(m.from.Address, "@"); ok {
+		return domain
+	}
+	return "localhost"
+}
+
+// sendSMTP sends a message through the SMTP server, upgrading to TLS when the
+// server offers STARTTLS
+func (m *Mailer) sendSMTP(to string, body []byte) error {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	conn, err := net.DialTimeout("tcp", addr, sendTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// newMessageID returns a random Message-ID local part
+func newMessageID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package presence
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Availability states
+const (
+	Available    = "available"
+	Busy         = "busy"
+	DoNotDisturb = "dnd"
+)
+
+// ErrInvalidState is returned for unknown availability states
+var ErrInvalidState = errors.New("status must be available, busy or dnd")
+
+// Status is a user's availability
+type Status struct {
+	State     string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // nil for users who never set a status
+}
+
+// expired reports whether a status has run out at a given time
+func (s Status) expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// Manager stores user statuses in memory; users without a status are available
+type Manager struct {
+	statuses map[string]Status
+	mu       sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		statuses: make(map[string]Status),
+	}
+}
+
+// Set changes a user's status; a zero ttl keeps it until changed
+func (m *Manager) Set(userID, state, message string, ttl time.Duration) (Status, error) {
+	switch state {
+	case Available, Busy, DoNotDisturb:
+	default:
+		return Status{}, ErrInvalidState
+	}
+
+	now := time.Now()
+	status := Status{State: state, UpdatedAt: &now}
+	if state != Available {
+		status.Message = message
+		if ttl > 0 {
+			expiresAt := now.Add(ttl)
+			status.ExpiresAt = &expiresAt
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.statuses[userID] = status
+	return status, nil
+}
+
+// Get returns a user's current status; expired statuses read as available
+func (m *Manager) Get(userID string) Status {
+	m.mu.RLock()
+	status, exists := m.statuses[userID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return Status{State: Available}
+	}
+	if status.expired(time.Now()) {
+		return Status{State: Available, UpdatedAt: status.ExpiresAt}
+	}
+	return status
+}
+
+// DoNotDisturb reports whether a user currently does not want to be disturbed
+func (m *Manager) DoNotDisturb(userID string) bool {
+	return m.Get(userID).State == DoNotDisturb
+}
@@ -0,0 +1,76 @@
+package queue
+
+// Policy decides what happens when a bounded per-connection queue is full
+type Policy int
+
+const (
+	// DropNewest discards the message being sent
+	DropNewest Policy = iota
+
+	// DropOldest discards the oldest queued message to make room for the new one
+	DropOldest
+
+	// Disconnect reports an overflow so the caller can disconnect the slow consumer
+	Disconnect
+)
+
+// String returns the policy name used in logs and metrics
+func (p Policy) String() string {
+	switch p {
+	case DropOldest:
+		return "drop_oldest"
+	case Disconnect:
+		return "disconnect"
+	default:
+		return "drop_newest"
+	}
+}
+
+// Result is the outcome of Push
+type Result int
+
+const (
+	// Queued means the message was queued without dropping anything
+	Queued Result = iota
+
+	// DroppedOldest means the oldest message was discarded to queue the new one
+	DroppedOldest
+
+	// DroppedNewest means the new message was discarded
+	DroppedNewest
+
+	// Overflow means the queue is full and the policy asks for a disconnect
+	Overflow
+)
+
+// Dropped reports whether a message was lost
+func (r Result) Dropped() bool {
+	return r != Queued
+}
+
+// Push enqueues v on a bounded channel without blocking, applying the policy when it is full
+func Push[T any](ch chan T, v T, policy Policy) Result {
+	select {
+	case ch <- v:
+		return Queued
+	default:
+	}
+
+	switch policy {
+	case DropOldest:
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- v:
+			return DroppedOldest
+		default:
+			return DroppedNewest
+		}
+	case Disconnect:
+		return Overflow
+	default:
+		return DroppedNewest
+	}
+}
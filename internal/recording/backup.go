@@ -0,0 +1,50 @@
+package recording
+
+import "errors"
+
+// ErrRecordingExists is returned when importing a recording whose ID is taken
+var ErrRecordingExists = errors.New("recording already exists")
+
+// ExportMetadata returns copies of the metadata of every finished recording; media files are
+// not included
+func (r *Recorder) ExportMetadata() []Recording {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Recording, 0, len(r.recordings))
+	for _, recording := range r.recordings {
+		if !recording.Active {
+			list = append(list, *recording)
+		}
+	}
+	return list
+}
+
+// Metadata returns a copy of a recording's metadata
+func (r *Recorder) Metadata(recordingID string) (Recording, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		return Recording{}, false
+	}
+	return *recording, true
+}
+
+// ImportMetadata adds the metadata of a finished recording from a backup, keeping its ID. Its
+// media files are expected at the same paths under the recordings directory.
+func (r *Recorder) ImportMetadata(recording Recording) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.recordings[recording.ID]; exists {
+		return ErrRecordingExists
+	}
+	recording.Active = false
+	if recording.Storage == "" {
+		recording.Storage = StorageLocal
+	}
+	r.recordings[recording.ID] = &recording
+	return nil
+}
@@ -0,0 +1,111 @@
+package recording
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Chapter kinds
+const (
+	ChapterScreenShare = "screen_share" // a participant started sharing their screen
+	ChapterSpeaker     = "speaker"      // another participant started speaking
+)
+
+// ArtifactChapters is the artifact kind of the WebVTT chapters of a recording
+const ArtifactChapters = "chapters"
+
+// chaptersName is the file name of a recording's WebVTT chapters in its tracks directory
+const chaptersName = "chapters.vtt"
+
+// minSpeakerChapter is the shortest time a chapter runs before a change of speaker
+// starts the next one, so quick exchanges do not split a recording into fragments
+const minSpeakerChapter = 30 * time.Second
+
+// Chapter is a navigable point of a recording, generated from a room event
+type Chapter struct {
+	Start       float64 `json:"start_seconds"` // offset from the start of the recording
+	Kind        string  `json:"kind"`
+	Title       string  `json:"title"`
+	Participant string  `json:"participant,omitempty"` // display name of the participant the event is about
+}
+
+// chapterTitle describes a chapter for players listing them
+func chapterTitle(kind, participant string) string {
+	if participant == "" {
+		participant = "Participant"
+	}
+	if kind == ChapterScreenShare {
+		return "Screen share: " + participant
+	}
+	return participant
+}
+
+// AddChapter starts a chapter in every active recording of a room at the current
+// offset. Speaker chapters are skipped while the same participant is still the
+// speaker, or until the current chapter has run for minSpeakerChapter.
+func (r *Recorder) AddChapter(roomID, kind, participant string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rec := range r.active[roomID] {
+		rec.addChapter(kind, participant)
+	}
+}
+
+// addChapter starts a chapter of a recording; the caller holds r.mu
+func (rec *Recording) addChapter(kind, participant string) {
+	offset := time.Since(rec.StartedAt).Seconds()
+	if kind == ChapterSpeaker {
+		if participant == rec.speaker {
+			return
+		}
+		rec.speaker = participant
+		if n := len(rec.Chapters); n > 0 && offset-rec.Chapters[n-1].Start < minSpeakerChapter.Seconds() {
+			return
+		}
+	}
+	rec.Chapters = append(rec.Chapters, Chapter{
+		Start:       offset,
+		Kind:        kind,
+		Title:       chapterTitle(kind, participant),
+		Participant: participant,
+	})
+}
+
+// Chapters returns the chapters of a recording, in order
+func (r *Recorder) Chapters(recordingID string) []Chapter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		return nil
+	}
+	return append([]Chapter{}, recording.Chapters...)
+}
+
+// ChaptersVTT renders chapters as a WebVTT chapters track for a recording lasting
+// duration seconds; each chapter ends where the next one starts, the last one with
+// the recording
+func ChaptersVTT(chapters []Chapter, duration float64) []byte {
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+	for i, chapter := range chapters {
+		end := duration
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+		if end <= chapter.Start {
+			continue
+		}
+		fmt.Fprintf(&vtt, "\n%d\n%s --> %s\n%s\n", i+1, vttTime(chapter.Start), vttTime(end), chapter.Title)
+	}
+	return []byte(vtt.String())
+}
+
+// vttTime formats an offset in seconds as a WebVTT timestamp
+func vttTime(seconds float64) string {
+	ms := int64(seconds * 1000)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
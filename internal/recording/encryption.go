@@ -0,0 +1,152 @@
+package recording
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zubans/video-call-server/internal/encryption"
+)
+
+// plainPrefix starts the names of decrypted copies that FFmpeg reads
+const plainPrefix = ".plain-"
+
+// SetKeyring sets the keyring that encrypts the files of recordings once they are
+// processed; nil leaves new recordings unencrypted. Encrypted files stay readable
+// only while a keyring holding their master key is set.
+func (r *Recorder) SetKeyring(keyring *encryption.Keyring) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keyring = keyring
+}
+
+// Open opens a file of a recording for reading, decrypting it if it is encrypted
+func (r *Recorder) Open(path string) (io.ReadSeekCloser, error) {
+	r.mu.RLock()
+	keyring := r.keyring
+	r.mu.RUnlock()
+
+	return encryption.Open(context.Background(), path, keyring)
+}
+
+// dataKey returns the data key of a recording: the one its composed file is encrypted
+// with, or a new one. ok is false when encryption is off.
+func (r *Recorder) dataKey(composite string) (key encryption.DataKey, ok bool, err error) {
+	r.mu.RLock()
+	keyring := r.keyring
+	r.mu.RUnlock()
+	if keyring == nil {
+		return encryption.DataKey{}, false, nil
+	}
+
+	ctx := context.Background()
+	if encrypted, _ := encryption.IsEncrypted(composite); encrypted {
+		key, err = encryption.ReadKey(composite)
+		if err != nil {
+			return encryption.DataKey{}, false, err
+		}
+		key, err = keyring.Unwrap(ctx, key)
+	} else {
+		key, err = keyring.NewDataKey(ctx)
+	}
+	if err != nil {
+		return encryption.DataKey{}, false, err
+	}
+	return key, true, nil
+}
+
+// encryptRecording encrypts the composed file and every file in the tracks directory
+// of a recording that is not encrypted yet, then records the master key on it
+func (r *Recorder) encryptRecording(recordingID string) error {
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return nil
+	}
+	composite, tracksDir := recording.Filename, recording.TracksDir
+	r.mu.RUnlock()
+
+	key, ok, err := r.dataKey(composite)
+	if err != nil || !ok {
+		return err
+	}
+
+	paths := []string{composite}
+	if tracksDir != "" {
+		filepath.WalkDir(tracksDir, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && entry.Type().IsRegular() {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+	}
+	if err := encryptFiles(key, paths...); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if recording, exists := r.recordings[recordingID]; exists {
+		recording.KeyID = key.KeyID
+	}
+	r.mu.Unlock()
+	return nil
+}
+
+// encryptFiles encrypts the files at paths that are not encrypted yet; partial outputs
+// and decrypted copies are skipped
+func encryptFiles(key encryption.DataKey, paths ...string) error {
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if strings.HasPrefix(name, plainPrefix) || strings.HasSuffix(name, ".partial") || strings.HasSuffix(name, ".encrypting") {
+			continue
+		}
+		encrypted, err := encryption.IsEncrypted(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if encrypted {
+			continue
+		}
+		if err := encryption.EncryptFile(path, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plainCopy returns a path FFmpeg can read the content of a file from: the file itself,
+// or a decrypted copy next to it that remove deletes
+func (r *Recorder) plainCopy(path string) (plain string, remove func(), err error) {
+	if encrypted, err := encryption.IsEncrypted(path); err != nil || !encrypted {
+		return path, func() {}, err
+	}
+
+	src, err := r.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp(filepath.Dir(path), plainPrefix+"*-"+filepath.Base(path))
+	if err != nil {
+		return "", nil, err
+	}
+	plain = dst.Name()
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(plain)
+		return "", nil, err
+	}
+	return plain, func() { os.Remove(plain) }, nil
+}
@@ -0,0 +1,259 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ArtifactExport is the kind of artifacts produced by Export
+const ArtifactExport = "export"
+
+// Export formats
+const (
+	FormatWebM = "webm"
+	FormatMP4  = "mp4"
+)
+
+// Caption modes of exports
+const (
+	CaptionsNone  = ""      // no captions
+	CaptionsBurn  = "burn"  // captions rendered into the video
+	CaptionsTrack = "track" // captions attached as a subtitle track
+)
+
+var (
+	// ErrNotProcessed is returned when exporting a recording before it is processed
+	ErrNotProcessed = errors.New("recording has not been processed yet")
+
+	// ErrNoTimedTranscript is returned when captions are requested for a recording
+	// without a WebVTT or SubRip transcript
+	ErrNoTimedTranscript = errors.New("recording has no timed transcript")
+
+	// ErrNoVideo is returned when burning captions into an audio-only recording
+	ErrNoVideo = errors.New("recording has no video")
+
+	// ErrExportInProgress is returned while the same export is still running
+	ErrExportInProgress = errors.New("export is already in progress")
+)
+
+// ExportOptions selects the container and captions of an export
+type ExportOptions struct {
+	Format   string `json:"format"`
+	Captions string `json:"captions"`
+}
+
+// Validate checks the format and caption mode
+func (o ExportOptions) Validate() error {
+	if o.Format != FormatWebM && o.Format != FormatMP4 {
+		return fmt.Errorf("invalid format: %q", o.Format)
+	}
+	if o.Captions != CaptionsNone && o.Captions != CaptionsBurn && o.Captions != CaptionsTrack {
+		return fmt.Errorf("invalid captions: %q", o.Captions)
+	}
+	return nil
+}
+
+// Name returns the file name of the export, e.g. "export-burn.mp4"
+func (o ExportOptions) Name() string {
+	if o.Captions == CaptionsNone {
+		return "export." + o.Format
+	}
+	return "export-" + o.Captions + "." + o.Format
+}
+
+// exportSource is what an export is made from
+type exportSource struct {
+	composite string
+	tracksDir string
+	subtitles string // WebVTT or SubRip transcript, if any
+	hasVideo  bool
+}
+
+// CheckExport reports whether a recording can be exported with the options, e.g. to
+// reject a request before exporting in the background
+func (r *Recorder) CheckExport(recordingID string, options ExportOptions) error {
+	source, err := r.exportSource(recordingID, options)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.exporting[filepath.Join(source.tracksDir, options.Name())] {
+		return ErrExportInProgress
+	}
+	return nil
+}
+
+// exportSource validates an export and returns its inputs
+func (r *Recorder) exportSource(recordingID string, options ExportOptions) (*exportSource, error) {
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("recording not found: %s", recordingID)
+	}
+	if err := offline(recording); err != nil {
+		r.mu.RUnlock()
+		return nil, err
+	}
+	if recording.Manifest == nil || recording.TracksDir == "" {
+		r.mu.RUnlock()
+		return nil, ErrNotProcessed
+	}
+	source := &exportSource{composite: recording.Filename, tracksDir: recording.TracksDir}
+	for _, path := range recording.Tracks {
+		if filepath.Ext(path) != ".ogg" {
+			source.hasVideo = true
+		}
+	}
+	r.mu.RUnlock()
+
+	if path, exists := r.TranscriptPath(recordingID); exists {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".vtt", ".srt":
+			source.subtitles = path
+		}
+	}
+	if options.Captions != CaptionsNone && source.subtitles == "" {
+		return nil, ErrNoTimedTranscript
+	}
+	if options.Captions == CaptionsBurn && !source.hasVideo {
+		return nil, ErrNoVideo
+	}
+
+	// FFmpeg runs in the tracks directory, so relative paths would not resolve
+	for _, path := range []*string{&source.composite, &source.tracksDir, &source.subtitles} {
+		if *path == "" {
+			continue
+		}
+		if *path, err = filepath.Abs(*path); err != nil {
+			return nil, err
+		}
+	}
+	return source, nil
+}
+
+// Export converts the composed file of a processed recording to a format with FFmpeg,
+// optionally with captions from its transcript burned in or attached as a subtitle
+// track, and adds the result to the recording's manifest. Exports with the same
+// options replace each other.
+func (r *Recorder) Export(recordingID string, options ExportOptions) (Artifact, error) {
+	source, err := r.exportSource(recordingID, options)
+	if err != nil {
+		return Artifact{}, err
+	}
+	output := filepath.Join(source.tracksDir, options.Name())
+
+	r.mu.Lock()
+	if r.exporting[output] {
+		r.mu.Unlock()
+		return Artifact{}, ErrExportInProgress
+	}
+	r.exporting[output] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.exporting, output)
+		r.mu.Unlock()
+	}()
+
+	// FFmpeg reads decrypted copies of encrypted inputs
+	composite := source.composite
+	for _, path := range []*string{&source.composite, &source.subtitles} {
+		if *path == "" {
+			continue
+		}
+		plain, remove, err := r.plainCopy(*path)
+		if err != nil {
+			return Artifact{}, err
+		}
+		defer remove()
+		*path = plain
+	}
+
+	// FFmpeg runs in the tracks directory so the subtitles filter gets a plain file
+	// name rather than a path needing filter escaping
+	cmd := exec.Command(ffmpegPath(), exportArgs(source, options, filepath.Base(output))...)
+	cmd.Dir = source.tracksDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return Artifact{}, fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	artifact, err := describeArtifact(ArtifactExport, output)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if key, ok, err := r.dataKey(composite); err != nil {
+		return Artifact{}, err
+	} else if ok {
+		if err := encryptFiles(key, output); err != nil {
+			return Artifact{}, err
+		}
+	}
+
+	// Readers may hold the current manifest, so it is replaced rather than changed
+	r.mu.Lock()
+	if recording, exists := r.recordings[recordingID]; exists && recording.Manifest != nil {
+		manifest := *recording.Manifest
+		manifest.Artifacts = []Artifact{}
+		for _, existing := range recording.Manifest.Artifacts {
+			if existing.Name != artifact.Name {
+				manifest.Artifacts = append(manifest.Artifacts, existing)
+			}
+		}
+		manifest.Artifacts = append(manifest.Artifacts, artifact)
+		recording.Manifest = &manifest
+	}
+	r.mu.Unlock()
+
+	return artifact, nil
+}
+
+// exportArgs builds the FFmpeg arguments of an export. The composed file holds VP8
+// and Opus, which WebM exports keep unless captions are burned in; MP4 exports are
+// transcoded to H.264 and AAC. WebM carries subtitle tracks as WebVTT, MP4 as mov_text.
+func exportArgs(source *exportSource, options ExportOptions, output string) []string {
+	args := []string{"-y", "-loglevel", "error", "-i", source.composite}
+	if options.Captions == CaptionsTrack {
+		args = append(args, "-i", source.subtitles, "-map", "0", "-map", "1")
+	}
+
+	videoCodec := []string{"-c:v", "copy"}
+	if options.Format == FormatMP4 {
+		videoCodec = []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p"}
+	}
+	if options.Captions == CaptionsBurn {
+		args = append(args, "-vf", "subtitles="+filepath.Base(source.subtitles))
+		if options.Format == FormatWebM {
+			videoCodec = []string{"-c:v", "libvpx", "-b:v", "1M"}
+		}
+	}
+	if source.hasVideo {
+		args = append(args, videoCodec...)
+	}
+
+	switch options.Format {
+	case FormatMP4:
+		args = append(args, "-c:a", "aac", "-b:a", "128k", "-movflags", "+faststart")
+		if options.Captions == CaptionsTrack {
+			args = append(args, "-c:s", "mov_text")
+		}
+	default:
+		args = append(args, "-c:a", "copy")
+		if options.Captions == CaptionsTrack {
+			args = append(args, "-c:s", "webvtt")
+		}
+	}
+	return append(args, "-f", options.Format, output)
+}
@@ -0,0 +1,66 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// ErrLegalHold is returned when deleting a recording under legal hold
+var ErrLegalHold = errors.New("recording is under legal hold")
+
+// SetLegalHold places a legal hold on a recording, or releases it when hold is nil.
+// Recordings under hold cannot be deleted.
+func (r *Recorder) SetLegalHold(recordingID string, hold *models.LegalHold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		return fmt.Errorf("recording not found: %s", recordingID)
+	}
+	recording.LegalHold = hold
+	return nil
+}
+
+// SetHoldCheck sets how DeleteRecording learns of holds beyond the recording's own,
+// such as a hold on its room or owner. check is called with the recorder locked, so
+// it must not call back into the recorder.
+func (r *Recorder) SetHoldCheck(check func(StoredRecording) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.holdCheck = check
+}
+
+// heldLocked reports whether a recording may not be deleted: it is under legal hold
+// or the hold check reports a hold on its room or owner. r.mu must be held.
+func (r *Recorder) heldLocked(recording *Recording) bool {
+	if recording.LegalHold != nil {
+		return true
+	}
+	return r.holdCheck != nil && r.holdCheck(StoredRecording{
+		ID:        recording.ID,
+		RoomID:    recording.RoomID,
+		OwnerID:   recording.OwnerID,
+		TenantID:  recording.TenantID,
+		StartedAt: recording.StartedAt,
+		Active:    recording.Active,
+		Storage:   recording.Storage,
+	})
+}
+
+// LegalHolds returns the holds on recordings, by recording ID
+func (r *Recorder) LegalHolds() map[string]models.LegalHold {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	holds := make(map[string]models.LegalHold)
+	for _, recording := range r.recordings {
+		if recording.LegalHold != nil {
+			holds[recording.ID] = *recording.LegalHold
+		}
+	}
+	return holds
+}
@@ -0,0 +1,223 @@
+package recording
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Artifact kinds
+const (
+	ArtifactComposite  = "composite"
+	ArtifactTrack      = "track"
+	ArtifactTranscript = "transcript"
+	ArtifactThumbnail  = "thumbnail"
+)
+
+// thumbnailName is the file name of a recording's thumbnail in its tracks directory
+const thumbnailName = "thumbnail.jpg"
+
+// Artifact is a file produced by a recording
+type Artifact struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url,omitempty"`
+	Path   string `json:"-"`
+}
+
+// Manifest lists the artifacts of a processed recording
+type Manifest struct {
+	RecordingID string     `json:"recording_id"`
+	RoomID      string     `json:"room_id"`
+	Mode        string     `json:"mode"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     time.Time  `json:"ended_at"`
+	ProcessedAt time.Time  `json:"processed_at"`
+	Chapters    []Chapter  `json:"chapters"`
+	Artifacts   []Artifact `json:"artifacts"`
+}
+
+// Process post-processes a stopped recording: it composes the tracks, renders a
+// thumbnail, writes the chapters as WebVTT, encrypts the files if a keyring is set and
+// returns the manifest of all artifacts, which is also kept on the recording.
+// Transcripts are listed when a transcript.* file is present in the tracks directory.
+func (r *Recorder) Process(recordingID string) (*Manifest, error) {
+	if err := r.Compose(recordingID); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("recording not found: %s", recordingID)
+	}
+	manifest := &Manifest{
+		RecordingID: recording.ID,
+		RoomID:      recording.RoomID,
+		Mode:        recording.Mode,
+		StartedAt:   recording.StartedAt,
+		EndedAt:     recording.EndedAt,
+		Chapters:    append([]Chapter{}, recording.Chapters...),
+	}
+	composite := recording.Filename
+	tracksDir := recording.TracksDir
+	tracks := append([]string(nil), recording.Tracks...)
+	r.mu.RUnlock()
+
+	hasVideo := false
+	for _, path := range tracks {
+		if filepath.Ext(path) != ".ogg" {
+			hasVideo = true
+		}
+	}
+
+	paths := map[string][]string{
+		ArtifactComposite: {composite},
+		ArtifactTrack:     tracks,
+	}
+	if tracksDir != "" {
+		if hasVideo {
+			thumbnail := filepath.Join(tracksDir, thumbnailName)
+			if err := renderThumbnail(composite, thumbnail); err != nil {
+				logger.Errorf("Failed to render thumbnail of recording %s: %v", recordingID, err)
+			} else {
+				paths[ArtifactThumbnail] = []string{thumbnail}
+			}
+		}
+		transcripts, _ := filepath.Glob(filepath.Join(tracksDir, "transcript.*"))
+		paths[ArtifactTranscript] = transcripts
+		if len(manifest.Chapters) > 0 {
+			chapters := filepath.Join(tracksDir, chaptersName)
+			vtt := ChaptersVTT(manifest.Chapters, manifest.EndedAt.Sub(manifest.StartedAt).Seconds())
+			if err := os.WriteFile(chapters, vtt, 0644); err != nil {
+				logger.Errorf("Failed to write chapters of recording %s: %v", recordingID, err)
+			} else {
+				paths[ArtifactChapters] = []string{chapters}
+			}
+		}
+	}
+
+	manifest.Artifacts = []Artifact{}
+	for _, kind := range []string{ArtifactComposite, ArtifactTrack, ArtifactTranscript, ArtifactThumbnail, ArtifactChapters} {
+		for _, path := range paths[kind] {
+			artifact, err := describeArtifact(kind, path)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Artifacts = append(manifest.Artifacts, artifact)
+		}
+	}
+	manifest.ProcessedAt = time.Now()
+
+	// Checksums above are of the plain content, which downloads return
+	if err := r.encryptRecording(recordingID); err != nil {
+		return nil, fmt.Errorf("failed to encrypt recording: %v", err)
+	}
+
+	r.mu.Lock()
+	if recording, exists := r.recordings[recordingID]; exists {
+		recording.Manifest = manifest
+	}
+	r.mu.Unlock()
+
+	return manifest, nil
+}
+
+// Manifest returns the manifest of a processed recording; it is nil until processing completes
+func (r *Recorder) Manifest(recordingID string) *Manifest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if recording, exists := r.recordings[recordingID]; exists {
+		return recording.Manifest
+	}
+	return nil
+}
+
+// Artifact returns an artifact of a processed recording by name
+func (r *Recorder) Artifact(recordingID, name string) (Artifact, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists || recording.Manifest == nil {
+		return Artifact{}, false
+	}
+	for _, artifact := range recording.Manifest.Artifacts {
+		if artifact.Name == name {
+			return artifact, true
+		}
+	}
+	return Artifact{}, false
+}
+
+// TranscriptPath returns the transcript file of a recording: the transcript artifact
+// of its manifest, or a transcript.* file added to its tracks directory since
+func (r *Recorder) TranscriptPath(recordingID string) (string, bool) {
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return "", false
+	}
+	manifest := recording.Manifest
+	tracksDir := recording.TracksDir
+	r.mu.RUnlock()
+
+	if manifest != nil {
+		for _, artifact := range manifest.Artifacts {
+			if artifact.Kind == ArtifactTranscript {
+				return artifact.Path, true
+			}
+		}
+	}
+	if tracksDir == "" {
+		return "", false
+	}
+	transcripts, _ := filepath.Glob(filepath.Join(tracksDir, "transcript.*"))
+	if len(transcripts) == 0 {
+		return "", false
+	}
+	return transcripts[0], true
+}
+
+// describeArtifact measures and checksums an artifact file
+func describeArtifact(kind, path string) (Artifact, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to open artifact: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to checksum artifact: %v", err)
+	}
+
+	return Artifact{
+		Kind:   kind,
+		Name:   filepath.Base(path),
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Path:   path,
+	}, nil
+}
+
+// renderThumbnail saves a frame of a composed recording as a JPEG
+func renderThumbnail(input, output string) error {
+	args := []string{"-y", "-loglevel", "error", "-i", input, "-frames:v", "1", "-vf", "scale=320:-2", output}
+	if out, err := exec.Command(ffmpegPath(), args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the HLS variant in the tracks directory
+const (
+	hlsDirName     = "hls"
+	HLSPlaylist    = "index.m3u8"
+	hlsSegmentName = "segment%05d.ts"
+
+	// Target duration of HLS segments, in seconds
+	hlsSegmentSeconds = 6
+)
+
+var (
+	// ErrHLSNotReady is returned for recordings whose HLS variant is not generated yet
+	ErrHLSNotReady = errors.New("HLS variant is not generated yet")
+
+	// ErrHLSInProgress is returned while the HLS variant is being generated
+	ErrHLSInProgress = errors.New("HLS variant is being generated")
+)
+
+// Composite returns the composed file of a processed recording
+func (r *Recorder) Composite(recordingID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		return "", fmt.Errorf("recording not found: %s", recordingID)
+	}
+	if err := offline(recording); err != nil {
+		return "", err
+	}
+	if recording.Manifest == nil {
+		return "", ErrNotProcessed
+	}
+	return recording.Filename, nil
+}
+
+// HLSFile returns a file of the HLS variant of a recording, such as HLSPlaylist or a
+// segment. ErrHLSNotReady means the variant has to be generated with GenerateHLS first.
+func (r *Recorder) HLSFile(recordingID, name string) (string, error) {
+	dir, err := r.hlsDir(recordingID)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.RLock()
+	generating := r.exporting[dir]
+	r.mu.RUnlock()
+
+	if _, err := os.Stat(filepath.Join(dir, HLSPlaylist)); err != nil {
+		if generating {
+			return "", ErrHLSInProgress
+		}
+		return "", ErrHLSNotReady
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", os.ErrNotExist
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// GenerateHLS transcodes the composed file of a processed recording into an HLS VOD
+// variant of H.264 and AAC segments, encrypted like the recording. The variant is
+// written next to its final location and moved there once complete, so players never
+// see a partial playlist.
+func (r *Recorder) GenerateHLS(recordingID string) error {
+	dir, err := r.hlsDir(recordingID)
+	if err != nil {
+		return err
+	}
+	composite, err := r.Composite(recordingID)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	if r.exporting[dir] {
+		r.mu.Unlock()
+		return ErrHLSInProgress
+	}
+	r.exporting[dir] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.exporting, dir)
+		r.mu.Unlock()
+	}()
+
+	if _, err := os.Stat(filepath.Join(dir, HLSPlaylist)); err == nil {
+		return nil
+	}
+
+	partial := dir + ".partial"
+	if err := os.RemoveAll(partial); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(partial, 0755); err != nil {
+		return fmt.Errorf("failed to create HLS directory: %v", err)
+	}
+
+	// FFmpeg reads a decrypted copy of an encrypted file
+	plain, remove, err := r.plainCopy(composite)
+	if err != nil {
+		os.RemoveAll(partial)
+		return err
+	}
+	defer remove()
+
+	args := []string{"-y", "-loglevel", "error", "-i", plain,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+		"-c:a", "aac", "-b:a", "128k",
+		"-f", "hls", "-hls_time", fmt.Sprint(hlsSegmentSeconds), "-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(partial, hlsSegmentName),
+		filepath.Join(partial, HLSPlaylist)}
+	if out, err := exec.Command(ffmpegPath(), args...).CombinedOutput(); err != nil {
+		os.RemoveAll(partial)
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if key, ok, err := r.dataKey(composite); err != nil {
+		os.RemoveAll(partial)
+		return err
+	} else if ok {
+		files, _ := filepath.Glob(filepath.Join(partial, "*"))
+		if err := encryptFiles(key, files...); err != nil {
+			os.RemoveAll(partial)
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(partial, dir)
+}
+
+// hlsDir returns the directory of the HLS variant of a recording
+func (r *Recorder) hlsDir(recordingID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		return "", fmt.Errorf("recording not found: %s", recordingID)
+	}
+	if err := offline(recording); err != nil {
+		return "", err
+	}
+	if recording.Manifest == nil || recording.TracksDir == "" {
+		return "", ErrNotProcessed
+	}
+	return filepath.Join(recording.TracksDir, hlsDirName), nil
+}
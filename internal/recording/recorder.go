@@ -1,30 +1,65 @@
 package recording
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/archive"
+	"github.com/zubans/video-call-server/internal/encryption"
+	"github.com/zubans/video-call-server/internal/models"
 )
 
+// DefaultDir is where recordings are stored unless RECORDINGS_DIR is set
+const DefaultDir = "./recordings"
+
 // Recorder manages call recordings
 type Recorder struct {
 	recordings map[string]*Recording
+	active     map[string][]*Recording          // active recordings by room ID
+	files      map[string]map[string]*trackFile // capture files of active recordings, by recording and track ID
+	exporting  map[string]bool                  // outputs of exports and HLS variants being generated, by path
+	watermark  Watermark                        // overlay of composed videos
+	archive    archive.Store                    // cold tier of old recordings; nil disables archiving
+	keyring    *encryption.Keyring              // encrypts processed recordings; nil disables encryption
+	holdCheck  func(StoredRecording) bool       // holds on a recording's room or owner; nil checks only its own
 	mu         sync.RWMutex
 	basePath   string
 }
 
 // Recording represents a call recording
 type Recording struct {
-	ID        string
-	RoomID    string
-	Filename  string
-	StartedAt time.Time
-	EndedAt   time.Time
-	Active    bool
+	ID         string
+	RoomID     string
+	OwnerID    string // creator of the room when recording started
+	TenantID   string // tenant of the room; empty is the default tenant
+	Filename   string
+	StartedAt  time.Time
+	EndedAt    time.Time
+	Active     bool
+	Mode       string
+	TracksDir  string            // directory of the raw per-track captures
+	Tracks     []string          // captured track files, set when the recording stops
+	Publishers map[string]string // display names of the participants who published Tracks, by file
+	Chapters   []Chapter         // generated from room events while recording
+	Manifest   *Manifest         // set once the recording has been processed
+	Storage    string            // tier of the media files: StorageLocal, StorageArchived or StorageRestoring
+	KeyID      string            // master key wrapping the data key of the encrypted files; empty if not encrypted
+	Integrity  *Verification     // result of the last check of the artifacts against their checksums
+	LegalHold  *models.LegalHold // set while the recording may not be deleted
+
+	ArchivedAt         time.Time // when the media files moved to the archive
+	ArchivedBytes      int64     // size of the archived copy
+	RestoreRequestedAt time.Time // when a restore from the archive was last requested
+	RestoredAt         time.Time // when the media files last came back from the archive
+
+	speaker    string            // participant who spoke last, for speaker chapters
 }
 
 // NewRecorder creates a new Recorder instance
@@ -36,12 +71,23 @@ func NewRecorder(basePath string) *Recorder {
 	
 	return &Recorder{
 		recordings: make(map[string]*Recording),
+		active:     make(map[string][]*Recording),
+		files:      make(map[string]map[string]*trackFile),
+		exporting:  make(map[string]bool),
 		basePath:   basePath,
 	}
 }
 
-// StartRecording starts a new recording for a room
-func (r *Recorder) StartRecording(roomID string) (*Recording, error) {
+// StartRecording starts a new recording for a room of a tenant owned by ownerID in the given mode (ModeFull if empty)
+func (r *Recorder) StartRecording(tenantID, roomID, ownerID, mode string) (*Recording, error) {
+	switch mode {
+	case "":
+		mode = ModeFull
+	case ModeFull, ModeScreenShare:
+	default:
+		return nil, ErrInvalidMode
+	}
+	
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	
@@ -55,14 +101,16 @@ func (r *Recorder) StartRecording(roomID string) (*Recording, error) {
 	recording := &Recording{
 		ID:        recordingID,
 		RoomID:    roomID,
+		OwnerID:   ownerID,
+		TenantID:  tenantID,
 		Filename:  filename,
 		StartedAt: time.Now(),
 		Active:    true,
+		Mode:      mode,
+		TracksDir: strings.TrimSuffix(filename, ".webm"),
+		Storage:   StorageLocal,
 	}
 	
-	// Store recording
-	r.recordings[recordingID] = recording
-	
 	// Create empty file
 	file, err := os.Create(filename)
 	if err != nil {
@@ -70,6 +118,16 @@ func (r *Recorder) StartRecording(roomID string) (*Recording, error) {
 	}
 	file.Close()
 	
+	// Create the directory for per-track captures
+	if err := os.MkdirAll(recording.TracksDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording tracks directory: %v", err)
+	}
+	
+	// Store recording
+	r.recordings[recordingID] = recording
+	r.active[roomID] = append(r.active[roomID], recording)
+	r.files[recordingID] = make(map[string]*trackFile)
+	
 	return recording, nil
 }
 
@@ -93,6 +151,9 @@ func (r *Recorder) StopRecording(recordingID string) error {
 	recording.Active = false
 	recording.EndedAt = time.Now()
 	
+	// Finish the per-track captures
+	recording.Tracks = r.deactivate(recording)
+	
 	return nil
 }
 
@@ -105,14 +166,14 @@ func (r *Recorder) GetRecording(recordingID string) (*Recording, bool) {
 	return recording, exists
 }
 
-// ListRecordings returns all recordings for a room
-func (r *Recorder) ListRecordings(roomID string) []*Recording {
+// ListRecordings returns all recordings of a tenant for a room
+func (r *Recorder) ListRecordings(tenantID, roomID string) []*Recording {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
 	var recordings []*Recording
 	for _, recording := range r.recordings {
-		if recording.RoomID == roomID {
+		if recording.RoomID == roomID && recording.TenantID == tenantID {
 			// Return a copy to prevent external modification
 			rec := *recording
 			recordings = append(recordings, &rec)
@@ -122,7 +183,29 @@ func (r *Recorder) ListRecordings(roomID string) []*Recording {
 	return recordings
 }
 
-// DeleteRecording deletes a recording file and removes it from the registry
+// ListRecordingsForRooms returns the recordings of a tenant for several rooms at once, by room ID
+func (r *Recorder) ListRecordingsForRooms(tenantID string, roomIDs []string) map[string][]*Recording {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	result := make(map[string][]*Recording, len(roomIDs))
+	for _, roomID := range roomIDs {
+		result[roomID] = nil
+	}
+	for _, recording := range r.recordings {
+		if _, wanted := result[recording.RoomID]; wanted && recording.TenantID == tenantID {
+			// Return a copy to prevent external modification
+			rec := *recording
+			result[recording.RoomID] = append(result[recording.RoomID], &rec)
+		}
+	}
+	
+	return result
+}
+
+// DeleteRecording deletes a recording file, or its archived copy, and removes it from
+// the registry. Recordings under legal hold, directly or through their room or owner,
+// are kept.
 func (r *Recorder) DeleteRecording(recordingID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -132,10 +215,30 @@ func (r *Recorder) DeleteRecording(recordingID string) error {
 	if !exists {
 		return fmt.Errorf("recording not found: %s", recordingID)
 	}
+
+	// Nothing is removed from a held recording, its archived copy included
+	if r.heldLocked(recording) {
+		return ErrLegalHold
+	}
+	if recording.Storage != StorageLocal && r.archive != nil {
+		if err := r.archive.Delete(context.Background(), archiveKey(recordingID)); err != nil {
+			return err
+		}
+	}
+	
+	// Stop capturing
+	if recording.Active {
+		r.deactivate(recording)
+	}
 	
 	// Delete file
-	if err := os.Remove(recording.Filename); err != nil {
-		return fmt.Errorf("failed to delete recording file: %v", err)
+	if recording.Storage == StorageLocal {
+		if err := os.Remove(recording.Filename); err != nil {
+			return fmt.Errorf("failed to delete recording file: %v", err)
+		}
+		if recording.TracksDir != "" {
+			os.RemoveAll(recording.TracksDir)
+		}
 	}
 	
 	// Remove from registry
@@ -155,4 +258,36 @@ func (r *Recorder) GetRecordingFilePath(recordingID string) (string, error) {
 	}
 	
 	return recording.Filename, nil
-}
\ No newline at end of file
+}
+// PruneRecordings deletes recording files in dir last modified before cutoff and
+// returns their paths. With dryRun set, files are only listed.
+func PruneRecordings(dir string, cutoff time.Time, dryRun bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recordings directory: %v", err)
+	}
+
+	var pruned []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".webm" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return pruned, fmt.Errorf("failed to delete %s: %v", path, err)
+			}
+			// Per-track captures live in a directory named after the file
+			os.RemoveAll(strings.TrimSuffix(path, ".webm"))
+		}
+		pruned = append(pruned, path)
+	}
+
+	return pruned, nil
+}
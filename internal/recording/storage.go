@@ -0,0 +1,89 @@
+package recording
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// StoredRecording is a recording with the disk space it uses
+type StoredRecording struct {
+	ID        string    `json:"id"`
+	RoomID    string    `json:"room_id"`
+	OwnerID   string    `json:"owner_id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Active    bool      `json:"active"`
+	Storage   string    `json:"storage"`
+	Bytes     int64     `json:"bytes"` // on the node; zero once archived
+	LegalHold bool      `json:"legal_hold,omitempty"`
+}
+
+// Filter selects recordings by age, size and room; zero fields match everything
+type Filter struct {
+	StartedBefore time.Time
+	MinBytes      int64
+	RoomID        string
+}
+
+// matches reports whether a recording passes the filter
+func (f Filter) matches(stored StoredRecording) bool {
+	if !f.StartedBefore.IsZero() && !stored.StartedAt.Before(f.StartedBefore) {
+		return false
+	}
+	if f.MinBytes > 0 && stored.Bytes < f.MinBytes {
+		return false
+	}
+	return f.RoomID == "" || stored.RoomID == f.RoomID
+}
+
+// Stored returns the recordings matching a filter with the bytes each uses on disk:
+// the composed file plus the per-track captures and other artifacts
+func (r *Recorder) Stored(filter Filter) []StoredRecording {
+	r.mu.RLock()
+	recordings := make([]Recording, 0, len(r.recordings))
+	for _, recording := range r.recordings {
+		recordings = append(recordings, *recording)
+	}
+	r.mu.RUnlock()
+
+	stored := []StoredRecording{}
+	for _, recording := range recordings {
+		entry := StoredRecording{
+			ID:        recording.ID,
+			RoomID:    recording.RoomID,
+			OwnerID:   recording.OwnerID,
+			TenantID:  recording.TenantID,
+			StartedAt: recording.StartedAt,
+			Active:    recording.Active,
+			Storage:   recording.Storage,
+			Bytes:     diskUsage(recording.Filename) + diskUsage(recording.TracksDir),
+			LegalHold: recording.LegalHold != nil,
+		}
+		if filter.matches(entry) {
+			stored = append(stored, entry)
+		}
+	}
+	return stored
+}
+
+// diskUsage returns the size of a file or of all files under a directory; missing paths count as zero
+func diskUsage(path string) int64 {
+	if path == "" {
+		return 0
+	}
+
+	var total int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
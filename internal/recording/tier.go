@@ -0,0 +1,448 @@
+package recording
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/archive"
+)
+
+// Storage tiers of the media files of a recording
+const (
+	StorageLocal     = "local"     // on the node, playable
+	StorageArchived  = "archived"  // in the archive; a restore must be requested to play it
+	StorageRestoring = "restoring" // a restore was requested and is not complete yet
+)
+
+var (
+	// ErrArchived is returned for recordings whose media files are in the archive
+	ErrArchived = errors.New("recording is archived")
+
+	// ErrRestoring is returned for recordings being restored from the archive
+	ErrRestoring = errors.New("recording is being restored from the archive")
+
+	// ErrNotArchived is returned when restoring a recording stored locally
+	ErrNotArchived = errors.New("recording is not archived")
+
+	// ErrNoArchive is returned when archiving without an archive configured
+	ErrNoArchive = errors.New("no recording archive is configured")
+)
+
+// SetArchive sets the store old recordings are moved to
+func (r *Recorder) SetArchive(store archive.Store) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.archive = store
+}
+
+// archiveKey returns the key of the archived copy of a recording
+func archiveKey(recordingID string) string {
+	return recordingID + ".tar"
+}
+
+// offline returns ErrArchived or ErrRestoring for recordings whose media files are not
+// on the node
+func offline(recording *Recording) error {
+	switch recording.Storage {
+	case StorageArchived:
+		return ErrArchived
+	case StorageRestoring:
+		return ErrRestoring
+	}
+	return nil
+}
+
+// busy reports whether an export, an HLS variant or an archive copy of a recording is
+// being generated; r.mu must be held
+func (r *Recorder) busy(recording *Recording) bool {
+	for path := range r.exporting {
+		if path == recording.TracksDir+".tar" || strings.HasPrefix(path, recording.TracksDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveCandidates returns the processed recordings stored on the node that should move
+// to the archive, least recently used first: those that ended or were last restored
+// before idleBefore, then the others while the recordings on the node use more than
+// quota bytes. A zero idleBefore or quota disables the respective rule.
+func (r *Recorder) ArchiveCandidates(idleBefore time.Time, quota int64) []string {
+	type candidate struct {
+		id        string
+		idleSince time.Time
+		bytes     int64
+	}
+
+	r.mu.RLock()
+	var stored, finished []Recording
+	for _, recording := range r.recordings {
+		if recording.Storage != StorageLocal {
+			continue
+		}
+		stored = append(stored, *recording)
+		if !recording.Active && recording.Manifest != nil && !r.busy(recording) {
+			finished = append(finished, *recording)
+		}
+	}
+	r.mu.RUnlock()
+
+	var used int64
+	if quota > 0 {
+		for _, recording := range stored {
+			used += diskUsage(recording.Filename) + diskUsage(recording.TracksDir)
+		}
+	}
+
+	local := make([]candidate, 0, len(finished))
+	for _, recording := range finished {
+		idleSince := recording.EndedAt
+		if recording.RestoredAt.After(idleSince) {
+			idleSince = recording.RestoredAt
+		}
+		local = append(local, candidate{
+			id:        recording.ID,
+			idleSince: idleSince,
+			bytes:     diskUsage(recording.Filename) + diskUsage(recording.TracksDir),
+		})
+	}
+
+	sort.Slice(local, func(i, j int) bool {
+		return local[i].idleSince.Before(local[j].idleSince)
+	})
+	var ids []string
+	for _, entry := range local {
+		idle := !idleBefore.IsZero() && entry.idleSince.Before(idleBefore)
+		if !idle && (quota <= 0 || used <= quota) {
+			break
+		}
+		ids = append(ids, entry.id)
+		used -= entry.bytes
+	}
+	return ids
+}
+
+// Archive moves the media files of a finished recording to the archive as a tar file
+// and returns its size. The files stay playable until the copy is complete.
+func (r *Recorder) Archive(ctx context.Context, recordingID string) (int64, error) {
+	r.mu.Lock()
+	recording, exists := r.recordings[recordingID]
+	switch {
+	case !exists:
+		r.mu.Unlock()
+		return 0, fmt.Errorf("recording not found: %s", recordingID)
+	case r.archive == nil:
+		r.mu.Unlock()
+		return 0, ErrNoArchive
+	case recording.Active:
+		r.mu.Unlock()
+		return 0, fmt.Errorf("recording is active: %s", recordingID)
+	case recording.Storage != StorageLocal:
+		r.mu.Unlock()
+		return 0, offline(recording)
+	case r.busy(recording):
+		r.mu.Unlock()
+		return 0, ErrExportInProgress
+	}
+	store := r.archive
+	dir := filepath.Dir(recording.Filename)
+	names := []string{filepath.Base(recording.Filename)}
+	if recording.TracksDir != "" {
+		names = append(names, filepath.Base(recording.TracksDir))
+	}
+	marker := recording.TracksDir + ".tar"
+	r.exporting[marker] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.exporting, marker)
+		r.mu.Unlock()
+	}()
+
+	reader, writer := io.Pipe()
+	counter := &countingWriter{w: writer}
+	go func() {
+		writer.CloseWithError(writeTar(counter, dir, names))
+	}()
+	err := store.Put(ctx, archiveKey(recordingID), reader)
+	reader.CloseWithError(errors.New("archive upload stopped"))
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	if _, exists := r.recordings[recordingID]; !exists {
+		r.mu.Unlock()
+		store.Delete(ctx, archiveKey(recordingID))
+		return 0, fmt.Errorf("recording not found: %s", recordingID)
+	}
+	if r.exportingOther(recording, marker) {
+		// An export started while copying and reads the local files
+		r.mu.Unlock()
+		store.Delete(ctx, archiveKey(recordingID))
+		return 0, ErrExportInProgress
+	}
+	recording.Storage = StorageArchived
+	recording.ArchivedAt = time.Now()
+	recording.ArchivedBytes = counter.n
+	recording.RestoreRequestedAt = time.Time{}
+	filename, tracksDir := recording.Filename, recording.TracksDir
+	r.mu.Unlock()
+
+	os.Remove(filename)
+	if tracksDir != "" {
+		os.RemoveAll(tracksDir)
+	}
+	return counter.n, nil
+}
+
+// exportingOther reports whether anything but the archive copy marked by marker is
+// being generated for a recording; r.mu must be held
+func (r *Recorder) exportingOther(recording *Recording, marker string) bool {
+	for path := range r.exporting {
+		if path != marker && strings.HasPrefix(path, recording.TracksDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestRestore asks the archive to make an archived recording readable and marks it
+// restoring; CompleteRestore brings its files back once the archive is ready. Requests
+// for recordings already being restored do nothing.
+func (r *Recorder) RequestRestore(ctx context.Context, recordingID string) error {
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return fmt.Errorf("recording not found: %s", recordingID)
+	}
+	storage, store := recording.Storage, r.archive
+	r.mu.RUnlock()
+
+	switch {
+	case storage == StorageLocal:
+		return ErrNotArchived
+	case storage == StorageRestoring:
+		return nil
+	case store == nil:
+		return ErrNoArchive
+	}
+	if err := store.Restore(ctx, archiveKey(recordingID)); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if recording.Storage == StorageArchived {
+		recording.Storage = StorageRestoring
+		recording.RestoreRequestedAt = time.Now()
+	}
+	return nil
+}
+
+// Restoring returns the IDs of the recordings being restored
+func (r *Recorder) Restoring() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []string
+	for _, recording := range r.recordings {
+		if recording.Storage == StorageRestoring {
+			ids = append(ids, recording.ID)
+		}
+	}
+	return ids
+}
+
+// CompleteRestore brings the media files of a recording being restored back to the node
+// once the archive has made them readable, then deletes the archived copy. It reports
+// whether the recording was restored; restores already being completed are skipped.
+func (r *Recorder) CompleteRestore(ctx context.Context, recordingID string) (bool, error) {
+	r.mu.Lock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.Unlock()
+		return false, fmt.Errorf("recording not found: %s", recordingID)
+	}
+	marker := recording.TracksDir + ".tar"
+	if recording.Storage != StorageRestoring || r.exporting[marker] {
+		r.mu.Unlock()
+		return false, nil
+	}
+	store := r.archive
+	if store == nil {
+		r.mu.Unlock()
+		return false, ErrNoArchive
+	}
+	dir := filepath.Dir(recording.Filename)
+	names := []string{filepath.Base(recording.Filename)}
+	if recording.TracksDir != "" {
+		names = append(names, filepath.Base(recording.TracksDir))
+	}
+	r.exporting[marker] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.exporting, marker)
+		r.mu.Unlock()
+	}()
+
+	key := archiveKey(recordingID)
+	ready, err := store.Ready(ctx, key)
+	if err != nil || !ready {
+		return false, err
+	}
+
+	object, err := store.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	err = readTar(object, dir, names)
+	object.Close()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	recording.Storage = StorageLocal
+	recording.RestoredAt = time.Now()
+	recording.ArchivedAt = time.Time{}
+	recording.ArchivedBytes = 0
+	r.mu.Unlock()
+
+	if err := store.Delete(ctx, key); err != nil {
+		return true, fmt.Errorf("failed to delete archived copy: %v", err)
+	}
+	return true, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeTar writes the named files and directories under dir to w as a tar stream, with
+// paths relative to dir
+func writeTar(w io.Writer, dir string, names []string) error {
+	stream := tar.NewWriter(w)
+	for _, name := range names {
+		root := filepath.Join(dir, name)
+		if _, err := os.Stat(root); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			relative, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(relative)
+			if err := stream.WriteHeader(header); err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(stream, file)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %v", name, err)
+		}
+	}
+	return stream.Close()
+}
+
+// readTar extracts a tar stream written by writeTar into dir. Only entries under the
+// given names are accepted, so a tampered archive cannot write elsewhere.
+func readTar(r io.Reader, dir string, names []string) error {
+	stream := tar.NewReader(r)
+	for {
+		header, err := stream.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %v", err)
+		}
+
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) || !expected(name, names) {
+			return fmt.Errorf("unexpected archive entry: %q", header.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, stream)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to restore %s: %v", header.Name, err)
+			}
+			os.Chtimes(path, header.ModTime, header.ModTime)
+		}
+	}
+}
+
+// expected reports whether a relative path is one of names or under one of them
+func expected(path string, names []string) bool {
+	for _, name := range names {
+		if path == name || strings.HasPrefix(path, name+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/h264writer"
+	"github.com/pion/webrtc/v3/pkg/media/ivfwriter"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+
+	"github.com/zubans/video-call-server/internal/errreport"
+	"github.com/zubans/video-call-server/internal/logging"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// logger writes the recording subsystem log
+var logger = logging.New(logging.Recording)
+
+// Recording modes
+const (
+	// ModeFull captures every track of the room
+	ModeFull = "full"
+
+	// ModeScreenShare captures only screen-share video and the participants' audio,
+	// producing presentation-style recordings
+	ModeScreenShare = "screen_share"
+)
+
+// ErrInvalidMode is returned for unknown recording modes
+var ErrInvalidMode = errors.New("mode must be full or screen_share")
+
+// Track describes a published track offered to the recorder
+type Track struct {
+	ID          string
+	Kind        string
+	Source      string
+	MimeType    string
+	Participant string // display name of the publisher
+}
+
+// trackFile is the raw capture of one track
+type trackFile struct {
+	path        string
+	participant string
+	writer      media.Writer // nil when the codec cannot be captured
+	mu          sync.Mutex
+}
+
+// includes reports whether a recording captures a track
+func (rec *Recording) includes(track Track) bool {
+	if rec.Mode == ModeScreenShare {
+		return track.Kind == "audio" || track.Source == models.SourceScreenShare
+	}
+	return true
+}
+
+// IsRecording reports whether a room has an active recording
+func (r *Recorder) IsRecording(roomID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.active[roomID]) > 0
+}
+
+// WriteRTP captures a packet of a published track into every active recording of
+// the room that includes the track
+func (r *Recorder) WriteRTP(roomID string, track Track, packet *rtp.Packet) {
+	r.mu.RLock()
+	var targets []*Recording
+	for _, rec := range r.active[roomID] {
+		if rec.includes(track) {
+			targets = append(targets, rec)
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, rec := range targets {
+		file := r.trackFile(rec, track)
+		if file == nil {
+			continue
+		}
+
+		file.mu.Lock()
+		if file.writer != nil {
+			if err := file.writer.WriteRTP(packet); err != nil {
+				logger.Errorf("Failed to record track %s: %v", track.ID, err)
+				errreport.Capture(err, map[string]string{
+					"subsystem":    "recording",
+					"room_id":      roomID,
+					"recording_id": rec.ID,
+					"track_id":     track.ID,
+				})
+				file.writer.Close()
+				file.writer = nil
+			}
+		}
+		file.mu.Unlock()
+	}
+}
+
+// trackFile returns the capture file of a track in a recording, creating it on the first packet
+func (r *Recorder) trackFile(rec *Recording, track Track) *trackFile {
+	r.mu.RLock()
+	file, exists := r.files[rec.ID][track.ID]
+	r.mu.RUnlock()
+	if exists {
+		return file
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The recording may have stopped, or another packet created the file meanwhile
+	files, active := r.files[rec.ID]
+	if !active {
+		return nil
+	}
+	if file, exists := files[track.ID]; exists {
+		return file
+	}
+
+	file = &trackFile{participant: track.Participant}
+	writer, ext, err := newTrackWriter(track.MimeType)
+	if err == nil {
+		file.path = filepath.Join(rec.TracksDir, fmt.Sprintf("%s_%s%s", track.Source, sanitize(track.ID), ext))
+		if track.Source == "" {
+			file.path = filepath.Join(rec.TracksDir, sanitize(track.ID)+ext)
+		}
+		file.writer, err = writer(file.path)
+	}
+	if err != nil {
+		logger.Warnf("Recording %s skips track %s: %v", rec.ID, track.ID, err)
+		file.writer = nil
+	}
+	files[track.ID] = file
+
+	// Presentations are worth jumping to
+	if track.Kind == "video" && track.Source == models.SourceScreenShare {
+		rec.addChapter(ChapterScreenShare, track.Participant)
+	}
+
+	return file
+}
+
+// newTrackWriter returns a constructor of a raw file writer for a codec and the file extension
+func newTrackWriter(mimeType string) (func(path string) (media.Writer, error), string, error) {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeVP8), strings.ToLower(webrtc.MimeTypeAV1):
+		return func(path string) (media.Writer, error) {
+			return ivfwriter.New(path, ivfwriter.WithCodec(mimeType))
+		}, ".ivf", nil
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return func(path string) (media.Writer, error) {
+			return h264writer.New(path)
+		}, ".h264", nil
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return func(path string) (media.Writer, error) {
+			return oggwriter.New(path, 48000, 2)
+		}, ".ogg", nil
+	}
+	return nil, "", fmt.Errorf("unsupported codec %s", mimeType)
+}
+
+// deactivate stops capturing into a recording and returns the paths of the track files
+// that were written, recording their publishers; the caller holds r.mu
+func (r *Recorder) deactivate(recording *Recording) []string {
+	active := r.active[recording.RoomID][:0]
+	for _, other := range r.active[recording.RoomID] {
+		if other.ID != recording.ID {
+			active = append(active, other)
+		}
+	}
+	if len(active) == 0 {
+		delete(r.active, recording.RoomID)
+	} else {
+		r.active[recording.RoomID] = active
+	}
+
+	paths := []string{}
+	recording.Publishers = make(map[string]string)
+	for _, file := range r.files[recording.ID] {
+		file.mu.Lock()
+		if file.writer != nil {
+			if err := file.writer.Close(); err != nil {
+				logger.Errorf("Failed to close recorded track %s: %v", file.path, err)
+			}
+			file.writer = nil
+			paths = append(paths, file.path)
+			recording.Publishers[file.path] = file.participant
+		}
+		file.mu.Unlock()
+	}
+	delete(r.files, recording.ID)
+	sort.Strings(paths)
+	return paths
+}
+
+// TrackFiles returns the raw per-track files of a recording
+func (r *Recorder) TrackFiles(recordingID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		return nil
+	}
+	paths := make([]string, len(recording.Tracks))
+	copy(paths, recording.Tracks)
+	return paths
+}
+
+// Compose muxes the captured tracks of a stopped recording into its WebM file with FFmpeg:
+// the first captured video (the screen share in screen_share mode) with the watermark, if
+// any, and all audio mixed down. The per-track files are kept.
+func (r *Recorder) Compose(recordingID string) error {
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return fmt.Errorf("recording not found: %s", recordingID)
+	}
+	if recording.Active {
+		r.mu.RUnlock()
+		return fmt.Errorf("recording is still active: %s", recordingID)
+	}
+	output := recording.Filename
+	tracksDir := recording.TracksDir
+	startedAt := recording.StartedAt
+	publishers := recording.Publishers
+	watermark := r.watermark
+	var video, audio []string
+	for _, path := range recording.Tracks {
+		if filepath.Ext(path) == ".ogg" {
+			audio = append(audio, path)
+		} else {
+			video = append(video, path)
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(video) == 0 && len(audio) == 0 {
+		return nil
+	}
+
+	args := []string{"-y", "-loglevel", "error"}
+	if len(video) > 0 {
+		args = append(args, "-i", video[0])
+	}
+	for _, path := range audio {
+		args = append(args, "-i", path)
+	}
+
+	audioIndex := 0
+	var filters []string
+	if len(video) > 0 {
+		videoOutput := "0:v"
+		if watermark.Enabled() {
+			logoInput := 1 + len(audio)
+			if watermark.Logo != "" {
+				args = append(args, "-i", watermark.Logo)
+			}
+			filter, err := watermark.watermarkFilter("0:v", "marked", logoInput, tracksDir, publishers[video[0]], startedAt)
+			if err != nil {
+				return err
+			}
+			filters = append(filters, filter)
+			videoOutput = "[marked]"
+		}
+		args = append(args, "-map", videoOutput, "-c:v", "libvpx", "-b:v", "1M")
+		audioIndex = 1
+	}
+	switch {
+	case len(audio) == 1:
+		args = append(args, "-map", fmt.Sprintf("%d:a", audioIndex), "-c:a", "libopus")
+	case len(audio) > 1:
+		var inputs strings.Builder
+		for i := range audio {
+			fmt.Fprintf(&inputs, "[%d:a]", audioIndex+i)
+		}
+		filters = append(filters, fmt.Sprintf("%samix=inputs=%d:duration=longest[mix]", inputs.String(), len(audio)))
+		args = append(args, "-map", "[mix]", "-c:a", "libopus")
+	}
+	if len(filters) > 0 {
+		args = append(args, "-filter_complex", strings.Join(filters, ";"))
+	}
+	args = append(args, "-f", "webm", output)
+
+	if out, err := exec.Command(ffmpegPath(), args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ffmpegPath returns the FFmpeg binary used to compose recordings
+func ffmpegPath() string {
+	if path := os.Getenv("FFMPEG_PATH"); path != "" {
+		return path
+	}
+	return "ffmpeg"
+}
+
+// sanitize makes a track ID safe to use in a file name
+func sanitize(id string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+}
@@ -0,0 +1,120 @@
+package recording
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Results of checking an artifact against its manifest entry
+const (
+	CheckOK         = "ok"
+	CheckMismatch   = "mismatch"   // the content no longer matches its size or checksum
+	CheckMissing    = "missing"    // the file is gone
+	CheckUnreadable = "unreadable" // the file cannot be read, e.g. it fails decryption
+)
+
+// ArtifactCheck is the result of checking one artifact
+type ArtifactCheck struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Status   string `json:"status"`
+	Expected string `json:"expected_sha256"`
+	Actual   string `json:"actual_sha256,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Verification is the result of checking every artifact of a recording
+type Verification struct {
+	VerifiedAt time.Time       `json:"verified_at"`
+	OK         bool            `json:"ok"`
+	Artifacts  []ArtifactCheck `json:"artifacts"`
+}
+
+// Verify recomputes the checksums of the artifacts of a processed recording and
+// compares them with its manifest, detecting bit rot and tampering. Encrypted files
+// are decrypted, which also authenticates them. The result is kept on the recording.
+func (r *Recorder) Verify(recordingID string) (*Verification, error) {
+	r.mu.RLock()
+	recording, exists := r.recordings[recordingID]
+	if !exists {
+		r.mu.RUnlock()
+		return nil, fmt.Errorf("recording not found: %s", recordingID)
+	}
+	if err := offline(recording); err != nil {
+		r.mu.RUnlock()
+		return nil, err
+	}
+	if recording.Manifest == nil {
+		r.mu.RUnlock()
+		return nil, ErrNotProcessed
+	}
+	artifacts := recording.Manifest.Artifacts
+	r.mu.RUnlock()
+
+	verification := &Verification{OK: true, Artifacts: make([]ArtifactCheck, 0, len(artifacts))}
+	for _, artifact := range artifacts {
+		check := r.checkArtifact(artifact)
+		if check.Status != CheckOK {
+			verification.OK = false
+		}
+		verification.Artifacts = append(verification.Artifacts, check)
+	}
+	verification.VerifiedAt = time.Now()
+
+	r.mu.Lock()
+	if recording, exists := r.recordings[recordingID]; exists {
+		recording.Integrity = verification
+	}
+	r.mu.Unlock()
+	return verification, nil
+}
+
+// checkArtifact compares the content of an artifact with its manifest entry
+func (r *Recorder) checkArtifact(artifact Artifact) ArtifactCheck {
+	check := ArtifactCheck{Name: artifact.Name, Kind: artifact.Kind, Expected: artifact.SHA256}
+
+	file, err := r.Open(artifact.Path)
+	if err != nil {
+		check.Status = CheckUnreadable
+		if errors.Is(err, os.ErrNotExist) {
+			check.Status = CheckMissing
+		}
+		check.Error = err.Error()
+		return check
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		check.Status = CheckUnreadable
+		check.Error = err.Error()
+		return check
+	}
+	check.Actual = hex.EncodeToString(hash.Sum(nil))
+	if check.Actual != artifact.SHA256 || size != artifact.Size {
+		check.Status = CheckMismatch
+		return check
+	}
+	check.Status = CheckOK
+	return check
+}
+
+// Verifiable returns the IDs of the processed recordings stored on the node
+func (r *Recorder) Verifiable() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []string
+	for _, recording := range r.recordings {
+		if recording.Manifest != nil && recording.Storage == StorageLocal {
+			ids = append(ids, recording.ID)
+		}
+	}
+	return ids
+}
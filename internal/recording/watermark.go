@@ -0,0 +1,169 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Watermark positions
+const (
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+)
+
+// Watermark layout, in pixels
+const (
+	watermarkMargin     = 16
+	watermarkLogoHeight = 64
+	watermarkFontSize   = 24
+	watermarkLineHeight = 32
+)
+
+// ErrInvalidPosition is returned for unknown watermark positions
+var ErrInvalidPosition = errors.New("position must be top-left, top-right, bottom-left or bottom-right")
+
+// Watermark is an overlay rendered into the video of composed recordings, e.g. to
+// meet compliance rules requiring recordings to identify their origin
+type Watermark struct {
+	Logo        string `json:"logo,omitempty"`        // PNG image, such as an organization logo
+	Text        string `json:"text,omitempty"`        // fixed text, such as the organization name or a classification
+	Participant bool   `json:"participant,omitempty"` // name of the participant whose video is shown
+	Timestamp   bool   `json:"timestamp,omitempty"`   // wall-clock time of each frame, in UTC
+	Position    string `json:"position,omitempty"`    // corner of the overlay, bottom-right by default
+	Font        string `json:"font,omitempty"`        // font file for text; FFmpeg's default font otherwise
+}
+
+// Enabled reports whether the watermark renders anything
+func (w Watermark) Enabled() bool {
+	return w.Logo != "" || w.Text != "" || w.Participant || w.Timestamp
+}
+
+// Validate checks the position and that the logo and font files exist
+func (w Watermark) Validate() error {
+	switch w.Position {
+	case "", PositionTopLeft, PositionTopRight, PositionBottomLeft, PositionBottomRight:
+	default:
+		return ErrInvalidPosition
+	}
+	for _, path := range []string{w.Logo, w.Font} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetWatermark sets the watermark of recordings composed from now on
+func (r *Recorder) SetWatermark(watermark Watermark) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.watermark = watermark
+}
+
+// watermarkFilter returns the filtergraph drawing the watermark onto the video labelled
+// input as output, with the logo read from input logoInput. Text is passed to drawtext
+// in files written to dir, so it needs no escaping.
+func (w Watermark) watermarkFilter(input, output string, logoInput int, dir, participant string, startedAt time.Time) (string, error) {
+	top := w.Position == PositionTopLeft || w.Position == PositionTopRight
+	left := w.Position == PositionTopLeft || w.Position == PositionBottomLeft
+
+	var lines []string
+	if w.Text != "" {
+		lines = append(lines, w.Text)
+	}
+	if w.Participant && participant != "" {
+		lines = append(lines, participant)
+	}
+	if w.Timestamp {
+		lines = append(lines, fmt.Sprintf("%%{pts:gmtime:%d} UTC", startedAt.Unix()))
+	}
+
+	var filters []string
+	current := input
+	if w.Logo != "" {
+		x, y := overlayPosition(top, left)
+		filters = append(filters,
+			fmt.Sprintf("[%d:v]scale=-1:%d[logo]", logoInput, watermarkLogoHeight),
+			fmt.Sprintf("[%s][logo]overlay=x=%s:y=%s[logoed]", current, x, y))
+		current = "logoed"
+	}
+
+	var drawtext []string
+	for i, line := range lines {
+		path := filepath.Join(dir, fmt.Sprintf("watermark-%d.txt", i))
+		if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+			return "", fmt.Errorf("failed to write watermark text: %v", err)
+		}
+
+		// Only the timestamp line expands %{...}
+		expansion := "none"
+		if w.Timestamp && i == len(lines)-1 {
+			expansion = "normal"
+		}
+		x, y := textPosition(top, left, i, len(lines), w.Logo != "")
+		options := []string{
+			"textfile=" + escapeFilterValue(path),
+			"expansion=" + expansion,
+			fmt.Sprintf("fontsize=%d", watermarkFontSize),
+			"fontcolor=white", "borderw=2", "bordercolor=black@0.6",
+			"x=" + x, "y=" + y,
+		}
+		if w.Font != "" {
+			options = append(options, "fontfile="+escapeFilterValue(w.Font))
+		}
+		drawtext = append(drawtext, "drawtext="+strings.Join(options, ":"))
+	}
+	if len(drawtext) > 0 {
+		filters = append(filters, fmt.Sprintf("[%s]%s[%s]", current, strings.Join(drawtext, ","), output))
+	} else {
+		filters = append(filters, fmt.Sprintf("[%s]null[%s]", current, output))
+	}
+	return strings.Join(filters, ";"), nil
+}
+
+// overlayPosition returns the overlay filter coordinates of the logo
+func overlayPosition(top, left bool) (string, string) {
+	x, y := fmt.Sprint(watermarkMargin), fmt.Sprint(watermarkMargin)
+	if !left {
+		x = fmt.Sprintf("W-w-%d", watermarkMargin)
+	}
+	if !top {
+		y = fmt.Sprintf("H-h-%d", watermarkMargin)
+	}
+	return x, y
+}
+
+// textPosition returns the drawtext coordinates of a line of the watermark; lines
+// are stacked next to the logo, below it in top corners and above it in bottom ones
+func textPosition(top, left bool, line, lines int, logo bool) (string, string) {
+	x := fmt.Sprint(watermarkMargin)
+	if !left {
+		x = fmt.Sprintf("w-tw-%d", watermarkMargin)
+	}
+
+	offset := watermarkMargin
+	if logo {
+		offset += watermarkLogoHeight + watermarkMargin/2
+	}
+	if top {
+		return x, fmt.Sprint(offset + line*watermarkLineHeight)
+	}
+	return x, fmt.Sprintf("h-%d", offset+(lines-line)*watermarkLineHeight)
+}
+
+// escapeFilterValue escapes a filter option value for both levels of FFmpeg's
+// filtergraph parsing
+func escapeFilterValue(value string) string {
+	option := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(value)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(option)
+}
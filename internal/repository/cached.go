@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/cache"
+	"github.com/zubans/video-call-server/internal/chat"
+)
+
+// Cache key prefixes
+const (
+	roomKeyPrefix = "room:"
+	userKeyPrefix = "user:"
+	chatKeyPrefix = "chat:"
+)
+
+// NewCached puts a cache in front of the room and user lookups and the recent chat
+// history reads of repos. Values stay cached for at most ttl and are invalidated when
+// written through the returned repositories; writes in a transaction invalidate them
+// once it commits. The chatWindow most recent messages of a room are cached; reads of
+// more are not.
+func NewCached(repos Repositories, c cache.Cache, ttl time.Duration, chatWindow int) Repositories {
+	cached := Repositories{
+		Rooms:      &CachedRoomRepository{RoomRepository: repos.Rooms, cache: c, ttl: ttl},
+		Users:      &CachedUserRepository{UserRepository: repos.Users, cache: c, ttl: ttl},
+		Chat:       &CachedChatRepository{ChatRepository: repos.Chat, cache: c, ttl: ttl, window: chatWindow},
+		Recordings: repos.Recordings,
+		Outbox:     repos.Outbox,
+	}
+	if repos.Transactor != nil {
+		cached.Transactor = &cachedTransactor{repos: repos, cache: c, ttl: ttl, window: chatWindow}
+	}
+	return cached
+}
+
+// cachedTransactor runs transactions on cached repositories, holding back their
+// invalidations until the transaction commits so that no reader caches a value the
+// transaction is about to replace
+type cachedTransactor struct {
+	repos  Repositories
+	cache  cache.Cache
+	ttl    time.Duration
+	window int
+}
+
+// Atomic runs fn in a transaction and invalidates the values it wrote once it commits
+func (t *cachedTransactor) Atomic(ctx context.Context, fn func(Repositories) error) error {
+	deferred := &deferredCache{}
+	err := t.repos.Atomic(ctx, func(tx Repositories) error {
+		return fn(NewCached(tx, deferred, t.ttl, t.window))
+	})
+	if err != nil {
+		return err
+	}
+	return deferred.apply(ctx, t.cache)
+}
+
+// deferredCache records the invalidations made in a transaction; reads miss, as values
+// read in a transaction may not be committed
+type deferredCache struct {
+	keys     []string
+	prefixes []string
+}
+
+func (d *deferredCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (d *deferredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (d *deferredCache) Delete(ctx context.Context, keys ...string) error {
+	d.keys = append(d.keys, keys...)
+	return nil
+}
+
+func (d *deferredCache) DeletePrefix(ctx context.Context, prefix string) error {
+	d.prefixes = append(d.prefixes, prefix)
+	return nil
+}
+
+// apply makes the recorded invalidations on c
+func (d *deferredCache) apply(ctx context.Context, c cache.Cache) error {
+	if len(d.keys) > 0 {
+		if err := c.Delete(ctx, d.keys...); err != nil {
+			return err
+		}
+	}
+	for _, prefix := range d.prefixes {
+		if err := c.DeletePrefix(ctx, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CachedRoomRepository caches GetRoom of a RoomRepository
+type CachedRoomRepository struct {
+	RoomRepository
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// SaveRoom adds or replaces a room and invalidates its cached copy
+func (r *CachedRoomRepository) SaveRoom(ctx context.Context, room Room) error {
+	if err := r.RoomRepository.SaveRoom(ctx, room); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, roomKeyPrefix+room.ID)
+}
+
+// GetRoom returns a room by ID, from the cache when possible
+func (r *CachedRoomRepository) GetRoom(ctx context.Context, id string) (Room, error) {
+	var room Room
+	if cached(ctx, r.cache, roomKeyPrefix+id, &room) {
+		return room, nil
+	}
+
+	room, err := r.RoomRepository.GetRoom(ctx, id)
+	if err != nil {
+		return room, err
+	}
+	store(ctx, r.cache, roomKeyPrefix+id, room, r.ttl)
+	return room, nil
+}
+
+// DeleteRoom removes a room and its cached copy
+func (r *CachedRoomRepository) DeleteRoom(ctx context.Context, id string) error {
+	if err := r.RoomRepository.DeleteRoom(ctx, id); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, roomKeyPrefix+id)
+}
+
+// CachedUserRepository caches GetUser of a UserRepository
+type CachedUserRepository struct {
+	UserRepository
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// SaveUser adds or replaces an account and invalidates its cached copy
+func (r *CachedUserRepository) SaveUser(ctx context.Context, user auth.UserBackup) error {
+	if err := r.UserRepository.SaveUser(ctx, user); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, userKeyPrefix+user.ID)
+}
+
+// GetUser returns an account by ID, from the cache when possible
+func (r *CachedUserRepository) GetUser(ctx context.Context, id string) (auth.UserBackup, error) {
+	var user auth.UserBackup
+	if cached(ctx, r.cache, userKeyPrefix+id, &user) {
+		return user, nil
+	}
+
+	user, err := r.UserRepository.GetUser(ctx, id)
+	if err != nil {
+		return user, err
+	}
+	store(ctx, r.cache, userKeyPrefix+id, user, r.ttl)
+	return user, nil
+}
+
+// DeleteUser removes an account and its cached copy
+func (r *CachedUserRepository) DeleteUser(ctx context.Context, id string) error {
+	if err := r.UserRepository.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, userKeyPrefix+id)
+}
+
+// CachedChatRepository caches the most recent messages of each room read through a
+// ChatRepository
+type CachedChatRepository struct {
+	ChatRepository
+	cache  cache.Cache
+	ttl    time.Duration
+	window int
+}
+
+// SaveMessage adds or replaces a message and invalidates the cached history of its room
+func (r *CachedChatRepository) SaveMessage(ctx context.Context, message chat.Message) error {
+	if err := r.ChatRepository.SaveMessage(ctx, message); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, chatKeyPrefix+message.RoomID)
+}
+
+// ListMessages returns the most recent messages of a room, oldest first, from the cache
+// when no more than the cached window are asked for
+func (r *CachedChatRepository) ListMessages(ctx context.Context, roomID string, limit int) ([]chat.Message, error) {
+	if limit <= 0 || limit > r.window {
+		return r.ChatRepository.ListMessages(ctx, roomID, limit)
+	}
+
+	var history []chat.Message
+	if !cached(ctx, r.cache, chatKeyPrefix+roomID, &history) {
+		var err error
+		history, err = r.ChatRepository.ListMessages(ctx, roomID, r.window)
+		if err != nil {
+			return nil, err
+		}
+		store(ctx, r.cache, chatKeyPrefix+roomID, history, r.ttl)
+	}
+
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return history, nil
+}
+
+// DeleteRoomMessages removes every message of a room and its cached history
+func (r *CachedChatRepository) DeleteRoomMessages(ctx context.Context, roomID string) error {
+	if err := r.ChatRepository.DeleteRoomMessages(ctx, roomID); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, chatKeyPrefix+roomID)
+}
+
+// PurgeDeletedMessages removes messages soft-deleted before a time, except those under
+// legal hold; any room may be affected, so every cached history is invalidated
+func (r *CachedChatRepository) PurgeDeletedMessages(ctx context.Context, before time.Time, held chat.Held) error {
+	if err := r.ChatRepository.PurgeDeletedMessages(ctx, before, held); err != nil {
+		return err
+	}
+	return r.cache.DeletePrefix(ctx, chatKeyPrefix)
+}
+
+// cached decodes the value cached under key into target and reports whether it was
+// found. A failing cache counts as a miss, so reads fall back to the repository.
+func cached(ctx context.Context, c cache.Cache, key string, target interface{}) bool {
+	data, found, err := c.Get(ctx, key)
+	if err != nil || !found {
+		return false
+	}
+	return json.Unmarshal(data, target) == nil
+}
+
+// store caches a value read from a repository; failures only cost a later miss
+func store(ctx context.Context, c cache.Cache, key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	c.Set(ctx, key, data, ttl)
+}
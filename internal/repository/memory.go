@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// NewMemory creates in-memory repositories; the chat repository keeps the chatLimit most
+// recent messages of each room, or all of them if chatLimit is 0. They have no
+// Transactor: their writes cannot fail, and nothing survives a restart anyway.
+func NewMemory(chatLimit int) Repositories {
+	return Repositories{
+		Rooms:      NewMemoryRoomRepository(),
+		Users:      NewMemoryUserRepository(),
+		Chat:       NewMemoryChatRepository(chatLimit),
+		Recordings: NewMemoryRecordingRepository(),
+		Outbox:     NewMemoryOutboxRepository(),
+	}
+}
+
+// MemoryRoomRepository keeps rooms in memory
+type MemoryRoomRepository struct {
+	rooms map[string]Room
+	mu    sync.RWMutex
+}
+
+// NewMemoryRoomRepository creates a new MemoryRoomRepository
+func NewMemoryRoomRepository() *MemoryRoomRepository {
+	return &MemoryRoomRepository{
+		rooms: make(map[string]Room),
+	}
+}
+
+// SaveRoom adds or replaces a room
+func (m *MemoryRoomRepository) SaveRoom(ctx context.Context, room Room) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if room.Schedule != nil {
+		schedule := *room.Schedule
+		room.Schedule = &schedule
+	}
+	m.rooms[room.ID] = room
+	return nil
+}
+
+// GetRoom returns a room by ID
+func (m *MemoryRoomRepository) GetRoom(ctx context.Context, id string) (Room, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	room, exists := m.rooms[id]
+	if !exists {
+		return Room{}, ErrNotFound
+	}
+	return room, nil
+}
+
+// ListRooms returns every room, oldest first
+func (m *MemoryRoomRepository) ListRooms(ctx context.Context) ([]Room, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		list = append(list, room)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// DeleteRoom removes a room; removing an unknown room is not an error
+func (m *MemoryRoomRepository) DeleteRoom(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.rooms, id)
+	return nil
+}
+
+// MemoryUserRepository keeps accounts in memory
+type MemoryUserRepository struct {
+	users map[string]auth.UserBackup
+	mu    sync.RWMutex
+}
+
+// NewMemoryUserRepository creates a new MemoryUserRepository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: make(map[string]auth.UserBackup),
+	}
+}
+
+// SaveUser adds or replaces an account
+func (m *MemoryUserRepository) SaveUser(ctx context.Context, user auth.UserBackup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.users[user.ID] = user
+	return nil
+}
+
+// GetUser returns an account by ID
+func (m *MemoryUserRepository) GetUser(ctx context.Context, id string) (auth.UserBackup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, exists := m.users[id]
+	if !exists {
+		return auth.UserBackup{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// ListUsers returns every account, by ID
+func (m *MemoryUserRepository) ListUsers(ctx context.Context) ([]auth.UserBackup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]auth.UserBackup, 0, len(m.users))
+	for _, user := range m.users {
+		list = append(list, user)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// DeleteUser removes an account; removing an unknown account is not an error
+func (m *MemoryUserRepository) DeleteUser(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.users, id)
+	return nil
+}
+
+// MemoryChatRepository keeps chat messages in memory, oldest first by room
+type MemoryChatRepository struct {
+	rooms map[string][]chat.Message
+	limit int
+	mu    sync.RWMutex
+}
+
+// NewMemoryChatRepository creates a MemoryChatRepository keeping the limit most recent
+// messages of each room, or all of them if limit is 0
+func NewMemoryChatRepository(limit int) *MemoryChatRepository {
+	return &MemoryChatRepository{
+		rooms: make(map[string][]chat.Message),
+		limit: limit,
+	}
+}
+
+// SaveMessage adds or replaces a message
+func (m *MemoryChatRepository) SaveMessage(ctx context.Context, message chat.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	history := m.rooms[message.RoomID]
+	for i := range history {
+		if history[i].ID == message.ID {
+			history[i] = message
+			return nil
+		}
+	}
+
+	history = append(history, message)
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	if m.limit > 0 && len(history) > m.limit {
+		history = history[len(history)-m.limit:]
+	}
+	m.rooms[message.RoomID] = history
+	return nil
+}
+
+// ListMessages returns the most recent messages of a room, oldest first
+func (m *MemoryChatRepository) ListMessages(ctx context.Context, roomID string, limit int) ([]chat.Message, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.rooms[roomID]
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return append([]chat.Message(nil), history...), nil
+}
+
+// DeleteRoomMessages removes every message of a room
+func (m *MemoryChatRepository) DeleteRoomMessages(ctx context.Context, roomID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.rooms, roomID)
+	return nil
+}
+
+// PurgeDeletedMessages removes messages soft-deleted before a time, except those under
+// legal hold
+func (m *MemoryChatRepository) PurgeDeletedMessages(ctx context.Context, before time.Time, held chat.Held) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for roomID, history := range m.rooms {
+		kept := history[:0]
+		for _, message := range history {
+			if message.DeletedAt == nil || !message.DeletedAt.Before(before) || held.Covers(&message) {
+				kept = append(kept, message)
+			}
+		}
+		m.rooms[roomID] = kept
+	}
+	return nil
+}
+
+// MemoryRecordingRepository keeps recording metadata in memory
+type MemoryRecordingRepository struct {
+	recordings map[string]recording.Recording
+	mu         sync.RWMutex
+}
+
+// NewMemoryRecordingRepository creates a new MemoryRecordingRepository
+func NewMemoryRecordingRepository() *MemoryRecordingRepository {
+	return &MemoryRecordingRepository{
+		recordings: make(map[string]recording.Recording),
+	}
+}
+
+// SaveRecording adds or replaces a recording
+func (m *MemoryRecordingRepository) SaveRecording(ctx context.Context, rec recording.Recording) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recordings[rec.ID] = rec
+	return nil
+}
+
+// GetRecording returns a recording by ID
+func (m *MemoryRecordingRepository) GetRecording(ctx context.Context, id string) (recording.Recording, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, exists := m.recordings[id]
+	if !exists {
+		return recording.Recording{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// ListRecordings returns every recording, oldest first
+func (m *MemoryRecordingRepository) ListRecordings(ctx context.Context) ([]recording.Recording, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]recording.Recording, 0, len(m.recordings))
+	for _, rec := range m.recordings {
+		list = append(list, rec)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+	return list, nil
+}
+
+// DeleteRecording removes a recording; removing an unknown recording is not an error
+func (m *MemoryRecordingRepository) DeleteRecording(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.recordings, id)
+	return nil
+}
+
+// MemoryOutboxRepository keeps outbound events in memory
+type MemoryOutboxRepository struct {
+	entries []OutboxEntry // by ID
+	lastID  int64
+	mu      sync.Mutex
+}
+
+// NewMemoryOutboxRepository creates a new MemoryOutboxRepository
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{}
+}
+
+// Enqueue adds entries, due for delivery at once
+func (m *MemoryOutboxRepository) Enqueue(ctx context.Context, entries ...OutboxEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		m.lastID++
+		entry.ID = m.lastID
+		entry.CreatedAt = now
+		entry.NextAttempt = now
+		m.entries = append(m.entries, entry)
+	}
+	return nil
+}
+
+// Pending returns up to limit entries queued for a destination, oldest first
+func (m *MemoryOutboxRepository) Pending(ctx context.Context, destination string, limit int) ([]OutboxEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var queued []OutboxEntry
+	for _, entry := range m.entries {
+		if len(queued) == limit {
+			break
+		}
+		if entry.Destination == destination {
+			queued = append(queued, entry)
+		}
+	}
+	return queued, nil
+}
+
+// Retry records a failed attempt and when to attempt delivery again
+func (m *MemoryOutboxRepository) Retry(ctx context.Context, id int64, next time.Time, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, ok := m.find(id); ok {
+		m.entries[i].Attempts++
+		m.entries[i].NextAttempt = next
+		m.entries[i].LastError = reason
+	}
+	return nil
+}
+
+// Remove removes an entry; removing an unknown entry is not an error
+func (m *MemoryOutboxRepository) Remove(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i, ok := m.find(id); ok {
+		m.entries = append(m.entries[:i], m.entries[i+1:]...)
+	}
+	return nil
+}
+
+// find returns the index of an entry; the caller holds m.mu
+func (m *MemoryOutboxRepository) find(id int64) (int, bool) {
+	i := sort.Search(len(m.entries), func(i int) bool {
+		return m.entries[i].ID >= id
+	})
+	return i, i < len(m.entries) && m.entries[i].ID == id
+}
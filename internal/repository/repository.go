@@ -0,0 +1,127 @@
+// Package repository defines how the server persists rooms, users, chat messages and
+// recordings. The server keeps its working state in memory and writes changes through
+// to the repositories, from which it reloads that state when it starts. In-memory
+// implementations are provided; the storage package implements them on SQL.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// ErrNotFound is returned when an entry does not exist
+var ErrNotFound = errors.New("not found")
+
+// Room is the persisted metadata of a room; participants and media are not kept
+type Room struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	CreatorID string               `json:"creator_id"`
+	TenantID  string               `json:"tenant_id,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	IsActive  bool                 `json:"is_active"`
+	IsPublic  bool                 `json:"is_public"`
+	JoinCode  string               `json:"join_code"`
+	Settings  models.RoomSettings  `json:"settings"`
+	Schedule  *models.RoomSchedule `json:"schedule,omitempty"`
+	EndedAt   time.Time            `json:"ended_at,omitempty"`
+	DeletedAt time.Time            `json:"deleted_at,omitempty"` // set while the room is soft-deleted
+	DeletedBy string               `json:"deleted_by,omitempty"`
+	LegalHold *models.LegalHold    `json:"legal_hold,omitempty"`
+}
+
+// RoomRepository persists room metadata
+type RoomRepository interface {
+	// SaveRoom adds a room or replaces the room with its ID
+	SaveRoom(ctx context.Context, room Room) error
+	GetRoom(ctx context.Context, id string) (Room, error)
+	ListRooms(ctx context.Context) ([]Room, error)
+	DeleteRoom(ctx context.Context, id string) error
+}
+
+// UserRepository persists accounts, including password hashes and SSO subjects
+type UserRepository interface {
+	// SaveUser adds an account or replaces the account with its ID
+	SaveUser(ctx context.Context, user auth.UserBackup) error
+	GetUser(ctx context.Context, id string) (auth.UserBackup, error)
+	ListUsers(ctx context.Context) ([]auth.UserBackup, error)
+	DeleteUser(ctx context.Context, id string) error
+}
+
+// ChatRepository persists the chat history of rooms, soft-deleted messages included
+type ChatRepository interface {
+	// SaveMessage adds a message or replaces the message with its ID
+	SaveMessage(ctx context.Context, message chat.Message) error
+	// ListMessages returns the most recent messages of a room, oldest first; limit 0 returns all
+	ListMessages(ctx context.Context, roomID string, limit int) ([]chat.Message, error)
+	DeleteRoomMessages(ctx context.Context, roomID string) error
+	// PurgeDeletedMessages removes messages soft-deleted before a time, except those
+	// under legal hold
+	PurgeDeletedMessages(ctx context.Context, before time.Time, held chat.Held) error
+}
+
+// RecordingRepository persists the metadata of finished recordings; media files stay
+// in the recordings directory
+type RecordingRepository interface {
+	// SaveRecording adds a recording or replaces the recording with its ID
+	SaveRecording(ctx context.Context, recording recording.Recording) error
+	GetRecording(ctx context.Context, id string) (recording.Recording, error)
+	ListRecordings(ctx context.Context) ([]recording.Recording, error)
+	DeleteRecording(ctx context.Context, id string) error
+}
+
+// OutboxEntry is an event waiting to be delivered to one destination
+type OutboxEntry struct {
+	ID          int64
+	Destination string // e.g. "broker" or "webhook:<url>"
+	EventType   string
+	Body        []byte // the event encoded as the destination receives it
+	CreatedAt   time.Time
+	Attempts    int // failed delivery attempts so far
+	NextAttempt time.Time
+	LastError   string
+}
+
+// OutboxRepository persists outbound events until they are delivered, so that events
+// survive a crash between a state change and its delivery
+type OutboxRepository interface {
+	// Enqueue adds entries, due for delivery at once
+	Enqueue(ctx context.Context, entries ...OutboxEntry) error
+	// Pending returns up to limit entries queued for a destination, oldest first
+	Pending(ctx context.Context, destination string, limit int) ([]OutboxEntry, error)
+	// Retry records a failed attempt and when to attempt delivery again
+	Retry(ctx context.Context, id int64, next time.Time, reason string) error
+	// Remove removes a delivered or abandoned entry
+	Remove(ctx context.Context, id int64) error
+}
+
+// Transactor runs writes to several repositories so that they take effect together or
+// not at all
+type Transactor interface {
+	// Atomic runs fn with repositories whose writes are committed if fn returns nil
+	Atomic(ctx context.Context, fn func(Repositories) error) error
+}
+
+// Repositories are the repositories the server persists its state to
+type Repositories struct {
+	Rooms      RoomRepository
+	Users      UserRepository
+	Chat       ChatRepository
+	Recordings RecordingRepository
+	Outbox     OutboxRepository
+	Transactor Transactor // nil if writes cannot be grouped
+}
+
+// Atomic runs fn in a transaction of r.Transactor, or directly on r when there is none
+func (r Repositories) Atomic(ctx context.Context, fn func(Repositories) error) error {
+	if r.Transactor == nil {
+		return fn(r)
+	}
+	return r.Transactor.Atomic(ctx, fn)
+}
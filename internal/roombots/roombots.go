@@ -0,0 +1,389 @@
+package roombots
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/hostpolicy"
+	"github.com/zubans/video-call-server/internal/logging"
+	"github.com/zubans/video-call-server/internal/webhooks"
+)
+
+// logger writes the server subsystem log
+var logger = logging.New(logging.Server)
+
+// secretPrefix marks bot secrets so they are recognizable in configs and logs
+const secretPrefix = "vcb_"
+
+// EventTypes lists the room events that can be delivered to bots
+var EventTypes = []string{events.ParticipantJoined, events.ParticipantLeft, events.ChatMessage, events.RecordingReady, events.NotesReady, events.RoomEnded}
+
+// Actions bots can take in a room
+const (
+	ActionChat              = "chat"
+	ActionRemoveParticipant = "remove_participant"
+	ActionDeleteMessage     = "delete_message"
+)
+
+// Delivery settings
+const (
+	deliveryTimeout  = 10 * time.Second
+	deliveryAttempts = 3
+	retryBackoff     = 2 * time.Second
+
+	// Largest bot response read, in bytes
+	maxResponseSize = 64 << 10
+
+	// Most actions applied from one response
+	maxActions = 20
+)
+
+var (
+	// ErrBotNotFound is returned for unknown bots and bots of other users
+	ErrBotNotFound = errors.New("bot not found")
+
+	// ErrInvalidURL is returned for endpoints that are not http(s)
+	ErrInvalidURL = errors.New("url must be an http or https URL")
+
+	// ErrInvalidEvent is returned when subscribing to an event bots cannot receive
+	ErrInvalidEvent = errors.New("invalid event type")
+
+	// ErrInvalidAction is returned for malformed actions
+	ErrInvalidAction = errors.New("invalid action")
+)
+
+// Bot is an external service receiving the events of a room, or of every room its
+// owner creates, at an HTTP endpoint. It answers with actions, such as chat messages
+// or moderation, taken on behalf of the owner. Deliveries are signed with the bot's
+// secret, which also authenticates the bot when it acts later on its own.
+type Bot struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	OwnerID   string    `json:"owner_id"`
+	RoomID    string    `json:"room_id,omitempty"` // empty for every room of the owner
+	URLHost   string    `json:"url_host"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	url       string
+	secret    string
+}
+
+// subscribed reports whether the bot receives an event type
+func (b *Bot) subscribed(eventType string) bool {
+	for _, t := range b.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Action is something a bot does in a room
+type Action struct {
+	Type      string `json:"type"`
+	Message   string `json:"message,omitempty"`    // chat
+	ClientID  string `json:"client_id,omitempty"`  // remove_participant
+	MessageID string `json:"message_id,omitempty"` // delete_message
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Validate checks that the action has the fields its type needs
+func (a *Action) Validate() error {
+	switch a.Type {
+	case ActionChat:
+		if strings.TrimSpace(a.Message) == "" {
+			return fmt.Errorf("%w: message is required", ErrInvalidAction)
+		}
+	case ActionRemoveParticipant:
+		if a.ClientID == "" {
+			return fmt.Errorf("%w: client_id is required", ErrInvalidAction)
+		}
+	case ActionDeleteMessage:
+		if a.MessageID == "" {
+			return fmt.Errorf("%w: message_id is required", ErrInvalidAction)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAction, a.Type)
+	}
+	return nil
+}
+
+// Delivery is the body posted to a bot for each event
+type Delivery struct {
+	BotID string `json:"bot_id"`
+	events.Event
+}
+
+// Response is the optional JSON a bot answers a delivery with
+type Response struct {
+	Actions []Action `json:"actions"`
+}
+
+// ActionHandler applies the actions of a bot in a room, returning an error per action
+type ActionHandler func(bot Bot, roomID string, actions []Action) []error
+
+// Manager stores bots in memory and delivers room events to them
+type Manager struct {
+	bots    map[string]*Bot
+	mu      sync.RWMutex
+	client  *http.Client
+	hosts   hostpolicy.Policy // hosts bot endpoints may be on
+	ownerOf func(roomID string) string
+	handle  ActionHandler
+}
+
+// NewManager creates a new Manager; ownerOf returns the creator of a room, handle
+// applies the actions bots answer deliveries with and hosts limits where bot
+// endpoints may be, both when a bot is registered and on every delivery
+func NewManager(ownerOf func(roomID string) string, handle ActionHandler, hosts hostpolicy.Policy) *Manager {
+	return &Manager{
+		bots:    make(map[string]*Bot),
+		client:  hosts.Client(deliveryTimeout),
+		hosts:   hosts,
+		ownerOf: ownerOf,
+		handle:  handle,
+	}
+}
+
+// generateSecret returns a new random bot secret
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Create registers a bot and returns it with its secret; an empty event list
+// subscribes to every event in EventTypes
+func (m *Manager) Create(name, ownerID, roomID, endpoint string, eventTypes []string) (*Bot, string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, "", ErrInvalidURL
+	}
+	if err := m.hosts.CheckHost(parsed.Hostname()); err != nil {
+		return nil, "", err
+	}
+	for _, eventType := range eventTypes {
+		known := false
+		for _, t := range EventTypes {
+			known = known || t == eventType
+		}
+		if !known {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidEvent, eventType)
+		}
+	}
+	if len(eventTypes) == 0 {
+		eventTypes = EventTypes
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	bot := &Bot{
+		ID:        uuid.New().String(),
+		Name:      name,
+		OwnerID:   ownerID,
+		RoomID:    roomID,
+		URLHost:   parsed.Host,
+		Events:    append([]string(nil), eventTypes...),
+		CreatedAt: time.Now(),
+		url:       endpoint,
+		secret:    secret,
+	}
+
+	m.mu.Lock()
+	m.bots[bot.ID] = bot
+	m.mu.Unlock()
+
+	copied := *bot
+	return &copied, secret, nil
+}
+
+// Delete removes a bot of an owner
+func (m *Manager) Delete(id, ownerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bot, exists := m.bots[id]
+	if !exists || bot.OwnerID != ownerID {
+		return ErrBotNotFound
+	}
+	delete(m.bots, id)
+	return nil
+}
+
+// List returns copies of the bots of an owner, oldest first
+func (m *Manager) List(ownerID string) []Bot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bots := []Bot{}
+	for _, bot := range m.bots {
+		if bot.OwnerID == ownerID {
+			bots = append(bots, *bot)
+		}
+	}
+	sort.Slice(bots, func(i, j int) bool {
+		return bots[i].CreatedAt.Before(bots[j].CreatedAt)
+	})
+	return bots
+}
+
+// Authenticate returns the bot a secret belongs to
+func (m *Manager) Authenticate(secret string) (*Bot, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return nil, ErrBotNotFound
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, bot := range m.bots {
+		if subtle.ConstantTimeCompare([]byte(bot.secret), []byte(secret)) == 1 {
+			copied := *bot
+			return &copied, nil
+		}
+	}
+	return nil, ErrBotNotFound
+}
+
+// CanAccess reports whether a bot serves a room
+func (m *Manager) CanAccess(bot *Bot, roomID string) bool {
+	if bot.RoomID != "" {
+		return bot.RoomID == roomID
+	}
+	return m.ownerOf(roomID) == bot.OwnerID
+}
+
+// Run delivers events from the event bus until the channel is closed
+func (m *Manager) Run(ch <-chan events.Event) {
+	for event := range ch {
+		m.Dispatch(event)
+	}
+}
+
+// Dispatch delivers a room event to the bots serving the room. Events carrying the
+// "bot_id" of a bot, such as its own chat messages, are not sent back to it.
+func (m *Manager) Dispatch(event events.Event) {
+	if event.RoomID == "" {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	m.mu.RLock()
+	var targets []Bot
+	for _, bot := range m.bots {
+		if bot.subscribed(event.Type) && event.Data["bot_id"] != bot.ID {
+			targets = append(targets, *bot)
+		}
+	}
+	m.mu.RUnlock()
+
+	// Slow bots must not hold up other events
+	for _, bot := range targets {
+		if m.CanAccess(&bot, event.RoomID) {
+			go m.deliver(bot, event)
+		}
+	}
+}
+
+// deliver posts an event to a bot, retrying failed attempts, and applies the actions
+// it answers with
+func (m *Manager) deliver(bot Bot, event events.Event) {
+	body, err := json.Marshal(Delivery{BotID: bot.ID, Event: event})
+	if err != nil {
+		logger.Errorf("Failed to encode %s for bot %s: %v", event.Type, bot.Name, err)
+		return
+	}
+
+	var resp *Response
+	for attempt := 1; attempt <= deliveryAttempts; attempt++ {
+		if resp, err = m.post(bot, event.Type, body); err == nil {
+			break
+		}
+		if attempt < deliveryAttempts {
+			time.Sleep(time.Duration(attempt) * retryBackoff)
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to deliver %s to bot %s: %v", event.Type, bot.Name, err)
+		return
+	}
+
+	if len(resp.Actions) > maxActions {
+		logger.Warnf("Bot %s answered %s with %d actions, applying the first %d", bot.Name, event.Type, len(resp.Actions), maxActions)
+		resp.Actions = resp.Actions[:maxActions]
+	}
+	var actions []Action
+	for _, action := range resp.Actions {
+		if err := action.Validate(); err != nil {
+			logger.Warnf("Bot %s answered %s with an %v", bot.Name, event.Type, err)
+			continue
+		}
+		actions = append(actions, action)
+	}
+	if len(actions) == 0 {
+		return
+	}
+
+	for i, err := range m.handle(bot, event.RoomID, actions) {
+		if err != nil {
+			logger.Warnf("Failed to apply %s action of bot %s in room %s: %v", actions[i].Type, bot.Name, event.RoomID, err)
+		}
+	}
+}
+
+// post makes a single delivery attempt and decodes the bot's response; an empty
+// response body means no actions
+func (m *Manager) post(bot Bot, eventType string, body []byte) (*Response, error) {
+	req, err := http.NewRequest(http.MethodPost, bot.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooks.EventHeader, eventType)
+	req.Header.Set(webhooks.SignatureHeader, webhooks.Sign([]byte(bot.secret), body))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	var response Response
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &response); err != nil {
+			// The event was delivered; only the answer is unusable
+			logger.Warnf("Bot %s answered %s with invalid JSON: %v", bot.Name, eventType, err)
+		}
+	}
+	return &response, nil
+}
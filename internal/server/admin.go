@@ -0,0 +1,168 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/audit"
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// adminBootstrapHeader carries ADMIN_BOOTSTRAP_TOKEN when registering the first admin
+const adminBootstrapHeader = "X-Admin-Bootstrap-Token"
+
+// adminMiddleware only lets users with the admin role in the default tenant through
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Admins of other tenants moderate their own rooms but do not operate the deployment
+		if c.GetString("role") != auth.RoleAdmin || tenantID(c) != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Admin access required")})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isAdminUsername reports whether a username is listed in ADMIN_USERS
+func (s *Server) isAdminUsername(username string) bool {
+	for _, admin := range s.settings().AdminUsers {
+		if admin == username {
+			return true
+		}
+	}
+	return false
+}
+
+// promoteAdminUsers grants the admin role to the existing accounts of the default
+// tenant named in ADMIN_USERS. Names are only matched when the configuration is
+// loaded: registering, signing in with SSO or being provisioned under a listed name
+// never grants admin.
+func (s *Server) promoteAdminUsers(usernames []string) {
+	for _, username := range usernames {
+		user, exists := auth.GetUserByUsername("", username)
+		if !exists {
+			serverLog.Warnf("ADMIN_USERS names %q, but there is no such account", username)
+			continue
+		}
+		if user.Role == auth.RoleAdmin {
+			continue
+		}
+
+		auth.SetUserRole(user.ID, auth.RoleAdmin)
+		s.saveUser(user.ID)
+		s.audit.Record(audit.Entry{
+			Actor:  "ADMIN_USERS",
+			Action: "user.promote",
+			Target: user.ID,
+		})
+		serverLog.Infof("Granted the admin role to %s from ADMIN_USERS", username)
+	}
+}
+
+// validBootstrapToken reports whether a token matches ADMIN_BOOTSTRAP_TOKEN
+func (s *Server) validBootstrapToken(token string) bool {
+	return s.adminBootstrapToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminBootstrapToken)) == 1
+}
+
+// recordAudit stores an audit entry for the current user
+func (s *Server) recordAudit(c *gin.Context, action, target string, details map[string]string) {
+	s.audit.Record(audit.Entry{
+		ActorID: c.GetString("user_id"),
+		Actor:   c.GetString("username"),
+		Action:  action,
+		Target:  target,
+		Details: details,
+	})
+}
+
+// findClient locates a participant by client ID across all rooms
+func (s *Server) findClient(clientID string) (*models.Room, *models.Client, bool) {
+	s.roomManager.Mu.RLock()
+	defer s.roomManager.Mu.RUnlock()
+
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		client, exists := room.Clients[clientID]
+		room.Mu.RUnlock()
+		if exists {
+			return room, client, true
+		}
+	}
+	return nil, nil, false
+}
+
+// removeClient closes a participant's peer connection and signal channel and removes it from the room
+func (s *Server) removeClient(room *models.Room, client *models.Client) {
+	if !s.dropClient(room, client) {
+		return
+	}
+
+	// Close peer connection
+	if client.Conn != nil {
+		client.Conn.Close()
+	}
+
+	// The hold music stops with its last listener
+	s.stopHoldMusic(room, client)
+
+	// A queue call ends when its agent leaves
+	s.endQueueCall(room.ID, client.UserID)
+
+	// End the participant's session; its signal channel and WebSockets are released with it
+	s.releaseClient(client.ID)
+}
+
+// adminDisconnectHandler force-closes the WebSocket and peer connection of a client
+func (s *Server) adminDisconnectHandler(c *gin.Context) {
+	clientID := c.Param("client_id")
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The body is optional
+	_ = c.ShouldBindJSON(&req)
+
+	room, client, found := s.findClient(clientID)
+	websockets := s.hub.DisconnectSender(clientID)
+
+	if !found && websockets == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Client not found")})
+		return
+	}
+
+	details := map[string]string{
+		"websockets_closed": strconv.Itoa(websockets),
+		"reason":            req.Reason,
+	}
+	if found {
+		s.removeClient(room, client)
+		details["room_id"] = room.ID
+		details["user_id"] = client.UserID
+	}
+
+	s.recordAudit(c, "connection.disconnect", clientID, details)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Client disconnected",
+		"client_id":         clientID,
+		"peer_closed":       found,
+		"websockets_closed": websockets,
+	})
+}
+
+// adminAuditHandler lists recent audit entries, optionally of an action (or actions
+// with a prefix ending in a dot) and a target
+func (s *Server) adminAuditHandler(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	filter := audit.Filter{Action: c.Query("action"), Target: c.Query("target")}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": s.audit.Recent(limit, filter),
+	})
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+const (
+	// qualitySampleInterval is how often the connection quality of participants is
+	// sampled for the call analytics
+	qualitySampleInterval = 10 * time.Second
+
+	// activityFlushInterval is how often the talk and camera time measured by a
+	// forwarding loop is added to the call log
+	activityFlushInterval = time.Second
+
+	// maxPacketGap caps the time a single packet counts for, so a stalled track does
+	// not count as active
+	maxPacketGap = 200 * time.Millisecond
+
+	// speechLevel is the audio level, in -dBov, below which audio counts as speech
+	speechLevel = 50
+)
+
+// mediaActivity measures how long a forwarded track was active: audio while it carried
+// speech, video while it carried frames
+type mediaActivity struct {
+	kind    string
+	levelID uint8 // ID of the audio level header extension, 0 if not negotiated
+	last    time.Time
+	flushed time.Time
+	active  time.Duration // since the last flush
+}
+
+// newMediaActivity creates the activity of a track of a kind
+func newMediaActivity(kind string, levelID uint8) *mediaActivity {
+	now := time.Now()
+	return &mediaActivity{kind: kind, levelID: levelID, last: now, flushed: now}
+}
+
+// audioLevelID returns the ID negotiated for the audio level header extension of a
+// receiver, or 0 if there is none
+func audioLevelID(receiver *webrtc.RTPReceiver) uint8 {
+	if receiver == nil {
+		return 0
+	}
+	for _, extension := range receiver.GetParameters().HeaderExtensions {
+		if extension.URI == sdp.AudioLevelURI {
+			return uint8(extension.ID)
+		}
+	}
+	return 0
+}
+
+// observe accounts for a received packet and reports whether the activity is due to be
+// added to the call log
+func (a *mediaActivity) observe(packet *rtp.Packet, now time.Time) bool {
+	gap := now.Sub(a.last)
+	if gap > maxPacketGap {
+		gap = maxPacketGap
+	}
+	a.last = now
+
+	if a.kind == webrtc.RTPCodecTypeVideo.String() || a.speech(packet) {
+		a.active += gap
+	}
+	return now.Sub(a.flushed) >= activityFlushInterval
+}
+
+// speech reports whether an audio packet carries speech according to its audio level
+func (a *mediaActivity) speech(packet *rtp.Packet) bool {
+	if a.levelID == 0 {
+		return false
+	}
+	payload := packet.GetExtension(a.levelID)
+	if payload == nil {
+		return false
+	}
+	var level rtp.AudioLevelExtension
+	if err := level.Unmarshal(payload); err != nil {
+		return false
+	}
+	return level.Level < speechLevel
+}
+
+// recordActivity adds the activity measured since the last call to the call log.
+// Video counts as camera time unless the owner turned the camera off or shares a
+// screen; audio counts as talk time unless the owner is muted. Participants on hold
+// are not part of the call.
+func (s *Server) recordActivity(room *models.Room, ownerID string, published *models.PublishedTrack, activity *mediaActivity) {
+	active := activity.active
+	activity.active = 0
+	activity.flushed = time.Now()
+	if active <= 0 {
+		return
+	}
+
+	room.Mu.RLock()
+	owner, exists := room.Clients[ownerID]
+	if !exists || owner.IsBot || onHold(owner) {
+		room.Mu.RUnlock()
+		return
+	}
+	source := published.Source
+	audioMuted, videoMuted := owner.AudioMuted, owner.VideoMuted
+	room.Mu.RUnlock()
+
+	switch activity.kind {
+	case webrtc.RTPCodecTypeVideo.String():
+		if !videoMuted && (source == "" || source == models.SourceCamera) {
+			s.calls.addActivity(room.ID, ownerID, 0, active.Seconds())
+		}
+	case webrtc.RTPCodecTypeAudio.String():
+		if !audioMuted && (source == "" || source == models.SourceMicrophone) {
+			s.calls.addActivity(room.ID, ownerID, active.Seconds(), 0)
+		}
+	}
+}
+
+// addActivity adds talk and camera time of a participant present in a call
+func (l *callLog) addActivity(roomID, clientID string, talkSeconds, cameraSeconds float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	participant, exists := l.presentParticipant(roomID, clientID)
+	if !exists {
+		return
+	}
+	participant.TalkSeconds += talkSeconds
+	participant.CameraSeconds += cameraSeconds
+}
+
+// addQualitySample adds a connection quality sample of a participant present in a call
+// to its running averages
+func (l *callLog) addQualitySample(roomID, clientID string, quality connectionQuality) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	participant, exists := l.presentParticipant(roomID, clientID)
+	if !exists {
+		return
+	}
+	participant.QualitySamples++
+	n := float64(participant.QualitySamples)
+	participant.AvgRTTMs += (quality.RTTMs - participant.AvgRTTMs) / n
+	participant.AvgPacketLossPercent += (quality.PacketLossPercent - participant.AvgPacketLossPercent) / n
+}
+
+// presentParticipant returns the participant of a client present in a room's call;
+// l.mu must be held
+func (l *callLog) presentParticipant(roomID, clientID string) (*callParticipant, bool) {
+	call, exists := l.active[roomID]
+	if !exists {
+		return nil, false
+	}
+	presence, present := call.present[clientID]
+	if !present {
+		return nil, false
+	}
+	return call.participants[presence.userID], true
+}
+
+// callAnalytics is a call of a room as reported by the analytics API
+type callAnalytics struct {
+	callRecord
+	InProgress bool `json:"in_progress"`
+}
+
+// analytics returns the calls of a room, newest first: the call in progress, counted up
+// to now, followed by the finished ones
+func (l *callLog) analytics(room *models.Room) []callAnalytics {
+	room.Mu.RLock()
+	name, tenantID, creatorID := room.Name, room.TenantID, room.CreatorID
+	room.Mu.RUnlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	calls := []callAnalytics{}
+	if call, exists := l.active[room.ID]; exists {
+		now := time.Now()
+		record := call.record
+		record.Participants = make([]callParticipant, 0, len(call.participants))
+		seconds := make(map[string]float64)
+		for _, presence := range call.present {
+			seconds[presence.userID] += now.Sub(presence.joinedAt).Seconds()
+		}
+		for _, participant := range call.participants {
+			current := *participant
+			current.Seconds += seconds[current.UserID]
+			record.ParticipantSeconds += current.Seconds
+			record.Participants = append(record.Participants, current)
+		}
+		sortParticipants(record.Participants)
+		if len(call.present) > 0 {
+			record.DurationSeconds = now.Sub(record.StartedAt).Seconds()
+		} else {
+			record.DurationSeconds = record.EndedAt.Sub(record.StartedAt).Seconds()
+		}
+		record.RoomName = name
+		record.TenantID = tenantID
+		record.CreatorID = creatorID
+		record.Recordings = []string{}
+		calls = append(calls, callAnalytics{callRecord: record, InProgress: true})
+	}
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if l.records[i].RoomID == room.ID {
+			calls = append(calls, callAnalytics{callRecord: l.records[i]})
+		}
+	}
+	return calls
+}
+
+// runQualitySampler samples the connection quality of every participant for the call
+// analytics until the server stops
+func (s *Server) runQualitySampler() {
+	ticker := time.NewTicker(qualitySampleInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.roomManager.Mu.RLock()
+		rooms := make([]*models.Room, 0, len(s.roomManager.Rooms))
+		for _, room := range s.roomManager.Rooms {
+			rooms = append(rooms, room)
+		}
+		s.roomManager.Mu.RUnlock()
+
+		for _, room := range rooms {
+			s.sampleQuality(room)
+		}
+	}
+}
+
+// sampleQuality records the connection quality of the participants of a room
+func (s *Server) sampleQuality(room *models.Room) {
+	room.Mu.RLock()
+	var clients []*models.Client
+	for _, client := range room.Clients {
+		if !client.IsBot && client.Conn != nil && !onHold(client) {
+			clients = append(clients, client)
+		}
+	}
+	room.Mu.RUnlock()
+
+	// Stats are read outside the room lock
+	for _, client := range clients {
+		quality := measureQuality(client.Conn)
+		if quality.Quality != qualityUnknown {
+			s.calls.addQualitySample(room.ID, client.ID, quality)
+		}
+	}
+}
+
+// roomAnalyticsHandler returns per-participant engagement statistics of the calls of a
+// room: time in the call, talk and camera time, joins and leaves, and average connection
+// quality. Only the room creator may read them.
+func (s *Server) roomAnalyticsHandler(c *gin.Context) {
+	room, ok := s.ownedRoom(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"room_id": room.ID,
+		"calls":   s.calls.analytics(room),
+	})
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/apikeys"
+)
+
+// apiKeyHeader carries API keys on integration requests
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyMiddleware authenticates machine integrations by API key; user JWTs are not accepted
+func (s *Server) apiKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(apiKeyHeader)
+		if secret == "" {
+			// Provisioning clients such as SCIM send keys as bearer tokens
+			header := c.GetHeader("Authorization")
+			secret = strings.TrimPrefix(strings.TrimPrefix(header, "ApiKey "), "Bearer ")
+		}
+		if secret == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "API key required")})
+			c.Abort()
+			return
+		}
+
+		key, err := s.apiKeys.Authenticate(secret)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid API key")})
+			c.Abort()
+			return
+		}
+
+		// Requests act on behalf of the admin who created the key, in the key's tenant
+		c.Set("user_id", key.OwnerID)
+		c.Set("username", key.Name)
+		c.Set("api_key", key)
+		c.Set("tenant_id", key.TenantID)
+
+		if !s.checkTenantRoute(c) {
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireScope only lets API keys granting a scope through
+func (s *Server) requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.MustGet("api_key").(*apikeys.Key)
+		if !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": trf(c, "API key lacks scope %s", scope)})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// adminCreateAPIKeyHandler issues a new API key; the secret is only returned here
+func (s *Server) adminCreateAPIKeyHandler(c *gin.Context) {
+	var req struct {
+		Name     string   `json:"name" binding:"required"`
+		Scopes   []string `json:"scopes" binding:"required,min=1"`
+		TenantID string   `json:"tenant_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if !s.knownTenant(req.TenantID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Unknown tenant")})
+		return
+	}
+
+	key, secret, err := s.apiKeys.Create(req.Name, c.GetString("user_id"), req.TenantID, req.Scopes)
+	if err != nil {
+		if errors.Is(err, apikeys.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error()), "scopes": apikeys.Scopes})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create API key")})
+		return
+	}
+
+	s.recordAudit(c, "api_key.create", key.ID, map[string]string{
+		"name":   key.Name,
+		"scopes": strings.Join(key.Scopes, ","),
+		"tenant": key.TenantID,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created",
+		"key":     key,
+		"secret":  secret,
+	})
+}
+
+// adminListAPIKeysHandler lists API keys without their secrets
+func (s *Server) adminListAPIKeysHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"keys": s.apiKeys.List(),
+	})
+}
+
+// adminRotateAPIKeyHandler replaces the secret of an API key
+func (s *Server) adminRotateAPIKeyHandler(c *gin.Context) {
+	key, secret, err := s.apiKeys.Rotate(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, apikeys.ErrKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "API key not found")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to rotate API key")})
+		return
+	}
+
+	s.recordAudit(c, "api_key.rotate", key.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key rotated",
+		"key":     key,
+		"secret":  secret,
+	})
+}
+
+// adminRevokeAPIKeyHandler deletes an API key
+func (s *Server) adminRevokeAPIKeyHandler(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.apiKeys.Revoke(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "API key not found")})
+		return
+	}
+
+	s.recordAudit(c, "api_key.revoke", id, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
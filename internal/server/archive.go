@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/archive"
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// Recording archive backends
+const (
+	archiveDir = "dir"
+	archiveS3  = "s3"
+)
+
+const (
+	// archiveInterval is how often recordings are checked for moving to the archive
+	archiveInterval = time.Hour
+
+	// restoreCheckInterval is how often the archive is asked whether requested restores
+	// are ready
+	restoreCheckInterval = time.Minute
+
+	// archiveTimeout bounds moving one recording to or from the archive
+	archiveTimeout = 2 * time.Hour
+)
+
+// recordingArchiveKind returns the archive backend set by RECORDING_ARCHIVE
+func recordingArchiveKind() string {
+	return strings.ToLower(os.Getenv("RECORDING_ARCHIVE"))
+}
+
+// newRecordingArchive returns the archive configured by RECORDING_ARCHIVE, or nil if
+// recordings stay on the node
+func newRecordingArchive() archive.Store {
+	switch kind := recordingArchiveKind(); kind {
+	case "", "none":
+		return nil
+	case archiveDir:
+		dir := os.Getenv("ARCHIVE_DIR")
+		if dir == "" {
+			serverLog.Fatalf("ARCHIVE_DIR is required when RECORDING_ARCHIVE=dir")
+		}
+		store, err := archive.NewDir(dir)
+		if err != nil {
+			serverLog.Fatalf("Failed to open recording archive: %v", err)
+		}
+		serverLog.Infof("Archiving recordings to %s", dir)
+		return store
+	case archiveS3:
+		config := archive.S3Config{
+			Endpoint:     envString("S3_ENDPOINT", "s3.amazonaws.com"),
+			Secure:       envBool("S3_SECURE", true),
+			Region:       os.Getenv("S3_REGION"),
+			Bucket:       os.Getenv("S3_BUCKET"),
+			AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+			SecretKey:    os.Getenv("S3_SECRET_KEY"),
+			StorageClass: envString("S3_STORAGE_CLASS", "GLACIER"),
+			RestoreDays:  int(envInt64("S3_RESTORE_DAYS", 1)),
+		}
+		if config.Bucket == "" {
+			serverLog.Fatalf("S3_BUCKET is required when RECORDING_ARCHIVE=s3")
+		}
+		store, err := archive.NewS3(config)
+		if err != nil {
+			serverLog.Fatalf("Failed to open recording archive: %v", err)
+		}
+		serverLog.Infof("Archiving recordings to S3 bucket %s (%s)", config.Bucket, config.StorageClass)
+		return store
+	default:
+		serverLog.Fatalf("Unknown RECORDING_ARCHIVE %q: use dir, s3 or none", kind)
+		return nil
+	}
+}
+
+// runRecordingArchiver moves recordings to the archive once they have not been used for
+// RECORDING_ARCHIVE_AFTER_DAYS days, or sooner while the recordings on the node exceed
+// RECORDING_HOT_QUOTA_BYTES, and completes requested restores, until the server stops
+func (s *Server) runRecordingArchiver() {
+	if kind := recordingArchiveKind(); kind == "" || kind == "none" {
+		return
+	}
+	afterDays := envInt64("RECORDING_ARCHIVE_AFTER_DAYS", 30)
+	quota := envInt64("RECORDING_HOT_QUOTA_BYTES", 0)
+
+	archiveTicker := time.NewTicker(archiveInterval)
+	defer archiveTicker.Stop()
+	restoreTicker := time.NewTicker(restoreCheckInterval)
+	defer restoreTicker.Stop()
+
+	s.archiveRecordings(afterDays, quota)
+	for {
+		select {
+		case <-archiveTicker.C:
+			s.archiveRecordings(afterDays, quota)
+		case <-restoreTicker.C:
+			s.completeRestores()
+		}
+	}
+}
+
+// archiveRecordings moves the recordings due for the archive. afterDays of 0 archives
+// only to stay under quota.
+func (s *Server) archiveRecordings(afterDays, quota int64) {
+	var idleBefore time.Time
+	if afterDays > 0 {
+		idleBefore = time.Now().AddDate(0, 0, -int(afterDays))
+	}
+
+	for _, recordingID := range s.recorder.ArchiveCandidates(idleBefore, quota) {
+		s.archiveRecording(recordingID)
+	}
+}
+
+// archiveRecording moves a recording to the archive and announces it with
+// recording.archived
+func (s *Server) archiveRecording(recordingID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+	bytes, err := s.recorder.Archive(ctx, recordingID)
+	cancel()
+	if err != nil {
+		recordingLog.Errorf("Failed to archive recording %s: %v", recordingID, err)
+		return err
+	}
+
+	rec, _ := s.recorder.Metadata(recordingID)
+	recordingLog.Infof("Archived recording %s (%d bytes)", recordingID, bytes)
+	s.saveRecording(recordingID, newEvent(events.RecordingArchived, rec.RoomID, map[string]interface{}{
+		"recording_id": recordingID,
+		"bytes":        bytes,
+	}))
+	return nil
+}
+
+// completeRestores brings back the recordings whose restore the archive has completed
+// and announces each with recording.restored
+func (s *Server) completeRestores() {
+	for _, recordingID := range s.recorder.Restoring() {
+		ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+		restored, err := s.recorder.CompleteRestore(ctx, recordingID)
+		cancel()
+		if err != nil {
+			recordingLog.Errorf("Failed to restore recording %s: %v", recordingID, err)
+		}
+		if !restored {
+			continue
+		}
+
+		rec, _ := s.recorder.Metadata(recordingID)
+		recordingLog.Infof("Restored recording %s from the archive", recordingID)
+		s.saveRecording(recordingID, newEvent(events.RecordingRestored, rec.RoomID, map[string]interface{}{
+			"recording_id": recordingID,
+		}))
+	}
+}
+
+// respondOffline responds with 409 and reports true for recordings whose media files
+// are in the archive
+func respondOffline(c *gin.Context, rec *recording.Recording) bool {
+	switch rec.Storage {
+	case recording.StorageArchived:
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is archived; request a restore first")})
+		return true
+	case recording.StorageRestoring:
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is being restored from the archive")})
+		return true
+	}
+	return false
+}
+
+// restoreRecordingHandler requests the restore of an archived recording. Restores from
+// Glacier take hours; the recording's Storage is restoring until its files are back,
+// which is announced with recording.restored.
+func (s *Server) restoreRecordingHandler(c *gin.Context) {
+	rec, exists := s.recorder.GetRecording(c.Param("id"))
+	if !exists || rec.TenantID != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return
+	}
+	userID := c.GetString("user_id")
+	if userID != rec.OwnerID && userID != s.roomOwner(rec.RoomID) && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), repositoryTimeout)
+	err := s.recorder.RequestRestore(ctx, rec.ID)
+	cancel()
+	if err != nil {
+		switch {
+		case errors.Is(err, recording.ErrNotArchived):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is not archived")})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to restore recording")})
+		}
+		return
+	}
+	s.saveRecording(rec.ID)
+	// Archives that need no retrieval are ready at once
+	go s.completeRestores()
+
+	metadata, _ := s.recorder.Metadata(rec.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"recording_id":         rec.ID,
+		"storage":              metadata.Storage,
+		"restore_requested_at": metadata.RestoreRequestedAt,
+	})
+}
+
+// adminArchiveRecordingHandler moves a finished recording to the archive at once
+func (s *Server) adminArchiveRecordingHandler(c *gin.Context) {
+	rec, exists := s.recorder.GetRecording(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return
+	}
+	if rec.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is still in progress")})
+		return
+	}
+	if respondOffline(c, rec) {
+		return
+	}
+
+	if err := s.archiveRecording(rec.ID); err != nil {
+		switch {
+		case errors.Is(err, recording.ErrNoArchive):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "No recording archive is configured")})
+		case errors.Is(err, recording.ErrExportInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Export is already in progress")})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to archive recording")})
+		}
+		return
+	}
+
+	metadata, _ := s.recorder.Metadata(rec.ID)
+	s.recordAudit(c, "recording.archive", rec.ID, map[string]string{
+		"bytes": strconv.FormatInt(metadata.ArchivedBytes, 10),
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"recording_id":   rec.ID,
+		"storage":        metadata.Storage,
+		"archived_at":    metadata.ArchivedAt,
+		"archived_bytes": metadata.ArchivedBytes,
+	})
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/backup"
+	"github.com/zubans/video-call-server/internal/repository"
+)
+
+// backupCounts counts the entries of each part of a backup
+type backupCounts struct {
+	Users      int `json:"users"`
+	Rooms      int `json:"rooms"`
+	Messages   int `json:"messages"`
+	Recordings int `json:"recordings"`
+}
+
+// exportBackup collects the state kept in backups: accounts, room metadata, chat history
+// and recording metadata
+func (s *Server) exportBackup() *backup.Archive {
+	archive := &backup.Archive{
+		Manifest: backup.Manifest{
+			CreatedAt: time.Now().UTC(),
+			Node:      s.nodeID(),
+		},
+		Users:      auth.ExportUsers(),
+		Chat:       s.chatManager.Export(),
+		Recordings: s.recorder.ExportMetadata(),
+	}
+
+	s.roomManager.Mu.RLock()
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		archive.Rooms = append(archive.Rooms, roomRecord(room))
+		room.Mu.RUnlock()
+	}
+	s.roomManager.Mu.RUnlock()
+
+	return archive
+}
+
+// restoreRoom adds a room from a backup, keeping its ID; the join code is replaced when
+// another room has it. It returns false if a room with the ID exists.
+func (s *Server) restoreRoom(c *gin.Context, record repository.Room) bool {
+	s.trashMu.Lock()
+	_, deleted := s.deletedRooms[record.ID]
+	s.trashMu.Unlock()
+	if deleted {
+		return false
+	}
+
+	room := roomFromRecord(record)
+
+	s.roomManager.Mu.Lock()
+	if _, exists := s.roomManager.Rooms[room.ID]; exists {
+		s.roomManager.Mu.Unlock()
+		return false
+	}
+	if _, taken := s.roomByCodeLocked(room.JoinCode); taken || room.JoinCode == "" {
+		room.JoinCode = s.newJoinCodeLocked()
+	}
+	s.roomManager.Rooms[room.ID] = room
+	s.roomManager.Mu.Unlock()
+
+	// Record this node as the room's host so other nodes route joins here
+	if err := s.claimRoom(c, room.ID); err != nil {
+		s.roomManager.Mu.Lock()
+		delete(s.roomManager.Rooms, room.ID)
+		s.roomManager.Mu.Unlock()
+
+		serverLog.Errorf("Failed to claim restored room %s: %v", room.ID, err)
+		return false
+	}
+	s.saveRoom(room)
+	return true
+}
+
+// adminBackupHandler streams a backup archive of accounts, room metadata, chat history
+// and recording metadata. Recording media files are not included.
+func (s *Server) adminBackupHandler(c *gin.Context) {
+	archive := s.exportBackup()
+
+	// Encode before answering so a failure can still be reported
+	var buf bytes.Buffer
+	if err := backup.Write(&buf, archive); err != nil {
+		serverLog.Errorf("Failed to write backup: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create backup")})
+		return
+	}
+
+	messages := 0
+	for _, list := range archive.Chat {
+		messages += len(list)
+	}
+	s.recordAudit(c, "backup.create", s.nodeID(), map[string]string{
+		"users":      strconv.Itoa(len(archive.Users)),
+		"rooms":      strconv.Itoa(len(archive.Rooms)),
+		"messages":   strconv.Itoa(messages),
+		"recordings": strconv.Itoa(len(archive.Recordings)),
+	})
+
+	filename := "backup-" + archive.Manifest.CreatedAt.Format("20060102-150405") + ".tar.gz"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "application/gzip", buf.Bytes())
+}
+
+// adminRestoreHandler restores a backup archive sent as the request body. Entries whose
+// IDs already exist are skipped, so restoring the same backup twice changes nothing;
+// chat messages are merged into the history of their room.
+func (s *Server) adminRestoreHandler(c *gin.Context) {
+	archive, err := backup.Read(c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": trf(c, "Request body exceeds %d bytes", maxBytesErr.Limit)})
+			return
+		}
+		if errors.Is(err, backup.ErrUnsupportedVersion) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Unsupported backup version")})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid backup archive")})
+		return
+	}
+
+	var restored, skipped backupCounts
+
+	for _, user := range archive.Users {
+		if err := auth.ImportUser(user); err != nil {
+			skipped.Users++
+			continue
+		}
+		s.saveUser(user.ID)
+		restored.Users++
+	}
+
+	for _, entry := range archive.Rooms {
+		if !s.restoreRoom(c, entry) {
+			skipped.Rooms++
+			continue
+		}
+		restored.Rooms++
+		s.metrics.IncrementRoomsCreated(entry.TenantID)
+	}
+	s.roomManager.Mu.RLock()
+	s.metrics.SetRoomsActive(float64(len(s.roomManager.Rooms)))
+	s.roomManager.Mu.RUnlock()
+
+	for roomID, messages := range archive.Chat {
+		added := s.chatManager.Import(roomID, messages)
+		restored.Messages += added
+		skipped.Messages += len(messages) - added
+		for _, message := range messages {
+			s.saveMessage(roomID, message.ID)
+		}
+	}
+
+	for _, rec := range archive.Recordings {
+		if err := s.recorder.ImportMetadata(rec); err != nil {
+			skipped.Recordings++
+			continue
+		}
+		s.saveRecording(rec.ID)
+		restored.Recordings++
+	}
+
+	s.recordAudit(c, "backup.restore", s.nodeID(), map[string]string{
+		"created_at": archive.Manifest.CreatedAt.Format(time.RFC3339),
+		"users":      strconv.Itoa(restored.Users),
+		"rooms":      strconv.Itoa(restored.Rooms),
+		"messages":   strconv.Itoa(restored.Messages),
+		"recordings": strconv.Itoa(restored.Recordings),
+	})
+	serverLog.Infof("Restored backup of %s: %d users, %d rooms, %d messages, %d recordings",
+		archive.Manifest.CreatedAt.Format(time.RFC3339), restored.Users, restored.Rooms, restored.Messages, restored.Recordings)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Backup restored",
+		"created_at": archive.Manifest.CreatedAt,
+		"restored":   restored,
+		"skipped":    skipped,
+	})
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/hostpolicy"
+	"github.com/zubans/video-call-server/internal/media"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// mediaBot is a server-side virtual participant publishing a media source into a room
+type mediaBot struct {
+	ID        string
+	Type      string // "media" for bots, "ingest" for camera ingest
+	RoomID    string
+	Name      string
+	SourceURI string
+	CreatedBy string
+	CreatedAt time.Time
+	Source    media.Source
+	Client    *models.Client
+	Track     *models.PublishedTrack
+}
+
+// Bot types
+const (
+	botTypeMedia  = "media"
+	botTypeIngest = "ingest"
+)
+
+// botManager keeps track of all media bots
+type botManager struct {
+	bots map[string]*mediaBot
+	mu   sync.RWMutex
+}
+
+// newBotManager creates an empty botManager
+func newBotManager() *botManager {
+	return &botManager{
+		bots: make(map[string]*mediaBot),
+	}
+}
+
+// mediaDir returns the directory media files for bots are served from
+func mediaDir() string {
+	if dir := os.Getenv("MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return "./media"
+}
+
+// resolveMediaFile maps a requested file name onto the media directory, rejecting paths escaping it
+func resolveMediaFile(name string) (string, error) {
+	base, err := filepath.Abs(mediaDir())
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(base, filepath.Clean("/"+name))
+	if !strings.HasPrefix(path, base+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid media file: %s", name)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("media file not found: %s", name)
+	}
+
+	return path, nil
+}
+
+// errStreamHost is returned for streams on hosts the server may not pull media from
+var errStreamHost = errors.New("Stream host is not allowed")
+
+// checkStreamHost keeps bots and ingests from reaching internal services: with
+// MEDIA_STREAM_HOSTS set only the hosts listed there are allowed, otherwise hosts
+// resolving to loopback, private, link-local or unspecified addresses are refused
+func checkStreamHost(parsed *url.URL) error {
+	if hostpolicy.New(envList("MEDIA_STREAM_HOSTS")).CheckHost(parsed.Hostname()) != nil {
+		return errStreamHost
+	}
+	return nil
+}
+
+// botView returns the JSON representation of a bot
+func botView(bot *mediaBot) gin.H {
+	return gin.H{
+		"id":               bot.ID,
+		"type":             bot.Type,
+		"room_id":          bot.RoomID,
+		"name":             bot.Name,
+		"source":           bot.SourceURI,
+		"kind":             bot.Track.Kind,
+		"running":          bot.Source.Running(),
+		"position_seconds": bot.Source.Position().Seconds(),
+		"created_at":       bot.CreatedAt,
+	}
+}
+
+// roomBot looks up a bot by room and bot ID, checking that the user owns the room
+func (s *Server) roomBot(c *gin.Context) (*models.Room, *mediaBot, bool) {
+	room, ok := s.ownedRoom(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	s.bots.mu.RLock()
+	bot, exists := s.bots.bots[c.Param("bot_id")]
+	s.bots.mu.RUnlock()
+
+	if !exists || bot.RoomID != room.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Bot not found")})
+		return nil, nil, false
+	}
+
+	return room, bot, true
+}
+
+// ownedRoom looks up the room from the :id path parameter and checks that the user created it
+func (s *Server) ownedRoom(c *gin.Context) (*models.Room, bool) {
+	userID := c.MustGet("user_id").(string)
+
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return nil, false
+	}
+
+	if room.CreatorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return nil, false
+	}
+
+	return room, true
+}
+
+// createBotHandler adds a virtual participant publishing a media file or live stream
+func (s *Server) createBotHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		Name      string `json:"name"`
+		File      string `json:"file"` // file name inside MEDIA_DIR (.ivf or .ogg)
+		URL       string `json:"url"`  // rtsp:// or rtmp:// stream
+		Loop      bool   `json:"loop"`
+		Autostart bool   `json:"autostart"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if (req.File == "") == (req.URL == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Exactly one of file or url is required")})
+		return
+	}
+
+	room, ok := s.ownedRoom(c)
+	if !ok {
+		return
+	}
+
+	botID := generateBotID()
+	if req.Name == "" {
+		req.Name = "Media bot"
+	}
+
+	// Create media source
+	var source media.Source
+	var sourceURI string
+	if req.File != "" {
+		path, err := resolveMediaFile(req.File)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+		player, err := media.NewFilePlayer(path, botID, req.Loop)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+		source, sourceURI = player, req.File
+	} else {
+		parsed, err := url.Parse(req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid stream URL")})
+			return
+		}
+		if err := checkStreamHost(parsed); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+		stream, err := media.NewStreamSource(req.URL, botID, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+
+		// Never echo stream credentials back to clients
+		parsed.User = nil
+		source, sourceURI = stream, parsed.String()
+	}
+
+	bot := s.addBot(room, botID, botTypeMedia, req.Name, sourceURI, userID, source)
+
+	if req.Autostart {
+		if err := source.Start(); err != nil {
+			sfuLog.Errorf("Failed to autostart bot %s: %v", bot.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot created successfully",
+		"bot":     botView(bot),
+	})
+}
+
+// addBot joins a media source into a room as a virtual participant
+func (s *Server) addBot(room *models.Room, botID, botType, name, sourceURI, userID string, source media.Source) *mediaBot {
+	client := &models.Client{
+		ID:       botID,
+		UserID:   userID,
+		Username: name,
+		JoinedAt: time.Now(),
+		IsBot:    true,
+	}
+
+	s.addClient(room, client)
+
+	bot := &mediaBot{
+		ID:        botID,
+		Type:      botType,
+		RoomID:    room.ID,
+		Name:      name,
+		SourceURI: sourceURI,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+		Source:    source,
+		Client:    client,
+		Track:     s.publishTrack(room, client.ID, source.Track()),
+	}
+
+	s.bots.mu.Lock()
+	s.bots.bots[bot.ID] = bot
+	s.bots.mu.Unlock()
+
+	return bot
+}
+
+// removeBot stops a bot and removes it from its room
+func (s *Server) removeBot(room *models.Room, bot *mediaBot) {
+	s.bots.mu.Lock()
+	delete(s.bots.bots, bot.ID)
+	s.bots.mu.Unlock()
+
+	if err := bot.Source.Close(); err != nil {
+		sfuLog.Errorf("Failed to close source of bot %s: %v", bot.ID, err)
+	}
+
+	s.unpublishTrack(room, bot.Track)
+
+	s.dropClient(room, bot.Client)
+}
+
+// listBotsHandler lists the bots of a room
+func (s *Server) listBotsHandler(c *gin.Context) {
+	room, ok := s.ownedRoom(c)
+	if !ok {
+		return
+	}
+
+	s.bots.mu.RLock()
+	bots := []gin.H{}
+	for _, bot := range s.bots.bots {
+		if bot.RoomID == room.ID {
+			bots = append(bots, botView(bot))
+		}
+	}
+	s.bots.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"bots": bots,
+	})
+}
+
+// startBotHandler starts or resumes publishing
+func (s *Server) startBotHandler(c *gin.Context) {
+	_, bot, ok := s.roomBot(c)
+	if !ok {
+		return
+	}
+
+	if err := bot.Source.Start(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot started",
+		"bot":     botView(bot),
+	})
+}
+
+// stopBotHandler pauses publishing
+func (s *Server) stopBotHandler(c *gin.Context) {
+	_, bot, ok := s.roomBot(c)
+	if !ok {
+		return
+	}
+
+	if err := bot.Source.Stop(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot stopped",
+		"bot":     botView(bot),
+	})
+}
+
+// seekBotHandler moves the playback position of a file bot
+func (s *Server) seekBotHandler(c *gin.Context) {
+	var req struct {
+		PositionSeconds *float64 `json:"position_seconds" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	_, bot, ok := s.roomBot(c)
+	if !ok {
+		return
+	}
+
+	position := time.Duration(*req.PositionSeconds * float64(time.Second))
+	if err := bot.Source.Seek(position); err != nil {
+		status := http.StatusBadRequest
+		if !errors.Is(err, media.ErrSeekNotSupported) && position >= 0 {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot position updated",
+		"bot":     botView(bot),
+	})
+}
+
+// deleteBotHandler removes a bot from its room
+func (s *Server) deleteBotHandler(c *gin.Context) {
+	room, bot, ok := s.roomBot(c)
+	if !ok {
+		return
+	}
+
+	s.removeBot(room, bot)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Bot removed successfully",
+	})
+}
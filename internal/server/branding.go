@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/branding"
+)
+
+// brandingHandler serves the branding of an organization to white-label clients
+// styling their pre-join screens; it needs no authentication
+func (s *Server) brandingHandler(c *gin.Context) {
+	org, exists := s.branding.Get(c.Param("org_slug"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Branding not found")})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, org)
+}
+
+// adminSetBrandingHandler creates or replaces the branding of an organization
+func (s *Server) adminSetBrandingHandler(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required,max=100"`
+		LogoURL     string `json:"logo_url" binding:"omitempty,http_url"`
+		WelcomeText string `json:"welcome_text" binding:"max=2000"`
+		Colors      struct {
+			Primary    string `json:"primary" binding:"omitempty,hexcolor"`
+			Secondary  string `json:"secondary" binding:"omitempty,hexcolor"`
+			Background string `json:"background" binding:"omitempty,hexcolor"`
+			Text       string `json:"text" binding:"omitempty,hexcolor"`
+		} `json:"colors"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	slug := c.Param("org_slug")
+	if !branding.ValidSlug(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Invalid organization slug")})
+		return
+	}
+
+	org := s.branding.Set(branding.Branding{
+		Slug:        slug,
+		Name:        req.Name,
+		LogoURL:     req.LogoURL,
+		WelcomeText: req.WelcomeText,
+		Colors:      branding.Colors(req.Colors),
+	})
+	s.recordAudit(c, "branding.set", slug, map[string]string{"name": org.Name})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Branding saved",
+		"branding": org,
+	})
+}
+
+// adminListBrandingHandler lists the branding of every organization
+func (s *Server) adminListBrandingHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"branding": s.branding.List()})
+}
+
+// adminDeleteBrandingHandler removes the branding of an organization
+func (s *Server) adminDeleteBrandingHandler(c *gin.Context) {
+	slug := c.Param("org_slug")
+	if err := s.branding.Delete(slug); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Branding not found")})
+		return
+	}
+
+	s.recordAudit(c, "branding.delete", slug, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Branding deleted"})
+}
+
+// orgSlug returns the slug of a user's organization if it has branding
+func (s *Server) orgSlug(userID string) string {
+	user, exists := auth.GetUserByID(userID)
+	if !exists || user.Org == "" {
+		return ""
+	}
+	slug := branding.Slug(user.Org)
+	if _, exists := s.branding.Get(slug); !exists {
+		return ""
+	}
+	return slug
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"os"
+	"strings"
+
+	"github.com/zubans/video-call-server/internal/broker"
+)
+
+// Event brokers selected by EVENT_BROKER
+const (
+	brokerKafka = "kafka"
+	brokerNATS  = "nats"
+)
+
+// newEventBroker connects to the message broker configured by EVENT_BROKER that server
+// events are published to; nil publishes none.
+//
+// Kafka brokers are listed in KAFKA_BROKERS and events go to KAFKA_TOPIC. NATS is
+// reached at NATS_URL and events go to subjects under NATS_SUBJECT_PREFIX.
+func newEventBroker() broker.Publisher {
+	switch kind := strings.ToLower(os.Getenv("EVENT_BROKER")); kind {
+	case "", "none":
+		return nil
+	case brokerKafka:
+		brokers := envList("KAFKA_BROKERS")
+		if len(brokers) == 0 {
+			serverLog.Fatalf("KAFKA_BROKERS is required when EVENT_BROKER=kafka")
+		}
+		topic := envString("KAFKA_TOPIC", "videocall.events")
+		serverLog.Infof("Publishing events to Kafka topic %s", topic)
+		return broker.NewKafka(brokers, topic)
+	case brokerNATS:
+		prefix := envString("NATS_SUBJECT_PREFIX", "videocall.events")
+		publisher, err := broker.NewNATS(envString("NATS_URL", "nats://localhost:4222"), prefix)
+		if err != nil {
+			serverLog.Fatalf("Failed to connect to event broker: %v", err)
+		}
+		serverLog.Infof("Publishing events to NATS subjects %s.>", prefix)
+		return publisher
+	default:
+		serverLog.Fatalf("Unknown EVENT_BROKER %q: use kafka, nats or none", kind)
+		return nil
+	}
+}
+
+// roomTenantID returns the tenant of a room, or "" for the default tenant and unknown
+// rooms
+func (s *Server) roomTenantID(roomID string) string {
+	tenant, _ := s.roomTenant(roomID)
+	return tenant
+}
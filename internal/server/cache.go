@@ -0,0 +1,55 @@
+package server
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/cache"
+)
+
+// Cache backends selected by CACHE_BACKEND
+const (
+	// cacheLRU keeps hot reads in an in-process LRU
+	cacheLRU = "lru"
+
+	// cacheRedis adds a Redis cache behind the in-process LRU
+	cacheRedis = "redis"
+)
+
+// newCache creates the cache configured by CACHE_BACKEND for the repositories' hot
+// reads; nil disables caching.
+//
+// CACHE_SIZE bounds the LRU entries and CACHE_TTL_SECONDS how long values stay
+// cached. With the redis backend, values read from Redis stay in the LRU for at most
+// CACHE_LOCAL_TTL_SECONDS.
+func newCache() cache.Cache {
+	backend := strings.ToLower(os.Getenv("CACHE_BACKEND"))
+	if backend == "" || backend == "none" {
+		return nil
+	}
+
+	local := cache.NewLRU(int(envInt64("CACHE_SIZE", 10000)))
+	switch backend {
+	case cacheLRU:
+		serverLog.Infof("Caching reads in memory")
+		return local
+	case cacheRedis:
+		redisURL := envString("CACHE_REDIS_URL", os.Getenv("REDIS_URL"))
+		if redisURL == "" {
+			serverLog.Fatalf("CACHE_REDIS_URL or REDIS_URL is required when CACHE_BACKEND=redis")
+		}
+		// Each node keeps its own database, so its cached values are its own
+		hostname, _ := os.Hostname()
+		prefix := envString("CACHE_KEY_PREFIX", envString("CLUSTER_KEY_PREFIX", "videocall:")+"cache:"+envString("NODE_ID", hostname)+":")
+		shared, err := cache.NewRedis(redisURL, prefix)
+		if err != nil {
+			serverLog.Fatalf("Failed to connect to cache: %v", err)
+		}
+		serverLog.Infof("Caching reads in memory and Redis")
+		return cache.NewTiered(local, shared, time.Duration(envInt64("CACHE_LOCAL_TTL_SECONDS", 5))*time.Second)
+	default:
+		serverLog.Fatalf("Unknown CACHE_BACKEND %q: use lru, redis or none", backend)
+		return nil
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/translate"
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+// Caption delivery settings
+const (
+	// Captions of a room that may wait for translation; later ones are dropped
+	captionQueueSize = 64
+
+	// How long the delivery goroutine of a room without captions is kept
+	captionIdleTimeout = time.Minute
+
+	// How long a translation may take before the caption is delivered untranslated
+	translationTimeout = 5 * time.Second
+)
+
+// captionMessage is the payload of "caption" messages delivered by the server
+type captionMessage struct {
+	RoomID           string `json:"room_id"`
+	SenderID         string `json:"sender_id"`
+	Text             string `json:"text"`
+	Language         string `json:"language"`
+	OriginalLanguage string `json:"original_language,omitempty"` // set on translated captions
+	Final            bool   `json:"final"`
+}
+
+// captionQueues deliver the captions of each room in order on a goroutine per room,
+// so slow translations never hold up the signaling connection of the speaker
+type captionQueues struct {
+	queues map[string]chan func()
+	mu     sync.Mutex
+}
+
+// newTranslator creates the caption translation backend named by TRANSLATE_BACKEND
+// with TRANSLATE_URL and TRANSLATE_API_KEY. Without a backend, captions are delivered
+// as spoken and nil is returned.
+func newTranslator() translate.Translator {
+	backend := os.Getenv("TRANSLATE_BACKEND")
+	if backend == "" {
+		return nil
+	}
+
+	translator, err := translate.New(backend, translate.Config{
+		URL:    os.Getenv("TRANSLATE_URL"),
+		APIKey: os.Getenv("TRANSLATE_API_KEY"),
+	})
+	if err != nil {
+		serverLog.Errorf("Caption translation disabled: %v", err)
+		return nil
+	}
+	return translator
+}
+
+// relayCaption delivers a caption to the other participants of the room, grouped by
+// the caption language each of them subscribed to, and marks speaker changes in
+// recordings of the room
+func (s *Server) relayCaption(roomID, senderID string, caption *websocket.CaptionPayload) {
+	room, exists := s.getRoom(roomID)
+	if !exists || !s.featureEnabled(room.TenantID, featureTranscription) {
+		return
+	}
+
+	room.Mu.RLock()
+	speaker, exists := room.Clients[senderID]
+	if !exists || onHold(speaker) || (speaker.Permissions != nil && !speaker.Permissions.CanPublish) {
+		room.Mu.RUnlock()
+		return
+	}
+	name := speaker.DisplayName
+	recipients := make(map[string][]string)
+	for _, client := range room.Clients {
+		if client.ID != senderID && !client.IsBot && !onHold(client) {
+			recipients[client.CaptionLanguage] = append(recipients[client.CaptionLanguage], client.ID)
+		}
+	}
+	room.Mu.RUnlock()
+
+	// Recognized speech tells who is speaking, which starts chapters of recordings
+	if caption.Final && s.recorder.IsRecording(roomID) {
+		s.recorder.AddChapter(roomID, recording.ChapterSpeaker, name)
+	}
+
+	if len(recipients) > 0 {
+		s.enqueueCaption(roomID, func() {
+			s.deliverCaption(roomID, senderID, caption, recipients)
+		})
+	}
+}
+
+// deliverCaption sends a caption to its recipients, translated into their languages.
+// Interim captions are only delivered to participants reading the spoken language;
+// final ones are translated once per language and delivered untranslated if that fails.
+func (s *Server) deliverCaption(roomID, senderID string, caption *websocket.CaptionPayload, recipients map[string][]string) {
+	original := captionMessage{
+		RoomID:   roomID,
+		SenderID: senderID,
+		Text:     caption.Text,
+		Language: caption.Language,
+		Final:    caption.Final,
+	}
+
+	var wg sync.WaitGroup
+	for language, clientIDs := range recipients {
+		if s.translator == nil || language == "" || sameLanguage(language, caption.Language) {
+			s.sendCaption(clientIDs, original)
+			continue
+		}
+		if !caption.Final {
+			continue
+		}
+
+		wg.Add(1)
+		go func(language string, clientIDs []string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), translationTimeout)
+			defer cancel()
+			text, err := s.translator.Translate(ctx, caption.Text, caption.Language, language)
+			if err != nil || strings.TrimSpace(text) == "" {
+				serverLog.Warnf("Failed to translate caption in room %s from %s to %s: %v", roomID, caption.Language, language, err)
+				s.sendCaption(clientIDs, original)
+				return
+			}
+
+			translated := original
+			translated.Text = text
+			translated.Language = language
+			translated.OriginalLanguage = caption.Language
+			s.sendCaption(clientIDs, translated)
+		}(language, clientIDs)
+	}
+	wg.Wait()
+}
+
+// sendCaption sends a caption to signaling clients
+func (s *Server) sendCaption(clientIDs []string, caption captionMessage) {
+	for _, clientID := range clientIDs {
+		s.hub.SendToSender(clientID, "caption", caption)
+	}
+}
+
+// sameLanguage reports whether two language tags share their primary language
+func sameLanguage(a, b string) bool {
+	a, _, _ = strings.Cut(a, "-")
+	b, _, _ = strings.Cut(b, "-")
+	return strings.EqualFold(a, b)
+}
+
+// enqueueCaption queues caption delivery for a room, starting the room's delivery
+// goroutine on first use. Captions are dropped while the queue is full.
+func (s *Server) enqueueCaption(roomID string, job func()) {
+	s.captions.mu.Lock()
+	defer s.captions.mu.Unlock()
+
+	if s.captions.queues == nil {
+		s.captions.queues = make(map[string]chan func())
+	}
+	queue, exists := s.captions.queues[roomID]
+	if !exists {
+		queue = make(chan func(), captionQueueSize)
+		s.captions.queues[roomID] = queue
+		go s.runCaptions(roomID, queue)
+	}
+
+	select {
+	case queue <- job:
+	default:
+		serverLog.Warnf("Dropping caption in room %s: translation is falling behind", roomID)
+	}
+}
+
+// runCaptions delivers the captions of a room until none arrive for captionIdleTimeout
+func (s *Server) runCaptions(roomID string, queue chan func()) {
+	timer := time.NewTimer(captionIdleTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case job := <-queue:
+			job()
+			timer.Reset(captionIdleTimeout)
+		case <-timer.C:
+			s.captions.mu.Lock()
+			if len(queue) == 0 {
+				delete(s.captions.queues, roomID)
+				s.captions.mu.Unlock()
+				return
+			}
+			s.captions.mu.Unlock()
+			timer.Reset(captionIdleTimeout)
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/cluster"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+const (
+	// routingCascade serves remote rooms locally by relaying their tracks from the owner node
+	routingCascade = "cascade"
+
+	// clusterSecretHeader authenticates requests between nodes
+	clusterSecretHeader = "X-Cluster-Secret"
+
+	// cascadeSyncInterval is how often an edge node checks the origin for new or removed tracks
+	cascadeSyncInterval = 2 * time.Second
+
+	// relayTimeout bounds relay setup requests between nodes
+	relayTimeout = 10 * time.Second
+)
+
+// relayTrackView describes a track available for relaying
+type relayTrackView struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	ClientID string `json:"client_id"`
+}
+
+// relayRoomView describes a room hosted on an origin node
+type relayRoomView struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	CreatorID string           `json:"creator_id"`
+	TenantID  string           `json:"tenant_id,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+	Tracks    []relayTrackView `json:"tracks"`
+}
+
+// cascade mirrors the tracks of a room hosted on an origin node into a local edge room
+type cascade struct {
+	room   *models.Room
+	origin cluster.Node
+
+	// Relayed tracks by origin track ID
+	relays map[string]*webrtc.PeerConnection
+	mu     sync.Mutex
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// clusterMiddleware authenticates requests from other nodes by the shared CLUSTER_SECRET
+func (s *Server) clusterMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(clusterSecretHeader)
+		if s.clusterSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(s.clusterSecret)) != 1 {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Invalid cluster secret")})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// relayRoomHandler describes a local room and its tracks to an edge node
+func (s *Server) relayRoomHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	room.Mu.RLock()
+	view := relayRoomView{
+		ID:        room.ID,
+		Name:      room.Name,
+		CreatorID: room.CreatorID,
+		TenantID:  room.TenantID,
+		CreatedAt: room.CreatedAt,
+		Tracks:    make([]relayTrackView, 0, len(room.Tracks)),
+	}
+	for _, published := range room.Tracks {
+		view.Tracks = append(view.Tracks, relayTrackView{
+			ID:       published.ID,
+			Kind:     published.Kind,
+			ClientID: published.ClientID,
+		})
+	}
+	room.Mu.RUnlock()
+
+	c.JSON(http.StatusOK, view)
+}
+
+// relayTrackHandler answers an edge node's offer to receive a published track
+func (s *Server) relayTrackHandler(c *gin.Context) {
+	var req struct {
+		NodeID string                    `json:"node_id" binding:"required"`
+		Offer  webrtc.SessionDescription `json:"offer" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	// Find room and track
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	room.Mu.RLock()
+	published, exists := room.Tracks[c.Param("track_id")]
+	room.Mu.RUnlock()
+
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Track not found")})
+		return
+	}
+
+	pc, err := s.newPeerConnection(models.RoomSettings{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create peer connection")})
+		return
+	}
+
+	sender, err := pc.AddTrack(published.Track)
+	if err != nil {
+		pc.Close()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to add track")})
+		return
+	}
+
+	// Read incoming RTCP so interceptors (NACK, reports) keep working
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := sender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	answer, err := answerOffer(pc, req.Offer)
+	if err != nil {
+		pc.Close()
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	// Register the relay unless the track was unpublished meanwhile
+	relayID := fmt.Sprintf("%s/%d", req.NodeID, time.Now().UnixNano())
+	registered := false
+	s.roomUpdate(room, func() {
+		if room.Tracks[published.ID] == published {
+			published.Relays[relayID] = pc
+			registered = true
+		}
+	})
+
+	if !registered {
+		pc.Close()
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Track not found")})
+		return
+	}
+
+	// Forget the relay once the edge node goes away
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateClosed || state == webrtc.PeerConnectionStateFailed {
+			s.roomUpdate(room, func() {
+				delete(published.Relays, relayID)
+			})
+			pc.Close()
+		}
+	})
+
+	sfuLog.Debugf("Relaying track %s of room %s to node %s", published.ID, room.ID, req.NodeID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"answer": answer,
+	})
+}
+
+// answerOffer applies a remote offer and returns the answer with all ICE candidates gathered
+func answerOffer(pc *webrtc.PeerConnection, offer webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
+	if err := pc.SetRemoteDescription(offer); err != nil {
+		return nil, fmt.Errorf("invalid offer: %v", err)
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create answer: %v", err)
+	}
+
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return nil, fmt.Errorf("failed to set local description: %v", err)
+	}
+	<-gathered
+
+	return pc.LocalDescription(), nil
+}
+
+// cascadeRoom returns a local edge room mirroring a room hosted on another node.
+// It returns nil if no node hosts the room.
+func (s *Server) cascadeRoom(c *gin.Context, roomID string) (*models.Room, error) {
+	if s.cluster == nil {
+		return nil, nil
+	}
+
+	owner, ok, err := s.cluster.RoomOwner(c.Request.Context(), roomID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || owner.ID == s.cluster.LocalNode().ID {
+		return nil, nil
+	}
+
+	var view relayRoomView
+	if err := s.clusterRequest(http.MethodGet, owner, "/cluster/rooms/"+roomID, nil, &view); err != nil {
+		return nil, err
+	}
+
+	s.roomManager.Mu.Lock()
+	defer s.roomManager.Mu.Unlock()
+
+	// Another request may have created the edge room meanwhile
+	if room, exists := s.roomManager.Rooms[roomID]; exists {
+		return room, nil
+	}
+
+	room := &models.Room{
+		ID:        view.ID,
+		Name:      view.Name,
+		CreatorID: view.CreatorID,
+		TenantID:  view.TenantID,
+		Clients:   make(map[string]*models.Client),
+		Tracks:    make(map[string]*models.PublishedTrack),
+		CreatedAt: view.CreatedAt,
+		IsActive:  true,
+	}
+	s.roomManager.Rooms[roomID] = room
+
+	cc := &cascade{
+		room:   room,
+		origin: owner,
+		relays: make(map[string]*webrtc.PeerConnection),
+		stop:   make(chan struct{}),
+	}
+	s.cascadesMu.Lock()
+	s.cascades[roomID] = cc
+	s.cascadesMu.Unlock()
+
+	go s.runCascade(cc)
+
+	sfuLog.Infof("Serving room %s as an edge of node %s", roomID, owner.ID)
+	return room, nil
+}
+
+// stopCascade stops relaying into an edge room and removes it once its session ends
+func (s *Server) stopCascade(loop *roomLoop, room *models.Room) {
+	s.cascadesMu.Lock()
+	cc, exists := s.cascades[room.ID]
+	delete(s.cascades, room.ID)
+	s.cascadesMu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	cc.once.Do(func() { close(cc.stop) })
+
+	s.roomManager.Mu.Lock()
+	delete(s.roomManager.Rooms, room.ID)
+	s.roomManager.Mu.Unlock()
+
+	loop.stop()
+	s.metrics.ForgetRoom(room.ID)
+}
+
+// runCascade keeps the edge room's relayed tracks in sync with the origin until stopped
+func (s *Server) runCascade(cc *cascade) {
+	ticker := time.NewTicker(cascadeSyncInterval)
+	defer ticker.Stop()
+
+	defer func() {
+		cc.mu.Lock()
+		for trackID, pc := range cc.relays {
+			pc.Close()
+			delete(cc.relays, trackID)
+		}
+		cc.mu.Unlock()
+	}()
+
+	for {
+		s.syncCascade(cc)
+
+		select {
+		case <-cc.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncCascade starts relays for new origin tracks and stops relays for removed ones
+func (s *Server) syncCascade(cc *cascade) {
+	var view relayRoomView
+	err := s.clusterRequest(http.MethodGet, cc.origin, "/cluster/rooms/"+cc.room.ID, nil, &view)
+	if err != nil {
+		sfuLog.Errorf("Failed to sync edge room %s from node %s: %v", cc.room.ID, cc.origin.ID, err)
+		return
+	}
+
+	current := make(map[string]bool, len(view.Tracks))
+	for _, track := range view.Tracks {
+		current[track.ID] = true
+	}
+
+	cc.mu.Lock()
+	var added []relayTrackView
+	for _, track := range view.Tracks {
+		if _, relayed := cc.relays[track.ID]; !relayed {
+			added = append(added, track)
+		}
+	}
+	for trackID, pc := range cc.relays {
+		if !current[trackID] {
+			pc.Close()
+			delete(cc.relays, trackID)
+		}
+	}
+	cc.mu.Unlock()
+
+	for _, track := range added {
+		pc, err := s.relayTrack(cc, track)
+		if err != nil {
+			sfuLog.Errorf("Failed to relay track %s of room %s: %v", track.ID, cc.room.ID, err)
+			continue
+		}
+
+		cc.mu.Lock()
+		cc.relays[track.ID] = pc
+		cc.mu.Unlock()
+	}
+}
+
+// relayTrack receives a track from the origin node and publishes it into the edge room
+func (s *Server) relayTrack(cc *cascade, track relayTrackView) (*webrtc.PeerConnection, error) {
+	kind := webrtc.NewRTPCodecType(track.Kind)
+	if kind == 0 {
+		return nil, fmt.Errorf("unknown track kind %q", track.Kind)
+	}
+
+	pc, err := s.newPeerConnection(models.RoomSettings{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create peer connection: %v", err)
+	}
+
+	if _, err := pc.AddTransceiverFromKind(kind, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	}); err != nil {
+		pc.Close()
+		return nil, fmt.Errorf("failed to add transceiver: %v", err)
+	}
+
+	// Relayed tracks are owned by the origin node so they reach every local participant
+	pc.OnTrack(func(remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		s.forwardRemoteTrack(cc.room, "node:"+cc.origin.ID, remote, receiver, nil)
+	})
+
+	// Drop the relay if the origin goes away; the next sync re-establishes it
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateClosed || state == webrtc.PeerConnectionStateFailed {
+			cc.mu.Lock()
+			if cc.relays[track.ID] == pc {
+				delete(cc.relays, track.ID)
+			}
+			cc.mu.Unlock()
+			pc.Close()
+		}
+	})
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		pc.Close()
+		return nil, fmt.Errorf("failed to create offer: %v", err)
+	}
+
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(offer); err != nil {
+		pc.Close()
+		return nil, fmt.Errorf("failed to set local description: %v", err)
+	}
+	<-gathered
+
+	var resp struct {
+		Answer webrtc.SessionDescription `json:"answer"`
+	}
+	path := fmt.Sprintf("/cluster/rooms/%s/tracks/%s/relay", cc.room.ID, track.ID)
+	body := gin.H{"node_id": s.cluster.LocalNode().ID, "offer": pc.LocalDescription()}
+	if err := s.clusterRequest(http.MethodPost, cc.origin, path, body, &resp); err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	if err := pc.SetRemoteDescription(resp.Answer); err != nil {
+		pc.Close()
+		return nil, fmt.Errorf("invalid answer: %v", err)
+	}
+
+	return pc, nil
+}
+
+// clusterRequest sends an authenticated JSON request to another node
+func (s *Server) clusterRequest(method string, node cluster.Node, path string, body, out interface{}) error {
+	if node.URL == "" {
+		return cluster.ErrNodeUnavailable
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(node.URL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(clusterSecretHeader, s.clusterSecret)
+
+	client := &http.Client{Timeout: relayTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to node %s failed: %v", node.ID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return errors.New(apiErr.Error)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// maxCallRecords bounds the number of completed call detail records kept in memory
+const maxCallRecords = 1000
+
+// callParticipant is a user's share of a call
+type callParticipant struct {
+	UserID        string    `json:"user_id"`
+	Username      string    `json:"username"`
+	FirstJoin     time.Time `json:"first_joined_at"`
+	Joins         int       `json:"joins"`
+	Leaves        int       `json:"leaves"`
+	Seconds       float64   `json:"seconds"`
+	TalkSeconds   float64   `json:"talk_seconds"`   // while the participant's audio carried speech
+	CameraSeconds float64   `json:"camera_seconds"` // while the participant's camera was on
+
+	// Connection quality averaged over periodic samples, see runQualitySampler
+	QualitySamples       int     `json:"quality_samples"`
+	AvgRTTMs             float64 `json:"avg_rtt_ms"`
+	AvgPacketLossPercent float64 `json:"avg_packet_loss_percent"`
+}
+
+// callRecord is the call detail record (CDR) of a room, completed when the room is closed
+type callRecord struct {
+	RoomID             string            `json:"room_id"`
+	RoomName           string            `json:"room_name"`
+	TenantID           string            `json:"tenant_id,omitempty"`
+	CreatorID          string            `json:"creator_id"`
+	StartedAt          time.Time         `json:"started_at"`
+	EndedAt            time.Time         `json:"ended_at"`
+	ClosedAt           time.Time         `json:"closed_at"`
+	DurationSeconds    float64           `json:"duration_seconds"`
+	ParticipantSeconds float64           `json:"participant_seconds"`
+	PeakParticipants   int               `json:"peak_participants"`
+	Participants       []callParticipant `json:"participants"`
+	Recordings         []string          `json:"recordings"`
+	Reason             string            `json:"reason"`
+}
+
+// callPresence is a participant currently in a call
+type callPresence struct {
+	userID   string
+	joinedAt time.Time
+}
+
+// callState accumulates the record of a call in progress
+type callState struct {
+	record       callRecord
+	participants map[string]*callParticipant // by user ID
+	present      map[string]callPresence     // by client ID
+}
+
+// callLog tracks calls in progress and keeps the records of finished ones
+type callLog struct {
+	active  map[string]*callState
+	records []callRecord
+	mu      sync.Mutex
+}
+
+// newCallLog creates an empty call log
+func newCallLog() *callLog {
+	return &callLog{
+		active: make(map[string]*callState),
+	}
+}
+
+// join records a participant joining a room; bots are not billed and are left out
+func (l *callLog) join(roomID string, client *models.Client) {
+	if client.IsBot {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	call, exists := l.active[roomID]
+	if !exists {
+		call = &callState{
+			record:       callRecord{RoomID: roomID, StartedAt: now},
+			participants: make(map[string]*callParticipant),
+			present:      make(map[string]callPresence),
+		}
+		l.active[roomID] = call
+	}
+
+	participant, exists := call.participants[client.UserID]
+	if !exists {
+		participant = &callParticipant{UserID: client.UserID, Username: client.Username, FirstJoin: now}
+		call.participants[client.UserID] = participant
+	}
+	participant.Joins++
+	call.present[client.ID] = callPresence{userID: client.UserID, joinedAt: now}
+
+	if len(call.present) > call.record.PeakParticipants {
+		call.record.PeakParticipants = len(call.present)
+	}
+}
+
+// leave records a participant leaving a room
+func (l *callLog) leave(roomID string, client *models.Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	call, exists := l.active[roomID]
+	if !exists {
+		return
+	}
+	presence, present := call.present[client.ID]
+	if !present {
+		return
+	}
+	delete(call.present, client.ID)
+
+	now := time.Now()
+	participant := call.participants[presence.userID]
+	participant.Seconds += now.Sub(presence.joinedAt).Seconds()
+	participant.Leaves++
+	call.record.EndedAt = now
+}
+
+// finish completes the record of a room's call; it returns false if nobody joined the room
+func (l *callLog) finish(room *models.Room, reason string, recordings []string) (callRecord, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	call, exists := l.active[room.ID]
+	if !exists {
+		return callRecord{}, false
+	}
+	delete(l.active, room.ID)
+
+	now := time.Now()
+	record := call.record
+	// Participants still present are counted up to now
+	for _, presence := range call.present {
+		call.participants[presence.userID].Seconds += now.Sub(presence.joinedAt).Seconds()
+		record.EndedAt = now
+	}
+
+	room.Mu.RLock()
+	record.RoomName = room.Name
+	record.TenantID = room.TenantID
+	record.CreatorID = room.CreatorID
+	room.Mu.RUnlock()
+
+	record.ClosedAt = now
+	record.DurationSeconds = record.EndedAt.Sub(record.StartedAt).Seconds()
+	record.Reason = reason
+	record.Recordings = recordings
+	if record.Recordings == nil {
+		record.Recordings = []string{}
+	}
+
+	record.Participants = make([]callParticipant, 0, len(call.participants))
+	for _, participant := range call.participants {
+		record.ParticipantSeconds += participant.Seconds
+		record.Participants = append(record.Participants, *participant)
+	}
+	sortParticipants(record.Participants)
+
+	l.records = append(l.records, record)
+	if len(l.records) > maxCallRecords {
+		l.records = l.records[len(l.records)-maxCallRecords:]
+	}
+
+	return record, true
+}
+
+// sortParticipants orders participants by when they first joined
+func sortParticipants(participants []callParticipant) {
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].FirstJoin.Before(participants[j].FirstJoin)
+	})
+}
+
+// attendees returns the users who took part in a room's call between from and to,
+// in the call in progress or in finished calls overlapping that period
+func (l *callLog) attendees(roomID string, from, to time.Time) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	add := func(participant callParticipant) {
+		if !seen[participant.UserID] && !participant.FirstJoin.After(to) {
+			seen[participant.UserID] = true
+			userIDs = append(userIDs, participant.UserID)
+		}
+	}
+
+	if call, exists := l.active[roomID]; exists {
+		for _, participant := range call.participants {
+			add(*participant)
+		}
+	}
+	for _, record := range l.records {
+		if record.RoomID == roomID && !record.StartedAt.After(to) && !record.EndedAt.Before(from) {
+			for _, participant := range record.Participants {
+				add(participant)
+			}
+		}
+	}
+	return userIDs
+}
+
+// list returns completed records, newest first, optionally only those of one room
+func (l *callLog) list(roomID string) []callRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	records := []callRecord{}
+	for i := len(l.records) - 1; i >= 0; i-- {
+		if roomID == "" || l.records[i].RoomID == roomID {
+			records = append(records, l.records[i])
+		}
+	}
+	return records
+}
+
+// adminCallRecordsHandler returns call detail records of closed rooms
+func (s *Server) adminCallRecordsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"records": s.calls.list(c.Query("room_id")),
+	})
+}
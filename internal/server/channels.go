@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/notify/channels"
+)
+
+// adminCreateChannelHandler connects a Slack or Microsoft Teams incoming webhook;
+// events left out of the toggles are posted
+func (s *Server) adminCreateChannelHandler(c *gin.Context) {
+	var req struct {
+		Name     string          `json:"name" binding:"required"`
+		Provider string          `json:"provider" binding:"required"`
+		URL      string          `json:"url" binding:"required"`
+		Events   map[string]bool `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	channel, err := s.chatHooks.Create(strings.TrimSpace(req.Name), req.Provider, req.URL, req.Events, c.GetString("user_id"))
+	if err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	s.recordAudit(c, "chat_channel.create", channel.ID, map[string]string{
+		"name":     channel.Name,
+		"provider": channel.Provider,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Channel created",
+		"channel": channel,
+	})
+}
+
+// adminListChannelsHandler lists chat channels without their webhook URLs
+func (s *Server) adminListChannelsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"channels": s.chatHooks.List(),
+		"events":   channels.EventTypes,
+	})
+}
+
+// adminUpdateChannelHandler renames a chat channel or switches events on and off
+func (s *Server) adminUpdateChannelHandler(c *gin.Context) {
+	var req struct {
+		Name   *string         `json:"name"`
+		Events map[string]bool `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "name is required")})
+			return
+		}
+		req.Name = &name
+	}
+
+	channel, err := s.chatHooks.Update(c.Param("id"), req.Name, req.Events)
+	if err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	s.recordAudit(c, "chat_channel.update", channel.ID, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Channel updated",
+		"channel": channel,
+	})
+}
+
+// adminDeleteChannelHandler disconnects a chat channel
+func (s *Server) adminDeleteChannelHandler(c *gin.Context) {
+	id := c.Param("id")
+	if err := s.chatHooks.Delete(id); err != nil {
+		respondChannelError(c, err)
+		return
+	}
+
+	s.recordAudit(c, "chat_channel.delete", id, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Channel deleted"})
+}
+
+// respondChannelError answers a failed chat channel operation
+func respondChannelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, channels.ErrChannelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Channel not found")})
+	case errors.Is(err, channels.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, channels.ErrInvalidEvent.Error()), "events": channels.EventTypes})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+	}
+}
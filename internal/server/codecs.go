@@ -0,0 +1,83 @@
+package server
+
+import (
+	"github.com/pion/interceptor"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// videoFeedback is the RTCP feedback negotiated for video codecs
+var videoFeedback = []webrtc.RTCPFeedback{
+	{Type: webrtc.TypeRTCPFBGoogREMB},
+	{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"},
+	{Type: webrtc.TypeRTCPFBNACK},
+	{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"},
+	{Type: webrtc.TypeRTCPFBTransportCC},
+}
+
+// videoCodecs are the video codecs a room can be restricted to, by name
+var videoCodecs = map[string]webrtc.RTPCodecParameters{
+	"vp8": {
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000, RTCPFeedback: videoFeedback},
+		PayloadType:        96,
+	},
+	"vp9": {
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP9, ClockRate: 90000, SDPFmtpLine: "profile-id=0", RTCPFeedback: videoFeedback},
+		PayloadType:        98,
+	},
+	"h264": {
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000, SDPFmtpLine: "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f", RTCPFeedback: videoFeedback},
+		PayloadType:        102,
+	},
+	"av1": {
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeAV1, ClockRate: 90000, RTCPFeedback: videoFeedback},
+		PayloadType:        45,
+	},
+}
+
+// opusCodec is always offered; rooms only restrict video
+var opusCodec = webrtc.RTPCodecParameters{
+	RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"},
+	PayloadType:        111,
+}
+
+// newPeerConnection creates a server-side peer connection, negotiating only the video
+// codecs the room allows. Participant and relay connections alike go through it, so
+// all media uses the ports of the ICE settings.
+func (s *Server) newPeerConnection(settings models.RoomSettings) (*webrtc.PeerConnection, error) {
+	engine := &webrtc.MediaEngine{}
+	if len(settings.VideoCodecs) == 0 {
+		if err := engine.RegisterDefaultCodecs(); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := engine.RegisterCodec(opusCodec, webrtc.RTPCodecTypeAudio); err != nil {
+			return nil, err
+		}
+		for _, name := range settings.VideoCodecs {
+			if err := engine.RegisterCodec(videoCodecs[name], webrtc.RTPCodecTypeVideo); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Audio levels let the server tell when participants speak, see mediaActivity
+	if err := engine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{URI: sdp.AudioLevelURI}, webrtc.RTPCodecTypeAudio); err != nil {
+		return nil, err
+	}
+
+	// Keep the NACK, RTCP report and TWCC handling of default peer connections
+	registry := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(engine, registry); err != nil {
+		return nil, err
+	}
+
+	api := webrtc.NewAPI(
+		webrtc.WithMediaEngine(engine),
+		webrtc.WithInterceptorRegistry(registry),
+		webrtc.WithSettingEngine(s.iceSettings),
+	)
+	return api.NewPeerConnection(s.webrtcConfig())
+}
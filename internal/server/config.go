@@ -0,0 +1,263 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/audit"
+	"github.com/zubans/video-call-server/internal/logging"
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+// defaultICEServers are used when ICE_SERVERS is not set
+var defaultICEServers = []string{"stun:stun.l.google.com:19302"}
+
+// runtimeConfig is the configuration that can be reloaded without a restart.
+// Active calls keep their peer connections; new values apply to new requests.
+type runtimeConfig struct {
+	AllowedOrigins         []string            `json:"allowed_origins"`
+	AdminUsers             []string            `json:"admin_users"`
+	ICEServers             []string            `json:"ice_servers"`
+	TURNUsername           string              `json:"turn_username,omitempty"`
+	TURNRegions            []turnRegion        `json:"turn_regions,omitempty"`
+	LoadLimits             loadLimits          `json:"load_limits"`
+	RetryAfterSeconds      int                 `json:"retry_after_seconds"`
+	UserSearchPerMinute    int                 `json:"user_search_per_minute"`
+	TenantRateLimit        int                 `json:"tenant_rate_limit"` // requests per minute of each tenant
+	Tenants                []string            `json:"tenants,omitempty"`
+	TenantDomain           string              `json:"tenant_domain,omitempty"`
+	Features               featureFlags        `json:"features"`
+	Spam                   spamLimits          `json:"spam"`
+	HoldMusicFile          string              `json:"hold_music_file,omitempty"`
+	QueueWrapUpSeconds     int                 `json:"queue_wrap_up_seconds"`
+	Maintenance            maintenanceSettings `json:"maintenance"`
+	RoomIdleTimeoutSeconds int                 `json:"room_idle_timeout_seconds"`
+	BodyLimits             bodyLimits          `json:"body_limits"`
+	MaxChatMessageLength   int                 `json:"max_chat_message_length"`
+	MaxRoomNameLength      int                 `json:"max_room_name_length"`
+	IdempotencyTTLSeconds  int                 `json:"idempotency_ttl_seconds"`
+	RestoreWindowSeconds   int                 `json:"restore_window_seconds"`
+	DefaultLanguage        string              `json:"default_language"`
+	ReminderMinutes        []int               `json:"reminder_minutes"`
+	Watermark              recording.Watermark `json:"watermark"`
+	Logging                logging.Config      `json:"logging"`
+	LoadedAt               time.Time           `json:"loaded_at"`
+
+	// TURN password is never reported
+	turnCredential string
+}
+
+// configFile tracks the variables set from CONFIG_FILE together with the values they
+// had in the environment before, so that a key removed from the file falls back to
+// it on reload instead of keeping the stale value
+var configFile = struct {
+	previous map[string]*string // nil when the variable was not set
+	mu       sync.Mutex
+}{previous: make(map[string]*string)}
+
+// loadConfigFile sets environment variables from CONFIG_FILE, a file of KEY=VALUE
+// lines, so that edited values are picked up on reload. Keys no longer in the file
+// are restored to their value from before the file set them. A file with an error is
+// not applied at all.
+func loadConfigFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	values := make(map[string]string)
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+
+			key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+			}
+			values[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	configFile.mu.Lock()
+	defer configFile.mu.Unlock()
+
+	for key, previous := range configFile.previous {
+		if _, kept := values[key]; kept {
+			continue
+		}
+		if previous != nil {
+			os.Setenv(key, *previous)
+		} else {
+			os.Unsetenv(key)
+		}
+		delete(configFile.previous, key)
+	}
+
+	for key, value := range values {
+		if _, tracked := configFile.previous[key]; !tracked {
+			if previous, set := os.LookupEnv(key); set {
+				configFile.previous[key] = &previous
+			} else {
+				configFile.previous[key] = nil
+			}
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// readRuntimeConfig reads the reloadable configuration from the environment
+func readRuntimeConfig() *runtimeConfig {
+	iceServers := envList("ICE_SERVERS")
+	if len(iceServers) == 0 {
+		iceServers = defaultICEServers
+	}
+
+	return &runtimeConfig{
+		AllowedOrigins: envList("ALLOWED_ORIGINS"),
+		AdminUsers:     envList("ADMIN_USERS"),
+		ICEServers:     iceServers,
+		TURNUsername:   os.Getenv("TURN_USERNAME"),
+		turnCredential: os.Getenv("TURN_CREDENTIAL"),
+		TURNRegions:    readTURNRegions(),
+		LoadLimits: loadLimits{
+			MaxCPUPercent:    float64(envInt64("LOAD_MAX_CPU_PERCENT", 0)),
+			MaxBandwidthMbps: float64(envInt64("LOAD_MAX_BANDWIDTH_MBPS", 0)),
+			MaxTracks:        int(envInt64("LOAD_MAX_TRACKS", 0)),
+			MaxRooms:         int(envInt64("LOAD_MAX_ROOMS", 0)),
+		},
+		RetryAfterSeconds:      int(envInt64("LOAD_RETRY_AFTER_SECONDS", 30)),
+		UserSearchPerMinute:    int(envInt64("USER_SEARCH_RATE_LIMIT", 30)),
+		TenantRateLimit:        int(envInt64("TENANT_RATE_LIMIT", 0)),
+		Tenants:                envList("TENANTS"),
+		TenantDomain:           strings.ToLower(os.Getenv("TENANT_DOMAIN")),
+		Features:               readFeatureFlags(),
+		Spam:                   readSpamLimits(),
+		HoldMusicFile:          os.Getenv("HOLD_MUSIC_FILE"),
+		QueueWrapUpSeconds:     int(envInt64("QUEUE_WRAP_UP_SECONDS", 30)),
+		Maintenance:            readMaintenanceSettings(),
+		RoomIdleTimeoutSeconds: int(envInt64("ROOM_IDLE_TIMEOUT_SECONDS", 300)),
+		BodyLimits:             readBodyLimits(),
+		MaxChatMessageLength:   int(envInt64("MAX_CHAT_MESSAGE_LENGTH", 4000)),
+		MaxRoomNameLength:      int(envInt64("MAX_ROOM_NAME_LENGTH", 100)),
+		IdempotencyTTLSeconds:  int(envInt64("IDEMPOTENCY_TTL_SECONDS", 86400)),
+		RestoreWindowSeconds:   int(envInt64("RESTORE_WINDOW_SECONDS", 604800)),
+		DefaultLanguage:        readDefaultLanguage(),
+		ReminderMinutes:        readReminderMinutes(),
+		Watermark:              readWatermark(),
+		Logging:                readLoggingConfig(),
+		LoadedAt:               time.Now(),
+	}
+}
+
+// settings returns the current reloadable configuration
+func (s *Server) settings() *runtimeConfig {
+	return s.config.Load()
+}
+
+// applyConfig installs a configuration and pushes it to components holding their own copy
+func (s *Server) applyConfig(config *runtimeConfig) {
+	s.config.Store(config)
+	logging.Configure(config.Logging)
+
+	if len(config.AllowedOrigins) == 0 {
+		serverLog.Warnf("ALLOWED_ORIGINS is not set: accepting requests from any origin")
+	}
+	websocket.SetAllowedOrigins(config.AllowedOrigins)
+	s.recorder.SetWatermark(config.Watermark)
+	s.promoteAdminUsers(config.AdminUsers)
+}
+
+// reloadConfig re-reads CONFIG_FILE and the environment and applies the result.
+// On error the previous configuration stays in effect.
+func (s *Server) reloadConfig() (*runtimeConfig, error) {
+	if err := loadConfigFile(); err != nil {
+		return nil, err
+	}
+
+	config := readRuntimeConfig()
+	s.applyConfig(config)
+
+	serverLog.Infof("Configuration reloaded: %d allowed origins, %d ICE servers", len(config.AllowedOrigins), len(config.ICEServers))
+	return config, nil
+}
+
+// handleReloadSignal reloads the configuration every time the process receives SIGHUP
+func (s *Server) handleReloadSignal(signals <-chan os.Signal) {
+	for range signals {
+		if _, err := s.reloadConfig(); err != nil {
+			serverLog.Errorf("Configuration reload failed: %v", err)
+			continue
+		}
+		s.audit.Record(audit.Entry{
+			Actor:  "SIGHUP",
+			Action: "config.reload",
+			Target: "server",
+		})
+	}
+}
+
+// allowOrigin reports whether CORS requests from an origin are allowed
+func (s *Server) allowOrigin(origin string) bool {
+	origins := s.settings().AllowedOrigins
+	if len(origins) == 0 {
+		return true
+	}
+
+	origin = strings.TrimRight(strings.ToLower(origin), "/")
+	for _, allowed := range origins {
+		if strings.TrimRight(strings.ToLower(allowed), "/") == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// webrtcConfig returns the configuration for server-side peer connections
+func (s *Server) webrtcConfig() webrtc.Configuration {
+	config := s.settings()
+	stun, turn := splitICEServers(config.ICEServers)
+	return webrtc.Configuration{ICEServers: iceServers(config, stun, turn)}
+}
+
+// adminConfigHandler returns the reloadable configuration in effect
+func (s *Server) adminConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.settings())
+}
+
+// adminReloadConfigHandler reloads configuration without restarting the server
+func (s *Server) adminReloadConfigHandler(c *gin.Context) {
+	config, err := s.reloadConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": trf(c, "Failed to reload configuration: %v", err)})
+		return
+	}
+
+	s.recordAudit(c, "config.reload", "server", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Configuration reloaded",
+		"config":  config,
+	})
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/contacts"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+const (
+	// minSearchQueryLength keeps searches from listing every user
+	minSearchQueryLength = 2
+
+	// maxSearchResults caps the number of users returned by a search
+	maxSearchResults = 20
+)
+
+// userSummary is the public view of a user shown to other users; emails are never included
+func userSummary(user *auth.User) gin.H {
+	return gin.H{
+		"id":           user.ID,
+		"username":     user.Username,
+		"display_name": user.Name(),
+		"avatar_url":   user.AvatarURL,
+	}
+}
+
+// searchUsersHandler finds users by username prefix, or by email prefix once the query contains "@"
+func (s *Server) searchUsersHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if !s.searchLimiter.allow(userID, s.settings().UserSearchPerMinute) {
+		c.Header("Retry-After", strconv.Itoa(int(rateWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": tr(c, "Too many searches, try again later")})
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) < minSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Query must be at least 2 characters")})
+		return
+	}
+
+	// Users who blocked the searcher are not shown
+	results := []gin.H{}
+	for _, user := range auth.SearchUsers(tenantID(c), query, maxSearchResults+1) {
+		if blocked, _ := s.contacts.IsBlocked(user.ID, userID); blocked || user.ID == userID {
+			continue
+		}
+		if len(results) < maxSearchResults {
+			results = append(results, userSummary(user))
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": results,
+	})
+}
+
+// listContactsHandler returns the current user's contacts, favorites first
+func (s *Server) listContactsHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	list := []gin.H{}
+	for _, contact := range s.contacts.List(userID) {
+		// Skip contacts whose accounts no longer exist
+		user, exists := auth.GetUserByID(contact.UserID)
+		if !exists {
+			continue
+		}
+
+		entry := userSummary(user)
+		entry["favorite"] = contact.Favorite
+		entry["added_at"] = contact.AddedAt
+		entry["status"] = s.presence.Get(contact.UserID)
+		list = append(list, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"contacts": list,
+	})
+}
+
+// addContactHandler saves a user to the current user's contacts
+func (s *Server) addContactHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		UserID   string `json:"user_id" binding:"required"`
+		Favorite bool   `json:"favorite"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Cannot add yourself as a contact")})
+		return
+	}
+	if user, exists := auth.GetUserByID(req.UserID); !exists || user.TenantID != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User not found")})
+		return
+	}
+
+	contact := s.contacts.Add(userID, req.UserID, req.Favorite)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contact added",
+		"contact": contact,
+	})
+}
+
+// updateContactHandler marks or unmarks a contact as a favorite
+func (s *Server) updateContactHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		Favorite bool `json:"favorite"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	contact, err := s.contacts.SetFavorite(userID, c.Param("user_id"), req.Favorite)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Contact not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contact updated",
+		"contact": contact,
+	})
+}
+
+// removeContactHandler deletes a user from the current user's contacts
+func (s *Server) removeContactHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if err := s.contacts.Remove(userID, c.Param("user_id")); err == contacts.ErrContactNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Contact not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact removed"})
+}
+
+// blockedFromRoom reports whether the room's creator blocked a user from their rooms
+func (s *Server) blockedFromRoom(room *models.Room, userID string) bool {
+	_, fromRooms := s.contacts.IsBlocked(room.CreatorID, userID)
+	return fromRooms
+}
+
+// listBlocksHandler returns the users the current user has blocked
+func (s *Server) listBlocksHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	list := []gin.H{}
+	for _, block := range s.contacts.Blocked(userID) {
+		entry := gin.H{"id": block.UserID}
+		if user, exists := auth.GetUserByID(block.UserID); exists {
+			entry = userSummary(user)
+		}
+		entry["block_from_rooms"] = block.FromRooms
+		entry["blocked_at"] = block.BlockedAt
+		list = append(list, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blocked": list,
+	})
+}
+
+// blockUserHandler blocks a user; with block_from_rooms they also cannot join rooms the current user creates
+func (s *Server) blockUserHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		UserID         string `json:"user_id" binding:"required"`
+		BlockFromRooms bool   `json:"block_from_rooms"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Cannot block yourself")})
+		return
+	}
+	if user, exists := auth.GetUserByID(req.UserID); !exists || user.TenantID != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User not found")})
+		return
+	}
+
+	block := s.contacts.Block(userID, req.UserID, req.BlockFromRooms)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User blocked",
+		"block":   block,
+	})
+}
+
+// unblockUserHandler removes a block
+func (s *Server) unblockUserHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if err := s.contacts.Unblock(userID, c.Param("user_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User is not blocked")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
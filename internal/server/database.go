@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"os"
+
+	"github.com/zubans/video-call-server/internal/storage"
+)
+
+// openDatabase opens the SQLite database at SQLITE_PATH and brings its schema up to
+// date; nil keeps all state in memory. With DB_AUTO_MIGRATE=false pending migrations
+// are left to the migrate command and the server refuses to start until they are applied.
+func openDatabase() *storage.DB {
+	path := os.Getenv("SQLITE_PATH")
+	if path == "" {
+		return nil
+	}
+
+	if backend, address := clusterAddress(); address != "" {
+		serverLog.Warnf("SQLITE_PATH is meant for single-node installs: clustered nodes share tokens through %s", backend)
+	}
+
+	db, err := storage.OpenSQLite(path)
+	if err != nil {
+		serverLog.Fatalf("Failed to open SQLite database: %v", err)
+	}
+	serverLog.Infof("Using SQLite database %s", path)
+
+	ctx := context.Background()
+	if envBool("DB_AUTO_MIGRATE", true) {
+		applied, err := db.MigrateUp(ctx)
+		for _, migration := range applied {
+			serverLog.Infof("Applied database migration %d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			serverLog.Fatalf("Failed to migrate database: %v", err)
+		}
+		return db
+	}
+
+	current, latest, err := db.SchemaVersion(ctx)
+	if err != nil {
+		serverLog.Fatalf("Failed to read database schema version: %v", err)
+	}
+	if current < latest {
+		serverLog.Fatalf("Database schema is at version %d, this release needs %d: run the migrate command", current, latest)
+	}
+	return db
+}
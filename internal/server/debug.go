@@ -0,0 +1,337 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+// roomDebug is a snapshot of the full state of a live room for incident debugging
+type roomDebug struct {
+	RoomID        string        `json:"room_id"`
+	Name          string        `json:"name"`
+	CreatorID     string        `json:"creator_id"`
+	IsActive      bool          `json:"is_active"`
+	CreatedAt     time.Time     `json:"created_at"`
+	CapturedAt    time.Time     `json:"captured_at"`
+	Recording     bool          `json:"recording"`
+	LoopQueued    int           `json:"loop_queued"` // state changes waiting for the room's event loop
+	Clients       []clientDebug `json:"clients"`
+	Tracks        []trackDebug  `json:"tracks"`
+	SignalingOnly []string      `json:"signaling_only"` // WebSockets in the room without a participant
+}
+
+// clientDebug is the state of a participant
+type clientDebug struct {
+	ClientID    string                `json:"client_id"`
+	UserID      string                `json:"user_id"`
+	Username    string                `json:"username"`
+	IsBot       bool                  `json:"is_bot"`
+	JoinedAt    time.Time             `json:"joined_at"`
+	AudioMuted  bool                  `json:"audio_muted"`
+	VideoMuted  bool                  `json:"video_muted"`
+	Sources     map[string]string     `json:"track_sources"`
+	Peer        *peerDebug            `json:"peer"` // nil without a server-side peer connection
+	SignalQueue queueDebug            `json:"signal_queue"`
+	WebSockets  []websocket.SendQueue `json:"websockets"`
+}
+
+// queueDebug is the depth of a participant's signal channel
+type queueDebug struct {
+	Queued   int   `json:"queued"`
+	Capacity int   `json:"capacity"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// peerDebug is the state of a server-side peer connection
+type peerDebug struct {
+	ConnectionState    string               `json:"connection_state"`
+	ICEConnectionState string               `json:"ice_connection_state"`
+	ICEGatheringState  string               `json:"ice_gathering_state"`
+	SignalingState     string               `json:"signaling_state"`
+	SelectedPair       *candidatePairDebug  `json:"selected_pair"`
+	CandidatePairs     []candidatePairDebug `json:"candidate_pairs"`
+	Inbound            []rtpStreamDebug     `json:"inbound"`
+	Outbound           []rtpStreamDebug     `json:"outbound"`
+}
+
+// candidatePairDebug is an ICE candidate pair with its candidates resolved
+type candidatePairDebug struct {
+	ID                string          `json:"id"`
+	State             string          `json:"state"`
+	Nominated         bool            `json:"nominated"`
+	Local             *candidateDebug `json:"local"`
+	Remote            *candidateDebug `json:"remote"`
+	RTTMs             float64         `json:"rtt_ms"`
+	BytesSent         uint64          `json:"bytes_sent"`
+	BytesReceived     uint64          `json:"bytes_received"`
+	RequestsSent      uint64          `json:"requests_sent"`
+	ResponsesReceived uint64          `json:"responses_received"`
+}
+
+// candidateDebug is an ICE candidate
+type candidateDebug struct {
+	Type     string `json:"type"`
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int32  `json:"port"`
+	Relay    string `json:"relay_protocol,omitempty"`
+}
+
+// rtpStreamDebug holds the counters of an RTP stream received from or sent to a participant
+type rtpStreamDebug struct {
+	SSRC        uint32  `json:"ssrc"`
+	Kind        string  `json:"kind"`
+	TrackID     string  `json:"track_id,omitempty"`
+	Packets     uint32  `json:"packets"`
+	Bytes       uint64  `json:"bytes"`
+	PacketsLost int32   `json:"packets_lost,omitempty"` // inbound only
+	JitterMs    float64 `json:"jitter_ms,omitempty"`    // inbound only
+	NACKs       uint32  `json:"nacks"`
+	PLIs        uint32  `json:"plis"`
+	FIRs        uint32  `json:"firs"`
+	LastPacket  string  `json:"last_packet,omitempty"`
+}
+
+// trackDebug is a track published in the room
+type trackDebug struct {
+	ID          string   `json:"id"`
+	ClientID    string   `json:"client_id"`
+	Kind        string   `json:"kind"`
+	Source      string   `json:"source,omitempty"`
+	Subscribers []string `json:"subscribers"`
+	Relays      int      `json:"relays"`
+}
+
+// adminRoomDebugHandler dumps the full state of a live room, such as when a user
+// reports frozen video
+func (s *Server) adminRoomDebugHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	snapshot := roomDebug{
+		RoomID:        room.ID,
+		CapturedAt:    time.Now(),
+		Recording:     s.recorder.IsRecording(room.ID),
+		LoopQueued:    s.roomLoopQueued(room.ID),
+		Clients:       []clientDebug{},
+		Tracks:        []trackDebug{},
+		SignalingOnly: []string{},
+	}
+
+	room.Mu.RLock()
+	snapshot.Name = room.Name
+	snapshot.CreatorID = room.CreatorID
+	snapshot.IsActive = room.IsActive
+	snapshot.CreatedAt = room.CreatedAt
+	clients := make([]*models.Client, 0, len(room.Clients))
+	for _, client := range room.Clients {
+		sources := make(map[string]string, len(client.TrackSources))
+		for trackID, source := range client.TrackSources {
+			sources[trackID] = source
+		}
+		snapshot.Clients = append(snapshot.Clients, clientDebug{
+			ClientID:   client.ID,
+			UserID:     client.UserID,
+			Username:   client.Username,
+			IsBot:      client.IsBot,
+			JoinedAt:   client.JoinedAt,
+			AudioMuted: client.AudioMuted,
+			VideoMuted: client.VideoMuted,
+			Sources:    sources,
+			SignalQueue: queueDebug{
+				Queued:   len(client.Signal),
+				Capacity: cap(client.Signal),
+				Dropped:  atomic.LoadInt64(&client.SignalDrops),
+			},
+		})
+		clients = append(clients, client)
+	}
+	for _, published := range room.Tracks {
+		track := trackDebug{
+			ID:          published.ID,
+			ClientID:    published.ClientID,
+			Kind:        published.Kind,
+			Source:      published.Source,
+			Subscribers: make([]string, 0, len(published.Senders)),
+			Relays:      len(published.Relays),
+		}
+		for clientID := range published.Senders {
+			track.Subscribers = append(track.Subscribers, clientID)
+		}
+		sort.Strings(track.Subscribers)
+		snapshot.Tracks = append(snapshot.Tracks, track)
+	}
+	room.Mu.RUnlock()
+
+	// Stats and hub lookups happen outside the room lock
+	participants := make(map[string]bool, len(clients))
+	for i, client := range clients {
+		snapshot.Clients[i].Peer = debugPeer(client.Conn)
+		snapshot.Clients[i].WebSockets = s.hub.SenderQueues(client.ID)
+		participants[client.ID] = true
+	}
+	for _, senderID := range s.hub.RoomSenders(room.ID) {
+		if !participants[senderID] {
+			snapshot.SignalingOnly = append(snapshot.SignalingOnly, senderID)
+		}
+	}
+
+	sort.Slice(snapshot.Clients, func(i, j int) bool {
+		return snapshot.Clients[i].JoinedAt.Before(snapshot.Clients[j].JoinedAt)
+	})
+	sort.Slice(snapshot.Tracks, func(i, j int) bool {
+		return snapshot.Tracks[i].ID < snapshot.Tracks[j].ID
+	})
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// debugPeer collects the states, ICE candidate pairs and RTP stream stats of a peer connection
+func debugPeer(pc *webrtc.PeerConnection) *peerDebug {
+	if pc == nil {
+		return nil
+	}
+
+	peer := &peerDebug{
+		ConnectionState:    pc.ConnectionState().String(),
+		ICEConnectionState: pc.ICEConnectionState().String(),
+		ICEGatheringState:  pc.ICEGatheringState().String(),
+		SignalingState:     pc.SignalingState().String(),
+		CandidatePairs:     []candidatePairDebug{},
+		Inbound:            []rtpStreamDebug{},
+		Outbound:           []rtpStreamDebug{},
+	}
+
+	stats := pc.GetStats()
+	candidate := func(id string) *candidateDebug {
+		stat, ok := stats[id].(webrtc.ICECandidateStats)
+		if !ok {
+			return nil
+		}
+		return &candidateDebug{
+			Type:     stat.CandidateType.String(),
+			Protocol: stat.Protocol,
+			Address:  stat.IP,
+			Port:     stat.Port,
+			Relay:    stat.RelayProtocol,
+		}
+	}
+	for _, stat := range stats {
+		switch stat := stat.(type) {
+		case webrtc.ICECandidatePairStats:
+			peer.CandidatePairs = append(peer.CandidatePairs, candidatePairDebug{
+				ID:                stat.ID,
+				State:             string(stat.State),
+				Nominated:         stat.Nominated,
+				Local:             candidate(stat.LocalCandidateID),
+				Remote:            candidate(stat.RemoteCandidateID),
+				RTTMs:             stat.CurrentRoundTripTime * 1000,
+				BytesSent:         stat.BytesSent,
+				BytesReceived:     stat.BytesReceived,
+				RequestsSent:      stat.RequestsSent,
+				ResponsesReceived: stat.ResponsesReceived,
+			})
+		case webrtc.InboundRTPStreamStats:
+			peer.Inbound = append(peer.Inbound, rtpStreamDebug{
+				SSRC:        uint32(stat.SSRC),
+				Kind:        stat.Kind,
+				TrackID:     stat.TrackID,
+				Packets:     stat.PacketsReceived,
+				Bytes:       stat.BytesReceived,
+				PacketsLost: stat.PacketsLost,
+				JitterMs:    stat.Jitter * 1000,
+				NACKs:       stat.NACKCount,
+				PLIs:        stat.PLICount,
+				FIRs:        stat.FIRCount,
+				LastPacket:  statsTime(stat.LastPacketReceivedTimestamp),
+			})
+		case webrtc.OutboundRTPStreamStats:
+			peer.Outbound = append(peer.Outbound, rtpStreamDebug{
+				SSRC:       uint32(stat.SSRC),
+				Kind:       stat.Kind,
+				TrackID:    stat.TrackID,
+				Packets:    stat.PacketsSent,
+				Bytes:      stat.BytesSent,
+				NACKs:      stat.NACKCount,
+				PLIs:       stat.PLICount,
+				FIRs:       stat.FIRCount,
+				LastPacket: statsTime(stat.LastPacketSentTimestamp),
+			})
+		}
+	}
+	sort.Slice(peer.CandidatePairs, func(i, j int) bool {
+		return peer.CandidatePairs[i].ID < peer.CandidatePairs[j].ID
+	})
+	sort.Slice(peer.Inbound, func(i, j int) bool { return peer.Inbound[i].SSRC < peer.Inbound[j].SSRC })
+	sort.Slice(peer.Outbound, func(i, j int) bool { return peer.Outbound[i].SSRC < peer.Outbound[j].SSRC })
+
+	if selected := selectedCandidatePair(pc); selected != nil {
+		for i, pair := range peer.CandidatePairs {
+			if sameCandidate(pair.Local, selected.Local) && sameCandidate(pair.Remote, selected.Remote) {
+				peer.SelectedPair = &peer.CandidatePairs[i]
+				break
+			}
+		}
+	}
+	return peer
+}
+
+// selectedCandidatePair returns the candidate pair the ICE transport of a peer
+// connection uses, or nil before one is selected
+func selectedCandidatePair(pc *webrtc.PeerConnection) *webrtc.ICECandidatePair {
+	var transport *webrtc.DTLSTransport
+	for _, transceiver := range pc.GetTransceivers() {
+		if sender := transceiver.Sender(); sender != nil && sender.Transport() != nil {
+			transport = sender.Transport()
+		} else if receiver := transceiver.Receiver(); receiver != nil && receiver.Transport() != nil {
+			transport = receiver.Transport()
+		}
+		if transport != nil {
+			break
+		}
+	}
+	if transport == nil || transport.ICETransport() == nil {
+		return nil
+	}
+
+	pair, err := transport.ICETransport().GetSelectedCandidatePair()
+	if err != nil {
+		return nil
+	}
+	return pair
+}
+
+// sameCandidate reports whether candidate stats describe an ICE candidate
+func sameCandidate(stats *candidateDebug, candidate *webrtc.ICECandidate) bool {
+	return stats != nil && candidate != nil && stats.Address == candidate.Address && stats.Port == int32(candidate.Port)
+}
+
+// statsTime formats a stats timestamp, which is in milliseconds since the epoch
+func statsTime(timestamp webrtc.StatsTimestamp) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return timestamp.Time().UTC().Format(time.RFC3339Nano)
+}
+
+// roomLoopQueued returns how many state changes wait for the event loop of a room
+func (s *Server) roomLoopQueued(roomID string) int {
+	s.roomLoops.mu.Lock()
+	defer s.roomLoops.mu.Unlock()
+
+	if loop, exists := s.roomLoops.loops[roomID]; exists {
+		return len(loop.commands)
+	}
+	return 0
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// demoPage is a minimal browser client for validating a deployment end to end
+//
+//go:embed demo/index.html
+var demoPage []byte
+
+// demoHandler serves the built-in demo client
+func (s *Server) demoHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", demoPage)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+const (
+	// defaultDrainDeadline is how long a drain waits for rooms to end by default
+	defaultDrainDeadline = 10 * time.Minute
+
+	// drainCheckInterval is how often drain progress is checked
+	drainCheckInterval = 2 * time.Second
+)
+
+// drainState tracks an in-progress drain of this node
+type drainState struct {
+	active    bool
+	force     bool
+	startedAt time.Time
+	deadline  time.Time
+	drained   bool
+	cancel    chan struct{}
+	mu        sync.RWMutex
+}
+
+// draining reports whether the node stopped accepting new rooms
+func (d *drainState) draining() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.active
+}
+
+// activeSessions returns rooms that still have human participants and their participant count
+func (s *Server) activeSessions() (rooms []*models.Room, participants int) {
+	s.roomManager.Mu.RLock()
+	defer s.roomManager.Mu.RUnlock()
+
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		humans := 0
+		for _, client := range room.Clients {
+			if !client.IsBot {
+				humans++
+			}
+		}
+		room.Mu.RUnlock()
+
+		if humans > 0 {
+			rooms = append(rooms, room)
+			participants += humans
+		}
+	}
+	return rooms, participants
+}
+
+// drainStatus describes drain progress
+func (s *Server) drainStatus() gin.H {
+	rooms, participants := s.activeSessions()
+
+	d := &s.drain
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	status := gin.H{
+		"draining":     d.active,
+		"drained":      d.drained,
+		"active_rooms": len(rooms),
+		"participants": participants,
+	}
+	if d.active {
+		status["force"] = d.force
+		status["started_at"] = d.startedAt
+		status["deadline"] = d.deadline
+		status["remaining_seconds"] = int(time.Until(d.deadline).Seconds())
+	}
+	return status
+}
+
+// runDrain waits for active rooms to end, force-closing what remains at the deadline if requested
+func (s *Server) runDrain(deadline time.Time, force bool, cancel chan struct{}) {
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		rooms, _ := s.activeSessions()
+		if len(rooms) == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			if !force {
+				serverLog.Warnf("Drain deadline passed with %d active rooms", len(rooms))
+				return
+			}
+
+			serverLog.Warnf("Drain deadline passed, closing %d active rooms", len(rooms))
+			for _, room := range rooms {
+				s.closeRoomSessions(room)
+			}
+			break
+		}
+
+		select {
+		case <-cancel:
+			return
+		case <-ticker.C:
+		}
+	}
+
+	s.drain.mu.Lock()
+	if s.drain.cancel == cancel {
+		s.drain.drained = true
+	}
+	s.drain.mu.Unlock()
+
+	serverLog.Infof("Node drained")
+	s.publishEvent(events.NodeDrained, "", nil)
+}
+
+// closeRoomSessions disconnects every participant of a room
+func (s *Server) closeRoomSessions(room *models.Room) {
+	room.Mu.RLock()
+	clients := make([]*models.Client, 0, len(room.Clients))
+	for _, client := range room.Clients {
+		clients = append(clients, client)
+	}
+	room.Mu.RUnlock()
+
+	for _, client := range clients {
+		s.hub.DisconnectSender(client.ID)
+		s.removeClient(room, client)
+	}
+}
+
+// adminDrainHandler stops accepting new rooms and waits for existing rooms to end
+func (s *Server) adminDrainHandler(c *gin.Context) {
+	var req struct {
+		DeadlineSeconds int  `json:"deadline_seconds"`
+		Force           bool `json:"force"`
+	}
+	// The body is optional
+	_ = c.ShouldBindJSON(&req)
+
+	deadline := defaultDrainDeadline
+	if req.DeadlineSeconds > 0 {
+		deadline = time.Duration(req.DeadlineSeconds) * time.Second
+	}
+
+	until := time.Now().Add(deadline)
+	if !s.startDrain(until, req.Force) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Node is already draining")})
+		return
+	}
+
+	s.recordAudit(c, "node.drain", s.nodeID(), map[string]string{
+		"deadline_seconds": strconv.Itoa(int(deadline.Seconds())),
+		"force":            strconv.FormatBool(req.Force),
+	})
+
+	c.JSON(http.StatusAccepted, s.drainStatus())
+}
+
+// startDrain stops accepting new rooms and waits for existing rooms to end until a
+// deadline, reporting false when the node is already draining
+func (s *Server) startDrain(until time.Time, force bool) bool {
+	d := &s.drain
+	d.mu.Lock()
+	if d.active {
+		d.mu.Unlock()
+		return false
+	}
+	d.active = true
+	d.force = force
+	d.drained = false
+	d.startedAt = time.Now()
+	d.deadline = until
+	d.cancel = make(chan struct{})
+	cancel := d.cancel
+	d.mu.Unlock()
+
+	// Tell connected clients when the node goes away so they can reconnect elsewhere
+	rooms, _ := s.activeSessions()
+	for _, room := range rooms {
+		s.hub.Publish(room.ID, "server-draining", gin.H{
+			"room_id":  room.ID,
+			"deadline": until,
+			"force":    force,
+		})
+	}
+
+	go s.runDrain(until, force, cancel)
+
+	s.publishEvent(events.NodeDraining, "", map[string]interface{}{
+		"deadline": until,
+		"force":    force,
+	})
+
+	serverLog.Infof("Draining node: deadline %s, force %t", until.Format(time.RFC3339), force)
+	return true
+}
+
+// cancelDrain resumes accepting new rooms, reporting false when the node is not draining
+func (s *Server) cancelDrain() bool {
+	d := &s.drain
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.active {
+		return false
+	}
+	d.active = false
+	d.drained = false
+	close(d.cancel)
+	d.cancel = nil
+	return true
+}
+
+// adminDrainStatusHandler reports drain progress
+func (s *Server) adminDrainStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.drainStatus())
+}
+
+// adminCancelDrainHandler resumes accepting new rooms
+func (s *Server) adminCancelDrainHandler(c *gin.Context) {
+	if !s.cancelDrain() {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Node is not draining")})
+		return
+	}
+
+	s.recordAudit(c, "node.drain_cancel", s.nodeID(), nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Drain cancelled",
+	})
+}
+
+// nodeID returns the cluster node ID, or "local" when running standalone
+func (s *Server) nodeID() string {
+	if s.cluster != nil {
+		return s.cluster.LocalNode().ID
+	}
+	return "local"
+}
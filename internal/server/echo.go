@@ -0,0 +1,387 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// echoTestDuration is how long an echo test runs before the server hangs up
+const echoTestDuration = time.Minute
+
+// echoTrack measures a track sent back to its publisher
+type echoTrack struct {
+	kind      string
+	codec     string
+	clockRate uint32
+
+	started      time.Time
+	packets      uint64
+	bytes        uint64
+	lost         uint64
+	lastSequence uint16
+	lastTransit  float64
+	jitter       float64
+}
+
+// echoTrackStats is the JSON representation of an echoed track
+type echoTrackStats struct {
+	Kind              string  `json:"kind"`
+	Codec             string  `json:"codec"`
+	PacketsReceived   uint64  `json:"packets_received"`
+	PacketsLost       uint64  `json:"packets_lost"`
+	PacketLossPercent float64 `json:"packet_loss_percent"`
+	BytesReceived     uint64  `json:"bytes_received"`
+	BitrateKbps       float64 `json:"bitrate_kbps"`
+	JitterMs          float64 `json:"jitter_ms"`
+}
+
+// echoSession is a peer connection that sends a user's media straight back to them
+type echoSession struct {
+	id        string
+	userID    string
+	pc        *webrtc.PeerConnection
+	startedAt time.Time
+	timer     *time.Timer
+
+	mu     sync.Mutex
+	tracks []*echoTrack
+}
+
+// echoTests keeps the running echo tests, one per user
+type echoTests struct {
+	sessions map[string]*echoSession
+	mu       sync.Mutex
+}
+
+// newEchoTests creates an empty echoTests
+func newEchoTests() *echoTests {
+	return &echoTests{
+		sessions: make(map[string]*echoSession),
+	}
+}
+
+// add registers a session and returns the user's previous one, if any
+func (e *echoTests) add(session *echoSession) *echoSession {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var previous *echoSession
+	for _, existing := range e.sessions {
+		if existing.userID == session.userID {
+			previous = existing
+		}
+	}
+	e.sessions[session.id] = session
+	return previous
+}
+
+// get returns a user's session by ID
+func (e *echoTests) get(id, userID string) (*echoSession, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	session, exists := e.sessions[id]
+	if !exists || session.userID != userID {
+		return nil, false
+	}
+	return session, true
+}
+
+// remove forgets a session; it reports whether the session was still registered
+func (e *echoTests) remove(session *echoSession) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.sessions[session.id] != session {
+		return false
+	}
+	delete(e.sessions, session.id)
+	return true
+}
+
+// close hangs up an echo test unless it has already ended
+func (e *echoTests) close(session *echoSession) {
+	if !e.remove(session) {
+		return
+	}
+	session.timer.Stop()
+	if err := session.pc.Close(); err != nil {
+		sfuLog.Errorf("Failed to close echo test %s: %v", session.id, err)
+	}
+}
+
+// observe updates track statistics with a received packet, estimating interarrival
+// jitter as described in RFC 3550
+func (t *echoTrack) observe(sequence uint16, timestamp uint32, size int, arrival time.Time) {
+	if t.packets == 0 {
+		t.started = arrival
+	} else if gap := sequence - t.lastSequence - 1; gap > 0 && gap < maxSequenceGap {
+		t.lost += uint64(gap)
+	}
+	t.lastSequence = sequence
+	t.packets++
+	t.bytes += uint64(size)
+
+	if t.clockRate == 0 {
+		return
+	}
+	transit := arrival.Sub(t.started).Seconds()*float64(t.clockRate) - float64(timestamp)
+	if t.packets > 1 {
+		d := transit - t.lastTransit
+		if d < 0 {
+			d = -d
+		}
+		t.jitter += (d - t.jitter) / 16
+	}
+	t.lastTransit = transit
+}
+
+// stats returns the JSON representation of the track
+func (t *echoTrack) stats(now time.Time) echoTrackStats {
+	stats := echoTrackStats{
+		Kind:            t.kind,
+		Codec:           t.codec,
+		PacketsReceived: t.packets,
+		PacketsLost:     t.lost,
+		BytesReceived:   t.bytes,
+	}
+	if t.packets+t.lost > 0 {
+		stats.PacketLossPercent = float64(t.lost) / float64(t.packets+t.lost) * 100
+	}
+	if elapsed := now.Sub(t.started).Seconds(); t.packets > 0 && elapsed > 0 {
+		stats.BitrateKbps = float64(t.bytes) * 8 / elapsed / 1000
+	}
+	if t.clockRate > 0 {
+		stats.JitterMs = t.jitter / float64(t.clockRate) * 1000
+	}
+	return stats
+}
+
+// report describes the session's connection and tracks
+func (session *echoSession) report() gin.H {
+	now := time.Now()
+
+	session.mu.Lock()
+	tracks := make([]echoTrackStats, 0, len(session.tracks))
+	for _, track := range session.tracks {
+		tracks = append(tracks, track.stats(now))
+	}
+	session.mu.Unlock()
+
+	// The kind of local candidate in use tells a direct path from a TURN relay
+	candidateType := ""
+	stats := session.pc.GetStats()
+	for _, stat := range stats {
+		if pair, ok := stat.(webrtc.ICECandidatePairStats); ok && pair.Nominated {
+			if local, ok := stats[pair.LocalCandidateID].(webrtc.ICECandidateStats); ok {
+				candidateType = local.CandidateType.String()
+			}
+		}
+	}
+
+	return gin.H{
+		"id":             session.id,
+		"started_at":     session.startedAt,
+		"expires_at":     session.startedAt.Add(echoTestDuration),
+		"connection":     measureQuality(session.pc),
+		"candidate_type": candidateType,
+		"tracks":         tracks,
+	}
+}
+
+// createEchoTestHandler starts a loopback echo test, WHIP style: the client posts an
+// SDP offer and gets back an answer whose tracks carry its own audio and video, so it
+// can check camera, microphone and network before joining a room. The Location header
+// addresses the session for stats and hang-up.
+func (s *Server) createEchoTestHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	if c.ContentType() != "application/sdp" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": tr(c, "Content-Type must be application/sdp")})
+		return
+	}
+	if !s.admit(c, false) {
+		return
+	}
+
+	offer, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if len(offer) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "SDP offer is required")})
+		return
+	}
+
+	pc, err := s.newPeerConnection(models.RoomSettings{})
+	if err != nil {
+		sfuLog.Errorf("Failed to create echo test peer connection: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create peer connection")})
+		return
+	}
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: string(offer)}); err != nil {
+		pc.Close()
+		c.JSON(http.StatusBadRequest, gin.H{"error": trf(c, "Invalid offer: %v", err)})
+		return
+	}
+
+	// Send every offered track back on the same transceiver with the negotiated codec
+	echoes := make(map[webrtc.RTPCodecType]*webrtc.TrackLocalStaticRTP)
+	for _, transceiver := range pc.GetTransceivers() {
+		kind := transceiver.Kind()
+		if _, exists := echoes[kind]; exists || transceiver.Receiver() == nil {
+			continue
+		}
+		codecs := transceiver.Receiver().GetParameters().Codecs
+		if len(codecs) == 0 {
+			continue
+		}
+		local, err := webrtc.NewTrackLocalStaticRTP(codecs[0].RTPCodecCapability, "echo-"+kind.String(), "echo")
+		if err != nil {
+			pc.Close()
+			c.JSON(http.StatusBadRequest, gin.H{"error": trf(c, "Invalid offer: %v", err)})
+			return
+		}
+		if _, err := pc.AddTrack(local); err != nil {
+			pc.Close()
+			c.JSON(http.StatusBadRequest, gin.H{"error": trf(c, "Invalid offer: %v", err)})
+			return
+		}
+		echoes[kind] = local
+	}
+	if len(echoes) == 0 {
+		pc.Close()
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Offer has no audio or video")})
+		return
+	}
+
+	session := &echoSession{
+		id:        uuid.New().String(),
+		userID:    userID,
+		pc:        pc,
+		startedAt: time.Now(),
+	}
+	session.timer = time.AfterFunc(echoTestDuration, func() {
+		s.echoTests.close(session)
+	})
+
+	pc.OnTrack(func(remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		local, exists := echoes[remote.Kind()]
+		if !exists {
+			return
+		}
+		s.echoTrack(session, remote, local)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			s.echoTests.close(session)
+		}
+	})
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		pc.Close()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create answer")})
+		return
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		pc.Close()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create answer")})
+		return
+	}
+	<-gathered
+
+	if previous := s.echoTests.add(session); previous != nil {
+		s.echoTests.close(previous)
+	}
+	sfuLog.Debugf("Started echo test %s for user %s", session.id, userID)
+
+	c.Header("Location", "/echo-test/"+session.id)
+	c.Data(http.StatusCreated, "application/sdp", []byte(pc.LocalDescription().SDP))
+}
+
+// echoTrack sends a received track back to its publisher, measuring it on the way
+func (s *Server) echoTrack(session *echoSession, remote *webrtc.TrackRemote, local *webrtc.TrackLocalStaticRTP) {
+	track := &echoTrack{
+		kind:      remote.Kind().String(),
+		codec:     remote.Codec().MimeType,
+		clockRate: remote.Codec().ClockRate,
+	}
+	session.mu.Lock()
+	session.tracks = append(session.tracks, track)
+	session.mu.Unlock()
+
+	// Ask for keyframes so the echoed video starts quickly and recovers from loss
+	done := make(chan struct{})
+	defer close(done)
+	if remote.Kind() == webrtc.RTPCodecTypeVideo {
+		go func() {
+			ticker := time.NewTicker(keyframeInterval)
+			defer ticker.Stop()
+			for {
+				if err := session.pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}); err != nil {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	for {
+		packet, _, err := remote.ReadRTP()
+		if err != nil {
+			return
+		}
+
+		session.mu.Lock()
+		track.observe(packet.SequenceNumber, packet.Timestamp, len(packet.Payload), time.Now())
+		session.mu.Unlock()
+
+		if err := local.WriteRTP(packet); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			sfuLog.Errorf("Failed to echo track of test %s: %v", session.id, err)
+			return
+		}
+	}
+}
+
+// echoTestStatsHandler reports the quality measured by a running echo test
+func (s *Server) echoTestStatsHandler(c *gin.Context) {
+	session, exists := s.echoTests.get(c.Param("id"), c.MustGet("user_id").(string))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Echo test not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, session.report())
+}
+
+// deleteEchoTestHandler hangs up an echo test and returns its final stats
+func (s *Server) deleteEchoTestHandler(c *gin.Context) {
+	session, exists := s.echoTests.get(c.Param("id"), c.MustGet("user_id").(string))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Echo test not found")})
+		return
+	}
+
+	report := session.report()
+	s.echoTests.close(session)
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/i18n"
+	"github.com/zubans/video-call-server/internal/notify/email"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// newMailer creates the mailer from the SMTP_* and EMAIL_* variables; without
+// SMTP_HOST emails are disabled and nil is returned
+func newMailer() *email.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+
+	mailer, err := email.NewMailer(email.Config{
+		Host:      host,
+		Port:      int(envInt64("SMTP_PORT", 587)),
+		Username:  os.Getenv("SMTP_USERNAME"),
+		Password:  os.Getenv("SMTP_PASSWORD"),
+		From:      envString("SMTP_FROM", "noreply@localhost"),
+		Workers:   int(envInt64("EMAIL_WORKERS", 2)),
+		QueueSize: int(envInt64("EMAIL_QUEUE_SIZE", 1000)),
+		Attempts:  int(envInt64("EMAIL_MAX_ATTEMPTS", 3)),
+	})
+	if err != nil {
+		serverLog.Warnf("Email notifications disabled: %v", err)
+		return nil
+	}
+	return mailer
+}
+
+// publicURL returns an absolute link to a path on this node when NODE_URL is set
+func publicURL(path string) string {
+	return strings.TrimSuffix(os.Getenv("NODE_URL"), "/") + path
+}
+
+// userLanguage returns the language of notifications for a user: the profile locale
+// when supported, otherwise DEFAULT_LANGUAGE
+func (s *Server) userLanguage(user *auth.User) string {
+	if language := i18n.Base(user.Locale); user.Locale != "" && i18n.Supported(language) {
+		return language
+	}
+	return s.settings().DefaultLanguage
+}
+
+// formatMeetingTime formats a time in a timezone for messages in a language
+func formatMeetingTime(language string, t time.Time, timezone string) string {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	return t.In(location).Format(i18n.Translate(language, "Jan 2, 2006 15:04"))
+}
+
+// sendEmail queues a templated email to a user's address in their language. Data is
+// completed with the user's name; fields given as functions of the language are
+// evaluated for it. Nothing is sent when email is not configured.
+func (s *Server) sendEmail(userID, template string, data map[string]interface{}) {
+	if s.mailer == nil {
+		return
+	}
+	user, exists := auth.GetUserByID(userID)
+	if !exists || user.Email == "" {
+		return
+	}
+
+	language := s.userLanguage(user)
+	fields := map[string]interface{}{"Name": user.Name()}
+	for key, value := range data {
+		if localize, ok := value.(func(language string) string); ok {
+			value = localize(language)
+		}
+		fields[key] = value
+	}
+
+	s.mailer.Send(email.Message{
+		To:       user.Email,
+		Template: template,
+		Language: language,
+		Data:     fields,
+	})
+}
+
+// sendVerificationEmail sends a user a link confirming their email address
+func (s *Server) sendVerificationEmail(userID string) error {
+	token, err := auth.CreateEmailVerification(userID)
+	if err != nil {
+		return err
+	}
+
+	s.sendEmail(userID, email.TemplateVerification, map[string]interface{}{
+		"Link":       publicURL("/verify-email?token=" + url.QueryEscape(token)),
+		"ValidHours": int(auth.EmailVerificationTTL.Hours()),
+	})
+	return nil
+}
+
+// sendInvites emails the invitees of a scheduled room; the caller passes a view
+// taken under room.Mu
+func (s *Server) sendInvites(roomID, name, joinCode, organizerID string, schedule *scheduleView) {
+	organizer, _ := profile(organizerID, organizerID)
+
+	for _, userID := range schedule.Invitees {
+		if userID == organizerID {
+			continue
+		}
+		s.sendEmail(userID, email.TemplateInvite, map[string]interface{}{
+			"Organizer": organizer,
+			"Room":      name,
+			"StartsAt": func(language string) string {
+				return formatMeetingTime(language, schedule.StartsAt, schedule.Timezone)
+			},
+			"Timezone":        schedule.Timezone,
+			"DurationMinutes": schedule.DurationMinutes,
+			"JoinCode":        joinCode,
+			"CalendarURL":     publicURL("/rooms/" + url.PathEscape(roomID) + "/ics"),
+		})
+	}
+}
+
+// sendReminderEmails emails a meeting reminder to its recipients
+func (s *Server) sendReminderEmails(due reminder) {
+	for _, userID := range due.recipients {
+		s.sendEmail(userID, email.TemplateReminder, map[string]interface{}{
+			"Room": due.name,
+			"StartsAt": func(language string) string {
+				return formatMeetingTime(language, due.schedule.StartsAt, due.schedule.Timezone)
+			},
+			"Timezone":      due.schedule.Timezone,
+			"MinutesBefore": due.minutesBefore,
+			"JoinCode":      due.joinCode,
+		})
+	}
+}
+
+// sendRecordingReadyEmail tells the owner of a recording that its artifacts can be downloaded
+func (s *Server) sendRecordingReadyEmail(recordingID string, manifest recording.Manifest) {
+	rec, exists := s.recorder.GetRecording(recordingID)
+	if !exists || rec.OwnerID == "" {
+		return
+	}
+
+	s.sendEmail(rec.OwnerID, email.TemplateRecordingReady, map[string]interface{}{
+		"Room":        s.roomName(manifest.RoomID),
+		"RecordingID": recordingID,
+		"Artifacts":   manifest.Artifacts,
+	})
+}
+
+// verifyEmailHandler confirms an email address from the link of a verification email
+func (s *Server) verifyEmailHandler(c *gin.Context) {
+	user, err := auth.VerifyEmail(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+	s.saveUser(user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified",
+		"email":   user.Email,
+	})
+}
+
+// resendVerificationHandler sends the current user a new verification email
+func (s *Server) resendVerificationHandler(c *gin.Context) {
+	user, ok := s.currentUser(c)
+	if !ok {
+		return
+	}
+	if s.mailer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Email is not configured")})
+		return
+	}
+	if user.EmailVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Email is already verified")})
+		return
+	}
+
+	if err := s.sendVerificationEmail(user.ID); err != nil {
+		serverLog.Errorf("Failed to create email verification for %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to send verification email")})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/zubans/video-call-server/internal/encryption"
+)
+
+// Master key backends of recording encryption
+const (
+	encryptionLocal  = "local"
+	encryptionAWSKMS = "aws-kms"
+)
+
+// newRecordingKeyring returns the keyring configured by RECORDING_ENCRYPTION, or nil if
+// recordings are stored unencrypted
+func newRecordingKeyring() *encryption.Keyring {
+	switch kind := strings.ToLower(os.Getenv("RECORDING_ENCRYPTION")); kind {
+	case "", "none":
+		return nil
+	case encryptionLocal:
+		path := os.Getenv("RECORDING_MASTER_KEY_FILE")
+		if path == "" {
+			serverLog.Fatalf("RECORDING_MASTER_KEY_FILE is required when RECORDING_ENCRYPTION=local")
+		}
+		kms, err := encryption.NewLocalKMS(path)
+		if err != nil {
+			serverLog.Fatalf("Failed to load recording master key: %v", err)
+		}
+		serverLog.Infof("Encrypting recordings with the master key in %s", path)
+		return encryption.NewKeyring(kms)
+	case encryptionAWSKMS:
+		keyID := os.Getenv("AWS_KMS_KEY_ID")
+		if keyID == "" {
+			serverLog.Fatalf("AWS_KMS_KEY_ID is required when RECORDING_ENCRYPTION=aws-kms")
+		}
+		kms, err := encryption.NewAWSKMS(context.Background(), keyID)
+		if err != nil {
+			serverLog.Fatalf("Failed to connect to AWS KMS: %v", err)
+		}
+		serverLog.Infof("Encrypting recordings with AWS KMS key %s", keyID)
+		return encryption.NewKeyring(kms)
+	default:
+		serverLog.Fatalf("Unknown RECORDING_ENCRYPTION %q: use local, aws-kms or none", kind)
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envString returns an environment variable or a default value
+func envString(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// envInt64 returns an integer environment variable or a default value
+func envInt64(key string, def int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		serverLog.Warnf("Invalid value for %s: %q, using %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// envBool returns a boolean environment variable or a default value
+func envBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		serverLog.Warnf("Invalid value for %s: %q, using %t", key, value, def)
+		return def
+	}
+	return b
+}
+
+// envList returns a comma-separated environment variable as a list, skipping empty items
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/errreport"
+)
+
+// configureErrorReporting sends captured errors to Sentry (SENTRY_DSN) and/or a generic
+// endpoint (ERROR_REPORT_URL); without either, errors are only logged
+func configureErrorReporting() {
+	var sinks []errreport.Sink
+
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		sink, err := errreport.NewSentrySink(dsn, os.Getenv("SENTRY_ENVIRONMENT"))
+		if err != nil {
+			serverLog.Warnf("Sentry reporting disabled: %v", err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+	if url := os.Getenv("ERROR_REPORT_URL"); url != "" {
+		sinks = append(sinks, errreport.NewHookSink(url))
+	}
+
+	errreport.Configure(sinks...)
+}
+
+// requestContext describes a request for an error report: route, user and room
+func requestContext(c *gin.Context) map[string]string {
+	context := map[string]string{
+		"method": c.Request.Method,
+		"route":  c.FullPath(),
+		"path":   c.Request.URL.Path,
+	}
+	if userID := c.GetString("user_id"); userID != "" {
+		context["user_id"] = userID
+	}
+	if username := c.GetString("username"); username != "" {
+		context["username"] = username
+	}
+
+	roomID := c.Param("id")
+	if roomID == "" {
+		roomID = c.Param("room_id")
+	}
+	if roomID == "" {
+		roomID = c.Query("room_id")
+	}
+	if roomID != "" {
+		context["room_id"] = roomID
+	}
+	return context
+}
+
+// errorReportingMiddleware recovers from handler panics with a 500 response and
+// reports them, along with errors attached to the context and other 500 responses.
+// 503s from admission control and cluster routing are expected and not reported.
+func (s *Server) errorReportingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if value := recover(); value != nil {
+				// The client is gone; nothing to report or answer
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+
+				stack := debug.Stack()
+				serverLog.Errorf("Panic serving %s %s: %v\n%s", c.Request.Method, c.Request.URL.Path, value, stack)
+				errreport.CapturePanic(value, stack, requestContext(c))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Internal server error")})
+			}
+		}()
+
+		c.Next()
+
+		if !errreport.Enabled() {
+			return
+		}
+		for _, err := range c.Errors {
+			errreport.Capture(err.Err, requestContext(c))
+		}
+		if c.Writer.Status() == http.StatusInternalServerError && len(c.Errors) == 0 {
+			errreport.Capture(fmt.Errorf("%s %s responded with 500", c.Request.Method, c.FullPath()), requestContext(c))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Capabilities that can be switched off for all tenants or for some of them
+const (
+	featureRecording     = "recording"
+	featureTranscription = "transcription"
+	featureNotes         = "notes"
+	featureBreakoutRooms = "breakout_rooms"
+	featureFileSharing   = "file_sharing"
+	featureBots          = "bots"
+	featureIngest        = "ingest"
+)
+
+// allFeatures lists the features reported by GET /features, in order
+var allFeatures = []string{
+	featureRecording,
+	featureTranscription,
+	featureNotes,
+	featureBreakoutRooms,
+	featureFileSharing,
+	featureBots,
+	featureIngest,
+}
+
+// featureFlags holds the disabled features of the default tenant and of tenants
+// overriding them; every feature is enabled unless listed
+type featureFlags struct {
+	Disabled []string            `json:"disabled,omitempty"`
+	Tenants  map[string][]string `json:"tenants,omitempty"`
+}
+
+// readFeatureFlags reads FEATURES_DISABLED and, for each tenant of TENANTS,
+// TENANT_<NAME>_FEATURES_ENABLED and TENANT_<NAME>_FEATURES_DISABLED
+func readFeatureFlags() featureFlags {
+	flags := featureFlags{Disabled: readFeatureList("FEATURES_DISABLED")}
+
+	for _, tenant := range envList("TENANTS") {
+		prefix := "TENANT_" + strings.ToUpper(strings.ReplaceAll(tenant, "-", "_")) + "_FEATURES_"
+		enabled := readFeatureList(prefix + "ENABLED")
+		disabled := readFeatureList(prefix + "DISABLED")
+		if len(enabled) == 0 && len(disabled) == 0 {
+			continue
+		}
+
+		var tenantDisabled []string
+		for _, feature := range allFeatures {
+			if slices.Contains(disabled, feature) || (slices.Contains(flags.Disabled, feature) && !slices.Contains(enabled, feature)) {
+				tenantDisabled = append(tenantDisabled, feature)
+			}
+		}
+		if flags.Tenants == nil {
+			flags.Tenants = make(map[string][]string)
+		}
+		flags.Tenants[strings.ToLower(tenant)] = tenantDisabled
+	}
+	return flags
+}
+
+// readFeatureList reads a comma-separated list of features, skipping unknown ones
+func readFeatureList(key string) []string {
+	var features []string
+	for _, feature := range envList(key) {
+		feature = strings.ToLower(feature)
+		if !slices.Contains(allFeatures, feature) {
+			serverLog.Warnf("Unknown feature %q in %s, ignoring it", feature, key)
+			continue
+		}
+		features = append(features, feature)
+	}
+	return features
+}
+
+// enabled reports whether a feature is available to a tenant
+func (f featureFlags) enabled(tenant, feature string) bool {
+	disabled, exists := f.Tenants[tenant]
+	if !exists {
+		disabled = f.Disabled
+	}
+	return !slices.Contains(disabled, feature)
+}
+
+// featureEnabled reports whether a feature is available to a tenant under the current configuration
+func (s *Server) featureEnabled(tenant, feature string) bool {
+	return s.settings().Features.enabled(tenant, feature)
+}
+
+// requireFeature rejects requests of tenants for which a feature is disabled
+func (s *Server) requireFeature(feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.featureEnabled(tenantID(c), feature) {
+			c.JSON(http.StatusForbidden, gin.H{"error": trf(c, "Feature %s is disabled", feature)})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// featuresHandler reports which features are available to the caller's tenant, so
+// clients can hide the controls of disabled ones
+func (s *Server) featuresHandler(c *gin.Context) {
+	flags := s.settings().Features
+	features := make(map[string]bool, len(allFeatures))
+	for _, feature := range allFeatures {
+		features[feature] = flags.enabled(tenantID(c), feature)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tenant_id": tenantID(c),
+		"features":  features,
+	})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/files"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// defaultMaxUploadSize is the default per-file upload limit (25 MiB)
+const defaultMaxUploadSize = 25 << 20
+
+// newFileManager creates the room file store from environment configuration
+func newFileManager() *files.Manager {
+	var scanner files.Scanner
+	if command := envString("FILE_SCAN_COMMAND", ""); command != "" {
+		scanner = &files.CommandScanner{Command: command}
+	}
+
+	return files.NewManager(
+		envString("UPLOADS_DIR", "./uploads"),
+		envInt64("MAX_UPLOAD_SIZE", defaultMaxUploadSize),
+		scanner,
+	)
+}
+
+// isRoomMember reports whether a user created the room or currently participates in it
+func isRoomMember(room *models.Room, userID string) bool {
+	room.Mu.RLock()
+	defer room.Mu.RUnlock()
+
+	if room.CreatorID == userID {
+		return true
+	}
+	for _, client := range room.Clients {
+		if client.UserID == userID && !client.IsBot {
+			return true
+		}
+	}
+	return false
+}
+
+// memberRoom looks up the room from the :id path parameter and checks that the user is a member
+func (s *Server) memberRoom(c *gin.Context) (*models.Room, bool) {
+	userID := c.MustGet("user_id").(string)
+
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return nil, false
+	}
+
+	if !isRoomMember(room, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Not a member of this room")})
+		return nil, false
+	}
+
+	return room, true
+}
+
+// uploadFileHandler shares a file with the current room session
+func (s *Server) uploadFileHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	username := c.MustGet("username").(string)
+
+	room, ok := s.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	// Leave headroom for multipart framing on top of the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.files.MaxSize()+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, files.ErrFileTooLarge.Error())})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "File is required")})
+		return
+	}
+
+	if header.Size > s.files.MaxSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, files.ErrFileTooLarge.Error())})
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Failed to read file")})
+		return
+	}
+	defer src.Close()
+
+	file, err := s.files.Save(room.ID, userID, username, header.Filename, header.Header.Get("Content-Type"), src)
+	switch {
+	case errors.Is(err, files.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, err.Error())})
+		return
+	case errors.Is(err, files.ErrFileRejected):
+		serverLog.Warnf("Upload %q to room %s rejected: %v", header.Filename, room.ID, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, files.ErrFileRejected.Error())})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to store file")})
+		return
+	}
+
+	// Let participants know a file is available
+	s.broadcastSignal(room, "", models.SignalMessage{
+		Type:      "file-shared",
+		Data:      file,
+		Timestamp: time.Now(),
+		SenderID:  userID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File uploaded successfully",
+		"file":    file,
+	})
+}
+
+// listFilesHandler lists files shared in the current room session
+func (s *Server) listFilesHandler(c *gin.Context) {
+	room, ok := s.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":           s.files.List(room.ID),
+		"max_upload_size": s.files.MaxSize(),
+	})
+}
+
+// downloadFileHandler serves a shared file to room members
+func (s *Server) downloadFileHandler(c *gin.Context) {
+	room, ok := s.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	file, exists := s.files.Get(room.ID, c.Param("file_id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "File not found")})
+		return
+	}
+
+	c.FileAttachment(file.Path, file.Name)
+}
+
+// deleteFileHandler removes a shared file; allowed for the uploader and the room creator
+func (s *Server) deleteFileHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	room, ok := s.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	file, exists := s.files.Get(room.ID, c.Param("file_id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "File not found")})
+		return
+	}
+
+	if file.UploaderID != userID && room.CreatorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the uploader or room creator can delete this file")})
+		return
+	}
+
+	if err := s.files.Delete(file.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to delete file")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File deleted successfully",
+	})
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// maxRoomTokenTTL caps the lifetime of room tokens; tokens minted without ttl_seconds
+// last GUEST_TOKEN_TTL_SECONDS
+const maxRoomTokenTTL = 24 * time.Hour
+
+// roomTokenRoutes are the routes a room-scoped token may call
+var roomTokenRoutes = map[string]bool{
+	"/logout":                             true,
+	"/join-room":                          true,
+	"/join-by-code":                       true,
+	"/leave-room":                         true,
+	"/ws":                                 true,
+	"/features":                           true,
+	"/wt":                                 true,
+	"/chat/send":                          true,
+	"/chat/history/:room_id":              true,
+	"/chat/messages/:room_id/deleted":     true,
+	"/chat/messages/:room_id/:message_id": true,
+	"/chat/messages/:room_id/:message_id/restore": true,
+	"/rooms/:id/files":           true,
+	"/rooms/:id/files/:file_id":  true,
+	"/rooms/:id/participants":    true,
+	"/rooms/:id/hold/:client_id": true,
+	"/recording/list/:room_id":   true,
+	"/recording/start":           true,
+	"/recording/stop":            true,
+}
+
+// hostRoutes additionally require the is_host grant
+var hostRoutes = map[string]bool{
+	"/recording/start":           true,
+	"/recording/stop":            true,
+	"/rooms/:id/hold/:client_id": true,
+}
+
+// roomGrant returns the grant of a room-scoped token, or nil for regular tokens
+func roomGrant(c *gin.Context) *auth.RoomGrant {
+	if grant, ok := c.Get("room_grant"); ok {
+		return grant.(*auth.RoomGrant)
+	}
+	return nil
+}
+
+// checkRoomTokenRoute restricts room-scoped tokens to the routes and the room they were minted for
+func checkRoomTokenRoute(c *gin.Context, grant *auth.RoomGrant) bool {
+	route := c.FullPath()
+	if !roomTokenRoutes[route] || (hostRoutes[route] && !grant.IsHost) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Room token does not allow this action")})
+		return false
+	}
+
+	for _, param := range []string{"id", "room_id"} {
+		if roomID := c.Param(param); roomID != "" && roomID != grant.RoomID {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Token is not valid for this room")})
+			return false
+		}
+	}
+	return true
+}
+
+// allowRoom checks that a room-scoped token, if any, was minted for a room given in the request body
+func allowRoom(c *gin.Context, roomID string) bool {
+	if grant := roomGrant(c); grant != nil && grant.RoomID != roomID {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Token is not valid for this room")})
+		return false
+	}
+	return true
+}
+
+// grantPermissions converts a token grant to participant permissions; nil means unrestricted
+func grantPermissions(grant *auth.RoomGrant) *models.Permissions {
+	if grant == nil {
+		return nil
+	}
+	return &models.Permissions{
+		CanPublish:   grant.CanPublish,
+		CanSubscribe: grant.CanSubscribe,
+		CanChat:      grant.CanChat,
+		IsHost:       grant.IsHost,
+	}
+}
+
+// canPublish reports whether the SFU should forward a participant's tracks
+func canPublish(client *models.Client) bool {
+	return client.Permissions == nil || client.Permissions.CanPublish
+}
+
+// canSubscribe reports whether the SFU should send a participant other tracks
+func canSubscribe(client *models.Client) bool {
+	return client.Permissions == nil || client.Permissions.CanSubscribe
+}
+
+// createRoomTokenHandler mints a room-scoped token pre-authorizing one participant
+func (s *Server) createRoomTokenHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	// API keys are scoped by their middleware; users must own the room or be admins
+	if _, viaKey := c.Get("api_key"); !viaKey && room.CreatorID != c.GetString("user_id") && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can mint room tokens")})
+		return
+	}
+
+	var req struct {
+		UserID       string `json:"user_id"`
+		Username     string `json:"username" binding:"required"`
+		CanPublish   *bool  `json:"can_publish"`
+		CanSubscribe *bool  `json:"can_subscribe"`
+		CanChat      *bool  `json:"can_chat"`
+		IsHost       bool   `json:"is_host"`
+		TTLSeconds   int64  `json:"ttl_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ttl := auth.Policy(auth.TokenGuest).TTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > maxRoomTokenTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "ttl_seconds must not exceed 86400")})
+		return
+	}
+
+	// Participants without an account get a guest identity
+	if req.UserID == "" {
+		req.UserID = "guest_" + uuid.New().String()
+	}
+
+	grant := auth.RoomGrant{
+		RoomID:       room.ID,
+		CanPublish:   req.CanPublish == nil || *req.CanPublish,
+		CanSubscribe: req.CanSubscribe == nil || *req.CanSubscribe,
+		CanChat:      req.CanChat == nil || *req.CanChat,
+		IsHost:       req.IsHost,
+	}
+
+	token, err := auth.GenerateRoomJWT(req.UserID, req.Username, room.TenantID, grant, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to generate token")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Room token created",
+		"token":      token,
+		"user_id":    req.UserID,
+		"grant":      grant,
+		"expires_at": time.Now().Add(ttl),
+	})
+}
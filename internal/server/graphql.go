@@ -0,0 +1,450 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+)
+
+// Chat messages returned per room by the GraphQL API
+const (
+	defaultGraphQLChat = 50
+	maxGraphQLChat     = 100
+)
+
+// recordingView is the GraphQL representation of a recording
+type recordingView struct {
+	ID        string     `json:"id"`
+	Mode      string     `json:"mode"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Active    bool       `json:"active"`
+	Processed bool       `json:"processed"`
+	Storage   string     `json:"storage"`
+}
+
+// batchLoader collects the keys resolvers ask for during a GraphQL request and fetches
+// them together, dataloader style. Resolvers queue a key and return a thunk; the
+// executor resolves a whole level of the query, such as the chat of every listed
+// room, before evaluating thunks, so the first thunk fetches the level in one batch.
+// Requests are executed on one goroutine, so loaders need no locking.
+type batchLoader[T any] struct {
+	fetch   func(keys []string) map[string]T
+	queued  map[string]bool
+	pending []string
+	results map[string]T
+}
+
+// newBatchLoader creates a loader fetching keys with fetch
+func newBatchLoader[T any](fetch func(keys []string) map[string]T) *batchLoader[T] {
+	return &batchLoader[T]{
+		fetch:   fetch,
+		queued:  make(map[string]bool),
+		results: make(map[string]T),
+	}
+}
+
+// load queues a key and returns a function yielding its value, fetching every queued
+// key on first use
+func (l *batchLoader[T]) load(key string) func() T {
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	return func() T {
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			for k, v := range l.fetch(keys) {
+				l.results[k] = v
+			}
+		}
+		return l.results[key]
+	}
+}
+
+// graphqlState is shared by the resolvers of one request: the caller and the loaders
+type graphqlState struct {
+	c        *gin.Context
+	userID   string
+	tenantID string
+	isAdmin  bool
+
+	participants *batchLoader[[]participantInfo]
+	chat         *batchLoader[[]*chat.Message]
+	recordings   *batchLoader[[]recordingView]
+	users        *batchLoader[*auth.User]
+}
+
+// graphqlStateKey is the context key of the request's graphqlState
+type graphqlStateKey struct{}
+
+// newGraphQLState creates the loaders of a request over the server's stores
+func (s *Server) newGraphQLState(c *gin.Context) *graphqlState {
+	return &graphqlState{
+		c:        c,
+		userID:   c.GetString("user_id"),
+		tenantID: tenantID(c),
+		isAdmin:  c.GetString("role") == auth.RoleAdmin,
+
+		participants: newBatchLoader(func(roomIDs []string) map[string][]participantInfo {
+			result := make(map[string][]participantInfo, len(roomIDs))
+			for _, roomID := range roomIDs {
+				if room, exists := s.getRoom(roomID); exists {
+					result[roomID] = s.roster(room)
+				}
+			}
+			return result
+		}),
+		chat: newBatchLoader(func(roomIDs []string) map[string][]*chat.Message {
+			return s.chatManager.GetRecentMessagesForRooms(roomIDs, maxGraphQLChat)
+		}),
+		recordings: newBatchLoader(func(roomIDs []string) map[string][]recordingView {
+			result := make(map[string][]recordingView, len(roomIDs))
+			for roomID, recordings := range s.recorder.ListRecordingsForRooms(tenantID(c), roomIDs) {
+				views := make([]recordingView, 0, len(recordings))
+				for _, rec := range recordings {
+					view := recordingView{
+						ID:        rec.ID,
+						Mode:      rec.Mode,
+						StartedAt: rec.StartedAt,
+						Active:    rec.Active,
+						Processed: rec.Manifest != nil,
+						Storage:   rec.Storage,
+					}
+					if !rec.EndedAt.IsZero() {
+						endedAt := rec.EndedAt
+						view.EndedAt = &endedAt
+					}
+					views = append(views, view)
+				}
+				result[roomID] = views
+			}
+			return result
+		}),
+		users: newBatchLoader(func(userIDs []string) map[string]*auth.User {
+			result := make(map[string]*auth.User, len(userIDs))
+			for _, userID := range userIDs {
+				if user, exists := auth.GetUserByID(userID); exists {
+					result[userID] = user
+				}
+			}
+			return result
+		}),
+	}
+}
+
+// stateOf returns the request state of a resolver
+func stateOf(p graphql.ResolveParams) *graphqlState {
+	return p.Context.Value(graphqlStateKey{}).(*graphqlState)
+}
+
+// loadUser resolves a user through the request's user loader; unknown users are null
+func loadUser(p graphql.ResolveParams, userID string) (interface{}, error) {
+	user := stateOf(p).users.load(userID)
+	return func() (interface{}, error) {
+		if found := user(); found != nil {
+			return userSummary(found), nil
+		}
+		return nil, nil
+	}, nil
+}
+
+// checkRoomAccess allows a room's participants, chat and recordings to its members and
+// the admins of its tenant
+func (s *Server) checkRoomAccess(state *graphqlState, roomID string) error {
+	room, exists := s.getRoom(roomID)
+	if exists && room.TenantID == state.tenantID && state.isAdmin {
+		return nil
+	}
+	if !exists || room.TenantID != state.tenantID || !isRoomMember(room, state.userID) {
+		return errors.New(tr(state.c, "Not a member of this room"))
+	}
+	return nil
+}
+
+// newGraphQLSchema builds the read-only GraphQL schema over rooms, participants, chat,
+// recordings and users. Field names follow the JSON of the REST API.
+func (s *Server) newGraphQLSchema() graphql.Schema {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"username":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"display_name": &graphql.Field{Type: graphql.String},
+			"avatar_url":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	trackType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Track",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"kind":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"subscribers": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Connection",
+		Fields: graphql.Fields{
+			"state":               &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"quality":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"rtt_ms":              &graphql.Field{Type: graphql.Float},
+			"packet_loss_percent": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	participantType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Participant",
+		Fields: graphql.Fields{
+			"client_id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"user_id":             &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"username":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"display_name":        &graphql.Field{Type: graphql.String},
+			"avatar_url":          &graphql.Field{Type: graphql.String},
+			"is_bot":              &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"joined_at":           &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"audio_muted":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"video_muted":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"signaling_connected": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"tracks":              &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(trackType)))},
+			"connection":          &graphql.Field{Type: graphql.NewNonNull(connectionType)},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "The participant's account; null for guests",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadUser(p, p.Source.(participantInfo).UserID)
+				},
+			},
+		},
+	})
+
+	chatMessageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ChatMessage",
+		Fields: graphql.Fields{
+			"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"type":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"event":        &graphql.Field{Type: graphql.String},
+			"user_id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"username":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"display_name": &graphql.Field{Type: graphql.String},
+			"avatar_url":   &graphql.Field{Type: graphql.String},
+			"content":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"timestamp":    &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	recordingType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Recording",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"mode":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"started_at": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"ended_at":   &graphql.Field{Type: graphql.DateTime},
+			"active":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"processed":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"storage":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	roomType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Room",
+		Fields: graphql.Fields{
+			"id":                &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":              &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"creator_id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"participant_count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"created_at":        &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"is_active":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"is_public":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"join_code":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"creator": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadUser(p, p.Source.(roomSummaryView).CreatorID)
+				},
+			},
+			"participants": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(participantType)),
+				Description: "Participants with live media details; members and admins only",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					state, roomID := stateOf(p), p.Source.(roomSummaryView).ID
+					if err := s.checkRoomAccess(state, roomID); err != nil {
+						return nil, err
+					}
+					participants := state.participants.load(roomID)
+					return func() (interface{}, error) {
+						if found := participants(); found != nil {
+							return found, nil
+						}
+						return []participantInfo{}, nil
+					}, nil
+				},
+			},
+			"chat": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(chatMessageType)),
+				Description: "The most recent chat messages, oldest first; members and admins only",
+				Args: graphql.FieldConfigArgument{
+					"last": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultGraphQLChat},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					state, roomID := stateOf(p), p.Source.(roomSummaryView).ID
+					last, _ := p.Args["last"].(int)
+					if last < 1 || last > maxGraphQLChat {
+						return nil, errors.New(trf(state.c, "last must be between 1 and %d", maxGraphQLChat))
+					}
+					if err := s.checkRoomAccess(state, roomID); err != nil {
+						return nil, err
+					}
+					messages := state.chat.load(roomID)
+					return func() (interface{}, error) {
+						found := messages()
+						if len(found) > last {
+							found = found[len(found)-last:]
+						}
+						return found, nil
+					}, nil
+				},
+			},
+			"recordings": &graphql.Field{
+				Type:        graphql.NewList(graphql.NewNonNull(recordingType)),
+				Description: "Recordings of the room; members and admins only",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					state, roomID := stateOf(p), p.Source.(roomSummaryView).ID
+					if err := s.checkRoomAccess(state, roomID); err != nil {
+						return nil, err
+					}
+					recordings := state.recordings.load(roomID)
+					return func() (interface{}, error) {
+						return recordings(), nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadUser(p, stateOf(p).userID)
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadUser(p, p.Args["id"].(string))
+				},
+			},
+			"room": &graphql.Field{
+				Type:        roomType,
+				Description: "A room; archived rooms are visible to their creator and admins only",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					state := stateOf(p)
+					room, exists := s.getRoom(p.Args["id"].(string))
+					if !exists || room.TenantID != state.tenantID {
+						return nil, nil
+					}
+
+					room.Mu.RLock()
+					summary := roomSummary(room)
+					room.Mu.RUnlock()
+
+					if !summary.IsActive && summary.CreatorID != state.userID && !state.isAdmin {
+						return nil, nil
+					}
+					return summary, nil
+				},
+			},
+			"rooms": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(roomType))),
+				Description: "Rooms with the filters, order and page of GET /rooms",
+				Args: graphql.FieldConfigArgument{
+					"q":                &graphql.ArgumentConfig{Type: graphql.String},
+					"creator_id":       &graphql.ArgumentConfig{Type: graphql.ID},
+					"status":           &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: roomStatusActive},
+					"min_participants": &graphql.ArgumentConfig{Type: graphql.Int},
+					"sort":             &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "-created_at"},
+					"limit":            &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultRoomsLimit},
+					"offset":           &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					state := stateOf(p)
+					q := roomQuery{
+						Tenant: state.tenantID,
+						Status: p.Args["status"].(string),
+						Sort:   p.Args["sort"].(string),
+						Limit:  p.Args["limit"].(int),
+					}
+					if name, ok := p.Args["q"].(string); ok {
+						q.Name = strings.ToLower(strings.TrimSpace(name))
+					}
+					q.CreatorID, _ = p.Args["creator_id"].(string)
+					q.MinParticipants, _ = p.Args["min_participants"].(int)
+					q.Offset, _ = p.Args["offset"].(int)
+
+					if err := q.validate(); err != nil {
+						return nil, errors.New(tr(state.c, err.Error()))
+					}
+					if q.Limit < 1 || q.Limit > maxRoomsLimit || q.Offset < 0 || q.MinParticipants < 0 {
+						return nil, errors.New(trf(state.c, "limit must be between 1 and %d; offset and min_participants must not be negative", maxRoomsLimit))
+					}
+
+					rooms, _ := s.listRooms(q, state.userID, state.isAdmin)
+					return rooms, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		panic(fmt.Sprintf("Invalid GraphQL schema: %v", err))
+	}
+	return schema
+}
+
+// graphqlHandler executes a read-only GraphQL query, letting dashboards fetch rooms
+// with their participants, chat and recordings in one request. Errors of individual
+// fields are reported in "errors" next to the data that could be resolved.
+func (s *Server) graphqlHandler(c *gin.Context) {
+	var req struct {
+		Query         string                 `json:"query" binding:"required"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+		Extensions    map[string]interface{} `json:"extensions"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(c.Request.Context(), graphqlStateKey{}, s.newGraphQLState(c)),
+	})
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/media"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// Why a participant is on hold
+const (
+	// Waiting in the lobby for a host; admitted automatically when one joins
+	holdLobby = "lobby"
+	// Put on hold by a host until they resume the participant
+	holdHost = "host"
+)
+
+// holdMusic is the looped HOLD_MUSIC_FILE played to the participants of a room on hold
+type holdMusic struct {
+	player  *media.FilePlayer
+	senders map[string]*webrtc.RTPSender // by client ID
+}
+
+// holdManager keeps the hold music of each room with participants on hold
+type holdManager struct {
+	rooms map[string]*holdMusic
+	mu    sync.Mutex
+}
+
+// newHoldManager creates an empty holdManager
+func newHoldManager() *holdManager {
+	return &holdManager{
+		rooms: make(map[string]*holdMusic),
+	}
+}
+
+// holdState is the payload of "hold" messages telling a participant it was put on hold or resumed
+type holdState struct {
+	RoomID string `json:"room_id"`
+	OnHold bool   `json:"on_hold"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// onHold reports whether a participant is on hold; the caller holds room.Mu
+func onHold(client *models.Client) bool {
+	return client.Hold != ""
+}
+
+// playHoldMusic adds the room's hold music to a participant's peer connection, starting
+// the music when it is the first listener. Without HOLD_MUSIC_FILE the participant
+// hears silence.
+func (s *Server) playHoldMusic(room *models.Room, client *models.Client) {
+	file := s.settings().HoldMusicFile
+	if file == "" || client.Conn == nil {
+		return
+	}
+
+	s.holds.mu.Lock()
+	music, exists := s.holds.rooms[room.ID]
+	if !exists {
+		path, err := resolveMediaFile(file)
+		if err != nil {
+			s.holds.mu.Unlock()
+			sfuLog.Errorf("Hold music unavailable: %v", err)
+			return
+		}
+		player, err := media.NewFilePlayer(path, "hold_"+room.ID, true)
+		if err != nil {
+			s.holds.mu.Unlock()
+			sfuLog.Errorf("Failed to open hold music %s: %v", file, err)
+			return
+		}
+		if err := player.Start(); err != nil {
+			s.holds.mu.Unlock()
+			sfuLog.Errorf("Failed to start hold music in room %s: %v", room.ID, err)
+			return
+		}
+		music = &holdMusic{player: player, senders: make(map[string]*webrtc.RTPSender)}
+		s.holds.rooms[room.ID] = music
+	}
+
+	sender, err := client.Conn.AddTrack(music.player.Track())
+	if err != nil {
+		s.holds.mu.Unlock()
+		sfuLog.Errorf("Failed to add hold music to client %s: %v", client.ID, err)
+		return
+	}
+	music.senders[client.ID] = sender
+	s.holds.mu.Unlock()
+
+	// Read incoming RTCP so interceptors keep working
+	go func() {
+		for {
+			if _, _, err := sender.ReadRTCP(); err != nil {
+				return
+			}
+		}
+	}()
+
+	s.renegotiate(client)
+}
+
+// stopHoldMusic removes the hold music from a participant's peer connection and stops
+// it once nobody in the room listens to it
+func (s *Server) stopHoldMusic(room *models.Room, client *models.Client) {
+	s.holds.mu.Lock()
+	music, exists := s.holds.rooms[room.ID]
+	if !exists {
+		s.holds.mu.Unlock()
+		return
+	}
+	sender, listening := music.senders[client.ID]
+	delete(music.senders, client.ID)
+	last := len(music.senders) == 0
+	if last {
+		delete(s.holds.rooms, room.ID)
+	}
+	s.holds.mu.Unlock()
+
+	if listening && client.Conn != nil && client.Conn.ConnectionState() != webrtc.PeerConnectionStateClosed {
+		if err := client.Conn.RemoveTrack(sender); err != nil {
+			sfuLog.Errorf("Failed to remove hold music from client %s: %v", client.ID, err)
+		} else {
+			s.renegotiate(client)
+		}
+	}
+	if last {
+		if err := music.player.Close(); err != nil {
+			sfuLog.Errorf("Failed to stop hold music in room %s: %v", room.ID, err)
+		}
+	}
+}
+
+// holdClient puts a participant on hold: it stops receiving the room's media, the room
+// stops receiving its media, and it hears the hold music instead. It reports whether
+// the participant was in the room and not already on hold.
+func (s *Server) holdClient(room *models.Room, client *models.Client, reason string) bool {
+	held := false
+	s.roomRun(room, func(loop *roomLoop) {
+		type detached struct {
+			client *models.Client
+			sender *webrtc.RTPSender
+		}
+
+		var senders []detached
+		loop.update(room, func() {
+			if room.Clients[client.ID] != client || onHold(client) || client.Conn == nil {
+				return
+			}
+			held = true
+			client.Hold = reason
+			for _, published := range room.Tracks {
+				if published.ClientID == client.ID {
+					for subscriberID, sender := range published.Senders {
+						if subscriber, exists := room.Clients[subscriberID]; exists {
+							senders = append(senders, detached{subscriber, sender})
+						}
+					}
+					published.Senders = make(map[string]*webrtc.RTPSender)
+				} else if sender, exists := published.Senders[client.ID]; exists {
+					senders = append(senders, detached{client, sender})
+					delete(published.Senders, client.ID)
+				}
+			}
+		})
+		if !held {
+			return
+		}
+
+		renegotiate := make(map[*models.Client]bool)
+		for _, d := range senders {
+			if err := d.client.Conn.RemoveTrack(d.sender); err != nil {
+				sfuLog.Errorf("Failed to detach track from client %s: %v", d.client.ID, err)
+				continue
+			}
+			renegotiate[d.client] = true
+		}
+		delete(renegotiate, client)
+		for other := range renegotiate {
+			s.renegotiate(other)
+		}
+
+		// Adding the music renegotiates the held participant
+		s.playHoldMusic(room, client)
+	})
+
+	if held {
+		s.hub.SendToSender(client.ID, "hold", holdState{RoomID: room.ID, OnHold: true, Reason: reason})
+	}
+	return held
+}
+
+// resumeClient takes a participant off hold, reconnecting its media with the room.
+// It reports whether the participant was on hold.
+func (s *Server) resumeClient(room *models.Room, client *models.Client) bool {
+	resumed := false
+	s.roomRun(room, func(loop *roomLoop) {
+		var own []*models.PublishedTrack
+		var subscribers []*models.Client
+		loop.update(room, func() {
+			if room.Clients[client.ID] != client || !onHold(client) {
+				return
+			}
+			resumed = true
+			client.Hold = ""
+			for _, published := range room.Tracks {
+				if published.ClientID == client.ID {
+					own = append(own, published)
+				}
+			}
+			for clientID, other := range room.Clients {
+				if clientID != client.ID && other.Conn != nil && !onHold(other) && canSubscribe(other) {
+					subscribers = append(subscribers, other)
+				}
+			}
+		})
+		if !resumed {
+			return
+		}
+
+		s.stopHoldMusic(room, client)
+		s.subscribeToRoomTracks(loop, room, client)
+		for _, published := range own {
+			for _, subscriber := range subscribers {
+				s.attachTrack(loop, room, published, subscriber)
+			}
+		}
+	})
+
+	if resumed {
+		s.hub.SendToSender(client.ID, "hold", holdState{RoomID: room.ID, OnHold: false})
+	}
+	return resumed
+}
+
+// admitLobby resumes the participants waiting in a room's lobby when a host joins
+func (s *Server) admitLobby(room *models.Room) {
+	room.Mu.RLock()
+	var waiting []*models.Client
+	for _, client := range room.Clients {
+		if client.Hold == holdLobby {
+			waiting = append(waiting, client)
+		}
+	}
+	room.Mu.RUnlock()
+
+	for _, client := range waiting {
+		s.resumeClient(room, client)
+	}
+}
+
+// heldClient looks up the room from the :id path parameter and the participant from
+// :client_id, checking that the caller hosts the room
+func (s *Server) heldClient(c *gin.Context) (*models.Room, *models.Client, bool) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return nil, nil, false
+	}
+	if !isRoomHost(c, room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return nil, nil, false
+	}
+
+	room.Mu.RLock()
+	client, exists := room.Clients[c.Param("client_id")]
+	room.Mu.RUnlock()
+	if !exists || client.IsBot {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Client not found")})
+		return nil, nil, false
+	}
+	return room, client, true
+}
+
+// holdParticipantHandler puts a participant on hold
+func (s *Server) holdParticipantHandler(c *gin.Context) {
+	room, client, ok := s.heldClient(c)
+	if !ok {
+		return
+	}
+
+	if !s.holdClient(room, client, holdHost) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Participant is already on hold")})
+		return
+	}
+
+	s.recordAudit(c, "participant.hold", room.ID, map[string]string{"client_id": client.ID, "user_id": client.UserID})
+	c.JSON(http.StatusOK, gin.H{"message": "Participant put on hold"})
+}
+
+// resumeParticipantHandler takes a participant off hold, also admitting it from the lobby
+func (s *Server) resumeParticipantHandler(c *gin.Context) {
+	room, client, ok := s.heldClient(c)
+	if !ok {
+		return
+	}
+
+	if !s.resumeClient(room, client) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Participant is not on hold")})
+		return
+	}
+
+	s.recordAudit(c, "participant.resume", room.ID, map[string]string{"client_id": client.ID, "user_id": client.UserID})
+	c.JSON(http.StatusOK, gin.H{"message": "Participant resumed"})
+}
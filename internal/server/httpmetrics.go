@@ -0,0 +1,29 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that matched no route, so arbitrary paths do not create series
+const unmatchedRoute = "unmatched"
+
+// httpMetricsMiddleware records request count, latency and in-flight requests by route
+// pattern and status code
+func (s *Server) httpMetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		s.metrics.AddHTTPRequestsInFlight(1)
+		defer s.metrics.AddHTTPRequestsInFlight(-1)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		s.metrics.ObserveHTTPRequest(c.Request.Method, route, strconv.Itoa(c.Writer.Status()), time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/i18n"
+)
+
+// readDefaultLanguage reads DEFAULT_LANGUAGE, the language of messages for clients
+// that state no preference
+func readDefaultLanguage() string {
+	language := i18n.Base(envString("DEFAULT_LANGUAGE", i18n.DefaultLanguage))
+	if !i18n.Supported(language) {
+		serverLog.Warnf("Unsupported DEFAULT_LANGUAGE %q, using %s", language, i18n.DefaultLanguage)
+		return i18n.DefaultLanguage
+	}
+	return language
+}
+
+// languageMiddleware negotiates the language of messages from Accept-Language
+func (s *Server) languageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("language", i18n.Negotiate(c.GetHeader("Accept-Language"), s.settings().DefaultLanguage))
+		c.Next()
+	}
+}
+
+// language returns the language of messages for a request: the one negotiated from
+// Accept-Language or, without that header, the locale in the caller's profile
+func language(c *gin.Context) string {
+	if c.GetHeader("Accept-Language") == "" {
+		if user, ok := auth.GetUserByID(c.GetString("user_id")); ok && i18n.Supported(i18n.Base(user.Locale)) && user.Locale != "" {
+			return i18n.Base(user.Locale)
+		}
+	}
+	if language := c.GetString("language"); language != "" {
+		return language
+	}
+	return i18n.DefaultLanguage
+}
+
+// tr translates a user-facing message into the request's language
+func tr(c *gin.Context, message string) string {
+	language := language(c)
+	c.Header("Content-Language", language)
+	return i18n.Translate(language, message)
+}
+
+// trf translates a format string into the request's language, then formats it
+func trf(c *gin.Context, format string, args ...interface{}) string {
+	language := language(c)
+	c.Header("Content-Language", language)
+	return i18n.Translatef(language, format, args...)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/webrtc/v3"
+)
+
+// readICESettings configures the ICE transport of server-side peer connections from
+// the ICE_* variables, so the SFU can run behind NAT, in containers and behind
+// strict firewalls:
+//
+//   - ICE_NAT_1TO1_IPS: public IPs announced instead of local addresses, as host
+//     candidates or, with ICE_NAT_1TO1_CANDIDATE_TYPE=srflx, as server reflexive ones
+//   - ICE_PORT_MIN and ICE_PORT_MAX: the range of UDP ports peer connections bind
+//   - ICE_UDP_MUX_PORT: a single UDP port shared by all peer connections
+//   - ICE_TCP_MUX_PORT: a TCP port for ICE-TCP candidates
+//   - ICE_NETWORK_TYPES: candidate network types (udp4, udp6, tcp4, tcp6); by
+//     default UDP over IPv4 and IPv6, and TCP as well when ICE_TCP_MUX_PORT is set
+//   - ICE_INTERFACES: network interfaces candidates are gathered on
+//
+// Invalid values are logged and ignored.
+func readICESettings() webrtc.SettingEngine {
+	var settings webrtc.SettingEngine
+
+	if types := envList("ICE_NETWORK_TYPES"); len(types) > 0 {
+		var networkTypes []webrtc.NetworkType
+		for _, name := range types {
+			networkType, err := webrtc.NewNetworkType(name)
+			if err != nil {
+				serverLog.Warnf("Invalid ICE network type %q: %v", name, err)
+				continue
+			}
+			networkTypes = append(networkTypes, networkType)
+		}
+		if len(networkTypes) > 0 {
+			settings.SetNetworkTypes(networkTypes)
+		}
+	}
+
+	var muxOptions []ice.UDPMuxFromPortOption
+	if interfaces := envList("ICE_INTERFACES"); len(interfaces) > 0 {
+		filter := func(name string) bool {
+			for _, allowed := range interfaces {
+				if name == allowed {
+					return true
+				}
+			}
+			return false
+		}
+		settings.SetInterfaceFilter(filter)
+		muxOptions = append(muxOptions, ice.UDPMuxFromPortWithInterfaceFilter(filter))
+	}
+
+	if ips := envList("ICE_NAT_1TO1_IPS"); len(ips) > 0 {
+		candidateType := webrtc.ICECandidateTypeHost
+		switch value := strings.ToLower(os.Getenv("ICE_NAT_1TO1_CANDIDATE_TYPE")); value {
+		case "", "host":
+		case "srflx":
+			candidateType = webrtc.ICECandidateTypeSrflx
+		default:
+			serverLog.Warnf("Invalid value for ICE_NAT_1TO1_CANDIDATE_TYPE: %q, using host", value)
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) == nil {
+				serverLog.Warnf("Invalid IP in ICE_NAT_1TO1_IPS: %q", ip)
+			}
+		}
+		settings.SetNAT1To1IPs(ips, candidateType)
+	}
+
+	if port := envInt64("ICE_UDP_MUX_PORT", 0); port > 0 {
+		// Listen on every interface address, as candidates are gathered per interface
+		mux, err := ice.NewMultiUDPMuxFromPort(int(port), muxOptions...)
+		if err != nil {
+			serverLog.Errorf("Failed to listen on ICE UDP port %d: %v", port, err)
+		} else {
+			settings.SetICEUDPMux(mux)
+			serverLog.Infof("ICE over UDP multiplexed on port %d", port)
+		}
+		if os.Getenv("ICE_PORT_MIN") != "" || os.Getenv("ICE_PORT_MAX") != "" {
+			serverLog.Warnf("ICE_PORT_MIN and ICE_PORT_MAX are ignored when ICE_UDP_MUX_PORT is set")
+		}
+	} else if minPort, maxPort := envInt64("ICE_PORT_MIN", 0), envInt64("ICE_PORT_MAX", 0); minPort > 0 || maxPort > 0 {
+		if minPort < 1 || maxPort > 65535 || minPort > maxPort {
+			serverLog.Warnf("Invalid ICE port range %d-%d, using ephemeral ports", minPort, maxPort)
+		} else if err := settings.SetEphemeralUDPPortRange(uint16(minPort), uint16(maxPort)); err == nil {
+			serverLog.Infof("ICE over UDP on ports %d-%d", minPort, maxPort)
+		}
+	}
+
+	if port := envInt64("ICE_TCP_MUX_PORT", 0); port > 0 {
+		listener, err := net.ListenTCP("tcp", &net.TCPAddr{Port: int(port)})
+		if err != nil {
+			serverLog.Errorf("Failed to listen on ICE TCP port %d: %v", port, err)
+		} else {
+			settings.SetICETCPMux(webrtc.NewICETCPMux(nil, listener, 8))
+			serverLog.Infof("ICE-TCP candidates on port %d", port)
+
+			// Pion gathers UDP candidates only unless TCP is asked for
+			if len(envList("ICE_NETWORK_TYPES")) == 0 {
+				settings.SetNetworkTypes([]webrtc.NetworkType{
+					webrtc.NetworkTypeUDP4, webrtc.NetworkTypeUDP6,
+					webrtc.NetworkTypeTCP4, webrtc.NetworkTypeTCP6,
+				})
+			}
+		}
+	}
+
+	return settings
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength is the longest accepted Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// idempotentResponse is the snapshot of a response replayed for retries with the same key
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore keeps response snapshots by caller, route and Idempotency-Key
+type idempotencyStore struct {
+	responses map[string]*idempotentResponse
+	mu        sync.Mutex
+}
+
+// newIdempotencyStore creates an empty idempotencyStore
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{
+		responses: make(map[string]*idempotentResponse),
+	}
+}
+
+// begin claims a key for a request. It returns the stored response for a retry,
+// or nil with claimed set when the caller should handle the request itself.
+func (st *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte, ttl time.Duration) (stored *idempotentResponse, claimed bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	if existing, exists := st.responses[key]; exists && now.Before(existing.expires) {
+		copied := *existing
+		return &copied, false
+	}
+
+	// Drop expired snapshots of other keys before adding a new one
+	for k, other := range st.responses {
+		if !now.Before(other.expires) {
+			delete(st.responses, k)
+		}
+	}
+	st.responses[key] = &idempotentResponse{fingerprint: fingerprint, expires: now.Add(ttl)}
+	return nil, true
+}
+
+// finish stores the response of a claimed key
+func (st *idempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if entry, exists := st.responses[key]; exists {
+		entry.done = true
+		entry.status = status
+		entry.header = header
+		entry.body = body
+	}
+}
+
+// release forgets a claimed key so that the request can be retried
+func (st *idempotencyStore) release(key string) {
+	st.mu.Lock()
+	delete(st.responses, key)
+	st.mu.Unlock()
+}
+
+// recordingWriter copies the response body while writing it
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes and records a chunk of the body
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes and records a chunk of the body
+func (w *recordingWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// idempotencyMiddleware makes POST requests carrying an Idempotency-Key header safe to
+// retry: the first response is stored for IDEMPOTENCY_TTL_SECONDS and replayed to
+// retries from the same caller with the same key and body. Server errors and 429s are
+// not stored, so those requests can be retried for real.
+func (s *Server) idempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader("Idempotency-Key")
+		if c.Request.Method != http.MethodPost || idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": tr(c, "Idempotency-Key must be at most 255 characters")})
+			return
+		}
+
+		ttl := time.Duration(s.settings().IdempotencyTTLSeconds) * time.Second
+		if ttl <= 0 {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respondBindError(c, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := c.GetString("user_id") + " " + c.Request.URL.Path + " " + idempotencyKey
+		fingerprint := sha256.Sum256(body)
+
+		stored, claimed := s.idempotency.begin(key, fingerprint, ttl)
+		if !claimed {
+			switch {
+			case stored.fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": tr(c, "Idempotency-Key was already used with a different request body")})
+			case !stored.done:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": tr(c, "A request with this Idempotency-Key is still in progress")})
+			default:
+				for name, values := range stored.header {
+					c.Writer.Header()[name] = values
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(stored.status, stored.header.Get("Content-Type"), stored.body)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		defer func() {
+			// Panics and server errors leave the key free for a retry
+			if !completed {
+				s.idempotency.release(key)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return
+		}
+		s.idempotency.finish(key, status, writer.Header().Clone(), writer.body.Bytes())
+		completed = true
+	}
+}
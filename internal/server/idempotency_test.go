@@ -0,0 +1,139 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newIdempotencyRouter serves POST /rooms behind the idempotency middleware of a test
+// server, for an authenticated caller, with handle as the route's handler
+func newIdempotencyRouter(t *testing.T, handle gin.HandlerFunc) *gin.Engine {
+	s, _ := newTestServer(t)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "caller")
+	}, s.idempotencyMiddleware())
+	router.POST("/rooms", handle)
+	return router
+}
+
+// postIdempotent sends a POST /rooms with an Idempotency-Key
+func postIdempotent(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/rooms", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestIdempotencyReplaysResponse checks that a retry with the same key and body gets
+// the stored response without running the handler again
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	var calls atomic.Int32
+	router := newIdempotencyRouter(t, func(c *gin.Context) {
+		n := calls.Add(1)
+		c.Header("Location", "/rooms/created")
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	first := postIdempotent(router, "key-1", `{"name": "standup"}`)
+	retry := postIdempotent(router, "key-1", `{"name": "standup"}`)
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want once", calls.Load())
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry got %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Location") != "/rooms/created" || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry headers = %v", retry.Header())
+	}
+
+	// Another key is a new request
+	if other := postIdempotent(router, "key-2", `{"name": "standup"}`); other.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("request with a new key got %d after %d calls", other.Code, calls.Load())
+	}
+}
+
+// TestIdempotencyRejectsDifferentBody checks that reusing a key for another request
+// body is refused rather than replaying an unrelated response
+func TestIdempotencyRejectsDifferentBody(t *testing.T) {
+	var calls atomic.Int32
+	router := newIdempotencyRouter(t, func(c *gin.Context) {
+		calls.Add(1)
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	postIdempotent(router, "key-1", `{"name": "standup"}`)
+	w := postIdempotent(router, "key-1", `{"name": "retro"}`)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with a different body got %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", calls.Load())
+	}
+}
+
+// TestIdempotencyConcurrentDuplicate checks that a duplicate arriving while the first
+// request is still being handled is refused, and that retries after it completes are
+// replayed
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := newIdempotencyRouter(t, func(c *gin.Context) {
+		calls.Add(1)
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": "room"})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postIdempotent(router, "key-1", `{"name": "standup"}`)
+	}()
+	<-started
+
+	if w := postIdempotent(router, "key-1", `{"name": "standup"}`); w.Code != http.StatusConflict {
+		t.Errorf("in-flight duplicate got %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	close(release)
+	first := <-done
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request got %d", first.Code)
+	}
+
+	retry := postIdempotent(router, "key-1", `{"name": "standup"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry after completion got %d %s", retry.Code, retry.Body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", calls.Load())
+	}
+}
+
+// TestIdempotencyServerErrorNotStored checks that a failed request leaves its key
+// free, so that the retry runs for real
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	var calls atomic.Int32
+	router := newIdempotencyRouter(t, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	postIdempotent(router, "key-1", `{}`)
+	if w := postIdempotent(router, "key-1", `{}`); w.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("retry after a server error got %d after %d calls", w.Code, calls.Load())
+	}
+}
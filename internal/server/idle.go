@@ -0,0 +1,119 @@
+package server
+
+import (
+	"time"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// idleCheckInterval is how often rooms are checked for idleness
+const idleCheckInterval = 15 * time.Second
+
+// runIdleReaper closes rooms that have had no participants for longer than the idle timeout
+func (s *Server) runIdleReaper() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		timeout := time.Duration(s.settings().RoomIdleTimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			continue
+		}
+
+		for _, room := range s.idleRooms(time.Now().Add(-timeout)) {
+			s.closeIdleRoom(room)
+		}
+	}
+}
+
+// idleRooms returns the active rooms that have been empty since before a cutoff
+func (s *Server) idleRooms(cutoff time.Time) []*models.Room {
+	s.roomManager.Mu.RLock()
+	defer s.roomManager.Mu.RUnlock()
+
+	var idle []*models.Room
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		if room.IsActive && !room.EmptySince.IsZero() && room.EmptySince.Before(cutoff) {
+			idle = append(idle, room)
+		}
+		room.Mu.RUnlock()
+	}
+	return idle
+}
+
+// closeIdleRoom closes a room nobody has used for the idle timeout. The room is
+// kept, so its creator can reopen it.
+func (s *Server) closeIdleRoom(room *models.Room) {
+	closed := false
+	s.roomUpdate(room, func() {
+		// A participant may have joined since the room was found idle
+		if !room.IsActive || room.EmptySince.IsZero() {
+			return
+		}
+		room.IsActive = false
+		room.EndedAt = time.Now()
+		room.Version++
+		closed = true
+	})
+	if !closed {
+		return
+	}
+	s.saveRoom(room)
+
+	s.endRoom(room, "idle")
+	serverLog.Infof("Room %s closed after being idle", room.ID)
+}
+
+// endRoom ends the call in a closed or archived room: remaining participants are
+// disconnected, recordings are finalized, the call detail record is completed and
+// room.ended is published
+func (s *Server) endRoom(room *models.Room, reason string) {
+	s.closeRoomSessions(room)
+
+	recordings := s.finalizeRecordings(room.TenantID, room.ID)
+
+	data := map[string]interface{}{
+		"reason": reason,
+	}
+	participants := 0
+	if record, ok := s.calls.finish(room, reason, recordings); ok {
+		data["cdr"] = record
+		participants = len(record.Participants)
+	}
+
+	room.Mu.RLock()
+	lifetime := time.Since(room.CreatedAt).Seconds()
+	room.Mu.RUnlock()
+	s.metrics.ObserveRoomClosed(lifetime, participants)
+
+	s.publishEvent(events.RoomEnded, room.ID, data)
+	s.metrics.ForgetRoom(room.ID)
+	s.spam.forgetRoom(room.ID)
+	s.endQueueCall(room.ID, "")
+	s.stopRoomLoop(room.ID)
+}
+
+// finalizeRecordings stops the active recordings of a room and returns their IDs
+func (s *Server) finalizeRecordings(tenantID, roomID string) []string {
+	var stopped []string
+	for _, recording := range s.recorder.ListRecordings(tenantID, roomID) {
+		if !recording.Active {
+			continue
+		}
+		if err := s.recorder.StopRecording(recording.ID); err != nil {
+			recordingLog.Errorf("Failed to stop recording %s: %v", recording.ID, err)
+			s.reportRecordingError(err, roomID, recording.ID)
+			continue
+		}
+
+		s.metrics.IncrementRecordingsCompleted()
+		s.publishEvent(events.RecordingStopped, roomID, map[string]interface{}{
+			"recording_id": recording.ID,
+		})
+		go s.processRecording(recording.ID)
+		stopped = append(stopped, recording.ID)
+	}
+	return stopped
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/media"
+)
+
+// createIngestHandler pulls an RTSP stream (e.g. an IP camera) into the room as a participant
+func (s *Server) createIngestHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		URL       string `json:"url" binding:"required"`
+		Name      string `json:"name"`
+		Transcode bool   `json:"transcode"` // always transcode to VP8 instead of forwarding H.264/VP8 as-is
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "rtsp" && parsed.Scheme != "rtsps") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "An rtsp:// or rtsps:// URL is required")})
+		return
+	}
+	if err := checkStreamHost(parsed); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	room, ok := s.ownedRoom(c)
+	if !ok {
+		return
+	}
+
+	ingestID := generateIngestID()
+	if req.Name == "" {
+		req.Name = parsed.Host
+	}
+
+	// Probe the stream and pick passthrough or transcoding
+	source, err := media.NewStreamSource(req.URL, ingestID, !req.Transcode)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	if err := source.Start(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	// Never echo camera credentials back to clients
+	parsed.User = nil
+
+	bot := s.addBot(room, ingestID, botTypeIngest, req.Name, parsed.String(), userID, source)
+	sfuLog.Infof("Ingest %s started in room %s from %s", bot.ID, room.ID, parsed.Host)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Ingest started successfully",
+		"ingest_id": bot.ID,
+		"client_id": bot.Client.ID,
+		"codec":     source.MimeType(),
+		"bot":       botView(bot),
+	})
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// integrityPass is the state of the last pass verifying every recording on the node
+type integrityPass struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Checked    int        `json:"checked"`
+	Failed     []string   `json:"failed"` // IDs of recordings with damaged artifacts
+}
+
+// integrityChecker tracks verification passes so that only one runs at a time
+type integrityChecker struct {
+	pass integrityPass
+	mu   sync.Mutex
+}
+
+// newIntegrityChecker creates a checker that has not run yet
+func newIntegrityChecker() *integrityChecker {
+	return &integrityChecker{pass: integrityPass{Failed: []string{}}}
+}
+
+// start marks a pass running and reports false if one already is
+func (i *integrityChecker) start() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.pass.Running {
+		return false
+	}
+	now := time.Now()
+	i.pass = integrityPass{Running: true, StartedAt: &now, Failed: []string{}}
+	return true
+}
+
+// record counts a verified recording
+func (i *integrityChecker) record(recordingID string, ok bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.pass.Checked++
+	if !ok {
+		i.pass.Failed = append(i.pass.Failed, recordingID)
+	}
+}
+
+// finish marks the running pass finished
+func (i *integrityChecker) finish() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := time.Now()
+	i.pass.Running = false
+	i.pass.FinishedAt = &now
+}
+
+// status returns a copy of the state of the last pass
+func (i *integrityChecker) status() integrityPass {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pass := i.pass
+	pass.Failed = append([]string{}, i.pass.Failed...)
+	return pass
+}
+
+// verifyRecording checks the artifacts of a recording against their checksums, stores
+// the result and announces damaged recordings with recording.corrupted
+func (s *Server) verifyRecording(recordingID string) (*recording.Verification, error) {
+	verification, err := s.recorder.Verify(recordingID)
+	if err != nil {
+		return nil, err
+	}
+	if verification.OK {
+		s.saveRecording(recordingID)
+		return verification, nil
+	}
+
+	var damaged []recording.ArtifactCheck
+	for _, check := range verification.Artifacts {
+		if check.Status != recording.CheckOK {
+			damaged = append(damaged, check)
+			recordingLog.Errorf("Artifact %s of recording %s is %s", check.Name, recordingID, check.Status)
+		}
+	}
+	rec, _ := s.recorder.Metadata(recordingID)
+	s.saveRecording(recordingID, newEvent(events.RecordingCorrupted, rec.RoomID, map[string]interface{}{
+		"recording_id": recordingID,
+		"artifacts":    damaged,
+	}))
+	return verification, nil
+}
+
+// verifyRecordings checks every processed recording on the node; recordings in the
+// archive are skipped. It reports false if a pass is already running.
+func (s *Server) verifyRecordings() bool {
+	if !s.integrity.start() {
+		return false
+	}
+	defer s.integrity.finish()
+
+	for _, recordingID := range s.recorder.Verifiable() {
+		verification, err := s.verifyRecording(recordingID)
+		if err != nil {
+			// Archived or deleted since listed
+			continue
+		}
+		s.integrity.record(recordingID, verification.OK)
+	}
+
+	status := s.integrity.status()
+	recordingLog.Infof("Verified %d recordings, %d damaged", status.Checked, len(status.Failed))
+	return true
+}
+
+// runIntegrityChecks verifies every recording each RECORDING_VERIFY_INTERVAL_HOURS hours
+// until the server stops; 0 disables the scheduled checks
+func (s *Server) runIntegrityChecks() {
+	hours := envInt64("RECORDING_VERIFY_INTERVAL_HOURS", 0)
+	if hours <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(hours) * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.verifyRecordings()
+	}
+}
+
+// adminVerifyRecordingHandler checks the artifacts of a recording against the
+// checksums computed when it was processed
+func (s *Server) adminVerifyRecordingHandler(c *gin.Context) {
+	rec, exists := s.recorder.GetRecording(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return
+	}
+	if respondOffline(c, rec) {
+		return
+	}
+
+	verification, err := s.verifyRecording(rec.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, recording.ErrNotProcessed):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has not been processed yet")})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to verify recording")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recording_id": rec.ID,
+		"verification": verification,
+	})
+}
+
+// adminStartVerifyHandler starts checking every recording on the node in the background
+func (s *Server) adminStartVerifyHandler(c *gin.Context) {
+	if s.integrity.status().Running {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Verification is already running")})
+		return
+	}
+
+	go s.verifyRecordings()
+	s.recordAudit(c, "recording.verify", "recordings", nil)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verifying recordings"})
+}
+
+// adminVerifyStatusHandler returns the state of the last verification of every
+// recording
+func (s *Server) adminVerifyStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.integrity.status())
+}
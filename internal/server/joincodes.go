@@ -0,0 +1,152 @@
+package server
+
+import (
+	"crypto/rand"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// joinCodeAlphabet leaves out letters that are easily confused when read aloud or handwritten
+const joinCodeAlphabet = "abcdefghijkmnpqrstuvwxyz"
+
+// joinCodeGroups are the group lengths of a join code, e.g. "abc-defg-hij"
+var joinCodeGroups = []int{3, 4, 3}
+
+// generateJoinCode returns a random join code
+func generateJoinCode() string {
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+
+	groups := make([]string, len(joinCodeGroups))
+	for i, length := range joinCodeGroups {
+		group := make([]byte, length)
+		for j := range group {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				panic(err)
+			}
+			group[j] = joinCodeAlphabet[n.Int64()]
+		}
+		groups[i] = string(group)
+	}
+	return strings.Join(groups, "-")
+}
+
+// normalizeJoinCode accepts codes typed in any case, with or without separators;
+// it returns "" when the input cannot be a join code
+func normalizeJoinCode(input string) string {
+	var letters []byte
+	for _, r := range strings.ToLower(input) {
+		switch {
+		case r >= 'a' && r <= 'z':
+			letters = append(letters, byte(r))
+		case r == '-' || r == ' ':
+		default:
+			return ""
+		}
+	}
+
+	groups := make([]string, 0, len(joinCodeGroups))
+	for _, length := range joinCodeGroups {
+		if len(letters) < length {
+			return ""
+		}
+		groups = append(groups, string(letters[:length]))
+		letters = letters[length:]
+	}
+	if len(letters) > 0 {
+		return ""
+	}
+	return strings.Join(groups, "-")
+}
+
+// newJoinCodeLocked returns a join code not used by any room; the caller holds roomManager.Mu
+func (s *Server) newJoinCodeLocked() string {
+	for {
+		code := generateJoinCode()
+		inUse := false
+		for _, room := range s.roomManager.Rooms {
+			if room.JoinCode == code {
+				inUse = true
+				break
+			}
+		}
+		if !inUse {
+			return code
+		}
+	}
+}
+
+// roomByCode finds the room with a join code
+func (s *Server) roomByCode(code string) (*models.Room, bool) {
+	s.roomManager.Mu.RLock()
+	defer s.roomManager.Mu.RUnlock()
+
+	return s.roomByCodeLocked(code)
+}
+
+// roomByCodeLocked finds the room with a join code; the caller holds roomManager.Mu
+func (s *Server) roomByCodeLocked(code string) (*models.Room, bool) {
+	for _, room := range s.roomManager.Rooms {
+		if room.JoinCode == code {
+			return room, true
+		}
+	}
+	return nil, false
+}
+
+// joinByCodeHandler joins the room a join code belongs to
+func (s *Server) joinByCodeHandler(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	code := normalizeJoinCode(req.Code)
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "code must look like abc-defg-hij")})
+		return
+	}
+
+	room, exists := s.roomByCode(code)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	// Room tokens only admit their own room
+	if !allowRoom(c, room.ID) {
+		return
+	}
+
+	s.joinRoom(c, room.ID)
+}
+
+// publicRoomsHandler lists the active rooms their creators chose to make public
+func (s *Server) publicRoomsHandler(c *gin.Context) {
+	q, err := parseRoomQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+	q.Status = roomStatusActive
+	q.PublicOnly = true
+
+	rooms, total := s.listRooms(q, c.GetString("user_id"), false)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rooms":  rooms,
+		"total":  total,
+		"limit":  q.Limit,
+		"offset": q.Offset,
+	})
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// What a legal hold can be placed on. A hold on a room also covers its recordings and
+// chat; a hold on a user covers the account, the recordings the user owns and the
+// messages the user sent.
+const (
+	holdRoom      = "room"
+	holdRecording = "recording"
+	holdUser      = "user"
+)
+
+// legalHoldView is a legal hold in the list of holds
+type legalHoldView struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	models.LegalHold
+}
+
+// userHeld reports whether a user is under legal hold
+func userHeld(userID string) bool {
+	user, exists := auth.GetUserByID(userID)
+	return exists && user.LegalHold != nil
+}
+
+// roomHeld reports whether a room may not be deleted: it or its creator is under
+// legal hold
+func roomHeld(room *models.Room) bool {
+	room.Mu.RLock()
+	held := room.LegalHold != nil
+	creatorID := room.CreatorID
+	room.Mu.RUnlock()
+
+	return held || userHeld(creatorID)
+}
+
+// findRoom returns a room, soft-deleted ones included
+func (s *Server) findRoom(roomID string) (*models.Room, bool) {
+	if room, exists := s.getRoom(roomID); exists {
+		return room, true
+	}
+
+	s.trashMu.Lock()
+	defer s.trashMu.Unlock()
+
+	deleted, exists := s.deletedRooms[roomID]
+	if !exists {
+		return nil, false
+	}
+	return deleted.room, true
+}
+
+// allRooms returns the rooms of every tenant, soft-deleted ones included
+func (s *Server) allRooms() []*models.Room {
+	s.roomManager.Mu.RLock()
+	rooms := make([]*models.Room, 0, len(s.roomManager.Rooms))
+	for _, room := range s.roomManager.Rooms {
+		rooms = append(rooms, room)
+	}
+	s.roomManager.Mu.RUnlock()
+
+	s.trashMu.Lock()
+	for _, deleted := range s.deletedRooms {
+		rooms = append(rooms, deleted.room)
+	}
+	s.trashMu.Unlock()
+	return rooms
+}
+
+// recordingHeld reports whether a recording may not be deleted: it, its room or its
+// owner is under legal hold
+func (s *Server) recordingHeld(rec recording.StoredRecording) bool {
+	if rec.LegalHold || userHeld(rec.OwnerID) {
+		return true
+	}
+	room, exists := s.findRoom(rec.RoomID)
+	return exists && roomHeld(room)
+}
+
+// messageHeld reports whether a chat message may not be deleted: its room or its
+// author is under legal hold
+func messageHeld(room *models.Room, message *chat.Message) bool {
+	return roomHeld(room) || userHeld(message.UserID)
+}
+
+// heldChat selects the chat messages under legal hold, for purges
+func (s *Server) heldChat() chat.Held {
+	held := chat.Held{Rooms: make(map[string]bool), Users: make(map[string]bool)}
+	for _, user := range auth.UsersOnLegalHold() {
+		held.Users[user.ID] = true
+	}
+
+	for _, room := range s.allRooms() {
+		room.Mu.RLock()
+		if room.LegalHold != nil || held.Users[room.CreatorID] {
+			held.Rooms[room.ID] = true
+		}
+		room.Mu.RUnlock()
+	}
+	return held
+}
+
+// legalHold returns the hold on the room, recording or user kind and id name, nil if
+// there is none. It reports false if there is no such target.
+func (s *Server) legalHold(kind, id string) (*models.LegalHold, bool) {
+	switch kind {
+	case holdRoom:
+		room, exists := s.findRoom(id)
+		if !exists {
+			return nil, false
+		}
+		room.Mu.RLock()
+		defer room.Mu.RUnlock()
+		return room.LegalHold, true
+	case holdRecording:
+		rec, exists := s.recorder.Metadata(id)
+		return rec.LegalHold, exists
+	case holdUser:
+		user, exists := auth.GetUserByID(id)
+		if !exists {
+			return nil, false
+		}
+		return user.LegalHold, true
+	}
+	return nil, false
+}
+
+// setLegalHold places a hold on the room, recording or user kind and id, or releases
+// it when hold is nil, and persists the change
+func (s *Server) setLegalHold(kind, id string, hold *models.LegalHold) {
+	switch kind {
+	case holdRoom:
+		room, exists := s.findRoom(id)
+		if !exists {
+			return
+		}
+		s.roomUpdate(room, func() {
+			room.LegalHold = hold
+		})
+
+		s.trashMu.Lock()
+		deleted, inTrash := s.deletedRooms[id]
+		s.trashMu.Unlock()
+		if inTrash {
+			s.saveDeletedRoom(deleted)
+		} else {
+			s.saveRoom(room)
+		}
+	case holdRecording:
+		if s.recorder.SetLegalHold(id, hold) == nil {
+			s.saveRecording(id)
+		}
+	case holdUser:
+		if auth.SetUserLegalHold(id, hold) == nil {
+			s.saveUser(id)
+		}
+	}
+}
+
+// adminPlaceLegalHoldHandler places a legal hold on a room, recording or user; held
+// data cannot be deleted, by users, storage cleanup, purges or SCIM deprovisioning,
+// until an admin releases the hold
+func (s *Server) adminPlaceLegalHoldHandler(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required,max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	kind, id := c.Param("kind"), c.Param("id")
+	current, exists := s.legalHold(kind, id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Legal hold target not found")})
+		return
+	}
+	if current != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Already under legal hold")})
+		return
+	}
+
+	hold := &models.LegalHold{
+		PlacedAt: time.Now(),
+		PlacedBy: c.GetString("user_id"),
+		Reason:   strings.TrimSpace(req.Reason),
+	}
+	s.setLegalHold(kind, id, hold)
+	s.recordAudit(c, "legal_hold.place", kind+":"+id, map[string]string{"reason": hold.Reason})
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Legal hold placed",
+		"legal_hold": legalHoldView{Kind: kind, ID: id, LegalHold: *hold},
+	})
+}
+
+// adminReleaseLegalHoldHandler releases the legal hold on a room, recording or user
+func (s *Server) adminReleaseLegalHoldHandler(c *gin.Context) {
+	kind, id := c.Param("kind"), c.Param("id")
+	previous, exists := s.legalHold(kind, id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Legal hold target not found")})
+		return
+	}
+	if previous == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Not under legal hold")})
+		return
+	}
+
+	s.setLegalHold(kind, id, nil)
+
+	s.recordAudit(c, "legal_hold.release", kind+":"+id, map[string]string{
+		"reason":    previous.Reason,
+		"placed_at": previous.PlacedAt.Format(time.RFC3339),
+		"placed_by": previous.PlacedBy,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Legal hold released"})
+}
+
+// adminListLegalHoldsHandler lists the legal holds in place, newest first
+func (s *Server) adminListLegalHoldsHandler(c *gin.Context) {
+	holds := []legalHoldView{}
+	for _, user := range auth.UsersOnLegalHold() {
+		holds = append(holds, legalHoldView{Kind: holdUser, ID: user.ID, LegalHold: *user.LegalHold})
+	}
+	for recordingID, hold := range s.recorder.LegalHolds() {
+		holds = append(holds, legalHoldView{Kind: holdRecording, ID: recordingID, LegalHold: hold})
+	}
+	for _, room := range s.allRooms() {
+		room.Mu.RLock()
+		if room.LegalHold != nil {
+			holds = append(holds, legalHoldView{Kind: holdRoom, ID: room.ID, LegalHold: *room.LegalHold})
+		}
+		room.Mu.RUnlock()
+	}
+
+	sort.Slice(holds, func(i, j int) bool {
+		return holds[i].PlacedAt.After(holds[j].PlacedAt)
+	})
+	c.JSON(http.StatusOK, gin.H{"legal_holds": holds})
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// lifecycle ties the server-side resources of every participant (peer connection,
+// signal channel, WebSocket) to a room-session context, so nothing outlives the
+// participant or the session and connections that never come up are reclaimed
+type lifecycle struct {
+	// How long a peer connection may take to connect, or stay disconnected
+	connectTimeout    time.Duration
+	disconnectTimeout time.Duration
+
+	// ICE restarts attempted on a failed peer connection, and how long each may take
+	iceRestarts       int
+	iceRestartTimeout time.Duration
+
+	rooms    map[string]*roomSession
+	sessions map[string]*clientSession // by client ID
+	mu       sync.Mutex
+}
+
+// roomSession is the context shared by all participants of a room session
+type roomSession struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	clients int
+}
+
+// clientSession tracks the resources of one participant
+type clientSession struct {
+	roomID   string
+	cancel   context.CancelFunc
+	timer    *time.Timer
+	restarts int       // ICE restarts since the peer connection failed
+	failedAt time.Time // when the peer connection failed; zero unless recovering
+}
+
+// newLifecycle reads lifecycle timeouts from the environment
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		connectTimeout:    time.Duration(envInt64("PEER_CONNECT_TIMEOUT_SECONDS", 30)) * time.Second,
+		disconnectTimeout: time.Duration(envInt64("PEER_DISCONNECT_TIMEOUT_SECONDS", 15)) * time.Second,
+		iceRestarts:       int(envInt64("PEER_ICE_RESTART_ATTEMPTS", 3)),
+		iceRestartTimeout: time.Duration(envInt64("PEER_ICE_RESTART_TIMEOUT_SECONDS", 10)) * time.Second,
+		rooms:             make(map[string]*roomSession),
+		sessions:          make(map[string]*clientSession),
+	}
+}
+
+// trackClient registers a participant's resources with its room session. The peer
+// connection must connect within the connect timeout or the participant is removed.
+func (s *Server) trackClient(room *models.Room, client *models.Client) {
+	l := s.lifecycle
+	l.mu.Lock()
+	rs, exists := l.rooms[room.ID]
+	if !exists {
+		ctx, cancel := context.WithCancel(context.Background())
+		rs = &roomSession{ctx: ctx, cancel: cancel}
+		l.rooms[room.ID] = rs
+	}
+	rs.clients++
+
+	ctx, cancel := context.WithCancel(rs.ctx)
+	cs := &clientSession{
+		roomID: room.ID,
+		cancel: cancel,
+	}
+	cs.timer = time.AfterFunc(l.connectTimeout, func() {
+		s.checkClient(room, client, cs)
+	})
+	l.sessions[client.ID] = cs
+	l.mu.Unlock()
+
+	go s.runClientSession(ctx, room, client)
+}
+
+// runClientSession delivers queued signals until the session ends, then releases
+// every resource of the participant
+func (s *Server) runClientSession(ctx context.Context, room *models.Room, client *models.Client) {
+	for {
+		select {
+		case <-ctx.Done():
+			s.removeClient(room, client)
+			s.hub.DisconnectSender(client.ID)
+			return
+		case msg := <-client.Signal:
+			signal, ok := msg.(models.SignalMessage)
+			if !ok {
+				continue
+			}
+			s.hub.SendToSender(client.ID, "signal", signalPayload{
+				RoomID:    room.ID,
+				Type:      signal.Type,
+				Data:      signal.Data,
+				SenderID:  signal.SenderID,
+				Timestamp: signal.Timestamp,
+			})
+		}
+	}
+}
+
+// signalPayload carries a server-side signaling message over the WebSocket
+type signalPayload struct {
+	RoomID    string      `json:"room_id"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	SenderID  string      `json:"sender_id,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// peerStateChanged arms or clears the participant's timeout as its peer connection
+// changes state; failed connections are recovered with ICE restarts
+func (s *Server) peerStateChanged(room *models.Room, client *models.Client, state webrtc.PeerConnectionState) {
+	l := s.lifecycle
+	l.mu.Lock()
+	cs, exists := l.sessions[client.ID]
+	if exists {
+		// Timers are armed under the lock so a released session is never re-armed
+		switch state {
+		case webrtc.PeerConnectionStateConnected:
+			cs.timer.Stop()
+		case webrtc.PeerConnectionStateDisconnected:
+			cs.timer.Reset(l.disconnectTimeout)
+		}
+	}
+	l.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	switch state {
+	case webrtc.PeerConnectionStateConnected:
+		s.peerRecovered(room, client, cs)
+	case webrtc.PeerConnectionStateFailed:
+		s.recoverPeer(room, client, cs)
+	case webrtc.PeerConnectionStateClosed:
+		s.releaseClient(client.ID)
+	}
+}
+
+// recoverPeer makes the next ICE restart attempt on a failed peer connection, or
+// expires the participant once PEER_ICE_RESTART_ATTEMPTS restarts did not bring it back
+func (s *Server) recoverPeer(room *models.Room, client *models.Client, cs *clientSession) {
+	l := s.lifecycle
+	l.mu.Lock()
+	if l.sessions[client.ID] != cs {
+		l.mu.Unlock()
+		return
+	}
+	if cs.restarts >= l.iceRestarts {
+		recovering := !cs.failedAt.IsZero()
+		l.mu.Unlock()
+
+		if recovering {
+			s.metrics.IncrementPeerRecoveries("failed")
+		}
+		s.expireClient(room, client, "peer connection failed")
+		return
+	}
+	cs.restarts++
+	if cs.failedAt.IsZero() {
+		cs.failedAt = time.Now()
+	}
+	attempt := cs.restarts
+
+	// The attempt is abandoned when the connection fails again or the timeout fires
+	cs.timer.Reset(l.iceRestartTimeout)
+	l.mu.Unlock()
+
+	s.metrics.IncrementICERestarts()
+	sfuLog.Infof("Restarting ICE for client %s in room %s (attempt %d of %d)", client.ID, room.ID, attempt, l.iceRestarts)
+
+	if err := s.restartICE(client); err != nil {
+		sfuLog.Errorf("Failed to restart ICE for client %s: %v", client.ID, err)
+		s.metrics.IncrementPeerRecoveries("failed")
+		s.expireClient(room, client, "peer connection failed")
+	}
+}
+
+// restartICE offers the participant new ICE credentials over signaling, so both
+// sides gather candidates and check connectivity again. The participant's
+// "server-answer" completes the restart; the offer carries every current track, so it
+// replaces an offer held back by renegotiate.
+func (s *Server) restartICE(client *models.Client) error {
+	offer, err := client.Conn.CreateOffer(&webrtc.OfferOptions{ICERestart: true})
+	if err != nil {
+		return err
+	}
+	if err := client.Conn.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&client.OfferPending, 0)
+
+	s.sendSignal(client, models.SignalMessage{
+		Type:      "offer",
+		Data:      offer,
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// peerRecovered records a peer connection that connected again after ICE restarts;
+// a restart only counts as recovered once the connection is back to connected
+func (s *Server) peerRecovered(room *models.Room, client *models.Client, cs *clientSession) {
+	l := s.lifecycle
+	l.mu.Lock()
+	failedAt, attempts := cs.failedAt, cs.restarts
+	cs.failedAt, cs.restarts = time.Time{}, 0
+	l.mu.Unlock()
+
+	if failedAt.IsZero() {
+		return
+	}
+	s.metrics.IncrementPeerRecoveries("recovered")
+	s.metrics.ObservePeerRecovery(time.Since(failedAt).Seconds())
+	sfuLog.Infof("Peer connection of client %s in room %s recovered after %d ICE restarts", client.ID, room.ID, attempts)
+}
+
+// checkClient runs when a participant's timeout fires. A failed peer connection
+// whose ICE restart timed out gets the next attempt. A peer connection the server
+// offered that has not connected by now is defunct, whether or not the participant's
+// WebSocket is up. One never offered holds no ICE or DTLS state: it is kept while
+// the participant signals peer-to-peer over a live WebSocket.
+func (s *Server) checkClient(room *models.Room, client *models.Client, cs *clientSession) {
+	if client.Conn != nil && client.Conn.ConnectionState() == webrtc.PeerConnectionStateConnected {
+		return
+	}
+
+	l := s.lifecycle
+	l.mu.Lock()
+	if l.sessions[client.ID] != cs {
+		// Released meanwhile
+		l.mu.Unlock()
+		return
+	}
+	if !cs.failedAt.IsZero() {
+		l.mu.Unlock()
+		s.recoverPeer(room, client, cs)
+		return
+	}
+	offered := client.Conn != nil && client.Conn.LocalDescription() != nil
+	if !offered && s.hub.HasSender(client.ID) {
+		cs.timer.Reset(l.connectTimeout)
+		l.mu.Unlock()
+		return
+	}
+	l.mu.Unlock()
+
+	s.expireClient(room, client, "peer connection did not connect")
+}
+
+// expireClient force-closes a participant whose connection timed out
+func (s *Server) expireClient(room *models.Room, client *models.Client, reason string) {
+	serverLog.Infof("Closing client %s in room %s: %s", client.ID, room.ID, reason)
+	s.releaseClient(client.ID)
+}
+
+// releaseClient ends a participant's session, closing all of its resources
+func (s *Server) releaseClient(clientID string) {
+	l := s.lifecycle
+	l.mu.Lock()
+	cs, exists := l.sessions[clientID]
+	if exists {
+		delete(l.sessions, clientID)
+		if rs, ok := l.rooms[cs.roomID]; ok {
+			if rs.clients--; rs.clients == 0 {
+				rs.cancel()
+				delete(l.rooms, cs.roomID)
+			}
+		}
+	}
+	l.mu.Unlock()
+
+	if exists {
+		cs.timer.Stop()
+		cs.cancel()
+	}
+}
+
+// endRoomLifecycle closes the resources of every participant still tracked in a room
+func (s *Server) endRoomLifecycle(roomID string) {
+	l := s.lifecycle
+	l.mu.Lock()
+	rs, exists := l.rooms[roomID]
+	delete(l.rooms, roomID)
+	for clientID, cs := range l.sessions {
+		if cs.roomID == roomID {
+			cs.timer.Stop()
+			delete(l.sessions, clientID)
+		}
+	}
+	l.mu.Unlock()
+
+	if exists {
+		rs.cancel()
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Route groups with their own request body limit
+const (
+	routesPublic       = "public"
+	routesAPI          = "api"
+	routesAdmin        = "admin"
+	routesIntegrations = "integrations"
+)
+
+// bodyLimits caps request bodies per route group, in bytes; 0 means unlimited.
+// File and avatar uploads are multipart and enforce their own size limits.
+type bodyLimits struct {
+	Public       int64 `json:"public"`
+	API          int64 `json:"api"`
+	Admin        int64 `json:"admin"`
+	Integrations int64 `json:"integrations"`
+}
+
+// readBodyLimits reads the BODY_LIMIT_* variables
+func readBodyLimits() bodyLimits {
+	return bodyLimits{
+		Public:       envInt64("BODY_LIMIT_PUBLIC_BYTES", 16<<10),
+		API:          envInt64("BODY_LIMIT_API_BYTES", 256<<10),
+		Admin:        envInt64("BODY_LIMIT_ADMIN_BYTES", 1<<20),
+		Integrations: envInt64("BODY_LIMIT_INTEGRATIONS_BYTES", 256<<10),
+	}
+}
+
+// limit returns the body limit of a route group
+func (l bodyLimits) limit(group string) int64 {
+	switch group {
+	case routesPublic:
+		return l.Public
+	case routesAdmin:
+		return l.Admin
+	case routesIntegrations:
+		return l.Integrations
+	}
+	return l.API
+}
+
+// bodyLimitMiddleware rejects request bodies larger than the group's limit with 413.
+// Bodies announcing their size are refused up front; others are cut off while read.
+func (s *Server) bodyLimitMiddleware(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := s.settings().BodyLimits.limit(group)
+		if limit <= 0 || c.Request.Body == nil || c.ContentType() == binding.MIMEMultipartPOSTForm {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": trf(c, "Request body exceeds %d bytes", limit)})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// configureValidation makes JSON decoding strict, rejecting unknown fields, and
+// names fields in validation errors by their JSON name
+func configureValidation() {
+	binding.EnableDecoderDisallowUnknownFields = true
+
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// respondBindError answers a request whose body failed to bind with 413 if it was
+// too large and otherwise 400 with a message naming the offending field
+func respondBindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": trf(c, "Request body exceeds %d bytes", maxBytesErr.Limit)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": bindErrorMessage(c, err)})
+}
+
+// bindErrorMessage describes a binding error in terms of the request's JSON fields,
+// in the request's language
+func bindErrorMessage(c *gin.Context, err error) string {
+	var validationErrs validator.ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		messages := make([]string, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			messages = append(messages, fieldErrorMessage(c, fieldErr))
+		}
+		return strings.Join(messages, "; ")
+	case errors.Is(err, io.EOF):
+		return tr(c, "Request body is required")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return tr(c, "Malformed JSON: unexpected end of body")
+	case errors.As(err, &syntaxErr):
+		return trf(c, "Malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return trf(c, "Request body must be a JSON object, not %s", typeErr.Value)
+		}
+		return trf(c, "%s must be %s, not %s", typeErr.Field, tr(c, jsonTypeName(typeErr.Type)), typeErr.Value)
+	}
+
+	// encoding/json reports unknown fields only as text
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return trf(c, "Unknown field %s", field)
+	}
+	return tr(c, err.Error())
+}
+
+// fieldErrorMessage describes a failed validation rule
+func fieldErrorMessage(c *gin.Context, fieldErr validator.FieldError) string {
+	field := fieldErr.Namespace()
+	if _, name, ok := strings.Cut(field, "."); ok {
+		field = name
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return trf(c, "%s is required", field)
+	case "min":
+		return trf(c, "%s must be at least %s", field, fieldErr.Param())
+	case "max":
+		return trf(c, "%s must be at most %s", field, fieldErr.Param())
+	}
+	return trf(c, "%s failed the %s check", field, fieldErr.Tag())
+}
+
+// jsonTypeName names a Go type the way a JSON client would see it
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	}
+	return "a " + t.String()
+}
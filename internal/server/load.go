@@ -0,0 +1,236 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+)
+
+// loadSampleInterval is how often CPU and bandwidth usage are sampled
+const loadSampleInterval = 5 * time.Second
+
+// cpuMetrics are the runtime CPU counters; busy time is total minus idle
+var cpuMetrics = []string{"/cpu/classes/total:cpu-seconds", "/cpu/classes/idle:cpu-seconds"}
+
+// loadLimits are the thresholds above which new rooms and joins are refused (0 disables)
+type loadLimits struct {
+	MaxCPUPercent    float64 `json:"max_cpu_percent,omitempty"`
+	MaxBandwidthMbps float64 `json:"max_bandwidth_mbps,omitempty"`
+	MaxTracks        int     `json:"max_tracks,omitempty"`
+	MaxRooms         int     `json:"max_rooms,omitempty"`
+}
+
+// loadReport is a snapshot of node load
+type loadReport struct {
+	CPUPercent    float64    `json:"cpu_percent"`
+	BandwidthMbps float64    `json:"bandwidth_mbps"`
+	Tracks        int        `json:"tracks"`
+	Rooms         int        `json:"rooms"`
+	Participants  int        `json:"participants"`
+	Accepting     bool       `json:"accepting"`
+	Reason        string     `json:"reason,omitempty"`
+	Limits        loadLimits `json:"limits"`
+	SampledAt     time.Time  `json:"sampled_at"`
+}
+
+// loadMonitor samples node CPU and media bandwidth in the background
+type loadMonitor struct {
+	cpuPercent    float64
+	bandwidthMbps float64
+	sampledAt     time.Time
+
+	// Previous counters for computing rates
+	lastCPU   float64
+	lastBytes map[string]uint64
+	lastTime  time.Time
+
+	mu sync.RWMutex
+}
+
+// newLoadMonitor creates a new loadMonitor; limits are part of the runtime configuration
+func newLoadMonitor() *loadMonitor {
+	return &loadMonitor{
+		lastBytes: make(map[string]uint64),
+	}
+}
+
+// busyCPUSeconds returns the CPU time spent by the process so far
+func busyCPUSeconds() float64 {
+	samples := make([]metrics.Sample, len(cpuMetrics))
+	for i, name := range cpuMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	var values [2]float64
+	for i, sample := range samples {
+		if sample.Value.Kind() == metrics.KindFloat64 {
+			values[i] = sample.Value.Float64()
+		}
+	}
+	return values[0] - values[1]
+}
+
+// runLoadMonitor samples load until the process exits
+func (s *Server) runLoadMonitor() {
+	ticker := time.NewTicker(loadSampleInterval)
+	defer ticker.Stop()
+
+	s.sampleLoad()
+	for range ticker.C {
+		s.sampleLoad()
+	}
+}
+
+// sampleLoad updates CPU and bandwidth usage since the previous sample
+func (s *Server) sampleLoad() {
+	now := time.Now()
+	cpu := busyCPUSeconds()
+
+	// Sum transport bytes of every server-side peer connection
+	bytes := make(map[string]uint64)
+	for _, pc := range s.peerConnections() {
+		for _, stat := range pc.conn.GetStats() {
+			if transport, ok := stat.(webrtc.TransportStats); ok {
+				bytes[pc.id] += transport.BytesSent + transport.BytesReceived
+			}
+		}
+	}
+
+	m := s.load
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.lastTime.IsZero() {
+		elapsed := now.Sub(m.lastTime).Seconds()
+
+		m.cpuPercent = (cpu - m.lastCPU) / (elapsed * float64(runtime.NumCPU())) * 100
+
+		var delta uint64
+		for id, total := range bytes {
+			// Counters of new connections start from zero
+			if last := m.lastBytes[id]; total >= last {
+				delta += total - last
+			} else {
+				delta += total
+			}
+		}
+		m.bandwidthMbps = float64(delta) * 8 / elapsed / 1e6
+		s.metrics.SetBandwidth(float64(delta) * 8 / elapsed)
+		m.sampledAt = now
+	}
+
+	m.lastCPU = cpu
+	m.lastBytes = bytes
+	m.lastTime = now
+}
+
+// idPeerConnection is a server-side peer connection with a stable ID
+type idPeerConnection struct {
+	id   string
+	conn *webrtc.PeerConnection
+}
+
+// peerConnections returns the peer connections of all participants and relays
+func (s *Server) peerConnections() []idPeerConnection {
+	var pcs []idPeerConnection
+
+	s.roomManager.Mu.RLock()
+	defer s.roomManager.Mu.RUnlock()
+
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		for clientID, client := range room.Clients {
+			if client.Conn != nil {
+				pcs = append(pcs, idPeerConnection{id: clientID, conn: client.Conn})
+			}
+		}
+		for _, published := range room.Tracks {
+			for relayID, pc := range published.Relays {
+				pcs = append(pcs, idPeerConnection{id: relayID, conn: pc})
+			}
+		}
+		room.Mu.RUnlock()
+	}
+
+	return pcs
+}
+
+// loadReport returns the current node load and whether new work is accepted
+func (s *Server) loadReport() loadReport {
+	report := loadReport{
+		Limits: s.settings().LoadLimits,
+	}
+
+	s.load.mu.RLock()
+	report.CPUPercent = s.load.cpuPercent
+	report.BandwidthMbps = s.load.bandwidthMbps
+	report.SampledAt = s.load.sampledAt
+	s.load.mu.RUnlock()
+
+	s.roomManager.Mu.RLock()
+	report.Rooms = len(s.roomManager.Rooms)
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		report.Tracks += len(room.Tracks)
+		report.Participants += len(room.Clients)
+		room.Mu.RUnlock()
+	}
+	s.roomManager.Mu.RUnlock()
+
+	report.Reason = s.overloadReason(report, true)
+	report.Accepting = report.Reason == ""
+
+	return report
+}
+
+// overloadReason returns why the node refuses new work, or "" if it has capacity.
+// The room limit only applies when creating rooms.
+func (s *Server) overloadReason(report loadReport, creatingRoom bool) string {
+	limits := s.settings().LoadLimits
+
+	switch {
+	case creatingRoom && s.drain.draining():
+		return "node is draining"
+	case creatingRoom && s.maintenanceBlocking():
+		return "maintenance is scheduled"
+	case limits.MaxCPUPercent > 0 && report.CPUPercent >= limits.MaxCPUPercent:
+		return fmt.Sprintf("cpu usage %.0f%% exceeds %.0f%%", report.CPUPercent, limits.MaxCPUPercent)
+	case limits.MaxBandwidthMbps > 0 && report.BandwidthMbps >= limits.MaxBandwidthMbps:
+		return fmt.Sprintf("bandwidth %.1f Mbps exceeds %.0f Mbps", report.BandwidthMbps, limits.MaxBandwidthMbps)
+	case limits.MaxTracks > 0 && report.Tracks >= limits.MaxTracks:
+		return fmt.Sprintf("track count %d reached limit %d", report.Tracks, limits.MaxTracks)
+	case creatingRoom && limits.MaxRooms > 0 && report.Rooms >= limits.MaxRooms:
+		return fmt.Sprintf("room count %d reached limit %d", report.Rooms, limits.MaxRooms)
+	}
+	return ""
+}
+
+// admit refuses a room creation or join with 503 and a retry hint when the node is overloaded
+func (s *Server) admit(c *gin.Context, creatingRoom bool) bool {
+	reason := s.overloadReason(s.loadReport(), creatingRoom)
+	if reason == "" {
+		return true
+	}
+
+	retryAfter := s.settings().RetryAfterSeconds
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":       tr(c, "Server is overloaded"),
+		"reason":      reason,
+		"retry_after": retryAfter,
+	})
+	return false
+}
+
+// loadHandler reports node load for external load balancers
+func (s *Server) loadHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.loadReport())
+}
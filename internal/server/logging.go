@@ -0,0 +1,59 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/logging"
+)
+
+// Subsystem loggers; their levels come from LOG_LEVEL and LOG_LEVELS
+var (
+	serverLog    = logging.New(logging.Server)
+	httpLog      = logging.New(logging.HTTP)
+	sfuLog       = logging.New(logging.SFU)
+	recordingLog = logging.New(logging.Recording)
+)
+
+// readLoggingConfig reads LOG_FORMAT (text or json), LOG_LEVEL and per-subsystem
+// overrides in LOG_LEVELS such as "hub=debug,sfu=warn"
+func readLoggingConfig() logging.Config {
+	config := logging.Config{Format: logging.FormatText, Level: logging.LevelInfo}
+
+	switch format := envString("LOG_FORMAT", logging.FormatText); format {
+	case logging.FormatText, logging.FormatJSON:
+		config.Format = format
+	default:
+		serverLog.Warnf("Invalid LOG_FORMAT %q, using %s", format, logging.FormatText)
+	}
+
+	if level, err := logging.ParseLevel(envString("LOG_LEVEL", "info")); err != nil {
+		serverLog.Warnf("Invalid LOG_LEVEL: %v", err)
+	} else {
+		config.Level = level
+	}
+
+	if levels, err := logging.ParseLevels(envString("LOG_LEVELS", "")); err != nil {
+		serverLog.Warnf("Invalid LOG_LEVELS: %v", err)
+	} else {
+		config.Levels = levels
+	}
+
+	return config
+}
+
+// accessLogMiddleware logs every request through the http subsystem logger
+func accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+
+		c.Next()
+
+		if !httpLog.Enabled(logging.LevelInfo) {
+			return
+		}
+		httpLog.Infof("%s %s %d %s %s", c.Request.Method, path, c.Writer.Status(), time.Since(start), c.ClientIP())
+	}
+}
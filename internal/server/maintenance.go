@@ -0,0 +1,340 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/events"
+)
+
+// maintenanceCheckInterval is how often warnings, drains and window ends are checked
+const maintenanceCheckInterval = 15 * time.Second
+
+// maintenanceSettings are how maintenance windows are announced and prepared for
+type maintenanceSettings struct {
+	// Minutes before a window's start at which active rooms are warned
+	WarningMinutes []int `json:"warning_minutes"`
+	// Minutes before a window's start from which new rooms are refused and the node drains
+	BlockMinutes int `json:"block_minutes"`
+}
+
+// readMaintenanceSettings reads MAINTENANCE_WARNING_MINUTES and MAINTENANCE_BLOCK_MINUTES
+func readMaintenanceSettings() maintenanceSettings {
+	settings := maintenanceSettings{
+		WarningMinutes: []int{60, 15, 5, 1},
+		BlockMinutes:   int(envInt64("MAINTENANCE_BLOCK_MINUTES", 15)),
+	}
+
+	if items := envList("MAINTENANCE_WARNING_MINUTES"); len(items) > 0 {
+		settings.WarningMinutes = nil
+		for _, item := range items {
+			n, err := strconv.Atoi(item)
+			if err != nil || n < 1 || n > maxReminderMinutes {
+				serverLog.Warnf("Invalid MAINTENANCE_WARNING_MINUTES entry %q, ignoring it", item)
+				continue
+			}
+			settings.WarningMinutes = append(settings.WarningMinutes, n)
+		}
+	}
+	return settings
+}
+
+// maintenanceWindow is a scheduled period of downtime of the node
+type maintenanceWindow struct {
+	ID        string    `json:"id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Message   string    `json:"message,omitempty"`
+	Drain     bool      `json:"drain"` // drain the node before the window starts
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	warned   map[int]bool // warnings sent, by minutes before the start
+	started  bool         // maintenance.started was published
+	draining bool         // the window started the node's drain
+}
+
+// maintenanceSchedule keeps the scheduled maintenance windows, by ID
+type maintenanceSchedule struct {
+	windows map[string]*maintenanceWindow
+	mu      sync.Mutex
+}
+
+// newMaintenanceSchedule creates an empty maintenanceSchedule
+func newMaintenanceSchedule() *maintenanceSchedule {
+	return &maintenanceSchedule{
+		windows: make(map[string]*maintenanceWindow),
+	}
+}
+
+// list returns copies of the windows not over yet, earliest first
+func (m *maintenanceSchedule) list() []maintenanceWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]maintenanceWindow, 0, len(m.windows))
+	for _, window := range m.windows {
+		list = append(list, *window)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartsAt.Before(list[j].StartsAt)
+	})
+	return list
+}
+
+// blocking returns the window refusing new rooms at a time: one that is under way or
+// starts within block
+func (m *maintenanceSchedule) blocking(now time.Time, block time.Duration) (maintenanceWindow, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, window := range m.windows {
+		if !now.Before(window.StartsAt.Add(-block)) && now.Before(window.EndsAt) {
+			return *window, true
+		}
+	}
+	return maintenanceWindow{}, false
+}
+
+// maintenanceNotice is the payload of "maintenance" messages counting down to a window
+type maintenanceNotice struct {
+	ID          string    `json:"id"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	MinutesLeft int       `json:"minutes_left"`
+	Message     string    `json:"message,omitempty"`
+}
+
+// maintenanceBlocking reports whether new rooms are refused for an upcoming or ongoing
+// maintenance window
+func (s *Server) maintenanceBlocking() bool {
+	block := time.Duration(s.settings().Maintenance.BlockMinutes) * time.Minute
+	_, blocking := s.maintenance.blocking(time.Now(), block)
+	return blocking
+}
+
+// runMaintenance warns active rooms of upcoming maintenance windows, drains the node
+// before windows that ask for it and ends windows once they are over
+func (s *Server) runMaintenance() {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.checkMaintenance(time.Now())
+	}
+}
+
+// checkMaintenance does what maintenance windows call for at a time
+func (s *Server) checkMaintenance(now time.Time) {
+	settings := s.settings().Maintenance
+	block := time.Duration(settings.BlockMinutes) * time.Minute
+
+	type action struct {
+		window maintenanceWindow
+		warn   bool
+		start  bool
+		drain  bool
+		end    bool
+	}
+
+	var actions []action
+	s.maintenance.mu.Lock()
+	for id, window := range s.maintenance.windows {
+		a := action{}
+		if !now.Before(window.EndsAt) {
+			delete(s.maintenance.windows, id)
+			a.end = true
+		} else if !now.Before(window.StartsAt) {
+			a.start = !window.started
+			window.started = true
+		} else {
+			// Offsets due at once, such as for a window scheduled at short notice, send one warning
+			for _, minutes := range settings.WarningMinutes {
+				if !window.warned[minutes] && !now.Before(window.StartsAt.Add(-time.Duration(minutes)*time.Minute)) {
+					window.warned[minutes] = true
+					a.warn = true
+				}
+			}
+		}
+		if window.Drain && !window.draining && !a.end && !now.Before(window.StartsAt.Add(-block)) {
+			window.draining = true
+			a.drain = true
+		}
+		a.window = *window
+		if a.warn || a.start || a.drain || a.end {
+			actions = append(actions, a)
+		}
+	}
+	s.maintenance.mu.Unlock()
+
+	for _, a := range actions {
+		window := a.window
+		if a.drain && !s.startDrain(window.StartsAt, true) {
+			// An operator's drain is under way and stays theirs; retry at the next check
+			s.maintenance.mu.Lock()
+			if current, exists := s.maintenance.windows[window.ID]; exists {
+				current.draining = false
+			}
+			s.maintenance.mu.Unlock()
+			window.draining = false
+		}
+		if a.warn {
+			s.announceMaintenance(window, now)
+		}
+		if a.start {
+			serverLog.Infof("Maintenance window %s started", window.ID)
+			s.publishEvent(events.MaintenanceStarted, "", maintenanceEventData(window))
+		}
+		if a.end {
+			if window.draining {
+				s.cancelDrain()
+			}
+			serverLog.Infof("Maintenance window %s ended", window.ID)
+			s.publishEvent(events.MaintenanceEnded, "", maintenanceEventData(window))
+		}
+	}
+}
+
+// announceMaintenance sends a "maintenance" countdown message to every room
+func (s *Server) announceMaintenance(window maintenanceWindow, now time.Time) {
+	notice := maintenanceNotice{
+		ID:          window.ID,
+		StartsAt:    window.StartsAt,
+		EndsAt:      window.EndsAt,
+		MinutesLeft: int(math.Ceil(window.StartsAt.Sub(now).Minutes())),
+		Message:     window.Message,
+	}
+
+	s.roomManager.Mu.RLock()
+	roomIDs := make([]string, 0, len(s.roomManager.Rooms))
+	for roomID := range s.roomManager.Rooms {
+		roomIDs = append(roomIDs, roomID)
+	}
+	s.roomManager.Mu.RUnlock()
+
+	for _, roomID := range roomIDs {
+		s.hub.Publish(roomID, "maintenance", notice)
+	}
+}
+
+// maintenanceEventData describes a maintenance window in server events
+func maintenanceEventData(window maintenanceWindow) map[string]interface{} {
+	return map[string]interface{}{
+		"maintenance_id": window.ID,
+		"starts_at":      window.StartsAt,
+		"ends_at":        window.EndsAt,
+		"message":        window.Message,
+	}
+}
+
+// maintenanceHandler lists upcoming and ongoing maintenance windows so clients can show
+// them ahead of time; it needs no authentication
+func (s *Server) maintenanceHandler(c *gin.Context) {
+	windows := s.maintenance.list()
+	for i := range windows {
+		windows[i].CreatedBy = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"windows": windows})
+}
+
+// adminScheduleMaintenanceHandler schedules a maintenance window
+func (s *Server) adminScheduleMaintenanceHandler(c *gin.Context) {
+	var req struct {
+		StartsAt        time.Time `json:"starts_at" binding:"required"`
+		DurationMinutes int       `json:"duration_minutes" binding:"required"`
+		Message         string    `json:"message" binding:"max=500"`
+		Drain           *bool     `json:"drain"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.DurationMinutes < 1 || req.DurationMinutes > maxMeetingMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, errInvalidDuration.Error())})
+		return
+	}
+	if !req.StartsAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "starts_at must be in the future")})
+		return
+	}
+
+	window := &maintenanceWindow{
+		ID:        uuid.New().String(),
+		StartsAt:  req.StartsAt.UTC(),
+		EndsAt:    req.StartsAt.UTC().Add(time.Duration(req.DurationMinutes) * time.Minute),
+		Message:   req.Message,
+		Drain:     req.Drain == nil || *req.Drain,
+		CreatedBy: c.GetString("user_id"),
+		CreatedAt: time.Now(),
+		warned:    make(map[int]bool),
+	}
+
+	s.maintenance.mu.Lock()
+	for _, other := range s.maintenance.windows {
+		if window.StartsAt.Before(other.EndsAt) && other.StartsAt.Before(window.EndsAt) {
+			s.maintenance.mu.Unlock()
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Maintenance window overlaps another one")})
+			return
+		}
+	}
+	s.maintenance.windows[window.ID] = window
+	s.maintenance.mu.Unlock()
+
+	s.recordAudit(c, "maintenance.schedule", window.ID, map[string]string{
+		"starts_at":        window.StartsAt.Format(time.RFC3339),
+		"duration_minutes": strconv.Itoa(req.DurationMinutes),
+		"drain":            strconv.FormatBool(window.Drain),
+	})
+	s.publishEvent(events.MaintenanceScheduled, "", maintenanceEventData(*window))
+
+	// Announce at once rather than at the next check
+	s.checkMaintenance(time.Now())
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Maintenance scheduled",
+		"maintenance": window,
+	})
+}
+
+// adminListMaintenanceHandler lists upcoming and ongoing maintenance windows
+func (s *Server) adminListMaintenanceHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"windows":  s.maintenance.list(),
+		"settings": s.settings().Maintenance,
+	})
+}
+
+// adminCancelMaintenanceHandler cancels a maintenance window, resuming room creation and
+// cancelling the drain it started
+func (s *Server) adminCancelMaintenanceHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	s.maintenance.mu.Lock()
+	window, exists := s.maintenance.windows[id]
+	if exists {
+		delete(s.maintenance.windows, id)
+	}
+	s.maintenance.mu.Unlock()
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Maintenance window not found")})
+		return
+	}
+
+	if window.draining {
+		s.cancelDrain()
+	}
+
+	s.recordAudit(c, "maintenance.cancel", id, nil)
+	s.publishEvent(events.MaintenanceEnded, "", maintenanceEventData(*window))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance cancelled"})
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+// keyframeInterval is how often publishers of forwarded video are asked for a keyframe
+const keyframeInterval = 3 * time.Second
+
+// maxSequenceGap is the largest jump in RTP sequence numbers counted as loss; larger
+// jumps are reordering or a restarted stream
+const maxSequenceGap = 1000
+
+// publishTrack registers a server-side track in the room and attaches it to every other participant
+func (s *Server) publishTrack(room *models.Room, ownerID string, track webrtc.TrackLocal) *models.PublishedTrack {
+	published := &models.PublishedTrack{
+		ID:       track.ID(),
+		ClientID: ownerID,
+		Kind:     track.Kind().String(),
+		Track:    track,
+		Senders:  make(map[string]*webrtc.RTPSender),
+		Relays:   make(map[string]*webrtc.PeerConnection),
+	}
+
+	s.roomRun(room, func(loop *roomLoop) {
+		var subscribers []*models.Client
+		loop.update(room, func() {
+			if owner, exists := room.Clients[ownerID]; exists {
+				published.Source = owner.TrackSources[published.ID]
+			}
+			room.Tracks[published.ID] = published
+			s.metrics.AddTracksActive(room.ID, published.Kind, 1)
+			// Tracks of participants on hold reach the room once they are resumed
+			if owner, exists := room.Clients[ownerID]; !exists || !onHold(owner) {
+				for clientID, client := range room.Clients {
+					if clientID != ownerID && client.Conn != nil && !onHold(client) && canSubscribe(client) {
+						subscribers = append(subscribers, client)
+					}
+				}
+			}
+		})
+
+		for _, client := range subscribers {
+			s.attachTrack(loop, room, published, client)
+		}
+	})
+
+	return published
+}
+
+// unpublishTrack removes a server-side track from the room and from all subscribers
+func (s *Server) unpublishTrack(room *models.Room, published *models.PublishedTrack) {
+	s.roomRun(room, func(loop *roomLoop) {
+		var senders map[string]*webrtc.RTPSender
+		var relays map[string]*webrtc.PeerConnection
+		subscribers := make(map[string]*models.Client)
+		loop.update(room, func() {
+			delete(room.Tracks, published.ID)
+			s.metrics.AddTracksActive(room.ID, published.Kind, -1)
+			senders = published.Senders
+			published.Senders = make(map[string]*webrtc.RTPSender)
+			relays = published.Relays
+			published.Relays = make(map[string]*webrtc.PeerConnection)
+			for clientID := range senders {
+				if client, ok := room.Clients[clientID]; ok {
+					subscribers[clientID] = client
+				}
+			}
+		})
+
+		for clientID, client := range subscribers {
+			if err := client.Conn.RemoveTrack(senders[clientID]); err != nil {
+				sfuLog.Errorf("Failed to remove track %s from client %s: %v", published.ID, clientID, err)
+				continue
+			}
+			s.renegotiate(client)
+		}
+
+		// Stop relaying the track to other nodes
+		for relayID, pc := range relays {
+			if err := pc.Close(); err != nil {
+				sfuLog.Errorf("Failed to close relay %s: %v", relayID, err)
+			}
+		}
+	})
+}
+
+// subscribeToRoomTracks attaches all tracks already published in the room to a new
+// participant, except those of participants on hold
+func (s *Server) subscribeToRoomTracks(loop *roomLoop, room *models.Room, client *models.Client) {
+	if !canSubscribe(client) {
+		return
+	}
+
+	room.Mu.RLock()
+	if onHold(client) {
+		room.Mu.RUnlock()
+		return
+	}
+	var tracks []*models.PublishedTrack
+	for _, published := range room.Tracks {
+		if owner, exists := room.Clients[published.ClientID]; published.ClientID != client.ID && (!exists || !onHold(owner)) {
+			tracks = append(tracks, published)
+		}
+	}
+	room.Mu.RUnlock()
+
+	for _, published := range tracks {
+		s.attachTrack(loop, room, published, client)
+	}
+}
+
+// attachTrack adds a published track to a participant's peer connection and renegotiates
+func (s *Server) attachTrack(loop *roomLoop, room *models.Room, published *models.PublishedTrack, client *models.Client) {
+	sender, err := client.Conn.AddTrack(published.Track)
+	if err != nil {
+		sfuLog.Errorf("Failed to add track %s to client %s: %v", published.ID, client.ID, err)
+		return
+	}
+
+	loop.update(room, func() {
+		published.Senders[client.ID] = sender
+	})
+
+	// Read incoming RTCP so interceptors (NACK, reports) keep working
+	go func() {
+		for {
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				return
+			}
+			for _, packet := range packets {
+				switch packet.(type) {
+				case *rtcp.TransportLayerNack:
+					s.metrics.IncrementNacks(room.ID)
+				case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+					s.metrics.IncrementPLIs(room.ID, "received")
+				}
+			}
+		}
+	}()
+
+	s.renegotiate(client)
+}
+
+// forwardRemoteTrack republishes a track received from a participant to the rest of the room
+func (s *Server) forwardRemoteTrack(room *models.Room, ownerID string, remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver, pc *webrtc.PeerConnection) {
+	local, err := webrtc.NewTrackLocalStaticRTP(remote.Codec().RTPCodecCapability, remote.ID(), remote.StreamID())
+	if err != nil {
+		sfuLog.Errorf("Failed to create forwarding track for %s: %v", ownerID, err)
+		return
+	}
+
+	published := s.publishTrack(room, ownerID, local)
+	defer s.unpublishTrack(room, published)
+
+	// Ask the publisher for keyframes periodically so new subscribers can start decoding
+	done := make(chan struct{})
+	defer close(done)
+	if remote.Kind() == webrtc.RTPCodecTypeVideo && pc != nil {
+		go func() {
+			ticker := time.NewTicker(keyframeInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if err := pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}); err != nil {
+						return
+					}
+					s.metrics.IncrementPLIs(room.ID, "sent")
+				}
+			}
+		}()
+	}
+
+	// Measure talk and camera time for the participant analytics
+	activity := newMediaActivity(published.Kind, audioLevelID(receiver))
+	defer s.recordActivity(room, ownerID, published, activity)
+
+	var lastSequence uint16
+	for received := 0; ; received++ {
+		packet, _, err := remote.ReadRTP()
+		if err != nil {
+			return
+		}
+		if activity.observe(packet, time.Now()) {
+			s.recordActivity(room, ownerID, published, activity)
+		}
+
+		// A forward jump in sequence numbers means packets were lost on the way in
+		if gap := packet.SequenceNumber - lastSequence - 1; received > 0 && gap > 0 && gap < maxSequenceGap {
+			s.metrics.AddPacketsDropped(room.ID, "lost", int(gap))
+		}
+		lastSequence = packet.SequenceNumber
+
+		if s.recorder.IsRecording(room.ID) {
+			room.Mu.RLock()
+			source := published.Source
+			participant := ""
+			held := false
+			if owner, exists := room.Clients[ownerID]; exists {
+				participant = owner.DisplayName
+				held = onHold(owner)
+			}
+			room.Mu.RUnlock()
+			// Participants on hold are not part of the call and are not recorded
+			if !held {
+				s.recorder.WriteRTP(room.ID, recording.Track{
+					ID:          published.ID,
+					Kind:        published.Kind,
+					Source:      source,
+					MimeType:    remote.Codec().MimeType,
+					Participant: participant,
+				}, packet)
+			}
+		}
+		if err := local.WriteRTP(packet); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			s.metrics.AddPacketsDropped(room.ID, "write_error", 1)
+			sfuLog.Errorf("Failed to forward track %s: %v", published.ID, err)
+			return
+		}
+		s.metrics.ObserveForwardedPacket(room.ID, published.Kind, len(packet.Payload))
+	}
+}
+
+// renegotiate creates a new offer for a participant and sends it over signaling. One
+// offer is outstanding at a time: while the participant has not answered, the next
+// offer is made once the answer arrives.
+func (s *Server) renegotiate(client *models.Client) {
+	if client.Conn.SignalingState() != webrtc.SignalingStateStable {
+		atomic.StoreInt32(&client.OfferPending, 1)
+		return
+	}
+
+	offer, err := client.Conn.CreateOffer(nil)
+	if err != nil {
+		sfuLog.Errorf("Failed to create offer for client %s: %v", client.ID, err)
+		return
+	}
+
+	if err := client.Conn.SetLocalDescription(offer); err != nil {
+		sfuLog.Errorf("Failed to set local description for client %s: %v", client.ID, err)
+		return
+	}
+
+	s.sendSignal(client, models.SignalMessage{
+		Type:      "offer",
+		Data:      offer,
+		Timestamp: time.Now(),
+	})
+}
+
+// offerOnJoin sends the server's offer to a participant whose signaling connection
+// joined the room. An offer made before the WebSocket connected was not delivered, so
+// it is sent again, with the candidates gathered since.
+func (s *Server) offerOnJoin(client *models.Client) {
+	switch {
+	case client.Conn.SignalingState() == webrtc.SignalingStateHaveLocalOffer:
+		s.sendSignal(client, models.SignalMessage{
+			Type:      "offer",
+			Data:      client.Conn.LocalDescription(),
+			Timestamp: time.Now(),
+		})
+	case len(client.Conn.GetTransceivers()) > 0:
+		s.renegotiate(client)
+	}
+}
+
+// applyServerSignal applies a participant's "server-answer" or "server-candidate" to
+// its peer connection with the server
+func (s *Server) applyServerSignal(client *models.Client, payload websocket.Payload) {
+	switch payload := payload.(type) {
+	case *websocket.SDPPayload:
+		if payload.SDP.Type != webrtc.SDPTypeAnswer.String() {
+			sfuLog.Warnf("Ignoring %s from client %s: the server only takes answers", payload.SDP.Type, client.ID)
+			return
+		}
+		answer := webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: payload.SDP.SDP}
+		if err := client.Conn.SetRemoteDescription(answer); err != nil {
+			sfuLog.Warnf("Failed to apply answer of client %s: %v", client.ID, err)
+			return
+		}
+
+		// Send the offer held back while this one was outstanding
+		if atomic.SwapInt32(&client.OfferPending, 0) == 1 {
+			s.renegotiate(client)
+		}
+	case *websocket.ICECandidatePayload:
+		candidate := webrtc.ICECandidateInit{
+			Candidate:        payload.Candidate.Candidate,
+			SDPMid:           payload.Candidate.SDPMid,
+			SDPMLineIndex:    payload.Candidate.SDPMLineIndex,
+			UsernameFragment: payload.Candidate.UsernameFragment,
+		}
+		if err := client.Conn.AddICECandidate(candidate); err != nil {
+			sfuLog.Warnf("Failed to add ICE candidate of client %s: %v", client.ID, err)
+		}
+	}
+}
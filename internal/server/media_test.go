@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/repository"
+	"github.com/zubans/video-call-server/internal/websocket"
+	"github.com/zubans/video-call-server/pkg/client"
+)
+
+// newTestServer starts a server on a local listener
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	return newTestServerWith(t, nil)
+}
+
+// newTestServerWith starts a server on a local listener that persists to repos and
+// loads its state from them, as after a restart; nil keeps in-memory repositories
+func newTestServerWith(t *testing.T, repos *repository.Repositories) (*Server, *httptest.Server) {
+	t.Helper()
+	t.Setenv("RECORDINGS_DIR", t.TempDir())
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	s := NewServer()
+	if repos != nil {
+		s.repos = *repos
+		s.loadState()
+	}
+	s.Initialize()
+	ts := httptest.NewServer(s.router)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// TestServerPeerConnectionLoopback connects a pion client to its peer connection with
+// the server: the server offers a published track, the client answers with
+// "server-answer" and both sides exchange candidates until connected
+func TestServerPeerConnectionLoopback(t *testing.T) {
+	s, ts := newTestServer(t)
+	ctx := context.Background()
+
+	api := client.New(ts.URL)
+	if err := api.Register(ctx, "loopback", "loopback@example.com", "Passw0rd!23"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := api.Login(ctx, "loopback", "Passw0rd!23"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	created, err := api.CreateRoom(ctx, "loopback")
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+
+	// A server-side track gives the server something to offer
+	room, _ := s.getRoom(created.ID)
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "loopback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.publishTrack(room, "server", track)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// The offer made on join is sent again once the WebSocket joins
+	body, _ := json.Marshal(map[string]string{"room_id": created.ID})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/join-room", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", api.Token())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("join room: %v", err)
+	}
+	var joined struct {
+		ClientID string `json:"client_id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&joined)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("join room: status %d", resp.StatusCode)
+	}
+
+	query := url.Values{"token": {api.Token()}, "room_id": {created.ID}}
+	conn, _, err := gorilla.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(ts.URL, "http")+"/ws?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	writes := make(chan []byte, 64)
+	go func() {
+		for {
+			select {
+			case message := <-writes:
+				conn.WriteMessage(gorilla.TextMessage, message)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	send := func(msgType string, payload interface{}) {
+		data, _ := websocket.EncodeEnvelope(websocket.ProtocolVersion, msgType, payload)
+		select {
+		case writes <- data:
+		case <-ctx.Done():
+		}
+	}
+	sender := websocket.RoomPayload{RoomID: created.ID, SenderID: joined.ClientID}
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	connected := make(chan struct{}, 1)
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateConnected {
+			select {
+			case connected <- struct{}{}:
+			default:
+			}
+		}
+	})
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		init := candidate.ToJSON()
+		send("server-candidate", &websocket.ICECandidatePayload{RoomPayload: sender, Candidate: &websocket.ICECandidate{
+			Candidate:     init.Candidate,
+			SDPMid:        init.SDPMid,
+			SDPMLineIndex: init.SDPMLineIndex,
+		}})
+	})
+
+	send("join", &sender)
+
+	// Answer the server's offer and apply its candidates
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			var env websocket.Envelope
+			for decoder.Decode(&env) == nil {
+				if env.Seq != 0 {
+					send("ack", &websocket.AckPayload{Seq: env.Seq})
+				}
+				if env.Type != "signal" {
+					continue
+				}
+				var signal struct {
+					Type string          `json:"type"`
+					Data json.RawMessage `json:"data"`
+				}
+				json.Unmarshal(env.Payload, &signal)
+
+				switch signal.Type {
+				case "offer":
+					var offer webrtc.SessionDescription
+					json.Unmarshal(signal.Data, &offer)
+					if err := pc.SetRemoteDescription(offer); err != nil {
+						t.Errorf("set remote description: %v", err)
+						return
+					}
+					answer, _ := pc.CreateAnswer(nil)
+					pc.SetLocalDescription(answer)
+					send("server-answer", &websocket.SDPPayload{RoomPayload: sender, SDP: &websocket.SessionDescription{
+						Type: answer.Type.String(),
+						SDP:  answer.SDP,
+					}})
+				case "ice-candidate":
+					var candidate webrtc.ICECandidateInit
+					json.Unmarshal(signal.Data, &candidate)
+					pc.AddICECandidate(candidate)
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-connected:
+	case <-ctx.Done():
+		t.Fatalf("client peer connection did not connect: %s", pc.ConnectionState())
+	}
+
+	room.Mu.RLock()
+	participant := room.Clients[joined.ClientID]
+	room.Mu.RUnlock()
+	for participant.Conn.ConnectionState() != webrtc.PeerConnectionStateConnected {
+		if ctx.Err() != nil {
+			t.Fatalf("server peer connection did not connect: %s", participant.Conn.ConnectionState())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(pc.GetReceivers()) == 0 {
+		t.Error("the published track was not offered to the client")
+	}
+}
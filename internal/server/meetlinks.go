@@ -0,0 +1,315 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/auth"
+)
+
+const (
+	// defaultLinkTokenTTL is how long one-time link tokens stay redeemable without ttl_seconds
+	defaultLinkTokenTTL = 24 * time.Hour
+
+	// maxLinkTokenTTL caps the lifetime of one-time link tokens
+	maxLinkTokenTTL = 7 * 24 * time.Hour
+)
+
+// linkToken is a one-time token embedded in a meeting link, exchanged for a room token
+type linkToken struct {
+	roomID   string
+	username string
+	grant    auth.RoomGrant
+	expires  time.Time
+}
+
+// linkTokenStore keeps unredeemed link tokens
+type linkTokenStore struct {
+	tokens map[string]*linkToken
+	mu     sync.Mutex
+}
+
+// newLinkTokenStore creates an empty linkTokenStore
+func newLinkTokenStore() *linkTokenStore {
+	return &linkTokenStore{
+		tokens: make(map[string]*linkToken),
+	}
+}
+
+// issue stores a link token and returns its secret
+func (st *linkTokenStore) issue(token *linkToken) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	secret := hex.EncodeToString(b)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	for k, other := range st.tokens {
+		if now.After(other.expires) {
+			delete(st.tokens, k)
+		}
+	}
+	st.tokens[secret] = token
+	return secret
+}
+
+// valid reports whether a token can still be redeemed for a room
+func (st *linkTokenStore) valid(secret, roomID string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	token, exists := st.tokens[secret]
+	return exists && token.roomID == roomID && time.Now().Before(token.expires)
+}
+
+// redeem uses up a token of a room
+func (st *linkTokenStore) redeem(secret, roomID string) (*linkToken, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	token, exists := st.tokens[secret]
+	if !exists || token.roomID != roomID || time.Now().After(token.expires) {
+		return nil, false
+	}
+	delete(st.tokens, secret)
+	return token, true
+}
+
+// meetingLinks returns the shareable URL of a meeting and, with MEETING_APP_SCHEME,
+// a deep link opening it in the native app
+func meetingLinks(code, token string) gin.H {
+	path := "/meet/" + url.PathEscape(code)
+	if token != "" {
+		path += "?t=" + url.QueryEscape(token)
+	}
+
+	base := envString("MEETING_URL_BASE", os.Getenv("NODE_URL"))
+	links := gin.H{"url": strings.TrimSuffix(base, "/") + path}
+	if scheme := os.Getenv("MEETING_APP_SCHEME"); scheme != "" {
+		links["app_url"] = scheme + ":/" + path
+	}
+	return links
+}
+
+// createMeetingLinkHandler returns a shareable link to a room; with one_time it
+// carries a token admitting a single participant without an account
+func (s *Server) createMeetingLinkHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if room.CreatorID != c.GetString("user_id") && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+
+	var req struct {
+		OneTime      bool   `json:"one_time"`
+		Username     string `json:"username"`
+		CanPublish   *bool  `json:"can_publish"`
+		CanSubscribe *bool  `json:"can_subscribe"`
+		CanChat      *bool  `json:"can_chat"`
+		IsHost       bool   `json:"is_host"`
+		TTLSeconds   int64  `json:"ttl_seconds"`
+	}
+
+	// The body is optional: without it the link only carries the join code
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+
+	room.Mu.RLock()
+	code := room.JoinCode
+	room.Mu.RUnlock()
+
+	if !req.OneTime {
+		c.JSON(http.StatusCreated, gin.H{
+			"message":   "Meeting link created",
+			"join_code": code,
+			"links":     meetingLinks(code, ""),
+		})
+		return
+	}
+
+	ttl := defaultLinkTokenTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	if ttl > maxLinkTokenTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "ttl_seconds must not exceed 604800")})
+		return
+	}
+
+	expires := time.Now().Add(ttl)
+	token := s.linkTokens.issue(&linkToken{
+		roomID:   room.ID,
+		username: strings.TrimSpace(req.Username),
+		grant: auth.RoomGrant{
+			RoomID:       room.ID,
+			CanPublish:   req.CanPublish == nil || *req.CanPublish,
+			CanSubscribe: req.CanSubscribe == nil || *req.CanSubscribe,
+			CanChat:      req.CanChat == nil || *req.CanChat,
+			IsHost:       req.IsHost,
+		},
+		expires: expires,
+	})
+
+	s.recordAudit(c, "room.link_create", room.ID, map[string]string{"one_time": "true"})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Meeting link created",
+		"join_code":  code,
+		"token":      token,
+		"expires_at": expires,
+		"links":      meetingLinks(code, token),
+	})
+}
+
+// meetingRoom finds the room of a meeting link code; replies 400 or 404 when there is none
+func (s *Server) meetingRoom(c *gin.Context) (string, bool) {
+	code := normalizeJoinCode(c.Param("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "code must look like abc-defg-hij")})
+		return "", false
+	}
+	room, exists := s.roomByCode(code)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return "", false
+	}
+	return room.ID, true
+}
+
+// meetingInfoHandler describes the meeting behind a link for pre-join screens: room
+// name, host and scheduled time. It needs no authentication, since the join code is
+// what is shared; with t it also tells whether the link's token can still be used.
+func (s *Server) meetingInfoHandler(c *gin.Context) {
+	roomID, ok := s.meetingRoom(c)
+	if !ok {
+		return
+	}
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	room.Mu.RLock()
+	info := gin.H{
+		"room_id":           room.ID,
+		"name":              room.Name,
+		"join_code":         room.JoinCode,
+		"is_active":         room.IsActive,
+		"participant_count": len(room.Clients),
+		"lobby":             room.Settings.Lobby,
+		"links":             meetingLinks(room.JoinCode, ""),
+	}
+	if schedule := viewSchedule(room.Schedule); schedule != nil {
+		info["schedule"] = gin.H{
+			"starts_at":        schedule.StartsAt,
+			"starts_at_local":  schedule.StartsAtLocal,
+			"ends_at":          schedule.EndsAt,
+			"timezone":         schedule.Timezone,
+			"duration_minutes": schedule.DurationMinutes,
+		}
+	}
+	creatorID := room.CreatorID
+	room.Mu.RUnlock()
+
+	displayName, avatarURL := profile(creatorID, creatorID)
+	info["host"] = gin.H{
+		"display_name": displayName,
+		"avatar_url":   avatarURL,
+	}
+	if slug := s.orgSlug(creatorID); slug != "" {
+		info["org_slug"] = slug
+	}
+	if token := c.Query("t"); token != "" {
+		info["token_valid"] = s.linkTokens.valid(token, roomID)
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// redeemMeetingLinkHandler exchanges the one-time token of a meeting link for a room
+// token; the link token cannot be used again
+func (s *Server) redeemMeetingLinkHandler(c *gin.Context) {
+	roomID, ok := s.meetingRoom(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Username string `json:"username"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	token, ok := s.linkTokens.redeem(req.Token, roomID)
+	if !ok {
+		c.JSON(http.StatusGone, gin.H{"error": tr(c, "Meeting link has expired or was already used")})
+		return
+	}
+
+	// The name chosen by the host takes precedence over the one typed by the guest
+	username := token.username
+	if username == "" {
+		username = strings.TrimSpace(req.Username)
+	}
+	if username == "" {
+		username = "Guest"
+	}
+
+	// The room token lasts until the end of a scheduled meeting
+	ttl := auth.Policy(auth.TokenGuest).TTL
+	if room, exists := s.getRoom(roomID); exists {
+		room.Mu.RLock()
+		if room.Schedule != nil {
+			if untilEnd := time.Until(scheduleEnd(room.Schedule)); untilEnd > ttl {
+				ttl = untilEnd
+			}
+		}
+		room.Mu.RUnlock()
+	}
+	if ttl > maxRoomTokenTTL {
+		ttl = maxRoomTokenTTL
+	}
+
+	userID := "guest_" + uuid.New().String()
+	tenant, _ := s.roomTenant(roomID)
+	roomToken, err := auth.GenerateRoomJWT(userID, username, tenant, token.grant, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to generate token")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Meeting link redeemed",
+		"token":      roomToken,
+		"room_id":    roomID,
+		"user_id":    userID,
+		"grant":      token.grant,
+		"expires_at": time.Now().Add(ttl),
+	})
+}
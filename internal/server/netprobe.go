@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/netprobe"
+)
+
+// newProber starts the network probe listeners on NETWORK_PROBE_UDP_ADDR and
+// NETWORK_PROBE_TCP_ADDR; without either, probing is disabled and nil is returned
+func newProber() *netprobe.Prober {
+	prober, err := netprobe.NewProber(os.Getenv("NETWORK_PROBE_UDP_ADDR"), os.Getenv("NETWORK_PROBE_TCP_ADDR"))
+	if err != nil {
+		serverLog.Errorf("Network probe disabled: %v", err)
+		return nil
+	}
+	return prober
+}
+
+// probeAddress returns the address clients send probe packets to. The host is
+// NETWORK_PROBE_HOST, or the host the client reached the API on.
+func probeAddress(c *gin.Context, port int) string {
+	if port == 0 {
+		return ""
+	}
+	host := os.Getenv("NETWORK_PROBE_HOST")
+	if host == "" {
+		host = c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// createNetworkProbeHandler starts a connectivity probe for the caller and tells it
+// where and how to send probe packets; a new probe finishes the caller's previous one
+func (s *Server) createNetworkProbeHandler(c *gin.Context) {
+	if s.probes == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Network probe is not configured")})
+		return
+	}
+
+	result := s.probes.Create(c.MustGet("user_id").(string), c.ClientIP())
+
+	response := gin.H{
+		"id":          result.ID,
+		"expires_at":  result.StartedAt.Add(netprobe.TTL),
+		"packets":     netprobe.Packets,
+		"interval_ms": netprobe.Interval.Milliseconds(),
+	}
+	if udp := probeAddress(c, s.probes.UDPPort()); udp != "" {
+		response["udp"] = udp
+	}
+	if tcp := probeAddress(c, s.probes.TCPPort()); tcp != "" {
+		response["tcp"] = tcp
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// getNetworkProbeHandler returns what a probe has measured so far and the
+// recommended way to join: video, audio-only or relay
+func (s *Server) getNetworkProbeHandler(c *gin.Context) {
+	if s.probes == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Network probe is not configured")})
+		return
+	}
+
+	result, exists := s.probes.Result(c.Param("id"), c.MustGet("user_id").(string))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Network probe not found")})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// adminNetworkProbesHandler aggregates finished network probes for troubleshooting
+func (s *Server) adminNetworkProbesHandler(c *gin.Context) {
+	if s.probes == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Network probe is not configured")})
+		return
+	}
+	c.JSON(http.StatusOK, s.probes.Summary())
+}
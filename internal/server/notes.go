@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/notes"
+	"github.com/zubans/video-call-server/internal/notify/email"
+)
+
+// errNoTranscript is returned for recordings without a transcript to summarize
+var errNoTranscript = errors.New("recording has no transcript")
+
+// newSummarizer creates the meeting notes backend named by NOTES_BACKEND with
+// NOTES_URL, NOTES_API_KEY and NOTES_MODEL. Without a backend, meeting notes are
+// disabled and nil is returned.
+func newSummarizer() notes.Summarizer {
+	backend := os.Getenv("NOTES_BACKEND")
+	if backend == "" {
+		return nil
+	}
+
+	summarizer, err := notes.New(backend, notes.Config{
+		URL:    os.Getenv("NOTES_URL"),
+		APIKey: os.Getenv("NOTES_API_KEY"),
+		Model:  os.Getenv("NOTES_MODEL"),
+	})
+	if err != nil {
+		serverLog.Errorf("Meeting notes disabled: %v", err)
+		return nil
+	}
+	return summarizer
+}
+
+// generateNotes summarizes the transcript of a recording into meeting notes,
+// attaches them to the room, publishes room.notes_ready and emails the notes to the
+// participants of the recorded call
+func (s *Server) generateNotes(recordingID string) (*notes.Notes, error) {
+	rec, exists := s.recorder.GetRecording(recordingID)
+	if !exists {
+		return nil, errors.New("recording not found")
+	}
+	path, exists := s.recorder.TranscriptPath(recordingID)
+	if !exists {
+		return nil, errNoTranscript
+	}
+	file, err := s.recorder.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	transcript, err := notes.ReadTranscript(path, file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// The owner gets the notes even if they did not attend
+	attendees := s.recordingAttendees(rec)
+	if rec.OwnerID != "" && !slices.Contains(attendees, rec.OwnerID) {
+		attendees = append([]string{rec.OwnerID}, attendees...)
+	}
+	names := make([]string, len(attendees))
+	for i, userID := range attendees {
+		names[i], _ = profile(userID, userID)
+	}
+
+	name := s.roomName(rec.RoomID)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(envInt64("NOTES_TIMEOUT_SECONDS", 120))*time.Second)
+	defer cancel()
+	summary, err := s.summarizer.Summarize(ctx, notes.Meeting{
+		Title:        name,
+		Participants: names,
+		Language:     s.settings().DefaultLanguage,
+	}, transcript)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := s.notes.Add(rec.RoomID, rec.ID, summary, attendees)
+
+	// The notes are part of the room's representation
+	if room, exists := s.getRoom(rec.RoomID); exists {
+		s.roomUpdate(room, func() {
+			room.Version++
+		})
+	}
+
+	s.publishEvent(events.NotesReady, rec.RoomID, map[string]interface{}{
+		"recording_id": rec.ID,
+		"name":         name,
+		"notes":        generated,
+	})
+	for _, userID := range attendees {
+		s.sendEmail(userID, email.TemplateMeetingNotes, map[string]interface{}{
+			"Room":        name,
+			"Summary":     generated.Summary,
+			"ActionItems": generated.ActionItems,
+		})
+	}
+
+	recordingLog.Infof("Generated meeting notes for recording %s with %d action items", rec.ID, len(generated.ActionItems))
+	return generated, nil
+}
+
+// summarizeRecording generates the meeting notes of a processed recording that has a
+// transcript, when a notes backend is configured and notes are enabled for its tenant
+func (s *Server) summarizeRecording(recordingID string) {
+	if s.summarizer == nil {
+		return
+	}
+	if rec, exists := s.recorder.GetRecording(recordingID); exists && !s.featureEnabled(rec.TenantID, featureNotes) {
+		return
+	}
+	if _, err := s.generateNotes(recordingID); err != nil && !errors.Is(err, errNoTranscript) {
+		recordingLog.Errorf("Failed to generate meeting notes for recording %s: %v", recordingID, err)
+	}
+}
+
+// visibleNotes returns the notes of a room the user may read, newest first: all of
+// them for hosts, otherwise those of calls the user attended
+func (s *Server) visibleNotes(c *gin.Context, room *models.Room) []*notes.Notes {
+	userID := c.GetString("user_id")
+	host := userID == s.roomOwner(room.ID) || c.GetString("role") == auth.RoleAdmin
+
+	visible := []*notes.Notes{}
+	for _, n := range s.notes.ForRoom(room.ID) {
+		if host || slices.Contains(n.Attendees, userID) {
+			visible = append(visible, n)
+		}
+	}
+	return visible
+}
+
+// listNotesHandler lists the meeting notes of a room
+func (s *Server) listNotesHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes": s.visibleNotes(c, room),
+	})
+}
+
+// generateNotesHandler (re)generates the meeting notes of a recording of the room,
+// e.g. after its transcript was added; allowed for the creator and admins
+func (s *Server) generateNotesHandler(c *gin.Context) {
+	var req struct {
+		RecordingID string `json:"recording_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if s.summarizer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Meeting notes are not configured")})
+		return
+	}
+
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if c.GetString("user_id") != s.roomOwner(room.ID) && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+
+	rec, exists := s.recorder.GetRecording(req.RecordingID)
+	if !exists || rec.RoomID != room.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return
+	}
+	if rec.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is still in progress")})
+		return
+	}
+	if _, exists := s.recorder.TranscriptPath(rec.ID); !exists {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has no transcript")})
+		return
+	}
+
+	// Backends may take minutes; the notes arrive as room.notes_ready
+	go func() {
+		if _, err := s.generateNotes(rec.ID); err != nil {
+			recordingLog.Errorf("Failed to generate meeting notes for recording %s: %v", rec.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Generating meeting notes",
+		"recording_id": rec.ID,
+	})
+}
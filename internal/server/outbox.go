@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/broker"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/repository"
+	"github.com/zubans/video-call-server/internal/webhooks"
+)
+
+const (
+	// outboxPollInterval is how often the outbox is checked for deliveries that are due
+	outboxPollInterval = time.Second
+
+	// outboxBatchSize is how many entries of a destination are read at a time
+	outboxBatchSize = 100
+
+	// outboxDeliveryTimeout bounds each delivery attempt
+	outboxDeliveryTimeout = 15 * time.Second
+
+	// outboxMaxBackoff caps the delay between delivery attempts
+	outboxMaxBackoff = time.Hour
+)
+
+// Outbox destinations; webhook destinations are followed by the endpoint URL
+const (
+	outboxBroker  = "broker"
+	outboxWebhook = "webhook:"
+)
+
+// outbox delivers server events to webhook endpoints and the message broker. Events are
+// queued in the outbox repository in the transaction of the state change they announce,
+// then delivered in order, per destination, with retries.
+type outbox struct {
+	broker      *broker.Forwarder    // nil without EVENT_BROKER
+	webhooks    *webhooks.Dispatcher // nil without WEBHOOK_URLS
+	maxAttempts int
+	wake        chan struct{}
+}
+
+// newOutbox creates the outbox of the destinations configured by EVENT_BROKER and
+// WEBHOOK_URLS. An entry is dropped after OUTBOX_MAX_ATTEMPTS failed deliveries.
+func (s *Server) newOutbox() *outbox {
+	o := &outbox{
+		maxAttempts: int(envInt64("OUTBOX_MAX_ATTEMPTS", 20)),
+		wake:        make(chan struct{}, 1),
+	}
+	if publisher := newEventBroker(); publisher != nil {
+		o.broker = broker.NewForwarder(publisher, s.nodeID(), envList("BROKER_EVENTS"), s.roomTenantID)
+	}
+	if urls := envList("WEBHOOK_URLS"); len(urls) > 0 {
+		o.webhooks = webhooks.NewDispatcher(urls, os.Getenv("WEBHOOK_SECRET"), envList("WEBHOOK_EVENTS"))
+	}
+	return o
+}
+
+// destinations returns the configured destinations
+func (o *outbox) destinations() []string {
+	var destinations []string
+	if o.broker != nil {
+		destinations = append(destinations, outboxBroker)
+	}
+	if o.webhooks != nil {
+		for _, url := range o.webhooks.URLs() {
+			destinations = append(destinations, outboxWebhook+url)
+		}
+	}
+	return destinations
+}
+
+// notify wakes the delivery loop after entries were queued
+func (o *outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close disconnects from the message broker
+func (o *outbox) close() error {
+	if o.broker == nil {
+		return nil
+	}
+	return o.broker.Close()
+}
+
+// outboxEntries encodes events for each destination that accepts them
+func (s *Server) outboxEntries(evs []events.Event) []repository.OutboxEntry {
+	if s.outbox == nil {
+		return nil
+	}
+
+	var entries []repository.OutboxEntry
+	for _, event := range evs {
+		if s.outbox.broker != nil && s.outbox.broker.Accepts(event.Type) {
+			body, err := s.outbox.broker.Encode(event)
+			if err != nil {
+				serverLog.Errorf("Failed to encode %s event for the broker: %v", event.Type, err)
+			} else {
+				entries = append(entries, repository.OutboxEntry{Destination: outboxBroker, EventType: event.Type, Body: body})
+			}
+		}
+		if s.outbox.webhooks != nil && s.outbox.webhooks.Accepts(event.Type) {
+			body, err := s.outbox.webhooks.Encode(event)
+			if err != nil {
+				serverLog.Errorf("Failed to encode %s event for webhooks: %v", event.Type, err)
+				continue
+			}
+			for _, url := range s.outbox.webhooks.URLs() {
+				entries = append(entries, repository.OutboxEntry{Destination: outboxWebhook + url, EventType: event.Type, Body: body})
+			}
+		}
+	}
+	return entries
+}
+
+// runOutbox delivers queued events until the server stops
+func (s *Server) runOutbox() {
+	destinations := s.outbox.destinations()
+	if len(destinations) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, destination := range destinations {
+			wg.Add(1)
+			go func(destination string) {
+				defer wg.Done()
+				s.drainOutbox(destination)
+			}(destination)
+		}
+		wg.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-s.outbox.wake:
+		}
+	}
+}
+
+// drainOutbox delivers the entries of a destination in order, stopping at the first
+// that is not due yet so that a failing endpoint does not receive events out of order
+func (s *Server) drainOutbox(destination string) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), repositoryTimeout)
+		entries, err := s.repos.Outbox.Pending(ctx, destination, outboxBatchSize)
+		cancel()
+		if err != nil {
+			serverLog.Errorf("Failed to read outbox: %v", err)
+			return
+		}
+
+		for _, entry := range entries {
+			if entry.NextAttempt.After(time.Now()) || !s.deliverOutboxEntry(entry) {
+				return
+			}
+		}
+		if len(entries) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// deliverOutboxEntry makes one delivery attempt and reports whether the destination can
+// move on to its next entry, either because the entry was delivered or dropped
+func (s *Server) deliverOutboxEntry(entry repository.OutboxEntry) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxDeliveryTimeout)
+	var err error
+	if entry.Destination == outboxBroker {
+		err = s.outbox.broker.Deliver(ctx, entry.Body)
+	} else {
+		url := strings.TrimPrefix(entry.Destination, outboxWebhook)
+		err = s.outbox.webhooks.Deliver(ctx, url, entry.EventType, entry.Body)
+	}
+	cancel()
+
+	ctx, cancel = context.WithTimeout(context.Background(), repositoryTimeout)
+	defer cancel()
+
+	if err == nil {
+		if err := s.repos.Outbox.Remove(ctx, entry.ID); err != nil {
+			serverLog.Errorf("Failed to remove delivered outbox entry %d: %v", entry.ID, err)
+			return false
+		}
+		return true
+	}
+
+	attempts := entry.Attempts + 1
+	if attempts >= s.outbox.maxAttempts {
+		serverLog.Errorf("Dropping %s event for %s after %d attempts: %v", entry.EventType, entry.Destination, attempts, err)
+		if err := s.repos.Outbox.Remove(ctx, entry.ID); err != nil {
+			serverLog.Errorf("Failed to remove outbox entry %d: %v", entry.ID, err)
+			return false
+		}
+		return true
+	}
+
+	serverLog.Warnf("Delivery of %s event to %s failed (attempt %d): %v", entry.EventType, entry.Destination, attempts, err)
+	next := time.Now().Add(outboxBackoff(attempts))
+	if err := s.repos.Outbox.Retry(ctx, entry.ID, next, err.Error()); err != nil {
+		serverLog.Errorf("Failed to reschedule outbox entry %d: %v", entry.ID, err)
+	}
+	return false
+}
+
+// outboxBackoff returns the delay before the next attempt after a number of failed ones
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 12 {
+		return outboxMaxBackoff
+	}
+	backoff := time.Second << attempts
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
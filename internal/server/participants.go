@@ -0,0 +1,230 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+// Connection quality levels
+const (
+	qualityGood    = "good"
+	qualityFair    = "fair"
+	qualityPoor    = "poor"
+	qualityUnknown = "unknown"
+)
+
+// connectionQuality summarizes the media path between the server and a participant
+type connectionQuality struct {
+	State             string  `json:"state"`
+	Quality           string  `json:"quality"`
+	RTTMs             float64 `json:"rtt_ms,omitempty"`
+	PacketLossPercent float64 `json:"packet_loss_percent"`
+}
+
+// participantInfo is a roster entry
+type participantInfo struct {
+	ClientID           string            `json:"client_id"`
+	UserID             string            `json:"user_id"`
+	Username           string            `json:"username"`
+	DisplayName        string            `json:"display_name,omitempty"`
+	AvatarURL          string            `json:"avatar_url,omitempty"`
+	IsBot              bool              `json:"is_bot"`
+	JoinedAt           time.Time         `json:"joined_at"`
+	AudioMuted         bool              `json:"audio_muted"`
+	VideoMuted         bool              `json:"video_muted"`
+	SignalingConnected bool              `json:"signaling_connected"`
+	Hold               string            `json:"hold,omitempty"`
+	Tracks             []trackInfo       `json:"tracks"`
+	Connection         connectionQuality `json:"connection"`
+}
+
+// trackInfo describes a track a participant publishes through the server
+type trackInfo struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// measureQuality derives connection quality from the peer connection's stats
+func measureQuality(pc *webrtc.PeerConnection) connectionQuality {
+	if pc == nil {
+		return connectionQuality{State: "none", Quality: qualityUnknown}
+	}
+
+	quality := connectionQuality{
+		State:   pc.ConnectionState().String(),
+		Quality: qualityUnknown,
+	}
+	if pc.ConnectionState() != webrtc.PeerConnectionStateConnected {
+		return quality
+	}
+
+	var received, lost int64
+	for _, stat := range pc.GetStats() {
+		switch stat := stat.(type) {
+		case webrtc.ICECandidatePairStats:
+			if stat.Nominated && stat.CurrentRoundTripTime > 0 {
+				quality.RTTMs = stat.CurrentRoundTripTime * 1000
+			}
+		case webrtc.InboundRTPStreamStats:
+			received += int64(stat.PacketsReceived)
+			lost += int64(stat.PacketsLost)
+		}
+	}
+	if received+lost > 0 {
+		quality.PacketLossPercent = float64(lost) / float64(received+lost) * 100
+	}
+
+	switch {
+	case quality.PacketLossPercent < 2 && quality.RTTMs < 200:
+		quality.Quality = qualityGood
+	case quality.PacketLossPercent < 8 && quality.RTTMs < 400:
+		quality.Quality = qualityFair
+	default:
+		quality.Quality = qualityPoor
+	}
+	return quality
+}
+
+// observeSignal records participant state carried by signaling messages, relays
+// captions and negotiates the participants' peer connections with the server
+func (s *Server) observeSignal(roomID, senderID, msgType string, payload websocket.Payload) {
+	switch msgType {
+	case "join", "server-answer", "server-candidate":
+		s.observePeerSignal(roomID, senderID, msgType, payload)
+		return
+	}
+
+	switch payload := payload.(type) {
+	case *websocket.CaptionPayload:
+		s.relayCaption(roomID, senderID, payload)
+		return
+	case *websocket.MutePayload, *websocket.TrackSourcePayload, *websocket.CaptionLanguagePayload:
+	default:
+		return
+	}
+
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		return
+	}
+
+	s.roomUpdate(room, func() {
+		client, exists := room.Clients[senderID]
+		if !exists {
+			return
+		}
+
+		if source, ok := payload.(*websocket.TrackSourcePayload); ok {
+			if client.TrackSources == nil {
+				client.TrackSources = make(map[string]string)
+			}
+			client.TrackSources[source.TrackID] = source.Source
+			// The track may already be published if it was announced after negotiation
+			if published, exists := room.Tracks[source.TrackID]; exists && published.ClientID == senderID {
+				published.Source = source.Source
+			}
+			return
+		}
+
+		if subscription, ok := payload.(*websocket.CaptionLanguagePayload); ok {
+			client.CaptionLanguage = subscription.Language
+			return
+		}
+
+		mute := payload.(*websocket.MutePayload)
+		if mute.Audio != nil {
+			client.AudioMuted = *mute.Audio
+		}
+		if mute.Video != nil {
+			client.VideoMuted = *mute.Video
+		}
+	})
+}
+
+// observePeerSignal offers a participant's peer connection with the server once its
+// signaling connection joins, and applies its answers and candidates
+func (s *Server) observePeerSignal(roomID, senderID, msgType string, payload websocket.Payload) {
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		return
+	}
+
+	room.Mu.RLock()
+	client, exists := room.Clients[senderID]
+	room.Mu.RUnlock()
+	if !exists || client.Conn == nil {
+		return
+	}
+
+	if msgType == "join" {
+		s.offerOnJoin(client)
+		return
+	}
+	s.applyServerSignal(client, payload)
+}
+
+// listParticipantsHandler returns the room roster with live media details
+func (s *Server) listParticipantsHandler(c *gin.Context) {
+	room, ok := s.memberRoom(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"room_id":      room.ID,
+		"participants": s.roster(room),
+	})
+}
+
+// roster returns the participants of a room with live media details, oldest first
+func (s *Server) roster(room *models.Room) []participantInfo {
+	room.Mu.RLock()
+	clients := make([]*models.Client, 0, len(room.Clients))
+	participants := make([]participantInfo, 0, len(room.Clients))
+	for _, client := range room.Clients {
+		info := participantInfo{
+			ClientID:    client.ID,
+			UserID:      client.UserID,
+			Username:    client.Username,
+			DisplayName: client.DisplayName,
+			AvatarURL:   client.AvatarURL,
+			IsBot:       client.IsBot,
+			JoinedAt:    client.JoinedAt,
+			AudioMuted:  client.AudioMuted,
+			VideoMuted:  client.VideoMuted,
+			Hold:        client.Hold,
+			Tracks:      []trackInfo{},
+		}
+		for _, published := range room.Tracks {
+			if published.ClientID == client.ID {
+				info.Tracks = append(info.Tracks, trackInfo{
+					ID:          published.ID,
+					Kind:        published.Kind,
+					Subscribers: len(published.Senders),
+				})
+			}
+		}
+		clients = append(clients, client)
+		participants = append(participants, info)
+	}
+	room.Mu.RUnlock()
+
+	// Stats and hub lookups happen outside the room lock
+	for i, client := range clients {
+		participants[i].Connection = measureQuality(client.Conn)
+		participants[i].SignalingConnected = client.IsBot || s.hub.HasSender(client.ID)
+	}
+
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].JoinedAt.Before(participants[j].JoinedAt)
+	})
+	return participants
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// hlsRetryAfterSeconds is suggested to players while the HLS variant is generated
+const hlsRetryAfterSeconds = 5
+
+// playbackBookmark is where a user stopped watching a recording
+type playbackBookmark struct {
+	RecordingID string    `json:"recording_id"`
+	RoomID      string    `json:"room_id"`
+	Position    float64   `json:"position_seconds"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// playbackBookmarks keeps the bookmarks of each user, by recording ID
+type playbackBookmarks struct {
+	users map[string]map[string]playbackBookmark
+	mu    sync.Mutex
+}
+
+// playableRecording returns the recording in the path if the user may watch it: its
+// owner, the room creator, admins and the users who attended the recorded call
+func (s *Server) playableRecording(c *gin.Context) (*recording.Recording, bool) {
+	rec, exists := s.recorder.GetRecording(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return nil, false
+	}
+
+	userID := c.GetString("user_id")
+	allowed := userID == rec.OwnerID || userID == s.roomOwner(rec.RoomID) || c.GetString("role") == auth.RoleAdmin ||
+		slices.Contains(s.recordingAttendees(rec), userID)
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You cannot watch this recording")})
+		return nil, false
+	}
+	if rec.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is still in progress")})
+		return nil, false
+	}
+	if respondOffline(c, rec) {
+		return nil, false
+	}
+	return rec, true
+}
+
+// playRecordingHandler streams the composed file of a recording with support for
+// range requests, so players can seek; ?format=hls redirects to the HLS playlist
+func (s *Server) playRecordingHandler(c *gin.Context) {
+	rec, ok := s.playableRecording(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("format") == "hls" {
+		location := "/recording/" + url.PathEscape(rec.ID) + "/hls/" + recording.HLSPlaylist
+		if token := c.Query("token"); token != "" {
+			location += "?token=" + url.QueryEscape(token)
+		}
+		c.Redirect(http.StatusFound, location)
+		return
+	}
+
+	path, err := s.recorder.Composite(rec.ID)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has not been processed yet")})
+		return
+	}
+	c.Header("Content-Type", "video/webm")
+	s.serveRecordingFile(c, path)
+}
+
+// serveRecordingFile serves a file of a recording, decrypted if it is encrypted, with
+// support for range requests
+func (s *Server) serveRecordingFile(c *gin.Context, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to read recording")})
+		return
+	}
+	file, err := s.recorder.Open(path)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to read recording")})
+		return
+	}
+	defer file.Close()
+
+	http.ServeContent(c.Writer, c.Request, filepath.Base(path), info.ModTime(), file)
+}
+
+// hlsHandler serves the playlist and segments of the HLS VOD variant of a recording.
+// The variant is generated on the first request for the playlist, which is answered
+// with 202 and Retry-After until it is ready.
+func (s *Server) hlsHandler(c *gin.Context) {
+	rec, ok := s.playableRecording(c)
+	if !ok {
+		return
+	}
+
+	name := c.Param("file")
+	path, err := s.recorder.HLSFile(rec.ID, name)
+	switch {
+	case errors.Is(err, recording.ErrNotProcessed):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has not been processed yet")})
+		return
+	case errors.Is(err, recording.ErrHLSNotReady), errors.Is(err, recording.ErrHLSInProgress):
+		if errors.Is(err, recording.ErrHLSNotReady) {
+			go s.generateHLS(rec)
+		}
+		c.Header("Retry-After", strconv.Itoa(hlsRetryAfterSeconds))
+		c.JSON(http.StatusAccepted, gin.H{"message": "Preparing HLS playback"})
+		return
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "File not found")})
+		return
+	}
+
+	if name != recording.HLSPlaylist {
+		c.Header("Content-Type", "video/mp2t")
+		s.serveRecordingFile(c, path)
+		return
+	}
+
+	file, err := s.recorder.Open(path)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to read recording")})
+		return
+	}
+	playlist, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to read recording")})
+		return
+	}
+	// Players cannot send the Authorization header with segment requests, so a token
+	// given in the query is passed on to the segments
+	if token := c.Query("token"); token != "" {
+		playlist = withSegmentToken(playlist, token)
+	}
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", playlist)
+}
+
+// withSegmentToken adds a token query parameter to the segment URIs of a playlist
+func withSegmentToken(playlist []byte, token string) []byte {
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" && !strings.HasPrefix(line, "#") {
+			line += "?token=" + url.QueryEscape(token)
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// generateHLS generates the HLS variant of a recording in the background
+func (s *Server) generateHLS(rec *recording.Recording) {
+	if err := s.recorder.GenerateHLS(rec.ID); err != nil {
+		if !errors.Is(err, recording.ErrHLSInProgress) {
+			recordingLog.Errorf("Failed to generate HLS variant of recording %s: %v", rec.ID, err)
+			s.reportRecordingError(err, rec.RoomID, rec.ID)
+		}
+		return
+	}
+	recordingLog.Infof("Generated HLS variant of recording %s", rec.ID)
+}
+
+// chaptersHandler lists the chapters of a recording for players to navigate;
+// ?format=vtt returns them as a WebVTT chapters track
+func (s *Server) chaptersHandler(c *gin.Context) {
+	rec, ok := s.playableRecording(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("format") == "vtt" {
+		vtt := recording.ChaptersVTT(s.recorder.Chapters(rec.ID), rec.EndedAt.Sub(rec.StartedAt).Seconds())
+		c.Data(http.StatusOK, "text/vtt; charset=utf-8", vtt)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recording_id": rec.ID,
+		"chapters":     s.recorder.Chapters(rec.ID),
+	})
+}
+
+// getBookmarkHandler returns where the user stopped watching a recording, or null
+func (s *Server) getBookmarkHandler(c *gin.Context) {
+	rec, ok := s.playableRecording(c)
+	if !ok {
+		return
+	}
+
+	s.playback.mu.Lock()
+	bookmark, exists := s.playback.users[c.GetString("user_id")][rec.ID]
+	s.playback.mu.Unlock()
+
+	if !exists {
+		c.JSON(http.StatusOK, gin.H{"bookmark": nil})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bookmark": bookmark})
+}
+
+// putBookmarkHandler stores where the user stopped watching a recording, so playback
+// can resume there on any device
+func (s *Server) putBookmarkHandler(c *gin.Context) {
+	var req struct {
+		Position *float64 `json:"position_seconds" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	rec, ok := s.playableRecording(c)
+	if !ok {
+		return
+	}
+	if *req.Position < 0 || (!rec.EndedAt.IsZero() && *req.Position > rec.EndedAt.Sub(rec.StartedAt).Seconds()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "position_seconds must be within the recording")})
+		return
+	}
+
+	bookmark := playbackBookmark{
+		RecordingID: rec.ID,
+		RoomID:      rec.RoomID,
+		Position:    *req.Position,
+		UpdatedAt:   time.Now(),
+	}
+	userID := c.GetString("user_id")
+
+	s.playback.mu.Lock()
+	if s.playback.users == nil {
+		s.playback.users = make(map[string]map[string]playbackBookmark)
+	}
+	if s.playback.users[userID] == nil {
+		s.playback.users[userID] = make(map[string]playbackBookmark)
+	}
+	s.playback.users[userID][rec.ID] = bookmark
+	s.playback.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"bookmark": bookmark})
+}
+
+// listBookmarksHandler lists the user's bookmarks, most recently updated first, e.g.
+// for a "continue watching" list
+func (s *Server) listBookmarksHandler(c *gin.Context) {
+	s.playback.mu.Lock()
+	bookmarks := []playbackBookmark{}
+	for _, bookmark := range s.playback.users[c.GetString("user_id")] {
+		bookmarks = append(bookmarks, bookmark)
+	}
+	s.playback.mu.Unlock()
+
+	sort.Slice(bookmarks, func(i, j int) bool {
+		return bookmarks[i].UpdatedAt.After(bookmarks[j].UpdatedAt)
+	})
+	c.JSON(http.StatusOK, gin.H{"bookmarks": bookmarks})
+}
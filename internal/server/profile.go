@@ -0,0 +1,238 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/branding"
+)
+
+const (
+	// maxAvatarSize is the largest accepted avatar image (2 MiB)
+	maxAvatarSize = 2 << 20
+
+	// maxDisplayNameLength is the longest accepted display name, in characters
+	maxDisplayNameLength = 64
+
+	// avatarPathPrefix is the public URL prefix avatars are served under
+	avatarPathPrefix = "/avatars/"
+)
+
+// avatarTypes maps accepted avatar content types to file extensions
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// localePattern matches BCP 47 tags such as "en", "ru-RU" or "pt-BR"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// avatarsDir returns the directory avatar images are stored in
+func avatarsDir() string {
+	return envString("AVATARS_DIR", "./avatars")
+}
+
+// profile returns the display name and avatar URL of a user; users without an
+// account (e.g. room token guests) are shown by their username
+func profile(userID, username string) (displayName, avatarURL string) {
+	if user, exists := auth.GetUserByID(userID); exists {
+		return user.Name(), user.AvatarURL
+	}
+	return username, ""
+}
+
+// profileResponse is the JSON representation of the current user's profile
+func profileResponse(user *auth.User) gin.H {
+	return gin.H{
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"role":           user.Role,
+		"display_name":   user.Name(),
+		"avatar_url":     user.AvatarURL,
+		"locale":         user.Locale,
+		"org":            user.Org,
+		"org_slug":       branding.Slug(user.Org),
+	}
+}
+
+// currentUser looks up the authenticated user's account
+func (s *Server) currentUser(c *gin.Context) (*auth.User, bool) {
+	user, exists := auth.GetUserByID(c.MustGet("user_id").(string))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User not found")})
+		return nil, false
+	}
+	return user, true
+}
+
+// getProfileHandler returns the current user's profile
+func (s *Server) getProfileHandler(c *gin.Context) {
+	user, ok := s.currentUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, profileResponse(user))
+}
+
+// updateProfileHandler changes the current user's display name and locale
+func (s *Server) updateProfileHandler(c *gin.Context) {
+	var req struct {
+		DisplayName *string `json:"display_name"`
+		Locale      *string `json:"locale"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "display_name must be at most 64 characters")})
+			return
+		}
+		req.DisplayName = &name
+	}
+	if req.Locale != nil && *req.Locale != "" && !localePattern.MatchString(*req.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "locale must be a language tag such as en or ru-RU")})
+		return
+	}
+
+	user, err := auth.UpdateProfile(c.MustGet("user_id").(string), auth.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		Locale:      req.Locale,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User not found")})
+		return
+	}
+	s.saveUser(user.ID)
+
+	c.JSON(http.StatusOK, profileResponse(user))
+}
+
+// uploadAvatarHandler stores a new avatar image for the current user
+func (s *Server) uploadAvatarHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	if _, ok := s.currentUser(c); !ok {
+		return
+	}
+
+	// Leave headroom for multipart framing on top of the image itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAvatarSize+1<<20)
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, "Avatar exceeds 2 MiB")})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Avatar file is required")})
+		return
+	}
+	if header.Size > maxAvatarSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tr(c, "Avatar exceeds 2 MiB")})
+		return
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Failed to read file")})
+		return
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxAvatarSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Failed to read file")})
+		return
+	}
+
+	// Trust the content, not the client-supplied type
+	ext, ok := avatarTypes[http.DetectContentType(data)]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": tr(c, "Avatar must be a PNG, JPEG, GIF or WebP image")})
+		return
+	}
+
+	dir := avatarsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to store avatar")})
+		return
+	}
+	name := uuid.New().String() + ext
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to store avatar")})
+		return
+	}
+
+	previous, err := auth.SetUserAvatar(userID, avatarPathPrefix+name)
+	if err != nil {
+		os.Remove(filepath.Join(dir, name))
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User not found")})
+		return
+	}
+	removeAvatar(previous)
+	s.saveUser(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Avatar updated",
+		"avatar_url": avatarPathPrefix + name,
+	})
+}
+
+// deleteAvatarHandler removes the current user's avatar
+func (s *Server) deleteAvatarHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	previous, err := auth.SetUserAvatar(userID, "")
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "User not found")})
+		return
+	}
+	removeAvatar(previous)
+	s.saveUser(userID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Avatar removed"})
+}
+
+// avatarHandler serves an avatar image; names are random, so avatars are public
+func (s *Server) avatarHandler(c *gin.Context) {
+	name := filepath.Base(c.Param("file"))
+	path := filepath.Join(avatarsDir(), name)
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Avatar not found")})
+		return
+	}
+
+	// A new upload gets a new name, so the image never changes
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.File(path)
+}
+
+// removeAvatar deletes a replaced avatar image
+func removeAvatar(avatarURL string) {
+	if !strings.HasPrefix(avatarURL, avatarPathPrefix) {
+		return
+	}
+	path := filepath.Join(avatarsDir(), filepath.Base(strings.TrimPrefix(avatarURL, avatarPathPrefix)))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		serverLog.Errorf("Failed to remove avatar %s: %v", path, err)
+	}
+}
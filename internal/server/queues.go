@@ -0,0 +1,420 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/callqueue"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// queuePosition is the payload of "queue-position" messages streamed to waiting callers
+// whenever their queue moves
+type queuePosition struct {
+	QueueID  string `json:"queue_id"`
+	Position int    `json:"position"` // counting from 1
+	Waiting  int    `json:"waiting"`
+}
+
+// queueCall is the payload of "queue-call" messages telling a caller its call was taken:
+// it joins the room with the enclosed token
+type queueCall struct {
+	QueueID   string    `json:"queue_id"`
+	RoomID    string    `json:"room_id"`
+	AgentID   string    `json:"agent_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// queueAssignment is the payload of "queue-assignment" messages routing a caller to an agent
+type queueAssignment struct {
+	QueueID string           `json:"queue_id"`
+	RoomID  string           `json:"room_id"`
+	Caller  callqueue.Caller `json:"caller"`
+}
+
+// queueClosed is the payload of "queue-closed" messages telling callers their queue was deleted
+type queueClosed struct {
+	QueueID string `json:"queue_id"`
+}
+
+// notifyQueuePositions sends every caller waiting in a queue its current position
+func (s *Server) notifyQueuePositions(queueID string) {
+	callers := s.queues.Callers(queueID)
+	s.metrics.SetQueueCallersWaiting(queueID, float64(len(callers)))
+	for i, caller := range callers {
+		s.hub.SendToUser(caller.UserID, "queue-position", queuePosition{
+			QueueID:  queueID,
+			Position: i + 1,
+			Waiting:  len(callers),
+		})
+	}
+}
+
+// tenantQueue looks up the queue from the :id path parameter; queues of other tenants
+// are not found
+func (s *Server) tenantQueue(c *gin.Context) (callqueue.Queue, bool) {
+	q, exists := s.queues.Get(c.Param("id"))
+	if !exists || q.TenantID != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Queue not found")})
+		return callqueue.Queue{}, false
+	}
+	return q, true
+}
+
+// isQueueAgent reports whether the caller takes the calls of a queue; admins take any
+func isQueueAgent(c *gin.Context, q callqueue.Queue) bool {
+	return c.GetString("role") == auth.RoleAdmin || q.HasAgent(c.GetString("user_id"))
+}
+
+// adminCreateQueueHandler creates a queue with the agents taking its calls
+func (s *Server) adminCreateQueueHandler(c *gin.Context) {
+	var req struct {
+		Name     string   `json:"name" binding:"required,max=100"`
+		Strategy string   `json:"strategy"`
+		Agents   []string `json:"agents" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	q, err := s.queues.Create(tenantID(c), strings.TrimSpace(req.Name), req.Strategy, req.Agents)
+	switch {
+	case errors.Is(err, callqueue.ErrQueueExists):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Queue already exists")})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	s.recordAudit(c, "queue.create", q.ID, map[string]string{"name": q.Name, "strategy": q.Strategy})
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Queue created",
+		"queue":   q,
+	})
+}
+
+// adminDeleteQueueHandler deletes a queue, letting the callers still waiting know
+func (s *Server) adminDeleteQueueHandler(c *gin.Context) {
+	q, ok := s.tenantQueue(c)
+	if !ok {
+		return
+	}
+
+	callers, err := s.queues.Delete(q.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Queue not found")})
+		return
+	}
+	for _, caller := range callers {
+		s.hub.SendToUser(caller.UserID, "queue-closed", queueClosed{QueueID: q.ID})
+	}
+	s.metrics.ForgetQueue(q.ID)
+
+	s.recordAudit(c, "queue.delete", q.ID, map[string]string{"name": q.Name})
+	c.JSON(http.StatusOK, gin.H{"message": "Queue deleted"})
+}
+
+// listQueuesHandler lists the queues of the caller's tenant
+func (s *Server) listQueuesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"queues": s.queues.List(tenantID(c))})
+}
+
+// joinQueueHandler puts the caller in line; its position is then streamed over the
+// WebSocket in "queue-position" messages. In routed queues an available agent may
+// take the call right away.
+func (s *Server) joinQueueHandler(c *gin.Context) {
+	q, ok := s.tenantQueue(c)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+	position, err := s.queues.Join(q.ID, userID, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Queue not found")})
+		return
+	}
+	s.dispatchQueue(q.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Joined queue",
+		"queue_id": q.ID,
+		"position": position,
+	})
+}
+
+// leaveQueueHandler takes the caller out of line
+func (s *Server) leaveQueueHandler(c *gin.Context) {
+	q, ok := s.tenantQueue(c)
+	if !ok {
+		return
+	}
+
+	if _, waiting := s.queues.Leave(q.ID, c.MustGet("user_id").(string)); !waiting {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "You are not in this queue")})
+		return
+	}
+	s.metrics.IncrementQueueAbandoned(q.ID)
+	s.notifyQueuePositions(q.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left queue"})
+}
+
+// listQueueCallersHandler lists the callers waiting in a queue to its agents
+func (s *Server) listQueueCallersHandler(c *gin.Context) {
+	q, ok := s.tenantQueue(c)
+	if !ok {
+		return
+	}
+	if !isQueueAgent(c, q) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only queue agents can take calls")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"callers": s.queues.Callers(q.ID)})
+}
+
+// listQueueAgentsHandler lists the agents of a queue with their statuses
+func (s *Server) listQueueAgentsHandler(c *gin.Context) {
+	q, ok := s.tenantQueue(c)
+	if !ok {
+		return
+	}
+	if !isQueueAgent(c, q) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only queue agents can take calls")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"agents": s.queues.Agents(q.ID)})
+}
+
+// getAgentStatusHandler returns the caller's agent status and the queues it takes calls of
+func (s *Server) getAgentStatusHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	c.JSON(http.StatusOK, gin.H{
+		"agent":  s.queues.Agent(userID),
+		"queues": s.queues.QueuesOf(userID),
+	})
+}
+
+// setAgentStatusHandler lets an agent become available for queue calls or go offline;
+// an available agent is routed waiting callers at once
+func (s *Server) setAgentStatusHandler(c *gin.Context) {
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	userID := c.MustGet("user_id").(string)
+	agent, err := s.queues.SetStatus(userID, req.Status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+	if agent.Status == callqueue.StatusAvailable {
+		s.dispatchAgentQueues(userID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"agent": s.queues.Agent(userID)})
+}
+
+// nextQueueCallerHandler lets an agent take the call of the caller first in line: it
+// creates a 1:1 room hosted by the agent and sends the caller a token for it in a
+// "queue-call" message
+func (s *Server) nextQueueCallerHandler(c *gin.Context) {
+	q, ok := s.tenantQueue(c)
+	if !ok {
+		return
+	}
+	agentID := c.MustGet("user_id").(string)
+	if !isQueueAgent(c, q) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only queue agents can take calls")})
+		return
+	}
+
+	// Refuse new rooms when the node is at capacity, before anyone leaves the line
+	if !s.admit(c, true) {
+		return
+	}
+
+	caller, err := s.queues.Next(q.ID, agentID)
+	switch {
+	case errors.Is(err, callqueue.ErrQueueEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "No callers waiting")})
+		return
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Queue not found")})
+		return
+	}
+
+	room, notified, err := s.connectQueueCall(c.Request.Context(), q, caller, agentID)
+	if err != nil {
+		serverLog.Errorf("Failed to connect call of queue %s: %v", q.ID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Failed to create room")})
+		return
+	}
+	s.notifyQueuePositions(q.ID)
+
+	s.recordAudit(c, "queue.next", q.ID, map[string]string{
+		"caller_id": caller.UserID,
+		"room_id":   room.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Call taken",
+		"room_id":  room.ID,
+		"caller":   caller,
+		"notified": notified,
+	})
+}
+
+// dispatchQueue routes the callers of a queue to its available agents by the queue's
+// strategy, then streams the new positions to those still waiting. Manual queues wait
+// for agents to pull callers, and routing pauses while the node is at capacity.
+func (s *Server) dispatchQueue(queueID string) {
+	defer s.notifyQueuePositions(queueID)
+
+	q, exists := s.queues.Get(queueID)
+	if !exists || q.Strategy == callqueue.StrategyManual {
+		return
+	}
+
+	for s.overloadReason(s.loadReport(), true) == "" {
+		caller, agentID, err := s.queues.Assign(queueID)
+		if err != nil {
+			return
+		}
+
+		room, _, err := s.connectQueueCall(context.Background(), q, caller, agentID)
+		if err != nil {
+			serverLog.Errorf("Failed to connect call of queue %s: %v", queueID, err)
+			return
+		}
+		s.hub.SendToUser(agentID, "queue-assignment", queueAssignment{
+			QueueID: queueID,
+			RoomID:  room.ID,
+			Caller:  caller,
+		})
+	}
+}
+
+// dispatchAgentQueues routes waiting callers of every queue of an agent who became available
+func (s *Server) dispatchAgentQueues(agentID string) {
+	for _, queueID := range s.queues.QueuesOf(agentID) {
+		s.dispatchQueue(queueID)
+	}
+}
+
+// connectQueueCall sets up the call of a caller taken out of a queue by an agent: it
+// creates their 1:1 room and sends the caller a token for it in a "queue-call" message,
+// reporting whether the caller was reached. When the call cannot be set up the caller
+// goes back first in line.
+func (s *Server) connectQueueCall(ctx context.Context, q callqueue.Queue, caller callqueue.Caller, agentID string) (*models.Room, bool, error) {
+	room, err := s.createQueueRoom(ctx, q, caller, agentID)
+	if err != nil {
+		s.queues.Requeue(q.ID, caller, agentID)
+		return nil, false, err
+	}
+
+	grant := auth.RoomGrant{
+		RoomID:       room.ID,
+		CanPublish:   true,
+		CanSubscribe: true,
+		CanChat:      true,
+	}
+	ttl := auth.Policy(auth.TokenGuest).TTL
+	token, err := auth.GenerateRoomJWT(caller.UserID, caller.Username, room.TenantID, grant, ttl)
+	if err != nil {
+		s.queues.Requeue(q.ID, caller, agentID)
+		return nil, false, err
+	}
+
+	s.queues.StartCall(room.ID, q.ID, agentID)
+	s.metrics.ObserveQueueAnswered(q.ID, time.Since(caller.JoinedAt).Seconds())
+
+	delivered := s.hub.SendToUser(caller.UserID, "queue-call", queueCall{
+		QueueID:   q.ID,
+		RoomID:    room.ID,
+		AgentID:   agentID,
+		Token:     token,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return room, delivered > 0, nil
+}
+
+// endQueueCall ends the queue call in a room when its agent leaves it, or with an empty
+// userID when the room ends. The agent wraps up for QUEUE_WRAP_UP_SECONDS and is then
+// routed the next caller.
+func (s *Server) endQueueCall(roomID, userID string) {
+	agentID, since, ok := s.queues.EndCall(roomID, userID)
+	if !ok {
+		return
+	}
+	s.hub.SendToUser(agentID, "agent-status", s.queues.Agent(agentID))
+
+	wrapUp := time.Duration(s.settings().QueueWrapUpSeconds) * time.Second
+	time.AfterFunc(wrapUp, func() {
+		if s.queues.FinishWrapUp(agentID, since) {
+			s.hub.SendToUser(agentID, "agent-status", s.queues.Agent(agentID))
+			s.dispatchAgentQueues(agentID)
+		}
+	})
+}
+
+// createQueueRoom creates the private 1:1 room where an agent takes a caller's call
+func (s *Server) createQueueRoom(ctx context.Context, q callqueue.Queue, caller callqueue.Caller, agentID string) (*models.Room, error) {
+	s.roomManager.Mu.Lock()
+	roomID := generateRoomID()
+	room := &models.Room{
+		ID:        roomID,
+		Name:      q.Name + ": " + caller.Username,
+		CreatorID: agentID,
+		TenantID:  q.TenantID,
+		Clients:   make(map[string]*models.Client),
+		Tracks:    make(map[string]*models.PublishedTrack),
+		CreatedAt: time.Now(),
+		IsActive:  true,
+		JoinCode:  s.newJoinCodeLocked(),
+		Settings: models.RoomSettings{
+			RecordingPolicy: models.RecordingManual,
+			MaxParticipants: 2,
+		},
+	}
+	s.roomManager.Rooms[roomID] = room
+	s.roomManager.Mu.Unlock()
+
+	// Record this node as the room's host so other nodes route joins here
+	if s.cluster != nil {
+		if err := s.cluster.ClaimRoom(ctx, roomID); err != nil {
+			s.roomManager.Mu.Lock()
+			delete(s.roomManager.Rooms, roomID)
+			s.roomManager.Mu.Unlock()
+			return nil, err
+		}
+	}
+
+	s.saveRoom(room, newEvent(events.RoomCreated, room.ID, map[string]interface{}{
+		"name":       room.Name,
+		"creator_id": room.CreatorID,
+		"queue_id":   q.ID,
+	}))
+	s.metrics.IncrementRoomsCreated(room.TenantID)
+	s.metrics.SetRoomsActive(float64(len(s.roomManager.Rooms)))
+
+	return room, nil
+}
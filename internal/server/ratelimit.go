@@ -0,0 +1,54 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindow is the length of a rate limiting window
+const rateWindow = time.Minute
+
+// rateLimiter counts events per key in fixed one-minute windows
+type rateLimiter struct {
+	windows map[string]*rateCounter
+	mu      sync.Mutex
+}
+
+// rateCounter is the event count of a key in the current window
+type rateCounter struct {
+	start time.Time
+	count int
+}
+
+// newRateLimiter creates a new rateLimiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		windows: make(map[string]*rateCounter),
+	}
+}
+
+// allow records an event for a key and reports whether it is within perMinute (0 disables the limit)
+func (l *rateLimiter) allow(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	counter, exists := l.windows[key]
+	if !exists || now.Sub(counter.start) >= rateWindow {
+		// Drop finished windows of other keys before adding a new one
+		for k, other := range l.windows {
+			if now.Sub(other.start) >= rateWindow {
+				delete(l.windows, k)
+			}
+		}
+		counter = &rateCounter{start: now}
+		l.windows[key] = counter
+	}
+
+	counter.count++
+	return counter.count <= perMinute
+}
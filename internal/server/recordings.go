@@ -0,0 +1,252 @@
+package server
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/errreport"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// processRecording post-processes a stopped recording, publishes recording.ready
+// with the manifest of its artifacts, emails the recording's owner and generates
+// meeting notes from the transcript
+func (s *Server) processRecording(recordingID string) {
+	manifest, err := s.recorder.Process(recordingID)
+	if err != nil {
+		recordingLog.Errorf("Failed to process recording %s: %v", recordingID, err)
+		roomID := ""
+		if rec, exists := s.recorder.GetRecording(recordingID); exists {
+			roomID = rec.RoomID
+		}
+		s.reportRecordingError(err, roomID, recordingID)
+		s.saveRecording(recordingID)
+		return
+	}
+
+	withURLs := withArtifactURLs(manifest)
+	s.saveRecording(recordingID, newEvent(events.RecordingReady, manifest.RoomID, map[string]interface{}{
+		"recording_id": recordingID,
+		"name":         s.roomName(manifest.RoomID),
+		"manifest":     withURLs,
+	}))
+	s.sendRecordingReadyEmail(recordingID, withURLs)
+	s.summarizeRecording(recordingID)
+}
+
+// readWatermark reads the watermark of recordings from WATERMARK_LOGO, WATERMARK_TEXT,
+// WATERMARK_PARTICIPANT, WATERMARK_TIMESTAMP, WATERMARK_POSITION and WATERMARK_FONT;
+// an invalid watermark is logged and disabled
+func readWatermark() recording.Watermark {
+	watermark := recording.Watermark{
+		Logo:        os.Getenv("WATERMARK_LOGO"),
+		Text:        os.Getenv("WATERMARK_TEXT"),
+		Participant: envBool("WATERMARK_PARTICIPANT", false),
+		Timestamp:   envBool("WATERMARK_TIMESTAMP", false),
+		Position:    os.Getenv("WATERMARK_POSITION"),
+		Font:        os.Getenv("WATERMARK_FONT"),
+	}
+	if err := watermark.Validate(); err != nil {
+		serverLog.Errorf("Recording watermark disabled: %v", err)
+		return recording.Watermark{}
+	}
+	return watermark
+}
+
+// recordingAttendees returns the users who took part in the call while a recording ran
+func (s *Server) recordingAttendees(rec *recording.Recording) []string {
+	endedAt := rec.EndedAt
+	if endedAt.IsZero() {
+		endedAt = time.Now()
+	}
+	return s.calls.attendees(rec.RoomID, rec.StartedAt, endedAt)
+}
+
+// roomName returns the name of a room, or its ID if the room is gone
+func (s *Server) roomName(roomID string) string {
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		return roomID
+	}
+	room.Mu.RLock()
+	defer room.Mu.RUnlock()
+	return room.Name
+}
+
+// reportRecordingError counts and reports a recording failure outside a request
+func (s *Server) reportRecordingError(err error, roomID, recordingID string) {
+	s.metrics.IncrementRecordingErrors()
+	errreport.Capture(err, map[string]string{
+		"subsystem":    "recording",
+		"room_id":      roomID,
+		"recording_id": recordingID,
+	})
+}
+
+// withArtifactURLs returns a copy of a manifest with download URLs of its artifacts.
+// URLs are absolute when NODE_URL is set, since the files are stored on this node.
+func withArtifactURLs(manifest *recording.Manifest) recording.Manifest {
+	result := *manifest
+	result.Artifacts = make([]recording.Artifact, len(manifest.Artifacts))
+	for i, artifact := range manifest.Artifacts {
+		artifact.URL = artifactURL(manifest.RoomID, manifest.RecordingID, artifact.Name)
+		result.Artifacts[i] = artifact
+	}
+	return result
+}
+
+// artifactURL returns the download URL of a recording artifact
+func artifactURL(roomID, recordingID, name string) string {
+	return strings.TrimSuffix(os.Getenv("NODE_URL"), "/") + "/integrations/recordings/" + url.PathEscape(roomID) + "/" +
+		url.PathEscape(recordingID) + "/artifacts/" + url.PathEscape(name)
+}
+
+// roomRecording returns the recording named in the path if it belongs to the room in the path
+func (s *Server) roomRecording(c *gin.Context) (*recording.Recording, bool) {
+	rec, exists := s.recorder.GetRecording(c.Param("recording_id"))
+	if !exists || rec.RoomID != c.Param("room_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return nil, false
+	}
+	if respondOffline(c, rec) {
+		return nil, false
+	}
+	return rec, true
+}
+
+// recordingManifestHandler returns the artifact manifest of a processed recording
+func (s *Server) recordingManifestHandler(c *gin.Context) {
+	rec, ok := s.roomRecording(c)
+	if !ok {
+		return
+	}
+
+	manifest := s.recorder.Manifest(rec.ID)
+	if manifest == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has not been processed yet")})
+		return
+	}
+
+	c.JSON(http.StatusOK, withArtifactURLs(manifest))
+}
+
+// recordingArtifactHandler serves an artifact file of a processed recording
+func (s *Server) recordingArtifactHandler(c *gin.Context) {
+	rec, ok := s.roomRecording(c)
+	if !ok {
+		return
+	}
+
+	artifact, exists := s.recorder.Artifact(rec.ID, c.Param("name"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Artifact not found")})
+		return
+	}
+
+	c.Header("X-Checksum-SHA256", artifact.SHA256)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Name}))
+	s.serveRecordingFile(c, artifact.Path)
+}
+
+// exportRecordingHandler exports a processed recording as WebM or MP4, optionally with
+// captions from its transcript burned in or attached as a subtitle track. The export
+// runs in the background and is announced by recording.exported; allowed for the
+// owner of the recording, the room creator and admins.
+func (s *Server) exportRecordingHandler(c *gin.Context) {
+	var req struct {
+		RecordingID string `json:"recording_id" binding:"required"`
+		Format      string `json:"format"`
+		Captions    string `json:"captions"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	options := recording.ExportOptions{Format: req.Format, Captions: req.Captions}
+	if options.Format == "" {
+		options.Format = recording.FormatWebM
+	}
+	if options.Format != recording.FormatWebM && options.Format != recording.FormatMP4 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "format must be webm or mp4")})
+		return
+	}
+	if options.Captions != recording.CaptionsNone && options.Captions != recording.CaptionsBurn && options.Captions != recording.CaptionsTrack {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "captions must be burn or track")})
+		return
+	}
+
+	rec, exists := s.recorder.GetRecording(req.RecordingID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+		return
+	}
+	userID := c.GetString("user_id")
+	if userID != rec.OwnerID && userID != s.roomOwner(rec.RoomID) && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+	if rec.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is still in progress")})
+		return
+	}
+
+	if err := s.recorder.CheckExport(rec.ID, options); err != nil {
+		switch {
+		case errors.Is(err, recording.ErrNotProcessed):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has not been processed yet")})
+		case errors.Is(err, recording.ErrNoTimedTranscript):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has no timed transcript")})
+		case errors.Is(err, recording.ErrNoVideo):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording has no video")})
+		case errors.Is(err, recording.ErrExportInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Export is already in progress")})
+		case errors.Is(err, recording.ErrArchived):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is archived; request a restore first")})
+		case errors.Is(err, recording.ErrRestoring):
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Recording is being restored from the archive")})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to export recording")})
+		}
+		return
+	}
+
+	go s.exportRecording(rec.ID, rec.RoomID, options)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Exporting recording",
+		"recording_id": rec.ID,
+		"name":         options.Name(),
+	})
+}
+
+// exportRecording runs an export and publishes recording.exported with the exported
+// artifact
+func (s *Server) exportRecording(recordingID, roomID string, options recording.ExportOptions) {
+	artifact, err := s.recorder.Export(recordingID, options)
+	if err != nil {
+		recordingLog.Errorf("Failed to export recording %s: %v", recordingID, err)
+		s.reportRecordingError(err, roomID, recordingID)
+		return
+	}
+
+	artifact.URL = artifactURL(roomID, recordingID, artifact.Name)
+	s.publishEvent(events.RecordingExported, roomID, map[string]interface{}{
+		"recording_id": recordingID,
+		"format":       options.Format,
+		"captions":     options.Captions,
+		"artifact":     artifact,
+	})
+	recordingLog.Infof("Exported recording %s as %s", recordingID, artifact.Name)
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/notify/email"
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// Usage report periods
+const (
+	reportDaily  = "daily"
+	reportWeekly = "weekly"
+)
+
+const (
+	// reportCheckInterval is how often the report job looks for periods that ended
+	reportCheckInterval = time.Minute
+
+	// maxUsageReports bounds the number of generated reports kept in memory
+	maxUsageReports = 400
+)
+
+// usageRow is the usage of one tenant over a report period
+type usageRow struct {
+	TenantID           string  `json:"tenant_id"`
+	Calls              int     `json:"calls"`
+	CallMinutes        float64 `json:"call_minutes"`
+	ParticipantMinutes float64 `json:"participant_minutes"`
+	UniqueUsers        int     `json:"unique_users"`
+	Recordings         int     `json:"recordings"`
+	StorageBytes       int64   `json:"storage_bytes"` // recordings stored when the report was generated
+}
+
+// usageReport is the usage of every tenant over a day or a week
+type usageReport struct {
+	Period      string     `json:"period"`
+	Start       time.Time  `json:"start"`
+	End         time.Time  `json:"end"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	Tenants     []usageRow `json:"tenants,omitempty"`
+	Total       usageRow   `json:"total"`
+}
+
+// usageReports keeps the reports generated by the report job
+type usageReports struct {
+	reports []usageReport
+	mu      sync.Mutex
+}
+
+// newUsageReports creates an empty report store
+func newUsageReports() *usageReports {
+	return &usageReports{}
+}
+
+// find returns the stored report of a period
+func (r *usageReports) find(period string, start time.Time) (usageReport, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, report := range r.reports {
+		if report.Period == period && report.Start.Equal(start) {
+			return report, true
+		}
+	}
+	return usageReport{}, false
+}
+
+// add stores a report, dropping the oldest beyond maxUsageReports
+func (r *usageReports) add(report usageReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, report)
+	if len(r.reports) > maxUsageReports {
+		r.reports = r.reports[len(r.reports)-maxUsageReports:]
+	}
+}
+
+// list returns the stored reports without their rows, newest first
+func (r *usageReports) list() []usageReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reports := make([]usageReport, 0, len(r.reports))
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		report := r.reports[i]
+		report.Tenants = nil
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// periodStart returns the start of the daily or weekly period containing t, in UTC.
+// Weeks start on Monday.
+func periodStart(period string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if period == reportWeekly {
+		offset := (int(day.Weekday()) + 6) % 7
+		day = day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// periodEnd returns the end of the period starting at start
+func periodEnd(period string, start time.Time) time.Time {
+	if period == reportWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// usageReportPeriods returns the periods listed in USAGE_REPORTS
+func usageReportPeriods() []string {
+	var periods []string
+	for _, period := range envList("USAGE_REPORTS") {
+		if period != reportDaily && period != reportWeekly {
+			serverLog.Warnf("Ignoring unknown usage report period %q: use daily or weekly", period)
+			continue
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// runUsageReports generates the reports of the periods in USAGE_REPORTS as each of
+// them ends and emails them to USAGE_REPORT_EMAILS. Periods that ended before the
+// server started are skipped: calls are kept in memory, so their data is gone.
+func (s *Server) runUsageReports() {
+	periods := usageReportPeriods()
+	if len(periods) == 0 {
+		return
+	}
+	started := time.Now()
+
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		for _, period := range periods {
+			end := periodStart(period, now)
+			start := periodStart(period, end.Add(-time.Nanosecond))
+			if end.Before(started) {
+				continue
+			}
+			if _, exists := s.reports.find(period, start); exists {
+				continue
+			}
+
+			report := s.usageReport(period, start, end)
+			s.reports.add(report)
+			s.emailUsageReport(report)
+			serverLog.Infof("Generated %s usage report for %s", period, start.Format(time.DateOnly))
+		}
+	}
+}
+
+// usageReport computes the usage of every tenant between start and end. Calls count in
+// the period they ended in; recordings in the period they started in.
+func (s *Server) usageReport(period string, start, end time.Time) usageReport {
+	rows := make(map[string]*usageRow)
+	row := func(tenantID string) *usageRow {
+		entry, exists := rows[tenantID]
+		if !exists {
+			entry = &usageRow{TenantID: tenantID}
+			rows[tenantID] = entry
+		}
+		return entry
+	}
+	row("")
+	for _, tenantID := range s.settings().Tenants {
+		row(tenantID)
+	}
+
+	users := make(map[string]map[string]bool)
+	for _, record := range s.calls.list("") {
+		if record.ClosedAt.Before(start) || !record.ClosedAt.Before(end) {
+			continue
+		}
+		entry := row(record.TenantID)
+		entry.Calls++
+		entry.CallMinutes += record.DurationSeconds / 60
+		entry.ParticipantMinutes += record.ParticipantSeconds / 60
+		if users[record.TenantID] == nil {
+			users[record.TenantID] = make(map[string]bool)
+		}
+		for _, participant := range record.Participants {
+			users[record.TenantID][participant.UserID] = true
+		}
+	}
+	for tenantID, seen := range users {
+		row(tenantID).UniqueUsers = len(seen)
+	}
+
+	for _, rec := range s.recorder.Stored(recording.Filter{}) {
+		entry := row(rec.TenantID)
+		entry.StorageBytes += rec.Bytes
+		if !rec.StartedAt.Before(start) && rec.StartedAt.Before(end) {
+			entry.Recordings++
+		}
+	}
+
+	report := usageReport{
+		Period:      period,
+		Start:       start,
+		End:         end,
+		GeneratedAt: time.Now().UTC(),
+		Tenants:     make([]usageRow, 0, len(rows)),
+	}
+	allUsers := make(map[string]bool)
+	for _, seen := range users {
+		for userID := range seen {
+			allUsers[userID] = true
+		}
+	}
+	for _, entry := range rows {
+		report.Tenants = append(report.Tenants, *entry)
+		report.Total.Calls += entry.Calls
+		report.Total.CallMinutes += entry.CallMinutes
+		report.Total.ParticipantMinutes += entry.ParticipantMinutes
+		report.Total.Recordings += entry.Recordings
+		report.Total.StorageBytes += entry.StorageBytes
+	}
+	report.Total.UniqueUsers = len(allUsers)
+	sort.Slice(report.Tenants, func(i, j int) bool {
+		return report.Tenants[i].TenantID < report.Tenants[j].TenantID
+	})
+	return report
+}
+
+// emailUsageReport sends a report to the addresses in USAGE_REPORT_EMAILS
+func (s *Server) emailUsageReport(report usageReport) {
+	if s.mailer == nil {
+		return
+	}
+	language := s.settings().DefaultLanguage
+	link := ""
+	if os.Getenv("NODE_URL") != "" {
+		link = publicURL(fmt.Sprintf("/admin/reports/usage?period=%s&start=%s&format=csv",
+			report.Period, report.Start.Format(time.DateOnly)))
+	}
+
+	for _, address := range envList("USAGE_REPORT_EMAILS") {
+		s.mailer.Send(email.Message{
+			To:       address,
+			Template: email.TemplateUsageReport,
+			Language: language,
+			Data: map[string]interface{}{
+				"Period":  report.Period,
+				"Start":   report.Start.Format(time.DateOnly),
+				"End":     report.End.Add(-time.Nanosecond).Format(time.DateOnly),
+				"Tenants": report.Tenants,
+				"Total":   report.Total,
+				"URL":     link,
+			},
+		})
+	}
+}
+
+// adminUsageReportHandler returns the usage report of a day or week as JSON or, with
+// format=csv, as CSV. period is daily (default) or weekly; start is any date in the
+// period, by default the last one that ended. Reports not generated by the report job
+// are computed on request; those of the current period cover it up to now.
+func (s *Server) adminUsageReportHandler(c *gin.Context) {
+	period := c.DefaultQuery("period", reportDaily)
+	if period != reportDaily && period != reportWeekly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "period must be daily or weekly")})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "format must be json or csv")})
+		return
+	}
+
+	var start time.Time
+	if value := c.Query("start"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "start must be a date such as 2026-03-01")})
+			return
+		}
+		start = periodStart(period, date)
+	} else {
+		start = periodStart(period, periodStart(period, time.Now()).Add(-time.Nanosecond))
+	}
+
+	report, exists := s.reports.find(period, start)
+	if !exists {
+		report = s.usageReport(period, start, periodEnd(period, start))
+	}
+	if tenant := c.Query("tenant"); tenant != "" {
+		report.Tenants = slices.DeleteFunc(slices.Clone(report.Tenants), func(row usageRow) bool {
+			return row.TenantID != tenant
+		})
+	}
+
+	if format == "csv" {
+		writeUsageCSV(c, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// adminListUsageReportsHandler lists the reports generated by the report job
+func (s *Server) adminListUsageReportsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"reports": s.reports.list(),
+	})
+}
+
+// writeUsageCSV responds with a report as CSV, one row per tenant
+func writeUsageCSV(c *gin.Context, report usageReport) {
+	filename := fmt.Sprintf("usage-%s-%s.csv", report.Period, report.Start.Format(time.DateOnly))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"tenant_id", "period_start", "period_end", "calls", "call_minutes",
+		"participant_minutes", "unique_users", "recordings", "storage_bytes"})
+	for _, row := range report.Tenants {
+		w.Write([]string{
+			row.TenantID,
+			report.Start.Format(time.RFC3339),
+			report.End.Format(time.RFC3339),
+			strconv.Itoa(row.Calls),
+			strconv.FormatFloat(row.CallMinutes, 'f', 1, 64),
+			strconv.FormatFloat(row.ParticipantMinutes, 'f', 1, 64),
+			strconv.Itoa(row.UniqueUsers),
+			strconv.Itoa(row.Recordings),
+			strconv.FormatInt(row.StorageBytes, 10),
+		})
+	}
+	w.Flush()
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/repository"
+	"github.com/zubans/video-call-server/internal/storage"
+)
+
+const (
+	// chatHistoryLimit is how many messages of each room the chat manager keeps, and so
+	// how many are reloaded at startup
+	chatHistoryLimit = 100
+
+	// repositoryTimeout bounds each write to the repositories
+	repositoryTimeout = 5 * time.Second
+)
+
+// newRepositories returns the repositories the server persists its state to: the
+// database's if one is configured, behind the cache configured by CACHE_BACKEND,
+// otherwise in-memory ones
+func newRepositories(db *storage.DB) repository.Repositories {
+	if db == nil {
+		return repository.NewMemory(chatHistoryLimit)
+	}
+
+	repos := db.Repositories()
+	if c := newCache(); c != nil {
+		ttl := time.Duration(envInt64("CACHE_TTL_SECONDS", 300)) * time.Second
+		repos = repository.NewCached(repos, c, ttl, chatHistoryLimit)
+	}
+	return repos
+}
+
+// roomRecord is the persisted metadata of a room; the caller holds room.Mu
+func roomRecord(room *models.Room) repository.Room {
+	record := repository.Room{
+		ID:        room.ID,
+		Name:      room.Name,
+		CreatorID: room.CreatorID,
+		TenantID:  room.TenantID,
+		CreatedAt: room.CreatedAt,
+		IsActive:  room.IsActive,
+		IsPublic:  room.IsPublic,
+		JoinCode:  room.JoinCode,
+		Settings:  room.Settings,
+		EndedAt:   room.EndedAt,
+	}
+	if room.Schedule != nil {
+		schedule := *room.Schedule
+		record.Schedule = &schedule
+	}
+	if room.LegalHold != nil {
+		hold := *room.LegalHold
+		record.LegalHold = &hold
+	}
+	return record
+}
+
+// roomFromRecord creates a room from its persisted metadata. Its schedule starts with
+// no reminders sent or attendees; meetings already over are not reported as missed.
+func roomFromRecord(record repository.Room) *models.Room {
+	room := &models.Room{
+		ID:        record.ID,
+		Name:      record.Name,
+		CreatorID: record.CreatorID,
+		TenantID:  record.TenantID,
+		Clients:   make(map[string]*models.Client),
+		Tracks:    make(map[string]*models.PublishedTrack),
+		CreatedAt: record.CreatedAt,
+		IsActive:  record.IsActive,
+		IsPublic:  record.IsPublic,
+		JoinCode:  record.JoinCode,
+		Settings:  record.Settings,
+		Schedule:  record.Schedule,
+		EndedAt:   record.EndedAt,
+		LegalHold: record.LegalHold,
+	}
+	if room.Schedule != nil {
+		room.Schedule.RemindersSent = make(map[int]bool)
+		room.Schedule.Attendees = make(map[string]bool)
+		room.Schedule.MissedReported = !time.Now().Before(scheduleEnd(room.Schedule))
+	}
+	return room
+}
+
+// saveRoom writes a room's metadata to the room repository, together with events
+// announcing the change
+func (s *Server) saveRoom(room *models.Room, evs ...events.Event) {
+	room.Mu.RLock()
+	record := roomRecord(room)
+	room.Mu.RUnlock()
+
+	s.persist("room", record.ID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Rooms.SaveRoom(ctx, record)
+	}, evs...)
+}
+
+// saveDeletedRoom writes a soft-deleted room to the room repository, so it stays
+// restorable after a restart, together with events announcing the deletion
+func (s *Server) saveDeletedRoom(deleted *deletedRoom, evs ...events.Event) {
+	deleted.room.Mu.RLock()
+	record := roomRecord(deleted.room)
+	deleted.room.Mu.RUnlock()
+	record.DeletedAt = deleted.deletedAt
+	record.DeletedBy = deleted.deletedBy
+
+	s.persist("room", record.ID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Rooms.SaveRoom(ctx, record)
+	}, evs...)
+}
+
+// forgetRoom removes a purged room and its chat history from the repositories
+func (s *Server) forgetRoom(roomID string) {
+	s.persist("room", roomID, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Chat.DeleteRoomMessages(ctx, roomID); err != nil {
+			return err
+		}
+		return repos.Rooms.DeleteRoom(ctx, roomID)
+	})
+}
+
+// saveUser writes an account to the user repository
+func (s *Server) saveUser(userID string) {
+	user, exists := auth.GetUserByID(userID)
+	if !exists {
+		return
+	}
+	record := auth.UserBackup{User: *user, SSOSubject: user.SSOSubject}
+
+	s.persist("user", userID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Users.SaveUser(ctx, record)
+	})
+}
+
+// forgetUser removes a deleted account from the user repository
+func (s *Server) forgetUser(userID string) {
+	s.persist("user", userID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Users.DeleteUser(ctx, userID)
+	})
+}
+
+// saveMessage writes a chat message to the chat repository
+func (s *Server) saveMessage(roomID, messageID string) {
+	message, exists := s.chatManager.Snapshot(roomID, messageID)
+	if !exists {
+		return
+	}
+
+	s.persist("chat message", messageID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Chat.SaveMessage(ctx, message)
+	})
+}
+
+// saveRecording writes the metadata of a finished recording to the recording
+// repository, together with events announcing it
+func (s *Server) saveRecording(recordingID string, evs ...events.Event) {
+	rec, exists := s.recorder.Metadata(recordingID)
+	if !exists || rec.Active {
+		s.persist("recording", recordingID, nil, evs...)
+		return
+	}
+
+	s.persist("recording", recordingID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Recordings.SaveRecording(ctx, rec)
+	}, evs...)
+}
+
+// forgetRecording removes a deleted recording from the recording repository
+func (s *Server) forgetRecording(recordingID string) {
+	s.persist("recording", recordingID, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Recordings.DeleteRecording(ctx, recordingID)
+	})
+}
+
+// persist runs a write to the repositories and queues events for webhooks and the
+// message broker in the same transaction, then publishes the events to in-process
+// subscribers. write may be nil to only queue events. The in-memory state is
+// authoritative while the server runs, so failures are logged rather than failing the
+// request.
+func (s *Server) persist(kind, id string, write func(ctx context.Context, repos repository.Repositories) error, evs ...events.Event) {
+	entries := s.outboxEntries(evs)
+	if write != nil || len(entries) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), repositoryTimeout)
+		err := s.repos.Atomic(ctx, func(repos repository.Repositories) error {
+			if write != nil {
+				if err := write(ctx, repos); err != nil {
+					return err
+				}
+			}
+			if len(entries) == 0 {
+				return nil
+			}
+			return repos.Outbox.Enqueue(ctx, entries...)
+		})
+		cancel()
+		if err != nil {
+			serverLog.Errorf("Failed to persist %s %s: %v", kind, id, err)
+		} else if len(entries) > 0 {
+			s.outbox.notify()
+		}
+	}
+
+	for _, event := range evs {
+		s.events.Publish(event)
+	}
+}
+
+// loadState reloads accounts, rooms, chat history and recordings from the repositories
+// at startup
+func (s *Server) loadState() {
+	ctx := context.Background()
+
+	users, err := s.repos.Users.ListUsers(ctx)
+	if err != nil {
+		serverLog.Fatalf("Failed to load users: %v", err)
+	}
+	for _, user := range users {
+		if err := auth.ImportUser(user); err != nil {
+			serverLog.Warnf("Failed to load user %s: %v", user.ID, err)
+		}
+	}
+
+	records, err := s.repos.Rooms.ListRooms(ctx)
+	if err != nil {
+		serverLog.Fatalf("Failed to load rooms: %v", err)
+	}
+	for _, record := range records {
+		room := roomFromRecord(record)
+		if !record.DeletedAt.IsZero() {
+			s.deletedRooms[room.ID] = &deletedRoom{room: room, deletedAt: record.DeletedAt, deletedBy: record.DeletedBy}
+		} else {
+			s.roomManager.Rooms[room.ID] = room
+			if s.cluster != nil {
+				if err := s.cluster.ClaimRoom(ctx, room.ID); err != nil {
+					serverLog.Warnf("Failed to claim room %s: %v", room.ID, err)
+				}
+			}
+		}
+
+		messages, err := s.repos.Chat.ListMessages(ctx, room.ID, chatHistoryLimit)
+		if err != nil {
+			serverLog.Fatalf("Failed to load chat history of room %s: %v", room.ID, err)
+		}
+		s.chatManager.Import(room.ID, messages)
+	}
+	s.metrics.SetRoomsActive(float64(len(s.roomManager.Rooms)))
+
+	recordings, err := s.repos.Recordings.ListRecordings(ctx)
+	if err != nil {
+		serverLog.Fatalf("Failed to load recordings: %v", err)
+	}
+	for _, rec := range recordings {
+		if err := s.recorder.ImportMetadata(rec); err != nil {
+			serverLog.Warnf("Failed to load recording %s: %v", rec.ID, err)
+		}
+	}
+
+	if len(users)+len(records)+len(recordings) > 0 {
+		serverLog.Infof("Loaded %d users, %d rooms and %d recordings", len(users), len(records), len(recordings))
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/repository"
+	"github.com/zubans/video-call-server/pkg/client"
+)
+
+// TestLoadStateFromRepositories starts a server on in-memory repositories holding the
+// state of an earlier run and checks that rooms, the trash and chat history come back
+func TestLoadStateFromRepositories(t *testing.T) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Hour)
+	repos := repository.NewMemory(chatHistoryLimit)
+	repos.Rooms.SaveRoom(ctx, repository.Room{ID: "kept", Name: "Kept", CreatorID: "owner", CreatedAt: created, IsActive: true})
+	repos.Rooms.SaveRoom(ctx, repository.Room{ID: "trashed", Name: "Trashed", CreatorID: "owner", CreatedAt: created,
+		DeletedAt: time.Now(), DeletedBy: "owner"})
+	repos.Chat.SaveMessage(ctx, chat.Message{ID: "m1", Type: "text", RoomID: "kept", UserID: "owner", Content: "hello", Timestamp: created})
+
+	s, _ := newTestServerWith(t, &repos)
+
+	room, exists := s.getRoom("kept")
+	if !exists {
+		t.Fatal("room was not loaded")
+	}
+	if room.Name != "Kept" || room.CreatorID != "owner" || !room.IsActive {
+		t.Errorf("loaded room = %q by %q, active %t", room.Name, room.CreatorID, room.IsActive)
+	}
+
+	if _, exists := s.getRoom("trashed"); exists {
+		t.Error("soft-deleted room was loaded as active")
+	}
+	if _, exists := s.findRoom("trashed"); !exists {
+		t.Error("soft-deleted room was not loaded into the trash")
+	}
+
+	messages := s.chatManager.GetMessages("kept")
+	if len(messages) != 1 || messages[0].Content != "hello" {
+		t.Errorf("loaded chat history = %v", messages)
+	}
+}
+
+// TestRoomChangesWriteThrough checks that a room and a chat message created through the
+// API reach the repositories
+func TestRoomChangesWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	repos := repository.NewMemory(chatHistoryLimit)
+	_, ts := newTestServerWith(t, &repos)
+
+	api := client.New(ts.URL)
+	if err := api.Register(ctx, "writer", "writer@example.com", "Passw0rd!23"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := api.Login(ctx, "writer", "Passw0rd!23"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	created, err := api.CreateRoom(ctx, "persisted")
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+
+	record, err := repos.Rooms.GetRoom(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("room was not saved: %v", err)
+	}
+	if record.Name != "persisted" || !record.IsActive {
+		t.Errorf("saved room = %q, active %t", record.Name, record.IsActive)
+	}
+
+	if err := api.SendChat(ctx, created.ID, "saved"); err != nil {
+		t.Fatalf("send chat: %v", err)
+	}
+	messages, err := repos.Chat.ListMessages(ctx, created.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Content != "saved" {
+		t.Errorf("saved chat history = %v", messages)
+	}
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/audit"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/hostpolicy"
+	"github.com/zubans/video-call-server/internal/roombots"
+)
+
+// botSecretHeader carries the secret of a room bot on bot API requests
+const botSecretHeader = "X-Bot-Secret"
+
+// Errors of bot actions
+var (
+	errBotRoomNotFound   = errors.New("room not found")
+	errBotClientNotFound = errors.New("client not found")
+	errBotMessageTooLong = errors.New("message is too long")
+)
+
+// roomOwner returns the creator of a room, or "" for unknown rooms
+func (s *Server) roomOwner(roomID string) string {
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		return ""
+	}
+
+	room.Mu.RLock()
+	defer room.Mu.RUnlock()
+	return room.CreatorID
+}
+
+// dispatchChat delivers a chat message to the room bots; botID names the bot that
+// posted it, if any, so it does not receive its own message
+func (s *Server) dispatchChat(message *chat.Message, botID string) {
+	data := map[string]interface{}{
+		"message_id":   message.ID,
+		"type":         message.Type,
+		"user_id":      message.UserID,
+		"username":     message.Username,
+		"display_name": message.DisplayName,
+		"content":      message.Content,
+	}
+	if botID != "" {
+		data["bot_id"] = botID
+	}
+
+	s.roomBots.Dispatch(events.Event{
+		Type:   events.ChatMessage,
+		RoomID: message.RoomID,
+		Time:   message.Timestamp,
+		Data:   data,
+	})
+}
+
+// applyBotActions carries out the actions of a room bot in a room on behalf of its
+// owner, who created the room or registered the bot for all of their rooms
+func (s *Server) applyBotActions(bot roombots.Bot, roomID string, actions []roombots.Action) []error {
+	errs := make([]error, len(actions))
+
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		for i := range errs {
+			errs[i] = errBotRoomNotFound
+		}
+		return errs
+	}
+
+	for i, action := range actions {
+		switch action.Type {
+		case roombots.ActionChat:
+			if limit := s.settings().MaxChatMessageLength; limit > 0 && utf8.RuneCountInString(action.Message) > limit {
+				errs[i] = errBotMessageTooLong
+				continue
+			}
+			message := s.chatManager.AddBotMessage(room.ID, chat.Sender{UserID: bot.OwnerID, Username: bot.Name}, action.Message)
+			s.saveMessage(room.ID, message.ID)
+			s.hub.Publish(room.ID, "chat", message)
+			s.metrics.IncrementChatMessagesSent(room.TenantID)
+			s.dispatchChat(message, bot.ID)
+
+		case roombots.ActionRemoveParticipant:
+			room.Mu.RLock()
+			client, found := room.Clients[action.ClientID]
+			room.Mu.RUnlock()
+
+			// Only clients of the bot's room, whether joined or just connected to its WebSocket
+			if !found && !slices.Contains(s.hub.RoomSenders(room.ID), action.ClientID) {
+				errs[i] = errBotClientNotFound
+				continue
+			}
+			s.hub.DisconnectSender(action.ClientID)
+			if found {
+				s.removeClient(room, client)
+			}
+
+			s.audit.Record(audit.Entry{
+				ActorID: bot.OwnerID,
+				Actor:   "bot:" + bot.Name,
+				Action:  "connection.disconnect",
+				Target:  action.ClientID,
+				Details: map[string]string{
+					"room_id": room.ID,
+					"bot_id":  bot.ID,
+					"reason":  action.Reason,
+				},
+			})
+
+		case roombots.ActionDeleteMessage:
+			deleted, err := s.chatManager.DeleteMessage(room.ID, action.MessageID, bot.OwnerID)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			s.saveMessage(room.ID, deleted.ID)
+			s.hub.Publish(room.ID, "chat-deleted", gin.H{
+				"room_id":    room.ID,
+				"message_id": deleted.ID,
+				"deleted_by": bot.OwnerID,
+			})
+		}
+	}
+
+	return errs
+}
+
+// createRoomBotHandler registers a bot for one of the user's rooms, or for all of
+// them without room_id; the secret is only returned here
+func (s *Server) createRoomBotHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		Name   string   `json:"name" binding:"required"`
+		URL    string   `json:"url" binding:"required"`
+		RoomID string   `json:"room_id"`
+		Events []string `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.RoomID != "" {
+		if _, exists := s.getRoom(req.RoomID); !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+			return
+		}
+		if s.roomOwner(req.RoomID) != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+			return
+		}
+	}
+
+	bot, secret, err := s.roomBots.Create(strings.TrimSpace(req.Name), userID, req.RoomID, req.URL, req.Events)
+	if err != nil {
+		respondRoomBotError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Bot registered",
+		"bot":     bot,
+		"secret":  secret,
+	})
+}
+
+// listRoomBotsHandler lists the user's bots and the events they can receive
+func (s *Server) listRoomBotsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"bots":   s.roomBots.List(c.MustGet("user_id").(string)),
+		"events": roombots.EventTypes,
+	})
+}
+
+// deleteRoomBotHandler unregisters one of the user's bots
+func (s *Server) deleteRoomBotHandler(c *gin.Context) {
+	if err := s.roomBots.Delete(c.Param("id"), c.MustGet("user_id").(string)); err != nil {
+		respondRoomBotError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bot deleted"})
+}
+
+// botAuthMiddleware authenticates room bots by their secret; user JWTs are not accepted
+func (s *Server) botAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(botSecretHeader)
+		if secret == "" {
+			secret = strings.TrimPrefix(c.GetHeader("Authorization"), "Bot ")
+		}
+		if secret == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Bot secret required")})
+			c.Abort()
+			return
+		}
+
+		bot, err := s.roomBots.Authenticate(secret)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid bot secret")})
+			c.Abort()
+			return
+		}
+
+		// Bots act on behalf of their owner
+		c.Set("user_id", bot.OwnerID)
+		c.Set("username", bot.Name)
+		c.Set("room_bot", bot)
+
+		c.Next()
+	}
+}
+
+// botActionsHandler lets a bot act in a room it serves outside of an event delivery,
+// e.g. to post meeting notes once they are ready
+func (s *Server) botActionsHandler(c *gin.Context) {
+	bot := c.MustGet("room_bot").(*roombots.Bot)
+
+	var req struct {
+		Actions []roombots.Action `json:"actions" binding:"required,min=1,max=20"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+	for _, action := range req.Actions {
+		if err := action.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+	}
+
+	roomID := c.Param("id")
+	if _, exists := s.getRoom(roomID); !exists || !s.roomBots.CanAccess(bot, roomID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	results := make([]gin.H, len(req.Actions))
+	for i, err := range s.applyBotActions(*bot, roomID, req.Actions) {
+		results[i] = gin.H{"type": req.Actions[i].Type, "ok": err == nil}
+		if err != nil {
+			results[i]["error"] = tr(c, err.Error())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// respondRoomBotError answers a failed room bot operation
+func respondRoomBotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, roombots.ErrBotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Bot not found")})
+	case errors.Is(err, hostpolicy.ErrHostNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Bot URL host is not allowed")})
+	case errors.Is(err, roombots.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, roombots.ErrInvalidEvent.Error()), "events": roombots.EventTypes})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+	}
+}
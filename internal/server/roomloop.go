@@ -0,0 +1,233 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// roomLoopQueueSize is the number of commands that may wait for a room's event loop
+const roomLoopQueueSize = 64
+
+// roomLoopIdle is how long a loop waits for a command before retiring, so that loops
+// started by late callbacks of closed rooms do not linger
+const roomLoopIdle = time.Minute
+
+// roomLoop serializes state changes of a room (joins, leaves, kicks, track
+// publication, settings, holds) on a single goroutine, so HTTP handlers, WebRTC
+// callbacks and background jobs observe them in one deterministic order. Changes are
+// sent to the loop with roomDo or roomUpdate; code already running in a command gets
+// the loop and uses its update and stop methods instead, since sending to the loop
+// from the loop would deadlock. room.Mu still guards reads of room state from other
+// goroutines.
+//
+// A room has at most one loop at a time. A loop retires itself when the room closes,
+// its last participant leaves or it has been idle for roomLoopIdle; the next command
+// starts a new one.
+type roomLoop struct {
+	roomID   string
+	commands chan func(*roomLoop)
+	quit     chan struct{} // closed when the loop has retired
+	retiring bool          // set by stop; only touched on the loop
+}
+
+// update runs fn with room.Mu held; it is the in-loop counterpart of roomUpdate and
+// must only be called from a command running on l
+func (l *roomLoop) update(room *models.Room, fn func()) {
+	if room.ID != l.roomID {
+		panic("room loop of " + l.roomID + " used for room " + room.ID)
+	}
+	room.Mu.Lock()
+	defer room.Mu.Unlock()
+	fn()
+}
+
+// stop retires the loop once the running command returns and no other command
+// waits; it must only be called from a command running on l
+func (l *roomLoop) stop() {
+	l.retiring = true
+}
+
+// roomLoops holds the event loops of all rooms
+type roomLoops struct {
+	loops map[string]*roomLoop
+	mu    sync.Mutex
+}
+
+// run executes commands until the loop retires
+func (r *roomLoops) run(l *roomLoop) {
+	idle := time.NewTimer(roomLoopIdle)
+	defer idle.Stop()
+
+	for {
+		select {
+		case cmd := <-l.commands:
+			cmd(l)
+			if l.retiring && r.retire(l) {
+				return
+			}
+		case <-idle.C:
+			if r.retire(l) {
+				return
+			}
+		}
+		idle.Reset(roomLoopIdle)
+	}
+}
+
+// retire removes a loop from the room loops unless a command is waiting for it, in
+// which case the room is in use again and the loop keeps running
+func (r *roomLoops) retire(l *roomLoop) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l.retiring = false
+	if len(l.commands) > 0 {
+		return false
+	}
+	delete(r.loops, l.roomID)
+	close(l.quit)
+	return true
+}
+
+// loopFor returns the event loop of a room, starting one if it has none
+func (s *Server) loopFor(roomID string) *roomLoop {
+	s.roomLoops.mu.Lock()
+	defer s.roomLoops.mu.Unlock()
+
+	if s.roomLoops.loops == nil {
+		s.roomLoops.loops = make(map[string]*roomLoop)
+	}
+
+	loop, exists := s.roomLoops.loops[roomID]
+	if !exists {
+		loop = &roomLoop{
+			roomID:   roomID,
+			commands: make(chan func(*roomLoop), roomLoopQueueSize),
+			quit:     make(chan struct{}),
+		}
+		s.roomLoops.loops[roomID] = loop
+		go s.roomLoops.run(loop)
+	}
+	return loop
+}
+
+// stopRoomLoop asks the event loop of a room that closed or was deleted to retire
+// after the commands already sent to it. It must not be called from the loop, which
+// uses loop.stop instead.
+func (s *Server) stopRoomLoop(roomID string) {
+	s.roomLoops.mu.Lock()
+	loop, exists := s.roomLoops.loops[roomID]
+	s.roomLoops.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	select {
+	case loop.commands <- (*roomLoop).stop:
+	case <-loop.quit:
+	}
+}
+
+// roomRun runs fn on the room's event loop, passing it the loop, and waits for it to
+// finish. A loop that retired before taking fn rejects it, and fn goes to the room's
+// next loop; it never runs on the caller's goroutine. It must not be called from the
+// room's loop.
+func (s *Server) roomRun(room *models.Room, fn func(loop *roomLoop)) {
+	for {
+		loop := s.loopFor(room.ID)
+		done := make(chan struct{})
+
+		select {
+		case loop.commands <- func(l *roomLoop) {
+			fn(l)
+			close(done)
+		}:
+		case <-loop.quit:
+			continue
+		}
+
+		select {
+		case <-done:
+			return
+		case <-loop.quit:
+			// fn completes before its loop retires, so done is closed if it ran
+			select {
+			case <-done:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// roomDo runs fn on the room's event loop and waits for it to finish; see roomRun
+func (s *Server) roomDo(room *models.Room, fn func()) {
+	s.roomRun(room, func(*roomLoop) {
+		fn()
+	})
+}
+
+// roomUpdate runs fn on the room's event loop with room.Mu held, for changes that
+// only touch room state
+func (s *Server) roomUpdate(room *models.Room, fn func()) {
+	s.roomRun(room, func(loop *roomLoop) {
+		loop.update(room, fn)
+	})
+}
+
+// addClient adds a participant to a room and subscribes it to the room's tracks
+func (s *Server) addClient(room *models.Room, client *models.Client) {
+	s.roomRun(room, func(loop *roomLoop) {
+		s.enterRoom(loop, room, client)
+	})
+}
+
+// joinClient adds a participant to a room like addClient, unless the room was closed,
+// archived or deleted since the caller checked it. It reports whether the participant
+// joined.
+func (s *Server) joinClient(room *models.Room, client *models.Client) bool {
+	joined := false
+	s.roomRun(room, func(loop *roomLoop) {
+		room.Mu.RLock()
+		joined = room.IsActive
+		room.Mu.RUnlock()
+		if joined {
+			s.enterRoom(loop, room, client)
+		}
+	})
+	return joined
+}
+
+// enterRoom adds a participant to a room from its event loop
+func (s *Server) enterRoom(loop *roomLoop, room *models.Room, client *models.Client) {
+	loop.update(room, func() {
+		room.Clients[client.ID] = client
+	})
+
+	// Update metrics and notify dashboards
+	s.participantJoined(loop, room, client)
+
+	// Subscribe to tracks already published in the room
+	if client.Conn != nil {
+		s.subscribeToRoomTracks(loop, room, client)
+	}
+}
+
+// dropClient removes a participant from a room, updating metrics and ending the
+// session if the room is left empty. It reports whether the participant was in the room.
+func (s *Server) dropClient(room *models.Room, client *models.Client) bool {
+	exists := false
+	s.roomRun(room, func(loop *roomLoop) {
+		loop.update(room, func() {
+			_, exists = room.Clients[client.ID]
+			delete(room.Clients, client.ID)
+		})
+
+		if exists {
+			s.participantLeft(loop, room, client)
+		}
+	})
+	return exists
+}
@@ -0,0 +1,420 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/notes"
+)
+
+// Room listing defaults
+const (
+	defaultRoomsLimit = 50
+	maxRoomsLimit     = 200
+)
+
+// Room listing status filters
+const (
+	roomStatusActive   = "active"
+	roomStatusArchived = "archived"
+	roomStatusAll      = "all"
+)
+
+// roomSorts maps sort parameters to comparisons of two rooms; a leading "-" reverses the order
+var roomSorts = map[string]func(a, b roomSummaryView) bool{
+	"created_at":   func(a, b roomSummaryView) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"name":         func(a, b roomSummaryView) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+	"participants": func(a, b roomSummaryView) bool { return a.ParticipantCount < b.ParticipantCount },
+}
+
+// roomSummaryView is the JSON representation of a room in listings
+type roomSummaryView struct {
+	ID               string              `json:"id"`
+	Name             string              `json:"name"`
+	CreatorID        string              `json:"creator_id"`
+	ParticipantCount int                 `json:"participant_count"`
+	CreatedAt        time.Time           `json:"created_at"`
+	IsActive         bool                `json:"is_active"`
+	IsPublic         bool                `json:"is_public"`
+	JoinCode         string              `json:"join_code"`
+	Settings         models.RoomSettings `json:"settings"`
+	Schedule         *scheduleView       `json:"schedule,omitempty"`
+	Notes            *notes.Notes        `json:"notes,omitempty"` // newest meeting notes, on single rooms
+	TenantID         string              `json:"-"`
+}
+
+// roomSummary returns the listing view of a room; the caller holds room.Mu
+func roomSummary(room *models.Room) roomSummaryView {
+	return roomSummaryView{
+		ID:               room.ID,
+		Name:             room.Name,
+		CreatorID:        room.CreatorID,
+		ParticipantCount: len(room.Clients),
+		CreatedAt:        room.CreatedAt,
+		IsActive:         room.IsActive,
+		IsPublic:         room.IsPublic,
+		JoinCode:         room.JoinCode,
+		Settings:         room.Settings,
+		Schedule:         viewSchedule(room.Schedule),
+		TenantID:         room.TenantID,
+	}
+}
+
+// roomQuery holds the filters, order and page of a room listing
+type roomQuery struct {
+	Tenant          string // only rooms of this tenant are listed
+	Name            string
+	CreatorID       string
+	Status          string
+	MinParticipants int
+	Sort            string
+	Limit           int
+	Offset          int
+	PublicOnly      bool
+}
+
+// parseRoomQuery reads listing parameters from the query string
+func parseRoomQuery(c *gin.Context) (roomQuery, error) {
+	q := roomQuery{
+		Tenant:    tenantID(c),
+		Name:      strings.ToLower(strings.TrimSpace(c.Query("q"))),
+		CreatorID: c.Query("creator_id"),
+		Status:    c.DefaultQuery("status", roomStatusActive),
+		Sort:      c.DefaultQuery("sort", "-created_at"),
+		Limit:     defaultRoomsLimit,
+	}
+
+	if err := q.validate(); err != nil {
+		return q, err
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"min_participants", &q.MinParticipants, 0},
+		{"limit", &q.Limit, maxRoomsLimit},
+		{"offset", &q.Offset, 0},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || (param.max > 0 && n > param.max) {
+			return q, fmt.Errorf("invalid %s: %q", param.name, raw)
+		}
+		*param.value = n
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultRoomsLimit
+	}
+
+	return q, nil
+}
+
+// validate checks the status filter and sort order
+func (q roomQuery) validate() error {
+	if q.Status != roomStatusActive && q.Status != roomStatusArchived && q.Status != roomStatusAll {
+		return fmt.Errorf("status must be %s, %s or %s", roomStatusActive, roomStatusArchived, roomStatusAll)
+	}
+	if _, ok := roomSorts[strings.TrimPrefix(q.Sort, "-")]; !ok {
+		return errors.New("sort must be created_at, name or participants, optionally prefixed with -")
+	}
+	return nil
+}
+
+// matches reports whether a room passes the listing filters
+func (q roomQuery) matches(room roomSummaryView) bool {
+	switch {
+	case room.TenantID != q.Tenant:
+		return false
+	case q.Status == roomStatusActive && !room.IsActive,
+		q.Status == roomStatusArchived && room.IsActive:
+		return false
+	case q.Name != "" && !strings.Contains(strings.ToLower(room.Name), q.Name):
+		return false
+	case q.CreatorID != "" && room.CreatorID != q.CreatorID:
+		return false
+	case room.ParticipantCount < q.MinParticipants:
+		return false
+	case q.PublicOnly && !room.IsPublic:
+		return false
+	}
+	return true
+}
+
+// listRooms filters, sorts and pages the rooms visible to a user; archived
+// rooms are only visible to their creator and admins
+func (s *Server) listRooms(q roomQuery, userID string, isAdmin bool) ([]roomSummaryView, int) {
+	s.roomManager.Mu.RLock()
+	rooms := make([]roomSummaryView, 0, len(s.roomManager.Rooms))
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		summary := roomSummary(room)
+		room.Mu.RUnlock()
+
+		if !summary.IsActive && !isAdmin && summary.CreatorID != userID {
+			continue
+		}
+		if q.matches(summary) {
+			rooms = append(rooms, summary)
+		}
+	}
+	s.roomManager.Mu.RUnlock()
+
+	less := roomSorts[strings.TrimPrefix(q.Sort, "-")]
+	desc := strings.HasPrefix(q.Sort, "-")
+	sort.SliceStable(rooms, func(i, j int) bool {
+		if desc {
+			return less(rooms[j], rooms[i])
+		}
+		return less(rooms[i], rooms[j])
+	})
+
+	total := len(rooms)
+	if q.Offset >= total {
+		return []roomSummaryView{}, total
+	}
+	end := q.Offset + q.Limit
+	if end > total {
+		end = total
+	}
+	return rooms[q.Offset:end], total
+}
+
+// roomETag is the entity tag of a room's current version; the caller holds room.Mu
+func roomETag(room *models.Room) string {
+	return fmt.Sprintf(`"%s-%d"`, room.ID, room.Version)
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists an entity tag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// getRoomHandler returns a room with its ETag and newest meeting notes; archived rooms
+// are only visible to their creator and admins. If-None-Match with the current ETag answers 304.
+func (s *Server) getRoomHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	room.Mu.RLock()
+	summary := roomSummary(room)
+	etag := roomETag(room)
+	room.Mu.RUnlock()
+
+	if !summary.IsActive && summary.CreatorID != c.GetString("user_id") && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	c.Header("ETag", etag)
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if visible := s.visibleNotes(c, room); len(visible) > 0 {
+		summary.Notes = visible[0]
+	}
+	c.JSON(http.StatusOK, gin.H{"room": summary})
+}
+
+// updateRoomHandler renames a room, lists it publicly, toggles chat announcements,
+// (re)schedules it, or archives and restores it; allowed for the creator and admins. The request must carry
+// the room's ETag in If-Match, so that concurrent edits are refused with 412 instead of
+// overwriting each other.
+func (s *Server) updateRoomHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if room.CreatorID != userID && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+
+	var req struct {
+		Name              *string `json:"name"`
+		IsActive          *bool   `json:"is_active"`
+		IsPublic          *bool   `json:"is_public"`
+		ChatAnnouncements *bool   `json:"chat_announcements"`
+		// Schedule replaces the room's schedule; null removes it
+		Schedule json.RawMessage `json:"schedule"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if !s.validRoomName(c, name) {
+			return
+		}
+		req.Name = &name
+	}
+
+	var schedule *models.RoomSchedule
+	if len(req.Schedule) > 0 && string(req.Schedule) != "null" {
+		var scheduleReq scheduleRequest
+		decoder := json.NewDecoder(bytes.NewReader(req.Schedule))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&scheduleReq); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		var err error
+		if schedule, err = scheduleReq.parse(tenantID(c)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+	}
+
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": tr(c, "If-Match header with the room's ETag is required")})
+		return
+	}
+
+	// On the room's loop, so that archiving is ordered with concurrent joins
+	var summary roomSummaryView
+	var etag, joinCode, creatorID string
+	modified, archived := false, false
+	s.roomUpdate(room, func() {
+		if etag = roomETag(room); !etagMatches(ifMatch, etag) {
+			modified = true
+			summary = roomSummary(room)
+			return
+		}
+		archived = req.IsActive != nil && !*req.IsActive && room.IsActive
+		if req.Name != nil {
+			room.Name = *req.Name
+		}
+		if req.IsActive != nil {
+			room.IsActive = *req.IsActive
+			// A reopened room gets a new idle period
+			if room.IsActive {
+				room.EndedAt = time.Time{}
+				if !room.EmptySince.IsZero() {
+					room.EmptySince = time.Now()
+				}
+			}
+		}
+		if req.IsPublic != nil {
+			room.IsPublic = *req.IsPublic
+		}
+		if req.ChatAnnouncements != nil {
+			room.Settings.ChatAnnouncements = *req.ChatAnnouncements
+		}
+		if len(req.Schedule) > 0 {
+			// A new schedule starts with no reminders sent
+			room.Schedule = schedule
+		}
+		room.Version++
+		summary = roomSummary(room)
+		etag = roomETag(room)
+		joinCode, creatorID = room.JoinCode, room.CreatorID
+	})
+	if modified {
+		c.Header("ETag", etag)
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"error": tr(c, "Room was modified by someone else; reload it and retry"),
+			"room":  summary,
+		})
+		return
+	}
+
+	if schedule != nil {
+		s.sendInvites(room.ID, summary.Name, joinCode, creatorID, summary.Schedule)
+	}
+
+	// Archived rooms cannot be used, so end the current call
+	if archived {
+		s.endRoom(room, "archived")
+		summary.ParticipantCount = 0
+	}
+
+	s.saveRoom(room, newEvent(events.RoomUpdated, room.ID, map[string]interface{}{
+		"name":      summary.Name,
+		"is_active": summary.IsActive,
+		"is_public": summary.IsPublic,
+	}))
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Room updated",
+		"room":    summary,
+	})
+}
+
+// listRoomsHandler lists rooms with optional search, filters, sort order and pagination
+func (s *Server) listRoomsHandler(c *gin.Context) {
+	s.respondRooms(c, "")
+}
+
+// listArchivedRoomsHandler lists archived rooms: all of them for admins, otherwise the user's own
+func (s *Server) listArchivedRoomsHandler(c *gin.Context) {
+	s.respondRooms(c, roomStatusArchived)
+}
+
+// respondRooms writes a page of rooms; status overrides the status query parameter when set
+func (s *Server) respondRooms(c *gin.Context, status string) {
+	q, err := parseRoomQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+	if status != "" {
+		q.Status = status
+	}
+
+	rooms, total := s.listRooms(q, c.GetString("user_id"), c.GetString("role") == auth.RoleAdmin)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rooms":  rooms,
+		"total":  total,
+		"limit":  q.Limit,
+		"offset": q.Offset,
+	})
+}
+
+// validRoomName checks a trimmed room name against MAX_ROOM_NAME_LENGTH, answering 400 if it fails
+func (s *Server) validRoomName(c *gin.Context, name string) bool {
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "name is required")})
+		return false
+	}
+	if limit := s.settings().MaxRoomNameLength; limit > 0 && utf8.RuneCountInString(name) > limit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trf(c, "name must be 1 to %d characters", limit)})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/cluster"
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+const (
+	// routingRedirect answers requests for remote rooms with a 307 to the owner node
+	routingRedirect = "redirect"
+
+	// routingProxy forwards requests for remote rooms to the owner node
+	routingProxy = "proxy"
+
+	// forwardedHeader marks requests already routed by another node to prevent loops
+	forwardedHeader = "X-Videocall-Forwarded-By"
+)
+
+// Cluster backends selected by CLUSTER_BACKEND
+const (
+	clusterRedis = "redis"
+	clusterNATS  = "nats"
+)
+
+// clusterAddress returns the backend selected by CLUSTER_BACKEND, redis by default, and
+// the address of its server: REDIS_URL or NATS_URL. An empty address runs the node
+// standalone.
+func clusterAddress() (backend, address string) {
+	backend = strings.ToLower(envString("CLUSTER_BACKEND", clusterRedis))
+	switch backend {
+	case clusterRedis:
+		return backend, os.Getenv("REDIS_URL")
+	case clusterNATS:
+		return backend, os.Getenv("NATS_URL")
+	default:
+		serverLog.Fatalf("Unknown CLUSTER_BACKEND %q: use redis or nats", backend)
+		return backend, ""
+	}
+}
+
+// newClusterRegistry joins the cluster configured by CLUSTER_BACKEND, through Redis or
+// a NATS key-value bucket; nil runs the node standalone
+func newClusterRegistry() *cluster.Registry {
+	backend, address := clusterAddress()
+	if address == "" {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	node := cluster.Node{
+		ID:  envString("NODE_ID", hostname),
+		URL: os.Getenv("NODE_URL"),
+	}
+	if node.URL == "" {
+		serverLog.Fatalf("NODE_URL is required when the node joins a cluster")
+	}
+
+	var registry *cluster.Registry
+	var err error
+	if backend == clusterNATS {
+		// Revoked tokens and token sessions must outlive the longest token
+		retention := auth.Policy(auth.TokenAccess).TTL
+		if refresh := auth.Policy(auth.TokenRefresh).TTL; refresh > retention {
+			retention = refresh
+		}
+		registry, err = cluster.NewNATSRegistry(address, envString("NATS_KV_BUCKET", "videocall"), retention+time.Hour, node)
+	} else {
+		registry, err = cluster.NewRegistry(address, envString("CLUSTER_KEY_PREFIX", "videocall:"), node)
+	}
+	if err != nil {
+		serverLog.Fatalf("Failed to join cluster: %v", err)
+	}
+
+	serverLog.Infof("Joined cluster through %s as node %s (%s)", backend, node.ID, node.URL)
+	return registry
+}
+
+// claimRoom records this node as the host of a new room
+func (s *Server) claimRoom(c *gin.Context, roomID string) error {
+	if s.cluster == nil {
+		return nil
+	}
+	return s.cluster.ClaimRoom(c.Request.Context(), roomID)
+}
+
+// routeToRoomOwner sends a request for a room not hosted here to the node hosting it.
+// It returns false if the room is not hosted by any other node.
+func (s *Server) routeToRoomOwner(c *gin.Context, roomID string) bool {
+	// In cascade mode remote rooms are served locally as edges
+	if s.cluster == nil || s.routingMode == routingCascade || c.GetHeader(forwardedHeader) != "" {
+		return false
+	}
+
+	owner, ok, err := s.cluster.RoomOwner(c.Request.Context(), roomID)
+	if err == cluster.ErrNodeUnavailable {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Room host is unavailable")})
+		return true
+	}
+	if err != nil {
+		serverLog.Errorf("Failed to route room %s: %v", roomID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Room routing unavailable")})
+		return true
+	}
+	if !ok || owner.ID == s.cluster.LocalNode().ID {
+		return false
+	}
+
+	target, err := url.Parse(owner.URL)
+	if err != nil {
+		serverLog.Warnf("Invalid URL %q for node %s: %v", owner.URL, owner.ID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Room host is unavailable")})
+		return true
+	}
+
+	if s.routingMode == routingProxy {
+		s.proxyTo(c, target)
+		return true
+	}
+
+	// 307 makes the client repeat the same method and body against the owner node
+	location := *target
+	location.Path = c.Request.URL.Path
+	location.RawQuery = c.Request.URL.RawQuery
+	c.Header("X-Room-Node", owner.ID)
+	c.Redirect(http.StatusTemporaryRedirect, location.String())
+	return true
+}
+
+// proxyTo forwards the request (including WebSocket upgrades) to another node
+func (s *Server) proxyTo(c *gin.Context, target *url.URL) {
+	// Restore a body already consumed by binding
+	if body, ok := c.Get(gin.BodyBytesKey); ok {
+		c.Request.Body = io.NopCloser(bytes.NewReader(body.([]byte)))
+		c.Request.ContentLength = int64(len(body.([]byte)))
+	}
+
+	c.Request.Header.Set(forwardedHeader, s.cluster.LocalNode().ID)
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		serverLog.Errorf("Failed to proxy %s to %s: %v", r.URL.Path, target, err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// wsHandler upgrades to WebSocket on the node hosting ?room_id=, if given
+func (s *Server) wsHandler(c *gin.Context) {
+	if roomID := c.Query("room_id"); roomID != "" {
+		if !s.allowTenantRoom(c, roomID) {
+			return
+		}
+		if _, exists := s.getRoom(roomID); !exists && s.routeToRoomOwner(c, roomID) {
+			return
+		}
+	}
+
+	websocket.ServeWs(s.hub, c.Writer, c.Request, c.MustGet("user_id").(string))
+}
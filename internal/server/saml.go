@@ -0,0 +1,370 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/zubans/video-call-server/internal/auth"
+)
+
+// samlRequestTTL is how long a user has to sign in at the identity provider
+const samlRequestTTL = 10 * time.Minute
+
+// samlProvider signs users in with a SAML 2.0 identity provider
+type samlProvider struct {
+	sp *saml.ServiceProvider
+
+	// Assertion attributes read into accounts
+	usernameAttr string
+	emailAttr    string
+	nameAttr     string
+	orgAttr      string
+	groupsAttr   string
+
+	adminGroups []string // groups whose members get the admin role
+	defaultOrg  string   // organization of users the identity provider asserts none for
+	redirectURL string   // app page receiving the tokens after sign-in
+	required    bool     // password sign-in is disabled for everyone but admins
+
+	// Pending authentication request IDs, by relay state
+	requests map[string]samlRequest
+	mu       sync.Mutex
+}
+
+// samlRequest is an authentication request awaiting the identity provider's response
+type samlRequest struct {
+	id      string
+	expires time.Time
+}
+
+// newSAML configures SAML SSO from SAML_IDP_METADATA_URL or SAML_IDP_METADATA_FILE;
+// it returns nil when neither is set
+func newSAML() *samlProvider {
+	metadataURL := os.Getenv("SAML_IDP_METADATA_URL")
+	metadataFile := os.Getenv("SAML_IDP_METADATA_FILE")
+	if metadataURL == "" && metadataFile == "" {
+		return nil
+	}
+
+	provider, err := loadSAML(metadataURL, metadataFile)
+	if err != nil {
+		serverLog.Errorf("SAML SSO disabled: %v", err)
+		return nil
+	}
+	return provider
+}
+
+// loadSAML reads the identity provider metadata and the service provider settings
+func loadSAML(metadataURL, metadataFile string) (*samlProvider, error) {
+	base := strings.TrimSuffix(os.Getenv("NODE_URL"), "/")
+	if base == "" {
+		return nil, errors.New("NODE_URL is required")
+	}
+	root, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NODE_URL: %v", err)
+	}
+
+	idp, err := readIDPMetadata(metadataURL, metadataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          os.Getenv("SAML_ENTITY_ID"),
+		MetadataURL:       *root.JoinPath("saml", "metadata"),
+		AcsURL:            *root.JoinPath("saml", "acs"),
+		IDPMetadata:       idp,
+		AuthnNameIDFormat: saml.NameIDFormat(envString("SAML_NAMEID_FORMAT", string(saml.UnspecifiedNameIDFormat))),
+		AllowIDPInitiated: envBool("SAML_ALLOW_IDP_INITIATED", false),
+	}
+	if sp.GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+		return nil, errors.New("identity provider has no HTTP-Redirect sign-in endpoint")
+	}
+
+	// With a key pair, authentication requests are signed and encrypted assertions accepted
+	if certFile := os.Getenv("SAML_CERT_FILE"); certFile != "" {
+		pair, err := tls.LoadX509KeyPair(certFile, os.Getenv("SAML_KEY_FILE"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SAML key pair: %v", err)
+		}
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SAML certificate: %v", err)
+		}
+		sp.Certificate = cert
+		sp.Key = pair.PrivateKey.(crypto.Signer)
+		switch pair.PrivateKey.(type) {
+		case *rsa.PrivateKey:
+			sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+		case *ecdsa.PrivateKey:
+			sp.SignatureMethod = dsig.ECDSASHA256SignatureMethod
+		}
+	}
+
+	return &samlProvider{
+		sp:           sp,
+		usernameAttr: envString("SAML_ATTR_USERNAME", "uid"),
+		emailAttr:    envString("SAML_ATTR_EMAIL", "email"),
+		nameAttr:     envString("SAML_ATTR_DISPLAY_NAME", "displayName"),
+		orgAttr:      envString("SAML_ATTR_ORG", "organization"),
+		groupsAttr:   envString("SAML_ATTR_GROUPS", "groups"),
+		adminGroups:  envList("SAML_ADMIN_GROUPS"),
+		defaultOrg:   os.Getenv("SAML_DEFAULT_ORG"),
+		redirectURL:  os.Getenv("SAML_REDIRECT_URL"),
+		required:     envBool("SAML_REQUIRED", false),
+		requests:     make(map[string]samlRequest),
+	}, nil
+}
+
+// readIDPMetadata loads the metadata of the identity provider from a URL or file;
+// of an aggregate, the first identity provider is used
+func readIDPMetadata(metadataURL, metadataFile string) (*saml.EntityDescriptor, error) {
+	var data []byte
+	if metadataURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SAML_IDP_METADATA_URL: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch identity provider metadata: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch identity provider metadata: status %d", resp.StatusCode)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20)); err != nil {
+			return nil, fmt.Errorf("failed to fetch identity provider metadata: %v", err)
+		}
+	} else {
+		var err error
+		if data, err = os.ReadFile(metadataFile); err != nil {
+			return nil, fmt.Errorf("failed to read identity provider metadata: %v", err)
+		}
+	}
+
+	entity := &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(data, entity); err == nil {
+		return entity, nil
+	}
+	entities := &saml.EntitiesDescriptor{}
+	if err := xml.Unmarshal(data, entities); err != nil {
+		return nil, fmt.Errorf("failed to parse identity provider metadata: %v", err)
+	}
+	for i := range entities.EntityDescriptors {
+		if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, errors.New("identity provider metadata has no identity provider")
+}
+
+// start records an authentication request and returns its relay state
+func (p *samlProvider) start(requestID string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	relayState := hex.EncodeToString(b)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for k, other := range p.requests {
+		if now.After(other.expires) {
+			delete(p.requests, k)
+		}
+	}
+	p.requests[relayState] = samlRequest{id: requestID, expires: now.Add(samlRequestTTL)}
+	return relayState
+}
+
+// finish removes the authentication request of a relay state and returns its ID
+func (p *samlProvider) finish(relayState string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	request, exists := p.requests[relayState]
+	if !exists || time.Now().After(request.expires) {
+		return "", false
+	}
+	delete(p.requests, relayState)
+	return request.id, true
+}
+
+// identity maps the attributes of an assertion to an account
+func (p *samlProvider) identity(assertion *saml.Assertion) auth.SSOIdentity {
+	values := func(name string) []string {
+		var found []string
+		for _, statement := range assertion.AttributeStatements {
+			for _, attribute := range statement.Attributes {
+				if attribute.Name == name || attribute.FriendlyName == name {
+					for _, value := range attribute.Values {
+						found = append(found, value.Value)
+					}
+				}
+			}
+		}
+		return found
+	}
+	first := func(name string) string {
+		if found := values(name); len(found) > 0 {
+			return strings.TrimSpace(found[0])
+		}
+		return ""
+	}
+
+	identity := auth.SSOIdentity{
+		Issuer:      assertion.Issuer.Value,
+		Username:    first(p.usernameAttr),
+		Email:       first(p.emailAttr),
+		DisplayName: first(p.nameAttr),
+		Org:         first(p.orgAttr),
+	}
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		identity.Subject = assertion.Subject.NameID.Value
+	}
+	if identity.Org == "" {
+		identity.Org = p.defaultOrg
+	}
+	if len(p.adminGroups) > 0 {
+		admin := false
+		for _, group := range values(p.groupsAttr) {
+			for _, adminGroup := range p.adminGroups {
+				admin = admin || group == adminGroup
+			}
+		}
+		identity.Admin = &admin
+	}
+	return identity
+}
+
+// ssoRequired reports whether users must sign in with SSO rather than a password
+func (s *Server) ssoRequired() bool {
+	return s.saml != nil && s.saml.required
+}
+
+// samlMetadataHandler serves the service provider metadata registered with the identity provider
+func (s *Server) samlMetadataHandler(c *gin.Context) {
+	if s.saml == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "SSO is not configured")})
+		return
+	}
+
+	metadata, err := xml.MarshalIndent(s.saml.sp.Metadata(), "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to generate SSO metadata")})
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// samlLoginHandler redirects the browser to the identity provider to sign in
+func (s *Server) samlLoginHandler(c *gin.Context) {
+	if s.saml == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "SSO is not configured")})
+		return
+	}
+
+	sp := s.saml.sp
+	request, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		serverLog.Errorf("Failed to create SAML authentication request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to start SSO sign-in")})
+		return
+	}
+	redirect, err := request.Redirect(s.saml.start(request.ID), sp)
+	if err != nil {
+		serverLog.Errorf("Failed to create SAML authentication request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to start SSO sign-in")})
+		return
+	}
+	c.Redirect(http.StatusFound, redirect.String())
+}
+
+// samlACSHandler receives the identity provider's response, provisions the account
+// on first sign-in and issues tokens. With SAML_REDIRECT_URL the browser is sent
+// there with the tokens in the URL fragment.
+func (s *Server) samlACSHandler(c *gin.Context) {
+	if s.saml == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "SSO is not configured")})
+		return
+	}
+
+	var requestIDs []string
+	if requestID, ok := s.saml.finish(c.PostForm("RelayState")); ok {
+		requestIDs = append(requestIDs, requestID)
+	}
+
+	assertion, err := s.saml.sp.ParseResponse(c.Request, requestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		serverLog.Warnf("Rejected SAML response: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "SSO sign-in failed")})
+		return
+	}
+
+	// Accounts are provisioned in the tenant of the host the response is posted to
+	tenant, ok := s.requestTenant(c)
+	if !ok {
+		return
+	}
+
+	identity := s.saml.identity(assertion)
+	identity.TenantID = tenant
+	user, created, err := auth.ProvisionSSOUser(identity)
+	if err != nil {
+		serverLog.Warnf("Failed to provision SSO user %q: %v", identity.Subject, err)
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "SSO sign-in failed")})
+		return
+	}
+	s.saveUser(user.ID)
+	if created {
+		s.metrics.IncrementUsersRegistered()
+	}
+
+	if s.saml.redirectURL == "" {
+		s.issueTokens(c, user, "Login successful")
+		return
+	}
+
+	token, refreshToken, err := tokenPair(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to generate token")})
+		return
+	}
+	fragment := url.Values{
+		"token":         {token},
+		"refresh_token": {refreshToken},
+		"user_id":       {user.ID},
+	}
+	c.Redirect(http.StatusSeeOther, s.saml.redirectURL+"#"+fragment.Encode())
+}
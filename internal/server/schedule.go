@@ -0,0 +1,497 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones for hosts without a zoneinfo database
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// Scheduling limits
+const (
+	reminderCheckInterval  = 30 * time.Second
+	defaultMeetingMinutes  = 60
+	maxMeetingMinutes      = 24 * 60
+	maxReminderMinutes     = 7 * 24 * 60
+	scheduleLocalTimeInput = "2006-01-02T15:04"
+
+	// earlyJoinWindow is how long before the start joining counts as attending
+	earlyJoinWindow = 15 * time.Minute
+)
+
+// Schedule validation errors
+var (
+	errInvalidTimezone = errors.New("timezone must be an IANA time zone such as Europe/Moscow")
+	errInvalidStart    = errors.New("starts_at must be RFC 3339 or a local time such as 2026-03-01T10:00")
+	errInvalidDuration = errors.New("duration_minutes must be between 1 and 1440")
+	errInvalidReminder = errors.New("reminder_minutes must be between 1 and 10080")
+	errUnknownInvitee  = errors.New("invitees must be existing user IDs")
+)
+
+// scheduleRequest is a room schedule as given when creating or updating a room.
+// starts_at is either RFC 3339 or a wall-clock time in the organizer's timezone.
+type scheduleRequest struct {
+	StartsAt        string   `json:"starts_at"`
+	DurationMinutes int      `json:"duration_minutes"`
+	Timezone        string   `json:"timezone"`
+	Invitees        []string `json:"invitees"`
+	ReminderMinutes []int    `json:"reminder_minutes"`
+}
+
+// parse validates a schedule request; invitees must be users of the tenant
+func (r *scheduleRequest) parse(tenantID string) (*models.RoomSchedule, error) {
+	timezone := r.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errInvalidTimezone
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, r.StartsAt)
+	if err != nil {
+		startsAt, err = time.ParseInLocation(scheduleLocalTimeInput, strings.TrimSuffix(r.StartsAt, ":00"), location)
+		if err != nil {
+			return nil, errInvalidStart
+		}
+	}
+
+	duration := r.DurationMinutes
+	if duration == 0 {
+		duration = defaultMeetingMinutes
+	}
+	if duration < 1 || duration > maxMeetingMinutes {
+		return nil, errInvalidDuration
+	}
+
+	for _, minutes := range r.ReminderMinutes {
+		if minutes < 1 || minutes > maxReminderMinutes {
+			return nil, errInvalidReminder
+		}
+	}
+
+	var invitees []string
+	seen := make(map[string]bool)
+	for _, userID := range r.Invitees {
+		if seen[userID] {
+			continue
+		}
+		if user, exists := auth.GetUserByID(userID); !exists || user.TenantID != tenantID {
+			return nil, errUnknownInvitee
+		}
+		seen[userID] = true
+		invitees = append(invitees, userID)
+	}
+
+	return &models.RoomSchedule{
+		StartsAt:        startsAt.UTC(),
+		DurationMinutes: duration,
+		Timezone:        location.String(),
+		Invitees:        invitees,
+		ReminderMinutes: append([]int(nil), r.ReminderMinutes...),
+		RemindersSent:   make(map[int]bool),
+		Attendees:       make(map[string]bool),
+	}, nil
+}
+
+// scheduleView is the JSON representation of a room schedule, with the start in the
+// organizer's timezone
+type scheduleView struct {
+	models.RoomSchedule
+	StartsAtLocal string    `json:"starts_at_local"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+// viewSchedule returns the view of a schedule, or nil; the caller holds room.Mu
+func viewSchedule(schedule *models.RoomSchedule) *scheduleView {
+	if schedule == nil {
+		return nil
+	}
+
+	view := &scheduleView{
+		RoomSchedule: *schedule,
+		EndsAt:       scheduleEnd(schedule),
+	}
+	view.Invitees = append([]string(nil), schedule.Invitees...)
+	view.ReminderMinutes = append([]int(nil), schedule.ReminderMinutes...)
+	view.RemindersSent = nil
+	view.Attendees = nil
+	if location, err := time.LoadLocation(schedule.Timezone); err == nil {
+		view.StartsAtLocal = schedule.StartsAt.In(location).Format(time.RFC3339)
+	}
+	return view
+}
+
+// scheduleEnd returns when a scheduled meeting ends
+func scheduleEnd(schedule *models.RoomSchedule) time.Time {
+	return schedule.StartsAt.Add(time.Duration(schedule.DurationMinutes) * time.Minute)
+}
+
+// readReminderMinutes reads REMINDER_MINUTES, the default reminder offsets in minutes before a meeting
+func readReminderMinutes() []int {
+	items := envList("REMINDER_MINUTES")
+	if len(items) == 0 {
+		return []int{15}
+	}
+
+	var minutes []int
+	for _, item := range items {
+		var n int
+		if _, err := fmt.Sscanf(item, "%d", &n); err != nil || n < 1 || n > maxReminderMinutes {
+			serverLog.Warnf("Invalid REMINDER_MINUTES entry %q, ignoring it", item)
+			continue
+		}
+		minutes = append(minutes, n)
+	}
+	return minutes
+}
+
+// reminder is a reminder due for a scheduled room
+type reminder struct {
+	room          *models.Room
+	name          string
+	joinCode      string
+	schedule      scheduleView
+	minutesBefore int
+	recipients    []string
+}
+
+// runReminders sends meeting reminders at their offsets before scheduled starts and
+// reports missed meetings once they are over
+func (s *Server) runReminders() {
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		for _, due := range s.dueReminders(now) {
+			s.sendReminder(due)
+		}
+		s.reportMissedMeetings(now)
+	}
+}
+
+// dueReminders marks and returns the reminders due at a time. When several offsets of a
+// room are due at once (a meeting scheduled at short notice), only the closest is sent.
+func (s *Server) dueReminders(now time.Time) []reminder {
+	defaults := s.settings().ReminderMinutes
+
+	var due []reminder
+	for _, room := range s.scheduledRooms() {
+		s.roomUpdate(room, func() {
+			schedule := room.Schedule
+			if schedule == nil || !room.IsActive || !now.Before(scheduleEnd(schedule)) {
+				return
+			}
+
+			offsets := schedule.ReminderMinutes
+			if len(offsets) == 0 {
+				offsets = defaults
+			}
+			closest := 0
+			for _, minutes := range offsets {
+				if schedule.RemindersSent[minutes] || now.Before(schedule.StartsAt.Add(-time.Duration(minutes)*time.Minute)) {
+					continue
+				}
+				schedule.RemindersSent[minutes] = true
+				if closest == 0 || minutes < closest {
+					closest = minutes
+				}
+			}
+			if closest > 0 {
+				due = append(due, reminder{
+					room:          room,
+					name:          room.Name,
+					joinCode:      room.JoinCode,
+					schedule:      *viewSchedule(schedule),
+					minutesBefore: closest,
+					recipients:    scheduleRecipients(room),
+				})
+			}
+		})
+	}
+	return due
+}
+
+// scheduledRooms returns the rooms with a schedule
+func (s *Server) scheduledRooms() []*models.Room {
+	s.roomManager.Mu.RLock()
+	defer s.roomManager.Mu.RUnlock()
+
+	var rooms []*models.Room
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		if room.Schedule != nil {
+			rooms = append(rooms, room)
+		}
+		room.Mu.RUnlock()
+	}
+	return rooms
+}
+
+// markAttendance records a user joining a scheduled meeting around its time; the caller holds room.Mu
+func markAttendance(room *models.Room, userID string, now time.Time) {
+	schedule := room.Schedule
+	if schedule == nil || now.Before(schedule.StartsAt.Add(-earlyJoinWindow)) || !now.Before(scheduleEnd(schedule)) {
+		return
+	}
+	schedule.Attendees[userID] = true
+}
+
+// reportMissedMeetings publishes call.missed for scheduled meetings that are over,
+// listing the organizer and invitees who never joined. Archived rooms are skipped,
+// since their meeting was called off.
+func (s *Server) reportMissedMeetings(now time.Time) {
+	for _, room := range s.scheduledRooms() {
+		var (
+			missed []string
+			name   string
+			view   *scheduleView
+		)
+		s.roomUpdate(room, func() {
+			schedule := room.Schedule
+			if schedule == nil || schedule.MissedReported || !room.IsActive || now.Before(scheduleEnd(schedule)) {
+				return
+			}
+			schedule.MissedReported = true
+
+			for _, userID := range scheduleRecipients(room) {
+				if !schedule.Attendees[userID] {
+					missed = append(missed, userID)
+				}
+			}
+			name = room.Name
+			view = viewSchedule(schedule)
+		})
+
+		if len(missed) == 0 {
+			continue
+		}
+		names := make([]string, len(missed))
+		for i, userID := range missed {
+			names[i], _ = profile(userID, userID)
+		}
+
+		s.publishEvent(events.CallMissed, room.ID, map[string]interface{}{
+			"name":            name,
+			"starts_at":       view.StartsAt,
+			"starts_at_local": view.StartsAtLocal,
+			"timezone":        view.Timezone,
+			"missed":          missed,
+			"missed_names":    names,
+		})
+	}
+}
+
+// scheduleRecipients returns the organizer and invitees of a scheduled room; the caller holds room.Mu
+func scheduleRecipients(room *models.Room) []string {
+	recipients := []string{room.CreatorID}
+	for _, userID := range room.Schedule.Invitees {
+		if userID != room.CreatorID {
+			recipients = append(recipients, userID)
+		}
+	}
+	return recipients
+}
+
+// sendReminder pushes a reminder to the recipients' open connections, skipping users in
+// do-not-disturb, emails it, and publishes room.reminder for webhooks
+func (s *Server) sendReminder(due reminder) {
+	payload := gin.H{
+		"room_id":         due.room.ID,
+		"name":            due.name,
+		"join_code":       due.joinCode,
+		"starts_at":       due.schedule.StartsAt,
+		"starts_at_local": due.schedule.StartsAtLocal,
+		"timezone":        due.schedule.Timezone,
+		"minutes_before":  due.minutesBefore,
+	}
+
+	for _, userID := range due.recipients {
+		if s.presence.DoNotDisturb(userID) {
+			continue
+		}
+		s.hub.SendToUser(userID, "room-reminder", payload)
+	}
+	s.sendReminderEmails(due)
+
+	s.publishEvent(events.RoomReminder, due.room.ID, map[string]interface{}{
+		"name":            due.name,
+		"starts_at":       due.schedule.StartsAt,
+		"starts_at_local": due.schedule.StartsAtLocal,
+		"timezone":        due.schedule.Timezone,
+		"minutes_before":  due.minutesBefore,
+		"recipients":      due.recipients,
+	})
+}
+
+// roomCalendarHandler exports a scheduled room as an iCalendar event. Times are given in
+// the organizer's timezone; the description also shows them in the viewer's timezone
+// (tz query parameter) and language.
+func (s *Server) roomCalendarHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	room.Mu.RLock()
+	schedule := viewSchedule(room.Schedule)
+	name, joinCode, active := room.Name, room.JoinCode, room.IsActive
+	room.Mu.RUnlock()
+
+	if !active && room.CreatorID != c.GetString("user_id") && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if schedule == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room is not scheduled")})
+		return
+	}
+
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	viewer := location
+	if tz := c.Query("tz"); tz != "" {
+		if viewer, err = time.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, errInvalidTimezone.Error())})
+			return
+		}
+	}
+
+	layout := tr(c, "Jan 2, 2006 15:04")
+	description := []string{
+		trf(c, "Starts: %s (%s)", schedule.StartsAt.In(location).Format(layout), location),
+	}
+	if viewer.String() != location.String() {
+		description = append(description, trf(c, "Your time: %s (%s)", schedule.StartsAt.In(viewer).Format(layout), viewer))
+	}
+	description = append(description, trf(c, "Join code: %s", joinCode))
+
+	reminders := schedule.ReminderMinutes
+	if len(reminders) == 0 {
+		reminders = s.settings().ReminderMinutes
+	}
+
+	ics := renderCalendar(calendarEvent{
+		uid:         room.ID,
+		summary:     name,
+		description: strings.Join(description, "\n"),
+		start:       schedule.StartsAt,
+		end:         schedule.EndsAt,
+		location:    location,
+		reminders:   reminders,
+		reminder:    tr(c, "Meeting reminder"),
+	})
+
+	c.Header("Content-Disposition", `attachment; filename="`+room.ID+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(ics))
+}
+
+// calendarEvent is a meeting exported to iCalendar
+type calendarEvent struct {
+	uid         string
+	summary     string
+	description string
+	start       time.Time
+	end         time.Time
+	location    *time.Location
+	reminders   []int
+	reminder    string
+}
+
+// renderCalendar writes an event as an RFC 5545 calendar. Times in other zones than UTC
+// come with a VTIMEZONE giving the zone's offset at the meeting.
+func renderCalendar(event calendarEvent) string {
+	var lines []string
+	add := func(line string) { lines = append(lines, foldLine(line)) }
+
+	add("BEGIN:VCALENDAR")
+	add("VERSION:2.0")
+	add("PRODID:-//video-call-server//scheduled rooms//EN")
+	add("CALSCALE:GREGORIAN")
+	add("METHOD:PUBLISH")
+
+	const local = "20060102T150405"
+	start := "DTSTART:" + event.start.UTC().Format(local) + "Z"
+	end := "DTEND:" + event.end.UTC().Format(local) + "Z"
+	if tzid := event.location.String(); tzid != "UTC" {
+		abbreviation, offset := event.start.In(event.location).Zone()
+		add("BEGIN:VTIMEZONE")
+		add("TZID:" + tzid)
+		add("BEGIN:STANDARD")
+		add("DTSTART:19700101T000000")
+		add("TZOFFSETFROM:" + formatOffset(offset))
+		add("TZOFFSETTO:" + formatOffset(offset))
+		add("TZNAME:" + abbreviation)
+		add("END:STANDARD")
+		add("END:VTIMEZONE")
+
+		start = "DTSTART;TZID=" + tzid + ":" + event.start.In(event.location).Format(local)
+		end = "DTEND;TZID=" + tzid + ":" + event.end.In(event.location).Format(local)
+	}
+
+	add("BEGIN:VEVENT")
+	add("UID:" + event.uid + "@video-call-server")
+	add("DTSTAMP:" + time.Now().UTC().Format(local) + "Z")
+	add(start)
+	add(end)
+	add("SUMMARY:" + escapeCalendarText(event.summary))
+	add("DESCRIPTION:" + escapeCalendarText(event.description))
+
+	reminders := append([]int(nil), event.reminders...)
+	sort.Sort(sort.Reverse(sort.IntSlice(reminders)))
+	for _, minutes := range reminders {
+		add("BEGIN:VALARM")
+		add("ACTION:DISPLAY")
+		add(fmt.Sprintf("TRIGGER:-PT%dM", minutes))
+		add("DESCRIPTION:" + escapeCalendarText(event.reminder))
+		add("END:VALARM")
+	}
+	add("END:VEVENT")
+	add("END:VCALENDAR")
+
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// formatOffset formats a UTC offset in seconds as +hhmm
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// escapeCalendarText escapes a TEXT value
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// foldLine splits content lines longer than 75 octets, without breaking UTF-8 sequences
+func foldLine(line string) string {
+	var folded strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			folded.WriteString("\r\n ")
+			width = 1
+		}
+		folded.WriteRune(r)
+		width += size
+	}
+	return folded.String()
+}
@@ -0,0 +1,962 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/groups"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// SCIM 2.0 schema URNs
+const (
+	scimUserSchema       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimEnterpriseSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	scimGroupSchema      = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListSchema       = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema      = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimConfigSchema     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimTypeSchema       = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// maxSCIMResults caps the resources returned by one list request
+const maxSCIMResults = 200
+
+// scimFilterPattern matches the equality filters identity providers look resources up with
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*([\w.:]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimMember is a member of a group, or a group of a user
+type scimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// scimMeta holds the metadata of a resource
+type scimMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+// scimUser is the SCIM representation of a user, used both ways
+type scimUser struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Name        *scimName    `json:"name,omitempty"`
+	Emails      []scimEmail  `json:"emails,omitempty"`
+	Active      *bool        `json:"active,omitempty"`
+	Password    string       `json:"password,omitempty"`
+	Groups      []scimMember `json:"groups,omitempty"`
+	Enterprise  *scimOrgUnit `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+// scimName is the name of a user
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// scimEmail is an email address of a user
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// scimOrgUnit is the enterprise extension of a user, whose organization maps to the user's org
+type scimOrgUnit struct {
+	Organization string `json:"organization,omitempty"`
+}
+
+// scimGroup is the SCIM representation of a group, used both ways
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+// scimPatch is a PATCH request
+type scimPatch struct {
+	Operations []scimOperation `json:"Operations"`
+}
+
+// scimOperation is an operation of a PATCH request
+type scimOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// scimJSON writes a SCIM response
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(status, body)
+}
+
+// scimError writes a SCIM error; scimType is empty or a SCIM error type such as uniqueness
+func scimError(c *gin.Context, status int, scimType, detail string) {
+	body := gin.H{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  tr(c, detail),
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	scimJSON(c, status, body)
+}
+
+// scimBody decodes a request body leniently: identity providers send attributes
+// this server does not store, which strict decoding would reject
+func scimBody(c *gin.Context, v interface{}) bool {
+	data, err := c.GetRawData()
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// scimLocation returns the URL of a resource
+func scimLocation(resource, id string) string {
+	return publicURL("/scim/v2/" + resource + "/" + id)
+}
+
+// scimList writes a page of a list response; startIndex is 1-based
+func scimList(c *gin.Context, resources []interface{}) {
+	startIndex, _ := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(maxSCIMResults)))
+	if err != nil || count > maxSCIMResults {
+		count = maxSCIMResults
+	}
+	if count < 0 {
+		count = 0
+	}
+
+	total := len(resources)
+	start := startIndex - 1
+	if start > total {
+		start = total
+	}
+	end := start + count
+	if end > total {
+		end = total
+	}
+
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":      []string{scimListSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": end - start,
+		"Resources":    resources[start:end],
+	})
+}
+
+// scimFilter parses an equality filter; ok is false for filters this server does not support
+func scimFilter(filter string) (attribute, value string, ok bool) {
+	match := scimFilterPattern.FindStringSubmatch(filter)
+	if match == nil {
+		return "", "", false
+	}
+	value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(match[2])
+	return strings.ToLower(match[1]), value, true
+}
+
+// toSCIMUser returns the SCIM representation of a user
+func (s *Server) toSCIMUser(user *auth.User) scimUser {
+	active := !user.Deactivated
+	resource := scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          user.ID,
+		ExternalID:  user.ExternalID,
+		UserName:    user.Username,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Meta:        &scimMeta{ResourceType: "User", Location: scimLocation("Users", user.ID)},
+	}
+	if user.DisplayName != "" {
+		resource.Name = &scimName{Formatted: user.DisplayName}
+	}
+	if user.Email != "" {
+		resource.Emails = []scimEmail{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.Org != "" {
+		resource.Schemas = append(resource.Schemas, scimEnterpriseSchema)
+		resource.Enterprise = &scimOrgUnit{Organization: user.Org}
+	}
+	for _, group := range s.groups.Of(user.ID) {
+		resource.Groups = append(resource.Groups, scimMember{
+			Value:   group.ID,
+			Display: group.DisplayName,
+			Ref:     scimLocation("Groups", group.ID),
+		})
+	}
+	return resource
+}
+
+// attributes maps a SCIM user to account attributes; users are active unless
+// active is false, and the display name falls back to the name parts
+func (u *scimUser) attributes() auth.UserAttributes {
+	attrs := auth.UserAttributes{
+		Username:    strings.TrimSpace(u.UserName),
+		DisplayName: u.DisplayName,
+		ExternalID:  u.ExternalID,
+		Password:    u.Password,
+		Active:      u.Active == nil || *u.Active,
+	}
+	if attrs.DisplayName == "" && u.Name != nil {
+		attrs.DisplayName = u.Name.Formatted
+		if attrs.DisplayName == "" {
+			attrs.DisplayName = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+		}
+	}
+	for i, email := range u.Emails {
+		if i == 0 || email.Primary {
+			attrs.Email = email.Value
+		}
+	}
+	if u.Enterprise != nil {
+		attrs.Org = u.Enterprise.Organization
+	}
+	return attrs
+}
+
+// toSCIMGroup returns the SCIM representation of a group
+func toSCIMGroup(group groups.Group) scimGroup {
+	resource := scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     make([]scimMember, 0, len(group.Members)),
+		Meta: &scimMeta{
+			ResourceType: "Group",
+			Created:      &group.CreatedAt,
+			LastModified: &group.UpdatedAt,
+			Location:     scimLocation("Groups", group.ID),
+		},
+	}
+	for _, userID := range group.Members {
+		member := scimMember{Value: userID, Ref: scimLocation("Users", userID)}
+		if user, exists := auth.GetUserByID(userID); exists {
+			member.Display = user.Name()
+		}
+		resource.Members = append(resource.Members, member)
+	}
+	return resource
+}
+
+// scimMemberIDs returns the user IDs of group members, failing if a user does not
+// exist in the tenant
+func scimMemberIDs(tenantID string, members []scimMember) ([]string, bool) {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		if user, exists := auth.GetUserByID(member.Value); !exists || user.TenantID != tenantID {
+			return nil, false
+		}
+		ids = append(ids, member.Value)
+	}
+	return ids, true
+}
+
+// syncGroupRoles grants the admin role to members of the groups in SCIM_ADMIN_GROUPS
+// and the user role to everyone else among userIDs, except admins promoted from
+// ADMIN_USERS
+func (s *Server) syncGroupRoles(userIDs []string) {
+	adminGroups := envList("SCIM_ADMIN_GROUPS")
+	if len(adminGroups) == 0 {
+		return
+	}
+
+	for _, userID := range userIDs {
+		user, exists := auth.GetUserByID(userID)
+		if !exists {
+			continue
+		}
+
+		role := auth.RoleUser
+		if user.TenantID == "" && user.Role == auth.RoleAdmin && s.isAdminUsername(user.Username) {
+			role = auth.RoleAdmin
+		}
+		for _, group := range s.groups.Of(userID) {
+			for _, name := range adminGroups {
+				if strings.EqualFold(group.DisplayName, name) {
+					role = auth.RoleAdmin
+				}
+			}
+		}
+		auth.SetUserRole(userID, role)
+		s.saveUser(userID)
+	}
+}
+
+// disconnectUser removes a user's participants from every room, such as when the
+// user is deactivated
+func (s *Server) disconnectUser(userID string) {
+	type participant struct {
+		room   *models.Room
+		client *models.Client
+	}
+	var participants []participant
+
+	s.roomManager.Mu.RLock()
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		for _, client := range room.Clients {
+			if client.UserID == userID {
+				participants = append(participants, participant{room, client})
+			}
+		}
+		room.Mu.RUnlock()
+	}
+	s.roomManager.Mu.RUnlock()
+
+	for _, p := range participants {
+		s.removeClient(p.room, p.client)
+	}
+}
+
+// scimServiceProviderConfigHandler describes the SCIM features this server supports
+func (s *Server) scimServiceProviderConfigHandler(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": maxSCIMResults},
+		"changePassword": gin.H{"supported": true},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "API key",
+			"description": "API key with the scim scope, sent as a bearer token",
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "location": publicURL("/scim/v2/ServiceProviderConfig")},
+	})
+}
+
+// scimResourceTypesHandler lists the resource types this server provisions
+func (s *Server) scimResourceTypesHandler(c *gin.Context) {
+	resourceType := func(name, endpoint, schema string) gin.H {
+		return gin.H{
+			"schemas":  []string{scimTypeSchema},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta":     gin.H{"resourceType": "ResourceType", "location": publicURL("/scim/v2/ResourceTypes/" + name)},
+		}
+	}
+
+	user := resourceType("User", "/Users", scimUserSchema)
+	user["schemaExtensions"] = []gin.H{{"schema": scimEnterpriseSchema, "required": false}}
+	scimList(c, []interface{}{user, resourceType("Group", "/Groups", scimGroupSchema)})
+}
+
+// scimListUsersHandler lists users, optionally filtered by userName, externalId or email
+func (s *Server) scimListUsersHandler(c *gin.Context) {
+	var attribute, value string
+	if filter := c.Query("filter"); filter != "" {
+		var ok bool
+		if attribute, value, ok = scimFilter(filter); !ok {
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Unsupported filter")
+			return
+		}
+	}
+
+	resources := []interface{}{}
+	for _, user := range auth.ListUsers(tenantID(c)) {
+		switch attribute {
+		case "":
+		case "username":
+			if !strings.EqualFold(user.Username, value) {
+				continue
+			}
+		case "externalid":
+			if user.ExternalID != value {
+				continue
+			}
+		case "emails", "emails.value":
+			if !strings.EqualFold(user.Email, value) {
+				continue
+			}
+		case "id":
+			if user.ID != value {
+				continue
+			}
+		default:
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Unsupported filter")
+			return
+		}
+		resources = append(resources, s.toSCIMUser(user))
+	}
+	scimList(c, resources)
+}
+
+// scimTenantUser returns a user of the API key's tenant; users of other tenants are
+// answered as not found
+func scimTenantUser(c *gin.Context, userID string) (*auth.User, bool) {
+	user, exists := auth.GetUserByID(userID)
+	if !exists || user.TenantID != tenantID(c) {
+		scimError(c, http.StatusNotFound, "", "User not found")
+		return nil, false
+	}
+	return user, true
+}
+
+// scimGetUserHandler returns a user
+func (s *Server) scimGetUserHandler(c *gin.Context) {
+	user, ok := scimTenantUser(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	scimJSON(c, http.StatusOK, s.toSCIMUser(user))
+}
+
+// scimCreateUserHandler provisions a user
+func (s *Server) scimCreateUserHandler(c *gin.Context) {
+	var req scimUser
+	if !scimBody(c, &req) {
+		return
+	}
+	if strings.TrimSpace(req.UserName) == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+
+	attrs := req.attributes()
+	attrs.TenantID = tenantID(c)
+	user, err := auth.CreateUser(attrs)
+	if err != nil {
+		s.scimUserError(c, err)
+		return
+	}
+	s.syncGroupRoles([]string{user.ID})
+	s.saveUser(user.ID)
+	s.metrics.IncrementUsersRegistered()
+	s.recordAudit(c, "scim.user_create", user.ID, map[string]string{"username": user.Username})
+
+	scimJSON(c, http.StatusCreated, s.toSCIMUser(user))
+}
+
+// scimReplaceUserHandler replaces the attributes of a user
+func (s *Server) scimReplaceUserHandler(c *gin.Context) {
+	if _, ok := scimTenantUser(c, c.Param("id")); !ok {
+		return
+	}
+
+	var req scimUser
+	if !scimBody(c, &req) {
+		return
+	}
+	if strings.TrimSpace(req.UserName) == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "userName is required")
+		return
+	}
+
+	s.updateSCIMUser(c, c.Param("id"), req.attributes())
+}
+
+// scimPatchUserHandler changes attributes of a user, such as deactivating them
+func (s *Server) scimPatchUserHandler(c *gin.Context) {
+	user, ok := scimTenantUser(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req scimPatch
+	if !scimBody(c, &req) {
+		return
+	}
+
+	// Operations apply to the JSON representation, which is then read back
+	current, _ := json.Marshal(s.toSCIMUser(user))
+	var resource map[string]interface{}
+	json.Unmarshal(current, &resource)
+	for _, op := range req.Operations {
+		if err := patchResource(resource, op); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidPath", err.Error())
+			return
+		}
+	}
+	if active, ok := resource["active"].(string); ok {
+		resource["active"] = strings.EqualFold(active, "true")
+	}
+
+	patched, _ := json.Marshal(resource)
+	var updated scimUser
+	if err := json.Unmarshal(patched, &updated); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidValue", "Invalid request body")
+		return
+	}
+	s.updateSCIMUser(c, user.ID, updated.attributes())
+}
+
+// updateSCIMUser replaces the attributes of a user, disconnecting them when deactivated
+func (s *Server) updateSCIMUser(c *gin.Context, userID string, attrs auth.UserAttributes) {
+	user, err := auth.UpdateUser(userID, attrs)
+	if err != nil {
+		s.scimUserError(c, err)
+		return
+	}
+	s.syncGroupRoles([]string{user.ID})
+	s.saveUser(user.ID)
+	if user.Deactivated {
+		s.disconnectUser(user.ID)
+	}
+	s.recordAudit(c, "scim.user_update", user.ID, map[string]string{
+		"username": user.Username,
+		"active":   strconv.FormatBool(!user.Deactivated),
+	})
+
+	scimJSON(c, http.StatusOK, s.toSCIMUser(user))
+}
+
+// scimDeleteUserHandler deletes a user
+func (s *Server) scimDeleteUserHandler(c *gin.Context) {
+	userID := c.Param("id")
+	if _, ok := scimTenantUser(c, userID); !ok {
+		return
+	}
+	if err := auth.DeleteUser(userID); err != nil {
+		s.scimUserError(c, err)
+		return
+	}
+	s.groups.RemoveUser(userID)
+	s.disconnectUser(userID)
+	s.forgetUser(userID)
+	s.recordAudit(c, "scim.user_delete", userID, nil)
+
+	c.Status(http.StatusNoContent)
+}
+
+// scimUserError writes the SCIM error for a failed user change
+func (s *Server) scimUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		scimError(c, http.StatusNotFound, "", "User not found")
+	case errors.Is(err, auth.ErrUserExists):
+		scimError(c, http.StatusConflict, "uniqueness", "User already exists")
+	case errors.Is(err, auth.ErrLegalHold):
+		scimError(c, http.StatusConflict, "", "User is under legal hold")
+	default:
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+	}
+}
+
+// scimListGroupsHandler lists groups, optionally filtered by displayName or externalId
+func (s *Server) scimListGroupsHandler(c *gin.Context) {
+	var attribute, value string
+	if filter := c.Query("filter"); filter != "" {
+		var ok bool
+		if attribute, value, ok = scimFilter(filter); !ok {
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Unsupported filter")
+			return
+		}
+	}
+
+	resources := []interface{}{}
+	for _, group := range s.groups.List(tenantID(c)) {
+		switch attribute {
+		case "":
+		case "displayname":
+			if !strings.EqualFold(group.DisplayName, value) {
+				continue
+			}
+		case "externalid":
+			if group.ExternalID != value {
+				continue
+			}
+		case "id":
+			if group.ID != value {
+				continue
+			}
+		default:
+			scimError(c, http.StatusBadRequest, "invalidFilter", "Unsupported filter")
+			return
+		}
+		resources = append(resources, toSCIMGroup(group))
+	}
+	scimList(c, resources)
+}
+
+// scimTenantGroup returns a group of the API key's tenant; groups of other tenants
+// are answered as not found
+func (s *Server) scimTenantGroup(c *gin.Context, groupID string) (groups.Group, bool) {
+	group, exists := s.groups.Get(groupID)
+	if !exists || group.TenantID != tenantID(c) {
+		scimError(c, http.StatusNotFound, "", "Group not found")
+		return groups.Group{}, false
+	}
+	return group, true
+}
+
+// scimGetGroupHandler returns a group
+func (s *Server) scimGetGroupHandler(c *gin.Context) {
+	group, ok := s.scimTenantGroup(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	scimJSON(c, http.StatusOK, toSCIMGroup(group))
+}
+
+// scimCreateGroupHandler creates a group
+func (s *Server) scimCreateGroupHandler(c *gin.Context) {
+	var req scimGroup
+	if !scimBody(c, &req) {
+		return
+	}
+	if strings.TrimSpace(req.DisplayName) == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	members, ok := scimMemberIDs(tenantID(c), req.Members)
+	if !ok {
+		scimError(c, http.StatusBadRequest, "invalidValue", "User not found")
+		return
+	}
+
+	group, err := s.groups.Create(tenantID(c), req.DisplayName, req.ExternalID, members)
+	if err != nil {
+		s.scimGroupError(c, err)
+		return
+	}
+	s.syncGroupRoles(group.Members)
+	s.recordAudit(c, "scim.group_create", group.ID, map[string]string{"display_name": group.DisplayName})
+
+	scimJSON(c, http.StatusCreated, toSCIMGroup(group))
+}
+
+// scimReplaceGroupHandler replaces the name and members of a group
+func (s *Server) scimReplaceGroupHandler(c *gin.Context) {
+	before, ok := s.scimTenantGroup(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req scimGroup
+	if !scimBody(c, &req) {
+		return
+	}
+	if strings.TrimSpace(req.DisplayName) == "" {
+		scimError(c, http.StatusBadRequest, "invalidValue", "displayName is required")
+		return
+	}
+	members, ok := scimMemberIDs(before.TenantID, req.Members)
+	if !ok {
+		scimError(c, http.StatusBadRequest, "invalidValue", "User not found")
+		return
+	}
+
+	group, err := s.groups.Replace(before.ID, req.DisplayName, req.ExternalID, members)
+	if err != nil {
+		s.scimGroupError(c, err)
+		return
+	}
+	s.syncGroupRoles(append(before.Members, group.Members...))
+	s.recordAudit(c, "scim.group_update", group.ID, map[string]string{"display_name": group.DisplayName})
+
+	scimJSON(c, http.StatusOK, toSCIMGroup(group))
+}
+
+// scimPatchGroupHandler renames a group or adds, removes or replaces its members
+func (s *Server) scimPatchGroupHandler(c *gin.Context) {
+	before, ok := s.scimTenantGroup(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req scimPatch
+	if !scimBody(c, &req) {
+		return
+	}
+
+	group := before
+	for _, op := range req.Operations {
+		var err error
+		if group, err = s.patchGroup(group, op); err != nil {
+			if errors.Is(err, groups.ErrGroupNotFound) || errors.Is(err, groups.ErrGroupExists) {
+				s.scimGroupError(c, err)
+			} else {
+				scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+			}
+			return
+		}
+	}
+	s.syncGroupRoles(append(before.Members, group.Members...))
+	s.recordAudit(c, "scim.group_update", group.ID, map[string]string{"display_name": group.DisplayName})
+
+	scimJSON(c, http.StatusOK, toSCIMGroup(group))
+}
+
+// patchGroup applies a PATCH operation to a group
+func (s *Server) patchGroup(group groups.Group, op scimOperation) (groups.Group, error) {
+	path := strings.ToLower(strings.TrimSpace(op.Path))
+	kind := strings.ToLower(op.Op)
+
+	// members[value eq "id"] addresses a single member
+	if strings.HasPrefix(path, "members[") {
+		filter := strings.TrimSuffix(strings.TrimSpace(op.Path)[len("members["):], "]")
+		attribute, value, ok := scimFilter(filter)
+		if !ok || attribute != "value" || kind != "remove" {
+			return group, errors.New("unsupported patch path")
+		}
+		return s.groups.RemoveMembers(group.ID, []string{value})
+	}
+
+	switch path {
+	case "members":
+		var members []scimMember
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &members); err != nil {
+				return group, errors.New("invalid request body")
+			}
+		}
+		ids, ok := scimMemberIDs(group.TenantID, members)
+		if !ok && kind != "remove" {
+			return group, errors.New("user not found")
+		}
+		switch kind {
+		case "add":
+			return s.groups.AddMembers(group.ID, ids)
+		case "remove":
+			if len(members) == 0 {
+				return s.groups.RemoveMembers(group.ID, group.Members)
+			}
+			ids = ids[:0]
+			for _, member := range members {
+				ids = append(ids, member.Value)
+			}
+			return s.groups.RemoveMembers(group.ID, ids)
+		case "replace":
+			return s.groups.Replace(group.ID, group.DisplayName, group.ExternalID, ids)
+		}
+	case "displayname", "externalid", "":
+		if kind != "add" && kind != "replace" {
+			break
+		}
+		displayName, externalID := group.DisplayName, group.ExternalID
+		if path == "" {
+			var value struct {
+				DisplayName *string      `json:"displayName"`
+				ExternalID  *string      `json:"externalId"`
+				Members     []scimMember `json:"members"`
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return group, errors.New("invalid request body")
+			}
+			if value.DisplayName != nil {
+				displayName = *value.DisplayName
+			}
+			if value.ExternalID != nil {
+				externalID = *value.ExternalID
+			}
+			if value.Members != nil {
+				ids, ok := scimMemberIDs(group.TenantID, value.Members)
+				if !ok {
+					return group, errors.New("user not found")
+				}
+				if kind == "add" {
+					ids = append(group.Members, ids...)
+				}
+				group.Members = ids
+			}
+		} else {
+			var value string
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return group, errors.New("invalid request body")
+			}
+			if path == "displayname" {
+				displayName = value
+			} else {
+				externalID = value
+			}
+		}
+		if strings.TrimSpace(displayName) == "" {
+			return group, errors.New("displayName is required")
+		}
+		return s.groups.Replace(group.ID, displayName, externalID, group.Members)
+	}
+	return group, errors.New("unsupported patch operation")
+}
+
+// scimDeleteGroupHandler deletes a group
+func (s *Server) scimDeleteGroupHandler(c *gin.Context) {
+	if _, ok := s.scimTenantGroup(c, c.Param("id")); !ok {
+		return
+	}
+	group, err := s.groups.Delete(c.Param("id"))
+	if err != nil {
+		s.scimGroupError(c, err)
+		return
+	}
+	s.syncGroupRoles(group.Members)
+	s.recordAudit(c, "scim.group_delete", group.ID, map[string]string{"display_name": group.DisplayName})
+
+	c.Status(http.StatusNoContent)
+}
+
+// scimGroupError writes the SCIM error for a failed group change
+func (s *Server) scimGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, groups.ErrGroupNotFound):
+		scimError(c, http.StatusNotFound, "", "Group not found")
+	case errors.Is(err, groups.ErrGroupExists):
+		scimError(c, http.StatusConflict, "uniqueness", "Group already exists")
+	default:
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+	}
+}
+
+// patchResource applies a PATCH operation to the JSON representation of a user.
+// Paths are attributes, sub-attributes ("name.givenName"), extension attributes
+// prefixed with their schema URN, or multi-valued attributes with a filter
+// ("emails[type eq \"work\"].value"), which set the primary value.
+func patchResource(resource map[string]interface{}, op scimOperation) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return errors.New("unsupported patch operation")
+	}
+
+	var value interface{}
+	if kind != "remove" {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return errors.New("invalid request body")
+		}
+	}
+
+	// Without a path, the value holds the attributes to set
+	if op.Path == "" {
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("unsupported patch operation")
+		}
+		for name, attribute := range attributes {
+			if strings.HasPrefix(name, "urn:") && !strings.EqualFold(name, scimEnterpriseSchema) {
+				schema, attr := splitURNPath(name)
+				setPath(resource, schema, attr, attribute)
+				continue
+			}
+			setPath(resource, "", name, attribute)
+		}
+		return nil
+	}
+
+	schema, path := splitURNPath(op.Path)
+	if open := strings.Index(path, "["); open >= 0 {
+		// Filtered multi-valued attributes hold a single primary value here
+		attribute := path[:open]
+		sub := "value"
+		if close := strings.Index(path, "]."); close > open {
+			sub = path[close+2:]
+		}
+		if kind == "remove" {
+			delete(resource, attribute)
+			return nil
+		}
+		resource[attribute] = []interface{}{map[string]interface{}{sub: value, "primary": true}}
+		return nil
+	}
+
+	if kind == "remove" {
+		setPath(resource, schema, path, nil)
+		return nil
+	}
+	setPath(resource, schema, path, value)
+	return nil
+}
+
+// splitURNPath splits an attribute path prefixed with a schema URN, such as
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:organization"
+func splitURNPath(path string) (schema, attribute string) {
+	if !strings.HasPrefix(strings.ToLower(path), "urn:") {
+		return "", path
+	}
+	i := strings.LastIndex(path, ":")
+	schema, attribute = path[:i], path[i+1:]
+	if strings.EqualFold(schema, scimUserSchema) {
+		schema = ""
+	}
+	return schema, attribute
+}
+
+// setPath sets an attribute, or removes it for a nil value. Attribute names are
+// matched case-insensitively, as SCIM requires.
+func setPath(resource map[string]interface{}, schema, path string, value interface{}) {
+	target := resource
+	if schema != "" {
+		extension, _ := resource[schema].(map[string]interface{})
+		if extension == nil {
+			extension = make(map[string]interface{})
+			resource[schema] = extension
+		}
+		target = extension
+	}
+
+	name, sub, nested := strings.Cut(path, ".")
+	name = resourceKey(target, name)
+	if !nested {
+		if value == nil {
+			delete(target, name)
+		} else if fields, ok := value.(map[string]interface{}); ok && target[name] != nil {
+			// Complex values are merged into the current one
+			if current, ok := target[name].(map[string]interface{}); ok {
+				for k, v := range fields {
+					current[resourceKey(current, k)] = v
+				}
+				return
+			}
+			target[name] = value
+		} else {
+			target[name] = value
+		}
+		return
+	}
+
+	parent, _ := target[name].(map[string]interface{})
+	if parent == nil {
+		parent = make(map[string]interface{})
+		target[name] = parent
+	}
+	sub = resourceKey(parent, sub)
+	if value == nil {
+		delete(parent, sub)
+	} else {
+		parent[sub] = value
+	}
+}
+
+// resourceKey returns the key an attribute name has in a JSON object, matched
+// case-insensitively, or the name itself for new attributes
+func resourceKey(object map[string]interface{}, name string) string {
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
@@ -2,25 +2,54 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
 	"github.com/pion/webrtc/v3"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quic-go/webtransport-go"
 
+	"github.com/zubans/video-call-server/internal/apikeys"
+	"github.com/zubans/video-call-server/internal/audit"
 	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/branding"
+	"github.com/zubans/video-call-server/internal/callqueue"
 	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/cluster"
+	"github.com/zubans/video-call-server/internal/contacts"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/files"
+	"github.com/zubans/video-call-server/internal/geoip"
+	"github.com/zubans/video-call-server/internal/groups"
+	"github.com/zubans/video-call-server/internal/hostpolicy"
+	"github.com/zubans/video-call-server/internal/logging"
 	"github.com/zubans/video-call-server/internal/metrics"
 	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/netprobe"
+	"github.com/zubans/video-call-server/internal/notes"
+	"github.com/zubans/video-call-server/internal/notify/channels"
+	"github.com/zubans/video-call-server/internal/notify/email"
+	"github.com/zubans/video-call-server/internal/presence"
 	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/repository"
+	"github.com/zubans/video-call-server/internal/roombots"
+	"github.com/zubans/video-call-server/internal/storage"
+	"github.com/zubans/video-call-server/internal/templates"
+	"github.com/zubans/video-call-server/internal/translate"
 	"github.com/zubans/video-call-server/internal/websocket"
 )
 
@@ -33,12 +62,120 @@ type Server struct {
 	recorder    *recording.Recorder
 	hub         *websocket.Hub
 	metrics     *metrics.Metrics
+	bots        *botManager
+	files       *files.Manager
+	audit       *audit.Logger
+	apiKeys     *apikeys.Manager
+	contacts    *contacts.Manager
+	presence    *presence.Manager
+	calls       *callLog
+	reports     *usageReports
+	integrity   *integrityChecker
+	templates   *templates.Manager
+	events      *events.Bus
+	cluster     *cluster.Registry
+	outbox      *outbox
+	db          *storage.DB
+	repos       repository.Repositories
+	load        *loadMonitor
+	lifecycle   *lifecycle
+	mailer      *email.Mailer
+	chatHooks   *channels.Manager
+	roomBots    *roombots.Manager
+	summarizer  notes.Summarizer
+	notes       *notes.Store
+	translator  translate.Translator
+	probes      *netprobe.Prober
+	geoip       *geoip.Database
+	iceSettings webrtc.SettingEngine
 	httpServer  *http.Server
+	wtServer    *webtransport.Server
+	schema      graphql.Schema
 	wg          sync.WaitGroup
+
+	// Send queue limits for server-side signal channels
+	signalQueueSize       int
+	slowConsumerThreshold int64
+
+	// Configuration reloadable on SIGHUP or via the admin API
+	config atomic.Pointer[runtimeConfig]
+
+	// Token letting the create-admin command register an admin
+	adminBootstrapToken string
+
+	// How requests for rooms hosted on other nodes are routed
+	routingMode string
+
+	// Shared secret authenticating requests between nodes
+	clusterSecret string
+
+	// Edge rooms relayed from other nodes, by room ID
+	cascades   map[string]*cascade
+	cascadesMu sync.Mutex
+
+	// Per-user limit on user searches
+	searchLimiter *rateLimiter
+
+	// Per-tenant limit on API requests
+	tenantLimiter *rateLimiter
+
+	// Flood and spam detection of chat and signaling
+	spam *spamGuard
+
+	// Hold music of rooms with participants on hold
+	holds *holdManager
+
+	// Responses stored for retries carrying an Idempotency-Key
+	idempotency *idempotencyStore
+
+	// One-time tokens of meeting links
+	linkTokens *linkTokenStore
+
+	// SAML SSO (nil when not configured)
+	saml *samlProvider
+
+	// User groups provisioned over SCIM
+	groups *groups.Manager
+
+	// Branding of organizations, by slug
+	branding *branding.Manager
+
+	// Call queues of callers waiting for an agent
+	queues *callqueue.Manager
+
+	// Running pre-join echo tests
+	echoTests *echoTests
+
+	// Soft-deleted rooms awaiting restore or purge, by room ID
+	deletedRooms map[string]*deletedRoom
+	trashMu      sync.Mutex
+
+	// Drain mode for rolling deployments
+	drain drainState
+
+	// Scheduled maintenance windows
+	maintenance *maintenanceSchedule
+
+	// Per-room event loops serializing room state changes
+	roomLoops roomLoops
+
+	// Per-room queues delivering captions in order
+	captions captionQueues
+
+	// Playback positions of recordings, by user
+	playback playbackBookmarks
 }
 
 // NewServer creates a new Server instance
 func NewServer() *Server {
+	// Load settings from CONFIG_FILE before reading the environment
+	if err := loadConfigFile(); err != nil {
+		serverLog.Errorf("Failed to load config file: %v", err)
+	}
+
+	// Token lifetimes bound how long the cluster keeps revoked tokens
+	configureTokens()
+
 	// Initialize room manager
 	roomManager := &models.RoomManager{
 		Rooms: make(map[string]*models.Room),
@@ -53,7 +190,13 @@ func NewServer() *Server {
 	chatManager := chat.NewChatManager()
 
 	// Initialize recorder
-	recorder := recording.NewRecorder("./recordings")
+	recorder := recording.NewRecorder(envString("RECORDINGS_DIR", recording.DefaultDir))
+	if store := newRecordingArchive(); store != nil {
+		recorder.SetArchive(store)
+	}
+	if keyring := newRecordingKeyring(); keyring != nil {
+		recorder.SetKeyring(keyring)
+	}
 
 	// Initialize WebSocket hub
 	hub := websocket.NewHub()
@@ -61,14 +204,82 @@ func NewServer() *Server {
 	// Initialize metrics
 	metr := metrics.AppMetrics
 
-	return &Server{
+	s := &Server{
 		roomManager: roomManager,
 		userManager: userManager,
 		chatManager: chatManager,
 		recorder:    recorder,
 		hub:         hub,
 		metrics:     metr,
+		bots:        newBotManager(),
+		files:       newFileManager(),
+		audit:       audit.NewLogger(),
+		apiKeys:     apikeys.NewManager(),
+		contacts:    contacts.NewManager(),
+		presence:    presence.NewManager(),
+		calls:       newCallLog(),
+		reports:     newUsageReports(),
+		integrity:   newIntegrityChecker(),
+		templates:   templates.NewManager(),
+		events:      events.NewBus(),
+		cluster:     newClusterRegistry(),
+		db:          openDatabase(),
+		load:        newLoadMonitor(),
+		lifecycle:   newLifecycle(),
+
+		signalQueueSize:       int(envInt64("SIGNAL_QUEUE_SIZE", 100)),
+		slowConsumerThreshold: envInt64("SLOW_CONSUMER_DROP_THRESHOLD", 50),
+
+		adminBootstrapToken: os.Getenv("ADMIN_BOOTSTRAP_TOKEN"),
+
+		routingMode:   envString("CLUSTER_ROUTING", routingRedirect),
+		clusterSecret: os.Getenv("CLUSTER_SECRET"),
+		cascades:      make(map[string]*cascade),
+		searchLimiter: newRateLimiter(),
+		tenantLimiter: newRateLimiter(),
+		spam:          newSpamGuard(),
+		holds:         newHoldManager(),
+		idempotency:   newIdempotencyStore(),
+		linkTokens:    newLinkTokenStore(),
+		saml:          newSAML(),
+		groups:        groups.NewManager(),
+		branding:      branding.NewManager(),
+		queues:        callqueue.NewManager(),
+		echoTests:     newEchoTests(),
+		deletedRooms:  make(map[string]*deletedRoom),
+		maintenance:   newMaintenanceSchedule(),
+		mailer:        newMailer(),
+		chatHooks:     channels.NewManager(),
+		probes:        newProber(),
+		geoip:         newGeoIP(),
+		iceSettings:   readICESettings(),
+		summarizer:    newSummarizer(),
+		notes:         notes.NewStore(),
+		translator:    newTranslator(),
+	}
+	s.config.Store(readRuntimeConfig())
+	s.roomBots = roombots.NewManager(s.roomOwner, s.applyBotActions, hostpolicy.New(envList("ROOM_BOT_HOSTS")))
+	// Recordings held through their room or owner are not deleted either
+	s.recorder.SetHoldCheck(s.recordingHeld)
+
+	// Reload the state persisted by earlier runs
+	s.repos = newRepositories(s.db)
+	s.loadState()
+
+	// Deliver events to webhook endpoints and the message broker through the outbox
+	s.outbox = s.newOutbox()
+
+	// Share revoked tokens and token sessions between nodes
+	if s.cluster != nil {
+		auth.SetRevocationStore(s.cluster)
+		auth.SetSessionStore(s.cluster)
+	} else if s.db != nil {
+		// Keep revoked tokens and token sessions across restarts of a single node
+		auth.SetRevocationStore(s.db)
+		auth.SetSessionStore(s.db)
 	}
+
+	return s
 }
 
 // Initialize sets up the server routes and components
@@ -76,12 +287,81 @@ func (s *Server) Initialize() {
 	// Set Gin to release mode
 	gin.SetMode(gin.ReleaseMode)
 
+	// Report errors and panics to Sentry or a generic endpoint, if configured
+	configureErrorReporting()
+
+	// Route the standard library logger through the server log, and log
+	// requests and recovered panics at their own levels
+	log.SetFlags(0)
+	log.SetOutput(serverLog.Writer(logging.LevelInfo))
+
+	// Reject unknown JSON fields and report validation errors by JSON field name
+	configureValidation()
+
 	// Create router
-	s.router = gin.Default()
+	s.router = gin.New()
+	s.router.Use(accessLogMiddleware(), gin.RecoveryWithWriter(httpLog.Writer(logging.LevelError)))
+
+	// Configure WebSocket compression
+	if err := websocket.ConfigureCompression(websocket.CompressionConfig{
+		Enabled:   envBool("WS_COMPRESSION", websocket.DefaultCompressionConfig.Enabled),
+		Level:     int(envInt64("WS_COMPRESSION_LEVEL", int64(websocket.DefaultCompressionConfig.Level))),
+		Threshold: int(envInt64("WS_COMPRESSION_THRESHOLD", int64(websocket.DefaultCompressionConfig.Threshold))),
+	}); err != nil {
+		serverLog.Warnf("Invalid WebSocket compression settings, using defaults: %v", err)
+	}
+
+	// Restrict browser origins and bind signaling clients to authenticated users
+	s.applyConfig(s.settings())
+	s.hub.SetSenderAuthorizer(s.authorizeSignalSender)
+	s.hub.SetMessageObserver(s.observeSignal)
+	s.hub.SetMessageFilter(s.filterSignal)
 
 	// Start WebSocket hub
+	s.hub.SetSlowConsumerThreshold(int(s.slowConsumerThreshold))
 	go s.hub.Run()
 
+	// Start sampling node load for admission control
+	go s.runLoadMonitor()
+
+	// Close rooms left empty for longer than the idle timeout
+	go s.runIdleReaper()
+
+	// Sample participants' connection quality for the call analytics
+	go s.runQualitySampler()
+
+	// Generate daily and weekly usage reports
+	go s.runUsageReports()
+
+	// Move old recordings to the archive and bring back restored ones
+	go s.runRecordingArchiver()
+
+	// Check recordings against their checksums for bit rot and tampering
+	go s.runIntegrityChecks()
+
+	// Purge soft-deleted rooms and chat messages past the restore window
+	go s.runTrashPurger()
+
+	// Remind organizers and invitees of scheduled meetings
+	go s.runReminders()
+
+	// Announce maintenance windows and drain the node ahead of them
+	go s.runMaintenance()
+
+	// Post selected events to Slack and Teams channels
+	chatEvents, _ := s.events.Subscribe()
+	go s.chatHooks.Run(chatEvents)
+
+	// Deliver room events to room bots
+	botEvents, _ := s.events.Subscribe()
+	go s.roomBots.Run(botEvents)
+
+	// Deliver queued events to webhook endpoints and the message broker
+	go s.runOutbox()
+
+	// Build the GraphQL query API
+	s.schema = s.newGraphQLSchema()
+
 	// Setup routes
 	s.setupRoutes()
 
@@ -95,50 +375,276 @@ func (s *Server) Initialize() {
 		Addr:    ":" + port,
 		Handler: s.router,
 	}
+
+	// Offer WebTransport signaling over HTTP/3, if configured
+	s.wtServer = newWebTransport(s.router)
 }
 
 // setupRoutes sets up the server routes
 func (s *Server) setupRoutes() {
+	s.router.Use(s.httpMetricsMiddleware())
+	s.router.Use(s.languageMiddleware())
+	s.router.Use(s.errorReportingMiddleware())
 	s.router.Use(cors.New(cors.Config{
-		AllowAllOrigins:  true,
+		AllowOriginFunc:  s.allowOrigin,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Idempotency-Key", "If-Match", "If-None-Match", "Accept-Language"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed", "ETag", "Content-Language"},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
 	// Public routes
-	s.router.POST("/register", s.registerHandler)
-	s.router.POST("/login", s.loginHandler)
-	s.router.GET("/health", s.healthHandler)
+	public := s.router.Group("/")
+	public.Use(s.bodyLimitMiddleware(routesPublic))
+	{
+		public.POST("/register", s.registerHandler)
+		public.POST("/login", s.loginHandler)
+		public.POST("/refresh", s.refreshHandler)
+		public.GET("/health", s.healthHandler)
+		public.GET("/load", s.loadHandler)
+		public.GET("/maintenance", s.maintenanceHandler)
+		public.GET("/demo", s.demoHandler)
+		public.GET("/avatars/:file", s.avatarHandler)
+		public.GET("/verify-email", s.verifyEmailHandler)
+		public.GET("/meet/:code/info", s.meetingInfoHandler)
+		public.POST("/meet/:code/redeem", s.redeemMeetingLinkHandler)
+		public.GET("/branding/:org_slug", s.brandingHandler)
+	}
+
+	// SAML SSO; responses carrying certificates exceed the public body limit
+	sso := s.router.Group("/saml")
+	sso.Use(s.bodyLimitMiddleware(routesAPI))
+	{
+		sso.GET("/metadata", s.samlMetadataHandler)
+		sso.GET("/login", s.samlLoginHandler)
+		sso.POST("/acs", s.samlACSHandler)
+	}
 
 	// Protected routes
 	authorized := s.router.Group("/")
-	authorized.Use(s.authMiddleware())
+	authorized.Use(s.bodyLimitMiddleware(routesAPI), s.authMiddleware(), s.tenantRateLimitMiddleware(), s.idempotencyMiddleware())
 	{
+		// Session
+		authorized.POST("/logout", s.logoutHandler)
+
+		// Profile
+		authorized.GET("/users/me", s.getProfileHandler)
+		authorized.PATCH("/users/me", s.updateProfileHandler)
+		authorized.PUT("/users/me/avatar", s.uploadAvatarHandler)
+		authorized.DELETE("/users/me/avatar", s.deleteAvatarHandler)
+		authorized.GET("/users/me/status", s.getStatusHandler)
+		authorized.PUT("/users/me/status", s.setStatusHandler)
+		authorized.POST("/users/me/verify-email", s.resendVerificationHandler)
+
+		// User search and contacts
+		authorized.GET("/users/search", s.searchUsersHandler)
+		authorized.GET("/contacts", s.listContactsHandler)
+		authorized.POST("/contacts", s.addContactHandler)
+		authorized.PATCH("/contacts/:user_id", s.updateContactHandler)
+		authorized.DELETE("/contacts/:user_id", s.removeContactHandler)
+		authorized.GET("/blocks", s.listBlocksHandler)
+		authorized.POST("/blocks", s.blockUserHandler)
+		authorized.DELETE("/blocks/:user_id", s.unblockUserHandler)
+
+		// Room templates
+		authorized.POST("/templates", s.createTemplateHandler)
+		authorized.GET("/templates", s.listTemplatesHandler)
+		authorized.GET("/templates/:id", s.getTemplateHandler)
+		authorized.PUT("/templates/:id", s.updateTemplateHandler)
+		authorized.DELETE("/templates/:id", s.deleteTemplateHandler)
+
+		// Pre-join device and network tests
+		authorized.POST("/echo-test", s.createEchoTestHandler)
+		authorized.GET("/echo-test/:id", s.echoTestStatsHandler)
+		authorized.DELETE("/echo-test/:id", s.deleteEchoTestHandler)
+		authorized.POST("/network-probe", s.createNetworkProbeHandler)
+		authorized.GET("/network-probe/:id", s.getNetworkProbeHandler)
+		authorized.GET("/ice-servers", s.iceServersHandler)
+		authorized.GET("/features", s.featuresHandler)
+
 		// Room management
 		authorized.POST("/create-room", s.createRoomHandler)
 		authorized.POST("/join-room", s.joinRoomHandler)
+		authorized.POST("/join-by-code", s.joinByCodeHandler)
 		authorized.POST("/leave-room", s.leaveRoomHandler)
 		authorized.GET("/rooms", s.listRoomsHandler)
+		authorized.GET("/rooms/archived", s.listArchivedRoomsHandler)
+		authorized.GET("/rooms/public", s.publicRoomsHandler)
+		authorized.GET("/rooms/deleted", s.listDeletedRoomsHandler)
+		authorized.GET("/rooms/:id", s.getRoomHandler)
+		authorized.PATCH("/rooms/:id", s.updateRoomHandler)
+		authorized.DELETE("/rooms/:id", s.deleteRoomHandler)
+		authorized.POST("/rooms/:id/restore", s.restoreRoomHandler)
+		authorized.GET("/rooms/:id/ics", s.roomCalendarHandler)
+		authorized.POST("/rooms/:id/link", s.createMeetingLinkHandler)
+		authorized.POST("/rooms/:id/tokens", s.createRoomTokenHandler)
+		authorized.GET("/rooms/:id/participants", s.listParticipantsHandler)
+		authorized.GET("/rooms/:id/analytics", s.roomAnalyticsHandler)
+		authorized.DELETE("/rooms/:id/participants/:user_id/mute", s.unmuteParticipantHandler)
+		authorized.POST("/rooms/:id/hold/:client_id", s.holdParticipantHandler)
+		authorized.POST("/rooms/:id/transfer", s.transferParticipantHandler)
+		authorized.DELETE("/rooms/:id/hold/:client_id", s.resumeParticipantHandler)
+		authorized.GET("/rooms/:id/notes", s.listNotesHandler)
+		authorized.POST("/rooms/:id/notes", s.requireFeature(featureNotes), s.generateNotesHandler)
+
+		// Media bots (virtual participants)
+		authorized.POST("/rooms/:id/bots", s.requireFeature(featureBots), s.createBotHandler)
+		authorized.GET("/rooms/:id/bots", s.listBotsHandler)
+		authorized.POST("/rooms/:id/bots/:bot_id/start", s.requireFeature(featureBots), s.startBotHandler)
+		authorized.POST("/rooms/:id/bots/:bot_id/stop", s.stopBotHandler)
+		authorized.POST("/rooms/:id/bots/:bot_id/seek", s.seekBotHandler)
+		authorized.DELETE("/rooms/:id/bots/:bot_id", s.deleteBotHandler)
+		authorized.POST("/rooms/:id/ingest", s.requireFeature(featureIngest), s.createIngestHandler)
+
+		// Room bots receiving room events by webhook
+		authorized.POST("/room-bots", s.requireFeature(featureBots), s.createRoomBotHandler)
+		authorized.GET("/room-bots", s.listRoomBotsHandler)
+		authorized.DELETE("/room-bots/:id", s.deleteRoomBotHandler)
+
+		// In-call file transfer
+		authorized.POST("/rooms/:id/files", s.requireFeature(featureFileSharing), s.uploadFileHandler)
+		authorized.GET("/rooms/:id/files", s.listFilesHandler)
+		authorized.GET("/rooms/:id/files/:file_id", s.downloadFileHandler)
+		authorized.DELETE("/rooms/:id/files/:file_id", s.deleteFileHandler)
+
+		// Call queues: callers wait in line, agents take the next call
+		authorized.GET("/queues", s.listQueuesHandler)
+		authorized.POST("/queues/:id/join", s.joinQueueHandler)
+		authorized.DELETE("/queues/:id/join", s.leaveQueueHandler)
+		authorized.GET("/queues/:id/callers", s.listQueueCallersHandler)
+		authorized.GET("/queues/:id/agents", s.listQueueAgentsHandler)
+		authorized.POST("/queues/:id/next", s.nextQueueCallerHandler)
+		authorized.GET("/queue-agent", s.getAgentStatusHandler)
+		authorized.PUT("/queue-agent/status", s.setAgentStatusHandler)
 
 		// WebSocket connection
-		authorized.GET("/ws", func(c *gin.Context) {
-			websocket.ServeWs(s.hub, c.Writer, c.Request)
-		})
+		authorized.GET("/ws", s.wsHandler)
+
+		// WebTransport connection over HTTP/3
+		authorized.Handle(http.MethodConnect, "/wt", s.webTransportHandler)
 
 		// Chat
 		authorized.POST("/chat/send", s.sendChatMessageHandler)
 		authorized.GET("/chat/history/:room_id", s.getChatHistoryHandler)
+		authorized.GET("/chat/messages/:room_id/deleted", s.listDeletedChatMessagesHandler)
+		authorized.DELETE("/chat/messages/:room_id/:message_id", s.deleteChatMessageHandler)
+		authorized.POST("/chat/messages/:room_id/:message_id/restore", s.restoreChatMessageHandler)
 
 		// Recording
-		authorized.POST("/recording/start", s.startRecordingHandler)
+		authorized.POST("/recording/start", s.requireFeature(featureRecording), s.startRecordingHandler)
 		authorized.POST("/recording/stop", s.stopRecordingHandler)
+		authorized.POST("/recording/export", s.exportRecordingHandler)
+		authorized.GET("/recording/bookmarks", s.listBookmarksHandler)
+		authorized.POST("/recording/:id/restore", s.restoreRecordingHandler)
+		authorized.GET("/recording/:id/play", s.playRecordingHandler)
+		authorized.GET("/recording/:id/hls/:file", s.hlsHandler)
+		authorized.GET("/recording/:id/chapters", s.chaptersHandler)
+		authorized.GET("/recording/:id/bookmark", s.getBookmarkHandler)
+		authorized.PUT("/recording/:id/bookmark", s.putBookmarkHandler)
 		authorized.GET("/recording/list/:room_id", s.listRecordingsHandler)
 
+		// GraphQL queries over rooms, participants, chat, recordings and users
+		authorized.POST("/graphql", s.graphqlHandler)
+
 		// Metrics
 		authorized.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	}
+
+	// Admin routes
+	admin := s.router.Group("/admin")
+	admin.Use(s.bodyLimitMiddleware(routesAdmin), s.authMiddleware(), s.adminMiddleware(), s.idempotencyMiddleware())
+	{
+		admin.POST("/connections/:client_id/disconnect", s.adminDisconnectHandler)
+		admin.GET("/audit", s.adminAuditHandler)
+		admin.GET("/events", s.adminEventsHandler)
+		admin.GET("/cdr", s.adminCallRecordsHandler)
+		admin.GET("/reports", s.adminListUsageReportsHandler)
+		admin.GET("/reports/usage", s.adminUsageReportHandler)
+		admin.GET("/storage/usage", s.adminStorageUsageHandler)
+		admin.POST("/storage/cleanup", s.adminStorageCleanupHandler)
+		admin.POST("/recordings/:id/archive", s.adminArchiveRecordingHandler)
+		admin.POST("/recordings/:id/verify", s.adminVerifyRecordingHandler)
+		admin.POST("/recordings/verify", s.adminStartVerifyHandler)
+		admin.GET("/recordings/verify", s.adminVerifyStatusHandler)
+		admin.GET("/legal-holds", s.adminListLegalHoldsHandler)
+		admin.PUT("/legal-holds/:kind/:id", s.adminPlaceLegalHoldHandler)
+		admin.DELETE("/legal-holds/:kind/:id", s.adminReleaseLegalHoldHandler)
+		admin.POST("/drain", s.adminDrainHandler)
+		admin.GET("/drain", s.adminDrainStatusHandler)
+		admin.GET("/backup", s.adminBackupHandler)
+		admin.POST("/restore", s.adminRestoreHandler)
+		admin.DELETE("/drain", s.adminCancelDrainHandler)
+		admin.POST("/maintenance", s.adminScheduleMaintenanceHandler)
+		admin.GET("/maintenance", s.adminListMaintenanceHandler)
+		admin.DELETE("/maintenance/:id", s.adminCancelMaintenanceHandler)
+		admin.GET("/config", s.adminConfigHandler)
+		admin.POST("/config/reload", s.adminReloadConfigHandler)
+		admin.POST("/api-keys", s.adminCreateAPIKeyHandler)
+		admin.GET("/api-keys", s.adminListAPIKeysHandler)
+		admin.POST("/api-keys/:id/rotate", s.adminRotateAPIKeyHandler)
+		admin.DELETE("/api-keys/:id", s.adminRevokeAPIKeyHandler)
+		admin.POST("/chat-channels", s.adminCreateChannelHandler)
+		admin.GET("/chat-channels", s.adminListChannelsHandler)
+		admin.PATCH("/chat-channels/:id", s.adminUpdateChannelHandler)
+		admin.DELETE("/chat-channels/:id", s.adminDeleteChannelHandler)
+		admin.GET("/network-probes", s.adminNetworkProbesHandler)
+		admin.GET("/rooms/:id/debug", s.adminRoomDebugHandler)
+		admin.GET("/branding", s.adminListBrandingHandler)
+		admin.PUT("/branding/:org_slug", s.adminSetBrandingHandler)
+		admin.DELETE("/branding/:org_slug", s.adminDeleteBrandingHandler)
+		admin.POST("/queues", s.adminCreateQueueHandler)
+		admin.DELETE("/queues/:id", s.adminDeleteQueueHandler)
+	}
+
+	// SCIM 2.0 provisioning by identity providers, authenticated by API key
+	scim := s.router.Group("/scim/v2")
+	scim.Use(s.bodyLimitMiddleware(routesIntegrations), s.apiKeyMiddleware(), s.tenantRateLimitMiddleware(), s.requireScope(apikeys.ScopeSCIM))
+	{
+		scim.GET("/ServiceProviderConfig", s.scimServiceProviderConfigHandler)
+		scim.GET("/ResourceTypes", s.scimResourceTypesHandler)
+		scim.GET("/Users", s.scimListUsersHandler)
+		scim.POST("/Users", s.scimCreateUserHandler)
+		scim.GET("/Users/:id", s.scimGetUserHandler)
+		scim.PUT("/Users/:id", s.scimReplaceUserHandler)
+		scim.PATCH("/Users/:id", s.scimPatchUserHandler)
+		scim.DELETE("/Users/:id", s.scimDeleteUserHandler)
+		scim.GET("/Groups", s.scimListGroupsHandler)
+		scim.POST("/Groups", s.scimCreateGroupHandler)
+		scim.GET("/Groups/:id", s.scimGetGroupHandler)
+		scim.PUT("/Groups/:id", s.scimReplaceGroupHandler)
+		scim.PATCH("/Groups/:id", s.scimPatchGroupHandler)
+		scim.DELETE("/Groups/:id", s.scimDeleteGroupHandler)
+	}
+
+	// Server-to-server integrations authenticated by API key
+	integrations := s.router.Group("/integrations")
+	integrations.Use(s.bodyLimitMiddleware(routesIntegrations), s.apiKeyMiddleware(), s.tenantRateLimitMiddleware(), s.idempotencyMiddleware())
+	{
+		integrations.POST("/rooms", s.requireScope(apikeys.ScopeRoomsWrite), s.createRoomHandler)
+		integrations.GET("/rooms", s.requireScope(apikeys.ScopeRoomsRead), s.listRoomsHandler)
+		integrations.POST("/rooms/:id/tokens", s.requireScope(apikeys.ScopeRoomsWrite), s.createRoomTokenHandler)
+		integrations.POST("/rooms/:id/transfer", s.requireScope(apikeys.ScopeRoomsWrite), s.transferParticipantHandler)
+		integrations.GET("/recordings/:room_id", s.requireScope(apikeys.ScopeRecordingsRead), s.listRecordingsHandler)
+		integrations.GET("/recordings/:room_id/:recording_id/manifest", s.requireScope(apikeys.ScopeRecordingsRead), s.recordingManifestHandler)
+		integrations.GET("/recordings/:room_id/:recording_id/artifacts/:name", s.requireScope(apikeys.ScopeRecordingsRead), s.recordingArtifactHandler)
+	}
+
+	// Room bots acting on their own, authenticated by bot secret
+	botAPI := s.router.Group("/bot-api")
+	botAPI.Use(s.bodyLimitMiddleware(routesIntegrations), s.botAuthMiddleware(), s.idempotencyMiddleware())
+	{
+		botAPI.POST("/rooms/:id/actions", s.botActionsHandler)
+	}
+
+	// Node-to-node routes
+	if s.cluster != nil {
+		internal := s.router.Group("/cluster")
+		internal.Use(s.clusterMiddleware())
+		{
+			internal.GET("/rooms/:id", s.relayRoomHandler)
+			internal.POST("/rooms/:id/tracks/:track_id/relay", s.relayTrackHandler)
+		}
+	}
 }
 
 // authMiddleware is a middleware for JWT authentication
@@ -151,7 +657,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 			tokenString = c.Query("token")
 		}
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Authorization token required")})
 			c.Abort()
 			return
 		}
@@ -159,7 +665,43 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// Validate token
 		claims, err := auth.ValidateJWT(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid token")})
+			c.Abort()
+			return
+		}
+
+		// Reject logged-out tokens
+		if claims.ID != "" {
+			revoked, err := auth.IsTokenRevoked(claims.ID)
+			if err != nil {
+				serverLog.Errorf("Token revocation check failed: %v", err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Failed to verify token")})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Token has been revoked")})
+				c.Abort()
+				return
+			}
+		}
+
+		// Refresh tokens are only exchanged at /refresh
+		if claims.TokenType() == auth.TokenRefresh {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid token")})
+			c.Abort()
+			return
+		}
+
+		// Tokens with idle expiry must have been used recently
+		if !s.checkIdle(c, claims) {
+			c.Abort()
+			return
+		}
+
+		// Deactivated and deleted users lose access immediately
+		if !auth.UserActive(claims.UserID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Account is deactivated")})
 			c.Abort()
 			return
 		}
@@ -167,6 +709,27 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// Add user info to context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("token_id", claims.ID)
+		c.Set("tenant_id", claims.Tenant)
+
+		// Room-scoped tokens only reach their room
+		if claims.Room != nil {
+			if !checkRoomTokenRoute(c, claims.Room) {
+				c.Abort()
+				return
+			}
+			c.Set("room_grant", claims.Room)
+		}
+
+		// Rooms and recordings of other tenants do not exist for the caller
+		if !s.checkTenantRoute(c) {
+			c.Abort()
+			return
+		}
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
@@ -182,26 +745,59 @@ func (s *Server) Run() {
 	go func() {
 		defer s.wg.Done()
 
-		log.Printf("Video call server starting on port %s", s.httpServer.Addr)
+		serverLog.Infof("Video call server starting on port %s", s.httpServer.Addr)
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			serverLog.Fatalf("Server failed to start: %v", err)
 		}
 	}()
 
+	// Start WebTransport server, if configured
+	if s.wtServer != nil {
+		go func() {
+			serverLog.Infof("WebTransport signaling on UDP %s", s.wtServer.H3.Addr)
+			if err := s.wtServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serverLog.Errorf("WebTransport server failed: %v", err)
+			}
+		}()
+	}
+
+	// Reload configuration on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go s.handleReloadSignal(reload)
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
-		log.Println("Shutting down server...")
+		serverLog.Infof("Shutting down server...")
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if s.wtServer != nil {
+			if err := s.wtServer.Close(); err != nil {
+				serverLog.Errorf("Failed to close WebTransport server: %v", err)
+			}
+		}
 		if err := s.httpServer.Shutdown(ctx); err != nil {
-			log.Fatalf("Server shutdown failed: %v", err)
+			serverLog.Fatalf("Server shutdown failed: %v", err)
+		}
+		if s.cluster != nil {
+			if err := s.cluster.Close(); err != nil {
+				serverLog.Errorf("Failed to leave cluster: %v", err)
+			}
 		}
-		log.Println("Server shutdown complete")
+		if err := s.outbox.close(); err != nil {
+			serverLog.Errorf("Failed to close event broker: %v", err)
+		}
+		if s.db != nil {
+			if err := s.db.Close(); err != nil {
+				serverLog.Errorf("Failed to close database: %v", err)
+			}
+		}
+		serverLog.Infof("Server shutdown complete")
 	}()
 
 	// Wait for server to stop
@@ -217,20 +813,52 @@ func (s *Server) registerHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	tenant, ok := s.requestTenant(c)
+	if !ok {
+		return
+	}
+
+	// Operators may bootstrap an admin with the shared bootstrap token
+	bootstrap := c.GetHeader(adminBootstrapHeader)
+	if bootstrap != "" && !s.validBootstrapToken(bootstrap) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Invalid bootstrap token")})
+		return
+	}
+
+	// With mandatory SSO, accounts are only created by the identity provider
+	if bootstrap == "" && s.ssoRequired() {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Password sign-in is disabled, sign in with SSO")})
 		return
 	}
 
 	// Register user
-	user, err := auth.RegisterUser(req.Username, req.Email, req.Password)
+	user, err := auth.RegisterUser(tenant, req.Username, req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
 		return
 	}
 
+	// Only the bootstrap token grants admin on registration; ADMIN_USERS promotes
+	// existing accounts when the configuration is loaded
+	if bootstrap != "" {
+		auth.SetUserRole(user.ID, auth.RoleAdmin)
+	}
+	s.saveUser(user.ID)
+
 	// Update metrics
 	s.metrics.IncrementUsersRegistered()
 
+	s.sendEmail(user.ID, email.TemplateWelcome, nil)
+	if s.mailer != nil {
+		if err := s.sendVerificationEmail(user.ID); err != nil {
+			serverLog.Errorf("Failed to create email verification for %s: %v", user.ID, err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User registered successfully",
 		"user_id": user.ID,
@@ -245,29 +873,66 @@ func (s *Server) loginHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	tenant, ok := s.requestTenant(c)
+	if !ok {
 		return
 	}
 
 	// Authenticate user
-	user, err := auth.AuthenticateUser(req.Identifier, req.Password)
+	user, err := auth.AuthenticateUser(tenant, req.Identifier, req.Password)
+	if errors.Is(err, auth.ErrUserDeactivated) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Account is deactivated")})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid credentials")})
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateJWT(user.ID, user.Username)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	// With mandatory SSO, only admins keep password sign-in, for break-glass access
+	if s.ssoRequired() && user.Role != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Password sign-in is disabled, sign in with SSO")})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
-		"user_id": user.ID,
-	})
+	s.issueTokens(c, user, "Login successful")
+}
+
+// logoutHandler revokes the token used for the request and, if given, the user's
+// refresh token
+func (s *Server) logoutHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// The body is optional
+	_ = c.ShouldBindJSON(&req)
+
+	tokenID := c.GetString("token_id")
+	if tokenID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Token cannot be revoked")})
+		return
+	}
+
+	if err := auth.RevokeToken(tokenID, c.GetTime("token_expires_at")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to revoke token")})
+		return
+	}
+
+	if req.RefreshToken != "" {
+		claims, err := auth.ValidateJWT(req.RefreshToken)
+		if err == nil && claims.TokenType() == auth.TokenRefresh && claims.UserID == c.GetString("user_id") && claims.ID != "" {
+			if err := auth.RevokeToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to revoke token")})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
 
 // createRoomHandler handles room creation
@@ -276,11 +941,53 @@ func (s *Server) createRoomHandler(c *gin.Context) {
 	_ = c.MustGet("username").(string)
 
 	var req struct {
-		Name string `json:"name" binding:"required"`
+		Name       string               `json:"name" binding:"required"`
+		IsPublic   bool                 `json:"is_public"`
+		TemplateID string               `json:"template_id"`
+		Settings   *models.RoomSettings `json:"settings"`
+		Schedule   *scheduleRequest     `json:"schedule"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if !s.validRoomName(c, req.Name) {
+		return
+	}
+
+	var schedule *models.RoomSchedule
+	if req.Schedule != nil {
+		var err error
+		if schedule, err = req.Schedule.parse(tenantID(c)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+	}
+
+	// Settings given in the request take precedence over the template
+	var settings models.RoomSettings
+	if req.TemplateID != "" {
+		template, err := s.templates.Get(userID, req.TemplateID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Template not found")})
+			return
+		}
+		settings = template.Settings
+	}
+	if req.Settings != nil {
+		settings = *req.Settings
+	}
+	settings, err := normalizeRoomSettings(settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	// Refuse new rooms when the node is at capacity
+	if !s.admit(c, true) {
 		return
 	}
 
@@ -291,89 +998,217 @@ func (s *Server) createRoomHandler(c *gin.Context) {
 		ID:        roomID,
 		Name:      req.Name,
 		CreatorID: userID,
+		TenantID:  tenantID(c),
 		Clients:   make(map[string]*models.Client),
+		Tracks:    make(map[string]*models.PublishedTrack),
 		CreatedAt: time.Now(),
 		IsActive:  true,
+		IsPublic:  req.IsPublic,
+		JoinCode:  s.newJoinCodeLocked(),
+		Settings:  settings,
+		Schedule:  schedule,
 	}
 	s.roomManager.Rooms[roomID] = room
 	s.roomManager.Mu.Unlock()
 
+	// Record this node as the room's host so other nodes route joins here
+	if err := s.claimRoom(c, roomID); err != nil {
+		s.roomManager.Mu.Lock()
+		delete(s.roomManager.Rooms, roomID)
+		s.roomManager.Mu.Unlock()
+
+		serverLog.Errorf("Failed to claim room %s: %v", roomID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Failed to create room")})
+		return
+	}
+	s.saveRoom(room, newEvent(events.RoomCreated, room.ID, map[string]interface{}{
+		"name":       room.Name,
+		"creator_id": room.CreatorID,
+	}))
+
 	// Update metrics
-	s.metrics.IncrementRoomsCreated()
+	s.metrics.IncrementRoomsCreated(room.TenantID)
 	s.metrics.SetRoomsActive(float64(len(s.roomManager.Rooms)))
 
+	if schedule != nil {
+		s.sendInvites(room.ID, room.Name, room.JoinCode, userID, viewSchedule(schedule))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Room created successfully",
-		"room_id": room.ID,
-		"name":    room.Name,
+		"message":   "Room created successfully",
+		"room_id":   room.ID,
+		"name":      room.Name,
+		"is_public": room.IsPublic,
+		"join_code": room.JoinCode,
+		"settings":  room.Settings,
+		"schedule":  viewSchedule(schedule),
 	})
 }
 
 // joinRoomHandler handles joining a room
 func (s *Server) joinRoomHandler(c *gin.Context) {
-	userID := c.MustGet("user_id").(string)
-	username := c.MustGet("username").(string)
-
 	var req struct {
 		RoomID string `json:"room_id" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Keep the body so the request can be proxied to the room's node
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		respondBindError(c, err)
 		return
 	}
 
+	// Room tokens only admit their own room
+	if !allowRoom(c, req.RoomID) {
+		return
+	}
+
+	s.joinRoom(c, req.RoomID)
+}
+
+// joinRoom adds the caller to a room and creates their server-side peer connection
+func (s *Server) joinRoom(c *gin.Context, roomID string) {
+	userID := c.MustGet("user_id").(string)
+	username := c.MustGet("username").(string)
+
 	// Find room
 	s.roomManager.Mu.RLock()
-	room, exists := s.roomManager.Rooms[req.RoomID]
+	room, exists := s.roomManager.Rooms[roomID]
 	s.roomManager.Mu.RUnlock()
 
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+	// The room may be hosted by another node
+	if !exists && s.routeToRoomOwner(c, roomID) {
 		return
 	}
 
-	// Create WebRTC peer connection
-	config := webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{
-				URLs: []string{"stun:stun.l.google.com:19302"},
-			},
-		},
+	// Refuse joins when the node is at capacity
+	if !s.admit(c, false) {
+		return
 	}
 
-	peerConnection, err := webrtc.NewPeerConnection(config)
+	// Serve a room hosted by another node as a local edge
+	if !exists && s.routingMode == routingCascade {
+		var err error
+		if room, err = s.cascadeRoom(c, roomID); err != nil {
+			serverLog.Errorf("Failed to serve room %s as an edge: %v", roomID, err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Room host is unavailable")})
+			return
+		}
+		exists = room != nil
+	}
+
+	if !exists || room.TenantID != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+
+	// Archived rooms cannot be joined
+	room.Mu.RLock()
+	active := room.IsActive
+	ended := !room.EndedAt.IsZero()
+	settings := room.Settings
+	full := settings.MaxParticipants > 0 && len(room.Clients) >= settings.MaxParticipants
+	grant := roomGrant(c)
+	isHost := userID == room.CreatorID || c.GetString("role") == auth.RoleAdmin || (grant != nil && grant.IsHost)
+	waiting := settings.Lobby && !isHost && !hostPresent(room)
+	room.Mu.RUnlock()
+	if !active {
+		if ended {
+			c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room has ended")})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room is archived")})
+		return
+	}
+	if full {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room is full")})
+		return
+	}
+
+	// Lobby rooms only admit participants once a host is present; with hold music they
+	// wait connected, on hold
+	if waiting && s.settings().HoldMusicFile == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Waiting for the host to join"), "lobby": true})
+		return
+	}
+
+	// The host may have blocked this user from their rooms
+	if s.blockedFromRoom(room, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You cannot join this room")})
+		return
+	}
+
+	// Create WebRTC peer connection
+	peerConnection, err := s.newPeerConnection(settings)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create peer connection"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to create peer connection")})
 		return
 	}
 
 	// Create client
+	displayName, avatarURL := profile(userID, username)
 	client := &models.Client{
-		ID:       generateClientID(),
-		UserID:   userID,
-		Username: username,
-		Conn:     peerConnection,
-		Signal:   make(chan interface{}, 100),
-		JoinedAt: time.Now(),
+		ID:          generateClientID(),
+		UserID:      userID,
+		Username:    username,
+		DisplayName: displayName,
+		AvatarURL:   avatarURL,
+		Conn:        peerConnection,
+		Signal:      make(chan interface{}, s.signalQueueSize),
+		JoinedAt:    time.Now(),
+		Permissions: grantPermissions(grant),
+	}
+	if waiting {
+		client.Hold = holdLobby
 	}
 
-	// Add client to room
-	room.Mu.Lock()
-	room.Clients[client.ID] = client
-	room.Mu.Unlock()
-
-	// Update metrics
-	s.metrics.SetRoomParticipants(room.ID, float64(len(room.Clients)))
+	// Tie the participant's resources to the room session
+	s.trackClient(room, client)
 
 	// Setup WebRTC event handlers
 	s.setupWebRTCEvents(room, client)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":   "Joined room successfully",
-		"room_id":   room.ID,
-		"client_id": client.ID,
-	})
+	// Add client to room and subscribe it to published tracks, unless the room was
+	// archived or deleted meanwhile
+	if !s.joinClient(room, client) {
+		peerConnection.Close()
+		s.releaseClient(client.ID)
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room is archived")})
+		return
+	}
+
+	// Lobby participants hear the hold music until a host joins and admits them
+	if waiting {
+		s.playHoldMusic(room, client)
+
+		// A host may have joined meanwhile
+		room.Mu.RLock()
+		waiting = !hostPresent(room)
+		room.Mu.RUnlock()
+		if !waiting {
+			s.resumeClient(room, client)
+		}
+	} else if isHost {
+		s.admitLobby(room)
+	}
+
+	// Rooms with automatic recording start when participants arrive
+	s.autoRecord(room)
+
+	servers, _, _ := s.clientICEServers(c)
+	response := gin.H{
+		"message":     "Joined room successfully",
+		"room_id":     room.ID,
+		"client_id":   client.ID,
+		"ice_servers": servers,
+	}
+	if waiting {
+		response["lobby"] = true
+		response["hold"] = holdLobby
+	}
+	if url := s.webTransportURL(c); url != "" {
+		response["webtransport_url"] = url
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // leaveRoomHandler handles leaving a room
@@ -386,7 +1221,11 @@ func (s *Server) leaveRoomHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	if !allowRoom(c, req.RoomID) || !s.allowTenantRoom(c, req.RoomID) {
 		return
 	}
 
@@ -396,34 +1235,22 @@ func (s *Server) leaveRoomHandler(c *gin.Context) {
 	s.roomManager.Mu.RUnlock()
 
 	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
 		return
 	}
 
-	// Remove client from room
-	room.Mu.Lock()
+	// Find client
+	room.Mu.RLock()
 	client, clientExists := room.Clients[req.ClientID]
-	if clientExists {
-		// Close peer connection
-		if client.Conn != nil {
-			client.Conn.Close()
-		}
-
-		// Close signal channel
-		close(client.Signal)
-
-		// Remove client
-		delete(room.Clients, req.ClientID)
-	}
-	room.Mu.Unlock()
+	room.Mu.RUnlock()
 
 	if !clientExists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Client not found")})
 		return
 	}
 
-	// Update metrics
-	s.metrics.SetRoomParticipants(room.ID, float64(len(room.Clients)))
+	// Remove client from room
+	s.removeClient(room, client)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Left room successfully",
@@ -441,15 +1268,55 @@ func (s *Server) sendChatMessageHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	if limit := s.settings().MaxChatMessageLength; limit > 0 && utf8.RuneCountInString(req.Message) > limit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": trf(c, "message must be at most %d characters", limit)})
+		return
+	}
+
+	// Room tokens may only chat in their room and with the can_chat grant
+	if grant := roomGrant(c); grant != nil && (grant.RoomID != req.RoomID || !grant.CanChat) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Not allowed to chat in this room")})
+		return
+	}
+	if !s.allowTenantRoom(c, req.RoomID) {
+		return
+	}
+
+	// Users blocked by the host cannot post in their rooms
+	room, exists := s.getRoom(req.RoomID)
+	if exists && s.blockedFromRoom(room, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Not allowed to chat in this room")})
+		return
+	}
+
+	displayName, avatarURL := profile(userID, username)
+	sender := chat.Sender{
+		UserID:      userID,
+		Username:    username,
+		DisplayName: displayName,
+		AvatarURL:   avatarURL,
+	}
+
+	// Flooding participants are throttled or shadow-muted
+	if exists && !s.filterChat(c, req.RoomID, room.CreatorID, sender, req.Message) {
 		return
 	}
 
 	// Add message to chat
-	message := s.chatManager.AddMessage(req.RoomID, userID, username, req.Message)
+	message := s.chatManager.AddMessage(req.RoomID, sender, req.Message)
+
+	s.saveMessage(req.RoomID, message.ID)
+
+	// Deliver to connected participants (recorded for replay on reconnect)
+	s.hub.Publish(req.RoomID, "chat", message)
+	s.dispatchChat(message, "")
 
 	// Update metrics
-	s.metrics.IncrementChatMessagesSent()
+	s.metrics.IncrementChatMessagesSent(tenantID(c))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Message sent successfully",
@@ -475,26 +1342,55 @@ func (s *Server) startRecordingHandler(c *gin.Context) {
 
 	var req struct {
 		RoomID string `json:"room_id" binding:"required"`
+		Mode   string `json:"mode"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
 		return
 	}
 
+	if !allowRoom(c, req.RoomID) || !s.allowTenantRoom(c, req.RoomID) {
+		return
+	}
+
+	ownerID := ""
+	if room, exists := s.getRoom(req.RoomID); exists {
+		room.Mu.RLock()
+		policy := room.Settings.RecordingPolicy
+		ownerID = room.CreatorID
+		room.Mu.RUnlock()
+		if policy == models.RecordingDisabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Recording is disabled in this room")})
+			return
+		}
+	}
+
 	// Start recording
-	recording, err := s.recorder.StartRecording(req.RoomID)
+	started, err := s.recorder.StartRecording(tenantID(c), req.RoomID, ownerID, req.Mode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start recording"})
+		if errors.Is(err, recording.ErrInvalidMode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+			return
+		}
+		s.metrics.IncrementRecordingErrors()
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to start recording")})
 		return
 	}
 
 	// Update metrics
-	s.metrics.IncrementRecordingsStarted()
+	s.metrics.IncrementRecordingsStarted(started.TenantID)
+
+	s.publishEvent(events.RecordingStarted, req.RoomID, map[string]interface{}{
+		"recording_id": started.ID,
+		"mode":         started.Mode,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":      "Recording started successfully",
-		"recording_id": recording.ID,
+		"recording_id": started.ID,
+		"mode":         started.Mode,
 	})
 }
 
@@ -505,20 +1401,45 @@ func (s *Server) stopRecordingHandler(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondBindError(c, err)
+		return
+	}
+
+	// Recordings of other tenants do not exist for the caller
+	if recording, ok := s.recorder.GetRecording(req.RecordingID); ok && recording.TenantID != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
 		return
 	}
 
+	// Room tokens may only stop recordings of their room
+	if grant := roomGrant(c); grant != nil {
+		if recording, ok := s.recorder.GetRecording(req.RecordingID); !ok || recording.RoomID != grant.RoomID {
+			c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Token is not valid for this room")})
+			return
+		}
+	}
+
 	// Stop recording
 	err := s.recorder.StopRecording(req.RecordingID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop recording"})
+		s.metrics.IncrementRecordingErrors()
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to stop recording")})
 		return
 	}
 
 	// Update metrics
 	s.metrics.IncrementRecordingsCompleted()
 
+	roomID := ""
+	if recording, ok := s.recorder.GetRecording(req.RecordingID); ok {
+		roomID = recording.RoomID
+	}
+	s.publishEvent(events.RecordingStopped, roomID, map[string]interface{}{
+		"recording_id": req.RecordingID,
+	})
+	go s.processRecording(req.RecordingID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Recording stopped successfully",
 	})
@@ -529,7 +1450,7 @@ func (s *Server) listRecordingsHandler(c *gin.Context) {
 	roomID := c.Param("room_id")
 
 	// List recordings
-	recordings := s.recorder.ListRecordings(roomID)
+	recordings := s.recorder.ListRecordings(tenantID(c), roomID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"recordings": recordings,
@@ -549,70 +1470,60 @@ func (s *Server) setupWebRTCEvents(room *models.Room, client *models.Client) {
 	// Handle ICE candidates
 	client.Conn.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		if candidate != nil {
-			// Broadcast ICE candidate to other clients
-			room.Mu.RLock()
-			for clientID, otherClient := range room.Clients {
-				if clientID != client.ID && otherClient.Signal != nil {
-					select {
-					case otherClient.Signal <- models.SignalMessage{
-						Type:      "ice-candidate",
-						Data:      candidate.ToJSON(),
-						Timestamp: time.Now(),
-						SenderID:  client.ID,
-					}:
-					default:
-						log.Printf("Signal channel full for client %s", clientID)
-					}
-				}
-			}
-			room.Mu.RUnlock()
+			// Server candidates belong to this participant's connection with the server
+			s.sendSignal(client, models.SignalMessage{
+				Type:      "ice-candidate",
+				Data:      candidate.ToJSON(),
+				Timestamp: time.Now(),
+			})
 		}
 	})
 
 	// Handle tracks
 	client.Conn.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
 		// Log track reception
-		log.Printf("Track received from client %s: %s", client.ID, track.Kind())
+		sfuLog.Debugf("Track received from client %s: %s", client.ID, track.Kind())
+
+		// Participants without the publish grant are not forwarded
+		if !canPublish(client) {
+			sfuLog.Warnf("Client %s may not publish, ignoring %s track", client.ID, track.Kind())
+			return
+		}
+
+		// Forward the track to the other participants
+		s.forwardRemoteTrack(room, client.ID, track, receiver, client.Conn)
 	})
 
 	// Handle connection state changes
 	client.Conn.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
-		log.Printf("Connection state changed for client %s: %s", client.ID, state.String())
+		sfuLog.Debugf("Connection state changed for client %s: %s", client.ID, state.String())
 
-		// If connection is closed, remove client from room
-		if state == webrtc.PeerConnectionStateClosed || state == webrtc.PeerConnectionStateFailed {
-			room.Mu.Lock()
-			delete(room.Clients, client.ID)
-			room.Mu.Unlock()
-
-			// Update metrics
-			s.metrics.SetRoomParticipants(room.ID, float64(len(room.Clients)))
-		}
+		// Arm connection timeouts; closed or failed connections release the participant
+		s.peerStateChanged(room, client, state)
 	})
 }
 
-// listRoomsHandler handles listing active rooms
-func (s *Server) listRoomsHandler(c *gin.Context) {
+// authorizeSignalSender checks that a signaling client ID belongs to the user in the given room
+func (s *Server) authorizeSignalSender(userID, roomID, senderID string) bool {
+	room, exists := s.getRoom(roomID)
+	if !exists {
+		return false
+	}
+
+	room.Mu.RLock()
+	defer room.Mu.RUnlock()
+
+	client, exists := room.Clients[senderID]
+	return exists && client.UserID == userID
+}
+
+// getRoom returns a room by ID
+func (s *Server) getRoom(roomID string) (*models.Room, bool) {
 	s.roomManager.Mu.RLock()
 	defer s.roomManager.Mu.RUnlock()
 
-	var rooms []gin.H
-	for _, room := range s.roomManager.Rooms {
-		room.Mu.RLock()
-		rooms = append(rooms, gin.H{
-			"id":                room.ID,
-			"name":              room.Name,
-			"creator_id":        room.CreatorID,
-			"participant_count": len(room.Clients),
-			"created_at":        room.CreatedAt,
-			"is_active":         room.IsActive,
-		})
-		room.Mu.RUnlock()
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"rooms": rooms,
-	})
+	room, exists := s.roomManager.Rooms[roomID]
+	return room, exists
 }
 
 // generateRoomID generates a simple room ID (in production, use UUID)
@@ -624,3 +1535,13 @@ func generateRoomID() string {
 func generateClientID() string {
 	return fmt.Sprintf("client_%d", time.Now().UnixNano())
 }
+
+// generateBotID generates a simple bot ID (in production, use UUID)
+func generateBotID() string {
+	return fmt.Sprintf("bot_%d", time.Now().UnixNano())
+}
+
+// generateIngestID generates a simple ingest ID (in production, use UUID)
+func generateIngestID() string {
+	return fmt.Sprintf("ingest_%d", time.Now().UnixNano())
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"time"
+
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// participantJoined updates room metrics and publishes events after a participant
+// joins, including room.started for the first one; it runs on the room's loop
+func (s *Server) participantJoined(loop *roomLoop, room *models.Room, client *models.Client) {
+	var participants, humans int
+	var announce bool
+	var name, joinCode string
+	loop.update(room, func() {
+		participants = len(room.Clients)
+		for _, other := range room.Clients {
+			if !other.IsBot {
+				humans++
+			}
+		}
+		if !client.IsBot {
+			room.EmptySince = time.Time{}
+			markAttendance(room, client.UserID, time.Now())
+		}
+		announce = room.Settings.ChatAnnouncements
+		name, joinCode = room.Name, room.JoinCode
+	})
+
+	s.metrics.SetRoomParticipants(room.ID, float64(participants))
+	s.metrics.AddParticipants(1)
+	s.calls.join(room.ID, client)
+
+	s.publishEvent(events.ParticipantJoined, room.ID, map[string]interface{}{
+		"client_id":    client.ID,
+		"user_id":      client.UserID,
+		"username":     client.Username,
+		"display_name": client.DisplayName,
+		"avatar_url":   client.AvatarURL,
+		"is_bot":       client.IsBot,
+	})
+	s.publishEvent(events.RoomParticipants, room.ID, map[string]interface{}{
+		"participants": participants,
+	})
+
+	// The first participant starts the call
+	if !client.IsBot && humans == 1 {
+		startedBy := client.DisplayName
+		if startedBy == "" {
+			startedBy = client.Username
+		}
+		s.publishEvent(events.RoomStarted, room.ID, map[string]interface{}{
+			"name":       name,
+			"join_code":  joinCode,
+			"user_id":    client.UserID,
+			"started_by": startedBy,
+		})
+	}
+
+	if announce {
+		s.announce(room.ID, events.ParticipantJoined, client, "joined")
+	}
+}
+
+// participantLeft updates room metrics after a participant leaves and ends the
+// room session once no human participants remain; it runs on the room's loop
+func (s *Server) participantLeft(loop *roomLoop, room *models.Room, client *models.Client) {
+	var participants, humans int
+	var announce bool
+	loop.update(room, func() {
+		participants = len(room.Clients)
+		for _, other := range room.Clients {
+			if !other.IsBot {
+				humans++
+			}
+		}
+		// The idle timeout counts from when the last participant left
+		if humans == 0 && room.EmptySince.IsZero() {
+			room.EmptySince = time.Now()
+		}
+		announce = room.Settings.ChatAnnouncements
+	})
+
+	s.metrics.SetRoomParticipants(room.ID, float64(participants))
+	s.metrics.AddParticipants(-1)
+	s.calls.leave(room.ID, client)
+
+	s.publishEvent(events.ParticipantLeft, room.ID, map[string]interface{}{
+		"client_id": client.ID,
+		"user_id":   client.UserID,
+		"username":  client.Username,
+		"is_bot":    client.IsBot,
+	})
+	s.publishEvent(events.RoomParticipants, room.ID, map[string]interface{}{
+		"participants": participants,
+	})
+
+	if announce {
+		s.announce(room.ID, events.ParticipantLeft, client, "left")
+	}
+
+	if humans == 0 {
+		s.endRoomSession(loop, room)
+	}
+	// Nothing is left to serialize until someone joins again
+	if participants == 0 {
+		loop.stop()
+	}
+}
+
+// announce posts a system chat message such as "Alice joined" about a participant
+func (s *Server) announce(roomID, event string, client *models.Client, verb string) {
+	if client.IsBot {
+		return
+	}
+
+	name := client.DisplayName
+	if name == "" {
+		name = client.Username
+	}
+
+	message := s.chatManager.AddSystemMessage(roomID, event, chat.Sender{
+		UserID:      client.UserID,
+		Username:    client.Username,
+		DisplayName: client.DisplayName,
+		AvatarURL:   client.AvatarURL,
+	}, name+" "+verb)
+	s.saveMessage(roomID, message.ID)
+
+	s.hub.Publish(roomID, "chat", message)
+}
+
+// endRoomSession releases session-scoped resources of a room; it runs on the room's loop
+func (s *Server) endRoomSession(loop *roomLoop, room *models.Room) {
+	if n := s.files.DeleteRoomFiles(room.ID); n > 0 {
+		serverLog.Infof("Room %s session ended, expired %d shared files", room.ID, n)
+	}
+
+	// Close anything still tied to the session
+	s.endRoomLifecycle(room.ID)
+
+	// Edge rooms stop relaying from the origin node
+	s.stopCascade(loop, room)
+
+	s.publishEvent(events.RoomSessionEnded, room.ID, nil)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"sync/atomic"
+
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/queue"
+)
+
+// signalPolicies maps message types to the overflow policy of the signal queue.
+// Stale ICE candidates are superseded by newer ones, so the oldest are dropped;
+// everything else (offers, chat, room events) must not be lost, so a full queue
+// disconnects the slow consumer instead.
+var signalPolicies = map[string]queue.Policy{
+	"ice-candidate": queue.DropOldest,
+}
+
+// signalPolicy returns the overflow policy for a message type
+func signalPolicy(msgType string) queue.Policy {
+	if policy, ok := signalPolicies[msgType]; ok {
+		return policy
+	}
+	return queue.Disconnect
+}
+
+// sendSignal queues a signaling message for a client without blocking
+func (s *Server) sendSignal(client *models.Client, msg models.SignalMessage) bool {
+	if client.Signal == nil {
+		return false
+	}
+
+	policy := signalPolicy(msg.Type)
+	result := queue.Push(client.Signal, interface{}(msg), policy)
+	if !result.Dropped() {
+		return true
+	}
+
+	s.metrics.IncrementSignalMessagesDropped("signal", msg.Type, policy.String())
+	drops := atomic.AddInt64(&client.SignalDrops, 1)
+
+	if result == queue.Overflow || (s.slowConsumerThreshold > 0 && drops >= s.slowConsumerThreshold) {
+		serverLog.Warnf("Disconnecting slow consumer %s: signal queue full (%d dropped)", client.ID, drops)
+		s.metrics.IncrementSlowConsumerDisconnects("signal")
+		s.disconnectClient(client)
+		return false
+	}
+
+	serverLog.Warnf("Signal channel full for client %s, dropped %s message", client.ID, msg.Type)
+	return result == queue.DroppedOldest
+}
+
+// disconnectClient closes a participant's peer connection; the connection state
+// handler then removes it from the room. Closing happens asynchronously because
+// callers may hold the room lock.
+func (s *Server) disconnectClient(client *models.Client) {
+	if client.Conn == nil {
+		return
+	}
+	go client.Conn.Close()
+}
+
+// broadcastSignal queues a signaling message for every participant of a room except exceptID
+func (s *Server) broadcastSignal(room *models.Room, exceptID string, msg models.SignalMessage) {
+	room.Mu.RLock()
+	defer room.Mu.RUnlock()
+
+	for clientID, client := range room.Clients {
+		if clientID != exceptID {
+			s.sendSignal(client, msg)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/events"
+)
+
+// Flood detection settings
+const (
+	// How long strikes count towards a shadow mute
+	spamStrikeWindow = 10 * time.Minute
+
+	// How long the record of a participant that stopped sending is kept
+	spamRecordTTL = time.Hour
+)
+
+// Reasons a message is flagged, reported to hosts, webhooks and metrics
+const (
+	spamReasonChatRate   = "chat_rate"
+	spamReasonRepetition = "repetition"
+	spamReasonMentions   = "mentions"
+	spamReasonSignalRate = "signal_rate"
+	spamReasonMuted      = "muted"
+)
+
+// mentionPattern matches @mentions in chat messages
+var mentionPattern = regexp.MustCompile(`(^|\s)@\S+`)
+
+// spamLimits are the thresholds of flood detection; 0 disables a check
+type spamLimits struct {
+	ChatPerMinute   int `json:"chat_per_minute"`
+	ChatRepeats     int `json:"chat_repeats"`  // identical messages in a row
+	ChatMentions    int `json:"chat_mentions"` // mentions in one message
+	SignalPerMinute int `json:"signal_per_minute"`
+	Strikes         int `json:"strikes"` // flagged messages before a shadow mute
+	MuteSeconds     int `json:"mute_seconds"`
+}
+
+// readSpamLimits reads the SPAM_* variables
+func readSpamLimits() spamLimits {
+	return spamLimits{
+		ChatPerMinute:   int(envInt64("SPAM_CHAT_RATE_LIMIT", 20)),
+		ChatRepeats:     int(envInt64("SPAM_CHAT_REPEAT_LIMIT", 3)),
+		ChatMentions:    int(envInt64("SPAM_CHAT_MENTION_LIMIT", 5)),
+		SignalPerMinute: int(envInt64("SPAM_SIGNAL_RATE_LIMIT", 1200)),
+		Strikes:         int(envInt64("SPAM_STRIKE_LIMIT", 3)),
+		MuteSeconds:     int(envInt64("SPAM_MUTE_SECONDS", 600)),
+	}
+}
+
+// spamVerdict is what happens to a chat message after flood detection
+type spamVerdict int
+
+const (
+	// The message is delivered
+	spamAllowed spamVerdict = iota
+	// The message is refused with 429
+	spamThrottled
+	// The sender is told the message was sent, but nobody else receives it
+	spamShadowed
+)
+
+// spamGuard tracks the message rates, repeated messages and strikes of each
+// participant of each room
+type spamGuard struct {
+	records map[string]*spamRecord
+	mu      sync.Mutex
+}
+
+// spamRecord is the flood detection state of one user in one room
+type spamRecord struct {
+	chatWindow   time.Time
+	chatCount    int
+	signalWindow time.Time
+	signalCount  int
+	lastMessage  string
+	repeats      int
+	strikes      int
+	lastStrike   time.Time
+	mutedUntil   time.Time
+	seen         time.Time
+}
+
+// spamCheck is the outcome of checking a message
+type spamCheck struct {
+	verdict spamVerdict
+	reason  string // set when the message was flagged
+	strike  bool   // the message counted as a strike
+	muted   bool   // the sender was shadow-muted by this message
+}
+
+// newSpamGuard creates a new spamGuard
+func newSpamGuard() *spamGuard {
+	return &spamGuard{
+		records: make(map[string]*spamRecord),
+	}
+}
+
+// record returns the state of a user in a room; the caller holds g.mu
+func (g *spamGuard) record(roomID, userID string, now time.Time) *spamRecord {
+	key := roomID + "/" + userID
+	record, exists := g.records[key]
+	if !exists {
+		// Drop participants that went quiet before adding a new one
+		for k, other := range g.records {
+			if now.Sub(other.seen) >= spamRecordTTL && now.After(other.mutedUntil) {
+				delete(g.records, k)
+			}
+		}
+		record = &spamRecord{}
+		g.records[key] = record
+	}
+	record.seen = now
+	return record
+}
+
+// strike counts a flagged message and shadow-mutes the sender after enough of them
+func (r *spamRecord) strike(check spamCheck, limits spamLimits, now time.Time) spamCheck {
+	if now.Sub(r.lastStrike) >= spamStrikeWindow {
+		r.strikes = 0
+	}
+	r.strikes++
+	r.lastStrike = now
+	check.strike = true
+
+	if limits.Strikes > 0 && r.strikes >= limits.Strikes {
+		r.strikes = 0
+		r.mutedUntil = now.Add(time.Duration(limits.MuteSeconds) * time.Second)
+		check.muted = true
+	}
+	return check
+}
+
+// checkChat applies the chat heuristics to a message: senders over the rate limit are
+// throttled, repeated messages and mass mentions are shadowed, and muted senders have
+// every message shadowed
+func (g *spamGuard) checkChat(roomID, userID, content string, limits spamLimits) spamCheck {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	record := g.record(roomID, userID, now)
+	if now.Before(record.mutedUntil) {
+		return spamCheck{verdict: spamShadowed, reason: spamReasonMuted}
+	}
+
+	if now.Sub(record.chatWindow) >= rateWindow {
+		record.chatWindow = now
+		record.chatCount = 0
+	}
+	record.chatCount++
+	if limits.ChatPerMinute > 0 && record.chatCount > limits.ChatPerMinute {
+		// Only the first message over the limit in a window counts as a strike
+		check := spamCheck{verdict: spamThrottled, reason: spamReasonChatRate}
+		if record.chatCount == limits.ChatPerMinute+1 {
+			check = record.strike(check, limits, now)
+		}
+		return check
+	}
+
+	if limits.ChatMentions > 0 && len(mentionPattern.FindAllString(content, -1)) > limits.ChatMentions {
+		return record.strike(spamCheck{verdict: spamShadowed, reason: spamReasonMentions}, limits, now)
+	}
+
+	normalized := strings.ToLower(strings.Join(strings.Fields(content), " "))
+	if normalized == record.lastMessage {
+		record.repeats++
+	} else {
+		record.lastMessage = normalized
+		record.repeats = 1
+	}
+	if limits.ChatRepeats > 0 && record.repeats > limits.ChatRepeats {
+		return record.strike(spamCheck{verdict: spamShadowed, reason: spamReasonRepetition}, limits, now)
+	}
+
+	return spamCheck{verdict: spamAllowed}
+}
+
+// checkSignal applies the signaling rate limit to a message
+func (g *spamGuard) checkSignal(roomID, userID string, limits spamLimits) spamCheck {
+	if limits.SignalPerMinute <= 0 {
+		return spamCheck{verdict: spamAllowed}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	record := g.record(roomID, userID, now)
+	if now.Sub(record.signalWindow) >= rateWindow {
+		record.signalWindow = now
+		record.signalCount = 0
+	}
+	record.signalCount++
+	if record.signalCount <= limits.SignalPerMinute {
+		return spamCheck{verdict: spamAllowed}
+	}
+
+	check := spamCheck{verdict: spamThrottled, reason: spamReasonSignalRate}
+	if record.signalCount == limits.SignalPerMinute+1 {
+		check = record.strike(check, limits, now)
+	}
+	return check
+}
+
+// unmute lifts the shadow mute and strikes of a user in a room
+func (g *spamGuard) unmute(roomID, userID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	record, exists := g.records[roomID+"/"+userID]
+	if !exists || time.Now().After(record.mutedUntil) {
+		return false
+	}
+	record.mutedUntil = time.Time{}
+	record.strikes = 0
+	return true
+}
+
+// forgetRoom drops the records of a closed room
+func (g *spamGuard) forgetRoom(roomID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for key := range g.records {
+		if strings.HasPrefix(key, roomID+"/") {
+			delete(g.records, key)
+		}
+	}
+}
+
+// spamNotice is the payload of "spam-detected" messages sent to the hosts of a room
+type spamNotice struct {
+	RoomID   string `json:"room_id"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Reason   string `json:"reason"`
+	Muted    bool   `json:"shadow_muted"`
+}
+
+// reportSpam counts a flagged message and, for messages counted as strikes, tells the
+// hosts of the room and publishes participant.flagged
+func (s *Server) reportSpam(roomID, userID, username string, check spamCheck) {
+	s.metrics.IncrementSpamDetections(check.reason)
+	if !check.strike {
+		return
+	}
+	if check.muted {
+		serverLog.Warnf("Shadow-muted user %s in room %s for %s", userID, roomID, check.reason)
+	}
+
+	notice := spamNotice{
+		RoomID:   roomID,
+		UserID:   userID,
+		Username: username,
+		Reason:   check.reason,
+		Muted:    check.muted,
+	}
+	if room, exists := s.getRoom(roomID); exists {
+		room.Mu.RLock()
+		var hosts []string
+		for clientID, client := range room.Clients {
+			if client.UserID != userID && (client.UserID == room.CreatorID || (client.Permissions != nil && client.Permissions.IsHost)) {
+				hosts = append(hosts, clientID)
+			}
+		}
+		room.Mu.RUnlock()
+
+		for _, clientID := range hosts {
+			s.hub.SendToSender(clientID, "spam-detected", notice)
+		}
+	}
+
+	s.publishEvent(events.ParticipantFlagged, roomID, map[string]interface{}{
+		"user_id":      userID,
+		"reason":       check.reason,
+		"shadow_muted": check.muted,
+	})
+}
+
+// filterChat runs flood detection on a chat message of a participant and answers
+// throttled and shadowed messages; it reports whether the message should be delivered.
+// Room creators and admins are exempt.
+func (s *Server) filterChat(c *gin.Context, roomID, creatorID string, sender chat.Sender, content string) bool {
+	if sender.UserID == creatorID || c.GetString("role") == auth.RoleAdmin {
+		return true
+	}
+
+	check := s.spam.checkChat(roomID, sender.UserID, content, s.settings().Spam)
+	if check.verdict == spamAllowed {
+		return true
+	}
+	s.reportSpam(roomID, sender.UserID, sender.Username, check)
+
+	if check.verdict == spamThrottled {
+		c.Header("Retry-After", strconv.Itoa(int(rateWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": tr(c, "You are sending messages too fast")})
+		return false
+	}
+
+	// Shadowed messages look sent to their author but are neither stored nor delivered
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Message sent successfully",
+		"data": &chat.Message{
+			ID:          uuid.New().String(),
+			Type:        chat.TypeUser,
+			RoomID:      roomID,
+			UserID:      sender.UserID,
+			Username:    sender.Username,
+			DisplayName: sender.DisplayName,
+			AvatarURL:   sender.AvatarURL,
+			Content:     content,
+			Timestamp:   time.Now(),
+		},
+	})
+	return false
+}
+
+// filterSignal is the hub's message filter: it drops the signaling messages of
+// participants over SPAM_SIGNAL_RATE_LIMIT
+func (s *Server) filterSignal(roomID, senderID, msgType string) bool {
+	userID := senderID
+	if room, exists := s.getRoom(roomID); exists {
+		room.Mu.RLock()
+		if client, exists := room.Clients[senderID]; exists {
+			userID = client.UserID
+		}
+		room.Mu.RUnlock()
+	}
+
+	check := s.spam.checkSignal(roomID, userID, s.settings().Spam)
+	if check.verdict == spamAllowed {
+		return true
+	}
+
+	username := userID
+	if user, exists := auth.GetUserByID(userID); exists {
+		username = user.Username
+	}
+	s.reportSpam(roomID, userID, username, check)
+	return false
+}
+
+// unmuteParticipantHandler lets a host lift the shadow mute of a participant before it expires
+func (s *Server) unmuteParticipantHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if !isRoomHost(c, room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+
+	userID := c.Param("user_id")
+	if !s.spam.unmute(room.ID, userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Participant is not muted")})
+		return
+	}
+
+	s.recordAudit(c, "participant.unmute", room.ID, map[string]string{"user_id": userID})
+	c.JSON(http.StatusOK, gin.H{"message": "Participant unmuted"})
+}
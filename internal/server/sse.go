@@ -0,0 +1,77 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/events"
+)
+
+// sseKeepAlive is how often a comment is sent to keep idle SSE connections open
+const sseKeepAlive = 15 * time.Second
+
+// publishEvent publishes a server event for dashboards and integrations; events that
+// announce a persisted state change are passed to the save instead, so that they are
+// queued for delivery in the same transaction
+func (s *Server) publishEvent(eventType, roomID string, data map[string]interface{}) {
+	s.persist("event", eventType, nil, newEvent(eventType, roomID, data))
+}
+
+// newEvent creates a server event that happened now
+func newEvent(eventType, roomID string, data map[string]interface{}) events.Event {
+	return events.Event{
+		Type:   eventType,
+		Time:   time.Now(),
+		RoomID: roomID,
+		Data:   data,
+	}
+}
+
+// adminEventsHandler streams server events as Server-Sent Events
+func (s *Server) adminEventsHandler(c *gin.Context) {
+	ch, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Send a snapshot of current rooms so dashboards start from a known state
+	s.roomManager.Mu.RLock()
+	for _, room := range s.roomManager.Rooms {
+		room.Mu.RLock()
+		c.SSEvent(events.RoomParticipants, events.Event{
+			Type:   events.RoomParticipants,
+			Time:   time.Now(),
+			RoomID: room.ID,
+			Data:   gin.H{"name": room.Name, "participants": len(room.Clients)},
+		})
+		room.Mu.RUnlock()
+	}
+	s.roomManager.Mu.RUnlock()
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-ticker.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
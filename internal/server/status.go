@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/presence"
+)
+
+// maxStatusMessageLength is the longest accepted status message, in bytes
+const maxStatusMessageLength = 140
+
+// maxStatusTTL caps how long a status may be set for
+const maxStatusTTL = 7 * 24 * time.Hour
+
+// getStatusHandler returns the current user's availability
+func (s *Server) getStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.presence.Get(c.MustGet("user_id").(string)))
+}
+
+// setStatusHandler changes the current user's availability, optionally for a limited time
+func (s *Server) setStatusHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	var req struct {
+		Status          string `json:"status" binding:"required"`
+		Message         string `json:"message"`
+		DurationSeconds int64  `json:"duration_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if len(req.Message) > maxStatusMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "message must be at most 140 characters")})
+		return
+	}
+	ttl := time.Duration(req.DurationSeconds) * time.Second
+	if ttl < 0 || ttl > maxStatusTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "duration_seconds must be between 0 and 604800")})
+		return
+	}
+
+	status, err := s.presence.Set(userID, req.Status, req.Message, ttl)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+
+	s.publishStatus(userID, status)
+
+	// Announce the return to available when the status runs out
+	if status.ExpiresAt != nil {
+		time.AfterFunc(ttl, func() {
+			if current := s.presence.Get(userID); current.State == presence.Available && current.UpdatedAt != nil && current.UpdatedAt.Equal(*status.ExpiresAt) {
+				s.publishStatus(userID, current)
+			}
+		})
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// publishStatus publishes a presence event for a status change
+func (s *Server) publishStatus(userID string, status presence.Status) {
+	data := map[string]interface{}{
+		"user_id": userID,
+		"status":  status.State,
+	}
+	if status.Message != "" {
+		data["message"] = status.Message
+	}
+	if status.ExpiresAt != nil {
+		data["expires_at"] = *status.ExpiresAt
+	}
+	s.publishEvent(events.UserStatus, "", data)
+}
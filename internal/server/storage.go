@@ -0,0 +1,146 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/recording"
+)
+
+// storageUsage is the recording storage used by an owner or a room
+type storageUsage struct {
+	OwnerID    string `json:"owner_id"`
+	RoomID     string `json:"room_id,omitempty"`
+	Bytes      int64  `json:"bytes"`
+	Recordings int    `json:"recordings"`
+}
+
+// sortedUsage returns usage entries, largest first
+func sortedUsage(usage map[string]*storageUsage) []storageUsage {
+	sorted := make([]storageUsage, 0, len(usage))
+	for _, entry := range usage {
+		sorted = append(sorted, *entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Bytes > sorted[j].Bytes
+	})
+	return sorted
+}
+
+// adminStorageUsageHandler reports recording storage per owner and per room
+func (s *Server) adminStorageUsageHandler(c *gin.Context) {
+	owners := make(map[string]*storageUsage)
+	rooms := make(map[string]*storageUsage)
+	var total int64
+
+	stored := s.recorder.Stored(recording.Filter{})
+	for _, rec := range stored {
+		total += rec.Bytes
+
+		owner, exists := owners[rec.OwnerID]
+		if !exists {
+			owner = &storageUsage{OwnerID: rec.OwnerID}
+			owners[rec.OwnerID] = owner
+		}
+		owner.Bytes += rec.Bytes
+		owner.Recordings++
+
+		room, exists := rooms[rec.RoomID]
+		if !exists {
+			room = &storageUsage{OwnerID: rec.OwnerID, RoomID: rec.RoomID}
+			rooms[rec.RoomID] = room
+		}
+		room.Bytes += rec.Bytes
+		room.Recordings++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_bytes": total,
+		"recordings":  len(stored),
+		"owners":      sortedUsage(owners),
+		"rooms":       sortedUsage(rooms),
+	})
+}
+
+// adminStorageCleanupHandler deletes the recordings matching filters; with dry_run
+// set it only lists them. Active recordings and recordings under legal hold are skipped.
+func (s *Server) adminStorageCleanupHandler(c *gin.Context) {
+	var req struct {
+		OlderThan  string `json:"older_than"`
+		LargerThan int64  `json:"larger_than"`
+		RoomID     string `json:"room_id"`
+		DryRun     bool   `json:"dry_run"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	filter := recording.Filter{MinBytes: req.LargerThan, RoomID: req.RoomID}
+	if req.OlderThan != "" {
+		age, err := time.ParseDuration(req.OlderThan)
+		if err != nil || age <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "older_than must be a positive duration such as 720h")})
+			return
+		}
+		filter.StartedBefore = time.Now().Add(-age)
+	}
+	// Refuse to wipe everything by accident
+	if filter.StartedBefore.IsZero() && filter.MinBytes <= 0 && filter.RoomID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "At least one of older_than, larger_than or room_id is required")})
+		return
+	}
+
+	deleted := []recording.StoredRecording{}
+	var freed int64
+	skipped, held := 0, 0
+	for _, rec := range s.recorder.Stored(filter) {
+		if rec.Active {
+			skipped++
+			continue
+		}
+		if s.recordingHeld(rec) {
+			held++
+			continue
+		}
+		if !req.DryRun {
+			err := s.recorder.DeleteRecording(rec.ID)
+			if errors.Is(err, recording.ErrLegalHold) {
+				// Placed on hold since listed
+				held++
+				continue
+			}
+			if err != nil {
+				recordingLog.Errorf("Failed to delete recording %s: %v", rec.ID, err)
+				continue
+			}
+			s.forgetRecording(rec.ID)
+		}
+		deleted = append(deleted, rec)
+		freed += rec.Bytes
+	}
+
+	if !req.DryRun {
+		s.recordAudit(c, "storage.cleanup", "recordings", map[string]string{
+			"older_than":  req.OlderThan,
+			"larger_than": strconv.FormatInt(req.LargerThan, 10),
+			"room_id":     req.RoomID,
+			"deleted":     strconv.Itoa(len(deleted)),
+			"freed_bytes": strconv.FormatInt(freed, 10),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":        req.DryRun,
+		"recordings":     deleted,
+		"freed_bytes":    freed,
+		"skipped_active": skipped,
+		"skipped_held":   held,
+	})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/templates"
+)
+
+// normalizeRoomSettings validates room settings and fills in defaults
+func normalizeRoomSettings(settings models.RoomSettings) (models.RoomSettings, error) {
+	switch settings.RecordingPolicy {
+	case "":
+		settings.RecordingPolicy = models.RecordingManual
+	case models.RecordingManual, models.RecordingAuto, models.RecordingDisabled:
+	default:
+		return settings, fmt.Errorf("recording_policy must be %s, %s or %s", models.RecordingManual, models.RecordingAuto, models.RecordingDisabled)
+	}
+
+	if settings.MaxParticipants < 0 {
+		return settings, errors.New("max_participants must not be negative")
+	}
+
+	codecs := make([]string, 0, len(settings.VideoCodecs))
+	seen := make(map[string]bool)
+	for _, name := range settings.VideoCodecs {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := videoCodecs[name]; !ok {
+			return settings, fmt.Errorf("unsupported video codec %q: use vp8, vp9, h264 or av1", name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			codecs = append(codecs, name)
+		}
+	}
+	settings.VideoCodecs = codecs
+	if len(codecs) == 0 {
+		settings.VideoCodecs = nil
+	}
+
+	return settings, nil
+}
+
+// bindTemplate reads and validates a template from the request body
+func (s *Server) bindTemplate(c *gin.Context) (string, models.RoomSettings, bool) {
+	var req struct {
+		Name     string              `json:"name" binding:"required"`
+		Settings models.RoomSettings `json:"settings"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return "", models.RoomSettings{}, false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if !s.validRoomName(c, name) {
+		return "", models.RoomSettings{}, false
+	}
+
+	settings, err := normalizeRoomSettings(req.Settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, err.Error())})
+		return "", models.RoomSettings{}, false
+	}
+
+	return name, settings, true
+}
+
+// createTemplateHandler saves a named set of room settings
+func (s *Server) createTemplateHandler(c *gin.Context) {
+	name, settings, ok := s.bindTemplate(c)
+	if !ok {
+		return
+	}
+
+	template := s.templates.Create(c.MustGet("user_id").(string), name, settings)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Template created",
+		"template": template,
+	})
+}
+
+// listTemplatesHandler returns the current user's templates
+func (s *Server) listTemplatesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"templates": s.templates.List(c.MustGet("user_id").(string)),
+	})
+}
+
+// getTemplateHandler returns one of the current user's templates
+func (s *Server) getTemplateHandler(c *gin.Context) {
+	template, err := s.templates.Get(c.MustGet("user_id").(string), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Template not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// updateTemplateHandler replaces a template; rooms already created from it keep their settings
+func (s *Server) updateTemplateHandler(c *gin.Context) {
+	name, settings, ok := s.bindTemplate(c)
+	if !ok {
+		return
+	}
+
+	template, err := s.templates.Update(c.MustGet("user_id").(string), c.Param("id"), name, settings)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Template not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Template updated",
+		"template": template,
+	})
+}
+
+// deleteTemplateHandler removes a template
+func (s *Server) deleteTemplateHandler(c *gin.Context) {
+	if err := s.templates.Delete(c.MustGet("user_id").(string), c.Param("id")); err != nil {
+		if errors.Is(err, templates.ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Template not found")})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to delete template")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted"})
+}
+
+// hostPresent reports whether the room's creator or a participant with the
+// host grant is in the room; the caller holds room.Mu
+func hostPresent(room *models.Room) bool {
+	for _, client := range room.Clients {
+		if client.UserID == room.CreatorID || (client.Permissions != nil && client.Permissions.IsHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// autoRecord starts recording a room whose policy records automatically, unless a recording is running
+func (s *Server) autoRecord(room *models.Room) {
+	var started *recording.Recording
+	var err error
+	// On the room's loop, so that concurrent joins do not start two recordings
+	s.roomUpdate(room, func() {
+		if room.Settings.RecordingPolicy != models.RecordingAuto || !s.featureEnabled(room.TenantID, featureRecording) {
+			return
+		}
+		for _, active := range s.recorder.ListRecordings(room.TenantID, room.ID) {
+			if active.Active {
+				return
+			}
+		}
+		started, err = s.recorder.StartRecording(room.TenantID, room.ID, room.CreatorID, "")
+	})
+	if started == nil && err == nil {
+		return
+	}
+
+	if err != nil {
+		recordingLog.Errorf("Failed to start automatic recording of room %s: %v", room.ID, err)
+		s.reportRecordingError(err, room.ID, "")
+		return
+	}
+
+	s.metrics.IncrementRecordingsStarted(started.TenantID)
+	s.publishEvent(events.RecordingStarted, room.ID, map[string]interface{}{
+		"recording_id": started.ID,
+		"mode":         started.Mode,
+		"automatic":    true,
+	})
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tenantHeader names the tenant of requests made before signing in, such as
+// registration and login; signed-in requests take the tenant of their token
+const tenantHeader = "X-Tenant-ID"
+
+// tenantID returns the tenant of an authenticated request; empty is the default tenant
+func tenantID(c *gin.Context) string {
+	return c.GetString("tenant_id")
+}
+
+// knownTenant reports whether a tenant is listed in TENANTS; the default tenant always is
+func (s *Server) knownTenant(id string) bool {
+	return id == "" || slices.Contains(s.settings().Tenants, id)
+}
+
+// requestTenant resolves the tenant of a request made before signing in from the
+// X-Tenant-ID header or, with TENANT_DOMAIN, from the subdomain of the host (acme for
+// acme.calls.example.com). Requests naming neither belong to the default tenant;
+// unknown tenants are answered with 404.
+func (s *Server) requestTenant(c *gin.Context) (string, bool) {
+	id := strings.ToLower(strings.TrimSpace(c.GetHeader(tenantHeader)))
+	if domain := s.settings().TenantDomain; id == "" && domain != "" {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		id = strings.TrimSuffix(strings.ToLower(host), "."+domain)
+		if id == strings.ToLower(host) || strings.Contains(id, ".") {
+			id = ""
+		}
+	}
+
+	if !s.knownTenant(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Unknown tenant")})
+		return "", false
+	}
+	return id, true
+}
+
+// roomTenant returns the tenant of a live or soft-deleted room
+func (s *Server) roomTenant(roomID string) (string, bool) {
+	if room, exists := s.getRoom(roomID); exists {
+		return room.TenantID, true
+	}
+
+	s.trashMu.Lock()
+	defer s.trashMu.Unlock()
+	if deleted, exists := s.deletedRooms[roomID]; exists {
+		return deleted.room.TenantID, true
+	}
+	return "", false
+}
+
+// allowTenantRoom checks that a room given in the request body belongs to the caller's
+// tenant. Rooms of other tenants are answered as not found, so their IDs cannot be probed.
+func (s *Server) allowTenantRoom(c *gin.Context, roomID string) bool {
+	if tenant, exists := s.roomTenant(roomID); exists && tenant != tenantID(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return false
+	}
+	return true
+}
+
+// checkTenantRoute hides the rooms and recordings of other tenants named in the path.
+// Rooms unknown to this node pass; their handlers answer 404 or route the request to
+// the node hosting the room, which checks it again.
+func (s *Server) checkTenantRoute(c *gin.Context) bool {
+	route := strings.TrimPrefix(c.FullPath(), "/integrations")
+
+	roomID := c.Param("room_id")
+	if strings.HasPrefix(route, "/rooms/:id") {
+		roomID = c.Param("id")
+	}
+	if roomID != "" && !s.allowTenantRoom(c, roomID) {
+		return false
+	}
+
+	if strings.HasPrefix(route, "/recording/:id") {
+		if rec, exists := s.recorder.GetRecording(c.Param("id")); exists && rec.TenantID != tenantID(c) {
+			c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Recording not found")})
+			return false
+		}
+	}
+	return true
+}
+
+// tenantRateLimitMiddleware caps the requests of each tenant at TENANT_RATE_LIMIT per
+// minute, so that one customer cannot starve the others of a shared deployment
+func (s *Server) tenantRateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant := tenantID(c)
+		if !s.tenantLimiter.allow(tenant, s.settings().TenantRateLimit) {
+			s.metrics.IncrementTenantRequestsThrottled(tenant)
+			c.Header("Retry-After", strconv.Itoa(int(rateWindow.Seconds())))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": tr(c, "Too many requests, try again later")})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
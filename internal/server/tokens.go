@@ -0,0 +1,123 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+)
+
+// configureTokens sets the absolute and idle lifetime of each token type from
+// ACCESS_TOKEN_*, REFRESH_TOKEN_* and GUEST_TOKEN_* (TTL_SECONDS and IDLE_SECONDS;
+// an idle timeout of 0 disables idle expiry)
+func configureTokens() {
+	read := func(prefix string, ttl time.Duration) auth.TokenPolicy {
+		return auth.TokenPolicy{
+			TTL:  time.Duration(envInt64(prefix+"_TTL_SECONDS", int64(ttl/time.Second))) * time.Second,
+			Idle: time.Duration(envInt64(prefix+"_IDLE_SECONDS", 0)) * time.Second,
+		}
+	}
+
+	auth.SetTokenPolicy(auth.TokenAccess, read("ACCESS_TOKEN", 24*time.Hour))
+	auth.SetTokenPolicy(auth.TokenRefresh, read("REFRESH_TOKEN", 30*24*time.Hour))
+
+	guest := read("GUEST_TOKEN", time.Hour)
+	if guest.TTL > maxRoomTokenTTL {
+		serverLog.Warnf("GUEST_TOKEN_TTL_SECONDS exceeds the room token limit, using %s", maxRoomTokenTTL)
+		guest.TTL = maxRoomTokenTTL
+	}
+	auth.SetTokenPolicy(auth.TokenGuest, guest)
+}
+
+// tokenPair generates a new access token and refresh token for a user
+func tokenPair(user *auth.User) (token, refreshToken string, err error) {
+	token, err = auth.GenerateJWT(user.ID, user.Username, user.Role, user.TenantID)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err = auth.GenerateRefreshJWT(user.ID, user.Username, user.TenantID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// issueTokens answers a sign-in with a new access token and refresh token
+func (s *Server) issueTokens(c *gin.Context, user *auth.User, message string) {
+	token, refreshToken, err := tokenPair(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to generate token")})
+		return
+	}
+
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"message":            message,
+		"token":              token,
+		"expires_at":         now.Add(auth.Policy(auth.TokenAccess).TTL),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": now.Add(auth.Policy(auth.TokenRefresh).TTL),
+		"user_id":            user.ID,
+	})
+}
+
+// refreshHandler exchanges a refresh token for a new access token and refresh
+// token. The refresh token is claimed by revoking it atomically, so each one can be
+// used once even by concurrent requests; a token already claimed is being reused.
+func (s *Server) refreshHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	claims, err := auth.ValidateJWT(req.RefreshToken)
+	if err != nil || claims.TokenType() != auth.TokenRefresh || claims.ID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid refresh token")})
+		return
+	}
+
+	claimed, err := auth.TryRevokeToken(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		serverLog.Errorf("Token revocation failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Failed to verify token")})
+		return
+	}
+	if !claimed {
+		serverLog.Warnf("Refresh token %s of user %s was reused", claims.ID, claims.UserID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Token has been revoked")})
+		return
+	}
+	if !s.checkIdle(c, claims) {
+		return
+	}
+
+	user, exists := auth.GetUserByID(claims.UserID)
+	if !exists || user.Deactivated {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Invalid refresh token")})
+		return
+	}
+
+	s.issueTokens(c, user, "Token refreshed")
+}
+
+// checkIdle records a use of a token whose type has idle expiry, answering 401 if
+// it went unused for too long
+func (s *Server) checkIdle(c *gin.Context, claims *auth.Claims) bool {
+	alive, err := auth.TouchSession(claims)
+	if err != nil {
+		serverLog.Errorf("Session check failed: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "Failed to verify token")})
+		return false
+	}
+	if !alive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": tr(c, "Session expired due to inactivity")})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/events"
+)
+
+// transferMessage is the payload of "transfer" messages telling a participant to rejoin
+// in another room with the enclosed token
+type transferMessage struct {
+	FromRoomID string    `json:"from_room_id"`
+	RoomID     string    `json:"room_id"`
+	Token      string    `json:"token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// transferParticipantHandler moves a participant to another room: it mints a transfer
+// token for the target room, sends it to the participant in a "transfer" message and
+// removes the participant from this room. The client rejoins with the token.
+func (s *Server) transferParticipantHandler(c *gin.Context) {
+	var req struct {
+		ClientID     string `json:"client_id" binding:"required"`
+		TargetRoomID string `json:"target_room_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if req.TargetRoomID == room.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": tr(c, "Cannot transfer to the same room")})
+		return
+	}
+
+	// Rooms of other tenants are not found
+	target, exists := s.getRoom(req.TargetRoomID)
+	if !exists || target.TenantID != room.TenantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Target room not found")})
+		return
+	}
+	// API keys are scoped by their middleware; users must manage both rooms
+	if _, viaKey := c.Get("api_key"); !viaKey && (!isRoomHost(c, room) || !isRoomHost(c, target)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+
+	room.Mu.RLock()
+	client, exists := room.Clients[req.ClientID]
+	room.Mu.RUnlock()
+	if !exists || client.IsBot {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Client not found")})
+		return
+	}
+
+	target.Mu.RLock()
+	active := target.IsActive
+	full := target.Settings.MaxParticipants > 0 && len(target.Clients) >= target.Settings.MaxParticipants
+	target.Mu.RUnlock()
+	if !active {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room is archived")})
+		return
+	}
+	if full {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room is full")})
+		return
+	}
+	if s.blockedFromRoom(target, client.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "You cannot join this room")})
+		return
+	}
+
+	// The participant keeps its restrictions but not the host grant
+	grant := auth.RoomGrant{
+		RoomID:       target.ID,
+		CanPublish:   true,
+		CanSubscribe: true,
+		CanChat:      true,
+	}
+	if client.Permissions != nil {
+		grant.CanPublish = client.Permissions.CanPublish
+		grant.CanSubscribe = client.Permissions.CanSubscribe
+		grant.CanChat = client.Permissions.CanChat
+	}
+
+	ttl := auth.Policy(auth.TokenGuest).TTL
+	token, err := auth.GenerateRoomJWT(client.UserID, client.Username, target.TenantID, grant, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": tr(c, "Failed to generate token")})
+		return
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	// The message is delivered before the WebSocket closes with the teardown
+	delivered := s.hub.SendToSender(client.ID, "transfer", transferMessage{
+		FromRoomID: room.ID,
+		RoomID:     target.ID,
+		Token:      token,
+		ExpiresAt:  expiresAt,
+	})
+	s.removeClient(room, client)
+	s.hub.DisconnectSender(client.ID)
+
+	s.publishEvent(events.ParticipantTransferred, room.ID, map[string]interface{}{
+		"client_id":      client.ID,
+		"user_id":        client.UserID,
+		"target_room_id": target.ID,
+	})
+	s.recordAudit(c, "participant.transfer", room.ID, map[string]string{
+		"client_id":      client.ID,
+		"user_id":        client.UserID,
+		"target_room_id": target.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Participant transferred",
+		"user_id":        client.UserID,
+		"target_room_id": target.ID,
+		"token":          token,
+		"expires_at":     expiresAt,
+		"notified":       delivered > 0,
+	})
+}
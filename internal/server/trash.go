@@ -0,0 +1,362 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/events"
+	"github.com/zubans/video-call-server/internal/models"
+	"github.com/zubans/video-call-server/internal/repository"
+)
+
+// trashPurgeInterval is how often soft-deleted rooms and messages past the restore window are purged
+const trashPurgeInterval = time.Minute
+
+// deletedRoom is a soft-deleted room, kept out of the room manager until it is
+// restored or purged
+type deletedRoom struct {
+	room      *models.Room
+	deletedAt time.Time
+	deletedBy string
+}
+
+// deletedRoomView is the JSON representation of a soft-deleted room
+type deletedRoomView struct {
+	roomSummaryView
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// restoreWindow returns how long soft-deleted rooms and messages can be restored
+func (s *Server) restoreWindow() time.Duration {
+	return time.Duration(s.settings().RestoreWindowSeconds) * time.Second
+}
+
+// isRoomHost reports whether the caller manages a room: its creator, an admin, or
+// the holder of a host grant for it
+func isRoomHost(c *gin.Context, room *models.Room) bool {
+	if c.GetString("user_id") == room.CreatorID || c.GetString("role") == auth.RoleAdmin {
+		return true
+	}
+	grant := roomGrant(c)
+	return grant != nil && grant.IsHost && grant.RoomID == room.ID
+}
+
+// deleteRoomHandler soft-deletes a room: the call ends and the room disappears from
+// listings, but its creator or an admin can restore it within the restore window.
+// If-Match is honored when given.
+func (s *Server) deleteRoomHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	room, exists := s.getRoom(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if room.CreatorID != userID && c.GetString("role") != auth.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+	if roomHeld(room) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Room is under legal hold")})
+		return
+	}
+
+	// On the room's loop, so that joins already queued complete first and later ones
+	// find the room closed
+	ifMatch := c.GetHeader("If-Match")
+	var etag string
+	active, modified := false, false
+	s.roomUpdate(room, func() {
+		if etag = roomETag(room); ifMatch != "" && !etagMatches(ifMatch, etag) {
+			modified = true
+			return
+		}
+		active = room.IsActive
+		if active {
+			room.IsActive = false
+			room.EndedAt = time.Now()
+		}
+		room.Version++
+	})
+	if modified {
+		c.Header("ETag", etag)
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": tr(c, "Room was modified by someone else; reload it and retry")})
+		return
+	}
+
+	deleted := &deletedRoom{room: room, deletedAt: time.Now(), deletedBy: userID}
+	s.roomManager.Mu.Lock()
+	delete(s.roomManager.Rooms, room.ID)
+	s.metrics.SetRoomsActive(float64(len(s.roomManager.Rooms)))
+	s.roomManager.Mu.Unlock()
+
+	s.trashMu.Lock()
+	s.deletedRooms[room.ID] = deleted
+	s.trashMu.Unlock()
+
+	if active {
+		s.endRoom(room, "deleted")
+	}
+	s.stopRoomLoop(room.ID)
+
+	purgeAt := deleted.deletedAt.Add(s.restoreWindow())
+	s.recordAudit(c, "room.delete", room.ID, nil)
+	s.saveDeletedRoom(deleted, newEvent(events.RoomDeleted, room.ID, map[string]interface{}{
+		"deleted_by": userID,
+		"purge_at":   purgeAt,
+	}))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Room deleted",
+		"purge_at": purgeAt,
+	})
+}
+
+// restoreRoomHandler brings back a soft-deleted room within the restore window.
+// The room comes back archived; its creator can reopen it with PATCH /rooms/:id.
+func (s *Server) restoreRoomHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	roomID := c.Param("id")
+
+	s.trashMu.Lock()
+	deleted, exists := s.deletedRooms[roomID]
+	if !exists {
+		s.trashMu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Deleted room not found")})
+		return
+	}
+	if deleted.room.CreatorID != userID && c.GetString("role") != auth.RoleAdmin {
+		s.trashMu.Unlock()
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the room creator can manage this room")})
+		return
+	}
+	if time.Since(deleted.deletedAt) > s.restoreWindow() {
+		s.trashMu.Unlock()
+		c.JSON(http.StatusGone, gin.H{"error": tr(c, "Restore window has passed")})
+		return
+	}
+	delete(s.deletedRooms, roomID)
+	s.trashMu.Unlock()
+
+	room := deleted.room
+	s.roomManager.Mu.Lock()
+	room.Mu.Lock()
+	// The join code may have been given to another room in the meantime
+	if _, taken := s.roomByCodeLocked(room.JoinCode); taken {
+		room.JoinCode = s.newJoinCodeLocked()
+	}
+	room.Version++
+	summary := roomSummary(room)
+	etag := roomETag(room)
+	room.Mu.Unlock()
+	s.roomManager.Rooms[roomID] = room
+	s.metrics.SetRoomsActive(float64(len(s.roomManager.Rooms)))
+	s.roomManager.Mu.Unlock()
+	s.saveRoom(room, newEvent(events.RoomRestored, roomID, map[string]interface{}{
+		"restored_by": userID,
+	}))
+	s.recordAudit(c, "room.restore", roomID, nil)
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Room restored",
+		"room":    summary,
+	})
+}
+
+// listDeletedRoomsHandler lists soft-deleted rooms that can still be restored: all of
+// them for admins, otherwise the user's own
+func (s *Server) listDeletedRoomsHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+	isAdmin := c.GetString("role") == auth.RoleAdmin
+	window := s.restoreWindow()
+
+	s.trashMu.Lock()
+	rooms := make([]deletedRoomView, 0, len(s.deletedRooms))
+	for _, deleted := range s.deletedRooms {
+		if deleted.room.TenantID != tenantID(c) || (!isAdmin && deleted.room.CreatorID != userID) {
+			continue
+		}
+		deleted.room.Mu.RLock()
+		summary := roomSummary(deleted.room)
+		deleted.room.Mu.RUnlock()
+
+		rooms = append(rooms, deletedRoomView{
+			roomSummaryView: summary,
+			DeletedAt:       deleted.deletedAt,
+			DeletedBy:       deleted.deletedBy,
+			PurgeAt:         deleted.deletedAt.Add(window),
+		})
+	}
+	s.trashMu.Unlock()
+
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].DeletedAt.After(rooms[j].DeletedAt)
+	})
+
+	c.JSON(http.StatusOK, gin.H{"rooms": rooms})
+}
+
+// chatMessage finds the room and message addressed by the route; replies 404 when either is missing
+func (s *Server) chatMessage(c *gin.Context) (*models.Room, *chat.Message, bool) {
+	room, exists := s.getRoom(c.Param("room_id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return nil, nil, false
+	}
+	message, exists := s.chatManager.GetMessage(room.ID, c.Param("message_id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Message not found")})
+		return nil, nil, false
+	}
+	return room, message, true
+}
+
+// deleteChatMessageHandler soft-deletes a chat message; authors may delete their own
+// messages, hosts and admins any message. Participants receive chat-deleted.
+func (s *Server) deleteChatMessageHandler(c *gin.Context) {
+	userID := c.MustGet("user_id").(string)
+
+	room, message, ok := s.chatMessage(c)
+	if !ok {
+		return
+	}
+	if !isRoomHost(c, room) && (message.Type != chat.TypeUser || message.UserID != userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Not allowed to delete this message")})
+		return
+	}
+	if messageHeld(room, message) {
+		c.JSON(http.StatusConflict, gin.H{"error": tr(c, "Message is under legal hold")})
+		return
+	}
+
+	deleted, err := s.chatManager.DeleteMessage(room.ID, message.ID, userID)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, chat.ErrAlreadyDeleted) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+	s.saveMessage(room.ID, deleted.ID)
+
+	s.hub.Publish(room.ID, "chat-deleted", gin.H{
+		"room_id":    room.ID,
+		"message_id": deleted.ID,
+		"deleted_by": userID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Message deleted",
+		"purge_at": deleted.DeletedAt.Add(s.restoreWindow()),
+	})
+}
+
+// restoreChatMessageHandler puts a deleted message back within the restore window;
+// allowed for hosts and admins. Participants receive the message again as chat-restored.
+func (s *Server) restoreChatMessageHandler(c *gin.Context) {
+	room, message, ok := s.chatMessage(c)
+	if !ok {
+		return
+	}
+	if !isRoomHost(c, room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the host can restore messages")})
+		return
+	}
+	if message.DeletedAt != nil && time.Since(*message.DeletedAt) > s.restoreWindow() {
+		c.JSON(http.StatusGone, gin.H{"error": tr(c, "Restore window has passed")})
+		return
+	}
+
+	restored, err := s.chatManager.RestoreMessage(room.ID, message.ID)
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, chat.ErrNotDeleted) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": tr(c, err.Error())})
+		return
+	}
+	s.saveMessage(room.ID, restored.ID)
+
+	s.hub.Publish(room.ID, "chat-restored", restored)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Message restored",
+		"data":    restored,
+	})
+}
+
+// listDeletedChatMessagesHandler lists a room's deleted messages for hosts and admins
+func (s *Server) listDeletedChatMessagesHandler(c *gin.Context) {
+	room, exists := s.getRoom(c.Param("room_id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": tr(c, "Room not found")})
+		return
+	}
+	if !isRoomHost(c, room) {
+		c.JSON(http.StatusForbidden, gin.H{"error": tr(c, "Only the host can view deleted messages")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": s.chatManager.GetDeletedMessages(room.ID),
+	})
+}
+
+// runTrashPurger permanently removes soft-deleted rooms and messages once the
+// restore window has passed
+func (s *Server) runTrashPurger() {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.purgeTrash()
+	}
+}
+
+// purgeTrash removes soft-deletes older than the restore window. Rooms and messages
+// under legal hold, and rooms holding such messages, stay in the trash.
+func (s *Server) purgeTrash() {
+	before := time.Now().Add(-s.restoreWindow())
+	held := s.heldChat()
+
+	var purged []string
+	s.trashMu.Lock()
+	for roomID, deleted := range s.deletedRooms {
+		if deleted.deletedAt.Before(before) && !held.Rooms[roomID] && !s.chatManager.HasHeld(roomID, held) {
+			delete(s.deletedRooms, roomID)
+			purged = append(purged, roomID)
+		}
+	}
+	s.trashMu.Unlock()
+
+	for _, roomID := range purged {
+		s.chatManager.DeleteMessagesForRoom(roomID)
+		s.notes.DeleteRoom(roomID)
+		s.forgetRoom(roomID)
+		if s.cluster != nil {
+			if err := s.cluster.ReleaseRoom(context.Background(), roomID); err != nil {
+				serverLog.Warnf("Failed to release purged room %s: %v", roomID, err)
+			}
+		}
+		serverLog.Infof("Purged deleted room %s", roomID)
+	}
+
+	if n := s.chatManager.PurgeDeleted(before, held); n > 0 {
+		serverLog.Infof("Purged %d deleted chat messages", n)
+	}
+	s.persist("chat messages", "deleted before "+before.Format(time.RFC3339), func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Chat.PurgeDeletedMessages(ctx, before, held)
+	})
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/zubans/video-call-server/internal/geoip"
+)
+
+// How a client was matched to a TURN region
+const (
+	regionByNetwork   = "network"
+	regionByCountry   = "country"
+	regionByDistance  = "distance"
+	regionByContinent = "continent"
+	regionByDefault   = "default"
+)
+
+// turnRegion is a TURN cluster and the clients it serves: client networks, GeoIP
+// countries and continents, and its coordinates for picking the nearest cluster
+type turnRegion struct {
+	Name       string   `json:"name"`
+	URLs       []string `json:"urls"`
+	Networks   []string `json:"networks,omitempty"`
+	Countries  []string `json:"countries,omitempty"`
+	Continents []string `json:"continents,omitempty"`
+	Location   string   `json:"location,omitempty"`
+
+	networks    []*net.IPNet
+	latitude    float64
+	longitude   float64
+	hasLocation bool
+}
+
+// newGeoIP opens the MaxMind database at GEOIP_DATABASE; without it TURN regions are
+// matched by client network only and nil is returned
+func newGeoIP() *geoip.Database {
+	path := os.Getenv("GEOIP_DATABASE")
+	if path == "" {
+		return nil
+	}
+
+	db, err := geoip.Open(path)
+	if err != nil {
+		serverLog.Errorf("GeoIP disabled: %v", err)
+		return nil
+	}
+	return db
+}
+
+// readTURNRegions reads the TURN clusters named in TURN_REGIONS from the
+// TURN_REGION_<NAME>_* variables; regions without URLs are skipped
+func readTURNRegions() []turnRegion {
+	var regions []turnRegion
+	for _, name := range envList("TURN_REGIONS") {
+		prefix := "TURN_REGION_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		region := turnRegion{
+			Name:       name,
+			URLs:       envList(prefix + "URLS"),
+			Networks:   envList(prefix + "NETWORKS"),
+			Countries:  envList(prefix + "COUNTRIES"),
+			Continents: envList(prefix + "CONTINENTS"),
+			Location:   os.Getenv(prefix + "LOCATION"),
+		}
+		if len(region.URLs) == 0 {
+			serverLog.Warnf("TURN region %s has no %sURLS, skipping it", name, prefix)
+			continue
+		}
+
+		for _, cidr := range region.Networks {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				serverLog.Warnf("Invalid network %q for TURN region %s: %v", cidr, name, err)
+				continue
+			}
+			region.networks = append(region.networks, network)
+		}
+
+		if region.Location != "" {
+			lat, lon, _ := strings.Cut(region.Location, ",")
+			latitude, latErr := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+			longitude, lonErr := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+			if latErr != nil || lonErr != nil {
+				serverLog.Warnf("Invalid location %q for TURN region %s, expected latitude,longitude", region.Location, name)
+			} else {
+				region.latitude, region.longitude, region.hasLocation = latitude, longitude, true
+			}
+		}
+
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// nearestTURNRegion picks the region for a client address: a region listing the
+// client's network, then one listing its GeoIP country, then the closest region by
+// coordinates, then one listing its continent, and otherwise the first region.
+// It returns nil when no regions are configured.
+func (s *Server) nearestTURNRegion(regions []turnRegion, ip net.IP) (*turnRegion, string) {
+	if len(regions) == 0 {
+		return nil, ""
+	}
+
+	for i := range regions {
+		for _, network := range regions[i].networks {
+			if network.Contains(ip) {
+				return &regions[i], regionByNetwork
+			}
+		}
+	}
+
+	location, found := s.geoip.Lookup(ip)
+	if !found {
+		return &regions[0], regionByDefault
+	}
+
+	for i := range regions {
+		for _, country := range regions[i].Countries {
+			if strings.EqualFold(country, location.Country) {
+				return &regions[i], regionByCountry
+			}
+		}
+	}
+
+	if location.HasCoordinates {
+		var nearest *turnRegion
+		var nearestKm float64
+		for i := range regions {
+			if !regions[i].hasLocation {
+				continue
+			}
+			km := geoip.DistanceKm(location.Latitude, location.Longitude, regions[i].latitude, regions[i].longitude)
+			if nearest == nil || km < nearestKm {
+				nearest, nearestKm = &regions[i], km
+			}
+		}
+		if nearest != nil {
+			return nearest, regionByDistance
+		}
+	}
+
+	for i := range regions {
+		for _, continent := range regions[i].Continents {
+			if strings.EqualFold(continent, location.Continent) {
+				return &regions[i], regionByContinent
+			}
+		}
+	}
+
+	return &regions[0], regionByDefault
+}
+
+// splitICEServers separates STUN from TURN URLs
+func splitICEServers(urls []string) (stun, turn []string) {
+	for _, url := range urls {
+		if strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:") {
+			turn = append(turn, url)
+		} else {
+			stun = append(stun, url)
+		}
+	}
+	return stun, turn
+}
+
+// iceServers builds the ICE server list from STUN and TURN URLs, with the
+// configured TURN credentials
+func iceServers(config *runtimeConfig, stun, turn []string) []webrtc.ICEServer {
+	var servers []webrtc.ICEServer
+	if len(stun) > 0 {
+		servers = append(servers, webrtc.ICEServer{URLs: stun})
+	}
+	if len(turn) > 0 {
+		servers = append(servers, webrtc.ICEServer{
+			URLs:       turn,
+			Username:   config.TURNUsername,
+			Credential: config.turnCredential,
+		})
+	}
+	return servers
+}
+
+// clientICEServers returns the ICE servers for a client: the STUN servers of
+// ICE_SERVERS and the TURN servers of the region nearest to the client, or those of
+// ICE_SERVERS when no regions are configured. It also returns the chosen region
+// and how it was matched.
+func (s *Server) clientICEServers(c *gin.Context) ([]webrtc.ICEServer, string, string) {
+	config := s.settings()
+	stun, turn := splitICEServers(config.ICEServers)
+
+	region, matchedBy := s.nearestTURNRegion(config.TURNRegions, net.ParseIP(c.ClientIP()))
+	if region == nil {
+		return iceServers(config, stun, turn), "", ""
+	}
+
+	sfuLog.Debugf("Selected TURN region %s for %s by %s", region.Name, c.ClientIP(), matchedBy)
+	return iceServers(config, stun, region.URLs), region.Name, matchedBy
+}
+
+// iceServersHandler returns the ICE servers a client should use, with TURN servers
+// of the region nearest to it
+func (s *Server) iceServersHandler(c *gin.Context) {
+	servers, region, matchedBy := s.clientICEServers(c)
+
+	response := gin.H{"ice_servers": servers}
+	if region != "" {
+		response["turn_region"] = region
+		response["matched_by"] = matchedBy
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quic-go/webtransport-go"
+
+	"github.com/zubans/video-call-server/internal/websocket"
+)
+
+// newWebTransport creates the WebTransport signaling server on the UDP address
+// WEBTRANSPORT_ADDR with the certificate in WEBTRANSPORT_CERT_FILE and
+// WEBTRANSPORT_KEY_FILE. Sessions go through handler, so they are authenticated like
+// any other request. Without an address signaling is WebSocket only and nil is returned.
+func newWebTransport(handler http.Handler) *webtransport.Server {
+	addr := os.Getenv("WEBTRANSPORT_ADDR")
+	if addr == "" {
+		return nil
+	}
+
+	server, err := websocket.NewWebTransportServer(addr, os.Getenv("WEBTRANSPORT_CERT_FILE"), os.Getenv("WEBTRANSPORT_KEY_FILE"), handler)
+	if err != nil {
+		serverLog.Errorf("WebTransport disabled: %v", err)
+		return nil
+	}
+	return server
+}
+
+// webTransportURL returns the URL clients open WebTransport sessions at, on the host
+// the client reached the API on, or "" when WebTransport is disabled
+func (s *Server) webTransportURL(c *gin.Context) string {
+	if s.wtServer == nil {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(s.wtServer.H3.Addr)
+	if err != nil {
+		return ""
+	}
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return "https://" + net.JoinHostPort(host, port) + "/wt"
+}
+
+// webTransportHandler opens a WebTransport signaling session. The session speaks the
+// WebSocket protocol, so clients on lossy networks can switch transports freely.
+func (s *Server) webTransportHandler(c *gin.Context) {
+	if s.wtServer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": tr(c, "WebTransport is not configured")})
+		return
+	}
+
+	websocket.ServeWebTransport(s.hub, s.wtServer, c.Writer, c.Request, c.MustGet("user_id").(string))
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// migrations holds the schema changes, applied in order of their numeric prefix. Each
+// file has a "-- +goose Up" section and a "-- +goose Down" section undoing it.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migration is a schema change and whether it has been applied
+type Migration struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+}
+
+// migrator returns a goose provider applying the embedded migrations to the database
+func (d *DB) migrator() (*goose.Provider, error) {
+	fsys, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectSQLite3, d.db, fsys)
+}
+
+// MigrateUp applies the pending migrations and returns those applied
+func (d *DB) MigrateUp(ctx context.Context) ([]Migration, error) {
+	provider, err := d.migrator()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := provider.Up(ctx)
+	if err != nil {
+		return appliedMigrations(results, true), fmt.Errorf("failed to apply migrations: %v", err)
+	}
+	return appliedMigrations(results, true), nil
+}
+
+// MigrateDown undoes the given number of most recently applied migrations and returns
+// those undone
+func (d *DB) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	provider, err := d.migrator()
+	if err != nil {
+		return nil, err
+	}
+
+	var undone []Migration
+	for i := 0; i < steps; i++ {
+		result, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			// Every migration is undone
+			break
+		}
+		if err != nil {
+			return undone, fmt.Errorf("failed to undo migration: %v", err)
+		}
+		undone = append(undone, appliedMigrations([]*goose.MigrationResult{result}, false)...)
+	}
+	return undone, nil
+}
+
+// Migrations lists the known migrations in order and whether each is applied
+func (d *DB) Migrations(ctx context.Context) ([]Migration, error) {
+	provider, err := d.migrator()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration status: %v", err)
+	}
+
+	list := make([]Migration, 0, len(statuses))
+	for _, status := range statuses {
+		list = append(list, Migration{
+			Version:   status.Source.Version,
+			Name:      migrationName(status.Source.Path),
+			Applied:   status.State == goose.StateApplied,
+			AppliedAt: status.AppliedAt,
+		})
+	}
+	return list, nil
+}
+
+// SchemaVersion returns the version of the last applied migration and of the latest
+// known one; they differ while migrations are pending
+func (d *DB) SchemaVersion(ctx context.Context) (current, latest int64, err error) {
+	provider, err := d.migrator()
+	if err != nil {
+		return 0, 0, err
+	}
+	return provider.GetVersions(ctx)
+}
+
+// appliedMigrations describes the migrations that ran successfully
+func appliedMigrations(results []*goose.MigrationResult, applied bool) []Migration {
+	list := make([]Migration, 0, len(results))
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		list = append(list, Migration{
+			Version: result.Source.Version,
+			Name:    migrationName(result.Source.Path),
+			Applied: applied,
+		})
+	}
+	return list
+}
+
+// migrationName is a migration's file name without its version prefix and extension
+func migrationName(file string) string {
+	name := strings.TrimSuffix(path.Base(file), ".sql")
+	if _, rest, found := strings.Cut(name, "_"); found {
+		return rest
+	}
+	return name
+}
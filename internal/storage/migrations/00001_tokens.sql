@@ -0,0 +1,18 @@
+-- Revoked tokens and token sessions, kept until the tokens expire. Databases opened
+-- before migrations were introduced already have the tables.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	token_id   TEXT PRIMARY KEY,
+	expires_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS token_sessions (
+	token_id   TEXT PRIMARY KEY,
+	last_used  INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE token_sessions;
+DROP TABLE revoked_tokens;
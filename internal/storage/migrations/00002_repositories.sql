@@ -0,0 +1,43 @@
+-- Rooms, accounts, chat messages and recording metadata. Entries are kept as JSON
+-- documents, with the columns they are looked up by alongside.
+
+-- +goose Up
+CREATE TABLE rooms (
+	id         TEXT PRIMARY KEY,
+	tenant_id  TEXT NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+
+CREATE TABLE users (
+	id        TEXT PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT '',
+	username  TEXT NOT NULL,
+	email     TEXT NOT NULL,
+	data      TEXT NOT NULL
+);
+
+CREATE TABLE chat_messages (
+	id         TEXT PRIMARY KEY,
+	room_id    TEXT NOT NULL,
+	sent_at    INTEGER NOT NULL,
+	deleted_at INTEGER,
+	data       TEXT NOT NULL
+);
+
+CREATE INDEX chat_messages_room ON chat_messages (room_id, sent_at);
+
+CREATE TABLE recordings (
+	id         TEXT PRIMARY KEY,
+	room_id    TEXT NOT NULL,
+	tenant_id  TEXT NOT NULL DEFAULT '',
+	started_at INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE recordings;
+DROP INDEX chat_messages_room;
+DROP TABLE chat_messages;
+DROP TABLE users;
+DROP TABLE rooms;
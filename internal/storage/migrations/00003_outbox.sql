@@ -0,0 +1,21 @@
+-- Events waiting to be delivered to webhook endpoints and the message broker, one row
+-- per event and destination. Rows are written in the transaction of the state change
+-- they announce and removed once delivered.
+
+-- +goose Up
+CREATE TABLE outbox (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	destination     TEXT NOT NULL,
+	event_type      TEXT NOT NULL,
+	body            BLOB NOT NULL,
+	created_at      INTEGER NOT NULL,
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at INTEGER NOT NULL,
+	last_error      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX outbox_destination ON outbox (destination, id);
+
+-- +goose Down
+DROP INDEX outbox_destination;
+DROP TABLE outbox;
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/repository"
+)
+
+// OutboxRepository keeps outbound events in the outbox table
+type OutboxRepository struct {
+	db execer
+}
+
+// Enqueue adds entries, due for delivery at once
+func (r *OutboxRepository) Enqueue(ctx context.Context, entries ...repository.OutboxEntry) error {
+	now := time.Now().UnixMilli()
+	for _, entry := range entries {
+		_, err := r.db.ExecContext(ctx,
+			`INSERT INTO outbox (destination, event_type, body, created_at, next_attempt_at) VALUES (?, ?, ?, ?, ?)`,
+			entry.Destination, entry.EventType, entry.Body, now, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to queue event: %v", err)
+		}
+	}
+	return nil
+}
+
+// Pending returns up to limit entries queued for a destination, oldest first
+func (r *OutboxRepository) Pending(ctx context.Context, destination string, limit int) ([]repository.OutboxEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, destination, event_type, body, created_at, attempts, next_attempt_at, last_error
+		FROM outbox WHERE destination = ? ORDER BY id LIMIT ?`,
+		destination, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []repository.OutboxEntry
+	for rows.Next() {
+		var entry repository.OutboxEntry
+		var createdAt, nextAttempt int64
+		err := rows.Scan(&entry.ID, &entry.Destination, &entry.EventType, &entry.Body,
+			&createdAt, &entry.Attempts, &nextAttempt, &entry.LastError)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read outbox entry: %v", err)
+		}
+		entry.CreatedAt = time.UnixMilli(createdAt)
+		entry.NextAttempt = time.UnixMilli(nextAttempt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Retry records a failed attempt and when to attempt delivery again
+func (r *OutboxRepository) Retry(ctx context.Context, id int64, next time.Time, reason string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbox SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		next.UnixMilli(), reason, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox entry: %v", err)
+	}
+	return nil
+}
+
+// Remove removes a delivered or abandoned entry
+func (r *OutboxRepository) Remove(ctx context.Context, id int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to remove outbox entry: %v", err)
+	}
+	return nil
+}
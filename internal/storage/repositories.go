@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/auth"
+	"github.com/zubans/video-call-server/internal/chat"
+	"github.com/zubans/video-call-server/internal/recording"
+	"github.com/zubans/video-call-server/internal/repository"
+)
+
+// execer runs statements on the database or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Repositories returns the repositories kept in the database
+func (d *DB) Repositories() repository.Repositories {
+	repos := repositoriesOn(d.db)
+	repos.Transactor = d
+	return repos
+}
+
+// Atomic runs fn with repositories writing in one transaction, committed if fn returns
+// nil. Only the repositories passed to fn may be used until it returns: the database
+// has a single connection, which the transaction holds.
+func (d *DB) Atomic(ctx context.Context, fn func(repository.Repositories) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	if err := fn(repositoriesOn(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// repositoriesOn returns repositories running their statements on db
+func repositoriesOn(db execer) repository.Repositories {
+	return repository.Repositories{
+		Rooms:      &RoomRepository{db: db},
+		Users:      &UserRepository{db: db},
+		Chat:       &ChatRepository{db: db},
+		Recordings: &RecordingRepository{db: db},
+		Outbox:     &OutboxRepository{db: db},
+	}
+}
+
+// RoomRepository keeps rooms in the rooms table
+type RoomRepository struct {
+	db execer
+}
+
+// SaveRoom adds or replaces a room
+func (r *RoomRepository) SaveRoom(ctx context.Context, room repository.Room) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO rooms (id, tenant_id, created_at, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET tenant_id = excluded.tenant_id, data = excluded.data`,
+		room.ID, room.TenantID, room.CreatedAt.UnixMilli(), data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save room: %v", err)
+	}
+	return nil
+}
+
+// GetRoom returns a room by ID
+func (r *RoomRepository) GetRoom(ctx context.Context, id string) (repository.Room, error) {
+	var room repository.Room
+	err := getDocument(ctx, r.db, `SELECT data FROM rooms WHERE id = ?`, id, &room)
+	return room, err
+}
+
+// ListRooms returns every room, oldest first
+func (r *RoomRepository) ListRooms(ctx context.Context) ([]repository.Room, error) {
+	var list []repository.Room
+	err := listDocuments(ctx, r.db, `SELECT data FROM rooms ORDER BY created_at`, nil, func() interface{} {
+		list = append(list, repository.Room{})
+		return &list[len(list)-1]
+	})
+	return list, err
+}
+
+// DeleteRoom removes a room
+func (r *RoomRepository) DeleteRoom(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM rooms WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete room: %v", err)
+	}
+	return nil
+}
+
+// UserRepository keeps accounts in the users table
+type UserRepository struct {
+	db execer
+}
+
+// SaveUser adds or replaces an account
+func (r *UserRepository) SaveUser(ctx context.Context, user auth.UserBackup) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO users (id, tenant_id, username, email, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET tenant_id = excluded.tenant_id, username = excluded.username,
+			email = excluded.email, data = excluded.data`,
+		user.ID, user.TenantID, user.Username, user.Email, data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save user: %v", err)
+	}
+	return nil
+}
+
+// GetUser returns an account by ID
+func (r *UserRepository) GetUser(ctx context.Context, id string) (auth.UserBackup, error) {
+	var user auth.UserBackup
+	err := getDocument(ctx, r.db, `SELECT data FROM users WHERE id = ?`, id, &user)
+	return user, err
+}
+
+// ListUsers returns every account, by ID
+func (r *UserRepository) ListUsers(ctx context.Context) ([]auth.UserBackup, error) {
+	var list []auth.UserBackup
+	err := listDocuments(ctx, r.db, `SELECT data FROM users ORDER BY id`, nil, func() interface{} {
+		list = append(list, auth.UserBackup{})
+		return &list[len(list)-1]
+	})
+	return list, err
+}
+
+// DeleteUser removes an account
+func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete user: %v", err)
+	}
+	return nil
+}
+
+// ChatRepository keeps chat messages in the chat_messages table
+type ChatRepository struct {
+	db execer
+}
+
+// SaveMessage adds or replaces a message
+func (r *ChatRepository) SaveMessage(ctx context.Context, message chat.Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	var deletedAt sql.NullInt64
+	if message.DeletedAt != nil {
+		deletedAt = sql.NullInt64{Int64: message.DeletedAt.UnixMilli(), Valid: true}
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO chat_messages (id, room_id, sent_at, deleted_at, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET deleted_at = excluded.deleted_at, data = excluded.data`,
+		message.ID, message.RoomID, message.Timestamp.UnixMilli(), deletedAt, data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save chat message: %v", err)
+	}
+	return nil
+}
+
+// ListMessages returns the most recent messages of a room, oldest first
+func (r *ChatRepository) ListMessages(ctx context.Context, roomID string, limit int) ([]chat.Message, error) {
+	// SQLite treats a negative limit as none
+	if limit <= 0 {
+		limit = -1
+	}
+
+	var list []chat.Message
+	err := listDocuments(ctx, r.db,
+		`SELECT data FROM (
+			SELECT data, sent_at, rowid FROM chat_messages WHERE room_id = ? ORDER BY sent_at DESC, rowid DESC LIMIT ?
+		) ORDER BY sent_at, rowid`,
+		[]interface{}{roomID, limit},
+		func() interface{} {
+			list = append(list, chat.Message{})
+			return &list[len(list)-1]
+		},
+	)
+	return list, err
+}
+
+// DeleteRoomMessages removes every message of a room
+func (r *ChatRepository) DeleteRoomMessages(ctx context.Context, roomID string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM chat_messages WHERE room_id = ?`, roomID); err != nil {
+		return fmt.Errorf("failed to delete chat messages: %v", err)
+	}
+	return nil
+}
+
+// PurgeDeletedMessages removes messages soft-deleted before a time, except those under
+// legal hold. The author of a message is only kept in its data.
+func (r *ChatRepository) PurgeDeletedMessages(ctx context.Context, before time.Time, held chat.Held) error {
+	query := `DELETE FROM chat_messages WHERE deleted_at < ?`
+	args := []interface{}{before.UnixMilli()}
+	if len(held.Rooms) > 0 {
+		query += ` AND room_id NOT IN (` + placeholders(len(held.Rooms)) + `)`
+		for roomID := range held.Rooms {
+			args = append(args, roomID)
+		}
+	}
+	if len(held.Users) > 0 {
+		query += ` AND json_extract(data, '$.user_id') NOT IN (` + placeholders(len(held.Users)) + `)`
+		for userID := range held.Users {
+			args = append(args, userID)
+		}
+	}
+	_, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to purge chat messages: %v", err)
+	}
+	return nil
+}
+
+// placeholders returns n comma-separated query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// RecordingRepository keeps recording metadata in the recordings table
+type RecordingRepository struct {
+	db execer
+}
+
+// SaveRecording adds or replaces a recording
+func (r *RecordingRepository) SaveRecording(ctx context.Context, rec recording.Recording) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO recordings (id, room_id, tenant_id, started_at, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		rec.ID, rec.RoomID, rec.TenantID, rec.StartedAt.UnixMilli(), data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save recording: %v", err)
+	}
+	return nil
+}
+
+// GetRecording returns a recording by ID
+func (r *RecordingRepository) GetRecording(ctx context.Context, id string) (recording.Recording, error) {
+	var rec recording.Recording
+	err := getDocument(ctx, r.db, `SELECT data FROM recordings WHERE id = ?`, id, &rec)
+	return rec, err
+}
+
+// ListRecordings returns every recording, oldest first
+func (r *RecordingRepository) ListRecordings(ctx context.Context) ([]recording.Recording, error) {
+	var list []recording.Recording
+	err := listDocuments(ctx, r.db, `SELECT data FROM recordings ORDER BY started_at`, nil, func() interface{} {
+		list = append(list, recording.Recording{})
+		return &list[len(list)-1]
+	})
+	return list, err
+}
+
+// DeleteRecording removes a recording
+func (r *RecordingRepository) DeleteRecording(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM recordings WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete recording: %v", err)
+	}
+	return nil
+}
+
+// getDocument decodes the JSON document selected by a query for one ID
+func getDocument(ctx context.Context, db execer, query, id string, target interface{}) error {
+	var data []byte
+	err := db.QueryRowContext(ctx, query, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", id, err)
+	}
+	return json.Unmarshal(data, target)
+}
+
+// listDocuments decodes each JSON document selected by a query into the value returned
+// by next
+func listDocuments(ctx context.Context, db execer, query string, args []interface{}, next func() interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("failed to read row: %v", err)
+		}
+		if err := json.Unmarshal(data, next()); err != nil {
+			return fmt.Errorf("failed to decode row: %v", err)
+		}
+	}
+	return rows.Err()
+}
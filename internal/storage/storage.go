@@ -0,0 +1,53 @@
+// Package storage keeps server state in a SQL database. SQLite lets single-node installs
+// persist state without running a separate database server.
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// busyTimeout is how long a statement waits for another connection's write lock
+const busyTimeout = 5 * time.Second
+
+// DB is an open database; it implements the token stores of the auth package and
+// provides the repositories of the server's state
+type DB struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the SQLite database at path, creating the file if needed. Its tables
+// are created by MigrateUp.
+func OpenSQLite(path string) (*DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %v", err)
+		}
+	}
+
+	// WAL lets readers proceed while a write is in progress
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "foreign_keys(ON)")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// SQLite allows one writer at a time; a single connection avoids lock errors
+	db.SetMaxOpenConns(1)
+
+	return &DB{db: db}, nil
+}
+
+// Close closes the database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
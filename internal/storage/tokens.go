@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Revoke records a revoked token ID until the token expires, dropping entries whose
+// tokens have already expired
+func (d *DB) Revoke(tokenID string, expiresAt time.Time) error {
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	if _, err := d.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, now.UnixMilli()); err != nil {
+		return fmt.Errorf("failed to revoke token: %v", err)
+	}
+	_, err := d.db.Exec(
+		`INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)
+		ON CONFLICT (token_id) DO UPDATE SET expires_at = excluded.expires_at`,
+		tokenID, expiresAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %v", err)
+	}
+	return nil
+}
+
+// TryRevoke records a revoked token ID unless it is already revoked and not expired,
+// in a single statement, and reports whether this call revoked it
+func (d *DB) TryRevoke(tokenID string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return false, nil
+	}
+
+	result, err := d.db.Exec(
+		`INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)
+		ON CONFLICT (token_id) DO UPDATE SET expires_at = excluded.expires_at
+		WHERE revoked_tokens.expires_at <= ?`,
+		tokenID, expiresAt.UnixMilli(), now.UnixMilli(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke token: %v", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke token: %v", err)
+	}
+	return rows == 1, nil
+}
+
+// IsRevoked reports whether a token ID was revoked and has not expired yet
+func (d *DB) IsRevoked(tokenID string) (bool, error) {
+	var expiresAt int64
+	err := d.db.QueryRow(`SELECT expires_at FROM revoked_tokens WHERE token_id = ?`, tokenID).Scan(&expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %v", err)
+	}
+	return time.Now().Before(time.UnixMilli(expiresAt)), nil
+}
+
+// Touch records a use of a token and reports whether it was used within idle before;
+// idle tokens stay expired and records of expired tokens are dropped
+func (d *DB) Touch(tokenID string, idle time.Duration, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return false, nil
+	}
+
+	var lastUsed int64
+	err := d.db.QueryRow(`SELECT last_used FROM token_sessions WHERE token_id = ?`, tokenID).Scan(&lastUsed)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := d.db.Exec(`DELETE FROM token_sessions WHERE expires_at <= ?`, now.UnixMilli()); err != nil {
+			return false, fmt.Errorf("failed to record session: %v", err)
+		}
+	case err != nil:
+		return false, fmt.Errorf("failed to read session: %v", err)
+	case now.Sub(time.UnixMilli(lastUsed)) > idle:
+		return false, nil
+	}
+
+	_, err = d.db.Exec(
+		`INSERT INTO token_sessions (token_id, last_used, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (token_id) DO UPDATE SET last_used = excluded.last_used`,
+		tokenID, now.UnixMilli(), expiresAt.UnixMilli(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record session: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,116 @@
+package templates
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zubans/video-call-server/internal/models"
+)
+
+// ErrTemplateNotFound is returned when a template does not exist or belongs to another user
+var ErrTemplateNotFound = errors.New("template not found")
+
+// Template is a named set of room settings rooms can be created from
+type Template struct {
+	ID        string              `json:"id"`
+	OwnerID   string              `json:"owner_id"`
+	Name      string              `json:"name"`
+	Settings  models.RoomSettings `json:"settings"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// Manager stores room templates in memory; templates are private to their owner
+type Manager struct {
+	templates map[string]*Template
+	mu        sync.RWMutex
+}
+
+// NewManager creates a new Manager
+func NewManager() *Manager {
+	return &Manager{
+		templates: make(map[string]*Template),
+	}
+}
+
+// Create saves a new template for an owner
+func (m *Manager) Create(ownerID, name string, settings models.RoomSettings) Template {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	template := &Template{
+		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
+		Name:      name,
+		Settings:  settings,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.templates[template.ID] = template
+
+	return *template
+}
+
+// Get returns one of an owner's templates
+func (m *Manager) Get(ownerID, id string) (Template, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	template, exists := m.templates[id]
+	if !exists || template.OwnerID != ownerID {
+		return Template{}, ErrTemplateNotFound
+	}
+	return *template, nil
+}
+
+// Update replaces the name and settings of one of an owner's templates
+func (m *Manager) Update(ownerID, id, name string, settings models.RoomSettings) (Template, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	template, exists := m.templates[id]
+	if !exists || template.OwnerID != ownerID {
+		return Template{}, ErrTemplateNotFound
+	}
+	template.Name = name
+	template.Settings = settings
+	template.UpdatedAt = time.Now()
+
+	return *template, nil
+}
+
+// Delete removes one of an owner's templates
+func (m *Manager) Delete(ownerID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	template, exists := m.templates[id]
+	if !exists || template.OwnerID != ownerID {
+		return ErrTemplateNotFound
+	}
+	delete(m.templates, id)
+
+	return nil
+}
+
+// List returns an owner's templates by name
+func (m *Manager) List(ownerID string) []Template {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	templates := []Template{}
+	for _, template := range m.templates {
+		if template.OwnerID == ownerID {
+			templates = append(templates, *template)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates
+}
@@ -0,0 +1,131 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Limits on backend responses, in bytes
+const (
+	maxResponseSize = 1 << 20
+	maxErrorBody    = 512 // of the body quoted in errors
+)
+
+func init() {
+	Register("libretranslate", newLibreTranslate)
+	Register("webhook", newWebhook)
+}
+
+// libreTranslate translates with a LibreTranslate server, which can be self-hosted
+type libreTranslate struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// newLibreTranslate creates the libretranslate backend; URL is the server's base URL
+func newLibreTranslate(config Config) (Translator, error) {
+	if config.URL == "" {
+		return nil, errors.New("a URL is required for the libretranslate backend")
+	}
+	return &libreTranslate{url: strings.TrimSuffix(config.URL, "/"), apiKey: config.APIKey, client: &http.Client{}}, nil
+}
+
+// Translate calls /translate; LibreTranslate knows languages by their base tag
+func (b *libreTranslate) Translate(ctx context.Context, text, source, target string) (string, error) {
+	request := map[string]string{
+		"q":      text,
+		"source": base(source),
+		"target": base(target),
+		"format": "text",
+	}
+	if b.apiKey != "" {
+		request["api_key"] = b.apiKey
+	}
+	var response struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := postJSON(ctx, b.client, b.url+"/translate", "", request, &response); err != nil {
+		return "", err
+	}
+	return response.TranslatedText, nil
+}
+
+// webhook translates with an external service: {"text": "...", "source": "en",
+// "target": "ru"} is posted and {"text": "..."} is expected back
+type webhook struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// newWebhook creates the webhook backend
+func newWebhook(config Config) (Translator, error) {
+	if config.URL == "" {
+		return nil, errors.New("a URL is required for the webhook backend")
+	}
+	return &webhook{url: config.URL, apiKey: config.APIKey, client: &http.Client{}}, nil
+}
+
+// Translate posts the text to the service
+func (b *webhook) Translate(ctx context.Context, text, source, target string) (string, error) {
+	var response struct {
+		Text string `json:"text"`
+	}
+	err := postJSON(ctx, b.client, b.url, b.apiKey, map[string]string{
+		"text":   text,
+		"source": source,
+		"target": target,
+	}, &response)
+	if err != nil {
+		return "", err
+	}
+	return response.Text, nil
+}
+
+// base returns the primary language of a tag, e.g. "pt" for "pt-BR"
+func base(tag string) string {
+	language, _, _ := strings.Cut(tag, "-")
+	return language
+}
+
+// postJSON posts a JSON request with an optional bearer token and decodes the response
+func postJSON(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(respBody) > maxErrorBody {
+			respBody = respBody[:maxErrorBody]
+		}
+		return fmt.Errorf("backend responded with %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
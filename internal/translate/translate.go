@@ -0,0 +1,49 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownBackend is returned for backend names nothing is registered under
+var ErrUnknownBackend = errors.New("unknown translation backend")
+
+// Translator translates text between languages given as BCP 47 tags
+type Translator interface {
+	Translate(ctx context.Context, text, source, target string) (string, error)
+}
+
+// Config configures a backend; fields a backend does not use are ignored
+type Config struct {
+	URL    string
+	APIKey string
+}
+
+// backends maps backend names to their constructors
+var (
+	backends   = map[string]func(Config) (Translator, error){}
+	backendsMu sync.RWMutex
+)
+
+// Register makes a backend available under a name; built-in backends are
+// "libretranslate" and "webhook"
+func Register(name string, factory func(Config) (Translator, error)) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	backends[name] = factory
+}
+
+// New creates the translator of a registered backend
+func New(backend string, config Config) (Translator, error) {
+	backendsMu.RLock()
+	factory, exists := backends[backend]
+	backendsMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
+	}
+	return factory(config)
+}
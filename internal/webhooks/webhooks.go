@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/events"
+)
+
+// deliveryTimeout bounds each delivery attempt
+const deliveryTimeout = 10 * time.Second
+
+// Headers set on every delivery
+const (
+	EventHeader     = "X-Webhook-Event"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Dispatcher delivers server events to HTTP endpoints as JSON POST requests.
+// With a secret, the body is signed with HMAC-SHA256 as "sha256=<hex>".
+// Deliveries are queued in the server's outbox, which retries failed ones.
+type Dispatcher struct {
+	urls   []string
+	secret []byte
+	events map[string]bool // nil delivers every event
+	client *http.Client
+}
+
+// NewDispatcher creates a Dispatcher; an empty eventTypes list delivers every event
+func NewDispatcher(urls []string, secret string, eventTypes []string) *Dispatcher {
+	d := &Dispatcher{
+		urls:   urls,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: deliveryTimeout},
+	}
+	if len(eventTypes) > 0 {
+		d.events = make(map[string]bool, len(eventTypes))
+		for _, eventType := range eventTypes {
+			d.events[eventType] = true
+		}
+	}
+	return d
+}
+
+// URLs returns the endpoints events are delivered to
+func (d *Dispatcher) URLs() []string {
+	return d.urls
+}
+
+// Accepts reports whether events of a type are delivered
+func (d *Dispatcher) Accepts(eventType string) bool {
+	return d.events == nil || d.events[eventType]
+}
+
+// Encode returns the body delivered for an event
+func (d *Dispatcher) Encode(event events.Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// Deliver makes a single attempt to post an encoded event to one endpoint; retries are
+// left to the caller
+func (d *Dispatcher) Deliver(ctx context.Context, url, eventType string, body []byte) error {
+	return d.post(ctx, url, eventType, body)
+}
+
+// post makes a single delivery attempt
+func (d *Dispatcher) post(ctx context.Context, url, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if len(d.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(d.secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the signature header value of a webhook body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/queue"
+)
+
+const (
+	// Time to wait for an ack before retransmitting
+	ackRetryInterval = 2 * time.Second
+
+	// Deliveries (including the first) before giving up on an unacknowledged message
+	ackMaxAttempts = 5
+
+	// Maximum unacknowledged messages tracked per client; the oldest is dropped beyond this
+	maxPendingAcks = 128
+)
+
+// reliableTypes lists message types delivered with sequence numbers and retransmitted until
+// acknowledged: room signaling, and server control messages addressed to one participant
+var reliableTypes = map[string]bool{
+	"offer":      true,
+	"answer":     true,
+	"end-call":   true,
+	"signal":     true,
+	"hold":       true,
+	"transfer":   true,
+	"queue-call": true,
+}
+
+// AckPayload is the payload of "ack" messages sent by clients
+type AckPayload struct {
+	Seq uint64 `json:"seq"`
+}
+
+// Validate checks the sequence number
+func (p *AckPayload) Validate() error {
+	if p.Seq == 0 {
+		return errors.New("seq is required")
+	}
+	return nil
+}
+
+// pendingMessage is a reliable message awaiting acknowledgement
+type pendingMessage struct {
+	data     []byte
+	attempts int
+	sentAt   time.Time
+}
+
+// withSeq stamps a canonical JSON envelope with a sequence number
+func withSeq(message []byte, seq uint64) ([]byte, error) {
+	var env Envelope
+	if err := json.Unmarshal(message, &env); err != nil {
+		return nil, err
+	}
+	env.Seq = seq
+	return json.Marshal(env)
+}
+
+// trackPending remembers a reliable message until the client acknowledges it
+func (c *Client) trackPending(seq uint64, data []byte) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	if len(c.pending) >= maxPendingAcks {
+		// Sequence numbers grow monotonically, so the smallest is the oldest
+		var oldest uint64
+		for s := range c.pending {
+			if oldest == 0 || s < oldest {
+				oldest = s
+			}
+		}
+		delete(c.pending, oldest)
+	}
+
+	c.pending[seq] = &pendingMessage{data: data, attempts: 1, sentAt: time.Now()}
+}
+
+// ack marks a reliable message as delivered
+func (c *Client) ack(seq uint64) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	delete(c.pending, seq)
+}
+
+// retransmitDue resends unacknowledged messages whose retry interval elapsed. It
+// reports whether a message went unacknowledged through every attempt, meaning the
+// client stopped responding and should be dropped.
+func (c *Client) retransmitDue(now time.Time) bool {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	exhausted := false
+	for seq, msg := range c.pending {
+		if now.Sub(msg.sentAt) < ackRetryInterval {
+			continue
+		}
+		if msg.attempts >= ackMaxAttempts {
+			logger.Warnf("Giving up on message %d for client %s after %d attempts", seq, c.ID, msg.attempts)
+			delete(c.pending, seq)
+			exhausted = true
+			continue
+		}
+
+		if c.push(msg.data, "retransmit", queue.DropNewest).Dropped() {
+			// Try again on the next tick
+			continue
+		}
+		msg.attempts++
+		msg.sentAt = now
+	}
+	return exhausted
+}
+
+// retransmit resends due reliable messages for every registered client, disconnecting
+// clients that never acknowledged a message; they can reconnect and resume the room
+func (h *Hub) retransmit(now time.Time) {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	for _, client := range clients {
+		if client.retransmitDue(now) {
+			logger.Warnf("Disconnecting client %s: reliable messages were not acknowledged", client.ID)
+			h.leaveRoom(client)
+			client.closeSend()
+		}
+	}
+}
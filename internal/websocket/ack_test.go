@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newAckTestClient registers a client in a room of a new hub, without a connection
+func newAckTestClient(t *testing.T) (*Hub, *Client) {
+	t.Helper()
+	hub := NewHub()
+	client := NewClient(hub, nil, ProtocolVersion, jsonCodec{})
+	hub.clients[client] = true
+	hub.JoinRoom(client, "room")
+	return hub, client
+}
+
+// sendReliable broadcasts an "end-call" to the client's room and returns the delivered
+// message's sequence number
+func sendReliable(t *testing.T, hub *Hub, client *Client) uint64 {
+	t.Helper()
+	message, err := EncodeEnvelope(ProtocolVersion, "end-call", RoomPayload{RoomID: "room", SenderID: "peer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.BroadcastToRoom("room", message, nil)
+
+	var env Envelope
+	if err := json.Unmarshal(receive(t, client), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Seq == 0 {
+		t.Fatal("reliable message was sent without a sequence number")
+	}
+	return env.Seq
+}
+
+// receive returns the next queued message of a client
+func receive(t *testing.T, client *Client) []byte {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		return data
+	default:
+		t.Fatal("no message was queued")
+		return nil
+	}
+}
+
+// sendAck delivers an "ack" from the client as if it came over the wire
+func sendAck(client *Client, seq uint64) {
+	client.handleMessage([]byte(fmt.Sprintf(`{"v": 1, "type": "ack", "payload": {"seq": %d}}`, seq)))
+}
+
+// TestRetransmitWithoutAck checks that a message whose ack never arrives is sent
+// again once the retry interval has passed, and not before
+func TestRetransmitWithoutAck(t *testing.T) {
+	hub, client := newAckTestClient(t)
+	seq := sendReliable(t, hub, client)
+
+	hub.retransmit(time.Now())
+	if len(client.send) != 0 {
+		t.Fatal("message was retransmitted before the retry interval")
+	}
+
+	hub.retransmit(time.Now().Add(ackRetryInterval))
+	var env Envelope
+	if err := json.Unmarshal(receive(t, client), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Seq != seq || env.Type != "end-call" {
+		t.Errorf("retransmitted %s #%d, want end-call #%d", env.Type, env.Seq, seq)
+	}
+
+	sendAck(client, seq)
+	hub.retransmit(time.Now().Add(2 * ackRetryInterval))
+	if len(client.send) != 0 {
+		t.Error("acknowledged message was retransmitted")
+	}
+}
+
+// TestDuplicateAckIgnored checks that acknowledging a message again, or acknowledging
+// an unknown one, leaves the other pending messages alone
+func TestDuplicateAckIgnored(t *testing.T) {
+	hub, client := newAckTestClient(t)
+	first := sendReliable(t, hub, client)
+	second := sendReliable(t, hub, client)
+
+	sendAck(client, first)
+	sendAck(client, first)
+	sendAck(client, second+100)
+	if len(client.send) != 0 {
+		t.Errorf("acks were answered with %s", receive(t, client))
+	}
+
+	client.pendingMu.Lock()
+	_, pending := client.pending[second]
+	unacked := len(client.pending)
+	client.pendingMu.Unlock()
+	if !pending || unacked != 1 {
+		t.Fatalf("%d messages pending after the duplicate ack, want only #%d", unacked, second)
+	}
+
+	hub.retransmit(time.Now().Add(ackRetryInterval))
+	var env Envelope
+	if err := json.Unmarshal(receive(t, client), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Seq != second {
+		t.Errorf("retransmitted #%d, want #%d", env.Seq, second)
+	}
+}
+
+// TestClientDroppedAfterMaxAttempts checks that a client which never acknowledges a
+// message is disconnected once every attempt has been used
+func TestClientDroppedAfterMaxAttempts(t *testing.T) {
+	hub, client := newAckTestClient(t)
+	sendReliable(t, hub, client)
+
+	now := time.Now()
+	for attempt := 1; attempt < ackMaxAttempts; attempt++ {
+		now = now.Add(ackRetryInterval)
+		hub.retransmit(now)
+		receive(t, client)
+	}
+	if client.Room() != "room" {
+		t.Fatal("client was dropped before using every attempt")
+	}
+
+	hub.retransmit(now.Add(ackRetryInterval))
+	if client.Room() != "" {
+		t.Error("client is still in the room")
+	}
+	if _, open := <-client.send; open {
+		t.Error("client's connection was not closed")
+	}
+}
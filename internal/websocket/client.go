@@ -2,12 +2,18 @@ package websocket
 
 import (
 	"bytes"
-	"log"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+
+	"github.com/zubans/video-call-server/internal/metrics"
+	"github.com/zubans/video-call-server/internal/queue"
 )
 
 const (
@@ -33,17 +39,14 @@ var (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow connections from any origin
-		return true
-	},
+	CheckOrigin:     checkOrigin,
 }
 
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
 	hub *Hub
 
-	// The websocket connection.
+	// The websocket connection, nil for WebTransport clients.
 	conn *websocket.Conn
 
 	// Buffered channel of outbound messages.
@@ -52,20 +55,125 @@ type Client struct {
 	// Client ID
 	ID string
 
-	// Room ID
+	// Room ID, set once the client sends "join"
 	RoomID string
 
 	// User ID
 	UserID string
+
+	// Negotiated protocol version
+	version int
+
+	// Negotiated wire encoding
+	codec Codec
+
+	// Messages dropped because the send queue was full
+	drops atomic.Int64
+
+	// Guards send against use after close
+	sendMu sync.Mutex
+	closed bool
+
+	// Signaling client ID announced in "join" (the client_id from /join-room)
+	senderID string
+
+	// Guards RoomID and senderID
+	roomMu sync.RWMutex
+
+	// Reliable messages awaiting acknowledgement, by sequence number
+	pending   map[uint64]*pendingMessage
+	pendingMu sync.Mutex
+
+	// Minimum frame size to compress when permessage-deflate was negotiated
+	compressionThreshold int
 }
 
 // NewClient creates a new Client instance
-func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+func NewClient(hub *Hub, conn *websocket.Conn, version int, codec Codec) *Client {
 	return &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, 256),
-		ID:   uuid.New().String(),
+		hub:     hub,
+		conn:    conn,
+		send:    make(chan []byte, 256),
+		ID:      uuid.New().String(),
+		version: version,
+		codec:   codec,
+		pending: make(map[uint64]*pendingMessage),
+	}
+}
+
+// Version returns the negotiated protocol version
+func (c *Client) Version() int {
+	return c.version
+}
+
+// Room returns the room the client has joined, if any
+func (c *Client) Room() string {
+	c.roomMu.RLock()
+	defer c.roomMu.RUnlock()
+
+	return c.RoomID
+}
+
+// setRoom records the room the client has joined
+func (c *Client) setRoom(roomID string) {
+	c.roomMu.Lock()
+	defer c.roomMu.Unlock()
+
+	c.RoomID = roomID
+}
+
+// SenderID returns the signaling client ID announced when joining
+func (c *Client) SenderID() string {
+	c.roomMu.RLock()
+	defer c.roomMu.RUnlock()
+
+	return c.senderID
+}
+
+// setSenderID records the signaling client ID
+func (c *Client) setSenderID(senderID string) {
+	c.roomMu.Lock()
+	defer c.roomMu.Unlock()
+
+	c.senderID = senderID
+}
+
+// push queues an encoded message of a type without blocking, applying the overflow policy
+func (c *Client) push(data []byte, msgType string, policy queue.Policy) queue.Result {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closed {
+		return queue.DroppedNewest
+	}
+	result := queue.Push(c.send, data, policy)
+	if !result.Dropped() {
+		metrics.AppMetrics.IncrementWebSocketMessagesSent(msgType)
+	}
+	return result
+}
+
+// closeSend closes the send channel once, making WritePump close the connection
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// sendError queues an "error" envelope for this client without blocking
+func (c *Client) sendError(code, message string) {
+	data, err := c.codec.Encode(encodeError(c.version, code, message))
+	if err != nil {
+		logger.Errorf("Failed to encode error for client %s: %v", c.ID, err)
+		return
+	}
+
+	if c.push(data, "error", queue.DropNewest).Dropped() {
+		logger.Warnf("Dropping error for client %s: send buffer full", c.ID)
 	}
 }
 
@@ -82,12 +190,83 @@ func (c *Client) ReadPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				logger.Warnf("Unexpected close of client %s: %v", c.ID, err)
+				metrics.AppMetrics.IncrementWebSocketErrors()
 			}
 			break
 		}
-		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
-		c.hub.broadcast <- message
+		c.handleMessage(message)
+	}
+}
+
+// handleMessage validates a message received from the client in its wire encoding
+// and relays it to the client's room
+func (c *Client) handleMessage(message []byte) {
+	// Convert the wire encoding to canonical JSON
+	message, err := c.codec.Decode(message)
+	if err != nil {
+		metrics.AppMetrics.IncrementWebSocketErrors()
+		c.sendError(ErrCodeInvalidMessage, err.Error())
+		return
+	}
+	message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
+
+	// Validate against the negotiated protocol before relaying
+	env, payload, err := DecodeEnvelope(message, c.version)
+	if err != nil {
+		metrics.AppMetrics.IncrementWebSocketErrors()
+		var perr *protocolError
+		if errors.As(err, &perr) {
+			c.sendError(perr.Code, perr.Message)
+		}
+		return
+	}
+
+	// Acknowledgements stop retransmission and are not relayed
+	if ack, ok := payload.(*AckPayload); ok {
+		c.ack(ack.Seq)
+		return
+	}
+
+	// Joining (or resuming) binds the connection to a signaling client ID owned by the user
+	roomID, senderID := payload.(roomScoped).Room(), payload.(roomScoped).Sender()
+	if env.Type == "join" || env.Type == "events-since" {
+		if !c.hub.authorizeSender(c.UserID, roomID, senderID) {
+			c.sendError(ErrCodeForbidden, "sender_id does not belong to the authenticated user")
+			return
+		}
+	}
+
+	// Reconnecting clients catch up on missed events instead of joining again
+	if resume, ok := payload.(*ResumePayload); ok {
+		c.resume(resume)
+		return
+	}
+
+	// "join" moves the connection into the room; everything else must target the joined room
+	if env.Type == "join" {
+		c.hub.JoinRoom(c, roomID)
+		c.setSenderID(senderID)
+	} else if c.Room() != roomID {
+		c.sendError(ErrCodeNotInRoom, "join the room before sending messages to it")
+		return
+	} else if c.SenderID() != senderID {
+		c.sendError(ErrCodeForbidden, "sender_id does not match the joined client")
+		return
+	}
+
+	// Flooding clients have their messages dropped instead of relayed
+	if env.Type != "join" && !c.hub.allowMessage(roomID, senderID, env.Type) {
+		c.sendError(ErrCodeRateLimited, "too many messages, slow down")
+		return
+	}
+
+	c.hub.observeMessage(roomID, senderID, env.Type, payload)
+	if !serverTypes[env.Type] {
+		c.hub.BroadcastToRoom(roomID, message, c)
+	}
+	if env.Type == "join" {
+		c.sendJoined(roomID)
 	}
 }
 
@@ -108,20 +287,30 @@ func (c *Client) WritePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			// Add queued chat messages to the current websocket message.
+			// Binary frames carry exactly one message each.
+			n := len(c.send)
+			if c.codec.FrameType() != websocket.TextMessage {
+				n = 0
+			}
+
+			// Compress only frames large enough to benefit (no-op unless negotiated)
+			c.conn.EnableWriteCompression(n > 0 || len(message) >= c.compressionThreshold)
+
+			w, err := c.conn.NextWriter(c.codec.FrameType())
 			if err != nil {
+				metrics.AppMetrics.IncrementWebSocketErrors()
 				return
 			}
 			w.Write(message)
 
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
 			for i := 0; i < n; i++ {
 				w.Write(newline)
 				w.Write(<-c.send)
 			}
 
 			if err := w.Close(); err != nil {
+				metrics.AppMetrics.IncrementWebSocketErrors()
 				return
 			}
 		case <-ticker.C:
@@ -133,14 +322,41 @@ func (c *Client) WritePump() {
 	}
 }
 
-// ServeWs handles websocket requests from the peer.
-func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// ServeWs handles websocket requests from the peer. userID is the authenticated
+// user the connection is bound to.
+func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, userID string) {
+	// Negotiate the protocol version and encoding before upgrading
+	hs, err := negotiate(r)
 	if err != nil {
-		log.Println(err)
+		rejectHandshake(w, err)
 		return
 	}
-	client := NewClient(hub, conn)
+
+	// Echo the subprotocol if the client negotiated through Sec-WebSocket-Protocol
+	var responseHeader http.Header
+	if hs.subprotocol != "" {
+		responseHeader = http.Header{"Sec-WebSocket-Protocol": {hs.subprotocol}}
+	}
+
+	// Offer permessage-deflate if enabled; gorilla only uses it when the client agrees
+	cfg := compressionSettings()
+	up := upgrader
+	up.EnableCompression = cfg.Enabled
+
+	conn, err := up.Upgrade(w, r, responseHeader)
+	if err != nil {
+		metrics.AppMetrics.IncrementWebSocketErrors()
+		logger.Warnf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	if cfg.Enabled {
+		if err := conn.SetCompressionLevel(cfg.Level); err != nil {
+			logger.Errorf("Failed to set compression level: %v", err)
+		}
+	}
+	client := NewClient(hub, conn, hs.version, hs.codec)
+	client.UserID = userID
+	client.compressionThreshold = cfg.Threshold
 	client.hub.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in
@@ -148,3 +364,14 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 	go client.WritePump()
 	go client.ReadPump()
 }
+
+// rejectHandshake answers a handshake whose version or encoding is not supported
+func rejectHandshake(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(ErrorPayload{
+		Code:              ErrCodeUnsupportedVersion,
+		Message:           err.Error(),
+		SupportedVersions: SupportedVersions,
+	})
+}
@@ -0,0 +1,178 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec converts canonical JSON hub messages to and from a client's wire encoding
+type Codec interface {
+	// Name is the encoding name used during negotiation
+	Name() string
+
+	// FrameType is the WebSocket frame type used on the wire
+	FrameType() int
+
+	// Encode converts a canonical JSON message to the wire encoding
+	Encode(message []byte) ([]byte, error)
+
+	// Decode converts a wire message to canonical JSON
+	Decode(data []byte) ([]byte, error)
+}
+
+// jsonCodec sends messages as JSON text frames
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                          { return "json" }
+func (jsonCodec) FrameType() int                        { return websocket.TextMessage }
+func (jsonCodec) Encode(message []byte) ([]byte, error) { return message, nil }
+func (jsonCodec) Decode(data []byte) ([]byte, error)    { return data, nil }
+
+// msgpackCodec sends messages as MessagePack binary frames
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string   { return "msgpack" }
+func (msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+// msgpackEnvelope is Envelope on the MessagePack wire. The header is typed, so the
+// sequence numbers stay integers, and the payload is carried as a MessagePack value.
+type msgpackEnvelope struct {
+	V        int         `msgpack:"v"`
+	Type     string      `msgpack:"type"`
+	Seq      uint64      `msgpack:"seq,omitempty"`
+	EventSeq uint64      `msgpack:"event_seq,omitempty"`
+	Payload  interface{} `msgpack:"payload"`
+}
+
+// Encode converts a JSON envelope to MessagePack, keeping integers in the payload
+// (timestamps, counts) integers rather than floats
+func (msgpackCodec) Encode(message []byte) ([]byte, error) {
+	var env Envelope
+	if err := json.Unmarshal(message, &env); err != nil {
+		return nil, err
+	}
+
+	var payload interface{}
+	if len(env.Payload) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(env.Payload))
+		decoder.UseNumber()
+		if err := decoder.Decode(&payload); err != nil {
+			return nil, err
+		}
+	}
+
+	return msgpack.Marshal(msgpackEnvelope{
+		V:        env.V,
+		Type:     env.Type,
+		Seq:      env.Seq,
+		EventSeq: env.EventSeq,
+		Payload:  fromJSONNumbers(payload),
+	})
+}
+
+// Decode converts a MessagePack envelope to JSON. MessagePack integers decode as
+// integers, so they reach the JSON unchanged.
+func (msgpackCodec) Decode(data []byte) ([]byte, error) {
+	var env msgpackEnvelope
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields(true)
+	if err := decoder.Decode(&env); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{
+		V:        env.V,
+		Type:     env.Type,
+		Seq:      env.Seq,
+		EventSeq: env.EventSeq,
+		Payload:  payload,
+	})
+}
+
+// fromJSONNumbers replaces the json.Number values of a decoded JSON value with int64,
+// uint64 or float64, whichever holds the number exactly
+func fromJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = fromJSONNumbers(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = fromJSONNumbers(value)
+		}
+		return v
+	}
+	return v
+}
+
+// codecs lists the supported encodings by name
+var codecs = map[string]Codec{
+	"json":    jsonCodec{},
+	"msgpack": msgpackCodec{},
+}
+
+// codecByName returns the codec for an encoding name; an empty name selects JSON
+func codecByName(name string) (Codec, error) {
+	if name == "" {
+		return jsonCodec{}, nil
+	}
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding: %q", name)
+	}
+	return codec, nil
+}
+
+// encodedMessage is a broadcast message with per-codec encodings computed once
+type encodedMessage struct {
+	msgType string
+	json    []byte
+	encoded map[string][]byte
+}
+
+// newEncodedMessage wraps a canonical JSON message
+func newEncodedMessage(message []byte) *encodedMessage {
+	var env struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(message, &env)
+
+	return &encodedMessage{
+		msgType: env.Type,
+		json:    message,
+		encoded: map[string][]byte{"json": message},
+	}
+}
+
+// forCodec returns the message in the given encoding, caching the result
+func (m *encodedMessage) forCodec(codec Codec) ([]byte, error) {
+	if data, ok := m.encoded[codec.Name()]; ok {
+		return data, nil
+	}
+	data, err := codec.Encode(m.json)
+	if err != nil {
+		return nil, err
+	}
+	m.encoded[codec.Name()] = data
+	return data, nil
+}
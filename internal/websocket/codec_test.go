@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// TestMsgpackRoundTrip checks that a message survives MessagePack and back unchanged,
+// with sequence numbers and timestamps kept as integers on the wire
+func TestMsgpackRoundTrip(t *testing.T) {
+	message, err := EncodeEnvelope(ProtocolVersion, "chat", map[string]interface{}{
+		"room_id":   "room",
+		"timestamp": int64(1760000000123),
+		"big":       uint64(1<<63 + 1),
+		"ratio":     0.5,
+		"tags":      []interface{}{"a", int64(9007199254740993)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message, err = withSeq(message, 42); err != nil {
+		t.Fatal(err)
+	}
+
+	codec := msgpackCodec{}
+	wire, err := codec.Encode(message)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := msgpack.Unmarshal(wire, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, isFloat := decoded["seq"].(float64); isFloat {
+		t.Errorf("seq was sent as a float: %v", decoded["seq"])
+	}
+	payload := decoded["payload"].(map[string]interface{})
+	if _, isFloat := payload["timestamp"].(float64); isFloat {
+		t.Errorf("timestamp was sent as a float: %v", payload["timestamp"])
+	}
+
+	back, err := codec.Decode(wire)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !jsonEqual(t, message, back) {
+		t.Errorf("round trip changed the message:\n sent %s\n  got %s", message, back)
+	}
+}
+
+// TestMsgpackDecodeRejectsUnknownFields checks that MessagePack envelopes are held to
+// the same shape as JSON ones
+func TestMsgpackDecodeRejectsUnknownFields(t *testing.T) {
+	wire, err := msgpack.Marshal(map[string]interface{}{"v": 1, "type": "chat", "extra": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (msgpackCodec{}).Decode(wire); err == nil {
+		t.Error("envelope with an unknown field was accepted")
+	}
+}
+
+// jsonEqual compares two JSON documents, keeping numbers exact
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb interface{}
+	for _, doc := range []struct {
+		data []byte
+		v    *interface{}
+	}{{a, &va}, {b, &vb}} {
+		decoder := json.NewDecoder(bytes.NewReader(doc.data))
+		decoder.UseNumber()
+		if err := decoder.Decode(doc.v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"compress/flate"
+	"fmt"
+	"sync"
+)
+
+// CompressionConfig configures permessage-deflate (RFC 7692)
+type CompressionConfig struct {
+	// Enabled offers permessage-deflate during the handshake
+	Enabled bool
+
+	// Level is the flate compression level, from -2 (Huffman only) to 9 (best compression)
+	Level int
+
+	// Threshold is the minimum message size in bytes worth compressing;
+	// smaller messages are sent uncompressed
+	Threshold int
+}
+
+// DefaultCompressionConfig favours CPU over ratio, compressing only large payloads such as SDPs
+var DefaultCompressionConfig = CompressionConfig{
+	Enabled:   true,
+	Level:     flate.BestSpeed,
+	Threshold: 512,
+}
+
+var (
+	compression   = DefaultCompressionConfig
+	compressionMu sync.RWMutex
+)
+
+// ConfigureCompression sets the compression settings for new connections
+func ConfigureCompression(cfg CompressionConfig) error {
+	if cfg.Level < flate.HuffmanOnly || cfg.Level > flate.BestCompression {
+		return fmt.Errorf("invalid compression level: %d", cfg.Level)
+	}
+	if cfg.Threshold < 0 {
+		return fmt.Errorf("invalid compression threshold: %d", cfg.Threshold)
+	}
+
+	compressionMu.Lock()
+	defer compressionMu.Unlock()
+
+	compression = cfg
+	return nil
+}
+
+// compressionSettings returns the current compression settings
+func compressionSettings() CompressionConfig {
+	compressionMu.RLock()
+	defer compressionMu.RUnlock()
+
+	return compression
+}
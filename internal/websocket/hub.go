@@ -1,66 +1,420 @@
 package websocket
 
 import (
-	"log"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/errreport"
+	"github.com/zubans/video-call-server/internal/logging"
+	"github.com/zubans/video-call-server/internal/metrics"
+	"github.com/zubans/video-call-server/internal/queue"
 )
 
-// Hub maintains the set of active clients and broadcasts messages to the clients.
+// logger writes the hub subsystem log
+var logger = logging.New(logging.Hub)
+
+// Hub maintains the set of active clients and routes messages to per-room shards.
+// Each room has its own registry and lock, so broadcasts in different rooms never
+// contend with each other or with connection registration.
 type Hub struct {
 	// Registered clients.
 	clients map[*Client]bool
 
-	// Inbound messages from the clients.
-	broadcast chan []byte
-
 	// Register requests from the clients.
 	register chan *Client
 
 	// Unregister requests from clients.
 	unregister chan *Client
 
+	// Room shards by room ID
+	rooms   map[string]*roomShard
+	roomsMu sync.RWMutex
+
+	// Dropped messages after which a client is disconnected as a slow consumer
+	slowConsumerThreshold atomic.Int64
+
+	// Last sequence number assigned to a reliable message
+	seq atomic.Uint64
+
+	// Recent events and resume tokens for reconnecting clients
+	history      map[string]*eventLog
+	resumeTokens map[string]resumeToken
+	historyMu    sync.Mutex
+
+	// Binds signaling client IDs to authenticated users
+	authorize SenderAuthorizer
+	authMu    sync.RWMutex
+
+	// Observes messages relayed to rooms (guarded by authMu)
+	observe MessageObserver
+
+	// Throttles messages of flooding clients (guarded by authMu)
+	filter MessageFilter
+
 	// Mutex for thread safety
 	mu sync.RWMutex
 }
 
+// roomShard holds the clients of a single room
+type roomShard struct {
+	clients map[*Client]bool
+	mu      sync.RWMutex
+}
+
 // NewHub creates a new Hub instance
 func NewHub() *Hub {
-	return &Hub{
-		broadcast:  make(chan []byte),
+	h := &Hub{
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		clients:    make(map[*Client]bool),
+		rooms:      make(map[string]*roomShard),
+
+		history:      make(map[string]*eventLog),
+		resumeTokens: make(map[string]resumeToken),
 	}
+	h.slowConsumerThreshold.Store(50)
+	return h
 }
 
-// Run starts the hub's main loop
+// Run starts the hub's main loop handling connection registration and retransmission
 func (h *Hub) Run() {
+	ticker := time.NewTicker(ackRetryInterval / 2)
+	defer ticker.Stop()
+
 	for {
 		select {
+		case now := <-ticker.C:
+			h.retransmit(now)
+			h.pruneHistory()
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
+			h.updateConnectionMetrics()
 			h.mu.Unlock()
-			log.Printf("Client registered: %s", client.ID)
+			logger.Debugf("Client registered: %s", client.ID)
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				log.Printf("Client unregistered: %s", client.ID)
-			}
+			_, ok := h.clients[client]
+			delete(h.clients, client)
+			h.updateConnectionMetrics()
 			h.mu.Unlock()
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+			if ok {
+				roomID := client.Room()
+				h.leaveRoom(client)
+				client.closeSend()
+				logger.Debugf("Client unregistered: %s", client.ID)
+
+				// Let the remaining participants know
+				if roomID != "" && client.SenderID() != "" {
+					h.Publish(roomID, "leave", RoomPayload{RoomID: roomID, SenderID: client.SenderID()})
 				}
 			}
-			h.mu.RUnlock()
 		}
 	}
 }
+
+// updateConnectionMetrics reports the number of connections and of distinct users
+// connected; the caller holds h.mu
+func (h *Hub) updateConnectionMetrics() {
+	users := make(map[string]bool)
+	for client := range h.clients {
+		if client.UserID != "" {
+			users[client.UserID] = true
+		}
+	}
+	metrics.AppMetrics.SetWebSocketConnections(float64(len(h.clients)))
+	metrics.AppMetrics.SetUsersOnline(float64(len(users)))
+}
+
+// DisconnectSender closes every WebSocket bound to a signaling client ID,
+// returning how many were closed
+func (h *Hub) DisconnectSender(senderID string) int {
+	h.mu.RLock()
+	var targets []*Client
+	for client := range h.clients {
+		if client.SenderID() == senderID {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range targets {
+		h.leaveRoom(client)
+		client.closeSend()
+	}
+	return len(targets)
+}
+
+// HasSender reports whether any WebSocket is bound to a signaling client ID
+func (h *Hub) HasSender(senderID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		if client.SenderID() == senderID {
+			return true
+		}
+	}
+	return false
+}
+
+// SendQueue describes the outbound queue of a WebSocket
+type SendQueue struct {
+	ConnectionID string `json:"connection_id"`
+	Version      int    `json:"version"`
+	Queued       int    `json:"queued"`
+	Capacity     int    `json:"capacity"`
+	Unacked      int    `json:"unacked"` // reliable messages awaiting acknowledgement
+	Dropped      int64  `json:"dropped"`
+}
+
+// SenderQueues describes the send queues of every WebSocket bound to a signaling client ID
+func (h *Hub) SenderQueues(senderID string) []SendQueue {
+	h.mu.RLock()
+	var targets []*Client
+	for client := range h.clients {
+		if client.SenderID() == senderID {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	queues := make([]SendQueue, 0, len(targets))
+	for _, client := range targets {
+		client.pendingMu.Lock()
+		unacked := len(client.pending)
+		client.pendingMu.Unlock()
+
+		queues = append(queues, SendQueue{
+			ConnectionID: client.ID,
+			Version:      client.version,
+			Queued:       len(client.send),
+			Capacity:     cap(client.send),
+			Unacked:      unacked,
+			Dropped:      client.drops.Load(),
+		})
+	}
+	return queues
+}
+
+// SendToSender queues a server-originated message for every WebSocket bound to a
+// signaling client ID, returning how many were reached
+func (h *Hub) SendToSender(senderID, msgType string, payload interface{}) int {
+	h.mu.RLock()
+	var targets []*Client
+	for client := range h.clients {
+		if client.SenderID() == senderID {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range targets {
+		client.sendEnvelope(msgType, payload)
+	}
+	return len(targets)
+}
+
+// SendToUser sends a message to every connection of a user and returns how many received it
+func (h *Hub) SendToUser(userID, msgType string, payload interface{}) int {
+	h.mu.RLock()
+	var targets []*Client
+	for client := range h.clients {
+		if client.UserID == userID {
+			targets = append(targets, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, client := range targets {
+		client.sendEnvelope(msgType, payload)
+	}
+	return len(targets)
+}
+
+// RoomSenders returns the signaling client IDs of the WebSockets in a room
+func (h *Hub) RoomSenders(roomID string) []string {
+	h.roomsMu.RLock()
+	shard, ok := h.rooms[roomID]
+	h.roomsMu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	senders := make([]string, 0, len(shard.clients))
+	for client := range shard.clients {
+		if senderID := client.SenderID(); senderID != "" && !slices.Contains(senders, senderID) {
+			senders = append(senders, senderID)
+		}
+	}
+	sort.Strings(senders)
+	return senders
+}
+
+// JoinRoom moves a client into a room shard, leaving its previous room
+func (h *Hub) JoinRoom(client *Client, roomID string) {
+	if client.Room() == roomID {
+		return
+	}
+	h.leaveRoom(client)
+
+	h.roomsMu.Lock()
+	shard, ok := h.rooms[roomID]
+	if !ok {
+		shard = &roomShard{clients: make(map[*Client]bool)}
+		h.rooms[roomID] = shard
+	}
+	shard.mu.Lock()
+	shard.clients[client] = true
+	shard.mu.Unlock()
+	h.roomsMu.Unlock()
+
+	client.setRoom(roomID)
+}
+
+// leaveRoom removes a client from its room shard, dropping the shard once empty
+func (h *Hub) leaveRoom(client *Client) {
+	roomID := client.Room()
+	if roomID == "" {
+		return
+	}
+
+	h.roomsMu.Lock()
+	if shard, ok := h.rooms[roomID]; ok {
+		shard.mu.Lock()
+		delete(shard.clients, client)
+		empty := len(shard.clients) == 0
+		shard.mu.Unlock()
+		if empty {
+			delete(h.rooms, roomID)
+		}
+	}
+	h.roomsMu.Unlock()
+
+	client.setRoom("")
+}
+
+// Publish sends a server-originated message to every client in a room
+func (h *Hub) Publish(roomID, msgType string, payload interface{}) {
+	message, err := EncodeEnvelope(ProtocolVersion, msgType, payload)
+	if err != nil {
+		logger.Errorf("Failed to encode %s message: %v", msgType, err)
+		reportHubError(err, roomID, msgType)
+		return
+	}
+	h.BroadcastToRoom(roomID, message, nil)
+}
+
+// BroadcastToRoom delivers a canonical JSON message to every client in a room except
+// the sender (nil for server-originated messages). Reliable message types are stamped
+// with a sequence number and retransmitted until each recipient acknowledges them.
+func (h *Hub) BroadcastToRoom(roomID string, message []byte, sender *Client) {
+	// Encode once per codec rather than once per client
+	encoded := newEncodedMessage(message)
+	policy := sendPolicy(encoded.msgType)
+
+	// Record room events for replay, even while nobody is connected, so that clients
+	// resuming a session catch up on them
+	if replayableTypes[encoded.msgType] {
+		stamped, err := h.roomLog(roomID).append(message)
+		if err != nil {
+			logger.Errorf("Failed to record room event: %v", err)
+			reportHubError(err, roomID, encoded.msgType)
+			return
+		}
+		message = stamped
+		encoded = newEncodedMessage(message)
+	}
+
+	h.roomsMu.RLock()
+	shard, ok := h.rooms[roomID]
+	h.roomsMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var seq uint64
+	if reliableTypes[encoded.msgType] {
+		seq = h.seq.Add(1)
+		stamped, err := withSeq(message, seq)
+		if err != nil {
+			logger.Errorf("Failed to stamp message with sequence number: %v", err)
+			reportHubError(err, roomID, encoded.msgType)
+			return
+		}
+		encoded = newEncodedMessage(stamped)
+	}
+	threshold := h.slowConsumerThreshold.Load()
+
+	var slow []*Client
+	shard.mu.RLock()
+	for client := range shard.clients {
+		if client == sender {
+			continue
+		}
+
+		data, err := encoded.forCodec(client.codec)
+		if err != nil {
+			logger.Errorf("Failed to encode message for client %s: %v", client.ID, err)
+			reportHubError(err, roomID, encoded.msgType)
+			continue
+		}
+
+		result := client.push(data, encoded.msgType, policy)
+		if seq != 0 && result != queue.Overflow {
+			client.trackPending(seq, data)
+		}
+		if !result.Dropped() {
+			continue
+		}
+
+		metrics.AppMetrics.IncrementSignalMessagesDropped("websocket", encoded.msgType, policy.String())
+		drops := client.drops.Add(1)
+		if result == queue.Overflow || (threshold > 0 && drops >= threshold) {
+			logger.Warnf("Disconnecting slow consumer %s: send queue full (%d dropped)", client.ID, drops)
+			slow = append(slow, client)
+		}
+	}
+	shard.mu.RUnlock()
+
+	for _, client := range slow {
+		metrics.AppMetrics.IncrementSlowConsumerDisconnects("websocket")
+		h.leaveRoom(client)
+		client.closeSend()
+	}
+}
+
+// reportHubError reports a failure to relay a message to a room
+func reportHubError(err error, roomID, msgType string) {
+	metrics.AppMetrics.IncrementWebSocketErrors()
+	errreport.Capture(err, map[string]string{
+		"subsystem":    "hub",
+		"room_id":      roomID,
+		"message_type": msgType,
+	})
+}
+
+// SetSlowConsumerThreshold sets how many dropped messages disconnect a client; 0 disables the limit
+func (h *Hub) SetSlowConsumerThreshold(threshold int) {
+	h.slowConsumerThreshold.Store(int64(threshold))
+}
+
+// sendPolicies maps message types to the overflow policy of the send queue:
+// ICE candidates drop the oldest queued message, everything else disconnects
+var sendPolicies = map[string]queue.Policy{
+	"ice-candidate": queue.DropOldest,
+}
+
+// sendPolicy returns the overflow policy for a message type
+func sendPolicy(msgType string) queue.Policy {
+	if policy, ok := sendPolicies[msgType]; ok {
+		return policy
+	}
+	return queue.Disconnect
+}
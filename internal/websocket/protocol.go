@@ -0,0 +1,401 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolVersion is the current signaling protocol version
+const ProtocolVersion = 1
+
+// subprotocolPrefix is used to negotiate the version via Sec-WebSocket-Protocol, e.g. "videocall.v1"
+const subprotocolPrefix = "videocall.v"
+
+// SupportedVersions lists every protocol version the server understands
+var SupportedVersions = []int{1}
+
+// Error codes sent in "error" envelopes
+const (
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeInvalidMessage     = "invalid_message"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeInvalidPayload     = "invalid_payload"
+	ErrCodeNotInRoom          = "not_in_room"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeRateLimited        = "rate_limited"
+)
+
+// Envelope is the versioned wrapper around every hub message
+type Envelope struct {
+	V        int             `json:"v"`
+	Type     string          `json:"type"`
+	Seq      uint64          `json:"seq,omitempty"`       // set by the server on reliable messages
+	EventSeq uint64          `json:"event_seq,omitempty"` // set by the server on replayable room events
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// ErrorPayload is the payload of an "error" envelope
+type ErrorPayload struct {
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	SupportedVersions []int  `json:"supported_versions,omitempty"`
+}
+
+// Payload is a message payload that can validate itself
+type Payload interface {
+	Validate() error
+}
+
+// roomScoped is implemented by payloads addressed to a room
+type roomScoped interface {
+	Room() string
+	Sender() string
+}
+
+// RoomPayload holds the fields common to every room-scoped message
+type RoomPayload struct {
+	RoomID   string `json:"room_id"`
+	SenderID string `json:"sender_id"`
+}
+
+// Room returns the target room ID
+func (p *RoomPayload) Room() string {
+	return p.RoomID
+}
+
+// Sender returns the signaling client ID of the sender
+func (p *RoomPayload) Sender() string {
+	return p.SenderID
+}
+
+// Validate checks the common fields
+func (p *RoomPayload) Validate() error {
+	if p.RoomID == "" {
+		return errors.New("room_id is required")
+	}
+	if p.SenderID == "" {
+		return errors.New("sender_id is required")
+	}
+	return nil
+}
+
+// SessionDescription mirrors RTCSessionDescriptionInit
+type SessionDescription struct {
+	Type string `json:"type"`
+	SDP  string `json:"sdp"`
+}
+
+// SDPPayload is the payload of "offer" and "answer" messages
+type SDPPayload struct {
+	RoomPayload
+	SDP *SessionDescription `json:"sdp"`
+}
+
+// Validate checks the session description
+func (p *SDPPayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if p.SDP == nil || p.SDP.SDP == "" {
+		return errors.New("sdp is required")
+	}
+	if p.SDP.Type != "offer" && p.SDP.Type != "answer" && p.SDP.Type != "pranswer" && p.SDP.Type != "rollback" {
+		return fmt.Errorf("invalid sdp type: %q", p.SDP.Type)
+	}
+	return nil
+}
+
+// ICECandidate mirrors RTCIceCandidateInit
+type ICECandidate struct {
+	Candidate        string  `json:"candidate"`
+	SDPMid           *string `json:"sdpMid,omitempty"`
+	SDPMLineIndex    *uint16 `json:"sdpMLineIndex,omitempty"`
+	UsernameFragment *string `json:"usernameFragment,omitempty"`
+}
+
+// ICECandidatePayload is the payload of "ice-candidate" messages
+type ICECandidatePayload struct {
+	RoomPayload
+	Candidate *ICECandidate `json:"candidate"`
+}
+
+// Validate checks the candidate
+func (p *ICECandidatePayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if p.Candidate == nil {
+		return errors.New("candidate is required")
+	}
+	return nil
+}
+
+// MutePayload is the payload of "mute" messages announcing a participant's mute state
+type MutePayload struct {
+	RoomPayload
+	Audio *bool `json:"audio,omitempty"`
+	Video *bool `json:"video,omitempty"`
+}
+
+// Validate checks that at least one kind is given
+func (p *MutePayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if p.Audio == nil && p.Video == nil {
+		return errors.New("audio or video is required")
+	}
+	return nil
+}
+
+// trackSources are the sources a "track-source" message may announce
+var trackSources = map[string]bool{
+	"camera":             true,
+	"microphone":         true,
+	"screen_share":       true,
+	"screen_share_audio": true,
+}
+
+// TrackSourcePayload is the payload of "track-source" messages telling the server and
+// the room what a published track carries, e.g. a screen share
+type TrackSourcePayload struct {
+	RoomPayload
+	TrackID string `json:"track_id"`
+	Source  string `json:"source"`
+}
+
+// Validate checks the track ID and source
+func (p *TrackSourcePayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if p.TrackID == "" {
+		return errors.New("track_id is required")
+	}
+	if !trackSources[p.Source] {
+		return fmt.Errorf("invalid source: %q", p.Source)
+	}
+	return nil
+}
+
+// maxCaptionLength is the longest caption text accepted, in characters
+const maxCaptionLength = 1000
+
+// languagePattern matches BCP 47 tags such as "en", "ru-RU" or "pt-BR"
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// CaptionPayload is the payload of "caption" messages carrying the speech of the
+// sender recognized by its client. The server delivers captions to each participant
+// in the language they subscribed to, translating them when needed.
+type CaptionPayload struct {
+	RoomPayload
+	Text     string `json:"text"`
+	Language string `json:"language"` // spoken language
+	Final    bool   `json:"final"`    // false for interim results that will be replaced
+}
+
+// Validate checks the text and language
+func (p *CaptionPayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(p.Text) == "" {
+		return errors.New("text is required")
+	}
+	if len([]rune(p.Text)) > maxCaptionLength {
+		return fmt.Errorf("text is longer than %d characters", maxCaptionLength)
+	}
+	if !languagePattern.MatchString(p.Language) {
+		return fmt.Errorf("invalid language: %q", p.Language)
+	}
+	return nil
+}
+
+// CaptionLanguagePayload is the payload of "caption-language" messages choosing the
+// language the sender receives captions in; an empty language receives them as spoken
+type CaptionLanguagePayload struct {
+	RoomPayload
+	Language string `json:"language"`
+}
+
+// Validate checks the language
+func (p *CaptionLanguagePayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if p.Language != "" && !languagePattern.MatchString(p.Language) {
+		return fmt.Errorf("invalid language: %q", p.Language)
+	}
+	return nil
+}
+
+// payloadSchemas maps message types to constructors of their payloads
+var payloadSchemas = map[string]func() Payload{
+	"join":             func() Payload { return &RoomPayload{} },
+	"offer":            func() Payload { return &SDPPayload{} },
+	"answer":           func() Payload { return &SDPPayload{} },
+	"ice-candidate":    func() Payload { return &ICECandidatePayload{} },
+	"end-call":         func() Payload { return &RoomPayload{} },
+	"mute":             func() Payload { return &MutePayload{} },
+	"track-source":     func() Payload { return &TrackSourcePayload{} },
+	"caption":          func() Payload { return &CaptionPayload{} },
+	"caption-language": func() Payload { return &CaptionLanguagePayload{} },
+	"ack":              func() Payload { return &AckPayload{} },
+	"events-since":     func() Payload { return &ResumePayload{} },
+	"server-answer":    func() Payload { return &SDPPayload{} },
+	"server-candidate": func() Payload { return &ICECandidatePayload{} },
+}
+
+// serverTypes are handled by the server through the message observer instead of
+// being relayed to the room. "server-answer" and "server-candidate" answer the offers
+// and carry the ICE candidates of the sender's peer connection with the server.
+var serverTypes = map[string]bool{
+	"caption":          true,
+	"caption-language": true,
+	"server-answer":    true,
+	"server-candidate": true,
+}
+
+// protocolError is a validation failure reported back to the sender
+type protocolError struct {
+	Code    string
+	Message string
+}
+
+func (e *protocolError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// DecodeEnvelope parses and validates an incoming message for the negotiated version
+func DecodeEnvelope(data []byte, version int) (*Envelope, Payload, error) {
+	var env Envelope
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&env); err != nil {
+		return nil, nil, &protocolError{Code: ErrCodeInvalidMessage, Message: err.Error()}
+	}
+
+	if env.V != version {
+		return nil, nil, &protocolError{
+			Code:    ErrCodeUnsupportedVersion,
+			Message: fmt.Sprintf("message version %d does not match negotiated version %d", env.V, version),
+		}
+	}
+
+	newPayload, ok := payloadSchemas[env.Type]
+	if !ok {
+		return nil, nil, &protocolError{Code: ErrCodeUnknownType, Message: fmt.Sprintf("unknown message type: %q", env.Type)}
+	}
+
+	payload := newPayload()
+	if len(env.Payload) == 0 {
+		return nil, nil, &protocolError{Code: ErrCodeInvalidPayload, Message: "payload is required"}
+	}
+	if err := json.Unmarshal(env.Payload, payload); err != nil {
+		return nil, nil, &protocolError{Code: ErrCodeInvalidPayload, Message: err.Error()}
+	}
+	if err := payload.Validate(); err != nil {
+		return nil, nil, &protocolError{Code: ErrCodeInvalidPayload, Message: err.Error()}
+	}
+
+	return &env, payload, nil
+}
+
+// EncodeEnvelope wraps a payload into an envelope of the given version
+func EncodeEnvelope(version int, msgType string, payload interface{}) ([]byte, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Envelope{V: version, Type: msgType, Payload: raw})
+}
+
+// encodeError builds an "error" envelope
+func encodeError(version int, code, message string) []byte {
+	payload := ErrorPayload{Code: code, Message: message}
+	if code == ErrCodeUnsupportedVersion {
+		payload.SupportedVersions = SupportedVersions
+	}
+	data, _ := EncodeEnvelope(version, "error", payload)
+	return data
+}
+
+// isSupportedVersion reports whether the server understands a protocol version
+func isSupportedVersion(version int) bool {
+	for _, v := range SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// handshake is the result of protocol negotiation
+type handshake struct {
+	version     int
+	codec       Codec
+	subprotocol string // echoed in Sec-WebSocket-Protocol, empty if the client did not request one
+}
+
+// negotiate picks the protocol version and encoding from the handshake. The "v" and
+// "encoding" query parameters win; otherwise the best Sec-WebSocket-Protocol offered by
+// the client is used, e.g. "videocall.v1" or "videocall.v1+msgpack". Without either,
+// the current version with JSON encoding is used.
+func negotiate(r *http.Request) (*handshake, error) {
+	query := r.URL.Query()
+	if query.Get("v") != "" || query.Get("encoding") != "" {
+		version := ProtocolVersion
+		if value := query.Get("v"); value != "" {
+			v, err := strconv.Atoi(value)
+			if err != nil || !isSupportedVersion(v) {
+				return nil, fmt.Errorf("unsupported protocol version: %s", value)
+			}
+			version = v
+		}
+		codec, err := codecByName(query.Get("encoding"))
+		if err != nil {
+			return nil, err
+		}
+		return &handshake{version: version, codec: codec}, nil
+	}
+
+	requested := false
+	var best *handshake
+	for _, protocol := range websocket.Subprotocols(r) {
+		if !strings.HasPrefix(protocol, subprotocolPrefix) {
+			continue
+		}
+		requested = true
+
+		versionPart, encoding, _ := strings.Cut(strings.TrimPrefix(protocol, subprotocolPrefix), "+")
+		version, err := strconv.Atoi(versionPart)
+		if err != nil || !isSupportedVersion(version) {
+			continue
+		}
+		codec, err := codecByName(encoding)
+		if err != nil {
+			continue
+		}
+		// Prefer higher versions; keep the client's order within a version
+		if best == nil || version > best.version {
+			best = &handshake{version: version, codec: codec, subprotocol: protocol}
+		}
+	}
+
+	switch {
+	case best != nil:
+		return best, nil
+	case requested:
+		return nil, errors.New("none of the requested protocol versions or encodings are supported")
+	default:
+		return &handshake{version: ProtocolVersion, codec: jsonCodec{}}, nil
+	}
+}
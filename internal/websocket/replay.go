@@ -0,0 +1,291 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zubans/video-call-server/internal/metrics"
+	"github.com/zubans/video-call-server/internal/queue"
+)
+
+const (
+	// Events kept per room for replay after a reconnect
+	replayBufferSize = 256
+
+	// How long a room's events and resume tokens are kept after its last activity
+	replayRetention = 5 * time.Minute
+)
+
+// replayableTypes lists room events recorded for replay
+var replayableTypes = map[string]bool{
+	"join":  true,
+	"leave": true,
+	"chat":  true,
+}
+
+// ResumePayload is the payload of "events-since" messages sent by reconnecting clients
+type ResumePayload struct {
+	RoomPayload
+	ResumeToken string `json:"resume_token"`
+	Since       uint64 `json:"since"`
+}
+
+// Validate checks the resume token
+func (p *ResumePayload) Validate() error {
+	if err := p.RoomPayload.Validate(); err != nil {
+		return err
+	}
+	if p.ResumeToken == "" {
+		return errors.New("resume_token is required")
+	}
+	return nil
+}
+
+// JoinedPayload is sent to a client after it joins a room
+type JoinedPayload struct {
+	RoomID      string `json:"room_id"`
+	ResumeToken string `json:"resume_token"`
+	EventSeq    uint64 `json:"event_seq"`
+}
+
+// ReplayCompletePayload terminates a replay; Truncated means older events were no longer available
+type ReplayCompletePayload struct {
+	RoomID    string `json:"room_id"`
+	EventSeq  uint64 `json:"event_seq"`
+	Truncated bool   `json:"truncated"`
+}
+
+// loggedEvent is a recorded room event in canonical JSON, stamped with its event_seq
+type loggedEvent struct {
+	seq     uint64
+	message []byte
+}
+
+// eventLog is a ring buffer of recent room events
+type eventLog struct {
+	events     []loggedEvent
+	next       int
+	seq        uint64
+	lastActive time.Time
+	mu         sync.Mutex
+}
+
+// newEventLog creates an empty event log
+func newEventLog() *eventLog {
+	return &eventLog{
+		events:     make([]loggedEvent, 0, replayBufferSize),
+		lastActive: time.Now(),
+	}
+}
+
+// append stamps a message with the next event sequence number and records it
+func (l *eventLog) append(message []byte) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var env Envelope
+	if err := json.Unmarshal(message, &env); err != nil {
+		return nil, err
+	}
+	l.seq++
+	env.EventSeq = l.seq
+	stamped, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+
+	event := loggedEvent{seq: l.seq, message: stamped}
+	if len(l.events) < replayBufferSize {
+		l.events = append(l.events, event)
+	} else {
+		l.events[l.next] = event
+		l.next = (l.next + 1) % replayBufferSize
+	}
+	l.lastActive = time.Now()
+
+	return stamped, nil
+}
+
+// since returns events newer than seq in order; truncated is set if some were overwritten
+func (l *eventLog) since(seq uint64) (events [][]byte, last uint64, truncated bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := 0; i < len(l.events); i++ {
+		event := l.events[(l.next+i)%len(l.events)]
+		if i == 0 && event.seq > seq+1 {
+			truncated = true
+		}
+		if event.seq > seq {
+			events = append(events, event.message)
+		}
+	}
+	l.lastActive = time.Now()
+
+	return events, l.seq, truncated
+}
+
+// current returns the last assigned event sequence number
+func (l *eventLog) current() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.seq
+}
+
+// resumeToken binds a reconnect to the room it was issued for
+type resumeToken struct {
+	roomID    string
+	expiresAt time.Time
+}
+
+// roomLog returns the event log of a room, creating it if needed
+func (h *Hub) roomLog(roomID string) *eventLog {
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	l, ok := h.history[roomID]
+	if !ok {
+		l = newEventLog()
+		h.history[roomID] = l
+	}
+	return l
+}
+
+// issueResumeToken creates a token allowing a client to resume a room session
+func (h *Hub) issueResumeToken(roomID string) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	h.historyMu.Lock()
+	h.resumeTokens[token] = resumeToken{roomID: roomID, expiresAt: time.Now().Add(replayRetention)}
+	h.historyMu.Unlock()
+
+	return token
+}
+
+// validResumeToken reports whether a token was issued for the room and has not expired
+func (h *Hub) validResumeToken(token, roomID string) bool {
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	rt, ok := h.resumeTokens[token]
+	return ok && rt.roomID == roomID && time.Now().Before(rt.expiresAt)
+}
+
+// pruneHistory drops event logs and resume tokens past their retention
+func (h *Hub) pruneHistory() {
+	now := time.Now()
+
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	for token, rt := range h.resumeTokens {
+		if now.After(rt.expiresAt) {
+			delete(h.resumeTokens, token)
+		}
+	}
+	for roomID, l := range h.history {
+		l.mu.Lock()
+		idle := now.Sub(l.lastActive)
+		l.mu.Unlock()
+		if idle > replayRetention && !h.roomActive(roomID) {
+			delete(h.history, roomID)
+		}
+	}
+}
+
+// roomActive reports whether any client is connected to a room
+func (h *Hub) roomActive(roomID string) bool {
+	h.roomsMu.RLock()
+	defer h.roomsMu.RUnlock()
+
+	_, ok := h.rooms[roomID]
+	return ok
+}
+
+// sendJoined tells a client it joined a room and hands it a resume token
+func (c *Client) sendJoined(roomID string) {
+	c.sendEnvelope("joined", JoinedPayload{
+		RoomID:      roomID,
+		ResumeToken: c.hub.issueResumeToken(roomID),
+		EventSeq:    c.hub.roomLog(roomID).current(),
+	})
+}
+
+// resume joins a reconnecting client to its room and replays the events it missed
+func (c *Client) resume(p *ResumePayload) {
+	if !c.hub.validResumeToken(p.ResumeToken, p.RoomID) {
+		c.sendError(ErrCodeInvalidPayload, "invalid or expired resume_token")
+		return
+	}
+
+	c.hub.JoinRoom(c, p.RoomID)
+	c.setSenderID(p.SenderID)
+
+	events, last, truncated := c.hub.roomLog(p.RoomID).since(p.Since)
+	for _, event := range events {
+		data, err := c.codec.Encode(event)
+		if err != nil {
+			logger.Errorf("Failed to encode replayed event for client %s: %v", c.ID, err)
+			continue
+		}
+		if c.push(data, "replay", queue.DropNewest).Dropped() {
+			truncated = true
+		}
+	}
+
+	c.sendEnvelope("replay-complete", ReplayCompletePayload{
+		RoomID:    p.RoomID,
+		EventSeq:  last,
+		Truncated: truncated,
+	})
+	c.sendJoined(p.RoomID)
+}
+
+// sendEnvelope queues a server-originated message for this client only. Reliable
+// message types are stamped with a sequence number and retransmitted until the
+// client acknowledges them, including when the send queue is full at first.
+func (c *Client) sendEnvelope(msgType string, payload interface{}) {
+	message, err := EncodeEnvelope(c.version, msgType, payload)
+	if err != nil {
+		logger.Errorf("Failed to encode %s for client %s: %v", msgType, c.ID, err)
+		return
+	}
+
+	var seq uint64
+	if reliableTypes[msgType] {
+		seq = c.hub.seq.Add(1)
+		if message, err = withSeq(message, seq); err != nil {
+			logger.Errorf("Failed to stamp %s for client %s with sequence number: %v", msgType, c.ID, err)
+			return
+		}
+	}
+
+	data, err := c.codec.Encode(message)
+	if err != nil {
+		logger.Errorf("Failed to encode %s for client %s: %v", msgType, c.ID, err)
+		return
+	}
+
+	result := c.push(data, msgType, queue.DropNewest)
+	if seq != 0 {
+		c.trackPending(seq, data)
+	}
+	if !result.Dropped() {
+		return
+	}
+
+	metrics.AppMetrics.IncrementSignalMessagesDropped("websocket", msgType, queue.DropNewest.String())
+	c.drops.Add(1)
+	if seq != 0 {
+		logger.Warnf("Send buffer of client %s full, retransmitting %s %d later", c.ID, msgType, seq)
+		return
+	}
+	logger.Warnf("Dropping %s for client %s: send buffer full", msgType, c.ID)
+}
@@ -0,0 +1,106 @@
+package websocket
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+var (
+	allowedOrigins   map[string]bool
+	allowedOriginsMu sync.RWMutex
+)
+
+// SetAllowedOrigins restricts WebSocket handshakes to the given origins
+// (e.g. "https://app.example.com"); an empty list allows any origin
+func SetAllowedOrigins(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimRight(strings.ToLower(origin), "/")] = true
+	}
+
+	allowedOriginsMu.Lock()
+	defer allowedOriginsMu.Unlock()
+
+	allowedOrigins = allowed
+}
+
+// checkOrigin verifies the Origin header against the allowlist. Requests without an
+// Origin header come from non-browser clients and are allowed.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	allowedOriginsMu.RLock()
+	defer allowedOriginsMu.RUnlock()
+
+	if len(allowedOrigins) == 0 {
+		return true
+	}
+	return allowedOrigins[strings.TrimRight(strings.ToLower(origin), "/")]
+}
+
+// SenderAuthorizer reports whether a user owns the signaling client ID in a room
+type SenderAuthorizer func(userID, roomID, senderID string) bool
+
+// SetSenderAuthorizer installs the check binding sender IDs to authenticated users
+func (h *Hub) SetSenderAuthorizer(authorize SenderAuthorizer) {
+	h.authMu.Lock()
+	defer h.authMu.Unlock()
+
+	h.authorize = authorize
+}
+
+// authorizeSender checks a sender ID; without an authorizer every sender is rejected
+func (h *Hub) authorizeSender(userID, roomID, senderID string) bool {
+	h.authMu.RLock()
+	authorize := h.authorize
+	h.authMu.RUnlock()
+
+	return authorize != nil && userID != "" && authorize(userID, roomID, senderID)
+}
+
+// MessageObserver is notified of every validated message a joined client sends to its room
+type MessageObserver func(roomID, senderID, msgType string, payload Payload)
+
+// SetMessageObserver installs a hook letting the server track state carried by signaling messages
+func (h *Hub) SetMessageObserver(observe MessageObserver) {
+	h.authMu.Lock()
+	defer h.authMu.Unlock()
+
+	h.observe = observe
+}
+
+// observeMessage passes a relayed message to the observer, if any
+func (h *Hub) observeMessage(roomID, senderID, msgType string, payload Payload) {
+	h.authMu.RLock()
+	observe := h.observe
+	h.authMu.RUnlock()
+
+	if observe != nil {
+		observe(roomID, senderID, msgType, payload)
+	}
+}
+
+// MessageFilter reports whether a joined client may send another message to its room;
+// rejected messages are neither observed nor relayed
+type MessageFilter func(roomID, senderID, msgType string) bool
+
+// SetMessageFilter installs a check throttling the messages of flooding clients
+func (h *Hub) SetMessageFilter(filter MessageFilter) {
+	h.authMu.Lock()
+	defer h.authMu.Unlock()
+
+	h.filter = filter
+}
+
+// allowMessage passes a message to the filter; without a filter every message is allowed
+func (h *Hub) allowMessage(roomID, senderID, msgType string) bool {
+	h.authMu.RLock()
+	filter := h.filter
+	h.authMu.RUnlock()
+
+	return filter == nil || filter(roomID, senderID, msgType)
+}
@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/webtransport-go"
+
+	"github.com/zubans/video-call-server/internal/metrics"
+)
+
+// WebTransport carries the signaling protocol over HTTP/3 for clients on lossy
+// networks: QUIC recovers lost packets without stalling the whole connection behind
+// TCP retransmissions, and sessions survive client address changes.
+//
+// A client opens a session with the same v and encoding query parameters as the
+// WebSocket handshake, then opens one bidirectional stream. Every message on the
+// stream is a 4-byte big-endian length followed by the message in the negotiated
+// encoding. Envelopes, acknowledgements, rooms and replay work as over WebSocket.
+
+const (
+	// frameHeaderSize is the length prefix of a message on a WebTransport stream
+	frameHeaderSize = 4
+
+	// Time allowed for the client to open its signaling stream.
+	streamAcceptWait = 10 * time.Second
+
+	// QUIC keep-alive period; shorter than browser idle timeouts.
+	keepAlivePeriod = 15 * time.Second
+)
+
+// NewWebTransportServer creates a WebTransport server listening on a UDP address with
+// the given certificate. Session requests are served by handler, which calls
+// ServeWebTransport once the request is authenticated.
+func NewWebTransportServer(addr, certFile, keyFile string, handler http.Handler) (*webtransport.Server, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load WebTransport certificate: %w", err)
+	}
+
+	return &webtransport.Server{
+		H3: http3.Server{
+			Addr:      addr,
+			Handler:   handler,
+			TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+			QUICConfig: &quic.Config{
+				MaxIdleTimeout:  pongWait,
+				KeepAlivePeriod: keepAlivePeriod,
+			},
+		},
+		CheckOrigin: checkOrigin,
+	}, nil
+}
+
+// ServeWebTransport handles WebTransport session requests from the peer. userID is
+// the authenticated user the session is bound to.
+func ServeWebTransport(hub *Hub, server *webtransport.Server, w http.ResponseWriter, r *http.Request, userID string) {
+	// Negotiate the protocol version and encoding before upgrading
+	hs, err := negotiate(r)
+	if err != nil {
+		rejectHandshake(w, err)
+		return
+	}
+
+	session, err := server.Upgrade(unwrapResponseWriter(w), r)
+	if err != nil {
+		metrics.AppMetrics.IncrementWebSocketErrors()
+		logger.Warnf("WebTransport upgrade failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	client := NewClient(hub, nil, hs.version, hs.codec)
+	client.UserID = userID
+	go client.serveSession(session)
+}
+
+// unwrapResponseWriter returns the HTTP/3 response writer beneath middleware
+// wrappers, which the WebTransport upgrade needs to take over the stream
+func unwrapResponseWriter(w http.ResponseWriter) http.ResponseWriter {
+	for {
+		if _, ok := w.(http3.Hijacker); ok {
+			return w
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return w
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// serveSession waits for the client's signaling stream and pumps messages over it
+func (c *Client) serveSession(session *webtransport.Session) {
+	ctx, cancel := context.WithTimeout(session.Context(), streamAcceptWait)
+	stream, err := session.AcceptStream(ctx)
+	cancel()
+	if err != nil {
+		logger.Warnf("WebTransport session from %s opened no signaling stream: %v", session.RemoteAddr(), err)
+		session.CloseWithError(0, "no signaling stream")
+		return
+	}
+
+	c.hub.register <- c
+	go c.writeStream(session, stream)
+	c.readStream(session, stream)
+}
+
+// readStream pumps length-prefixed messages from the WebTransport stream to the hub.
+func (c *Client) readStream(session *webtransport.Session, stream *webtransport.Stream) {
+	defer func() {
+		c.hub.unregister <- c
+		session.CloseWithError(0, "")
+	}()
+
+	reader := bufio.NewReader(stream)
+	header := make([]byte, frameHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if !errors.Is(err, io.EOF) && session.Context().Err() == nil {
+				logger.Warnf("Unexpected close of client %s: %v", c.ID, err)
+				metrics.AppMetrics.IncrementWebSocketErrors()
+			}
+			return
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > maxMessageSize {
+			logger.Warnf("Closing client %s: %d byte message exceeds the limit", c.ID, size)
+			metrics.AppMetrics.IncrementWebSocketErrors()
+			return
+		}
+
+		message := make([]byte, size)
+		if _, err := io.ReadFull(reader, message); err != nil {
+			metrics.AppMetrics.IncrementWebSocketErrors()
+			return
+		}
+		c.handleMessage(message)
+	}
+}
+
+// writeStream pumps messages from the hub to the WebTransport stream, closing the
+// session when the hub closes the channel.
+func (c *Client) writeStream(session *webtransport.Session, stream *webtransport.Stream) {
+	defer session.CloseWithError(0, "")
+
+	for message := range c.send {
+		frame := make([]byte, frameHeaderSize+len(message))
+		binary.BigEndian.PutUint32(frame, uint32(len(message)))
+		copy(frame[frameHeaderSize:], message)
+
+		stream.SetWriteDeadline(time.Now().Add(writeWait))
+		if _, err := stream.Write(frame); err != nil {
+			metrics.AppMetrics.IncrementWebSocketErrors()
+			return
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
-	"github.com/zubans/video-call-server/internal/server"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/zubans/video-call-server/internal/cli"
 )
 
 func main() {
-	// Create and run server
-	s := server.NewServer()
-	s.Run()
+	if err := cli.Run(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		log.Fatal(err)
+	}
 }
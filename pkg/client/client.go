@@ -0,0 +1,206 @@
+// Package client joins video call server rooms programmatically. It implements the
+// HTTP API and the signaling protocol of the server, so bots, recorders and
+// integration tests can log in, join a room, publish and receive media with pion
+// and chat like a browser participant.
+//
+//	c := client.New("http://localhost:8181")
+//	if err := c.Login(ctx, "bot", "password"); err != nil { ... }
+//	session, err := c.Join(ctx, roomID)
+//	if err != nil { ... }
+//	defer session.Leave(ctx)
+//
+//	session.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) { ... })
+//	pc, err := session.Publish(videoTrack)
+//
+//	for event := range session.Events() {
+//		if event.Type == client.TypeChat { ... }
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// defaultTimeout bounds HTTP requests to the server
+const defaultTimeout = 30 * time.Second
+
+// APIError is an error response of the HTTP API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Room is a room created through the API
+type Room struct {
+	ID       string `json:"room_id"`
+	Name     string `json:"name"`
+	JoinCode string `json:"join_code"`
+}
+
+// ChatMessage is a message of a room's chat
+type ChatMessage struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Event       string    `json:"event,omitempty"`
+	RoomID      string    `json:"room_id"`
+	UserID      string    `json:"user_id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Content     string    `json:"content"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Client calls the HTTP API of a server as one user
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New creates a client for the server at baseURL, e.g. "https://calls.example.com"
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// SetToken authenticates further requests with a JWT, e.g. a room token minted by
+// the room's creator
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// Token returns the JWT requests are authenticated with
+func (c *Client) Token() string {
+	return c.token
+}
+
+// Register creates an account
+func (c *Client) Register(ctx context.Context, username, email, password string) error {
+	return c.do(ctx, http.MethodPost, "/register", map[string]string{
+		"username": username,
+		"email":    email,
+		"password": password,
+	}, nil)
+}
+
+// Login authenticates with a username or email and a password and keeps the token
+func (c *Client) Login(ctx context.Context, identifier, password string) error {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/login", map[string]string{
+		"identifier": identifier,
+		"password":   password,
+	}, &resp); err != nil {
+		return err
+	}
+	c.token = resp.Token
+	return nil
+}
+
+// CreateRoom creates a room with default settings
+func (c *Client) CreateRoom(ctx context.Context, name string) (*Room, error) {
+	var room Room
+	if err := c.do(ctx, http.MethodPost, "/create-room", map[string]string{"name": name}, &room); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+// SendChat posts a chat message to a room
+func (c *Client) SendChat(ctx context.Context, roomID, message string) error {
+	return c.do(ctx, http.MethodPost, "/chat/send", map[string]string{
+		"room_id": roomID,
+		"message": message,
+	}, nil)
+}
+
+// ChatHistory returns the recent chat messages of a room, oldest first
+func (c *Client) ChatHistory(ctx context.Context, roomID string) ([]ChatMessage, error) {
+	var resp struct {
+		Messages []ChatMessage `json:"messages"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/chat/history/"+roomID, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+// joinRoom registers the user as a participant of a room
+func (c *Client) joinRoom(ctx context.Context, roomID string) (clientID string, iceServers []webrtc.ICEServer, err error) {
+	var resp struct {
+		ClientID   string             `json:"client_id"`
+		ICEServers []webrtc.ICEServer `json:"ice_servers"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/join-room", map[string]string{"room_id": roomID}, &resp); err != nil {
+		return "", nil, err
+	}
+	return resp.ClientID, resp.ICEServers, nil
+}
+
+// leaveRoom removes a participant from a room
+func (c *Client) leaveRoom(ctx context.Context, roomID, clientID string) error {
+	return c.do(ctx, http.MethodPost, "/leave-room", map[string]string{
+		"room_id":   roomID,
+		"client_id": clientID,
+	}, nil)
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		if failure.Error == "" {
+			failure.Error = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: failure.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pion/webrtc/v3"
+
+	signaling "github.com/zubans/video-call-server/internal/websocket"
+)
+
+// OnTrack sets the handler of media tracks received from other participants. Set it
+// before publishing or before another participant calls.
+func (s *Session) OnTrack(handler func(*webrtc.TrackRemote, *webrtc.RTPReceiver)) {
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+
+	s.onTrack = handler
+}
+
+// Publish sends tracks to the room and offers a call to the other participant. Like
+// the browser client, the session keeps one peer connection with the room: it is
+// renegotiated when tracks are added, answered when another participant calls and
+// recreated with the published tracks when a participant joins after a call ended.
+func (s *Session) Publish(tracks ...webrtc.TrackLocal) (*webrtc.PeerConnection, error) {
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+
+	pc, err := s.peerConnection()
+	if err != nil {
+		return nil, err
+	}
+	for _, track := range tracks {
+		if err := addTrack(pc, track); err != nil {
+			return nil, err
+		}
+	}
+	s.tracks = append(s.tracks, tracks...)
+
+	if err := s.offer(pc); err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
+
+// PeerConnection returns the current peer connection with the room, or nil
+func (s *Session) PeerConnection() *webrtc.PeerConnection {
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+
+	return s.pc
+}
+
+// handlePeerEvent applies a signaling message from another participant to the peer
+// connection; failures are reported as error events
+func (s *Session) handlePeerEvent(event Event) {
+	s.peerMu.Lock()
+	defer s.peerMu.Unlock()
+
+	if err := s.applyPeerEvent(event); err != nil {
+		s.deliver(Event{Type: TypeError, Payload: errorPayload(ErrorCodePeerConnection, err)})
+	}
+}
+
+func (s *Session) applyPeerEvent(event Event) error {
+	switch event.Type {
+	case TypeJoin:
+		// Call the new participant if there is something to send
+		if len(s.tracks) == 0 || s.pc != nil {
+			return nil
+		}
+		pc, err := s.peerConnection()
+		if err != nil {
+			return err
+		}
+		return s.offer(pc)
+
+	case TypeOffer:
+		var payload signaling.SDPPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		pc, err := s.peerConnection()
+		if err != nil {
+			return err
+		}
+		if err := s.setRemoteDescription(pc, payload.SDP); err != nil {
+			return err
+		}
+		answer, err := pc.CreateAnswer(nil)
+		if err != nil {
+			return fmt.Errorf("failed to create answer: %w", err)
+		}
+		if err := pc.SetLocalDescription(answer); err != nil {
+			return fmt.Errorf("failed to set local description: %w", err)
+		}
+		return s.send(TypeAnswer, &signaling.SDPPayload{RoomPayload: s.roomPayload(), SDP: &signaling.SessionDescription{Type: answer.Type.String(), SDP: answer.SDP}})
+
+	case TypeAnswer:
+		if s.pc == nil {
+			return nil
+		}
+		var payload signaling.SDPPayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		return s.setRemoteDescription(s.pc, payload.SDP)
+
+	case TypeICECandidate:
+		var payload signaling.ICECandidatePayload
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		if payload.Candidate == nil {
+			return nil
+		}
+		candidate := webrtc.ICECandidateInit{
+			Candidate:        payload.Candidate.Candidate,
+			SDPMid:           payload.Candidate.SDPMid,
+			SDPMLineIndex:    payload.Candidate.SDPMLineIndex,
+			UsernameFragment: payload.Candidate.UsernameFragment,
+		}
+		// Candidates may arrive before the offer or answer they belong to
+		if s.pc == nil || s.pc.RemoteDescription() == nil {
+			s.candidates = append(s.candidates, candidate)
+			return nil
+		}
+		return s.pc.AddICECandidate(candidate)
+
+	case TypeEndCall, TypeLeave:
+		if s.pc != nil {
+			s.pc.Close()
+			s.pc = nil
+		}
+		s.candidates = nil
+	}
+	return nil
+}
+
+// peerConnection returns the peer connection, creating it with the published tracks
+// if needed; called with peerMu held
+func (s *Session) peerConnection() (*webrtc.PeerConnection, error) {
+	if s.pc != nil {
+		return s.pc, nil
+	}
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{ICEServers: s.ICEServers})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create peer connection: %w", err)
+	}
+	for _, track := range s.tracks {
+		if err := addTrack(pc, track); err != nil {
+			pc.Close()
+			return nil, err
+		}
+	}
+
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		init := candidate.ToJSON()
+		s.send(TypeICECandidate, &signaling.ICECandidatePayload{
+			RoomPayload: s.roomPayload(),
+			Candidate: &signaling.ICECandidate{
+				Candidate:        init.Candidate,
+				SDPMid:           init.SDPMid,
+				SDPMLineIndex:    init.SDPMLineIndex,
+				UsernameFragment: init.UsernameFragment,
+			},
+		})
+	})
+	pc.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		s.peerMu.Lock()
+		handler := s.onTrack
+		s.peerMu.Unlock()
+
+		if handler != nil {
+			handler(track, receiver)
+		}
+	})
+
+	s.pc = pc
+	return pc, nil
+}
+
+// offer sends an offer for the peer connection's current tracks; called with peerMu held
+func (s *Session) offer(pc *webrtc.PeerConnection) error {
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create offer: %w", err)
+	}
+	if err := pc.SetLocalDescription(offer); err != nil {
+		return fmt.Errorf("failed to set local description: %w", err)
+	}
+	return s.send(TypeOffer, &signaling.SDPPayload{RoomPayload: s.roomPayload(), SDP: &signaling.SessionDescription{Type: offer.Type.String(), SDP: offer.SDP}})
+}
+
+// setRemoteDescription applies a session description and the candidates that arrived
+// before it; called with peerMu held
+func (s *Session) setRemoteDescription(pc *webrtc.PeerConnection, sdp *signaling.SessionDescription) error {
+	if sdp == nil {
+		return errors.New("sdp is required")
+	}
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.NewSDPType(sdp.Type), SDP: sdp.SDP}); err != nil {
+		return fmt.Errorf("failed to set remote description: %w", err)
+	}
+
+	for _, candidate := range s.candidates {
+		if err := pc.AddICECandidate(candidate); err != nil {
+			return fmt.Errorf("failed to add ICE candidate: %w", err)
+		}
+	}
+	s.candidates = nil
+	return nil
+}
+
+// addTrack adds a track to a peer connection and drains its RTCP, which pion needs
+// read for interceptors such as NACK to work
+func addTrack(pc *webrtc.PeerConnection, track webrtc.TrackLocal) error {
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		return fmt.Errorf("failed to add track %s: %w", track.ID(), err)
+	}
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := sender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,368 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pion/webrtc/v3"
+
+	signaling "github.com/zubans/video-call-server/internal/websocket"
+)
+
+// Message types of the signaling protocol
+const (
+	TypeJoin            = "join"
+	TypeJoined          = "joined"
+	TypeLeave           = "leave"
+	TypeOffer           = "offer"
+	TypeAnswer          = "answer"
+	TypeICECandidate    = "ice-candidate"
+	TypeEndCall         = "end-call"
+	TypeMute            = "mute"
+	TypeTrackSource     = "track-source"
+	TypeCaption         = "caption"
+	TypeCaptionLanguage = "caption-language"
+	TypeChat            = "chat"
+	TypeSignal          = "signal"
+	TypeServerDraining  = "server-draining"
+	TypeError           = "error"
+)
+
+// ErrorCodePeerConnection is the code of error events raised by the session's own
+// peer connection rather than by the server
+const ErrorCodePeerConnection = "peer_connection"
+
+const (
+	// Time allowed to write a message to the server.
+	writeWait = 10 * time.Second
+
+	// Events queued for the application before further events are dropped.
+	eventBuffer = 256
+)
+
+// Event is a message received from the room
+type Event struct {
+	Type     string
+	SenderID string // signaling client ID of the participant that sent it, if any
+	EventSeq uint64 // position in the room's event log, for replayable events
+	Payload  json.RawMessage
+}
+
+// Decode unmarshals the event's payload, e.g. into a ChatMessage for TypeChat
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// Caption is the payload of TypeCaption events: speech of another participant in the
+// language the session subscribed to with SetCaptionLanguage
+type Caption struct {
+	SenderID         string `json:"sender_id"`
+	Text             string `json:"text"`
+	Language         string `json:"language"`
+	OriginalLanguage string `json:"original_language,omitempty"` // set when translated
+	Final            bool   `json:"final"`
+}
+
+// Session is a participant's signaling connection to a room
+type Session struct {
+	RoomID     string
+	ClientID   string
+	ICEServers []webrtc.ICEServer
+
+	client *Client
+	conn   *websocket.Conn
+
+	// Guards writes to conn
+	writeMu sync.Mutex
+
+	events chan Event
+	joined chan error
+	done   chan struct{}
+	err    error
+	closed atomic.Bool
+
+	// Reliable messages already delivered, by sequence number; used by the read loop only
+	seen map[uint64]bool
+
+	// The peer connection with the room and the tracks published on it
+	peerMu     sync.Mutex
+	pc         *webrtc.PeerConnection
+	tracks     []webrtc.TrackLocal
+	candidates []webrtc.ICECandidateInit
+	onTrack    func(*webrtc.TrackRemote, *webrtc.RTPReceiver)
+}
+
+// Join enters a room: it registers the user as a participant, connects the
+// signaling WebSocket and announces the participant, returning once the server has
+// confirmed the join.
+func (c *Client) Join(ctx context.Context, roomID string) (*Session, error) {
+	clientID, iceServers, err := c.joinRoom(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := url.Parse(c.baseURL + "/ws")
+	if err != nil {
+		return nil, err
+	}
+	switch endpoint.Scheme {
+	case "https":
+		endpoint.Scheme = "wss"
+	default:
+		endpoint.Scheme = "ws"
+	}
+	endpoint.RawQuery = url.Values{
+		"token":   {c.token},
+		"room_id": {roomID},
+		"v":       {strconv.Itoa(signaling.ProtocolVersion)},
+	}.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, endpoint.String(), nil)
+	if err != nil {
+		c.leaveRoom(context.Background(), roomID, clientID)
+		return nil, fmt.Errorf("failed to connect signaling: %w", err)
+	}
+
+	s := &Session{
+		RoomID:     roomID,
+		ClientID:   clientID,
+		ICEServers: iceServers,
+		client:     c,
+		conn:       conn,
+		events:     make(chan Event, eventBuffer),
+		joined:     make(chan error, 1),
+		done:       make(chan struct{}),
+		seen:       make(map[uint64]bool),
+	}
+	go s.readLoop()
+
+	if err := s.send(TypeJoin, s.roomPayload()); err != nil {
+		s.Leave(context.Background())
+		return nil, err
+	}
+	select {
+	case err = <-s.joined:
+	case <-s.done:
+		err = s.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		s.Leave(context.Background())
+		return nil, err
+	}
+	return s, nil
+}
+
+// Events returns the messages received from the room, including chat, participants
+// joining and leaving and errors. The channel is closed when the session ends; events
+// are dropped while eventBuffer of them are waiting to be read.
+func (s *Session) Events() <-chan Event {
+	return s.events
+}
+
+// Done is closed when the session ends
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the session ended, once Done is closed
+func (s *Session) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// SendChat posts a chat message to the room
+func (s *Session) SendChat(ctx context.Context, message string) error {
+	return s.client.SendChat(ctx, s.RoomID, message)
+}
+
+// Mute announces the participant's microphone and camera state; nil leaves a kind unchanged
+func (s *Session) Mute(audio, video *bool) error {
+	return s.send(TypeMute, &signaling.MutePayload{RoomPayload: s.roomPayload(), Audio: audio, Video: video})
+}
+
+// SetTrackSource tells the room what a published track carries: "camera",
+// "microphone", "screen_share" or "screen_share_audio"
+func (s *Session) SetTrackSource(trackID, source string) error {
+	return s.send(TypeTrackSource, &signaling.TrackSourcePayload{RoomPayload: s.roomPayload(), TrackID: trackID, Source: source})
+}
+
+// SendCaption shares recognized speech of the participant in a spoken language such as
+// "en"; interim results are sent with final false and replaced by later captions
+func (s *Session) SendCaption(text, language string, final bool) error {
+	return s.send(TypeCaption, &signaling.CaptionPayload{RoomPayload: s.roomPayload(), Text: text, Language: language, Final: final})
+}
+
+// SetCaptionLanguage chooses the language captions are received in, translated if the
+// server has a translation backend; an empty language receives them as spoken
+func (s *Session) SetCaptionLanguage(language string) error {
+	return s.send(TypeCaptionLanguage, &signaling.CaptionLanguagePayload{RoomPayload: s.roomPayload(), Language: language})
+}
+
+// Leave ends the call, removes the participant from the room and closes the session
+func (s *Session) Leave(ctx context.Context) error {
+	s.send(TypeEndCall, s.roomPayload())
+	err := s.client.leaveRoom(ctx, s.RoomID, s.ClientID)
+	s.Close()
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == 404 {
+		// The server already removed the participant
+		return nil
+	}
+	return err
+}
+
+// Close disconnects the signaling connection and the peer connection without
+// leaving the room; the server removes the participant after its timeout
+func (s *Session) Close() error {
+	if s.closed.Swap(true) {
+		<-s.done
+		return nil
+	}
+
+	s.writeMu.Lock()
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeWait))
+	s.writeMu.Unlock()
+
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+// roomPayload returns the room fields of messages the participant sends
+func (s *Session) roomPayload() signaling.RoomPayload {
+	return signaling.RoomPayload{RoomID: s.RoomID, SenderID: s.ClientID}
+}
+
+// send writes a message to the server
+func (s *Session) send(msgType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	message, err := json.Marshal(signaling.Envelope{V: signaling.ProtocolVersion, Type: msgType, Payload: data})
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return s.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// readLoop receives messages until the connection closes, then ends the session
+func (s *Session) readLoop() {
+	var err error
+	for {
+		var data []byte
+		if _, data, err = s.conn.ReadMessage(); err != nil {
+			break
+		}
+
+		// A frame may carry several messages
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var env signaling.Envelope
+			if err := decoder.Decode(&env); err != nil {
+				if !errors.Is(err, io.EOF) {
+					s.deliver(Event{Type: TypeError, Payload: errorPayload(signaling.ErrCodeInvalidMessage, err)})
+				}
+				break
+			}
+			s.receive(&env)
+		}
+	}
+
+	// Closing the session is not a failure
+	if s.closed.Load() {
+		err = nil
+	}
+	s.finish(err)
+}
+
+// finish releases the session's resources; called once, by the read loop
+func (s *Session) finish(err error) {
+	s.peerMu.Lock()
+	if s.pc != nil {
+		s.pc.Close()
+		s.pc = nil
+	}
+	s.peerMu.Unlock()
+
+	s.err = err
+	close(s.done)
+	close(s.events)
+}
+
+// receive acknowledges, deduplicates and dispatches a message
+func (s *Session) receive(env *signaling.Envelope) {
+	// Reliable messages are retransmitted until acknowledged
+	if env.Seq != 0 {
+		s.send("ack", &signaling.AckPayload{Seq: env.Seq})
+		if s.seen[env.Seq] {
+			return
+		}
+		s.seen[env.Seq] = true
+	}
+
+	var sender struct {
+		SenderID string `json:"sender_id"`
+	}
+	json.Unmarshal(env.Payload, &sender)
+	event := Event{Type: env.Type, SenderID: sender.SenderID, EventSeq: env.EventSeq, Payload: env.Payload}
+
+	switch env.Type {
+	case TypeJoined:
+		s.confirmJoin(nil)
+	case TypeError:
+		var failure signaling.ErrorPayload
+		json.Unmarshal(env.Payload, &failure)
+		s.confirmJoin(fmt.Errorf("signaling error %s: %s", failure.Code, failure.Message))
+	case TypeOffer, TypeAnswer, TypeICECandidate, TypeEndCall, TypeLeave, TypeJoin:
+		if event.SenderID != s.ClientID {
+			s.handlePeerEvent(event)
+		}
+	}
+
+	s.deliver(event)
+}
+
+// confirmJoin reports the outcome of the join to Join; only the first outcome counts
+func (s *Session) confirmJoin(err error) {
+	select {
+	case s.joined <- err:
+	default:
+	}
+}
+
+// deliver queues an event for the application without blocking
+func (s *Session) deliver(event Event) {
+	select {
+	case s.events <- event:
+	default:
+	}
+}
+
+// errorPayload encodes an error in the payload format of "error" messages
+func errorPayload(code string, err error) json.RawMessage {
+	data, _ := json.Marshal(signaling.ErrorPayload{Code: code, Message: err.Error()})
+	return data
+}